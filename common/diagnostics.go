@@ -0,0 +1,303 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package common
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DiagnosticCode identifies the kind of problem described by a diagnostic.
+type DiagnosticCode string
+
+// Diagnostic codes reported by the unipdf packages.
+const (
+	// DiagnosticGeneric is the code of the messages logged as errors or warnings without a
+	// specific code.
+	DiagnosticGeneric DiagnosticCode = "generic"
+	// DiagnosticXrefRepaired indicates that the cross-reference information or the trailer of a
+	// damaged file was repaired.
+	DiagnosticXrefRepaired DiagnosticCode = "xref-repaired"
+	// DiagnosticStreamLength indicates that the Length entry of a stream was invalid and was
+	// corrected.
+	DiagnosticStreamLength DiagnosticCode = "stream-length"
+	// DiagnosticObjectInvalid indicates that an object could not be loaded or has an invalid type.
+	DiagnosticObjectInvalid DiagnosticCode = "object-invalid"
+	// DiagnosticPageInvalid indicates that a node of the page tree is invalid.
+	DiagnosticPageInvalid DiagnosticCode = "page-invalid"
+	// DiagnosticContentInvalid indicates that a content stream could not be parsed or processed.
+	DiagnosticContentInvalid DiagnosticCode = "content-invalid"
+	// DiagnosticFontMissing indicates that a font referenced by a content stream is not in the
+	// resources.
+	DiagnosticFontMissing DiagnosticCode = "font-missing"
+	// DiagnosticFontInvalid indicates that a font could not be loaded.
+	DiagnosticFontInvalid DiagnosticCode = "font-invalid"
+)
+
+// Diagnostic describes a problem met while processing a document.
+type Diagnostic struct {
+	// Level is the severity of the problem.
+	Level LogLevel
+
+	// Code identifies the kind of problem.
+	Code DiagnosticCode
+
+	// Message describes the problem.
+	Message string
+
+	// ObjectNumber is the number of the object concerned, or 0 if the problem does not concern a
+	// specific object.
+	ObjectNumber int64
+
+	// PageNumber is the number of the page concerned (starting from 1), or 0 if the problem does
+	// not concern a specific page.
+	PageNumber int
+}
+
+// String returns a string describing the diagnostic.
+func (d Diagnostic) String() string {
+	var loc string
+	if d.PageNumber > 0 {
+		loc += fmt.Sprintf(" (page %d)", d.PageNumber)
+	}
+	if d.ObjectNumber > 0 {
+		loc += fmt.Sprintf(" (object %d)", d.ObjectNumber)
+	}
+	return fmt.Sprintf("%s%s: %s", d.Code, loc, d.Message)
+}
+
+// DiagnosticSink is implemented by the loggers which receive the diagnostics in a structured
+// form, in addition to the log messages.
+type DiagnosticSink interface {
+	Report(d Diagnostic)
+}
+
+// ReportDiagnostic reports `d` to `logger`: to its Report method if it is a DiagnosticSink,
+// otherwise as a log message at the level of the diagnostic. The global Log is used if `logger` is
+// nil.
+func ReportDiagnostic(logger Logger, d Diagnostic) {
+	ReportDiagnosticDepth(logger, 1, d)
+}
+
+// ReportDiagnosticDepth reports `d` to `logger` like ReportDiagnostic. If `d` is logged as a message,
+// its source is the caller `skip` frames above the caller of ReportDiagnosticDepth (0 for the direct
+// caller), which lets the reporting helpers attribute the message to their own caller.
+func ReportDiagnosticDepth(logger Logger, skip int, d Diagnostic) {
+	if logger == nil {
+		logger = Log
+	}
+	switch sink := logger.(type) {
+	case depthSink:
+		sink.reportDepth(skip+1, d)
+	case DiagnosticSink:
+		sink.Report(d)
+	default:
+		logDepth(logger, d.Level, skip+1, "%s", d)
+	}
+}
+
+// depthLogger is implemented by the loggers which can log a message for a caller further up the
+// stack, so that the loggers wrapping them report the actual source of the messages.
+type depthLogger interface {
+	logDepth(level LogLevel, skip int, format string, args ...interface{})
+}
+
+// depthSink is implemented by the diagnostic sinks which forward the diagnostics to a logger and
+// which can log them for a caller further up the stack.
+type depthSink interface {
+	reportDepth(skip int, d Diagnostic)
+}
+
+// logDepth logs the `format`, `args` message to `logger` at `level`, with the source of the caller
+// `skip` frames above the caller of logDepth when `logger` supports it.
+func logDepth(logger Logger, level LogLevel, skip int, format string, args ...interface{}) {
+	if dl, ok := logger.(depthLogger); ok {
+		dl.logDepth(level, skip+1, format, args...)
+		return
+	}
+	logLevel(logger, level, format, args...)
+}
+
+// logLevel logs the `format`, `args` message to `logger` at `level`.
+func logLevel(logger Logger, level LogLevel, format string, args ...interface{}) {
+	switch level {
+	case LogLevelError:
+		logger.Error(format, args...)
+	case LogLevelWarning:
+		logger.Warning(format, args...)
+	case LogLevelNotice:
+		logger.Notice(format, args...)
+	case LogLevelInfo:
+		logger.Info(format, args...)
+	case LogLevelDebug:
+		logger.Debug(format, args...)
+	default:
+		logger.Trace(format, args...)
+	}
+}
+
+// Diagnostics is a logger which records the diagnostics reported to it, in order to retrieve them
+// once a document is processed. It can be attached to the objects processing documents, such as
+// readers and writers, to attribute the problems to the document that caused them.
+// The log messages and the diagnostics are forwarded to an underlying logger. The errors and
+// warnings logged without a diagnostic code are recorded with the DiagnosticGeneric code.
+// Diagnostics is safe for concurrent use.
+type Diagnostics struct {
+	logger Logger
+
+	lock        sync.Mutex
+	diagnostics []Diagnostic
+}
+
+// NewDiagnostics returns a new diagnostics recorder forwarding the log messages and the
+// diagnostics to `logger`. The global Log, at the time of the logging, is used if `logger` is nil.
+func NewDiagnostics(logger Logger) *Diagnostics {
+	return &Diagnostics{logger: logger}
+}
+
+// Diagnostics returns the diagnostics recorded so far, in the order of reporting.
+func (l *Diagnostics) Diagnostics() []Diagnostic {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return append([]Diagnostic(nil), l.diagnostics...)
+}
+
+// Report records `d` and forwards it to the underlying logger.
+func (l *Diagnostics) Report(d Diagnostic) {
+	l.reportDepth(1, d)
+}
+
+// reportDepth records `d` and forwards it to the underlying logger for the caller `skip` frames
+// above the caller of reportDepth.
+func (l *Diagnostics) reportDepth(skip int, d Diagnostic) {
+	l.lock.Lock()
+	l.diagnostics = append(l.diagnostics, d)
+	l.lock.Unlock()
+	ReportDiagnosticDepth(l.Logger(), skip+1, d)
+}
+
+// Logger returns the underlying logger, to which the log messages and the diagnostics are forwarded.
+func (l *Diagnostics) Logger() Logger {
+	if l != nil && l.logger != nil {
+		return l.logger
+	}
+	return Log
+}
+
+// logDepth records a generic diagnostic for the errors and warnings, and logs the `format`, `args`
+// message at `level` for the caller `skip` frames above the caller of logDepth.
+func (l *Diagnostics) logDepth(level LogLevel, skip int, format string, args ...interface{}) {
+	if level <= LogLevelWarning {
+		l.lock.Lock()
+		l.diagnostics = append(l.diagnostics, Diagnostic{
+			Level:   level,
+			Code:    DiagnosticGeneric,
+			Message: fmt.Sprintf(format, args...),
+		})
+		l.lock.Unlock()
+	}
+	logDepth(l.Logger(), level, skip+1, format, args...)
+}
+
+// Error records and logs error message.
+func (l *Diagnostics) Error(format string, args ...interface{}) {
+	l.logDepth(LogLevelError, 1, format, args...)
+}
+
+// Warning records and logs warning message.
+func (l *Diagnostics) Warning(format string, args ...interface{}) {
+	l.logDepth(LogLevelWarning, 1, format, args...)
+}
+
+// Notice logs notice message.
+func (l *Diagnostics) Notice(format string, args ...interface{}) {
+	l.logDepth(LogLevelNotice, 1, format, args...)
+}
+
+// Info logs info message.
+func (l *Diagnostics) Info(format string, args ...interface{}) {
+	l.logDepth(LogLevelInfo, 1, format, args...)
+}
+
+// Debug logs debug message.
+func (l *Diagnostics) Debug(format string, args ...interface{}) {
+	l.logDepth(LogLevelDebug, 1, format, args...)
+}
+
+// Trace logs trace message.
+func (l *Diagnostics) Trace(format string, args ...interface{}) {
+	l.logDepth(LogLevelTrace, 1, format, args...)
+}
+
+// IsLogLevel returns true if the underlying logger logs the messages at `level`.
+func (l *Diagnostics) IsLogLevel(level LogLevel) bool {
+	return l.Logger().IsLogLevel(level)
+}
+
+// pageLogger is a logger reporting the diagnostics for a page.
+type pageLogger struct {
+	Logger
+	pageNum int
+}
+
+// WithPage returns a logger forwarding the log messages to `logger` and reporting its diagnostics
+// for page `pageNum`, unless they concern another page. The global Log is used if `logger` is nil.
+func WithPage(logger Logger, pageNum int) Logger {
+	if logger == nil {
+		logger = Log
+	}
+	return &pageLogger{Logger: logger, pageNum: pageNum}
+}
+
+// Report reports `d` for the page of the logger.
+func (l *pageLogger) Report(d Diagnostic) {
+	l.reportDepth(1, d)
+}
+
+// reportDepth reports `d` for the page of the logger, for the caller `skip` frames above the caller
+// of reportDepth.
+func (l *pageLogger) reportDepth(skip int, d Diagnostic) {
+	if d.PageNumber == 0 {
+		d.PageNumber = l.pageNum
+	}
+	ReportDiagnosticDepth(l.Logger, skip+1, d)
+}
+
+// logDepth logs the `format`, `args` message at `level` for the caller `skip` frames above the
+// caller of logDepth.
+func (l *pageLogger) logDepth(level LogLevel, skip int, format string, args ...interface{}) {
+	logDepth(l.Logger, level, skip+1, format, args...)
+}
+
+// Error logs error message.
+func (l *pageLogger) Error(format string, args ...interface{}) {
+	l.logDepth(LogLevelError, 1, format, args...)
+}
+
+// Warning logs warning message.
+func (l *pageLogger) Warning(format string, args ...interface{}) {
+	l.logDepth(LogLevelWarning, 1, format, args...)
+}
+
+// Notice logs notice message.
+func (l *pageLogger) Notice(format string, args ...interface{}) {
+	l.logDepth(LogLevelNotice, 1, format, args...)
+}
+
+// Info logs info message.
+func (l *pageLogger) Info(format string, args ...interface{}) {
+	l.logDepth(LogLevelInfo, 1, format, args...)
+}
+
+// Debug logs debug message.
+func (l *pageLogger) Debug(format string, args ...interface{}) {
+	l.logDepth(LogLevelDebug, 1, format, args...)
+}
+
+// Trace logs trace message.
+func (l *pageLogger) Trace(format string, args ...interface{}) {
+	l.logDepth(LogLevelTrace, 1, format, args...)
+}
@@ -0,0 +1,52 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package common
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestDiagnosticsCaller checks that the messages logged through the wrapping loggers report the
+// source of the logging call rather than the wrappers.
+func TestDiagnosticsCaller(t *testing.T) {
+	var buf bytes.Buffer
+	diagnostics := NewDiagnostics(NewWriterLogger(LogLevelTrace, &buf))
+	loggers := map[string]Logger{
+		"writer":      NewWriterLogger(LogLevelTrace, &buf),
+		"diagnostics": diagnostics,
+		"page":        WithPage(diagnostics, 2),
+	}
+
+	for name, logger := range loggers {
+		buf.Reset()
+		logger.Error("error %d", 1)
+		logger.Warning("warning %d", 2)
+		logger.Debug("debug %d", 3)
+		ReportDiagnostic(logger, Diagnostic{Level: LogLevelWarning, Code: DiagnosticFontMissing, Message: "F1"})
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 4, name)
+		for _, line := range lines {
+			require.Contains(t, line, " diagnostics_test.go:", name)
+		}
+		require.Contains(t, lines[0], "[ERROR]")
+		require.Contains(t, lines[0], "error 1")
+		require.Contains(t, lines[1], "warning 2")
+		require.Contains(t, lines[2], "debug 3")
+	}
+
+	got := diagnostics.Diagnostics()
+	require.Len(t, got, 6)
+	require.Equal(t, DiagnosticGeneric, got[0].Code)
+	require.Equal(t, "error 1", got[0].Message)
+	require.Equal(t, DiagnosticFontMissing, got[2].Code)
+	require.Equal(t, 0, got[2].PageNumber)
+	require.Equal(t, 2, got[5].PageNumber)
+}
@@ -136,7 +136,15 @@ func (l ConsoleLogger) Trace(format string, args ...interface{}) {
 
 // output writes `format`, `args` log message prefixed by the source file name, line and `prefix`
 func (l ConsoleLogger) output(f io.Writer, prefix string, format string, args ...interface{}) {
-	logToWriter(f, prefix, format, args...)
+	logToWriter(f, 3, prefix, format, args...)
+}
+
+// logDepth logs the `format`, `args` message at `level`, with the source file name and line of the
+// caller `skip` frames above the caller of logDepth.
+func (l ConsoleLogger) logDepth(level LogLevel, skip int, format string, args ...interface{}) {
+	if l.LogLevel >= level {
+		logToWriter(os.Stdout, skip+2, levelPrefix(level), format, args...)
+	}
 }
 
 var Log Logger = DummyLogger{}
@@ -217,11 +225,38 @@ func (l WriterLogger) Trace(format string, args ...interface{}) {
 
 // logToWriter writes `format`, `args` log message prefixed by the source file name, line and `prefix`
 func (l WriterLogger) logToWriter(f io.Writer, prefix string, format string, args ...interface{}) {
-	logToWriter(f, prefix, format, args)
+	logToWriter(f, 3, prefix, format, args...)
+}
+
+// logDepth logs the `format`, `args` message at `level`, with the source file name and line of the
+// caller `skip` frames above the caller of logDepth.
+func (l WriterLogger) logDepth(level LogLevel, skip int, format string, args ...interface{}) {
+	if l.LogLevel >= level {
+		logToWriter(l.Output, skip+2, levelPrefix(level), format, args...)
+	}
+}
+
+// levelPrefix returns the prefix of the messages logged at `level`.
+func levelPrefix(level LogLevel) string {
+	switch level {
+	case LogLevelError:
+		return "[ERROR] "
+	case LogLevelWarning:
+		return "[WARNING] "
+	case LogLevelNotice:
+		return "[NOTICE] "
+	case LogLevelInfo:
+		return "[INFO] "
+	case LogLevelDebug:
+		return "[DEBUG] "
+	}
+	return "[TRACE] "
 }
 
-func logToWriter(f io.Writer, prefix string, format string, args ...interface{}) {
-	_, file, line, ok := runtime.Caller(3)
+// logToWriter writes `format`, `args` log message to `f` prefixed by `prefix` and by the source
+// file name and line of the caller `depth` frames above logToWriter.
+func logToWriter(f io.Writer, depth int, prefix string, format string, args ...interface{}) {
+	_, file, line, ok := runtime.Caller(depth)
 	if !ok {
 		file = "???"
 		line = 0
@@ -309,7 +309,6 @@ func (csp *ContentStreamParser) ParseInlineImage() (*ContentStreamInlineImage, e
 	im := ContentStreamInlineImage{}
 
 	for {
-		csp.skipSpaces()
 		obj, isOperand, err := csp.parseObject()
 		if err != nil {
 			return nil, err
@@ -385,12 +384,12 @@ func (csp *ContentStreamParser) ParseInlineImage() (*ContentStreamInlineImage, e
 				common.Log.Trace("ID start")
 
 				// Skip the space if its there.
-				b, err := csp.reader.Peek(1)
+				b, err := csp.lexer.PeekBytes(1)
 				if err != nil {
 					return nil, err
 				}
 				if core.IsWhiteSpace(b[0]) {
-					csp.reader.Discard(1)
+					csp.lexer.Discard(1)
 				}
 
 				// Unfortunately there is no good way to know how many bytes to read since it
@@ -401,7 +400,7 @@ func (csp *ContentStreamParser) ParseInlineImage() (*ContentStreamInlineImage, e
 				state := 0
 				var skipBytes []byte
 				for {
-					c, err := csp.reader.ReadByte()
+					c, err := csp.lexer.ReadByte()
 					if err != nil {
 						common.Log.Debug("Unable to find end of image EI in inline image data")
 						return nil, err
@@ -450,7 +449,7 @@ func (csp *ContentStreamParser) ParseInlineImage() (*ContentStreamInlineImage, e
 							// Whitspace after EI.
 							// To ensure that is not a part of encoded image data: Peek up to 20 bytes ahead
 							// and check that the following data is valid objects/operands.
-							peekbytes, err := csp.reader.Peek(20)
+							peekbytes, err := csp.lexer.PeekBytes(20)
 							if err != nil && err != io.EOF {
 								return nil, err
 							}
@@ -248,3 +248,56 @@ EI (Very long string that is going to be only partially parser 12345678901234567
 		require.Equal(t, tcase.Expected, *ops)
 	}
 }
+
+func TestContentStreamParsing(t *testing.T) {
+	testcases := []struct {
+		Content  string
+		Expected ContentStreamOperations
+	}{
+		// Case 1. A sign within a number starts a new number.
+		{
+			`1.-2 3-4 m`,
+			ContentStreamOperations{
+				&ContentStreamOperation{Operand: "m", Params: []core.PdfObject{
+					core.MakeFloat(1), core.MakeInteger(-2), core.MakeInteger(3), core.MakeInteger(-4),
+				}},
+			},
+		},
+		// Case 2. Content streams contain no references: R is an operand.
+		{
+			`1 2 R 0 0 1 RG`,
+			ContentStreamOperations{
+				&ContentStreamOperation{Operand: "R", Params: makeParamsFromInts([]int64{1, 2})},
+				&ContentStreamOperation{Operand: "RG", Params: makeParamsFromInts([]int64{0, 0, 1})},
+			},
+		},
+		// Case 3. Content streams contain no streams: stream is an operand, not followed by data.
+		{
+			`<< /A 1 >> stream (text) Tj endstream`,
+			ContentStreamOperations{
+				&ContentStreamOperation{Operand: "stream", Params: []core.PdfObject{
+					makeDict(makeKeyVal("A", core.MakeInteger(1))),
+				}},
+				&ContentStreamOperation{Operand: "Tj", Params: []core.PdfObject{core.MakeString("text")}},
+				&ContentStreamOperation{Operand: "endstream"},
+			},
+		},
+		// Case 4. Unterminated string at the end of the content stream.
+		{
+			`q Q (unterminated`,
+			ContentStreamOperations{
+				&ContentStreamOperation{Operand: "q"},
+				&ContentStreamOperation{Operand: "Q"},
+			},
+		},
+	}
+
+	for i, tcase := range testcases {
+		t.Logf("Case %d", i+1)
+		parser := NewContentStreamParser(tcase.Content)
+		ops, err := parser.Parse()
+		require.NoError(t, err)
+		require.NotNil(t, ops)
+		require.Equal(t, tcase.Expected, *ops)
+	}
+}
@@ -0,0 +1,269 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"container/list"
+	"sort"
+)
+
+// ObjectCachePolicy defines the limits of the cache of objects loaded by the parser. By default the
+// cache is unbounded and every object looked up is kept in memory for the lifetime of the parser.
+// When a limit is set, the least recently used objects are evicted from the cache once the limit is
+// exceeded, and evicted objects are parsed again from the file on their next lookup.
+//
+// As the stream data of evicted objects is released with them, streams are effectively re-read
+// from the file on demand. A stream larger than MaxBytes is never kept in the cache.
+//
+// Note that objects returned by the parser remain valid after being evicted, but a later lookup of
+// the same object number returns a newly parsed copy. Changes made to an evicted object are thus not
+// visible through the parser, so bounded caches are intended for read-only processing.
+type ObjectCachePolicy struct {
+	// MaxObjects is the maximum number of cached entries (objects and decoded object streams).
+	// Zero means no limit.
+	MaxObjects int
+
+	// MaxBytes is the maximum estimated size in bytes of the cached entries, including stream
+	// data and decoded object streams. Zero means no limit.
+	MaxBytes int64
+}
+
+// bounded returns true if the policy limits the size of the cache.
+func (policy ObjectCachePolicy) bounded() bool {
+	return policy.MaxObjects > 0 || policy.MaxBytes > 0
+}
+
+// cacheKey identifies an entry of the object cache. Decoded object streams are cached separately
+// from the stream objects themselves.
+type cacheKey struct {
+	objNum int
+	objstm bool
+}
+
+// cacheEntry is an entry of the LRU list of cached objects.
+type cacheEntry struct {
+	key  cacheKey
+	size int64
+}
+
+// objectCacheLRU tracks the usage and estimated size of the cached objects when the cache is
+// bounded by an ObjectCachePolicy.
+type objectCacheLRU struct {
+	policy  ObjectCachePolicy
+	order   *list.List // Most recently used first.
+	entries map[cacheKey]*list.Element
+	size    int64
+}
+
+// newObjectCacheLRU returns a new LRU list for `policy`.
+func newObjectCacheLRU(policy ObjectCachePolicy) *objectCacheLRU {
+	return &objectCacheLRU{
+		policy:  policy,
+		order:   list.New(),
+		entries: map[cacheKey]*list.Element{},
+	}
+}
+
+// add records the entry `key` of `size` bytes as most recently used.
+func (lru *objectCacheLRU) add(key cacheKey, size int64) {
+	if elem, has := lru.entries[key]; has {
+		entry := elem.Value.(*cacheEntry)
+		lru.size += size - entry.size
+		entry.size = size
+		lru.order.MoveToFront(elem)
+		return
+	}
+	lru.entries[key] = lru.order.PushFront(&cacheEntry{key: key, size: size})
+	lru.size += size
+}
+
+// touch marks the entry `key` as most recently used.
+func (lru *objectCacheLRU) touch(key cacheKey) {
+	if elem, has := lru.entries[key]; has {
+		lru.order.MoveToFront(elem)
+	}
+}
+
+// exceeded returns true if the cached entries exceed the limits of the policy.
+func (lru *objectCacheLRU) exceeded() bool {
+	if lru.policy.MaxObjects > 0 && lru.order.Len() > lru.policy.MaxObjects {
+		return true
+	}
+	return lru.policy.MaxBytes > 0 && lru.size > lru.policy.MaxBytes
+}
+
+// removeOldest removes the least recently used entry and returns its key.
+func (lru *objectCacheLRU) removeOldest() (cacheKey, bool) {
+	elem := lru.order.Back()
+	if elem == nil {
+		return cacheKey{}, false
+	}
+	entry := lru.order.Remove(elem).(*cacheEntry)
+	delete(lru.entries, entry.key)
+	lru.size -= entry.size
+	return entry.key, true
+}
+
+// SetObjectCachePolicy sets the policy limiting the cache of loaded objects. Objects already cached
+// are evicted as needed to satisfy the new limits. Passing the zero value makes the cache unbounded.
+func (parser *PdfParser) SetObjectCachePolicy(policy ObjectCachePolicy) {
+	parser.lock.Lock()
+	defer parser.lock.Unlock()
+
+	if !policy.bounded() {
+		parser.cache = nil
+		return
+	}
+	parser.cache = newObjectCacheLRU(policy)
+
+	// Register the entries that are already cached, in a deterministic order.
+	var objNums []int
+	for objNum := range parser.ObjCache {
+		objNums = append(objNums, objNum)
+	}
+	sort.Ints(objNums)
+	for _, objNum := range objNums {
+		parser.cache.add(cacheKey{objNum: objNum}, estimateObjectSize(parser.ObjCache[objNum]))
+	}
+	objNums = objNums[:0]
+	for objNum := range parser.objstms {
+		objNums = append(objNums, objNum)
+	}
+	sort.Ints(objNums)
+	for _, objNum := range objNums {
+		parser.cache.add(cacheKey{objNum: objNum, objstm: true}, parser.objstms[objNum].size())
+	}
+	parser.evictObjects()
+}
+
+// GetObjectCachePolicy returns the policy limiting the cache of loaded objects.
+func (parser *PdfParser) GetObjectCachePolicy() ObjectCachePolicy {
+	parser.lock.Lock()
+	defer parser.lock.Unlock()
+
+	if parser.cache == nil {
+		return ObjectCachePolicy{}
+	}
+	return parser.cache.policy
+}
+
+// cachedObject returns the cached object `objNum`, if any.
+func (parser *PdfParser) cachedObject(objNum int) (PdfObject, bool) {
+	obj, ok := parser.ObjCache[objNum]
+	if ok && parser.cache != nil {
+		parser.cache.touch(cacheKey{objNum: objNum})
+	}
+	return obj, ok
+}
+
+// cacheObject adds `obj` to the object cache. The cache is not trimmed until evictObjects is
+// called, so that the object can be finalized (e.g. decrypted) first.
+func (parser *PdfParser) cacheObject(objNum int, obj PdfObject) {
+	parser.ObjCache[objNum] = obj
+	if parser.cache != nil {
+		parser.cache.add(cacheKey{objNum: objNum}, estimateObjectSize(obj))
+	}
+}
+
+// cachedObjectStream returns the cached decoded object stream `objNum`, if any.
+func (parser *PdfParser) cachedObjectStream(objNum int) (objectStream, bool) {
+	objstm, ok := parser.objstms[objNum]
+	if ok && parser.cache != nil {
+		parser.cache.touch(cacheKey{objNum: objNum, objstm: true})
+	}
+	return objstm, ok
+}
+
+// cacheObjectStream adds the decoded object stream `objstm` to the cache.
+func (parser *PdfParser) cacheObjectStream(objNum int, objstm objectStream) {
+	parser.objstms[objNum] = objstm
+	if parser.cache != nil {
+		parser.cache.add(cacheKey{objNum: objNum, objstm: true}, objstm.size())
+	}
+}
+
+// resetObjectCache empties the cache of objects and decoded object streams.
+func (parser *PdfParser) resetObjectCache() {
+	parser.ObjCache = objectCache{}
+	parser.objstms = make(objectStreams)
+	if parser.cache != nil {
+		parser.cache = newObjectCacheLRU(parser.cache.policy)
+	}
+}
+
+// evictObjects evicts the least recently used entries until the cache satisfies its policy.
+func (parser *PdfParser) evictObjects() {
+	if parser.cache == nil {
+		return
+	}
+	for parser.cache.exceeded() {
+		key, ok := parser.cache.removeOldest()
+		if !ok {
+			break
+		}
+		if key.objstm {
+			delete(parser.objstms, key.objNum)
+			continue
+		}
+		obj, has := parser.ObjCache[key.objNum]
+		if !has {
+			continue
+		}
+		parser.log().Trace("Evicting object %d from cache", key.objNum)
+		delete(parser.ObjCache, key.objNum)
+		if parser.crypter != nil {
+			// Release the object. A copy parsed again is decrypted again.
+			delete(parser.crypter.decryptedObjects, obj)
+		}
+	}
+}
+
+// size returns the estimated memory size of the decoded object stream.
+func (objstm objectStream) size() int64 {
+	return int64(len(objstm.ds)) + int64(len(objstm.offsets))*2*objectSizeOverhead
+}
+
+// objectSizeOverhead is the estimated memory overhead of an object.
+const objectSizeOverhead = 16
+
+// estimateObjectSize returns an estimate of the memory size of `obj`, including stream data.
+// Indirect objects and streams contained in `obj` are separate cache entries and are not counted.
+func estimateObjectSize(obj PdfObject) int64 {
+	switch t := obj.(type) {
+	case *PdfIndirectObject:
+		return objectSizeOverhead + estimateDirectObjectSize(t.PdfObject)
+	case *PdfObjectStream:
+		return objectSizeOverhead + int64(len(t.Stream)) + estimateDirectObjectSize(t.PdfObjectDictionary)
+	}
+	return estimateDirectObjectSize(obj)
+}
+
+// estimateDirectObjectSize returns the estimated memory size of the direct object `obj`.
+func estimateDirectObjectSize(obj PdfObject) int64 {
+	switch t := obj.(type) {
+	case *PdfObjectDictionary:
+		if t == nil {
+			return objectSizeOverhead
+		}
+		size := int64(objectSizeOverhead)
+		for _, key := range t.Keys() {
+			size += objectSizeOverhead + int64(len(key)) + estimateDirectObjectSize(t.Get(key))
+		}
+		return size
+	case *PdfObjectArray:
+		size := int64(objectSizeOverhead)
+		for _, elem := range t.Elements() {
+			size += estimateDirectObjectSize(elem)
+		}
+		return size
+	case *PdfObjectString:
+		return objectSizeOverhead + int64(len(t.val))
+	case *PdfObjectName:
+		return objectSizeOverhead + int64(len(*t))
+	}
+	// Numbers, booleans, null, references, and indirect objects or streams (cached separately).
+	return objectSizeOverhead
+}
@@ -0,0 +1,157 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// makeCacheTestPdf returns a document with `n` stream objects (objects 3 to n+2) of `streamLen` bytes.
+func makeCacheTestPdf(n, streamLen int) []byte {
+	objects := map[int]string{
+		1: "<< /Type /Catalog /Pages 2 0 R >>",
+		2: "<< /Type /Pages /Kids [] /Count 0 >>",
+	}
+	for i := 0; i < n; i++ {
+		data := strings.Repeat(fmt.Sprintf("%c", 'a'+i%26), streamLen)
+		objects[i+3] = fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", streamLen, data)
+	}
+	data, _ := appendRevision([]byte("%PDF-1.7\n"), objects, nil, n+3, 0)
+	return data
+}
+
+func TestObjectCacheMaxObjects(t *testing.T) {
+	parser, err := NewParser(bytes.NewReader(makeCacheTestPdf(10, 100)))
+	require.NoError(t, err)
+	parser.SetObjectCachePolicy(ObjectCachePolicy{MaxObjects: 3})
+
+	streams := map[int]*PdfObjectStream{}
+	for objNum := 3; objNum < 13; objNum++ {
+		obj, err := parser.LookupByNumber(objNum)
+		require.NoError(t, err)
+		stream, ok := obj.(*PdfObjectStream)
+		require.True(t, ok)
+		require.Len(t, stream.Stream, 100)
+		streams[objNum] = stream
+		require.LessOrEqual(t, len(parser.ObjCache), 3)
+	}
+	require.Equal(t, []int{10, 11, 12}, sortedObjNums(parser.ObjCache))
+
+	// Recently used objects are kept.
+	obj, err := parser.LookupByNumber(10)
+	require.NoError(t, err)
+	require.True(t, obj == streams[10])
+	_, err = parser.LookupByNumber(3)
+	require.NoError(t, err)
+	require.Equal(t, []int{3, 10, 12}, sortedObjNums(parser.ObjCache))
+
+	// Evicted objects are parsed again.
+	obj, err = parser.LookupByNumber(5)
+	require.NoError(t, err)
+	stream, ok := obj.(*PdfObjectStream)
+	require.True(t, ok)
+	require.False(t, stream == streams[5])
+	require.Equal(t, streams[5].Stream, stream.Stream)
+
+	// Unbounded again.
+	parser.SetObjectCachePolicy(ObjectCachePolicy{})
+	for objNum := 3; objNum < 13; objNum++ {
+		_, err := parser.LookupByNumber(objNum)
+		require.NoError(t, err)
+	}
+	require.Len(t, parser.ObjCache, 10)
+}
+
+func TestObjectCacheMaxBytes(t *testing.T) {
+	parser, err := NewParser(bytes.NewReader(makeCacheTestPdf(5, 1000)))
+	require.NoError(t, err)
+	for objNum := 1; objNum < 8; objNum++ {
+		_, err := parser.LookupByNumber(objNum)
+		require.NoError(t, err)
+	}
+	require.Len(t, parser.ObjCache, 7)
+
+	// Existing entries are evicted when the policy is set.
+	parser.SetObjectCachePolicy(ObjectCachePolicy{MaxBytes: 2500})
+	require.Equal(t, ObjectCachePolicy{MaxBytes: 2500}, parser.GetObjectCachePolicy())
+	require.Equal(t, []int{6, 7}, sortedObjNums(parser.ObjCache))
+	require.LessOrEqual(t, parser.cache.size, int64(2500))
+
+	// Streams larger than the limit are not kept.
+	parser.SetObjectCachePolicy(ObjectCachePolicy{MaxBytes: 500})
+	require.Empty(t, parser.ObjCache)
+	obj, err := parser.LookupByNumber(3)
+	require.NoError(t, err)
+	stream, ok := obj.(*PdfObjectStream)
+	require.True(t, ok)
+	require.Len(t, stream.Stream, 1000)
+	require.Empty(t, parser.ObjCache)
+
+	_, err = parser.LookupByNumber(1)
+	require.NoError(t, err)
+	require.Equal(t, []int{1}, sortedObjNums(parser.ObjCache))
+}
+
+func TestObjectCacheEncrypted(t *testing.T) {
+	f, err := os.Open("./testdata/testcase_encry.pdf")
+	require.NoError(t, err)
+	defer f.Close()
+
+	parser, err := NewParser(f)
+	require.NoError(t, err)
+	_, err = parser.IsEncrypted()
+	require.NoError(t, err)
+	ok, err := parser.Decrypt([]byte("456"))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	objNums := parser.GetObjectNums()
+	expected := map[int]string{}
+	for _, objNum := range objNums {
+		obj, err := parser.LookupByNumber(objNum)
+		require.NoError(t, err)
+		expected[objNum] = cacheTestContent(obj)
+	}
+
+	// Objects parsed again after eviction are decrypted again.
+	parser.SetObjectCachePolicy(ObjectCachePolicy{MaxObjects: 1})
+	for i := 0; i < 2; i++ {
+		for _, objNum := range objNums {
+			obj, err := parser.LookupByNumber(objNum)
+			require.NoError(t, err)
+			require.Equal(t, expected[objNum], cacheTestContent(obj), "object %d", objNum)
+		}
+	}
+	require.LessOrEqual(t, len(parser.crypter.decryptedObjects), len(parser.ObjCache)+2)
+}
+
+// cacheTestContent returns the content of the indirect or stream object `obj`.
+func cacheTestContent(obj PdfObject) string {
+	switch t := obj.(type) {
+	case *PdfIndirectObject:
+		return t.PdfObject.WriteString()
+	case *PdfObjectStream:
+		return t.PdfObjectDictionary.WriteString() + string(t.Stream)
+	}
+	return obj.WriteString()
+}
+
+// sortedObjNums returns the sorted object numbers of the objects in `cache`.
+func sortedObjNums(cache objectCache) []int {
+	var objNums []int
+	for objNum := range cache {
+		objNums = append(objNums, objNum)
+	}
+	sort.Ints(objNums)
+	return objNums
+}
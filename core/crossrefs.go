@@ -9,6 +9,7 @@ import (
 	"bufio"
 	"bytes"
 	"errors"
+	"io"
 	"os"
 	"strings"
 
@@ -68,120 +69,34 @@ type objectCache map[int]PdfObject
 
 // lookupObjectViaOS returns an object from an object stream.
 func (parser *PdfParser) lookupObjectViaOS(sobjNumber int, objNum int) (PdfObject, error) {
-	var bufReader *bytes.Reader
-	var objstm objectStream
-	var cached bool
-
-	objstm, cached = parser.objstms[sobjNumber]
+	objstm, cached := parser.cachedObjectStream(sobjNumber)
 	if !cached {
-		soi, err := parser.LookupByNumber(sobjNumber)
+		var err error
+		objstm, err = parser.loadObjectStream(sobjNumber)
 		if err != nil {
-			common.Log.Debug("Missing object stream with number %d", sobjNumber)
 			return nil, err
 		}
+	}
 
-		so, ok := soi.(*PdfObjectStream)
-		if !ok {
-			return nil, errors.New("invalid object stream")
-		}
-
-		if parser.crypter != nil && !parser.crypter.isDecrypted(so) {
-			return nil, errors.New("need to decrypt the stream")
-		}
-
-		sod := so.PdfObjectDictionary
-		common.Log.Trace("so d: %s\n", sod.String())
-		name, ok := sod.Get("Type").(*PdfObjectName)
-		if !ok {
-			common.Log.Debug("ERROR: Object stream should always have a Type")
-			return nil, errors.New("object stream missing Type")
-		}
-		if strings.ToLower(string(*name)) != "objstm" {
-			common.Log.Debug("ERROR: Object stream type shall always be ObjStm !")
-			return nil, errors.New("object stream type != ObjStm")
-		}
-
-		N, ok := sod.Get("N").(*PdfObjectInteger)
-		if !ok {
-			return nil, errors.New("invalid N in stream dictionary")
-		}
-		firstOffset, ok := sod.Get("First").(*PdfObjectInteger)
-		if !ok {
-			return nil, errors.New("invalid First in stream dictionary")
-		}
-
-		common.Log.Trace("type: %s number of objects: %d", name, *N)
-		ds, err := DecodeStream(so)
-		if err != nil {
-			return nil, err
-		}
-
-		common.Log.Trace("Decoded: %s", ds)
-
-		// Temporarily change the reader object to this decoded buffer.
-		// Change back afterwards.
-		bakOffset := parser.GetFileOffset()
-		defer func() { parser.SetFileOffset(bakOffset) }()
-
-		bufReader = bytes.NewReader(ds)
-		parser.reader = bufio.NewReader(bufReader)
-
-		common.Log.Trace("Parsing offset map")
-		// Load the offset map (relative to the beginning of the stream...)
-		offsets := map[int]int64{}
-		// Object list and offsets.
-		for i := 0; i < int(*N); i++ {
-			parser.skipSpaces()
-			// Object number.
-			obj, err := parser.parseNumber()
-			if err != nil {
-				return nil, err
-			}
-			onum, ok := obj.(*PdfObjectInteger)
-			if !ok {
-				return nil, errors.New("invalid object stream offset table")
-			}
-
-			parser.skipSpaces()
-			// Offset.
-			obj, err = parser.parseNumber()
-			if err != nil {
-				return nil, err
-			}
-			offset, ok := obj.(*PdfObjectInteger)
-			if !ok {
-				return nil, errors.New("invalid object stream offset table")
-			}
-
-			common.Log.Trace("obj %d offset %d", *onum, *offset)
-			offsets[int(*onum)] = int64(*firstOffset + *offset)
-		}
-
-		objstm = objectStream{N: int(*N), ds: ds, offsets: offsets}
-		parser.objstms[sobjNumber] = objstm
-	} else {
-		// Temporarily change the reader object to this decoded buffer.
-		// Point back afterwards.
-		bakOffset := parser.GetFileOffset()
-		defer func() { parser.SetFileOffset(bakOffset) }()
+	// Temporarily change the reader object to this decoded buffer.
+	// Point back afterwards.
+	bakOffset := parser.GetFileOffset()
+	defer func() { parser.SetFileOffset(bakOffset) }()
 
-		bufReader = bytes.NewReader(objstm.ds)
-		// Temporarily change the reader object to this decoded buffer.
-		parser.reader = bufio.NewReader(bufReader)
-	}
+	bufReader := bytes.NewReader(objstm.ds)
 
 	offset := objstm.offsets[objNum]
-	common.Log.Trace("ACTUAL offset[%d] = %d", objNum, offset)
+	parser.log().Trace("ACTUAL offset[%d] = %d", objNum, offset)
 
 	bufReader.Seek(offset, os.SEEK_SET)
 	parser.reader = bufio.NewReader(bufReader)
 
 	bb, _ := parser.reader.Peek(100)
-	common.Log.Trace("OBJ peek \"%s\"", string(bb))
+	parser.log().Trace("OBJ peek \"%s\"", string(bb))
 
 	val, err := parser.parseObject()
 	if err != nil {
-		common.Log.Debug("ERROR Fail to read object (%s)", err)
+		parser.log().Debug("ERROR Fail to read object (%s)", err)
 		return nil, err
 	}
 	if val == nil {
@@ -196,8 +111,118 @@ func (parser *PdfParser) lookupObjectViaOS(sobjNumber int, objNum int) (PdfObjec
 	return &io, nil
 }
 
+// loadObjectStream loads the object stream with object number `sobjNumber`, decoding its data and
+// the offset map of the objects it contains. The loaded object stream is cached.
+func (parser *PdfParser) loadObjectStream(sobjNumber int) (objectStream, error) {
+	soi, _, err := parser.lookupByNumberWrapper(sobjNumber, true)
+	if err != nil {
+		parser.log().Debug("Missing object stream with number %d", sobjNumber)
+		return objectStream{}, err
+	}
+
+	so, ok := soi.(*PdfObjectStream)
+	if !ok {
+		return objectStream{}, errors.New("invalid object stream")
+	}
+
+	if parser.crypter != nil && !parser.crypter.isDecrypted(so) {
+		return objectStream{}, errors.New("need to decrypt the stream")
+	}
+
+	sod := so.PdfObjectDictionary
+	parser.log().Trace("so d: %s\n", sod.String())
+	name, ok := sod.Get("Type").(*PdfObjectName)
+	if !ok {
+		parser.log().Debug("ERROR: Object stream should always have a Type")
+		return objectStream{}, errors.New("object stream missing Type")
+	}
+	if strings.ToLower(string(*name)) != "objstm" {
+		parser.log().Debug("ERROR: Object stream type shall always be ObjStm !")
+		return objectStream{}, errors.New("object stream type != ObjStm")
+	}
+
+	N, ok := sod.Get("N").(*PdfObjectInteger)
+	if !ok {
+		return objectStream{}, errors.New("invalid N in stream dictionary")
+	}
+	firstOffset, ok := sod.Get("First").(*PdfObjectInteger)
+	if !ok {
+		return objectStream{}, errors.New("invalid First in stream dictionary")
+	}
+
+	parser.log().Trace("type: %s number of objects: %d", name, *N)
+
+	// Resolve the filter parameters prior to decoding, as resolving references while decoding
+	// would require another lookup.
+	for _, key := range []PdfObjectName{"Filter", "DecodeParms"} {
+		if ref, ok := sod.Get(key).(*PdfObjectReference); ok {
+			obj, err := parser.resolve(ref)
+			if err != nil {
+				return objectStream{}, err
+			}
+			sod.Set(key, obj)
+		}
+	}
+	ds, err := DecodeStream(so)
+	if err != nil {
+		return objectStream{}, err
+	}
+
+	parser.log().Trace("Decoded: %s", ds)
+
+	// Temporarily change the reader object to this decoded buffer.
+	// Change back afterwards.
+	bakOffset := parser.GetFileOffset()
+	defer func() { parser.SetFileOffset(bakOffset) }()
+
+	bufReader := bytes.NewReader(ds)
+	parser.reader = bufio.NewReader(bufReader)
+
+	parser.log().Trace("Parsing offset map")
+	// Load the offset map (relative to the beginning of the stream...)
+	offsets := map[int]int64{}
+	// Object list and offsets.
+	for i := 0; i < int(*N); i++ {
+		parser.skipSpaces()
+		// Object number.
+		obj, err := parser.parseNumber()
+		if err != nil {
+			return objectStream{}, err
+		}
+		onum, ok := obj.(*PdfObjectInteger)
+		if !ok {
+			return objectStream{}, errors.New("invalid object stream offset table")
+		}
+
+		parser.skipSpaces()
+		// Offset.
+		obj, err = parser.parseNumber()
+		if err != nil {
+			return objectStream{}, err
+		}
+		offset, ok := obj.(*PdfObjectInteger)
+		if !ok {
+			return objectStream{}, errors.New("invalid object stream offset table")
+		}
+
+		parser.log().Trace("obj %d offset %d", *onum, *offset)
+		offsets[int(*onum)] = int64(*firstOffset + *offset)
+	}
+
+	objstm := objectStream{N: int(*N), ds: ds, offsets: offsets}
+	parser.cacheObjectStream(sobjNumber, objstm)
+	return objstm, nil
+}
+
 // LookupByNumber looks up a PdfObject by object number.  Returns an error on failure.
 func (parser *PdfParser) LookupByNumber(objNumber int) (PdfObject, error) {
+	if obj, _, ok := parser.lookupConcurrent(objNumber, nil); ok {
+		return obj, nil
+	}
+
+	parser.lock.Lock()
+	defer parser.lock.Unlock()
+
 	// Outside interface for lookupByNumberWrapper.  Default attempts repairs of bad xref tables.
 	obj, _, err := parser.lookupByNumberWrapper(objNumber, true)
 	return obj, err
@@ -207,6 +232,10 @@ func (parser *PdfParser) LookupByNumber(objNumber int) (PdfObject, error) {
 func (parser *PdfParser) lookupByNumberWrapper(objNumber int, attemptRepairs bool) (PdfObject, bool, error) {
 	obj, inObjStream, err := parser.lookupByNumber(objNumber, attemptRepairs)
 	if err != nil {
+		if attemptRepairs {
+			parser.report(common.LogLevelWarning, common.DiagnosticObjectInvalid, int64(objNumber),
+				"unable to load object: %v", err)
+		}
 		return nil, inObjStream, err
 	}
 
@@ -218,6 +247,7 @@ func (parser *PdfParser) lookupByNumberWrapper(objNumber int, attemptRepairs boo
 			return nil, inObjStream, err
 		}
 	}
+	parser.evictObjects()
 
 	return obj, inObjStream, nil
 }
@@ -237,9 +267,9 @@ func getObjectNumber(obj PdfObject) (int64, int64, error) {
 // lookupByNumber is used by LookupByNumber.
 // attemptRepairs signals whether to attempt repair if broken.
 func (parser *PdfParser) lookupByNumber(objNumber int, attemptRepairs bool) (PdfObject, bool, error) {
-	obj, ok := parser.ObjCache[objNumber]
+	obj, ok := parser.cachedObject(objNumber)
 	if ok {
-		common.Log.Trace("Returning cached object %d", objNumber)
+		parser.log().Trace("Returning cached object %d", objNumber)
 		return obj, false, nil
 	}
 
@@ -248,29 +278,29 @@ func (parser *PdfParser) lookupByNumber(objNumber int, attemptRepairs bool) (Pdf
 		// An indirect reference to an undefined object shall not be
 		// considered an error by a conforming reader; it shall be
 		// treated as a reference to the null object.
-		common.Log.Trace("Unable to locate object in xrefs! - Returning null object")
+		parser.log().Trace("Unable to locate object in xrefs! - Returning null object")
 		var nullObj PdfObjectNull
 		return &nullObj, false, nil
 	}
 
-	common.Log.Trace("Lookup obj number %d", objNumber)
+	parser.log().Trace("Lookup obj number %d", objNumber)
 	if xref.XType == XrefTypeTableEntry {
-		common.Log.Trace("xrefobj obj num %d", xref.ObjectNumber)
-		common.Log.Trace("xrefobj gen %d", xref.Generation)
-		common.Log.Trace("xrefobj offset %d", xref.Offset)
+		parser.log().Trace("xrefobj obj num %d", xref.ObjectNumber)
+		parser.log().Trace("xrefobj gen %d", xref.Generation)
+		parser.log().Trace("xrefobj offset %d", xref.Offset)
 
 		parser.rs.Seek(xref.Offset, os.SEEK_SET)
 		parser.reader = bufio.NewReader(parser.rs)
 
 		obj, err := parser.ParseIndirectObject()
 		if err != nil {
-			common.Log.Debug("ERROR Failed reading xref (%s)", err)
+			parser.log().Debug("ERROR Failed reading xref (%s)", err)
 			// Offset pointing to a non-object.  Try to repair the file.
 			if attemptRepairs {
-				common.Log.Debug("Attempting to repair xrefs (top down)")
+				parser.log().Debug("Attempting to repair xrefs (top down)")
 				xrefTable, err := parser.repairRebuildXrefsTopDown()
 				if err != nil {
-					common.Log.Debug("ERROR Failed repair (%s)", err)
+					parser.log().Debug("ERROR Failed repair (%s)", err)
 					return nil, false, err
 				}
 				parser.xrefs = *xrefTable
@@ -285,39 +315,39 @@ func (parser *PdfParser) lookupByNumber(objNumber int, attemptRepairs bool) (Pdf
 			// all the items in the xref and look each one up and correct.
 			realObjNum, _, _ := getObjectNumber(obj)
 			if int(realObjNum) != objNumber {
-				common.Log.Debug("Invalid xrefs: Rebuilding")
+				parser.log().Debug("Invalid xrefs: Rebuilding")
 				err := parser.rebuildXrefTable()
 				if err != nil {
 					return nil, false, err
 				}
 				// Empty the cache.
-				parser.ObjCache = objectCache{}
+				parser.resetObjectCache()
 				// Try looking up again and return.
 				return parser.lookupByNumberWrapper(objNumber, false)
 			}
 		}
 
-		common.Log.Trace("Returning obj")
-		parser.ObjCache[objNumber] = obj
+		parser.log().Trace("Returning obj")
+		parser.cacheObject(objNumber, obj)
 		return obj, false, nil
 	} else if xref.XType == XrefTypeObjectStream {
-		common.Log.Trace("xref from object stream!")
-		common.Log.Trace(">Load via OS!")
-		common.Log.Trace("Object stream available in object %d/%d", xref.OsObjNumber, xref.OsObjIndex)
+		parser.log().Trace("xref from object stream!")
+		parser.log().Trace(">Load via OS!")
+		parser.log().Trace("Object stream available in object %d/%d", xref.OsObjNumber, xref.OsObjIndex)
 
 		if xref.OsObjNumber == objNumber {
-			common.Log.Debug("ERROR Circular reference!?!")
+			parser.log().Debug("ERROR Circular reference!?!")
 			return nil, true, errors.New("xref circular reference")
 		}
 
 		if _, exists := parser.xrefs.ObjectMap[xref.OsObjNumber]; exists {
 			optr, err := parser.lookupObjectViaOS(xref.OsObjNumber, objNumber) //xref.OsObjIndex)
 			if err != nil {
-				common.Log.Debug("ERROR Returning ERR (%s)", err)
+				parser.log().Debug("ERROR Returning ERR (%s)", err)
 				return nil, true, err
 			}
-			common.Log.Trace("<Loaded via OS")
-			parser.ObjCache[objNumber] = optr
+			parser.log().Trace("<Loaded via OS")
+			parser.cacheObject(objNumber, optr)
 			if parser.crypter != nil {
 				// Mark as decrypted (inside object stream) for caching.
 				// and avoid decrypting decrypted object.
@@ -326,7 +356,7 @@ func (parser *PdfParser) lookupByNumber(objNumber int, attemptRepairs bool) (Pdf
 			return optr, true, nil
 		}
 
-		common.Log.Debug("?? Belongs to a non-cross referenced object ...!")
+		parser.log().Debug("?? Belongs to a non-cross referenced object ...!")
 		return nil, true, errors.New("os belongs to a non cross referenced object")
 	}
 	return nil, false, errors.New("unknown xref type")
@@ -334,7 +364,7 @@ func (parser *PdfParser) lookupByNumber(objNumber int, attemptRepairs bool) (Pdf
 
 // LookupByReference looks up a PdfObject by a reference.
 func (parser *PdfParser) LookupByReference(ref PdfObjectReference) (PdfObject, error) {
-	common.Log.Trace("Looking up reference %s", ref.String())
+	parser.log().Trace("Looking up reference %s", ref.String())
 	return parser.LookupByNumber(int(ref.ObjectNumber))
 }
 
@@ -345,29 +375,135 @@ func (parser *PdfParser) Resolve(obj PdfObject) (PdfObject, error) {
 		// Direct object already.
 		return obj, nil
 	}
+	if o, _, ok := parser.lookupConcurrent(int(ref.ObjectNumber), nil); ok {
+		return derefIndirect(o)
+	}
+
+	parser.lock.Lock()
+	defer parser.lock.Unlock()
+	return parser.resolve(obj)
+}
+
+// resolve implements Resolve. The lock needs to be held by the caller.
+func (parser *PdfParser) resolve(obj PdfObject) (PdfObject, error) {
+	ref, isRef := obj.(*PdfObjectReference)
+	if !isRef {
+		// Direct object already.
+		return obj, nil
+	}
 
 	bakOffset := parser.GetFileOffset()
 	defer func() { parser.SetFileOffset(bakOffset) }()
 
-	o, err := parser.LookupByReference(*ref)
+	o, _, err := parser.lookupByNumberWrapper(int(ref.ObjectNumber), true)
 	if err != nil {
 		return nil, err
 	}
+	return derefIndirect(o)
+}
 
-	io, isInd := o.(*PdfIndirectObject)
+// derefIndirect returns the direct object contained in the indirect object `o`, or `o` if not an
+// indirect object (stream or null object).
+func derefIndirect(o PdfObject) (PdfObject, error) {
+	ind, isInd := o.(*PdfIndirectObject)
 	if !isInd {
-		// Not indirect (Stream or null object).
 		return o, nil
 	}
-	o = io.PdfObject
-	_, isRef = o.(*PdfObjectReference)
-	if isRef {
-		return io, errors.New("multi depth trace pointer to pointer")
+	o = ind.PdfObject
+	if _, isRef := o.(*PdfObjectReference); isRef {
+		return ind, errors.New("multi depth trace pointer to pointer")
 	}
-
 	return o, nil
 }
 
+// resolveConcurrent resolves `obj` like Resolve, for a parser created by lookupConcurrent resolving
+// the stream Length references `lengthLookups`.
+func (parser *PdfParser) resolveConcurrent(obj PdfObject, lengthLookups map[int64]bool) (PdfObject, error) {
+	ref, isRef := obj.(*PdfObjectReference)
+	if !isRef {
+		return obj, nil
+	}
+	if o, _, ok := parser.lookupConcurrent(int(ref.ObjectNumber), lengthLookups); ok {
+		return derefIndirect(o)
+	}
+
+	parser.lock.Lock()
+	defer parser.lock.Unlock()
+	return parser.resolve(obj)
+}
+
+// lookupConcurrent looks up the object `objNumber` without holding the lock while parsing it, when
+// the underlying reader implements io.ReaderAt, so that objects can be parsed concurrently. The
+// object is parsed from its own section of the file, the lock only guarding the cross-reference
+// table, the object cache and the decryption. `lengthLookups` are the stream Length references being
+// resolved by the calling parsers, if any.
+// Returns false if the object has to be looked up with the lock held: objects contained in object
+// streams and objects which cannot be parsed (e.g. damaged cross-reference tables, needing repairs).
+// Also returns true if the object was cached.
+func (parser *PdfParser) lookupConcurrent(objNumber int, lengthLookups map[int64]bool) (PdfObject, bool, bool) {
+	ra, isReaderAt := parser.rs.(io.ReaderAt)
+	if !isReaderAt || parser.fileSize <= 0 {
+		return nil, false, false
+	}
+
+	parser.lock.Lock()
+	if obj, cached := parser.cachedObject(objNumber); cached {
+		parser.lock.Unlock()
+		return obj, true, true
+	}
+	xref, has := parser.xrefs.ObjectMap[objNumber]
+	parser.lock.Unlock()
+	if !has || xref.XType != XrefTypeTableEntry {
+		return nil, false, false
+	}
+
+	inProgress := make(map[int64]bool, len(lengthLookups))
+	for num, ok := range lengthLookups {
+		inProgress[num] = ok
+	}
+	rs := io.NewSectionReader(ra, 0, parser.fileSize)
+	if _, err := rs.Seek(xref.Offset, io.SeekStart); err != nil {
+		return nil, false, false
+	}
+	section := &PdfParser{
+		version:                               parser.version,
+		rs:                                    rs,
+		reader:                                bufio.NewReader(rs),
+		fileSize:                              parser.fileSize,
+		logger:                                parser.logger,
+		ObjCache:                              objectCache{},
+		parent:                                parser,
+		streamLengthReferenceLookupInProgress: inProgress,
+	}
+	obj, err := section.ParseIndirectObject()
+	if err != nil {
+		return nil, false, false
+	}
+	if realObjNum, _, _ := getObjectNumber(obj); int(realObjNum) != objNumber {
+		return nil, false, false
+	}
+
+	parser.lock.Lock()
+	defer parser.lock.Unlock()
+	if cachedObj, cached := parser.cachedObject(objNumber); cached {
+		// Looked up concurrently, keep a single instance.
+		return cachedObj, true, true
+	}
+	if cur, has := parser.xrefs.ObjectMap[objNumber]; !has || cur != xref {
+		// Cross-reference table repaired concurrently.
+		return nil, false, false
+	}
+	if parser.crypter != nil && !parser.crypter.isDecrypted(obj) {
+		if err := parser.crypter.Decrypt(obj, 0, 0); err != nil {
+			return nil, false, false
+		}
+	}
+	parser.repairs = append(parser.repairs, section.repairs...)
+	parser.cacheObject(objNumber, obj)
+	parser.evictObjects()
+	return obj, false, true
+}
+
 func printXrefTable(xrefTable XrefTable) {
 	common.Log.Debug("=X=X=X=")
 	common.Log.Debug("Xref table:")
@@ -28,8 +28,31 @@ type EncryptInfo struct {
 	ID0, ID1 string
 }
 
+// CryptOptions selects which parts of a document encrypted with crypt filters (V>=4) are left
+// in clear text, with the Identity crypt filter.
+type CryptOptions struct {
+	// UnencryptedStreams selects the Identity filter as the default stream filter (StmF).
+	UnencryptedStreams bool
+	// UnencryptedStrings selects the Identity filter as the string filter (StrF).
+	UnencryptedStrings bool
+	// UnencryptedEmbeddedFiles selects the Identity filter as the embedded file filter (EFF).
+	UnencryptedEmbeddedFiles bool
+	// UnencryptedMetadata leaves the document-level metadata stream unencrypted (EncryptMetadata).
+	UnencryptedMetadata bool
+}
+
 // PdfCryptNewEncrypt makes the document crypt handler based on a specified crypt filter.
 func PdfCryptNewEncrypt(cf crypto.Filter, userPass, ownerPass []byte, perm security.Permissions) (*PdfCrypt, *EncryptInfo, error) {
+	return PdfCryptNewEncryptWithOptions(cf, userPass, ownerPass, perm, nil)
+}
+
+// PdfCryptNewEncryptWithOptions makes the document crypt handler based on a specified crypt
+// filter, with the crypt filters of streams, strings and embedded files selected by `opts`.
+// The options only apply to crypt filters with V>=4 (AESV2, AESV3) and are ignored otherwise.
+func PdfCryptNewEncryptWithOptions(cf crypto.Filter, userPass, ownerPass []byte, perm security.Permissions, opts *CryptOptions) (*PdfCrypt, *EncryptInfo, error) {
+	if opts == nil {
+		opts = &CryptOptions{}
+	}
 	crypter := &PdfCrypt{
 		encryptedObjects: make(map[PdfObject]bool),
 		cryptFilters:     make(cryptFilters),
@@ -54,8 +77,17 @@ func PdfCryptNewEncrypt(cf crypto.Filter, userPass, ownerPass []byte, perm secur
 	)
 	crypter.cryptFilters[defaultFilter] = cf
 	if crypter.encrypt.V >= 4 {
-		crypter.streamFilter = defaultFilter
-		crypter.stringFilter = defaultFilter
+		crypter.cryptFilters["Identity"] = crypto.NewIdentity()
+		filterName := func(identity bool) string {
+			if identity {
+				return "Identity"
+			}
+			return defaultFilter
+		}
+		crypter.streamFilter = filterName(opts.UnencryptedStreams)
+		crypter.stringFilter = filterName(opts.UnencryptedStrings)
+		crypter.embeddedFileFilter = filterName(opts.UnencryptedEmbeddedFiles)
+		crypter.encryptStd.EncryptMetadata = !opts.UnencryptedMetadata
 	}
 	ed := crypter.newEncryptDict()
 
@@ -97,17 +129,26 @@ type PdfCrypt struct {
 	encrypt    encryptDict
 	encryptStd security.StdEncryptDict
 
+	// Public-key security handler parameters (Filter Adobe.PubSec) and permissions granted to the
+	// authenticated recipient.
+	encryptPubKey security.PubKeyEncryptDict
+	pubKeyPerms   security.Permissions
+
 	id0              string
 	encryptionKey    []byte
 	decryptedObjects map[PdfObject]bool
 	encryptedObjects map[PdfObject]bool
 	authenticated    bool
 	// Crypt filters (V4).
-	cryptFilters cryptFilters
-	streamFilter string
-	stringFilter string
+	cryptFilters       cryptFilters
+	streamFilter       string
+	stringFilter       string
+	embeddedFileFilter string
 
 	parser *PdfParser
+	// Document-level metadata stream, set when encrypting. Located through the catalog of the parser
+	// when decrypting.
+	metadata *PdfObjectStream
 
 	decryptedObjNum map[int]struct{}
 }
@@ -119,6 +160,10 @@ func encodeEncryptStd(d *security.StdEncryptDict, ed *PdfObjectDictionary) {
 
 	ed.Set("O", MakeStringFromBytes(d.O))
 	ed.Set("U", MakeStringFromBytes(d.U))
+	if d.R == 4 && !d.EncryptMetadata {
+		// Only stored when false, which changes the file encryption key.
+		ed.Set("EncryptMetadata", MakeBool(false))
+	}
 	if d.R >= 5 {
 		ed.Set("OE", MakeStringFromBytes(d.OE))
 		ed.Set("UE", MakeStringFromBytes(d.UE))
@@ -246,7 +291,7 @@ func decodeCryptFilter(cf *crypto.FilterDict, d *PdfObjectDictionary) error {
 func (crypt *PdfCrypt) newEncryptDict() *PdfObjectDictionary {
 	// Generate the encryption dictionary.
 	ed := MakeDict()
-	ed.Set("Filter", MakeName("Standard"))
+	ed.Set("Filter", MakeName(stdSecurityHandler))
 	ed.Set("V", MakeInteger(int64(crypt.encrypt.V)))
 	ed.Set("Length", MakeInteger(int64(crypt.encrypt.Length)))
 	return ed
@@ -383,6 +428,15 @@ func (crypt *PdfCrypt) loadCryptFilters(ed *PdfObjectDictionary) error {
 		crypt.streamFilter = string(*stmf)
 	}
 
+	// EFF embedded files filter, defaults to the streams filter.
+	crypt.embeddedFileFilter = crypt.streamFilter
+	if eff, ok := ed.Get("EFF").(*PdfObjectName); ok {
+		if _, exists := crypt.cryptFilters[string(*eff)]; !exists {
+			return fmt.Errorf("crypt filter for EFF not specified in CF dictionary (%s)", *eff)
+		}
+		crypt.embeddedFileFilter = string(*eff)
+	}
+
 	return nil
 }
 
@@ -415,6 +469,9 @@ func (crypt *PdfCrypt) saveCryptFilters(ed *PdfObjectDictionary) error {
 	}
 	ed.Set("StrF", MakeName(crypt.stringFilter))
 	ed.Set("StmF", MakeName(crypt.streamFilter))
+	if crypt.embeddedFileFilter != "" && crypt.embeddedFileFilter != crypt.streamFilter {
+		ed.Set("EFF", MakeName(crypt.embeddedFileFilter))
+	}
 	return nil
 }
 
@@ -434,13 +491,17 @@ func PdfCryptNewDecrypt(parser *PdfParser, ed, trailer *PdfObjectDictionary) (*P
 		common.Log.Debug("ERROR Crypt dictionary missing required Filter field!")
 		return crypter, errors.New("required crypt field Filter missing")
 	}
-	if *filter != "Standard" {
+	if *filter != stdSecurityHandler && *filter != pubKeySecurityHandler {
 		common.Log.Debug("ERROR Unsupported filter (%s)", *filter)
 		return crypter, errors.New("unsupported Filter")
 	}
 	crypter.encrypt.Filter = string(*filter)
 
-	if subfilter, ok := ed.Get("SubFilter").(*PdfObjectString); ok {
+	switch subfilter := ed.Get("SubFilter").(type) {
+	case *PdfObjectName:
+		crypter.encrypt.SubFilter = string(*subfilter)
+		common.Log.Debug("Using subfilter %s", subfilter)
+	case *PdfObjectString:
 		crypter.encrypt.SubFilter = subfilter.Str()
 		common.Log.Debug("Using subfilter %s", subfilter)
 	}
@@ -472,6 +533,14 @@ func PdfCryptNewDecrypt(parser *PdfParser, ed, trailer *PdfObjectDictionary) (*P
 		}
 	}
 
+	if crypter.isPubKey() {
+		// decode public-key security handler parameters
+		if err := crypter.decodeEncryptPubKey(ed); err != nil {
+			return crypter, err
+		}
+		return crypter, nil
+	}
+
 	// decode Standard security handler parameters
 	if err := decodeEncryptStd(&crypter.encryptStd, ed); err != nil {
 		return crypter, err
@@ -497,6 +566,9 @@ func PdfCryptNewDecrypt(parser *PdfParser, ed, trailer *PdfObjectDictionary) (*P
 
 // GetAccessPermissions returns the PDF access permissions as an AccessPermissions object.
 func (crypt *PdfCrypt) GetAccessPermissions() security.Permissions {
+	if crypt.isPubKey() {
+		return crypt.pubKeyPerms
+	}
 	return crypt.encryptStd.P
 }
 
@@ -511,6 +583,9 @@ func (crypt *PdfCrypt) securityHandler() security.StdHandler {
 // Also build the encryption/decryption key.
 func (crypt *PdfCrypt) authenticate(password []byte) (bool, error) {
 	crypt.authenticated = false
+	if crypt.isPubKey() {
+		return false, errPubKeyPassword
+	}
 	h := crypt.securityHandler()
 	fkey, perm, err := h.Authenticate(&crypt.encryptStd, password)
 	if err != nil {
@@ -530,6 +605,9 @@ func (crypt *PdfCrypt) authenticate(password []byte) (bool, error) {
 // The AccessPermissions shows what access the user has for editing etc.
 // An error is returned if there was a problem performing the authentication.
 func (crypt *PdfCrypt) checkAccessRights(password []byte) (bool, security.Permissions, error) {
+	if crypt.isPubKey() {
+		return false, 0, errPubKeyPassword
+	}
 	h := crypt.securityHandler()
 	// TODO(dennwc): it computes an encryption key as well; if necessary, define a new interface method to optimize this
 	fkey, perm, err := h.Authenticate(&crypt.encryptStd, password)
@@ -596,6 +674,96 @@ func (crypt *PdfCrypt) isDecrypted(obj PdfObject) bool {
 	return false
 }
 
+// streamCryptFilter returns the name of the crypt filter of `stream`: the crypt filter named by a
+// Crypt filter of the stream, the EFF filter for embedded files, the Identity filter for the
+// document-level metadata when EncryptMetadata is false, and the StmF filter otherwise
+// (7.6.6 Crypt Filters).
+func (crypt *PdfCrypt) streamCryptFilter(stream *PdfObjectStream) string {
+	dict := stream.PdfObjectDictionary
+	if crypt.encrypt.V < 4 {
+		return stdCryptFilter // RC4.
+	}
+
+	// The Crypt filter shall be the first filter in the Filter array entry.
+	filter := crypt.resolveDirect(dict.Get("Filter"))
+	decodeParams := crypt.resolveDirect(dict.Get("DecodeParms"))
+	if filters, ok := filter.(*PdfObjectArray); ok {
+		filter = crypt.resolveDirect(filters.Get(0))
+		if params, ok := decodeParams.(*PdfObjectArray); ok {
+			decodeParams = crypt.resolveDirect(params.Get(0))
+		}
+	}
+	if name, ok := filter.(*PdfObjectName); ok && *name == StreamEncodingFilterNameCrypt {
+		// Crypt filter overriding the default. Default option is Identity.
+		if params, ok := decodeParams.(*PdfObjectDictionary); ok {
+			if filterName, ok := crypt.resolveDirect(params.Get("Name")).(*PdfObjectName); ok {
+				if _, ok := crypt.cryptFilters[string(*filterName)]; ok {
+					common.Log.Trace("Using stream filter %s", *filterName)
+					return string(*filterName)
+				}
+				common.Log.Debug("ERROR: Crypt filter %s not specified in CF dictionary - using Identity", *filterName)
+			}
+		}
+		return "Identity"
+	}
+
+	if typ, ok := dict.Get("Type").(*PdfObjectName); ok {
+		switch *typ {
+		case "EmbeddedFile":
+			if crypt.embeddedFileFilter != "" {
+				return crypt.embeddedFileFilter
+			}
+		case "Metadata":
+			if !crypt.encryptMetadata() && crypt.isDocumentMetadata(stream) {
+				return "Identity"
+			}
+		}
+	}
+	return crypt.streamFilter
+}
+
+// SetDocumentMetadata sets the document-level metadata stream `stream`, referenced by the Metadata
+// entry of the catalog, which is left unencrypted when EncryptMetadata is false. Other metadata
+// streams, e.g. of pages, are encrypted. When decrypting, the metadata stream is located through
+// the catalog of the parser.
+func (crypt *PdfCrypt) SetDocumentMetadata(stream *PdfObjectStream) {
+	crypt.metadata = stream
+}
+
+// isDocumentMetadata returns true if `stream` is the document-level metadata stream.
+func (crypt *PdfCrypt) isDocumentMetadata(stream *PdfObjectStream) bool {
+	if crypt.metadata != nil {
+		return stream == crypt.metadata
+	}
+	if crypt.parser == nil || crypt.parser.trailer == nil {
+		return false
+	}
+	catalog, ok := crypt.resolveDirect(crypt.parser.trailer.Get("Root")).(*PdfObjectDictionary)
+	if !ok {
+		return false
+	}
+	ref, ok := catalog.Get("Metadata").(*PdfObjectReference)
+	return ok && ref.ObjectNumber == stream.ObjectNumber
+}
+
+// resolveDirect returns the direct object of `obj`, resolving references with the parser if
+// available.
+func (crypt *PdfCrypt) resolveDirect(obj PdfObject) PdfObject {
+	if ref, ok := obj.(*PdfObjectReference); ok && crypt.parser != nil {
+		// Objects are decrypted while being looked up, resolve without locking the parser.
+		obj, _ = crypt.parser.resolve(ref)
+	}
+	return TraceToDirectObject(obj)
+}
+
+// encryptMetadata returns true if the document-level metadata stream is encrypted.
+func (crypt *PdfCrypt) encryptMetadata() bool {
+	if crypt.isPubKey() {
+		return crypt.encryptPubKey.EncryptMetadata
+	}
+	return crypt.encryptStd.EncryptMetadata
+}
+
 // Decrypt a buffer with a selected crypt filter.
 func (crypt *PdfCrypt) decryptBytes(buf []byte, filter string, okey []byte) ([]byte, error) {
 	common.Log.Trace("Decrypt bytes")
@@ -646,47 +814,20 @@ func (crypt *PdfCrypt) Decrypt(obj PdfObject, parentObjNum, parentGenNum int64)
 		genNum := obj.GenerationNumber
 		common.Log.Trace("Decrypting stream %d %d !", objNum, genNum)
 
-		// TODO: Check for crypt filter (V4).
-		// The Crypt filter shall be the first filter in the Filter array entry.
-
-		streamFilter := stdCryptFilter // Default RC4.
-		if crypt.encrypt.V >= 4 {
-			streamFilter = crypt.streamFilter
-			common.Log.Trace("this.streamFilter = %s", crypt.streamFilter)
-
-			if filters, ok := dict.Get("Filter").(*PdfObjectArray); ok {
-				// Crypt filter can only be the first entry.
-				if firstFilter, ok := GetName(filters.Get(0)); ok {
-					if *firstFilter == "Crypt" {
-						// Crypt filter overriding the default.
-						// Default option is Identity.
-						streamFilter = "Identity"
-
-						// Check if valid crypt filter specified in the decode params.
-						if decodeParams, ok := dict.Get("DecodeParms").(*PdfObjectDictionary); ok {
-							if filterName, ok := decodeParams.Get("Name").(*PdfObjectName); ok {
-								if _, ok := crypt.cryptFilters[string(*filterName)]; ok {
-									common.Log.Trace("Using stream filter %s", *filterName)
-									streamFilter = string(*filterName)
-								}
-							}
-						}
-					}
-				}
-			}
-
-			common.Log.Trace("with %s filter", streamFilter)
-			if streamFilter == "Identity" {
-				// Identity: pass unchanged.
-				return nil
-			}
-		}
-
+		// Strings of the stream dictionary are encrypted with the string filter, whatever the
+		// crypt filter of the stream data.
 		err := crypt.Decrypt(dict, objNum, genNum)
 		if err != nil {
 			return err
 		}
 
+		streamFilter := crypt.streamCryptFilter(obj)
+		common.Log.Trace("with %s filter", streamFilter)
+		if streamFilter == "Identity" {
+			// Identity: pass unchanged.
+			return nil
+		}
+
 		okey, err := crypt.makeKey(streamFilter, uint32(objNum), uint32(genNum), crypt.encryptionKey)
 		if err != nil {
 			return err
@@ -829,49 +970,18 @@ func (crypt *PdfCrypt) Encrypt(obj PdfObject, parentObjNum, parentGenNum int64)
 		genNum := obj.GenerationNumber
 		common.Log.Trace("Encrypting stream %d %d !", objNum, genNum)
 
-		// TODO: Check for crypt filter (V4).
-		// The Crypt filter shall be the first filter in the Filter array entry.
-
-		streamFilter := stdCryptFilter // Default RC4.
-		if crypt.encrypt.V >= 4 {
-			// For now.  Need to change when we add support for more than
-			// Identity / RC4.
-			streamFilter = crypt.streamFilter
-			common.Log.Trace("this.streamFilter = %s", crypt.streamFilter)
-
-			if filters, ok := dict.Get("Filter").(*PdfObjectArray); ok {
-				// Crypt filter can only be the first entry.
-				if firstFilter, ok := GetName(filters.Get(0)); ok {
-					if *firstFilter == "Crypt" {
-						// Crypt filter overriding the default.
-						// Default option is Identity.
-						streamFilter = "Identity"
-
-						// Check if valid crypt filter specified in the decode params.
-						if decodeParams, ok := dict.Get("DecodeParms").(*PdfObjectDictionary); ok {
-							if filterName, ok := decodeParams.Get("Name").(*PdfObjectName); ok {
-								if _, ok := crypt.cryptFilters[string(*filterName)]; ok {
-									common.Log.Trace("Using stream filter %s", *filterName)
-									streamFilter = string(*filterName)
-								}
-							}
-						}
-					}
-				}
-			}
-
-			common.Log.Trace("with %s filter", streamFilter)
-			if streamFilter == "Identity" {
-				// Identity: pass unchanged.
-				return nil
-			}
-		}
-
 		err := crypt.Encrypt(obj.PdfObjectDictionary, objNum, genNum)
 		if err != nil {
 			return err
 		}
 
+		streamFilter := crypt.streamCryptFilter(obj)
+		common.Log.Trace("with %s filter", streamFilter)
+		if streamFilter == "Identity" {
+			// Identity: pass unchanged.
+			return nil
+		}
+
 		okey, err := crypt.makeKey(streamFilter, uint32(objNum), uint32(genNum), crypt.encryptionKey)
 		if err != nil {
 			return err
@@ -0,0 +1,235 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"crypto"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core/security"
+	"github.com/unidoc/unipdf/v3/core/security/crypt"
+)
+
+// Names of the supported security handlers.
+const (
+	stdSecurityHandler    = "Standard"
+	pubKeySecurityHandler = "Adobe.PubSec"
+)
+
+// pubKeyCryptFilter is the name of the crypt filter used for public-key encryption (adbe.pkcs7.s5).
+const pubKeyCryptFilter = "DefaultCryptFilter"
+
+var errPubKeyPassword = errors.New("document is encrypted for certificates and cannot be opened with a password")
+
+// PdfCryptNewEncryptPubKey makes the document crypt handler for encrypting the document for the
+// certificates of `recipients` with the public-key security handler (7.6.5 Public-Key Security Handlers),
+// using the `subFilter` format of the encryption dictionary and the crypt filter `cf`.
+// The adbe.pkcs7.s3 and adbe.pkcs7.s4 formats can only be used with the RC4 (V2) crypt filter.
+func PdfCryptNewEncryptPubKey(cf crypt.Filter, subFilter string, recipients []security.PubKeyRecipient) (*PdfCrypt, *EncryptInfo, error) {
+	if cf == nil {
+		return nil, nil, errors.New("crypt filter required")
+	}
+	crypter := &PdfCrypt{
+		encryptedObjects: make(map[PdfObject]bool),
+		cryptFilters:     make(cryptFilters),
+		encryptPubKey: security.PubKeyEncryptDict{
+			EncryptMetadata: true,
+		},
+		pubKeyPerms:   security.PermOwner,
+		authenticated: true,
+	}
+	crypter.encrypt.Filter = pubKeySecurityHandler
+	crypter.encrypt.SubFilter = subFilter
+	crypter.encrypt.Length = cf.KeyLength() * 8
+
+	var vers Version
+	v := cf.PDFVersion()
+	vers.Major, vers.Minor = v[0], v[1]
+	switch subFilter {
+	case security.SubFilterPKCS7S3, security.SubFilterPKCS7S4:
+		if cf.Name() != "V2" {
+			return nil, nil, fmt.Errorf("%s requires RC4 encryption", subFilter)
+		}
+		crypter.encrypt.V = 2
+		crypter.cryptFilters[stdCryptFilter] = cf
+	case security.SubFilterPKCS7S5:
+		crypter.encrypt.V = 4
+		if V, _ := cf.HandlerVersion(); V == 5 {
+			crypter.encrypt.V = 5
+		}
+		crypter.cryptFilters[pubKeyCryptFilter] = cf
+		crypter.streamFilter = pubKeyCryptFilter
+		crypter.stringFilter = pubKeyCryptFilter
+		crypter.embeddedFileFilter = pubKeyCryptFilter
+		if vers.Major == 1 && vers.Minor < 6 {
+			vers.Minor = 6
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported public-key subfilter: %q", subFilter)
+	}
+
+	ekey, err := crypter.pubKeyHandler().GenerateParams(&crypter.encryptPubKey, recipients)
+	if err != nil {
+		return nil, nil, err
+	}
+	crypter.encryptionKey = ekey
+
+	recipientsArr := MakeArray()
+	for _, recipient := range crypter.encryptPubKey.Recipients {
+		recipientsArr.Append(MakeHexString(string(recipient)))
+	}
+
+	ed := MakeDict()
+	ed.Set("Filter", MakeName(pubKeySecurityHandler))
+	ed.Set("SubFilter", MakeName(subFilter))
+	ed.Set("V", MakeInteger(int64(crypter.encrypt.V)))
+	ed.Set("Length", MakeInteger(int64(crypter.encrypt.Length)))
+	if crypter.encrypt.V >= 4 {
+		// The public-key security handler expresses the crypt filter length in bits.
+		filter := encodeCryptFilter(cf, "")
+		filter.Set("Length", MakeInteger(int64(crypter.encrypt.Length)))
+		filter.Set("Recipients", recipientsArr)
+		filter.Set("EncryptMetadata", MakeBool(crypter.encryptPubKey.EncryptMetadata))
+		cfDict := MakeDict()
+		cfDict.Set(pubKeyCryptFilter, filter)
+		ed.Set("CF", cfDict)
+		ed.Set("StmF", MakeName(crypter.streamFilter))
+		ed.Set("StrF", MakeName(crypter.stringFilter))
+	} else {
+		ed.Set("Recipients", recipientsArr)
+	}
+
+	// Prepare the ID object for the trailer.
+	hashcode := md5.Sum([]byte(time.Now().Format(time.RFC850)))
+	id0 := string(hashcode[:])
+	b := make([]byte, 100)
+	rand.Read(b)
+	hashcode = md5.Sum(b)
+	id1 := string(hashcode[:])
+	crypter.id0 = id0
+
+	return crypter, &EncryptInfo{
+		Version: vers,
+		Encrypt: ed,
+		ID0:     id0, ID1: id1,
+	}, nil
+}
+
+// isPubKey returns true if the document is encrypted with the public-key security handler.
+func (crypt *PdfCrypt) isPubKey() bool {
+	return crypt.encrypt.Filter == pubKeySecurityHandler
+}
+
+// pubKeyHandler returns the public-key security handler for the file encryption key length.
+func (crypt *PdfCrypt) pubKeyHandler() security.PubKeyHandler {
+	length := crypt.encrypt.Length / 8
+	if crypt.encrypt.V >= 4 {
+		filter := crypt.streamFilter
+		if filter == "Identity" {
+			filter = crypt.stringFilter
+		}
+		if cf, ok := crypt.cryptFilters[filter]; ok && cf.KeyLength() > 0 {
+			length = cf.KeyLength()
+		}
+	}
+	return security.NewPubKeyHandler(length)
+}
+
+// decodeEncryptPubKey decodes the parameters of the public-key security handler from the encryption
+// dictionary `ed`. The recipients are contained in the encryption dictionary (adbe.pkcs7.s3 and
+// adbe.pkcs7.s4), or in the crypt filter dictionary of the default stream filter (adbe.pkcs7.s5).
+func (crypt *PdfCrypt) decodeEncryptPubKey(ed *PdfObjectDictionary) error {
+	d := &crypt.encryptPubKey
+	d.EncryptMetadata = true
+
+	recipients := ed.Get("Recipients")
+	if crypt.encrypt.V >= 4 {
+		filter := crypt.streamFilter
+		if filter == "Identity" {
+			filter = crypt.stringFilter
+		}
+		if cfDict, ok := GetDict(crypt.resolve(ed.Get("CF"))); ok {
+			if fdict, ok := GetDict(crypt.resolve(cfDict.Get(PdfObjectName(filter)))); ok {
+				recipients = fdict.Get("Recipients")
+				if em, ok := GetBoolVal(fdict.Get("EncryptMetadata")); ok {
+					d.EncryptMetadata = em
+				}
+			}
+		}
+	}
+
+	var objs []PdfObject
+	switch t := crypt.resolve(recipients).(type) {
+	case *PdfObjectString:
+		objs = append(objs, t)
+	case *PdfObjectArray:
+		objs = t.Elements()
+	default:
+		return errors.New("encrypt dictionary missing Recipients")
+	}
+	d.Recipients = nil
+	for _, obj := range objs {
+		str, ok := GetString(crypt.resolve(obj))
+		if !ok {
+			return errors.New("invalid Recipients entry")
+		}
+		d.Recipients = append(d.Recipients, str.Bytes())
+	}
+	if len(d.Recipients) == 0 {
+		return errors.New("encrypt dictionary missing Recipients")
+	}
+	return nil
+}
+
+// resolve resolves `obj` if it is a reference. The encryption dictionary is loaded prior to
+// decryption and its objects are not decrypted.
+func (crypt *PdfCrypt) resolve(obj PdfObject) PdfObject {
+	if ref, ok := obj.(*PdfObjectReference); ok && crypt.parser != nil {
+		resolved, err := crypt.parser.LookupByReference(*ref)
+		if err != nil {
+			common.Log.Debug("ERROR: Failed to resolve %s: %v", ref, err)
+			return nil
+		}
+		return TraceToDirectObject(resolved)
+	}
+	return obj
+}
+
+// authenticatePubKey checks whether the document is encrypted for the certificate `cert` and opens it
+// with the private key `pkey`. Also builds the encryption key and sets the granted permissions.
+func (crypt *PdfCrypt) authenticatePubKey(cert *x509.Certificate, pkey crypto.PrivateKey) (bool, error) {
+	crypt.authenticated = false
+	if !crypt.isPubKey() {
+		return false, errors.New("document is not encrypted for certificates")
+	}
+	fkey, perm, err := crypt.pubKeyHandler().Authenticate(&crypt.encryptPubKey, cert, pkey)
+	if err != nil {
+		return false, err
+	} else if len(fkey) == 0 {
+		return false, nil
+	}
+	common.Log.Trace("Authenticated with certificate %s", cert.Subject)
+	crypt.authenticated = true
+	crypt.encryptionKey = fkey
+	crypt.pubKeyPerms = perm
+	return true, nil
+}
+
+// DecryptWithCertificate attempts to decrypt a PDF file encrypted with the public-key security handler,
+// using the recipient certificate `cert` and its private key `pkey`. Returns true if successful,
+// false if the document is not encrypted for the certificate.
+func (parser *PdfParser) DecryptWithCertificate(cert *x509.Certificate, pkey crypto.PrivateKey) (bool, error) {
+	if parser.crypter == nil {
+		return false, errors.New("check encryption first")
+	}
+	return parser.crypter.authenticatePubKey(cert, pkey)
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/unidoc/unipdf/v3/common"
 	"github.com/unidoc/unipdf/v3/core/security"
+	"github.com/unidoc/unipdf/v3/core/security/crypt"
 )
 
 func init() {
@@ -95,3 +96,158 @@ func TestDecryption1(t *testing.T) {
 		return
 	}
 }
+
+// Test the selection of the crypt filters of streams (StmF, EFF, Crypt filter and EncryptMetadata).
+func TestCryptFilterSelection(t *testing.T) {
+	opts := &CryptOptions{
+		UnencryptedStreams:  true,
+		UnencryptedMetadata: true,
+	}
+	crypter, info, err := PdfCryptNewEncryptWithOptions(crypt.NewFilterAESV2(), []byte("user"), []byte("owner"), security.PermOwner, opts)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	for key, exp := range map[PdfObjectName]string{"StmF": "Identity", "StrF": stdCryptFilter, "EFF": stdCryptFilter} {
+		if name, ok := GetName(info.Encrypt.Get(key)); !ok || string(*name) != exp {
+			t.Fatalf("%s: expected %s, got %v", key, exp, info.Encrypt.Get(key))
+		}
+	}
+	if em, ok := GetBoolVal(info.Encrypt.Get("EncryptMetadata")); !ok || em {
+		t.Fatalf("EncryptMetadata: expected false, got %v", info.Encrypt.Get("EncryptMetadata"))
+	}
+
+	const content = "BT /F1 12 Tf (Hello) Tj ET"
+	makeStream := func(num int64, entries ...PdfObject) *PdfObjectStream {
+		dict := MakeDict()
+		for i := 0; i+1 < len(entries); i += 2 {
+			dict.Set(*entries[i].(*PdfObjectName), entries[i+1])
+		}
+		dict.Set("Title", MakeString("title"))
+		stream := &PdfObjectStream{PdfObjectDictionary: dict, Stream: []byte(content)}
+		stream.ObjectNumber = num
+		return stream
+	}
+	cases := []struct {
+		stream    *PdfObjectStream
+		encrypted bool
+	}{
+		{makeStream(1), false},
+		{makeStream(2, MakeName("Type"), MakeName("Metadata"), MakeName("Subtype"), MakeName("XML")), false},
+		{makeStream(3, MakeName("Type"), MakeName("EmbeddedFile")), true},
+		{makeStream(4, MakeName("Filter"), MakeName("Crypt"),
+			MakeName("DecodeParms"), NewCryptEncoder(stdCryptFilter).MakeDecodeParams()), true},
+		{makeStream(5, MakeName("Type"), MakeName("EmbeddedFile"), MakeName("Filter"), MakeArray(MakeName("Crypt"))), false},
+	}
+	crypter.SetDocumentMetadata(cases[1].stream)
+	for i, c := range cases {
+		if err := crypter.Encrypt(c.stream, 0, 0); err != nil {
+			t.Fatalf("Case %d: encrypt error: %v", i, err)
+		}
+		if (string(c.stream.Stream) != content) != c.encrypted {
+			t.Fatalf("Case %d: stream encrypted: expected %v", i, c.encrypted)
+		}
+		if title, _ := GetStringVal(c.stream.Get("Title")); title == "title" {
+			t.Fatalf("Case %d: stream dictionary string not encrypted", i)
+		}
+	}
+
+	// Decrypt with a handler loaded from the encryption dictionary.
+	trailer := MakeDict()
+	trailer.Set("ID", MakeArray(MakeString(info.ID0), MakeString(info.ID1)))
+	// The document metadata stream is located through the catalog.
+	catalog := MakeIndirectObject(MakeDict())
+	catalog.ObjectNumber = 10
+	catalog.PdfObject.(*PdfObjectDictionary).Set("Metadata", &PdfObjectReference{ObjectNumber: 2})
+	trailer.Set("Root", &PdfObjectReference{ObjectNumber: 10})
+	parser := NewParserFromString("")
+	parser.ObjCache[10] = catalog
+	parser.trailer = trailer
+	decrypter, err := PdfCryptNewDecrypt(parser, info.Encrypt, trailer)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if ok, err := decrypter.authenticate([]byte("user")); err != nil || !ok {
+		t.Fatalf("Authentication failed: %v", err)
+	}
+	for i, c := range cases {
+		if err := decrypter.Decrypt(c.stream, 0, 0); err != nil {
+			t.Fatalf("Case %d: decrypt error: %v", i, err)
+		}
+		if string(c.stream.Stream) != content {
+			t.Fatalf("Case %d: wrong decrypted stream: %q", i, c.stream.Stream)
+		}
+		if title, _ := GetStringVal(c.stream.Get("Title")); title != "title" {
+			t.Fatalf("Case %d: wrong decrypted string: %q", i, title)
+		}
+	}
+
+	// Only the metadata stream of the catalog is left unencrypted, not the metadata of pages.
+	crypter, info, err = PdfCryptNewEncryptWithOptions(crypt.NewFilterAESV2(), []byte("user"), []byte("owner"),
+		security.PermOwner, &CryptOptions{UnencryptedMetadata: true})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	docMetadata := makeStream(2, MakeName("Type"), MakeName("Metadata"), MakeName("Subtype"), MakeName("XML"))
+	pageMetadata := makeStream(6, MakeName("Type"), MakeName("Metadata"), MakeName("Subtype"), MakeName("XML"))
+	crypter.SetDocumentMetadata(docMetadata)
+	for _, stream := range []*PdfObjectStream{docMetadata, pageMetadata} {
+		if err := crypter.Encrypt(stream, 0, 0); err != nil {
+			t.Fatalf("Encrypt error: %v", err)
+		}
+	}
+	if string(docMetadata.Stream) != content || string(pageMetadata.Stream) == content {
+		t.Fatalf("Wrong metadata encryption: document %q, page %q", docMetadata.Stream, pageMetadata.Stream)
+	}
+
+	trailer.Set("ID", MakeArray(MakeString(info.ID0), MakeString(info.ID1)))
+	decrypter, err = PdfCryptNewDecrypt(parser, info.Encrypt, trailer)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if ok, err := decrypter.authenticate([]byte("user")); err != nil || !ok {
+		t.Fatalf("Authentication failed: %v", err)
+	}
+	for _, stream := range []*PdfObjectStream{docMetadata, pageMetadata} {
+		if err := decrypter.Decrypt(stream, 0, 0); err != nil {
+			t.Fatalf("Decrypt error: %v", err)
+		}
+		if string(stream.Stream) != content {
+			t.Fatalf("Wrong decrypted metadata %d: %q", stream.ObjectNumber, stream.Stream)
+		}
+	}
+}
+
+// Test decoding streams with a Crypt filter.
+func TestCryptEncoder(t *testing.T) {
+	data := []byte("stream data with a Crypt filter")
+	flate := NewFlateEncoder()
+	encoded, err := flate.EncodeBytes(data)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	stream := &PdfObjectStream{PdfObjectDictionary: MakeDict(), Stream: encoded}
+	stream.Set("Filter", MakeArray(MakeName(StreamEncodingFilterNameCrypt), MakeName(StreamEncodingFilterNameFlate)))
+	stream.Set("DecodeParms", MakeArray(NewCryptEncoder("StdCF").MakeDecodeParams(), MakeNull()))
+	decoded, err := DecodeStream(stream)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if string(decoded) != string(data) {
+		t.Fatalf("Wrong decoded data: %q", decoded)
+	}
+
+	stream = &PdfObjectStream{PdfObjectDictionary: NewCryptEncoder("StdCF").MakeStreamDict(), Stream: data}
+	enc, err := NewEncoderFromStream(stream)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	cryptEnc, ok := enc.(*CryptEncoder)
+	if !ok || cryptEnc.Name != "StdCF" {
+		t.Fatalf("Wrong encoder: %#v", enc)
+	}
+	decoded, err = enc.DecodeStream(stream)
+	if err != nil || string(decoded) != string(data) {
+		t.Fatalf("Wrong decoded data: %q (%v)", decoded, err)
+	}
+}
@@ -15,7 +15,7 @@ package core
 // - ASCII85
 // - CCITT Fax (dummy)
 // - JBIG2 (dummy)
-// - JPX (dummy)
+// - JPX (decoding only)
 
 import (
 	"bytes"
@@ -49,6 +49,7 @@ const (
 	StreamEncodingFilterNameCCITTFax  = "CCITTFaxDecode"
 	StreamEncodingFilterNameJBIG2     = "JBIG2Decode"
 	StreamEncodingFilterNameJPX       = "JPXDecode"
+	StreamEncodingFilterNameCrypt     = "Crypt"
 	StreamEncodingFilterNameRaw       = "Raw"
 )
 
@@ -1684,6 +1685,99 @@ func (enc *RawEncoder) EncodeBytes(data []byte) ([]byte, error) {
 	return data, nil
 }
 
+// CryptEncoder implements the Crypt filter, which selects the crypt filter used to encrypt and
+// decrypt a stream in place of the default stream crypt filter of the document (StmF or EFF).
+// The crypt filter is resolved against the CF dictionary of the encryption dictionary by the
+// security handler when the stream is read or written, so the encoder itself passes the data
+// through unchanged.
+type CryptEncoder struct {
+	// Name is the name of the crypt filter, Identity by default.
+	Name string
+}
+
+// NewCryptEncoder returns a new instance of CryptEncoder which selects the crypt filter `name`.
+func NewCryptEncoder(name string) *CryptEncoder {
+	if name == "" {
+		name = "Identity"
+	}
+	return &CryptEncoder{Name: name}
+}
+
+// newCryptEncoderFromStream creates a new Crypt encoder from a stream object, getting the
+// crypt filter name from the decode parameters.
+func newCryptEncoderFromStream(streamObj *PdfObjectStream, decodeParams *PdfObjectDictionary) *CryptEncoder {
+	encoder := NewCryptEncoder("")
+
+	// If decodeParams not provided, see if we can get from the stream.
+	if decodeParams == nil && streamObj.PdfObjectDictionary != nil {
+		obj := TraceToDirectObject(streamObj.PdfObjectDictionary.Get("DecodeParms"))
+		if arr, ok := obj.(*PdfObjectArray); ok && arr.Len() > 0 {
+			obj = TraceToDirectObject(arr.Get(0))
+		}
+		decodeParams, _ = obj.(*PdfObjectDictionary)
+	}
+	if decodeParams != nil {
+		encoder.UpdateParams(decodeParams)
+	}
+	return encoder
+}
+
+// GetFilterName returns the name of the encoding filter.
+func (enc *CryptEncoder) GetFilterName() string {
+	return StreamEncodingFilterNameCrypt
+}
+
+// MakeDecodeParams makes a new instance of an encoding dictionary based on
+// the current encoder settings.
+func (enc *CryptEncoder) MakeDecodeParams() PdfObject {
+	if enc.Name == "" || enc.Name == "Identity" {
+		return nil
+	}
+	decodeParams := MakeDict()
+	decodeParams.Set("Type", MakeName("CryptFilterDecodeParms"))
+	decodeParams.Set("Name", MakeName(enc.Name))
+	return decodeParams
+}
+
+// MakeStreamDict makes a new instance of an encoding dictionary for a stream object.
+func (enc *CryptEncoder) MakeStreamDict() *PdfObjectDictionary {
+	dict := MakeDict()
+	dict.Set("Filter", MakeName(enc.GetFilterName()))
+	if decodeParams := enc.MakeDecodeParams(); decodeParams != nil {
+		dict.Set("DecodeParms", decodeParams)
+	}
+	return dict
+}
+
+// UpdateParams updates the parameter values of the encoder.
+func (enc *CryptEncoder) UpdateParams(params *PdfObjectDictionary) {
+	if typ, ok := GetName(params.Get("Type")); ok && *typ != "CryptFilterDecodeParms" {
+		// Not crypt filter parameters, e.g. the dictionary of an image with a Name entry.
+		return
+	}
+	if name, ok := GetName(params.Get("Name")); ok {
+		enc.Name = name.String()
+	}
+}
+
+// DecodeBytes returns the passed in slice of bytes, which are decrypted by the security handler.
+// The purpose of the method is to satisfy the StreamEncoder interface.
+func (enc *CryptEncoder) DecodeBytes(encoded []byte) ([]byte, error) {
+	return encoded, nil
+}
+
+// DecodeStream returns the passed in stream as a slice of bytes.
+// The purpose of the method is to satisfy the StreamEncoder interface.
+func (enc *CryptEncoder) DecodeStream(streamObj *PdfObjectStream) ([]byte, error) {
+	return streamObj.Stream, nil
+}
+
+// EncodeBytes returns the passed in slice of bytes, which are encrypted by the security handler.
+// The purpose of the method is to satisfy the StreamEncoder interface.
+func (enc *CryptEncoder) EncodeBytes(data []byte) ([]byte, error) {
+	return data, nil
+}
+
 // CCITTFaxEncoder implements Group3 and Group4 facsimile (fax) encoder/decoder.
 type CCITTFaxEncoder struct {
 	K                      int
@@ -2004,54 +2098,6 @@ func (enc *CCITTFaxEncoder) EncodeBytes(data []byte) ([]byte, error) {
 	return encoder.Encode(pixels), nil
 }
 
-// JPXEncoder implements JPX encoder/decoder (dummy, for now)
-// FIXME: implement
-type JPXEncoder struct{}
-
-// NewJPXEncoder returns a new instance of JPXEncoder.
-func NewJPXEncoder() *JPXEncoder {
-	return &JPXEncoder{}
-}
-
-// GetFilterName returns the name of the encoding filter.
-func (enc *JPXEncoder) GetFilterName() string {
-	return StreamEncodingFilterNameJPX
-}
-
-// MakeDecodeParams makes a new instance of an encoding dictionary based on
-// the current encoder settings.
-func (enc *JPXEncoder) MakeDecodeParams() PdfObject {
-	return nil
-}
-
-// MakeStreamDict makes a new instance of an encoding dictionary for a stream object.
-func (enc *JPXEncoder) MakeStreamDict() *PdfObjectDictionary {
-	return MakeDict()
-}
-
-// UpdateParams updates the parameter values of the encoder.
-func (enc *JPXEncoder) UpdateParams(params *PdfObjectDictionary) {
-}
-
-// DecodeBytes decodes a slice of JPX encoded bytes and returns the result.
-func (enc *JPXEncoder) DecodeBytes(encoded []byte) ([]byte, error) {
-	common.Log.Debug("Error: Attempting to use unsupported encoding %s", enc.GetFilterName())
-	return encoded, ErrNoJPXDecode
-}
-
-// DecodeStream decodes a JPX encoded stream and returns the result as a
-// slice of bytes.
-func (enc *JPXEncoder) DecodeStream(streamObj *PdfObjectStream) ([]byte, error) {
-	common.Log.Debug("Error: Attempting to use unsupported encoding %s", enc.GetFilterName())
-	return streamObj.Stream, ErrNoJPXDecode
-}
-
-// EncodeBytes JPX encodes the passed in slice of bytes.
-func (enc *JPXEncoder) EncodeBytes(data []byte) ([]byte, error) {
-	common.Log.Debug("Error: Attempting to use unsupported encoding %s", enc.GetFilterName())
-	return data, ErrNoJPXDecode
-}
-
 // MultiEncoder supports serial encoding.
 type MultiEncoder struct {
 	// Encoders in the order that they are to be applied.
@@ -2166,6 +2212,17 @@ func newMultiEncoderFromStream(streamObj *PdfObjectStream) (*MultiEncoder, error
 			mencoder.AddEncoder(encoder)
 			common.Log.Trace("Added DCT encoder...")
 			common.Log.Trace("Multi encoder: %#v", mencoder)
+		} else if *name == StreamEncodingFilterNameJPX {
+			encoder, err := newJPXEncoderFromStream(streamObj, mencoder)
+			if err != nil {
+				return nil, err
+			}
+			mencoder.AddEncoder(encoder)
+		} else if *name == StreamEncodingFilterNameCrypt {
+			if dParams == nil {
+				dParams = MakeDict()
+			}
+			mencoder.AddEncoder(newCryptEncoderFromStream(streamObj, dParams))
 		} else {
 			common.Log.Error("Unsupported filter %s", *name)
 			return nil, fmt.Errorf("invalid filter in multi filter array")
@@ -0,0 +1,324 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"errors"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/internal/jpx"
+)
+
+// JPXEncoder implements the JPXDecode filter, which decodes JPEG 2000 images (raw codestreams,
+// JP2 and JPX files). Encoding is not supported.
+type JPXEncoder struct {
+	ColorComponents  int
+	BitsPerComponent int // 8 or 16 bit
+	Width            int
+	Height           int
+
+	// SMaskInData is the SMaskInData entry of the image dictionary: 0 if the opacity channel of
+	// the JPEG 2000 data is ignored, 1 if it is used as the soft mask of the image, 2 if it is
+	// used as the soft mask and the colour channels have been premultiplied by the opacity.
+	SMaskInData int
+
+	// ColorSpace is the colour space determined from the JPEG 2000 data: DeviceGray, DeviceRGB,
+	// DeviceCMYK or ICCBased, empty if unknown. It applies when the image dictionary has no
+	// ColorSpace entry. ICCProfile contains the ICC profile of the ICCBased colour space.
+	ColorSpace PdfObjectName
+	ICCProfile []byte
+
+	// ignorePalette is set when the image dictionary has an Indexed colour space, in which case
+	// the palette indices are decoded rather than the palette colours.
+	ignorePalette bool
+}
+
+// JPXImage is a JPEG 2000 image decoded by the JPXEncoder.
+type JPXImage struct {
+	Width, Height    int
+	ColorComponents  int
+	BitsPerComponent int // 8 or 16 bit
+
+	// Data contains the samples of the colour components, interleaved and in row major order.
+	Data []byte
+
+	// Alpha contains the opacity samples with the same number of bits per component, if the image
+	// has an opacity channel and SMaskInData is non-zero. It is nil otherwise.
+	Alpha []byte
+
+	ColorSpace PdfObjectName
+	ICCProfile []byte
+}
+
+// NewJPXEncoder returns a new instance of JPXEncoder.
+func NewJPXEncoder() *JPXEncoder {
+	return &JPXEncoder{
+		ColorComponents:  3,
+		BitsPerComponent: 8,
+	}
+}
+
+// newJPXEncoderFromStream creates a new JPX encoder from a stream object, with the image
+// parameters read from the JPEG 2000 data.
+func newJPXEncoderFromStream(streamObj *PdfObjectStream, multiEnc *MultiEncoder) (*JPXEncoder, error) {
+	encoder := NewJPXEncoder()
+
+	encDict := streamObj.PdfObjectDictionary
+	if encDict == nil {
+		// No encoding dictionary.
+		return encoder, nil
+	}
+	if smask, err := GetNumberAsInt64(encDict.Get("SMaskInData")); err == nil {
+		encoder.SMaskInData = int(smask)
+	}
+	if arr, ok := GetArray(encDict.Get("ColorSpace")); ok && arr.Len() > 0 {
+		if name, ok := GetName(arr.Get(0)); ok && (*name == "Indexed" || *name == "I") {
+			encoder.ignorePalette = true
+		}
+	}
+
+	// If using JPXDecode in combination with other filters, make sure to decode that first...
+	encoded := streamObj.Stream
+	if multiEnc != nil {
+		e, err := multiEnc.DecodeBytes(encoded)
+		if err != nil {
+			return nil, err
+		}
+		encoded = e
+	}
+
+	cfg, err := jpx.DecodeConfig(encoded, encoder.options())
+	if err != nil {
+		// Keep the defaults, the error is reported when decoding.
+		common.Log.Debug("Error decoding JPX configuration: %v", err)
+		return encoder, nil
+	}
+	encoder.Width = cfg.Width
+	encoder.Height = cfg.Height
+	encoder.ColorComponents = cfg.NumChannels
+	encoder.BitsPerComponent = jpxOutputDepth(cfg.Depth)
+	encoder.ColorSpace, encoder.ICCProfile = jpxColorSpace(cfg.ColorSpace, cfg.ICCProfile, cfg.NumChannels)
+	common.Log.Trace("JPX Encoder: %+v", encoder)
+	return encoder, nil
+}
+
+// GetFilterName returns the name of the encoding filter.
+func (enc *JPXEncoder) GetFilterName() string {
+	return StreamEncodingFilterNameJPX
+}
+
+// MakeDecodeParams makes a new instance of an encoding dictionary based on
+// the current encoder settings.
+func (enc *JPXEncoder) MakeDecodeParams() PdfObject {
+	return nil
+}
+
+// MakeStreamDict makes a new instance of an encoding dictionary for a stream object.
+func (enc *JPXEncoder) MakeStreamDict() *PdfObjectDictionary {
+	dict := MakeDict()
+	dict.Set("Filter", MakeName(enc.GetFilterName()))
+	return dict
+}
+
+// UpdateParams updates the parameter values of the encoder.
+func (enc *JPXEncoder) UpdateParams(params *PdfObjectDictionary) {
+	colorComponents, err := GetNumberAsInt64(params.Get("ColorComponents"))
+	if err == nil {
+		enc.ColorComponents = int(colorComponents)
+	}
+
+	bpc, err := GetNumberAsInt64(params.Get("BitsPerComponent"))
+	if err == nil {
+		enc.BitsPerComponent = int(bpc)
+	}
+
+	width, err := GetNumberAsInt64(params.Get("Width"))
+	if err == nil {
+		enc.Width = int(width)
+	}
+
+	height, err := GetNumberAsInt64(params.Get("Height"))
+	if err == nil {
+		enc.Height = int(height)
+	}
+
+	smask, err := GetNumberAsInt64(params.Get("SMaskInData"))
+	if err == nil {
+		enc.SMaskInData = int(smask)
+	}
+}
+
+// DecodeBytes decodes a slice of JPX encoded bytes and returns the samples of the colour
+// components, with 8 or 16 bits per component.
+func (enc *JPXEncoder) DecodeBytes(encoded []byte) ([]byte, error) {
+	img, err := enc.DecodeImage(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return img.Data, nil
+}
+
+// DecodeStream decodes a JPX encoded stream and returns the result as a
+// slice of bytes.
+func (enc *JPXEncoder) DecodeStream(streamObj *PdfObjectStream) ([]byte, error) {
+	return enc.DecodeBytes(streamObj.Stream)
+}
+
+// DecodeImage decodes a slice of JPX encoded bytes and returns the image with its opacity
+// channel and colour space.
+//
+// The samples are scaled to 8 bits per component if the bit depth of the image is at most 8,
+// to 16 bits otherwise. Palette indices (for Indexed colour spaces) are not scaled. Signed
+// samples are made unsigned by adding half of their range.
+func (enc *JPXEncoder) DecodeImage(encoded []byte) (*JPXImage, error) {
+	img, err := jpx.Decode(encoded, enc.options())
+	if err != nil {
+		common.Log.Debug("Error decoding JPX image: %v", err)
+		return nil, err
+	}
+	channels := img.Channels
+	alpha := img.Alpha
+	if alpha == nil && img.ColorSpace == jpx.ColorSpaceUnknown && enc.SMaskInData > 0 {
+		// Without a colour specification the last channel of a gray or RGB image with an extra
+		// channel is its opacity.
+		switch len(channels) {
+		case 2, 4, 5:
+			alpha = channels[len(channels)-1]
+			channels = channels[:len(channels)-1]
+		}
+	}
+	if len(channels) == 0 {
+		return nil, errors.New("JPX image without colour channels")
+	}
+
+	depth := 0
+	for _, ch := range channels {
+		if ch.Depth > depth {
+			depth = ch.Depth
+		}
+	}
+	out := &JPXImage{
+		Width:            img.Width,
+		Height:           img.Height,
+		ColorComponents:  len(channels),
+		BitsPerComponent: jpxOutputDepth(depth),
+	}
+	out.ColorSpace, out.ICCProfile = jpxColorSpace(img.ColorSpace, img.ICCProfile, len(channels))
+
+	numPixels := img.Width * img.Height
+	bytesPerSample := out.BitsPerComponent / 8
+	maxOut := uint32(1)<<uint(out.BitsPerComponent) - 1
+
+	var alphaValues []uint32
+	if alpha != nil && (enc.SMaskInData > 0 || img.Premultiplied) {
+		alphaValues = jpxScale(alpha, out.BitsPerComponent, true)
+	}
+
+	out.Data = make([]byte, numPixels*len(channels)*bytesPerSample)
+	for c, ch := range channels {
+		values := jpxScale(ch, out.BitsPerComponent, !enc.ignorePalette)
+		for i, v := range values {
+			if img.Premultiplied && alphaValues != nil {
+				// Unmultiply the colour by the opacity.
+				if a := alphaValues[i]; a > 0 {
+					v = uint32(uint64(v) * uint64(maxOut) / uint64(a))
+					if v > maxOut {
+						v = maxOut
+					}
+				}
+			}
+			putJPXSample(out.Data, (i*len(channels)+c)*bytesPerSample, bytesPerSample, v)
+		}
+	}
+	if alphaValues != nil && enc.SMaskInData > 0 {
+		out.Alpha = make([]byte, numPixels*bytesPerSample)
+		for i, v := range alphaValues {
+			putJPXSample(out.Alpha, i*bytesPerSample, bytesPerSample, v)
+		}
+	}
+	return out, nil
+}
+
+// EncodeBytes JPX encodes the passed in slice of bytes.
+func (enc *JPXEncoder) EncodeBytes(data []byte) ([]byte, error) {
+	common.Log.Debug("Error: Attempting to use unsupported encoding %s", enc.GetFilterName())
+	return data, ErrNoJPXDecode
+}
+
+// options returns the options of the JPEG 2000 decoder.
+func (enc *JPXEncoder) options() *jpx.Options {
+	return &jpx.Options{IgnorePalette: enc.ignorePalette}
+}
+
+// jpxOutputDepth returns the number of bits per component of the decoded samples of an image
+// with bit depth `depth`.
+func jpxOutputDepth(depth int) int {
+	if depth > 8 {
+		return 16
+	}
+	return 8
+}
+
+// jpxColorSpace returns the name of the PDF colour space corresponding to the colour space of a
+// JPEG 2000 image with `n` colour channels, and its ICC profile.
+func jpxColorSpace(cs jpx.ColorSpace, icc []byte, n int) (PdfObjectName, []byte) {
+	switch cs {
+	case jpx.ColorSpaceICC:
+		return "ICCBased", icc
+	case jpx.ColorSpaceGray:
+		return "DeviceGray", nil
+	case jpx.ColorSpaceRGB:
+		return "DeviceRGB", nil
+	case jpx.ColorSpaceCMYK:
+		return "DeviceCMYK", nil
+	}
+	switch n {
+	case 1:
+		return "DeviceGray", nil
+	case 3:
+		return "DeviceRGB", nil
+	case 4:
+		return "DeviceCMYK", nil
+	}
+	return "", nil
+}
+
+// jpxScale returns the unsigned samples of channel `ch` with `bits` bits per component. If
+// `scale` is false the values are only clamped.
+func jpxScale(ch *jpx.Channel, bits int, scale bool) []uint32 {
+	maxOut := int64(1)<<uint(bits) - 1
+	maxIn := int64(1)<<uint(ch.Depth) - 1
+	values := make([]uint32, len(ch.Data))
+	for i, s := range ch.Data {
+		v := int64(s)
+		if ch.Signed {
+			v += int64(1) << uint(ch.Depth-1)
+		}
+		if v < 0 {
+			v = 0
+		} else if v > maxIn {
+			v = maxIn
+		}
+		if scale && maxIn != maxOut {
+			v = (v*maxOut + maxIn/2) / maxIn
+		}
+		if v > maxOut {
+			v = maxOut
+		}
+		values[i] = uint32(v)
+	}
+	return values
+}
+
+// putJPXSample stores sample `v` of `n` bytes, in big endian order, at offset `i` of `data`.
+func putJPXSample(data []byte, i, n int, v uint32) {
+	if n == 2 {
+		data[i] = byte(v >> 8)
+		data[i+1] = byte(v)
+		return
+	}
+	data[i] = byte(v)
+}
@@ -0,0 +1,114 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// jpxStream returns an image stream with the JPEG 2000 data of `path` and the entries `entries`.
+func jpxStream(t *testing.T, path string, entries map[string]PdfObject) *PdfObjectStream {
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	dict := MakeDict()
+	dict.Set("Filter", MakeName(StreamEncodingFilterNameJPX))
+	for key, val := range entries {
+		dict.Set(PdfObjectName(key), val)
+	}
+	return &PdfObjectStream{PdfObjectDictionary: dict, Stream: data}
+}
+
+func TestJPXDecodeRGBA(t *testing.T) {
+	const w, h = 16, 12
+	expected := func(x, y int) [4]byte {
+		return [4]byte{byte(x * 16), byte(y * 20), 128, byte((x + y) * 8)}
+	}
+
+	// Without SMaskInData the opacity channel is ignored.
+	stream := jpxStream(t, "./testdata/rgba.jp2", nil)
+	decoded, err := DecodeStream(stream)
+	require.NoError(t, err)
+	require.Len(t, decoded, w*h*3)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			exp := expected(x, y)
+			require.Equal(t, exp[:3], decoded[(y*w+x)*3:(y*w+x+1)*3])
+		}
+	}
+
+	enc, err := NewEncoderFromStream(jpxStream(t, "./testdata/rgba.jp2", map[string]PdfObject{
+		"SMaskInData": MakeInteger(1),
+	}))
+	require.NoError(t, err)
+	jpxEnc, ok := enc.(*JPXEncoder)
+	require.True(t, ok)
+	require.Equal(t, w, jpxEnc.Width)
+	require.Equal(t, h, jpxEnc.Height)
+	require.Equal(t, 3, jpxEnc.ColorComponents)
+	require.Equal(t, 8, jpxEnc.BitsPerComponent)
+	require.Equal(t, 1, jpxEnc.SMaskInData)
+	require.Equal(t, PdfObjectName("DeviceRGB"), jpxEnc.ColorSpace)
+
+	img, err := jpxEnc.DecodeImage(stream.Stream)
+	require.NoError(t, err)
+	require.Len(t, img.Alpha, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			require.Equal(t, expected(x, y)[3], img.Alpha[y*w+x])
+		}
+	}
+}
+
+func TestJPXDecode16Bit(t *testing.T) {
+	const w, h = 10, 8
+	stream := jpxStream(t, "./testdata/gray12.j2k", nil)
+	enc, err := NewEncoderFromStream(stream)
+	require.NoError(t, err)
+	jpxEnc := enc.(*JPXEncoder)
+	require.Equal(t, 1, jpxEnc.ColorComponents)
+	require.Equal(t, 16, jpxEnc.BitsPerComponent)
+	require.Equal(t, PdfObjectName("DeviceGray"), jpxEnc.ColorSpace)
+
+	decoded, err := enc.DecodeStream(stream)
+	require.NoError(t, err)
+	require.Len(t, decoded, w*h*2)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			// The 12 bit samples are scaled to 16 bits.
+			v := (x*300 + y*50) % 4096
+			exp := (v*65535 + 2047) / 4095
+			i := (y*w + x) * 2
+			require.Equal(t, exp, int(decoded[i])<<8|int(decoded[i+1]))
+		}
+	}
+}
+
+func TestJPXDecodeInvalid(t *testing.T) {
+	stream := &PdfObjectStream{PdfObjectDictionary: MakeDict(), Stream: []byte("not a jpeg 2000 image")}
+	stream.Set("Filter", MakeName(StreamEncodingFilterNameJPX))
+	enc, err := NewEncoderFromStream(stream)
+	require.NoError(t, err)
+	_, err = enc.DecodeStream(stream)
+	require.Error(t, err)
+}
+
+func TestJPXMultiEncoder(t *testing.T) {
+	data, err := ioutil.ReadFile("./testdata/gray12.j2k")
+	require.NoError(t, err)
+	encoded, err := NewASCIIHexEncoder().EncodeBytes(data)
+	require.NoError(t, err)
+	stream := jpxStream(t, "./testdata/gray12.j2k", map[string]PdfObject{
+		"Filter": MakeArray(MakeName(StreamEncodingFilterNameASCIIHex), MakeName(StreamEncodingFilterNameJPX)),
+	})
+	stream.Stream = encoded
+
+	decoded, err := DecodeStream(stream)
+	require.NoError(t, err)
+	require.Len(t, decoded, 10*8*2)
+}
@@ -9,8 +9,6 @@ import (
 	"bufio"
 	"errors"
 	"io"
-
-	"github.com/unidoc/unipdf/v3/common"
 )
 
 // Offset reader encapsulates io.ReadSeeker and offsets it by the specified
@@ -64,7 +62,7 @@ func (parser *PdfParser) ReadAtLeast(p []byte, n int) (int, error) {
 	for remaining > 0 {
 		nRead, err := parser.reader.Read(p[start:])
 		if err != nil {
-			common.Log.Debug("ERROR Failed reading (%d;%d) %s", nRead, numRounds, err.Error())
+			parser.log().Debug("ERROR Failed reading (%d;%d) %s", nRead, numRounds, err.Error())
 			return start, errors.New("failed reading")
 		}
 		numRounds++
@@ -92,7 +90,30 @@ func (parser *PdfParser) SetFileOffset(offset int64) {
 }
 
 // ReadBytesAt reads byte content at specific offset and length within the PDF.
+// If the underlying reader implements io.ReaderAt, the content is read without changing the current
+// file offset, allowing concurrent use.
 func (parser *PdfParser) ReadBytesAt(offset, len int64) ([]byte, error) {
+	if _, ok := parser.rs.(io.ReaderAt); ok {
+		return parser.readBytesAt(offset, len)
+	}
+
+	parser.lock.Lock()
+	defer parser.lock.Unlock()
+	return parser.readBytesAt(offset, len)
+}
+
+// readBytesAt implements ReadBytesAt. The lock needs to be held by the caller, unless the underlying
+// reader implements io.ReaderAt.
+func (parser *PdfParser) readBytesAt(offset, len int64) ([]byte, error) {
+	if ra, ok := parser.rs.(io.ReaderAt); ok {
+		bb := make([]byte, len)
+		n, err := ra.ReadAt(bb, offset)
+		if err != nil && !(err == io.EOF && int64(n) == len) {
+			return nil, err
+		}
+		return bb, nil
+	}
+
 	curPos := parser.GetFileOffset()
 
 	_, err := parser.rs.Seek(offset, io.SeekStart)
@@ -0,0 +1,956 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/unidoc/unipdf/v3/common"
+)
+
+// TokenType identifies the type of a token returned by Lexer.
+type TokenType int
+
+// Token types returned by Lexer.
+const (
+	// TokenComment is a comment starting with '%' (up to, but not including the end of line).
+	TokenComment TokenType = iota
+	// TokenNumber is an integer or real number.
+	TokenNumber
+	// TokenName is a name object starting with '/'.
+	TokenName
+	// TokenString is a literal string enclosed in parentheses.
+	TokenString
+	// TokenHexString is a hexadecimal string enclosed in angle brackets.
+	TokenHexString
+	// TokenArrayStart is the '[' delimiter.
+	TokenArrayStart
+	// TokenArrayEnd is the ']' delimiter.
+	TokenArrayEnd
+	// TokenDictStart is the '<<' delimiter.
+	TokenDictStart
+	// TokenDictEnd is the '>>' delimiter.
+	TokenDictEnd
+	// TokenDelimiter is any other delimiter which is not part of a token (e.g. unbalanced ')', '{', '}').
+	TokenDelimiter
+	// TokenKeyword is a sequence of regular characters which is not a number, such as true, false,
+	// null, R, trailer, xref or content stream operators.
+	TokenKeyword
+	// TokenObj is the obj keyword, marking the start of an indirect object.
+	TokenObj
+	// TokenEndObj is the endobj keyword.
+	TokenEndObj
+	// TokenStream is the stream keyword following a dictionary. The stream data starts after the
+	// token and can be read or skipped with Lexer.ReadStream and Lexer.SkipStream.
+	TokenStream
+	// TokenEndStream is the endstream keyword.
+	TokenEndStream
+)
+
+// String returns a string describing the token type.
+func (t TokenType) String() string {
+	switch t {
+	case TokenComment:
+		return "comment"
+	case TokenNumber:
+		return "number"
+	case TokenName:
+		return "name"
+	case TokenString:
+		return "string"
+	case TokenHexString:
+		return "hexstring"
+	case TokenArrayStart:
+		return "array start"
+	case TokenArrayEnd:
+		return "array end"
+	case TokenDictStart:
+		return "dict start"
+	case TokenDictEnd:
+		return "dict end"
+	case TokenDelimiter:
+		return "delimiter"
+	case TokenKeyword:
+		return "keyword"
+	case TokenObj:
+		return "obj"
+	case TokenEndObj:
+		return "endobj"
+	case TokenStream:
+		return "stream"
+	case TokenEndStream:
+		return "endstream"
+	}
+	return fmt.Sprintf("token(%d)", int(t))
+}
+
+// Token represents a lexical token of a PDF file or content stream.
+type Token struct {
+	// Type of the token.
+	Type TokenType
+
+	// Offset is the byte offset of the first byte of the token, relative to the position of the
+	// underlying reader when the Lexer was created.
+	Offset int64
+
+	// Raw contains the bytes of the token as they appear in the input, e.g. including the
+	// enclosing parentheses of literal strings.
+	// NOTE: Raw refers to the internal buffer of the lexer and is only valid until the next call
+	// to a Lexer method. It needs to be copied if it is to be retained.
+	Raw []byte
+}
+
+// Int returns the value of a number token as an integer. The bool flag is false if the token
+// is not an integer.
+func (t Token) Int() (int64, bool) {
+	if t.Type != TokenNumber {
+		return 0, false
+	}
+	val, err := strconv.ParseInt(string(t.Raw), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return val, true
+}
+
+// Float returns the value of a number token as a float. The bool flag is false if the token is
+// not a number.
+func (t Token) Float() (float64, bool) {
+	if t.Type != TokenNumber {
+		return 0, false
+	}
+	val, err := strconv.ParseFloat(string(t.Raw), 64)
+	if err != nil {
+		return 0, false
+	}
+	return val, true
+}
+
+// Is returns true if the token is a keyword (including obj, endobj, stream and endstream) equal
+// to `keyword`.
+func (t Token) Is(keyword string) bool {
+	switch t.Type {
+	case TokenKeyword, TokenObj, TokenEndObj, TokenStream, TokenEndStream:
+		return string(t.Raw) == keyword
+	}
+	return false
+}
+
+// Name returns the decoded value of a name token, i.e. without the leading '/' and with '#'
+// hexadecimal escapes resolved.
+func (t Token) Name() PdfObjectName {
+	if t.Type != TokenName || len(t.Raw) == 0 {
+		return ""
+	}
+	raw := t.Raw[1:]
+	if bytes.IndexByte(raw, '#') < 0 {
+		return PdfObjectName(raw)
+	}
+
+	var b bytes.Buffer
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && i+2 < len(raw) {
+			code, err := hex.DecodeString(string(raw[i+1 : i+3]))
+			if err == nil {
+				b.Write(code)
+				i += 2
+				continue
+			}
+			common.Log.Debug("ERROR: Invalid hex following '#', continuing using literal - Output may be incorrect")
+		}
+		b.WriteByte(raw[i])
+	}
+	return PdfObjectName(b.String())
+}
+
+// Bytes returns the decoded content of a string or hexadecimal string token.
+func (t Token) Bytes() []byte {
+	switch t.Type {
+	case TokenString:
+		return decodeLiteralString(t.Raw)
+	case TokenHexString:
+		return decodeHexString(t.Raw)
+	}
+	return nil
+}
+
+// decodeLiteralString decodes the literal string `raw` including the enclosing parentheses
+// (7.3.4.2 Literal Strings).
+func decodeLiteralString(raw []byte) []byte {
+	if len(raw) > 0 && raw[0] == '(' {
+		raw = raw[1:]
+	}
+	if len(raw) > 0 && raw[len(raw)-1] == ')' {
+		raw = raw[:len(raw)-1]
+	}
+
+	out := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		if c != '\\' {
+			out = append(out, c)
+			continue
+		}
+		i++
+		if i >= len(raw) {
+			break
+		}
+		c = raw[i]
+		if IsOctalDigit(c) {
+			code := int(c - '0')
+			for j := 0; j < 2 && i+1 < len(raw) && IsOctalDigit(raw[i+1]); j++ {
+				i++
+				code = code*8 + int(raw[i]-'0')
+			}
+			out = append(out, byte(code))
+			continue
+		}
+		switch c {
+		case 'n':
+			out = append(out, '\n')
+		case 'r':
+			out = append(out, '\r')
+		case 't':
+			out = append(out, '\t')
+		case 'b':
+			out = append(out, '\b')
+		case 'f':
+			out = append(out, '\f')
+		case '(', ')', '\\':
+			out = append(out, c)
+		case '\r':
+			// Line continuation.
+			if i+1 < len(raw) && raw[i+1] == '\n' {
+				i++
+			}
+		}
+		// Unknown escapes and line continuations (\n) are ignored.
+	}
+	return out
+}
+
+// decodeHexString decodes the hexadecimal string `raw` including the enclosing angle brackets.
+// Characters which are not hexadecimal digits are ignored.
+func decodeHexString(raw []byte) []byte {
+	digits := make([]byte, 0, len(raw))
+	for _, c := range raw {
+		if ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F') {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	hex.Decode(out, digits)
+	return out
+}
+
+// lexToken is a token position within the lexer buffer.
+type lexToken struct {
+	typ    TokenType
+	offset int64
+	length int
+}
+
+// ErrStreamState is returned by the Lexer stream functions when the lexer is not positioned at
+// the start of stream data.
+var ErrStreamState = errors.New("lexer not positioned at stream data")
+
+// Lexer is a pull-style tokenizer for PDF objects and content streams. It reads the input
+// sequentially with a bounded buffer, yielding tokens with their byte offsets, and is suitable for
+// scanning arbitrarily large files.
+// Stream data following a stream keyword can be read with ReadStream or skipped with SkipStream.
+// If neither is called, the lexer skips the data up to the endstream keyword.
+type Lexer struct {
+	r    io.Reader
+	buf  []byte
+	pos  int   // Read position in buf.
+	end  int   // End of valid data in buf.
+	base int64 // Offset of buf[0].
+	err  error // Sticky read error.
+
+	// Offset of the first byte that needs to be retained in the buffer.
+	mark int64
+
+	// Tokens read ahead and not yet returned.
+	queue []lexToken
+
+	// Set when positioned at the start of stream data.
+	inStream bool
+
+	// Type of the last token lexed.
+	last TokenType
+
+	// Set when lexing a content stream, which contains no references nor streams.
+	contentStream bool
+}
+
+// lexerBufSize is the initial size of the lexer buffer.
+const lexerBufSize = 16 * 1024
+
+// NewLexer returns a new Lexer reading from `r`. Offsets of returned tokens are relative to the
+// position of `r` at the time the lexer is created.
+func NewLexer(r io.Reader) *Lexer {
+	return &Lexer{
+		r:   r,
+		buf: make([]byte, lexerBufSize),
+	}
+}
+
+// NewContentStreamLexer returns a new Lexer reading the content stream `r`. Content streams contain
+// no indirect references nor stream objects: integers are never read as references, and stream
+// keywords are regular keywords, not followed by stream data.
+func NewContentStreamLexer(r io.Reader) *Lexer {
+	lx := NewLexer(r)
+	lx.contentStream = true
+	return lx
+}
+
+// Offset returns the current offset of the lexer, i.e. the offset following the last token read
+// (not counting tokens read ahead internally).
+func (lx *Lexer) Offset() int64 {
+	if len(lx.queue) > 0 {
+		return lx.queue[0].offset
+	}
+	return lx.base + int64(lx.pos)
+}
+
+// fill reads data until at least `n` bytes are available from the current position.
+// Returns an error if less than `n` bytes are available.
+func (lx *Lexer) fill(n int) error {
+	for lx.end-lx.pos < n {
+		if lx.err != nil {
+			return lx.err
+		}
+
+		// Discard data which is no longer needed.
+		keep := lx.base + int64(lx.pos)
+		if len(lx.queue) > 0 && lx.queue[0].offset < keep {
+			keep = lx.queue[0].offset
+		}
+		if lx.mark < keep {
+			keep = lx.mark
+		}
+		if k := int(keep - lx.base); k > 0 {
+			copy(lx.buf, lx.buf[k:lx.end])
+			lx.end -= k
+			lx.pos -= k
+			lx.base += int64(k)
+		}
+
+		// Grow the buffer if needed.
+		if need := lx.pos + n; need > len(lx.buf) || lx.end == len(lx.buf) {
+			size := 2 * len(lx.buf)
+			for size < need {
+				size *= 2
+			}
+			buf := make([]byte, size)
+			copy(buf, lx.buf[:lx.end])
+			lx.buf = buf
+		}
+
+		m, err := lx.r.Read(lx.buf[lx.end:])
+		lx.end += m
+		if err != nil {
+			lx.err = err
+		}
+	}
+	return nil
+}
+
+// peekAt returns the byte `i` bytes after the current position.
+func (lx *Lexer) peekAt(i int) (byte, error) {
+	if lx.pos+i >= lx.end {
+		if err := lx.fill(i + 1); err != nil && lx.pos+i >= lx.end {
+			return 0, err
+		}
+	}
+	return lx.buf[lx.pos+i], nil
+}
+
+// Next returns the next token. Returns io.EOF when there are no more tokens.
+func (lx *Lexer) Next() (Token, error) {
+	var t lexToken
+	if len(lx.queue) > 0 {
+		t = lx.queue[0]
+		lx.queue = lx.queue[1:]
+	} else {
+		var err error
+		if t, err = lx.lex(); err != nil {
+			return Token{}, err
+		}
+	}
+	lx.mark = t.offset
+	return lx.token(t), nil
+}
+
+// Peek returns the next token without consuming it.
+func (lx *Lexer) Peek() (Token, error) {
+	return lx.peek(0)
+}
+
+// peek returns the `i`th token following the current position without consuming it.
+func (lx *Lexer) peek(i int) (Token, error) {
+	for len(lx.queue) <= i {
+		t, err := lx.lex()
+		if err != nil {
+			return Token{}, err
+		}
+		lx.queue = append(lx.queue, t)
+	}
+	return lx.token(lx.queue[i]), nil
+}
+
+// token returns the Token for position `t`.
+func (lx *Lexer) token(t lexToken) Token {
+	start := int(t.offset - lx.base)
+	return Token{Type: t.typ, Offset: t.offset, Raw: lx.buf[start : start+t.length]}
+}
+
+// lex reads the next token from the input.
+func (lx *Lexer) lex() (lexToken, error) {
+	if lx.inStream {
+		if err := lx.skipStreamData(); err != nil {
+			return lexToken{}, err
+		}
+	}
+
+	// Skip white space.
+	for {
+		c, err := lx.peekAt(0)
+		if err != nil {
+			return lexToken{}, err
+		}
+		if !IsWhiteSpace(c) {
+			break
+		}
+		lx.pos++
+	}
+
+	start := lx.base + int64(lx.pos)
+	if len(lx.queue) == 0 {
+		lx.mark = start
+	}
+	typ, n, err := lx.scan()
+	if err != nil {
+		return lexToken{}, err
+	}
+	// The buffer may have been compacted while scanning, recompute the position.
+	lx.pos = int(start-lx.base) + n
+	if typ == TokenStream && (lx.last != TokenDictEnd || lx.contentStream) {
+		// Stream data always follows a stream dictionary. Otherwise treat as a regular keyword, e.g.
+		// in content streams.
+		typ = TokenKeyword
+	}
+	if typ != TokenComment {
+		lx.last = typ
+	}
+	t := lexToken{typ: typ, offset: start, length: n}
+
+	if typ == TokenStream {
+		lx.skipStreamEOL()
+		lx.inStream = true
+	}
+	return t, nil
+}
+
+// scan determines the type and length of the token at the current position.
+func (lx *Lexer) scan() (TokenType, int, error) {
+	c, _ := lx.peekAt(0)
+	switch {
+	case c == '%':
+		n := 1
+		for {
+			c, err := lx.peekAt(n)
+			if err != nil || c == '\r' || c == '\n' {
+				break
+			}
+			n++
+		}
+		return TokenComment, n, nil
+	case c == '/':
+		n := 1
+		for {
+			c, err := lx.peekAt(n)
+			if err != nil || IsWhiteSpace(c) || IsDelimiter(c) {
+				break
+			}
+			n++
+		}
+		return TokenName, n, nil
+	case c == '(':
+		n, err := lx.scanLiteralString()
+		return TokenString, n, err
+	case c == '<':
+		if c2, err := lx.peekAt(1); err == nil && c2 == '<' {
+			return TokenDictStart, 2, nil
+		}
+		n := 1
+		for {
+			c, err := lx.peekAt(n)
+			if err != nil {
+				return TokenHexString, n, io.ErrUnexpectedEOF
+			}
+			n++
+			if c == '>' {
+				break
+			}
+		}
+		return TokenHexString, n, nil
+	case c == '>':
+		if c2, err := lx.peekAt(1); err == nil && c2 == '>' {
+			return TokenDictEnd, 2, nil
+		}
+		return TokenDelimiter, 1, nil
+	case c == '[':
+		return TokenArrayStart, 1, nil
+	case c == ']':
+		return TokenArrayEnd, 1, nil
+	case IsDelimiter(c):
+		return TokenDelimiter, 1, nil
+	case lx.isNumberStart(c):
+		return TokenNumber, lx.scanNumber(), nil
+	}
+
+	n := 1
+	for {
+		c, err := lx.peekAt(n)
+		if err != nil || IsWhiteSpace(c) || IsDelimiter(c) {
+			break
+		}
+		n++
+	}
+	start := lx.pos
+	switch string(lx.buf[start : start+n]) {
+	case "obj":
+		return TokenObj, n, nil
+	case "endobj":
+		return TokenEndObj, n, nil
+	case "stream":
+		return TokenStream, n, nil
+	case "endstream":
+		return TokenEndStream, n, nil
+	}
+	return TokenKeyword, n, nil
+}
+
+// isNumberStart checks whether a number starts with `c` at the current position.
+func (lx *Lexer) isNumberStart(c byte) bool {
+	if IsFloatDigit(c) {
+		return true
+	}
+	if c == '-' || c == '+' {
+		c2, err := lx.peekAt(1)
+		return err == nil && IsFloatDigit(c2)
+	}
+	return false
+}
+
+// scanNumber returns the length of the number at the current position.
+// Signs are allowed at the start and following an exponent marker.
+func (lx *Lexer) scanNumber() int {
+	n := 0
+	allowSigns := true
+	for {
+		c, err := lx.peekAt(n)
+		if err != nil {
+			break
+		}
+		if allowSigns && (c == '-' || c == '+') {
+			allowSigns = false
+		} else if IsFloatDigit(c) {
+			allowSigns = false
+		} else if (c == 'e' || c == 'E') && n > 0 {
+			// Only treat as exponent if followed by digits.
+			c2, err := lx.peekAt(n + 1)
+			if err != nil {
+				break
+			}
+			if c2 == '-' || c2 == '+' {
+				c2, err = lx.peekAt(n + 2)
+				if err != nil {
+					break
+				}
+			}
+			if !IsDecimalDigit(c2) {
+				break
+			}
+			allowSigns = true
+		} else {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// scanLiteralString returns the length of the literal string at the current position,
+// including the enclosing parentheses.
+func (lx *Lexer) scanLiteralString() (int, error) {
+	depth := 0
+	n := 0
+	for {
+		c, err := lx.peekAt(n)
+		if err != nil {
+			return n, io.ErrUnexpectedEOF
+		}
+		n++
+		switch c {
+		case '\\':
+			n++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return n, nil
+			}
+		}
+	}
+}
+
+// skipStreamEOL skips the end-of-line marker following the stream keyword.
+func (lx *Lexer) skipStreamEOL() {
+	c, err := lx.peekAt(0)
+	if err != nil {
+		return
+	}
+	if IsWhiteSpace(c) && c != '\r' && c != '\n' {
+		common.Log.Debug("Non-conformant stream not ending stream line properly with EOL marker")
+		lx.pos++
+		if c, err = lx.peekAt(0); err != nil {
+			return
+		}
+	}
+	if c == '\r' {
+		lx.pos++
+		if c, err = lx.peekAt(0); err != nil {
+			return
+		}
+	}
+	if c == '\n' {
+		lx.pos++
+	}
+}
+
+var endstreamKeyword = []byte("endstream")
+
+// scanStreamData advances the position to the next endstream keyword, passing the skipped data
+// to `fn` if not nil. Returns io.ErrUnexpectedEOF if the keyword is not found.
+func (lx *Lexer) scanStreamData(fn func(data []byte)) error {
+	lx.inStream = false
+	for {
+		if len(lx.queue) == 0 {
+			lx.mark = lx.base + int64(lx.pos)
+		}
+		data := lx.buf[lx.pos:lx.end]
+		if i := bytes.Index(data, endstreamKeyword); i >= 0 {
+			if fn != nil {
+				fn(data[:i])
+			}
+			lx.pos += i
+			return nil
+		}
+
+		// Keep the tail which might contain the beginning of the keyword.
+		n := len(data) - len(endstreamKeyword) + 1
+		if n > 0 {
+			if fn != nil {
+				fn(data[:n])
+			}
+			lx.pos += n
+		}
+		if err := lx.fill(lx.end - lx.pos + 1); err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+}
+
+// skipStreamData skips the stream data up to the endstream keyword.
+func (lx *Lexer) skipStreamData() error {
+	return lx.scanStreamData(nil)
+}
+
+// SkipStream skips the stream data following a TokenStream token without keeping it in memory.
+// If `length` is non-negative, exactly `length` bytes are skipped (using Seek if the underlying reader
+// implements io.Seeker), otherwise the data is skipped up to the endstream keyword.
+func (lx *Lexer) SkipStream(length int64) error {
+	if !lx.inStream || len(lx.queue) > 0 {
+		return ErrStreamState
+	}
+	if length < 0 {
+		return lx.skipStreamData()
+	}
+	lx.inStream = false
+
+	if avail := int64(lx.end - lx.pos); length <= avail {
+		lx.pos += int(length)
+		return nil
+	}
+
+	// Skip the buffered data and the remaining bytes from the reader.
+	remaining := length - int64(lx.end-lx.pos)
+	lx.base += int64(lx.end)
+	lx.pos, lx.end = 0, 0
+	lx.mark = lx.base
+
+	if seeker, ok := lx.r.(io.Seeker); ok && lx.err == nil {
+		if _, err := seeker.Seek(remaining, io.SeekCurrent); err != nil {
+			return err
+		}
+		lx.base += remaining
+		return nil
+	}
+	n, err := io.CopyN(ioutil.Discard, lx.r, remaining)
+	lx.base += n
+	if err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
+
+// ReadStream reads and returns the stream data following a TokenStream token.
+// If `length` is non-negative, exactly `length` bytes are read, otherwise the data is read up to
+// the endstream keyword (excluding the end-of-line marker preceding it).
+func (lx *Lexer) ReadStream(length int64) ([]byte, error) {
+	if !lx.inStream || len(lx.queue) > 0 {
+		return nil, ErrStreamState
+	}
+
+	if length < 0 {
+		var data []byte
+		err := lx.scanStreamData(func(b []byte) {
+			data = append(data, b...)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if n := len(data); n > 0 && data[n-1] == '\n' {
+			data = data[:n-1]
+		}
+		if n := len(data); n > 0 && data[n-1] == '\r' {
+			data = data[:n-1]
+		}
+		return data, nil
+	}
+	lx.inStream = false
+
+	data := make([]byte, length)
+	n := copy(data, lx.buf[lx.pos:lx.end])
+	lx.pos += n
+	if int64(n) < length {
+		lx.base += int64(lx.end)
+		lx.pos, lx.end = 0, 0
+		lx.mark = lx.base
+		m, err := io.ReadFull(lx.r, data[n:])
+		lx.base += int64(m)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// ReadByte reads a single byte from the input, bypassing the tokenizer. Can be used for reading
+// binary data, such as inline image data in content streams. Implements io.ByteReader.
+func (lx *Lexer) ReadByte() (byte, error) {
+	if len(lx.queue) > 0 {
+		return 0, errors.New("lexer has tokens read ahead")
+	}
+	c, err := lx.peekAt(0)
+	if err != nil {
+		return 0, err
+	}
+	lx.pos++
+	return c, nil
+}
+
+// PeekBytes returns the next `n` bytes of the input without advancing, bypassing the tokenizer.
+// If fewer than `n` bytes are available, the available bytes are returned along with an error.
+// The returned slice is only valid until the next call to a Lexer method.
+func (lx *Lexer) PeekBytes(n int) ([]byte, error) {
+	if len(lx.queue) > 0 {
+		return nil, errors.New("lexer has tokens read ahead")
+	}
+	err := lx.fill(n)
+	if avail := lx.end - lx.pos; avail < n {
+		n = avail
+	}
+	return lx.buf[lx.pos : lx.pos+n], err
+}
+
+// Discard skips the next `n` bytes of the input, bypassing the tokenizer.
+func (lx *Lexer) Discard(n int) (int, error) {
+	if len(lx.queue) > 0 {
+		return 0, errors.New("lexer has tokens read ahead")
+	}
+	err := lx.fill(n)
+	if avail := lx.end - lx.pos; avail < n {
+		n = avail
+	}
+	lx.pos += n
+	return n, err
+}
+
+// ReadObject reads a direct object starting with token `tok`, which was returned by the last call to
+// Next. Arrays and dictionaries are read until their closing delimiter, and integers followed by
+// a generation number and the R keyword are read as references, except in content streams.
+// Keywords other than true, false and null result in an error.
+func (lx *Lexer) ReadObject(tok Token) (PdfObject, error) {
+	switch tok.Type {
+	case TokenNumber:
+		return lx.readNumber(tok)
+	case TokenName:
+		name := tok.Name()
+		return &name, nil
+	case TokenString:
+		return MakeString(string(tok.Bytes())), nil
+	case TokenHexString:
+		return MakeHexString(string(tok.Bytes())), nil
+	case TokenArrayStart:
+		return lx.readArray()
+	case TokenDictStart:
+		return lx.readDict()
+	case TokenKeyword:
+		switch string(tok.Raw) {
+		case "true":
+			return MakeBool(true), nil
+		case "false":
+			return MakeBool(false), nil
+		case "null":
+			return MakeNull(), nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %s token %q at offset %d", tok.Type, tok.Raw, tok.Offset)
+}
+
+// NextObject reads the next direct object, skipping comments. See ReadObject.
+func (lx *Lexer) NextObject() (PdfObject, error) {
+	tok, err := lx.nextNonComment()
+	if err != nil {
+		return nil, err
+	}
+	return lx.ReadObject(tok)
+}
+
+// nextNonComment returns the next token which is not a comment.
+func (lx *Lexer) nextNonComment() (Token, error) {
+	for {
+		tok, err := lx.Next()
+		if err != nil || tok.Type != TokenComment {
+			return tok, err
+		}
+	}
+}
+
+// readNumber makes a number object from `tok`, reading a reference if the number is followed by a
+// generation number and the R keyword.
+func (lx *Lexer) readNumber(tok Token) (PdfObject, error) {
+	raw := tok.Raw
+	isFloat := bytes.IndexAny(raw, ".eE") >= 0
+	if isFloat {
+		val, err := strconv.ParseFloat(string(raw), 64)
+		if err != nil {
+			common.Log.Debug("Error parsing number %q err=%v. Using 0.0. Output may be incorrect", raw, err)
+			val = 0
+		}
+		return MakeFloat(val), nil
+	}
+
+	val, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil {
+		common.Log.Debug("Error parsing number %q err=%v. Using 0. Output may be incorrect", raw, err)
+		val = 0
+	}
+	if val < 0 || raw[0] == '+' || lx.contentStream {
+		return MakeInteger(val), nil
+	}
+
+	// Check for a reference: <objnum> <gennum> R.
+	gtok, err := lx.peek(0)
+	if err != nil || gtok.Type != TokenNumber {
+		return MakeInteger(val), nil
+	}
+	gen, ok := gtok.Int()
+	if !ok || gen < 0 {
+		return MakeInteger(val), nil
+	}
+	rtok, err := lx.peek(1)
+	if err != nil || !rtok.Is("R") {
+		return MakeInteger(val), nil
+	}
+	lx.queue = lx.queue[2:]
+	return &PdfObjectReference{ObjectNumber: val, GenerationNumber: gen}, nil
+}
+
+// readArray reads array elements up to the closing ']'.
+func (lx *Lexer) readArray() (*PdfObjectArray, error) {
+	arr := MakeArray()
+	for {
+		tok, err := lx.nextNonComment()
+		if err != nil {
+			return arr, err
+		}
+		if tok.Type == TokenArrayEnd {
+			return arr, nil
+		}
+		obj, err := lx.ReadObject(tok)
+		if err != nil {
+			return arr, err
+		}
+		arr.Append(obj)
+	}
+}
+
+// readDict reads dictionary entries up to the closing '>>'.
+func (lx *Lexer) readDict() (*PdfObjectDictionary, error) {
+	dict := MakeDict()
+	for {
+		tok, err := lx.nextNonComment()
+		if err != nil {
+			return nil, err
+		}
+		if tok.Type == TokenDictEnd {
+			return dict, nil
+		}
+		if tok.Type != TokenName {
+			return nil, fmt.Errorf("invalid dictionary key: %s token %q at offset %d", tok.Type, tok.Raw, tok.Offset)
+		}
+		key := tok.Name()
+
+		if len(key) > 4 && key[len(key)-4:] == "null" {
+			// Some writers have a bug where the null is appended without
+			// space.  For example "\Boundsnull"
+			if next, err := lx.peek(0); err == nil && next.Type == TokenName {
+				common.Log.Debug("Taking care of null bug (%s)", key)
+				dict.Set(key[:len(key)-4], MakeNull())
+				continue
+			}
+		}
+
+		val, err := lx.NextObject()
+		if err != nil {
+			return nil, err
+		}
+		dict.Set(key, val)
+	}
+}
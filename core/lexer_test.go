@@ -0,0 +1,242 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLexerTokens(t *testing.T) {
+	type token struct {
+		typ    TokenType
+		offset int64
+		raw    string
+	}
+
+	testcases := []struct {
+		input    string
+		expected []token
+	}{
+		{
+			"1 0 obj\n<< /Type /Catalog >>\nendobj",
+			[]token{
+				{TokenNumber, 0, "1"},
+				{TokenNumber, 2, "0"},
+				{TokenObj, 4, "obj"},
+				{TokenDictStart, 8, "<<"},
+				{TokenName, 11, "/Type"},
+				{TokenName, 17, "/Catalog"},
+				{TokenDictEnd, 26, ">>"},
+				{TokenEndObj, 29, "endobj"},
+			},
+		},
+		{
+			"% comment\r\n[-1.5 +2 .5 3e2 4e (a (b) \\)) <61 62>]",
+			[]token{
+				{TokenComment, 0, "% comment"},
+				{TokenArrayStart, 11, "["},
+				{TokenNumber, 12, "-1.5"},
+				{TokenNumber, 17, "+2"},
+				{TokenNumber, 20, ".5"},
+				{TokenNumber, 23, "3e2"},
+				{TokenNumber, 27, "4"},
+				{TokenKeyword, 28, "e"},
+				{TokenString, 30, "(a (b) \\))"},
+				{TokenHexString, 41, "<61 62>"},
+				{TokenArrayEnd, 48, "]"},
+			},
+		},
+		{
+			"BT /F1 12 Tf (Hi)Tj ET",
+			[]token{
+				{TokenKeyword, 0, "BT"},
+				{TokenName, 3, "/F1"},
+				{TokenNumber, 7, "12"},
+				{TokenKeyword, 10, "Tf"},
+				{TokenString, 13, "(Hi)"},
+				{TokenKeyword, 17, "Tj"},
+				{TokenKeyword, 20, "ET"},
+			},
+		},
+	}
+
+	for _, tcase := range testcases {
+		lexer := NewLexer(strings.NewReader(tcase.input))
+		var tokens []token
+		for {
+			tok, err := lexer.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			tokens = append(tokens, token{tok.Type, tok.Offset, string(tok.Raw)})
+		}
+		require.Equal(t, tcase.expected, tokens, tcase.input)
+	}
+}
+
+func TestLexerTokenValues(t *testing.T) {
+	lexer := NewLexer(strings.NewReader(`/A#20B (x\n\101\
+y) <4 1 4>`))
+
+	tok, err := lexer.Next()
+	require.NoError(t, err)
+	require.Equal(t, PdfObjectName("A B"), tok.Name())
+
+	tok, err = lexer.Next()
+	require.NoError(t, err)
+	require.Equal(t, []byte("x\nAy"), tok.Bytes())
+
+	// Odd number of hex digits is padded with 0.
+	tok, err = lexer.Next()
+	require.NoError(t, err)
+	require.Equal(t, []byte("A@"), tok.Bytes())
+}
+
+func TestLexerReadObject(t *testing.T) {
+	lexer := NewLexer(strings.NewReader(
+		"<< /Kids [1 0 R 2 0 R 3 4] /Flag true /Boundsnull /N 1 >> -1 0 R 5"))
+
+	obj, err := lexer.NextObject()
+	require.NoError(t, err)
+	dict, ok := obj.(*PdfObjectDictionary)
+	require.True(t, ok)
+
+	kids, ok := GetArray(dict.Get("Kids"))
+	require.True(t, ok)
+	require.Equal(t, 4, kids.Len())
+	ref, ok := kids.Get(1).(*PdfObjectReference)
+	require.True(t, ok)
+	require.Equal(t, int64(2), ref.ObjectNumber)
+	_, ok = kids.Get(3).(*PdfObjectInteger)
+	require.True(t, ok)
+
+	flag, ok := GetBoolVal(dict.Get("Flag"))
+	require.True(t, ok)
+	require.True(t, flag)
+
+	_, ok = dict.Get("Bounds").(*PdfObjectNull)
+	require.True(t, ok)
+
+	// Negative numbers are never references.
+	obj, err = lexer.NextObject()
+	require.NoError(t, err)
+	require.Equal(t, MakeInteger(-1), obj)
+	_, err = lexer.NextObject()
+	require.NoError(t, err)
+	_, err = lexer.NextObject()
+	require.Error(t, err)
+
+	obj, err = lexer.NextObject()
+	require.NoError(t, err)
+	val, ok := GetIntVal(obj)
+	require.True(t, ok)
+	require.Equal(t, 5, val)
+
+	_, err = lexer.NextObject()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestLexerStreams(t *testing.T) {
+	data := []byte("<< /Length 5 >>\nstream\r\nabcde\nendstream\n" +
+		"<< >> stream\nendstream inside\nendstream\n" +
+		"<< /Length 3 >> stream\nxyz\nendstream end")
+
+	lexer := NewLexer(bytes.NewReader(data))
+
+	// Read with known length.
+	_, err := lexer.NextObject()
+	require.NoError(t, err)
+	tok, err := lexer.Next()
+	require.NoError(t, err)
+	require.Equal(t, TokenStream, tok.Type)
+	stream, err := lexer.ReadStream(5)
+	require.NoError(t, err)
+	require.Equal(t, []byte("abcde"), stream)
+	tok, err = lexer.Next()
+	require.NoError(t, err)
+	require.Equal(t, TokenEndStream, tok.Type)
+
+	// Data skipped automatically when not read.
+	_, err = lexer.NextObject()
+	require.NoError(t, err)
+	tok, err = lexer.Next()
+	require.NoError(t, err)
+	require.Equal(t, TokenStream, tok.Type)
+	_, err = lexer.ReadStream(-1)
+	require.NoError(t, err)
+	tok, err = lexer.Next()
+	require.NoError(t, err)
+	require.Equal(t, TokenEndStream, tok.Type)
+	tok, err = lexer.Next()
+	require.NoError(t, err)
+	require.True(t, tok.Is("inside"))
+	tok, err = lexer.Next()
+	require.NoError(t, err)
+	require.Equal(t, TokenEndStream, tok.Type)
+
+	// Skipped with known length.
+	_, err = lexer.NextObject()
+	require.NoError(t, err)
+	_, err = lexer.Next()
+	require.NoError(t, err)
+	require.NoError(t, lexer.SkipStream(3))
+	tok, err = lexer.Next()
+	require.NoError(t, err)
+	require.Equal(t, TokenEndStream, tok.Type)
+	require.Equal(t, int64(bytes.LastIndex(data, []byte("endstream"))), tok.Offset)
+
+	tok, err = lexer.Next()
+	require.NoError(t, err)
+	require.True(t, tok.Is("end"))
+
+	// Not positioned at stream data.
+	require.Equal(t, ErrStreamState, lexer.SkipStream(-1))
+}
+
+func TestLexerLargeInput(t *testing.T) {
+	// Tokens spanning buffer boundaries and stream data larger than the buffer.
+	var buf bytes.Buffer
+	for i := 0; i < 5000; i++ {
+		buf.WriteString("/Name12345 123.456 ")
+	}
+	buf.WriteString("<< >>\nstream\n")
+	buf.Write(bytes.Repeat([]byte{'x'}, 3*lexerBufSize))
+	buf.WriteString("\nendstream\n(")
+	buf.WriteString(strings.Repeat("s", 2*lexerBufSize))
+	buf.WriteString(")")
+
+	lexer := NewLexer(&buf)
+	for i := 0; i < 5000; i++ {
+		tok, err := lexer.Next()
+		require.NoError(t, err)
+		require.Equal(t, PdfObjectName("Name12345"), tok.Name())
+		tok, err = lexer.Next()
+		require.NoError(t, err)
+		require.Equal(t, int64(i*19+11), tok.Offset)
+		val, ok := tok.Float()
+		require.True(t, ok)
+		require.Equal(t, 123.456, val)
+	}
+
+	_, err := lexer.NextObject()
+	require.NoError(t, err)
+	_, err = lexer.Next()
+	require.NoError(t, err)
+	tok, err := lexer.Next()
+	require.NoError(t, err)
+	require.Equal(t, TokenEndStream, tok.Type)
+
+	tok, err = lexer.Next()
+	require.NoError(t, err)
+	require.Equal(t, TokenString, tok.Type)
+	require.Len(t, tok.Bytes(), 2*lexerBufSize)
+}
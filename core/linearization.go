@@ -0,0 +1,768 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/unidoc/unipdf/v3/internal/jbig2/reader"
+	"github.com/unidoc/unipdf/v3/internal/jbig2/writer"
+)
+
+// LinearizationParams represents the linearization parameter dictionary of a linearized document
+// (Annex F.2 Linearization parameter dictionary). The dictionary is the first object of the file.
+//
+// Unless noted otherwise, the offsets are actual byte offsets in the file.
+type LinearizationParams struct {
+	// Version of the linearization (/Linearized).
+	Version float64
+
+	// FileLength is the length of the entire file in bytes (/L).
+	FileLength int64
+
+	// HintOffset and HintLength locate the primary hint stream (/H).
+	HintOffset int64
+	HintLength int64
+
+	// OverflowHintOffset and OverflowHintLength locate the overflow hint stream, if any (/H).
+	OverflowHintOffset int64
+	OverflowHintLength int64
+
+	// FirstPageObject is the object number of the page object of the first page (/O).
+	FirstPageObject int
+
+	// FirstPageEnd is the offset of the end of the first page section (/E).
+	FirstPageEnd int64
+
+	// NumPages is the number of pages in the document (/N).
+	NumPages int
+
+	// MainXrefOffset is the offset of the white-space character preceding the first entry of the
+	// main cross-reference table, or the offset of the main cross-reference stream (/T).
+	MainXrefOffset int64
+
+	// FirstPage is the zero-based number of the first page section page (/P).
+	FirstPage int
+}
+
+// HintTables represents the hint tables of the primary hint stream of a linearized document
+// (Annex F.4 Hint tables). Offsets in hint tables are computed as if the primary hint stream was
+// not present in the file.
+type HintTables struct {
+	PageOffsets   PageOffsetHintTable
+	SharedObjects SharedObjectHintTable
+}
+
+// PageOffsetHintTable represents the page offset hint table (Annex F.4.1).
+type PageOffsetHintTable struct {
+	// FirstPageOffset is the location of the page object of the first page.
+	FirstPageOffset int64
+
+	// Pages contains an entry for each page of the document.
+	Pages []PageOffsetHint
+}
+
+// PageOffsetHint is the entry of a page in the page offset hint table. The objects of a page are
+// contiguous and start with the page object, the pages following each other in the file.
+type PageOffsetHint struct {
+	// NumObjects is the number of objects of the page.
+	NumObjects int
+
+	// Length is the length of the page in bytes.
+	Length int64
+
+	// SharedObjects lists the identifiers of the shared object groups referenced by the page, i.e.
+	// indices in the shared object hint table. Empty for the first page.
+	SharedObjects []int
+
+	// ContentOffset is the offset of the content stream relative to the beginning of the page and
+	// ContentLength its length.
+	ContentOffset int64
+	ContentLength int64
+}
+
+// SharedObjectHintTable represents the shared object hint table (Annex F.4.2).
+type SharedObjectHintTable struct {
+	// FirstObjectNumber and FirstObjectOffset locate the first object of the shared objects section.
+	FirstObjectNumber int
+	FirstObjectOffset int64
+
+	// NumFirstPageEntries is the number of leading groups that are located in the first page
+	// section, the groups following them being located in the shared objects section.
+	NumFirstPageEntries int
+
+	// Groups contains the shared object groups, in file order.
+	Groups []SharedObjectHint
+}
+
+// SharedObjectHint is the entry of a group of shared objects in the shared object hint table.
+type SharedObjectHint struct {
+	// Length is the length of the group in bytes.
+	Length int64
+
+	// NumObjects is the number of objects in the group.
+	NumObjects int
+
+	// Signature is the MD5 signature of the group, if any.
+	Signature []byte
+}
+
+// hintTableItem is an item of a hint table: a sequence of values of `bits` bits each, starting at a
+// byte boundary.
+type hintTableItem struct {
+	bits   int
+	values []uint64
+}
+
+// bitsNeeded returns the number of bits needed to represent `v`.
+func bitsNeeded(v int64) int {
+	n := 0
+	for ; v > 0; v >>= 1 {
+		n++
+	}
+	return n
+}
+
+// minMaxInt64 returns the minimum and maximum values of `n` values returned by `get`.
+func minMaxInt64(n int, get func(i int) int64) (int64, int64) {
+	if n == 0 {
+		return 0, 0
+	}
+	min, max := get(0), get(0)
+	for i := 1; i < n; i++ {
+		v := get(i)
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	return min, max
+}
+
+// deltaItem returns the hint table item containing `n` values returned by `get`, relative to
+// `min`, and using the number of bits needed for `max` - `min`.
+func deltaItem(n int, get func(i int) int64, min, max int64) hintTableItem {
+	item := hintTableItem{bits: bitsNeeded(max - min)}
+	for i := 0; i < n; i++ {
+		item.values = append(item.values, uint64(get(i)-min))
+	}
+	return item
+}
+
+// headerItem returns a single value hint table item.
+func headerItem(bits int, v int64) hintTableItem {
+	return hintTableItem{bits: bits, values: []uint64{uint64(v)}}
+}
+
+// encodeHintTableItems returns the bit stream of `items`.
+func encodeHintTableItems(items []hintTableItem) ([]byte, error) {
+	size := 0
+	for _, item := range items {
+		size += (item.bits*len(item.values) + 7) / 8
+	}
+	w := writer.NewMSB(make([]byte, size))
+	for _, item := range items {
+		for _, v := range item.values {
+			if _, err := w.WriteBits(v, item.bits); err != nil {
+				return nil, err
+			}
+		}
+		w.FinishByte()
+	}
+	return w.Data(), nil
+}
+
+// Encode returns the content of the primary hint stream for the hint tables, along with the offset
+// of the shared object hint table within it (/S entry of the hint stream).
+func (tables *HintTables) Encode() ([]byte, int64, error) {
+	pageItems := tables.PageOffsets.items()
+	pageData, err := encodeHintTableItems(pageItems)
+	if err != nil {
+		return nil, 0, err
+	}
+	sharedData, err := encodeHintTableItems(tables.SharedObjects.items())
+	if err != nil {
+		return nil, 0, err
+	}
+	return append(pageData, sharedData...), int64(len(pageData)), nil
+}
+
+// items returns the items of the page offset hint table (Table F.3 and F.4).
+func (table *PageOffsetHintTable) items() []hintTableItem {
+	pages := table.Pages
+	n := len(pages)
+	numObjects := func(i int) int64 { return int64(pages[i].NumObjects) }
+	length := func(i int) int64 { return pages[i].Length }
+	contentOffset := func(i int) int64 { return pages[i].ContentOffset }
+	contentLength := func(i int) int64 { return pages[i].ContentLength }
+	numShared := func(i int) int64 { return int64(len(pages[i].SharedObjects)) }
+
+	minObjects, maxObjects := minMaxInt64(n, numObjects)
+	minLength, maxLength := minMaxInt64(n, length)
+	minContentOffset, maxContentOffset := minMaxInt64(n, contentOffset)
+	minContentLength, maxContentLength := minMaxInt64(n, contentLength)
+	_, maxShared := minMaxInt64(n, numShared)
+
+	var sharedIDs []uint64
+	var maxSharedID int64
+	for _, page := range pages {
+		for _, id := range page.SharedObjects {
+			sharedIDs = append(sharedIDs, uint64(id))
+			if int64(id) > maxSharedID {
+				maxSharedID = int64(id)
+			}
+		}
+	}
+
+	objectsItem := deltaItem(n, numObjects, minObjects, maxObjects)
+	lengthItem := deltaItem(n, length, minLength, maxLength)
+	sharedItem := deltaItem(n, numShared, 0, maxShared)
+	contentOffsetItem := deltaItem(n, contentOffset, minContentOffset, maxContentOffset)
+	contentLengthItem := deltaItem(n, contentLength, minContentLength, maxContentLength)
+	sharedIDsItem := hintTableItem{bits: bitsNeeded(maxSharedID), values: sharedIDs}
+	// Fractional positions of shared object references are not used (0 bits).
+	numeratorsItem := hintTableItem{}
+
+	return []hintTableItem{
+		// Header.
+		headerItem(32, minObjects),
+		headerItem(32, table.FirstPageOffset),
+		headerItem(16, int64(objectsItem.bits)),
+		headerItem(32, minLength),
+		headerItem(16, int64(lengthItem.bits)),
+		headerItem(32, minContentOffset),
+		headerItem(16, int64(contentOffsetItem.bits)),
+		headerItem(32, minContentLength),
+		headerItem(16, int64(contentLengthItem.bits)),
+		headerItem(16, int64(sharedItem.bits)),
+		headerItem(16, int64(sharedIDsItem.bits)),
+		headerItem(16, 0),
+		headerItem(16, 1),
+		// Page entries, grouped by item.
+		objectsItem,
+		lengthItem,
+		sharedItem,
+		sharedIDsItem,
+		numeratorsItem,
+		contentOffsetItem,
+		contentLengthItem,
+	}
+}
+
+// items returns the items of the shared object hint table (Table F.5 and F.6).
+func (table *SharedObjectHintTable) items() []hintTableItem {
+	groups := table.Groups
+	n := len(groups)
+	length := func(i int) int64 { return groups[i].Length }
+	numObjects := func(i int) int64 { return int64(groups[i].NumObjects - 1) }
+
+	minLength, maxLength := minMaxInt64(n, length)
+	_, maxObjects := minMaxInt64(n, numObjects)
+	lengthItem := deltaItem(n, length, minLength, maxLength)
+	objectsItem := deltaItem(n, numObjects, 0, maxObjects)
+
+	flagsItem := hintTableItem{bits: 1}
+	signaturesItem := hintTableItem{bits: 8}
+	for _, group := range groups {
+		if len(group.Signature) == 16 {
+			flagsItem.values = append(flagsItem.values, 1)
+			for _, b := range group.Signature {
+				signaturesItem.values = append(signaturesItem.values, uint64(b))
+			}
+		} else {
+			flagsItem.values = append(flagsItem.values, 0)
+		}
+	}
+
+	return []hintTableItem{
+		// Header.
+		headerItem(32, int64(table.FirstObjectNumber)),
+		headerItem(32, table.FirstObjectOffset),
+		headerItem(32, int64(table.NumFirstPageEntries)),
+		headerItem(32, int64(n)),
+		headerItem(16, int64(objectsItem.bits)),
+		headerItem(32, minLength),
+		headerItem(16, int64(lengthItem.bits)),
+		// Group entries, grouped by item.
+		lengthItem,
+		flagsItem,
+		signaturesItem,
+		objectsItem,
+	}
+}
+
+// maxHintTableEntries limits the number of entries of decoded hint tables.
+const maxHintTableEntries = 1 << 22
+
+// hintTableReader reads the items of a hint table.
+type hintTableReader struct {
+	r   *reader.Reader
+	err error
+}
+
+// read reads a value of `bits` bits.
+func (hr *hintTableReader) read(bits int) int64 {
+	if hr.err != nil || bits == 0 {
+		return 0
+	}
+	if bits > 32 {
+		hr.err = fmt.Errorf("invalid hint table item size: %d bits", bits)
+		return 0
+	}
+	v, err := hr.r.ReadBits(byte(bits))
+	if err != nil {
+		hr.err = err
+		return 0
+	}
+	return int64(v)
+}
+
+// readItem reads `n` values of `bits` bits, starting at a byte boundary.
+func (hr *hintTableReader) readItem(n, bits int) []int64 {
+	values := make([]int64, n)
+	for i := range values {
+		values[i] = hr.read(bits)
+	}
+	hr.r.Align()
+	return values
+}
+
+// DecodeHintTables decodes the content `data` of the primary hint stream of a document of `numPages`
+// pages, `sharedOffset` being the offset of the shared object hint table (/S entry of the stream).
+func DecodeHintTables(data []byte, sharedOffset int64, numPages int) (*HintTables, error) {
+	if sharedOffset < 0 || sharedOffset > int64(len(data)) {
+		return nil, errors.New("shared object hint table offset out of range")
+	}
+	if numPages < 0 || numPages > maxHintTableEntries {
+		return nil, errors.New("invalid number of pages")
+	}
+	tables := &HintTables{}
+
+	// Page offset hint table.
+	hr := &hintTableReader{r: reader.New(data[:sharedOffset])}
+	minObjects := hr.read(32)
+	tables.PageOffsets.FirstPageOffset = hr.read(32)
+	objectsBits := int(hr.read(16))
+	minLength := hr.read(32)
+	lengthBits := int(hr.read(16))
+	minContentOffset := hr.read(32)
+	contentOffsetBits := int(hr.read(16))
+	minContentLength := hr.read(32)
+	contentLengthBits := int(hr.read(16))
+	sharedBits := int(hr.read(16))
+	sharedIDBits := int(hr.read(16))
+	numeratorBits := int(hr.read(16))
+	hr.read(16) // Denominator.
+	if hr.err != nil {
+		return nil, fmt.Errorf("invalid page offset hint table header: %v", hr.err)
+	}
+
+	numObjects := hr.readItem(numPages, objectsBits)
+	lengths := hr.readItem(numPages, lengthBits)
+	numShared := hr.readItem(numPages, sharedBits)
+	var totalShared int64
+	for _, n := range numShared {
+		totalShared += n
+	}
+	if hr.err != nil || totalShared > maxHintTableEntries {
+		return nil, errors.New("invalid page offset hint table")
+	}
+	sharedIDs := hr.readItem(int(totalShared), sharedIDBits)
+	hr.readItem(int(totalShared), numeratorBits)
+	contentOffsets := hr.readItem(numPages, contentOffsetBits)
+	contentLengths := hr.readItem(numPages, contentLengthBits)
+	if hr.err != nil {
+		return nil, fmt.Errorf("invalid page offset hint table: %v", hr.err)
+	}
+
+	for i := 0; i < numPages; i++ {
+		page := PageOffsetHint{
+			NumObjects:    int(minObjects + numObjects[i]),
+			Length:        minLength + lengths[i],
+			ContentOffset: minContentOffset + contentOffsets[i],
+			ContentLength: minContentLength + contentLengths[i],
+		}
+		for j := int64(0); j < numShared[i]; j++ {
+			page.SharedObjects = append(page.SharedObjects, int(sharedIDs[0]))
+			sharedIDs = sharedIDs[1:]
+		}
+		tables.PageOffsets.Pages = append(tables.PageOffsets.Pages, page)
+	}
+
+	// Shared object hint table.
+	shared := &tables.SharedObjects
+	hr = &hintTableReader{r: reader.New(data[sharedOffset:])}
+	shared.FirstObjectNumber = int(hr.read(32))
+	shared.FirstObjectOffset = hr.read(32)
+	shared.NumFirstPageEntries = int(hr.read(32))
+	numGroups := hr.read(32)
+	groupObjectsBits := int(hr.read(16))
+	minGroupLength := hr.read(32)
+	groupLengthBits := int(hr.read(16))
+	if hr.err != nil {
+		return nil, fmt.Errorf("invalid shared object hint table header: %v", hr.err)
+	}
+	if numGroups > maxHintTableEntries || int64(shared.NumFirstPageEntries) > numGroups {
+		return nil, errors.New("invalid number of shared object groups")
+	}
+
+	n := int(numGroups)
+	groupLengths := hr.readItem(n, groupLengthBits)
+	flags := hr.readItem(n, 1)
+	var numSignatures int
+	for _, flag := range flags {
+		numSignatures += int(flag)
+	}
+	signatures := hr.readItem(16*numSignatures, 8)
+	groupObjects := hr.readItem(n, groupObjectsBits)
+	if hr.err != nil {
+		return nil, fmt.Errorf("invalid shared object hint table: %v", hr.err)
+	}
+
+	for i := 0; i < n; i++ {
+		group := SharedObjectHint{
+			Length:     minGroupLength + groupLengths[i],
+			NumObjects: int(groupObjects[i]) + 1,
+		}
+		if flags[i] == 1 {
+			group.Signature = make([]byte, 16)
+			for j := range group.Signature {
+				group.Signature[j] = byte(signatures[j])
+			}
+			signatures = signatures[16:]
+		}
+		shared.Groups = append(shared.Groups, group)
+	}
+
+	for _, page := range tables.PageOffsets.Pages {
+		for _, id := range page.SharedObjects {
+			if id >= n {
+				return nil, fmt.Errorf("shared object identifier %d out of range", id)
+			}
+		}
+	}
+	return tables, nil
+}
+
+// GetLinearizationParams returns the linearization parameters of the document, or nil if the
+// document is not linearized. The parameters are returned even if the document was modified by
+// incremental updates after being linearized (see IsLinearized).
+func (parser *PdfParser) GetLinearizationParams() *LinearizationParams {
+	parser.lock.Lock()
+	defer parser.lock.Unlock()
+
+	if !parser.linearizationLoaded {
+		parser.linearizationLoaded = true
+		params, err := parser.loadLinearizationParams()
+		if err != nil {
+			parser.log().Debug("Invalid linearization parameters: %v", err)
+		}
+		parser.linearization = params
+	}
+	return parser.linearization
+}
+
+// IsLinearized returns true if the document is linearized, i.e. it starts with a linearization
+// parameter dictionary and was not modified since it was linearized.
+func (parser *PdfParser) IsLinearized() bool {
+	params := parser.GetLinearizationParams()
+	return params != nil && params.FileLength == parser.fileSize
+}
+
+// loadLinearizationParams loads the linearization parameter dictionary, which is required to be
+// contained in the first 1024 bytes of the file. Returns nil if it is not present.
+func (parser *PdfParser) loadLinearizationParams() (*LinearizationParams, error) {
+	data, err := parser.readBytesAt(0, minInt64(1024, parser.fileSize))
+	if err != nil {
+		return nil, err
+	}
+	start := bytes.Index(data, []byte("%PDF-"))
+	if start < 0 {
+		return nil, nil
+	}
+
+	// First indirect object, following the header.
+	lexer := NewLexer(bytes.NewReader(data[start:]))
+	var toks []TokenType
+	for len(toks) < 3 {
+		tok, err := lexer.Next()
+		if err != nil {
+			return nil, nil
+		}
+		if tok.Type != TokenComment {
+			toks = append(toks, tok.Type)
+		}
+	}
+	if toks[0] != TokenNumber || toks[1] != TokenNumber || toks[2] != TokenObj {
+		return nil, nil
+	}
+	obj, err := lexer.NextObject()
+	if err != nil {
+		return nil, nil
+	}
+	dict, ok := obj.(*PdfObjectDictionary)
+	if !ok || dict.Get("Linearized") == nil {
+		return nil, nil
+	}
+
+	params := &LinearizationParams{}
+	params.Version, err = GetNumberAsFloat(dict.Get("Linearized"))
+	if err != nil {
+		return nil, errors.New("invalid Linearized entry")
+	}
+	intVal := func(key PdfObjectName, required bool) (int64, error) {
+		val, ok := GetIntVal(dict.Get(key))
+		if !ok && required {
+			return 0, fmt.Errorf("missing or invalid %s entry", key)
+		}
+		return int64(val), nil
+	}
+	var vals [6]int64
+	for i, key := range []PdfObjectName{"L", "O", "E", "N", "T", "P"} {
+		if vals[i], err = intVal(key, key != "P"); err != nil {
+			return nil, err
+		}
+	}
+	params.FileLength = vals[0]
+	params.FirstPageObject = int(vals[1])
+	params.FirstPageEnd = vals[2]
+	params.NumPages = int(vals[3])
+	params.MainXrefOffset = vals[4]
+	params.FirstPage = int(vals[5])
+
+	hint, ok := GetArray(dict.Get("H"))
+	if !ok || (hint.Len() != 2 && hint.Len() != 4) {
+		return nil, errors.New("missing or invalid H entry")
+	}
+	hintVals, err := hint.ToInt64Slice()
+	if err != nil {
+		return nil, errors.New("invalid H entry")
+	}
+	params.HintOffset, params.HintLength = hintVals[0], hintVals[1]
+	if len(hintVals) == 4 {
+		params.OverflowHintOffset, params.OverflowHintLength = hintVals[2], hintVals[3]
+	}
+	return params, nil
+}
+
+// GetHintTables loads and decodes the hint tables of the primary hint stream of a linearized
+// document.
+func (parser *PdfParser) GetHintTables() (*HintTables, error) {
+	params := parser.GetLinearizationParams()
+	if params == nil {
+		return nil, errors.New("document is not linearized")
+	}
+
+	parser.lock.Lock()
+	defer parser.lock.Unlock()
+
+	if params.HintOffset <= 0 || params.HintOffset >= parser.fileSize {
+		return nil, errors.New("hint stream offset out of range")
+	}
+	parser.rs.Seek(params.HintOffset, io.SeekStart)
+	parser.reader = bufio.NewReader(parser.rs)
+	obj, err := parser.ParseIndirectObject()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read hint stream: %v", err)
+	}
+	stream, ok := obj.(*PdfObjectStream)
+	if !ok {
+		return nil, errors.New("hint stream is not a stream object")
+	}
+	if parser.crypter != nil {
+		if !parser.crypter.authenticated {
+			return nil, errors.New("document needs to be decrypted first")
+		}
+		if err := parser.crypter.Decrypt(stream, 0, 0); err != nil {
+			return nil, err
+		}
+		// The hint stream is not cached, do not retain it.
+		delete(parser.crypter.decryptedObjects, stream)
+	}
+
+	data, err := DecodeStream(stream)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode hint stream: %v", err)
+	}
+	sharedOffset, ok := GetIntVal(stream.Get("S"))
+	if !ok {
+		return nil, errors.New("hint stream missing S entry")
+	}
+	return DecodeHintTables(data, int64(sharedOffset), params.NumPages)
+}
+
+// ValidateLinearization checks that the document is linearized and that its hint tables match the
+// layout of the file: the pages and shared object groups are located at object boundaries and contain
+// the indicated number of objects. If `pageObjNums` is not nil, it lists the object numbers of the
+// page objects in page order, and the pages located by the hint tables are checked against it.
+// The first inconsistency found is returned as an error.
+func (parser *PdfParser) ValidateLinearization(pageObjNums []int) error {
+	params := parser.GetLinearizationParams()
+	if params == nil {
+		return errors.New("document is not linearized")
+	}
+	if params.FileLength != parser.fileSize {
+		return fmt.Errorf("file length %d does not match linearized length %d (document updated)",
+			parser.fileSize, params.FileLength)
+	}
+	tables, err := parser.GetHintTables()
+	if err != nil {
+		return err
+	}
+	pages := tables.PageOffsets.Pages
+	if len(pages) != params.NumPages {
+		return fmt.Errorf("page offset hint table has %d entries, expected %d", len(pages), params.NumPages)
+	}
+	if pageObjNums != nil && len(pageObjNums) != params.NumPages {
+		return fmt.Errorf("document has %d pages, linearization parameters indicate %d",
+			len(pageObjNums), params.NumPages)
+	}
+
+	parser.lock.Lock()
+	defer parser.lock.Unlock()
+
+	// Offsets in hint tables do not account for the hint stream.
+	hintEnd := params.HintOffset + params.HintLength
+	hintFree := func(offset int64) int64 {
+		if offset >= hintEnd {
+			return offset - params.HintLength
+		}
+		return offset
+	}
+
+	var offsets []int64
+	objAt := map[int64]int{}
+	objStreams := map[int]bool{}
+	for objNum, xref := range parser.xrefs.ObjectMap {
+		if xref.XType == XrefTypeObjectStream {
+			objStreams[xref.OsObjNumber] = true
+		}
+		if xref.XType != XrefTypeTableEntry {
+			continue
+		}
+		if xref.Offset >= params.HintOffset && xref.Offset < hintEnd {
+			continue
+		}
+		offset := hintFree(xref.Offset)
+		offsets = append(offsets, offset)
+		objAt[offset] = objNum
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	countObjects := func(start, end int64) int {
+		i := sort.Search(len(offsets), func(i int) bool { return offsets[i] >= start })
+		j := sort.Search(len(offsets), func(i int) bool { return offsets[i] >= end })
+		return j - i
+	}
+	// lastOffset returns the index of the last object offset before `end`.
+	lastOffset := func(end int64) int {
+		return sort.Search(len(offsets), func(i int) bool { return offsets[i] >= end }) - 1
+	}
+
+	// Pages. Some writers locate the first page at the start of the first page section, which may
+	// contain objects preceding the page object.
+	firstPage, ok := parser.xrefs.ObjectMap[params.FirstPageObject]
+	if !ok || len(pages) == 0 {
+		return fmt.Errorf("first page object %d not found", params.FirstPageObject)
+	}
+	firstPageOffset := hintFree(firstPage.Offset)
+	start := tables.PageOffsets.FirstPageOffset
+	if firstPageOffset < start || firstPageOffset >= start+pages[0].Length {
+		return fmt.Errorf("first page object %d is not located in the first page at offset %d",
+			params.FirstPageObject, start)
+	}
+	if pageObjNums != nil && pageObjNums[0] != params.FirstPageObject {
+		return fmt.Errorf("page 1: first page object %d is not the page object %d",
+			params.FirstPageObject, pageObjNums[0])
+	}
+	for i, page := range pages {
+		objNum, ok := objAt[start]
+		if !ok {
+			return fmt.Errorf("page %d: no object at offset %d", i+1, start)
+		}
+		if i > 0 && pageObjNums != nil && pageObjNums[i] != objNum {
+			return fmt.Errorf("page %d: object %d located by hint table is not the page object %d",
+				i+1, objNum, pageObjNums[i])
+		}
+		n := countObjects(start, start+page.Length)
+		if i == 0 && n == page.NumObjects+1 && objStreams[objAt[offsets[lastOffset(start+page.Length)]]] {
+			// The object stream ending the first page section is not counted by some writers.
+			n--
+		}
+		if n != page.NumObjects {
+			return fmt.Errorf("page %d: %d objects found, hint table indicates %d", i+1, n, page.NumObjects)
+		}
+		if page.ContentOffset+page.ContentLength > page.Length {
+			return fmt.Errorf("page %d: content stream exceeds page length", i+1)
+		}
+		if i == 0 {
+			// The end of the first page section is an actual offset.
+			end := start + page.Length
+			if end != params.FirstPageEnd && end+params.HintLength != params.FirstPageEnd {
+				return fmt.Errorf("end of first page %d does not match linearization parameter %d",
+					end, params.FirstPageEnd)
+			}
+		}
+		start += page.Length
+	}
+
+	// Shared object groups.
+	shared := tables.SharedObjects
+	start = tables.PageOffsets.FirstPageOffset
+	for i, group := range shared.Groups {
+		if i == shared.NumFirstPageEntries {
+			first, ok := parser.xrefs.ObjectMap[shared.FirstObjectNumber]
+			if !ok || hintFree(first.Offset) != shared.FirstObjectOffset {
+				return fmt.Errorf("first shared object %d is not located at offset %d",
+					shared.FirstObjectNumber, shared.FirstObjectOffset)
+			}
+			start = shared.FirstObjectOffset
+		}
+		if _, ok := objAt[start]; !ok {
+			return fmt.Errorf("shared object group %d: no object at offset %d", i, start)
+		}
+		if n := countObjects(start, start+group.Length); n != group.NumObjects {
+			return fmt.Errorf("shared object group %d: %d objects found, hint table indicates %d",
+				i, n, group.NumObjects)
+		}
+		start += group.Length
+	}
+
+	// Main cross-reference section.
+	if err := parser.validateMainXrefOffset(params.MainXrefOffset); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateMainXrefOffset checks that `offset` (/T linearization parameter) points to the main
+// cross-reference section, i.e. the white-space preceding the first entry of the main cross-reference
+// table, or the main cross-reference stream or the white-space preceding it (as written by Acrobat).
+func (parser *PdfParser) validateMainXrefOffset(offset int64) error {
+	if offset < 0 || offset >= parser.fileSize {
+		return fmt.Errorf("main cross-reference offset %d out of range", offset)
+	}
+	bb, err := parser.readBytesAt(offset, minInt64(19, parser.fileSize-offset))
+	if err != nil {
+		return err
+	}
+	if prev, ok := GetIntVal(parser.trailer.Get("Prev")); ok {
+		// Cross-reference stream.
+		if int64(prev) == offset || (int64(prev) == offset+1 && IsWhiteSpace(bb[0])) {
+			return nil
+		}
+	}
+	if len(bb) == 19 && IsWhiteSpace(bb[0]) && reXrefEntry.Match(bb[1:]) {
+		return nil
+	}
+	return fmt.Errorf("main cross-reference section not found at offset %d", offset)
+}
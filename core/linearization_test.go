@@ -0,0 +1,77 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHintTablesEncodeDecode(t *testing.T) {
+	tables := &HintTables{
+		PageOffsets: PageOffsetHintTable{
+			FirstPageOffset: 1234,
+			Pages: []PageOffsetHint{
+				{NumObjects: 7, Length: 5000, ContentOffset: 300, ContentLength: 2000},
+				{NumObjects: 2, Length: 800, SharedObjects: []int{1, 7}, ContentOffset: 120, ContentLength: 600},
+				{NumObjects: 3, Length: 1500, SharedObjects: []int{7, 8}},
+			},
+		},
+		SharedObjects: SharedObjectHintTable{
+			FirstObjectNumber:   12,
+			FirstObjectOffset:   9000,
+			NumFirstPageEntries: 7,
+			Groups: []SharedObjectHint{
+				{Length: 100, NumObjects: 1}, {Length: 200, NumObjects: 1}, {Length: 50, NumObjects: 1},
+				{Length: 70, NumObjects: 1}, {Length: 3000, NumObjects: 1}, {Length: 10, NumObjects: 1},
+				{Length: 400, NumObjects: 1}, {Length: 120, NumObjects: 2},
+				{Length: 330, NumObjects: 1, Signature: []byte("0123456789abcdef")},
+			},
+		},
+	}
+
+	data, sharedOffset, err := tables.Encode()
+	require.NoError(t, err)
+	require.True(t, sharedOffset > 0 && sharedOffset < int64(len(data)))
+
+	decoded, err := DecodeHintTables(data, sharedOffset, 3)
+	require.NoError(t, err)
+	require.Equal(t, tables, decoded)
+
+	// Truncated data.
+	_, err = DecodeHintTables(data[:sharedOffset+4], sharedOffset, 3)
+	require.Error(t, err)
+	_, err = DecodeHintTables(data, int64(len(data)+1), 3)
+	require.Error(t, err)
+}
+
+func TestLinearizationParams(t *testing.T) {
+	parser := makeParserForText(`%PDF-1.7
+%âãÏÓ
+5 0 obj
+<< /Linearized 1 /L 100 /H [ 200 50 ] /O 7 /E 300 /N 2 /T 400 >>
+endobj
+`)
+	params := parser.GetLinearizationParams()
+	require.NotNil(t, params)
+	require.Equal(t, LinearizationParams{
+		Version:         1,
+		FileLength:      100,
+		HintOffset:      200,
+		HintLength:      50,
+		FirstPageObject: 7,
+		FirstPageEnd:    300,
+		NumPages:        2,
+		MainXrefOffset:  400,
+	}, *params)
+	// File length differs from L.
+	require.False(t, parser.IsLinearized())
+
+	parser = makeParserForText("%PDF-1.7\n1 0 obj\n<< /Type /Catalog >>\nendobj\n")
+	require.Nil(t, parser.GetLinearizationParams())
+	require.False(t, parser.IsLinearized())
+}
@@ -17,6 +17,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/unidoc/unipdf/v3/common"
 	"github.com/unidoc/unipdf/v3/core/security"
@@ -35,9 +36,20 @@ var reXrefSubsection = regexp.MustCompile(`(\d+)\s+(\d+)\s*$`)
 var reXrefEntry = regexp.MustCompile(`(\d+)\s+(\d+)\s+([nf])\s*$`)
 
 // PdfParser parses a PDF file and provides access to the object structure of the PDF.
+//
+// Object lookups (LookupByNumber, LookupByReference, Resolve and resolving references of loaded
+// objects) are safe for concurrent use. When the underlying reader implements io.ReaderAt, objects
+// defined by cross-reference table entries are parsed concurrently, each from its own section of the
+// file; otherwise, and for objects contained in object streams, lookups are serialized. The
+// low-level parsing functions operating at the current file offset (ParseIndirectObject, ParseDict,
+// GetFileOffset, SetFileOffset, ReadAtLeast) are not safe for concurrent use.
 type PdfParser struct {
 	version Version
 
+	// Guards the reader state, the cross-reference table and the object cache for concurrent
+	// object lookups.
+	lock sync.Mutex
+
 	rs               io.ReadSeeker
 	reader           *bufio.Reader
 	fileSize         int64
@@ -48,8 +60,28 @@ type PdfParser struct {
 	trailer          *PdfObjectDictionary
 	crypter          *PdfCrypt
 	repairsAttempted bool // Avoid multiple attempts for repair.
+	repairs          []RepairWarning
+
+	// Logger receiving the log messages and the diagnostics of the parser. The global common.Log is
+	// used if nil.
+	logger common.Logger
+
+	// Revisions of the document (incremental updates), ordered from oldest to newest.
+	revisions []*PdfRevision
+	// Revision whose cross-reference section is being parsed.
+	xrefSection *PdfRevision
 
 	ObjCache objectCache
+	// Usage of the cached objects, when the cache is bounded (nil otherwise).
+	cache *objectCacheLRU
+
+	// Linearization parameters, loaded on first use.
+	linearization       *LinearizationParams
+	linearizationLoaded bool
+
+	// Parser looking up the object parsed, when the parser only parses a single object from its own
+	// section of the file (see lookupConcurrent). Shared state is accessed through the parent.
+	parent *PdfParser
 
 	// Tracker for reference lookups when looking up Length entry of stream objects.
 	// The Length entries of stream objects are a special case, as they can require recursive parsing, i.e. look up
@@ -134,7 +166,7 @@ func (parser *PdfParser) skipComments() error {
 	for {
 		bb, err := parser.reader.Peek(1)
 		if err != nil {
-			common.Log.Debug("Error %s", err.Error())
+			parser.log().Debug("Error %s", err.Error())
 			return err
 		}
 
@@ -168,7 +200,7 @@ func (parser *PdfParser) readComment() (string, error) {
 	for {
 		bb, err := parser.reader.Peek(1)
 		if err != nil {
-			common.Log.Debug("Error %s", err.Error())
+			parser.log().Debug("Error %s", err.Error())
 			return r.String(), err
 		}
 		if isFirst && bb[0] != '%' {
@@ -192,7 +224,7 @@ func (parser *PdfParser) readTextLine() (string, error) {
 	for {
 		bb, err := parser.reader.Peek(1)
 		if err != nil {
-			common.Log.Debug("Error %s", err.Error())
+			parser.log().Debug("Error %s", err.Error())
 			return r.String(), err
 		}
 		if (bb[0] != '\r') && (bb[0] != '\n') {
@@ -227,7 +259,7 @@ func (parser *PdfParser) parseName() (PdfObjectName, error) {
 				parser.readComment()
 				parser.skipSpaces()
 			} else {
-				common.Log.Debug("ERROR Name starting with %s (% x)", bb, bb)
+				parser.log().Debug("ERROR Name starting with %s (% x)", bb, bb)
 				return PdfObjectName(r.String()), fmt.Errorf("invalid name: (%c)", bb[0])
 			}
 		} else {
@@ -243,7 +275,7 @@ func (parser *PdfParser) parseName() (PdfObjectName, error) {
 
 				code, err := hex.DecodeString(string(hexcode[1:3]))
 				if err != nil {
-					common.Log.Debug("ERROR: Invalid hex following '#', continuing using literal - Output may be incorrect")
+					parser.log().Debug("ERROR: Invalid hex following '#', continuing using literal - Output may be incorrect")
 
 					// Treat as literal '#' rather than hex code.
 					r.WriteByte('#')
@@ -326,7 +358,7 @@ func (parser *PdfParser) parseString() (*PdfObjectString, error) {
 				}
 				parser.reader.Discard(len(numeric) - 1)
 
-				common.Log.Trace("Numeric string \"%s\"", numeric)
+				parser.log().Trace("Numeric string \"%s\"", numeric)
 				code, err := strconv.ParseUint(string(numeric), 8, 32)
 				if err != nil {
 					return MakeString(r.String()), err
@@ -482,7 +514,7 @@ func (parser *PdfParser) parseNull() (PdfObjectNull, error) {
 // Detect the signature at the current file position and parse
 // the corresponding object.
 func (parser *PdfParser) parseObject() (PdfObject, error) {
-	common.Log.Trace("Read direct object")
+	parser.log().Trace("Read direct object")
 	parser.skipSpaces()
 	for {
 		bb, err := parser.reader.Peek(2)
@@ -497,38 +529,38 @@ func (parser *PdfParser) parseObject() (PdfObject, error) {
 			}
 		}
 
-		common.Log.Trace("Peek string: %s", string(bb))
+		parser.log().Trace("Peek string: %s", string(bb))
 		// Determine type.
 		if bb[0] == '/' {
 			name, err := parser.parseName()
-			common.Log.Trace("->Name: '%s'", name)
+			parser.log().Trace("->Name: '%s'", name)
 			return &name, err
 		} else if bb[0] == '(' {
-			common.Log.Trace("->String!")
+			parser.log().Trace("->String!")
 			str, err := parser.parseString()
 			return str, err
 		} else if bb[0] == '[' {
-			common.Log.Trace("->Array!")
+			parser.log().Trace("->Array!")
 			arr, err := parser.parseArray()
 			return arr, err
 		} else if (bb[0] == '<') && (bb[1] == '<') {
-			common.Log.Trace("->Dict!")
+			parser.log().Trace("->Dict!")
 			dict, err := parser.ParseDict()
 			return dict, err
 		} else if bb[0] == '<' {
-			common.Log.Trace("->Hex string!")
+			parser.log().Trace("->Hex string!")
 			str, err := parser.parseHexString()
 			return str, err
 		} else if bb[0] == '%' {
 			parser.readComment()
 			parser.skipSpaces()
 		} else {
-			common.Log.Trace("->Number or ref?")
+			parser.log().Trace("->Number or ref?")
 			// Reference or number?
 			// Let's peek farther to find out.
 			bb, _ = parser.reader.Peek(15)
 			peekStr := string(bb)
-			common.Log.Trace("Peek str: %s", peekStr)
+			parser.log().Trace("Peek str: %s", peekStr)
 
 			if (len(peekStr) > 3) && (peekStr[:4] == "null") {
 				null, err := parser.parseNull()
@@ -545,16 +577,16 @@ func (parser *PdfParser) parseObject() (PdfObject, error) {
 			result1 := reReference.FindStringSubmatch(string(peekStr))
 			if len(result1) > 1 {
 				bb, _ = parser.reader.ReadBytes('R')
-				common.Log.Trace("-> !Ref: '%s'", string(bb[:]))
+				parser.log().Trace("-> !Ref: '%s'", string(bb[:]))
 				ref, err := parseReference(string(bb))
-				ref.parser = parser
+				ref.parser = parser.owner()
 				return &ref, err
 			}
 
 			result2 := reNumeric.FindStringSubmatch(string(peekStr))
 			if len(result2) > 1 {
 				// Number object.
-				common.Log.Trace("-> Number!")
+				parser.log().Trace("-> Number!")
 				num, err := parser.parseNumber()
 				return num, err
 			}
@@ -562,13 +594,13 @@ func (parser *PdfParser) parseObject() (PdfObject, error) {
 			result2 = reExponential.FindStringSubmatch(string(peekStr))
 			if len(result2) > 1 {
 				// Number object (exponential)
-				common.Log.Trace("-> Exponential Number!")
-				common.Log.Trace("% s", result2)
+				parser.log().Trace("-> Exponential Number!")
+				parser.log().Trace("% s", result2)
 				num, err := parser.parseNumber()
 				return num, err
 			}
 
-			common.Log.Debug("ERROR Unknown (peek \"%s\")", peekStr)
+			parser.log().Debug("ERROR Unknown (peek \"%s\")", peekStr)
 			return nil, errors.New("object parsing error - unexpected pattern")
 		}
 	}
@@ -576,10 +608,10 @@ func (parser *PdfParser) parseObject() (PdfObject, error) {
 
 // ParseDict reads and parses a PDF dictionary object enclosed with '<<' and '>>'
 func (parser *PdfParser) ParseDict() (*PdfObjectDictionary, error) {
-	common.Log.Trace("Reading PDF Dict!")
+	parser.log().Trace("Reading PDF Dict!")
 
 	dict := MakeDict()
-	dict.parser = parser
+	dict.parser = parser.owner()
 
 	// Pass the '<<'
 	c, _ := parser.reader.ReadByte()
@@ -600,19 +632,19 @@ func (parser *PdfParser) ParseDict() (*PdfObjectDictionary, error) {
 			return nil, err
 		}
 
-		common.Log.Trace("Dict peek: %s (% x)!", string(bb), string(bb))
+		parser.log().Trace("Dict peek: %s (% x)!", string(bb), string(bb))
 		if (bb[0] == '>') && (bb[1] == '>') {
-			common.Log.Trace("EOF dictionary")
+			parser.log().Trace("EOF dictionary")
 			parser.reader.ReadByte()
 			parser.reader.ReadByte()
 			break
 		}
-		common.Log.Trace("Parse the name!")
+		parser.log().Trace("Parse the name!")
 
 		keyName, err := parser.parseName()
-		common.Log.Trace("Key: %s", keyName)
+		parser.log().Trace("Key: %s", keyName)
 		if err != nil {
-			common.Log.Debug("ERROR Returning name err %s", err)
+			parser.log().Debug("ERROR Returning name err %s", err)
 			return nil, err
 		}
 
@@ -620,8 +652,8 @@ func (parser *PdfParser) ParseDict() (*PdfObjectDictionary, error) {
 			// Some writers have a bug where the null is appended without
 			// space.  For example "\Boundsnull"
 			newKey := keyName[0 : len(keyName)-4]
-			common.Log.Debug("Taking care of null bug (%s)", keyName)
-			common.Log.Debug("New key \"%s\" = null", newKey)
+			parser.log().Debug("Taking care of null bug (%s)", keyName)
+			parser.log().Debug("New key \"%s\" = null", newKey)
 			parser.skipSpaces()
 			bb, _ := parser.reader.Peek(1)
 			if bb[0] == '/' {
@@ -638,12 +670,12 @@ func (parser *PdfParser) ParseDict() (*PdfObjectDictionary, error) {
 		}
 		dict.Set(keyName, val)
 
-		if common.Log.IsLogLevel(common.LogLevelTrace) {
+		if parser.log().IsLogLevel(common.LogLevelTrace) {
 			// Avoid calling unless needed as the String() can be heavy for large objects.
-			common.Log.Trace("dict[%s] = %s", keyName, val.String())
+			parser.log().Trace("dict[%s] = %s", keyName, val.String())
 		}
 	}
-	common.Log.Trace("returning PDF Dict!")
+	parser.log().Trace("returning PDF Dict!")
 
 	return dict, nil
 }
@@ -665,7 +697,7 @@ func (parser *PdfParser) parsePdfVersion() (int, int, error) {
 
 	if match := rePdfVersion.FindStringSubmatch(string(b)); len(match) < 3 {
 		if major, minor, err = parser.seekPdfVersionTopDown(); err != nil {
-			common.Log.Debug("Failed recovery - unable to find version")
+			parser.log().Debug("Failed recovery - unable to find version")
 			return 0, 0, err
 		}
 
@@ -689,7 +721,7 @@ func (parser *PdfParser) parsePdfVersion() (int, int, error) {
 	}
 	parser.reader = bufio.NewReader(parser.rs)
 
-	common.Log.Debug("Pdf version %d.%d", major, minor)
+	parser.log().Debug("Pdf version %d.%d", major, minor)
 	return major, minor, nil
 }
 
@@ -702,7 +734,7 @@ func (parser *PdfParser) parseXrefTable() (*PdfObjectDictionary, error) {
 		return nil, err
 	}
 
-	common.Log.Trace("xref first line: %s", txt)
+	parser.log().Trace("xref first line: %s", txt)
 	curObjNum := -1
 	secObjects := 0
 	insideSubsection := false
@@ -738,13 +770,13 @@ func (parser *PdfParser) parseXrefTable() (*PdfObjectDictionary, error) {
 			secObjects = second
 			insideSubsection = true
 			unmatchedContent = ""
-			common.Log.Trace("xref subsection: first object: %d objects: %d", curObjNum, secObjects)
+			parser.log().Trace("xref subsection: first object: %d objects: %d", curObjNum, secObjects)
 			continue
 		}
 		result2 := reXrefEntry.FindStringSubmatch(txt)
 		if len(result2) == 4 {
 			if insideSubsection == false {
-				common.Log.Debug("ERROR Xref invalid format!\n")
+				parser.log().Debug("ERROR Xref invalid format!\n")
 				return nil, errors.New("xref invalid format")
 			}
 
@@ -753,6 +785,16 @@ func (parser *PdfParser) parseXrefTable() (*PdfObjectDictionary, error) {
 			third := result2[3]
 			unmatchedContent = ""
 
+			if parser.xrefSection != nil {
+				if strings.ToLower(third) == "n" && first > 1 {
+					parser.xrefSection.setEntry(XrefObject{ObjectNumber: curObjNum,
+						XType:  XrefTypeTableEntry,
+						Offset: first, Generation: gen})
+				} else {
+					parser.xrefSection.setFree(curObjNum)
+				}
+			}
+
 			if strings.ToLower(third) == "n" && first > 1 {
 				// Object in use in the file!  Load it.
 				// Ignore free objects ('f').
@@ -782,7 +824,7 @@ func (parser *PdfParser) parseXrefTable() (*PdfObjectDictionary, error) {
 		}
 
 		if (len(txt) > 6) && (txt[:7] == "trailer") {
-			common.Log.Trace("Found trailer - %s", txt)
+			parser.log().Trace("Found trailer - %s", txt)
 			// Sometimes get "trailer << ...."
 			// Need to rewind to end of trailer text.
 			if len(txt) > 9 {
@@ -792,25 +834,28 @@ func (parser *PdfParser) parseXrefTable() (*PdfObjectDictionary, error) {
 
 			parser.skipSpaces()
 			parser.skipComments()
-			common.Log.Trace("Reading trailer dict!")
-			common.Log.Trace("peek: \"%s\"", txt)
+			parser.log().Trace("Reading trailer dict!")
+			parser.log().Trace("peek: \"%s\"", txt)
 			trailer, err = parser.ParseDict()
-			common.Log.Trace("EOF reading trailer dict!")
+			parser.log().Trace("EOF reading trailer dict!")
 			if err != nil {
-				common.Log.Debug("Error parsing trailer dict (%s)", err)
+				parser.log().Debug("Error parsing trailer dict (%s)", err)
 				return nil, err
 			}
 			break
 		}
 
 		if txt == "%%EOF" {
-			common.Log.Debug("ERROR: end of file - trailer not found - error!")
+			parser.log().Debug("ERROR: end of file - trailer not found - error!")
 			return nil, errors.New("end of file - trailer not found")
 		}
 
-		common.Log.Trace("xref more : %s", txt)
+		parser.log().Trace("xref more : %s", txt)
+	}
+	parser.log().Trace("EOF parsing xref table!")
+	if parser.xrefSection != nil {
+		parser.xrefSection.XrefType = XrefTypeTableEntry
 	}
-	common.Log.Trace("EOF parsing xref table!")
 	if parser.xrefType == nil {
 		t := XrefTypeTableEntry
 		parser.xrefType = &t
@@ -823,7 +868,7 @@ func (parser *PdfParser) parseXrefTable() (*PdfObjectDictionary, error) {
 // Also load the dictionary information (trailer dictionary).
 func (parser *PdfParser) parseXrefStream(xstm *PdfObjectInteger) (*PdfObjectDictionary, error) {
 	if xstm != nil {
-		common.Log.Trace("XRefStm xref table object at %d", xstm)
+		parser.log().Trace("XRefStm xref table object at %d", xstm)
 		parser.rs.Seek(int64(*xstm), io.SeekStart)
 		parser.reader = bufio.NewReader(parser.rs)
 	}
@@ -832,14 +877,14 @@ func (parser *PdfParser) parseXrefStream(xstm *PdfObjectInteger) (*PdfObjectDict
 
 	xrefObj, err := parser.ParseIndirectObject()
 	if err != nil {
-		common.Log.Debug("ERROR: Failed to read xref object")
+		parser.log().Debug("ERROR: Failed to read xref object")
 		return nil, errors.New("failed to read xref object")
 	}
 
-	common.Log.Trace("XRefStm object: %s", xrefObj)
+	parser.log().Trace("XRefStm object: %s", xrefObj)
 	xs, ok := xrefObj.(*PdfObjectStream)
 	if !ok {
-		common.Log.Debug("ERROR: XRefStm pointing to non-stream object!")
+		parser.log().Debug("ERROR: XRefStm pointing to non-stream object!")
 		return nil, errors.New("XRefStm pointing to a non-stream object")
 	}
 
@@ -847,12 +892,12 @@ func (parser *PdfParser) parseXrefStream(xstm *PdfObjectInteger) (*PdfObjectDict
 
 	sizeObj, ok := xs.PdfObjectDictionary.Get("Size").(*PdfObjectInteger)
 	if !ok {
-		common.Log.Debug("ERROR: Missing size from xref stm")
+		parser.log().Debug("ERROR: Missing size from xref stm")
 		return nil, errors.New("missing Size from xref stm")
 	}
 	// Sanity check to avoid DoS attacks. Maximum number of indirect objects on 32 bit system.
 	if int64(*sizeObj) > 8388607 {
-		common.Log.Debug("ERROR: xref Size exceeded limit, over 8388607 (%d)", *sizeObj)
+		parser.log().Debug("ERROR: xref Size exceeded limit, over 8388607 (%d)", *sizeObj)
 		return nil, errors.New("range check error")
 	}
 
@@ -864,7 +909,7 @@ func (parser *PdfParser) parseXrefStream(xstm *PdfObjectInteger) (*PdfObjectDict
 
 	wLen := wArr.Len()
 	if wLen != 3 {
-		common.Log.Debug("ERROR: Unsupported xref stm (len(W) != 3 - %d)", wLen)
+		parser.log().Debug("ERROR: Unsupported xref stm (len(W) != 3 - %d)", wLen)
 		return nil, errors.New("unsupported xref stm len(W) != 3")
 	}
 
@@ -880,7 +925,7 @@ func (parser *PdfParser) parseXrefStream(xstm *PdfObjectInteger) (*PdfObjectDict
 
 	ds, err := DecodeStream(xs)
 	if err != nil {
-		common.Log.Debug("ERROR: Unable to decode stream: %v", err)
+		parser.log().Debug("ERROR: Unable to decode stream: %v", err)
 		return nil, err
 	}
 
@@ -890,11 +935,11 @@ func (parser *PdfParser) parseXrefStream(xstm *PdfObjectInteger) (*PdfObjectDict
 	deltab := int(b[0] + b[1] + b[2])
 
 	if s0 < 0 || s1 < 0 || s2 < 0 {
-		common.Log.Debug("Error s value < 0 (%d,%d,%d)", s0, s1, s2)
+		parser.log().Debug("Error s value < 0 (%d,%d,%d)", s0, s1, s2)
 		return nil, errors.New("range check error")
 	}
 	if deltab == 0 {
-		common.Log.Debug("No xref objects in stream (deltab == 0)")
+		parser.log().Debug("No xref objects in stream (deltab == 0)")
 		return trailerDict, nil
 	}
 
@@ -916,16 +961,16 @@ func (parser *PdfParser) parseXrefStream(xstm *PdfObjectInteger) (*PdfObjectDict
 	// Default value: [0 Size].
 	var indexList []int
 	if indexObj != nil {
-		common.Log.Trace("Index: %b", indexObj)
+		parser.log().Trace("Index: %b", indexObj)
 		indicesArray, ok := indexObj.(*PdfObjectArray)
 		if !ok {
-			common.Log.Debug("Invalid Index object (should be an array)")
+			parser.log().Debug("Invalid Index object (should be an array)")
 			return nil, errors.New("invalid Index object")
 		}
 
 		// Expect indLen to be a multiple of 2.
 		if indicesArray.Len()%2 != 0 {
-			common.Log.Debug("WARNING Failure loading xref stm index not multiple of 2.")
+			parser.log().Debug("WARNING Failure loading xref stm index not multiple of 2.")
 			return nil, errors.New("range check error")
 		}
 
@@ -933,7 +978,7 @@ func (parser *PdfParser) parseXrefStream(xstm *PdfObjectInteger) (*PdfObjectDict
 
 		indices, err := indicesArray.ToIntegerArray()
 		if err != nil {
-			common.Log.Debug("Error getting index array as integers: %v", err)
+			parser.log().Debug("Error getting index array as integers: %v", err)
 			return nil, err
 		}
 
@@ -957,7 +1002,7 @@ func (parser *PdfParser) parseXrefStream(xstm *PdfObjectInteger) (*PdfObjectDict
 
 	if entries == objCount+1 {
 		// For compatibility, expand the object count.
-		common.Log.Debug("Incompatibility: Index missing coverage of 1 object - appending one - May lead to problems")
+		parser.log().Debug("Incompatibility: Index missing coverage of 1 object - appending one - May lead to problems")
 		maxIndex := objCount - 1
 		for _, ind := range indexList {
 			if ind > maxIndex {
@@ -970,12 +1015,12 @@ func (parser *PdfParser) parseXrefStream(xstm *PdfObjectInteger) (*PdfObjectDict
 
 	if entries != len(indexList) {
 		// If mismatch -> error (already allowing mismatch of 1 if Index not specified).
-		common.Log.Debug("ERROR: xref stm: num entries != len(indices) (%d != %d)", entries, len(indexList))
+		parser.log().Debug("ERROR: xref stm: num entries != len(indices) (%d != %d)", entries, len(indexList))
 		return nil, errors.New("xref stm num entries != len(indices)")
 	}
 
-	common.Log.Trace("Objects count %d", objCount)
-	common.Log.Trace("Indices: % d", indexList)
+	parser.log().Trace("Objects count %d", objCount)
+	parser.log().Trace("Indices: % d", indexList)
 
 	// Convert byte array to a larger integer, little-endian.
 	convertBytes := func(v []byte) int64 {
@@ -986,26 +1031,26 @@ func (parser *PdfParser) parseXrefStream(xstm *PdfObjectInteger) (*PdfObjectDict
 		return tmp
 	}
 
-	common.Log.Trace("Decoded stream length: %d", len(ds))
+	parser.log().Trace("Decoded stream length: %d", len(ds))
 	objIndex := 0
 	for i := 0; i < len(ds); i += deltab {
 		err := checkBounds(len(ds), i, i+s0)
 		if err != nil {
-			common.Log.Debug("Invalid slice range: %v", err)
+			parser.log().Debug("Invalid slice range: %v", err)
 			return nil, err
 		}
 		p1 := ds[i : i+s0]
 
 		err = checkBounds(len(ds), i+s0, i+s1)
 		if err != nil {
-			common.Log.Debug("Invalid slice range: %v", err)
+			parser.log().Debug("Invalid slice range: %v", err)
 			return nil, err
 		}
 		p2 := ds[i+s0 : i+s1]
 
 		err = checkBounds(len(ds), i+s1, i+s2)
 		if err != nil {
-			common.Log.Debug("Invalid slice range: %v", err)
+			parser.log().Debug("Invalid slice range: %v", err)
 			return nil, err
 		}
 		p3 := ds[i+s1 : i+s2]
@@ -1021,26 +1066,43 @@ func (parser *PdfParser) parseXrefStream(xstm *PdfObjectInteger) (*PdfObjectDict
 		}
 
 		if objIndex >= len(indexList) {
-			common.Log.Debug("XRef stream - Trying to access index out of bounds - breaking")
+			parser.log().Debug("XRef stream - Trying to access index out of bounds - breaking")
 			break
 		}
 		objNum := indexList[objIndex]
 		objIndex++
 
-		common.Log.Trace("%d. p1: % x", objNum, p1)
-		common.Log.Trace("%d. p2: % x", objNum, p2)
-		common.Log.Trace("%d. p3: % x", objNum, p3)
+		parser.log().Trace("%d. p1: % x", objNum, p1)
+		parser.log().Trace("%d. p2: % x", objNum, p2)
+		parser.log().Trace("%d. p3: % x", objNum, p3)
+
+		parser.log().Trace("%d. xref: %d %d %d", objNum, ftype, n2, n3)
+		if parser.xrefSection != nil {
+			switch ftype {
+			case 0:
+				parser.xrefSection.setFree(objNum)
+			case 1:
+				sectionObjNum := objNum
+				if n2 == xsOffset {
+					sectionObjNum = int(xs.ObjectNumber)
+				}
+				parser.xrefSection.setEntry(XrefObject{ObjectNumber: sectionObjNum,
+					XType: XrefTypeTableEntry, Offset: n2, Generation: int(n3)})
+			case 2:
+				parser.xrefSection.setEntry(XrefObject{ObjectNumber: objNum,
+					XType: XrefTypeObjectStream, OsObjNumber: int(n2), OsObjIndex: int(n3)})
+			}
+		}
 
-		common.Log.Trace("%d. xref: %d %d %d", objNum, ftype, n2, n3)
 		if ftype == 0 {
-			common.Log.Trace("- Free object - can probably ignore")
+			parser.log().Trace("- Free object - can probably ignore")
 		} else if ftype == 1 {
-			common.Log.Trace("- In use - uncompressed via offset %b", p2)
+			parser.log().Trace("- In use - uncompressed via offset %b", p2)
 			// If offset (n2) is same as the XRefs table offset, then update the Object number with the
 			// one that was parsed.  Fixes problem where the object number is incorrectly or not specified
 			// in the Index.
 			if n2 == xsOffset {
-				common.Log.Debug("Updating object number for XRef table %d -> %d", objNum, xs.ObjectNumber)
+				parser.log().Debug("Updating object number for XRef table %d -> %d", objNum, xs.ObjectNumber)
 				objNum = int(xs.ObjectNumber)
 			}
 
@@ -1055,15 +1117,15 @@ func (parser *PdfParser) parseXrefStream(xstm *PdfObjectInteger) (*PdfObjectDict
 			}
 		} else if ftype == 2 {
 			// Object type 2: Compressed object.
-			common.Log.Trace("- In use - compressed object")
+			parser.log().Trace("- In use - compressed object")
 			if _, ok := parser.xrefs.ObjectMap[objNum]; !ok {
 				obj := XrefObject{ObjectNumber: objNum,
 					XType: XrefTypeObjectStream, OsObjNumber: int(n2), OsObjIndex: int(n3)}
 				parser.xrefs.ObjectMap[objNum] = obj
-				common.Log.Trace("entry: %+v", obj)
+				parser.log().Trace("entry: %+v", obj)
 			}
 		} else {
-			common.Log.Debug("ERROR: --------INVALID TYPE XrefStm invalid?-------")
+			parser.log().Debug("ERROR: --------INVALID TYPE XrefStm invalid?-------")
 			// Continue, we do not define anything -> null object.
 			// 7.5.8.3:
 			//
@@ -1075,6 +1137,9 @@ func (parser *PdfParser) parseXrefStream(xstm *PdfObjectInteger) (*PdfObjectDict
 		}
 	}
 
+	if parser.xrefSection != nil && xstm == nil {
+		parser.xrefSection.XrefType = XrefTypeObjectStream
+	}
 	if parser.xrefType == nil {
 		t := XrefTypeObjectStream
 		parser.xrefType = &t
@@ -1097,12 +1162,12 @@ func (parser *PdfParser) parseXref() (*PdfObjectDictionary, error) {
 			parser.xrefOffset = parser.GetFileOffset()
 		}
 		if reIndirectObject.Match(bb) {
-			common.Log.Trace("xref points to an object. Probably xref object")
-			common.Log.Debug("starting with \"%s\"", string(bb))
+			parser.log().Trace("xref points to an object. Probably xref object")
+			parser.log().Debug("starting with \"%s\"", string(bb))
 			return parser.parseXrefStream(nil)
 		}
 		if reXrefTable.Match(bb) {
-			common.Log.Trace("Standard xref section table!")
+			parser.log().Trace("Standard xref section table!")
 			return parser.parseXrefTable()
 		}
 
@@ -1120,9 +1185,9 @@ func (parser *PdfParser) parseXref() (*PdfObjectDictionary, error) {
 		bb = append(lbb, bb...)
 	}
 
-	common.Log.Debug("Warning: Unable to find xref table or stream. Repair attempted: Looking for earliest xref from bottom.")
+	parser.log().Debug("Warning: Unable to find xref table or stream. Repair attempted: Looking for earliest xref from bottom.")
 	if err := parser.repairSeekXrefMarker(); err != nil {
-		common.Log.Debug("Repair failed - %v", err)
+		parser.log().Debug("Repair failed - %v", err)
 		return nil, err
 	}
 	return parser.parseXrefTable()
@@ -1151,21 +1216,21 @@ func (parser *PdfParser) seekToEOFMarker(fSize int64) error {
 		// Read the data.
 		b1 := make([]byte, buflen)
 		parser.rs.Read(b1)
-		common.Log.Trace("Looking for EOF marker: \"%s\"", string(b1))
+		parser.log().Trace("Looking for EOF marker: \"%s\"", string(b1))
 		ind := reEOF.FindAllStringIndex(string(b1), -1)
 		if ind != nil {
 			// Found it.
 			lastInd := ind[len(ind)-1]
-			common.Log.Trace("Ind: % d", ind)
+			parser.log().Trace("Ind: % d", ind)
 			parser.rs.Seek(-offset-buflen+int64(lastInd[0]), io.SeekEnd)
 			return nil
 		}
 
-		common.Log.Debug("Warning: EOF marker not found! - continue seeking")
+		parser.log().Debug("Warning: EOF marker not found! - continue seeking")
 		offset += buflen - 4
 	}
 
-	common.Log.Debug("Error: EOF marker was not found.")
+	parser.log().Debug("Error: EOF marker was not found.")
 	return errors.New("EOF not found")
 }
 
@@ -1197,13 +1262,13 @@ func (parser *PdfParser) loadXrefs() (*PdfObjectDictionary, error) {
 	if err != nil {
 		return nil, err
 	}
-	common.Log.Trace("fsize: %d", fSize)
+	parser.log().Trace("fsize: %d", fSize)
 	parser.fileSize = fSize
 
 	// Seek the EOF marker.
 	err = parser.seekToEOFMarker(fSize)
 	if err != nil {
-		common.Log.Debug("Failed seek to eof marker: %v", err)
+		parser.log().Debug("Failed seek to eof marker: %v", err)
 		return nil, err
 	}
 
@@ -1227,28 +1292,28 @@ func (parser *PdfParser) loadXrefs() (*PdfObjectDictionary, error) {
 	b2 := make([]byte, numBytes)
 	_, err = parser.rs.Read(b2)
 	if err != nil {
-		common.Log.Debug("Failed reading while looking for startxref: %v", err)
+		parser.log().Debug("Failed reading while looking for startxref: %v", err)
 		return nil, err
 	}
 
 	result := reStartXref.FindStringSubmatch(string(b2))
 	if len(result) < 2 {
-		common.Log.Debug("Error: startxref not found!")
+		parser.log().Debug("Error: startxref not found!")
 		return nil, errors.New("startxref not found")
 	}
 	if len(result) > 2 {
-		common.Log.Debug("ERROR: Multiple startxref (%s)!", b2)
+		parser.log().Debug("ERROR: Multiple startxref (%s)!", b2)
 		return nil, errors.New("multiple startxref entries?")
 	}
 	offsetXref, _ := strconv.ParseInt(result[1], 10, 64)
-	common.Log.Trace("startxref at %d", offsetXref)
+	parser.log().Trace("startxref at %d", offsetXref)
 
 	if offsetXref > fSize {
-		common.Log.Debug("ERROR: Xref offset outside of file")
-		common.Log.Debug("Attempting repair")
+		parser.log().Debug("ERROR: Xref offset outside of file")
+		parser.log().Debug("Attempting repair")
 		offsetXref, err = parser.repairLocateXref()
 		if err != nil {
-			common.Log.Debug("ERROR: Repair attempt failed (%s)")
+			parser.log().Debug("ERROR: Repair attempt failed (%s)")
 			return nil, err
 		}
 	}
@@ -1256,10 +1321,15 @@ func (parser *PdfParser) loadXrefs() (*PdfObjectDictionary, error) {
 	parser.rs.Seek(int64(offsetXref), io.SeekStart)
 	parser.reader = bufio.NewReader(parser.rs)
 
+	parser.revisions = nil
+	parser.beginRevisionSection(offsetXref)
+	defer parser.finalizeRevisions()
+
 	trailerDict, err := parser.parseXref()
 	if err != nil {
 		return nil, err
 	}
+	sectionEnd := parser.GetFileOffset()
 
 	// Check the XrefStm object also from the trailer.
 	xx := trailerDict.Get("XRefStm")
@@ -1273,6 +1343,7 @@ func (parser *PdfParser) loadXrefs() (*PdfObjectDictionary, error) {
 			return nil, err
 		}
 	}
+	parser.endRevisionSection(trailerDict, sectionEnd)
 
 	// Load old objects also.  Only if not already specified.
 	var prevList []int64
@@ -1293,30 +1364,40 @@ func (parser *PdfParser) loadXrefs() (*PdfObjectDictionary, error) {
 		if !ok {
 			// For compatibility: If Prev is invalid, just go with whatever xrefs are loaded already.
 			// i.e. not returning an error.  A debug message is logged.
-			common.Log.Debug("Invalid Prev reference: Not a *PdfObjectInteger (%T)", xx)
+			parser.log().Debug("Invalid Prev reference: Not a *PdfObjectInteger (%T)", xx)
 			return trailerDict, nil
 		}
 
 		off := *prevInt
-		common.Log.Trace("Another Prev xref table object at %d", off)
+		parser.log().Trace("Another Prev xref table object at %d", off)
 
 		// Can be either regular table, or an xref object...
 		parser.rs.Seek(int64(off), os.SEEK_SET)
 		parser.reader = bufio.NewReader(parser.rs)
 
+		parser.beginRevisionSection(int64(off))
 		ptrailerDict, err := parser.parseXref()
 		if err != nil {
-			common.Log.Debug("Warning: Error - Failed loading another (Prev) trailer")
-			common.Log.Debug("Attempting to continue by ignoring it")
+			parser.log().Debug("Warning: Error - Failed loading another (Prev) trailer")
+			parser.log().Debug("Attempting to continue by ignoring it")
+			parser.xrefSection = nil
 			break
 		}
+		sectionEnd := parser.GetFileOffset()
+		if xstm, ok := ptrailerDict.Get("XRefStm").(*PdfObjectInteger); ok {
+			// Hybrid-reference section of an earlier revision.
+			if _, err := parser.parseXrefStream(xstm); err != nil {
+				parser.log().Debug("Warning: Failed loading XRefStm of Prev trailer: %v", err)
+			}
+		}
+		parser.endRevisionSection(ptrailerDict, sectionEnd)
 
 		xx = ptrailerDict.Get("Prev")
 		if xx != nil {
 			prevoff := *(xx.(*PdfObjectInteger))
 			if intInSlice(int64(prevoff), prevList) {
 				// Prevent circular reference!
-				common.Log.Debug("Preventing circular xref referencing")
+				parser.log().Debug("Preventing circular xref referencing")
 				break
 			}
 			prevList = append(prevList, int64(prevoff))
@@ -1328,6 +1409,11 @@ func (parser *PdfParser) loadXrefs() (*PdfObjectDictionary, error) {
 
 // Return the closest object following offset from the xrefs table.
 func (parser *PdfParser) xrefNextObjectOffset(offset int64) int64 {
+	if parser.parent != nil {
+		parser.parent.lock.Lock()
+		defer parser.parent.lock.Unlock()
+		return parser.parent.xrefNextObjectOffset(offset)
+	}
 	nextOffset := int64(0)
 
 	if len(parser.xrefs.ObjectMap) == 0 {
@@ -1378,18 +1464,24 @@ func (parser *PdfParser) traceStreamLength(lengthObj PdfObject) (PdfObject, erro
 	if isRef {
 		lookupInProgress, has := parser.streamLengthReferenceLookupInProgress[lengthRef.ObjectNumber]
 		if has && lookupInProgress {
-			common.Log.Debug("Stream Length reference unresolved (illegal)")
+			parser.log().Debug("Stream Length reference unresolved (illegal)")
 			return nil, errors.New("illegal recursive loop")
 		}
 		// Mark lookup as in progress.
 		parser.streamLengthReferenceLookupInProgress[lengthRef.ObjectNumber] = true
 	}
 
-	slo, err := parser.Resolve(lengthObj)
+	var slo PdfObject
+	var err error
+	if parser.parent != nil {
+		slo, err = parser.parent.resolveConcurrent(lengthObj, parser.streamLengthReferenceLookupInProgress)
+	} else {
+		slo, err = parser.resolve(lengthObj)
+	}
 	if err != nil {
 		return nil, err
 	}
-	common.Log.Trace("Stream length? %s", slo)
+	parser.log().Trace("Stream length? %s", slo)
 
 	if isRef {
 		// Mark as completed lookup
@@ -1403,16 +1495,16 @@ func (parser *PdfParser) traceStreamLength(lengthObj PdfObject) (PdfObject, erro
 // Returns the indirect object (*PdfIndirectObject) or the stream object (*PdfObjectStream).
 func (parser *PdfParser) ParseIndirectObject() (PdfObject, error) {
 	indirect := PdfIndirectObject{}
-	indirect.parser = parser
-	common.Log.Trace("-Read indirect obj")
+	indirect.parser = parser.owner()
+	parser.log().Trace("-Read indirect obj")
 	bb, err := parser.reader.Peek(20)
 	if err != nil {
 		if err != io.EOF {
-			common.Log.Debug("ERROR: Fail to read indirect obj")
+			parser.log().Debug("ERROR: Fail to read indirect obj")
 			return &indirect, err
 		}
 	}
-	common.Log.Trace("(indirect obj peek \"%s\"", string(bb))
+	parser.log().Trace("(indirect obj peek \"%s\"", string(bb))
 
 	indices := reIndirectObject.FindStringSubmatchIndex(string(bb))
 	if len(indices) < 6 {
@@ -1421,25 +1513,25 @@ func (parser *PdfParser) ParseIndirectObject() (PdfObject, error) {
 			// with the EOF error.
 			return nil, err
 		}
-		common.Log.Debug("ERROR: Unable to find object signature (%s)", string(bb))
+		parser.log().Debug("ERROR: Unable to find object signature (%s)", string(bb))
 		return &indirect, errors.New("unable to detect indirect object signature")
 	}
 	parser.reader.Discard(indices[0]) // Take care of any small offset.
-	common.Log.Trace("Offsets % d", indices)
+	parser.log().Trace("Offsets % d", indices)
 
 	// Read the object header.
 	hlen := indices[1] - indices[0]
 	hb := make([]byte, hlen)
 	_, err = parser.ReadAtLeast(hb, hlen)
 	if err != nil {
-		common.Log.Debug("ERROR: unable to read - %s", err)
+		parser.log().Debug("ERROR: unable to read - %s", err)
 		return nil, err
 	}
-	common.Log.Trace("textline: %s", hb)
+	parser.log().Trace("textline: %s", hb)
 
 	result := reIndirectObject.FindStringSubmatch(string(hb))
 	if len(result) < 3 {
-		common.Log.Debug("ERROR: Unable to find object signature (%s)", string(hb))
+		parser.log().Debug("ERROR: Unable to find object signature (%s)", string(hb))
 		return &indirect, errors.New("unable to detect indirect object signature")
 	}
 
@@ -1453,30 +1545,30 @@ func (parser *PdfParser) ParseIndirectObject() (PdfObject, error) {
 		if err != nil {
 			return &indirect, err
 		}
-		common.Log.Trace("Ind. peek: %s (% x)!", string(bb), string(bb))
+		parser.log().Trace("Ind. peek: %s (% x)!", string(bb), string(bb))
 
 		if IsWhiteSpace(bb[0]) {
 			parser.skipSpaces()
 		} else if bb[0] == '%' {
 			parser.skipComments()
 		} else if (bb[0] == '<') && (bb[1] == '<') {
-			common.Log.Trace("Call ParseDict")
+			parser.log().Trace("Call ParseDict")
 			indirect.PdfObject, err = parser.ParseDict()
-			common.Log.Trace("EOF Call ParseDict: %v", err)
+			parser.log().Trace("EOF Call ParseDict: %v", err)
 			if err != nil {
 				return &indirect, err
 			}
-			common.Log.Trace("Parsed dictionary... finished.")
+			parser.log().Trace("Parsed dictionary... finished.")
 		} else if (bb[0] == '/') || (bb[0] == '(') || (bb[0] == '[') || (bb[0] == '<') {
 			indirect.PdfObject, err = parser.parseObject()
 			if err != nil {
 				return &indirect, err
 			}
-			common.Log.Trace("Parsed object ... finished.")
+			parser.log().Trace("Parsed object ... finished.")
 		} else if bb[0] == ']' {
 			// ']' not used as an array object ending marker, or array object
 			// terminated multiple times. Discarding the character.
-			common.Log.Debug("WARNING: ']' character not being used as an array ending marker. Skipping.")
+			parser.log().Debug("WARNING: ']' character not being used as an array ending marker. Skipping.")
 			parser.reader.Discard(1)
 		} else {
 			if bb[0] == 'e' {
@@ -1495,7 +1587,7 @@ func (parser *PdfParser) ParseIndirectObject() (PdfObject, error) {
 						if IsWhiteSpace(bb[discardBytes]) && bb[discardBytes] != '\r' && bb[discardBytes] != '\n' {
 							// If any other white space character... should not happen!
 							// Skip it..
-							common.Log.Debug("Non-conformant PDF not ending stream line properly with EOL marker")
+							parser.log().Debug("Non-conformant PDF not ending stream line properly with EOL marker")
 							discardBytes++
 						}
 						if bb[discardBytes] == '\r' {
@@ -1514,15 +1606,15 @@ func (parser *PdfParser) ParseIndirectObject() (PdfObject, error) {
 					if !isDict {
 						return nil, errors.New("stream object missing dictionary")
 					}
-					common.Log.Trace("Stream dict %s", dict)
+					parser.log().Trace("Stream dict %s", dict)
 
 					// Special stream length tracing function used to avoid endless recursive looping.
 					slo, err := parser.traceStreamLength(dict.Get("Length"))
 					if err != nil {
-						common.Log.Debug("Fail to trace stream length: %v", err)
+						parser.log().Debug("Fail to trace stream length: %v", err)
 						return nil, err
 					}
-					common.Log.Trace("Stream length? %s", slo)
+					parser.log().Trace("Stream length? %s", slo)
 
 					pstreamLength, ok := slo.(*PdfObjectInteger)
 					if !ok {
@@ -1537,32 +1629,44 @@ func (parser *PdfParser) ParseIndirectObject() (PdfObject, error) {
 					// Find next object with closest offset to current object and calculate
 					// the expected stream length based on that.
 					streamStartOffset := parser.GetFileOffset()
+
+					// Check that the stream data is followed by endstream and correct the length if needed.
+					newLength, corrected := parser.repairStreamLength(streamStartOffset, int64(streamLength))
+					if corrected {
+						parser.addRepairWarning(RepairStreamLength, indirect.ObjectNumber, streamStartOffset,
+							"stream length corrected from %d to %d", streamLength, newLength)
+						streamLength = PdfObjectInteger(newLength)
+						dict.Set("Length", MakeInteger(newLength))
+					}
+
 					nextObjectOffset := parser.xrefNextObjectOffset(streamStartOffset)
-					if streamStartOffset+int64(streamLength) > nextObjectOffset && nextObjectOffset > streamStartOffset {
-						common.Log.Debug("Expected ending at %d", streamStartOffset+int64(streamLength))
-						common.Log.Debug("Next object starting at %d", nextObjectOffset)
+					if !corrected && streamStartOffset+int64(streamLength) > nextObjectOffset && nextObjectOffset > streamStartOffset {
+						parser.log().Debug("Expected ending at %d", streamStartOffset+int64(streamLength))
+						parser.log().Debug("Next object starting at %d", nextObjectOffset)
 						// endstream + "\n" endobj + "\n" (17)
 						newLength := nextObjectOffset - streamStartOffset - 17
 						if newLength < 0 {
 							return nil, errors.New("invalid stream length, going past boundaries")
 						}
 
-						common.Log.Debug("Attempting a length correction to %d...", newLength)
+						parser.log().Debug("Attempting a length correction to %d...", newLength)
+						parser.addRepairWarning(RepairStreamLength, indirect.ObjectNumber, streamStartOffset,
+							"stream length corrected from %d to %d", streamLength, newLength)
 						streamLength = PdfObjectInteger(newLength)
 						dict.Set("Length", MakeInteger(newLength))
 					}
 
 					// Make sure is less than actual file size.
 					if int64(streamLength) > parser.fileSize {
-						common.Log.Debug("ERROR: Stream length cannot be larger than file size")
+						parser.log().Debug("ERROR: Stream length cannot be larger than file size")
 						return nil, errors.New("invalid stream length, larger than file size")
 					}
 
 					stream := make([]byte, streamLength)
 					_, err = parser.ReadAtLeast(stream, int(streamLength))
 					if err != nil {
-						common.Log.Debug("ERROR stream (%d): %X", len(stream), stream)
-						common.Log.Debug("ERROR: %v", err)
+						parser.log().Debug("ERROR stream (%d): %X", len(stream), stream)
+						parser.log().Debug("ERROR: %v", err)
 						return nil, err
 					}
 
@@ -1571,7 +1675,7 @@ func (parser *PdfParser) ParseIndirectObject() (PdfObject, error) {
 					streamobj.PdfObjectDictionary = indirect.PdfObject.(*PdfObjectDictionary)
 					streamobj.ObjectNumber = indirect.ObjectNumber
 					streamobj.GenerationNumber = indirect.GenerationNumber
-					streamobj.PdfObjectReference.parser = parser
+					streamobj.PdfObjectReference.parser = parser.owner()
 
 					parser.skipSpaces()
 					parser.reader.Discard(9) // endstream
@@ -1582,17 +1686,17 @@ func (parser *PdfParser) ParseIndirectObject() (PdfObject, error) {
 
 			indirect.PdfObject, err = parser.parseObject()
 			if indirect.PdfObject == nil {
-				common.Log.Debug("INCOMPATIBILITY: Indirect object not containing an object - assuming null object")
+				parser.log().Debug("INCOMPATIBILITY: Indirect object not containing an object - assuming null object")
 				indirect.PdfObject = MakeNull()
 			}
 			return &indirect, err
 		}
 	}
 	if indirect.PdfObject == nil {
-		common.Log.Debug("INCOMPATIBILITY: Indirect object not containing an object - assuming null object")
+		parser.log().Debug("INCOMPATIBILITY: Indirect object not containing an object - assuming null object")
 		indirect.PdfObject = MakeNull()
 	}
-	common.Log.Trace("Returning indirect!")
+	parser.log().Trace("Returning indirect!")
 	return &indirect, nil
 }
 
@@ -1615,27 +1719,40 @@ func NewParserFromString(txt string) *PdfParser {
 // NewParser creates a new parser for a PDF file via ReadSeeker. Loads the cross reference stream and trailer.
 // An error is returned on failure.
 func NewParser(rs io.ReadSeeker) (*PdfParser, error) {
+	return NewParserWithLogger(rs, nil)
+}
+
+// NewParserWithLogger creates a new parser for a PDF file via ReadSeeker, like NewParser, logging
+// its messages and diagnostics to `logger` rather than to the global parser.log().
+func NewParserWithLogger(rs io.ReadSeeker, logger common.Logger) (*PdfParser, error) {
 	parser := &PdfParser{
 		rs:                                    rs,
 		ObjCache:                              make(objectCache),
 		streamLengthReferenceLookupInProgress: map[int64]bool{},
+		logger:                                logger,
 	}
 
 	// Parse PDF version.
 	majorVersion, minorVersion, err := parser.parsePdfVersion()
 	if err != nil {
-		common.Log.Error("Unable to parse version: %v", err)
+		parser.log().Error("Unable to parse version: %v", err)
 		return nil, err
 	}
 	parser.version.Major = majorVersion
 	parser.version.Minor = minorVersion
 
 	// Start by reading the xrefs (from bottom).
-	if parser.trailer, err = parser.loadXrefs(); err != nil {
-		common.Log.Debug("ERROR: Failed to load xref table! %s", err)
-		return nil, err
+	parser.trailer, err = parser.loadXrefs()
+	if err != nil || len(parser.xrefs.ObjectMap) == 0 || parser.trailer.Get("Root") == nil {
+		// Missing or corrupt cross-reference information. Reconstruct by scanning the file.
+		parser.log().Debug("ERROR: Failed to load xref table! %v - attempting reconstruction", err)
+		parser.trailer, err = parser.repairReconstruct()
+		if err != nil {
+			parser.log().Debug("ERROR: Failed to reconstruct xref table! %s", err)
+			return nil, err
+		}
 	}
-	common.Log.Trace("Trailer: %s", parser.trailer)
+	parser.log().Trace("Trailer: %s", parser.trailer)
 
 	if len(parser.xrefs.ObjectMap) == 0 {
 		return nil, fmt.Errorf("empty XREF table - Invalid")
@@ -1644,17 +1761,56 @@ func NewParser(rs io.ReadSeeker) (*PdfParser, error) {
 	return parser, nil
 }
 
+// Logger returns the logger of the parser: the logger passed to NewParserWithLogger, or the global
+// common.Log.
+func (parser *PdfParser) Logger() common.Logger {
+	if parser.logger != nil {
+		return parser.logger
+	}
+	return common.Log
+}
+
+// log returns the logger of the parser.
+func (parser *PdfParser) log() common.Logger {
+	return parser.Logger()
+}
+
+// report reports a diagnostic with `code` concerning object `objNum` to the logger of the parser.
+func (parser *PdfParser) report(level common.LogLevel, code common.DiagnosticCode, objNum int64,
+	format string, args ...interface{}) {
+	common.ReportDiagnosticDepth(parser.log(), 1, common.Diagnostic{
+		Level:        level,
+		Code:         code,
+		Message:      fmt.Sprintf(format, args...),
+		ObjectNumber: objNum,
+	})
+}
+
+// owner returns the parser the objects parsed belong to.
+func (parser *PdfParser) owner() *PdfParser {
+	if parser.parent != nil {
+		return parser.parent
+	}
+	return parser
+}
+
 // Resolves a reference, returning the object and indicates whether or not it was cached.
 func (parser *PdfParser) resolveReference(ref *PdfObjectReference) (PdfObject, bool, error) {
-	cachedObj, isCached := parser.ObjCache[int(ref.ObjectNumber)]
+	if obj, cached, ok := parser.lookupConcurrent(int(ref.ObjectNumber), nil); ok {
+		return obj, cached, nil
+	}
+
+	parser.lock.Lock()
+	defer parser.lock.Unlock()
+
+	cachedObj, isCached := parser.cachedObject(int(ref.ObjectNumber))
 	if isCached {
 		return cachedObj, true, nil
 	}
-	obj, err := parser.LookupByReference(*ref)
+	obj, _, err := parser.lookupByNumberWrapper(int(ref.ObjectNumber), true)
 	if err != nil {
 		return nil, false, err
 	}
-	parser.ObjCache[int(ref.ObjectNumber)] = obj
 	return obj, false, nil
 }
 
@@ -1669,12 +1825,12 @@ func (parser *PdfParser) IsEncrypted() (bool, error) {
 		return false, nil
 	}
 
-	common.Log.Trace("Checking encryption dictionary!")
+	parser.log().Trace("Checking encryption dictionary!")
 	e := parser.trailer.Get("Encrypt")
 	if e == nil {
 		return false, nil
 	}
-	common.Log.Trace("Is encrypted!")
+	parser.log().Trace("Is encrypted!")
 	var (
 		dict *PdfObjectDictionary
 	)
@@ -1682,27 +1838,27 @@ func (parser *PdfParser) IsEncrypted() (bool, error) {
 	case *PdfObjectDictionary:
 		dict = e
 	case *PdfObjectReference:
-		common.Log.Trace("0: Look up ref %q", e)
+		parser.log().Trace("0: Look up ref %q", e)
 		encObj, err := parser.LookupByReference(*e)
-		common.Log.Trace("1: %q", encObj)
+		parser.log().Trace("1: %q", encObj)
 		if err != nil {
 			return false, err
 		}
 
 		encIndObj, ok := encObj.(*PdfIndirectObject)
 		if !ok {
-			common.Log.Debug("Encryption object not an indirect object")
+			parser.log().Debug("Encryption object not an indirect object")
 			return false, errors.New("type check error")
 		}
 		encDict, ok := encIndObj.PdfObject.(*PdfObjectDictionary)
 
-		common.Log.Trace("2: %q", encDict)
+		parser.log().Trace("2: %q", encDict)
 		if !ok {
 			return false, errors.New("trailer Encrypt object non dictionary")
 		}
 		dict = encDict
 	case *PdfObjectNull:
-		common.Log.Debug("Encrypt is a null object. File should not be encrypted.")
+		parser.log().Debug("Encrypt is a null object. File should not be encrypted.")
 		return false, nil
 	default:
 		return false, fmt.Errorf("unsupported type: %T", e)
@@ -1727,7 +1883,7 @@ func (parser *PdfParser) IsEncrypted() (bool, error) {
 		}
 	}
 	parser.crypter = crypter
-	common.Log.Trace("Crypter object %b", crypter)
+	parser.log().Trace("Crypter object %b", crypter)
 	return true, nil
 }
 
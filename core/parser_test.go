@@ -9,10 +9,15 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -919,3 +924,174 @@ func TestPDFVersionParse(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, string(b), expected)
 }
+
+func TestConcurrentLookup(t *testing.T) {
+	file, err := os.Open("./testdata/minimal.pdf")
+	require.NoError(t, err)
+	defer file.Close()
+
+	parser, err := NewParser(file)
+	require.NoError(t, err)
+	objNums := parser.GetObjectNums()
+	require.NotEmpty(t, objNums)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8*len(objNums))
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, objNum := range objNums {
+				_, err := parser.LookupByNumber(objNum)
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	obj, err := parser.LookupByNumber(1)
+	require.NoError(t, err)
+	require.Equal(t, parser.ObjCache[1], obj)
+}
+
+// makeTestPdfWithObjects returns a PDF file containing `n` dictionaries with an array of numbers
+// and a stream each, besides the catalog and the page tree.
+func makeTestPdfWithObjects(n int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := []int{buf.Len()}
+	buf.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	offsets = append(offsets, buf.Len())
+	buf.WriteString("2 0 obj\n<< /Type /Pages /Kids [] /Count 0 >>\nendobj\n")
+	for i := 0; i < n; i++ {
+		objNum := len(offsets) + 1
+		offsets = append(offsets, buf.Len())
+		if i%2 == 0 {
+			fmt.Fprintf(&buf, "%d 0 obj\n<< /Name /Object%d /Values [", objNum, objNum)
+			for j := 0; j < 200; j++ {
+				fmt.Fprintf(&buf, " %d.5", j)
+			}
+			buf.WriteString(" ] >>\nendobj\n")
+			continue
+		}
+		data := bytes.Repeat([]byte("0 0 m 100 100 l S\n"), 50)
+		fmt.Fprintf(&buf, "%d 0 obj\n<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", objNum, len(data), data)
+	}
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xrefOffset)
+	return buf.Bytes()
+}
+
+// barrierReader is a reader whose ReadAt calls wait, once armed, for two calls to be in progress
+// at the same time.
+type barrierReader struct {
+	*bytes.Reader
+	armed   int32
+	waiting int32
+	both    chan struct{}
+}
+
+func (r *barrierReader) ReadAt(p []byte, off int64) (int, error) {
+	if atomic.LoadInt32(&r.armed) == 1 {
+		if atomic.AddInt32(&r.waiting, 1) == 2 {
+			close(r.both)
+		}
+		select {
+		case <-r.both:
+		case <-time.After(time.Second):
+			return 0, errors.New("no concurrent read")
+		}
+	}
+	return r.Reader.ReadAt(p, off)
+}
+
+func TestConcurrentLookupParsing(t *testing.T) {
+	rs := &barrierReader{Reader: bytes.NewReader(makeTestPdfWithObjects(2)), both: make(chan struct{})}
+	parser, err := NewParser(rs)
+	require.NoError(t, err)
+	atomic.StoreInt32(&rs.armed, 1)
+
+	// Both objects are read at the same time, the lock is not held while parsing.
+	var wg sync.WaitGroup
+	for _, objNum := range []int{3, 4} {
+		wg.Add(1)
+		go func(objNum int) {
+			defer wg.Done()
+			_, err := parser.LookupByNumber(objNum)
+			require.NoError(t, err)
+		}(objNum)
+	}
+	wg.Wait()
+	select {
+	case <-rs.both:
+	default:
+		t.Fatal("objects not parsed concurrently")
+	}
+
+	obj, err := parser.LookupByNumber(4)
+	require.NoError(t, err)
+	stream, ok := obj.(*PdfObjectStream)
+	require.True(t, ok)
+	require.Equal(t, parser, stream.PdfObjectReference.parser)
+	require.Equal(t, parser, stream.PdfObjectDictionary.parser)
+	require.Equal(t, parser.ObjCache[4], obj)
+}
+
+// BenchmarkConcurrentLookup measures the lookup of all the objects of a file by concurrent
+// goroutines, with a reader implementing io.ReaderAt (objects parsed concurrently) and with a
+// reader only implementing io.ReadSeeker (objects parsed with the lock held).
+func BenchmarkConcurrentLookup(b *testing.B) {
+	data := makeTestPdfWithObjects(1000)
+	readers := []struct {
+		name string
+		rs   func() io.ReadSeeker
+	}{
+		{"ReaderAt", func() io.ReadSeeker { return bytes.NewReader(data) }},
+		{"ReadSeeker", func() io.ReadSeeker { return struct{ io.ReadSeeker }{bytes.NewReader(data)} }},
+	}
+
+	for _, reader := range readers {
+		for _, workers := range []int{1, runtime.GOMAXPROCS(0)} {
+			b.Run(fmt.Sprintf("%s/workers=%d", reader.name, workers), func(b *testing.B) {
+				parser, err := NewParser(reader.rs())
+				require.NoError(b, err)
+				objNums := parser.GetObjectNums()
+
+				b.ResetTimer()
+				for n := 0; n < b.N; n++ {
+					parser.lock.Lock()
+					parser.resetObjectCache()
+					parser.lock.Unlock()
+
+					next := int64(-1)
+					var wg sync.WaitGroup
+					for w := 0; w < workers; w++ {
+						wg.Add(1)
+						go func() {
+							defer wg.Done()
+							for {
+								i := int(atomic.AddInt64(&next, 1))
+								if i >= len(objNums) {
+									return
+								}
+								if _, err := parser.LookupByNumber(objNums[i]); err != nil {
+									b.Error(err)
+									return
+								}
+							}
+						}()
+					}
+					wg.Wait()
+				}
+			})
+		}
+	}
+}
@@ -8,14 +8,15 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
 
 	"bufio"
 	"io"
-	"strconv"
 
 	"github.com/unidoc/unipdf/v3/common"
 )
@@ -37,12 +38,13 @@ func (parser *PdfParser) repairLocateXref() (int64, error) {
 
 	results := repairReXrefTable.FindAllStringIndex(string(b2), -1)
 	if len(results) < 1 {
-		common.Log.Debug("ERROR: Repair: xref not found!")
+		parser.log().Debug("ERROR: Repair: xref not found!")
 		return 0, errors.New("repair: xref not found")
 	}
 
 	localOffset := int64(results[len(results)-1][0])
 	xrefOffset := curOffset + localOffset
+	parser.addRepairWarning(RepairXrefLocated, 0, xrefOffset, "invalid startxref offset, xref located by searching")
 	return xrefOffset, nil
 }
 
@@ -55,15 +57,15 @@ func (parser *PdfParser) rebuildXrefTable() error {
 	for objNum, xref := range parser.xrefs.ObjectMap {
 		obj, _, err := parser.lookupByNumberWrapper(objNum, false)
 		if err != nil {
-			common.Log.Debug("ERROR: Unable to look up object (%s)", err)
-			common.Log.Debug("ERROR: Xref table completely broken - attempting to repair ")
+			parser.log().Debug("ERROR: Unable to look up object (%s)", err)
+			parser.log().Debug("ERROR: Xref table completely broken - attempting to repair ")
 			xrefTable, err := parser.repairRebuildXrefsTopDown()
 			if err != nil {
-				common.Log.Debug("ERROR: Failed xref rebuild repair (%s)", err)
+				parser.log().Debug("ERROR: Failed xref rebuild repair (%s)", err)
 				return err
 			}
 			parser.xrefs = *xrefTable
-			common.Log.Debug("Repaired xref table built")
+			parser.log().Debug("Repaired xref table built")
 			return nil
 		}
 		actObjNum, actGenNum, err := getObjectNumber(obj)
@@ -77,27 +79,15 @@ func (parser *PdfParser) rebuildXrefTable() error {
 	}
 
 	parser.xrefs = newXrefs
-	common.Log.Debug("New xref table built")
+	parser.log().Debug("New xref table built")
 	printXrefTable(parser.xrefs)
+	parser.addRepairWarning(RepairXrefRenumbered, 0, -1, "cross-reference table renumbered")
 	return nil
 }
 
-// Parses and returns the object and generation number from a string such as "12 0 obj" -> (12,0,nil).
-func parseObjectNumberFromString(str string) (int, int, error) {
-	result := reIndirectObject.FindStringSubmatch(str)
-	if len(result) < 3 {
-		return 0, 0, errors.New("unable to detect indirect object signature")
-	}
-
-	on, _ := strconv.Atoi(result[1])
-	gn, _ := strconv.Atoi(result[2])
-
-	return on, gn, nil
-}
-
 // Parse the entire file from top down.
-// Goes through the file byte-by-byte looking for "<num> <generation> obj" patterns.
-// N.B. This collects the XrefTypeTableEntry data only.
+// Goes through the file looking for "<num> <generation> obj" patterns, using the last definition
+// of each object. Objects contained in object streams are also indexed.
 func (parser *PdfParser) repairRebuildXrefsTopDown() (*XrefTable, error) {
 	if parser.repairsAttempted {
 		// Avoid multiple repairs (only try once).
@@ -105,85 +95,17 @@ func (parser *PdfParser) repairRebuildXrefsTopDown() (*XrefTable, error) {
 	}
 	parser.repairsAttempted = true
 
-	// Go to beginning, reset reader.
-	parser.rs.Seek(0, os.SEEK_SET)
-	parser.reader = bufio.NewReader(parser.rs)
-
-	// Keep a running buffer of last bytes.
-	bufLen := 20
-	last := make([]byte, bufLen)
-
-	xrefTable := XrefTable{}
-	xrefTable.ObjectMap = make(map[int]XrefObject)
-	for {
-		b, err := parser.reader.ReadByte()
-		if err != nil {
-			if err == io.EOF {
-				break
-			} else {
-				return nil, err
-			}
-		}
-
-		// Format:
-		// object number - whitespace - generation number - obj
-		// e.g. "12 0 obj"
-		if b == 'j' && last[bufLen-1] == 'b' && last[bufLen-2] == 'o' && IsWhiteSpace(last[bufLen-3]) {
-			i := bufLen - 4
-			// Go past whitespace
-			for IsWhiteSpace(last[i]) && i > 0 {
-				i--
-			}
-			if i == 0 || !IsDecimalDigit(last[i]) {
-				continue
-			}
-			// Go past generation number
-			for IsDecimalDigit(last[i]) && i > 0 {
-				i--
-			}
-			if i == 0 || !IsWhiteSpace(last[i]) {
-				continue
-			}
-			// Go past whitespace
-			for IsWhiteSpace(last[i]) && i > 0 {
-				i--
-			}
-			if i == 0 || !IsDecimalDigit(last[i]) {
-				continue
-			}
-			// Go past object number.
-			for IsDecimalDigit(last[i]) && i > 0 {
-				i--
-			}
-			if i == 0 {
-				continue // Probably too long to be a valid object...
-			}
-
-			objOffset := parser.GetFileOffset() - int64(bufLen-i)
-
-			objstr := append(last[i+1:], b)
-			objNum, genNum, err := parseObjectNumberFromString(string(objstr))
-			if err != nil {
-				common.Log.Debug("Unable to parse object number: %v", err)
-				return nil, err
-			}
-
-			// Create and insert the XREF entry if not existing, or the generation number is higher.
-			if curXref, has := xrefTable.ObjectMap[objNum]; !has || curXref.Generation < genNum {
-				// Make the entry for the cross ref table.
-				xrefEntry := XrefObject{}
-				xrefEntry.XType = XrefTypeTableEntry
-				xrefEntry.ObjectNumber = int(objNum)
-				xrefEntry.Generation = int(genNum)
-				xrefEntry.Offset = objOffset
-				xrefTable.ObjectMap[objNum] = xrefEntry
-			}
-		}
-
-		last = append(last[1:bufLen], b)
+	scan, err := parser.repairScanObjects()
+	if err != nil {
+		return nil, err
 	}
+	parser.addRepairWarning(RepairXrefRebuilt, 0, -1,
+		"cross-reference table rebuilt by scanning the file (%d objects)", len(scan.xrefs))
 
-	return &xrefTable, nil
+	// Object streams of encrypted files can only be read once the decryption is set up.
+	encrypted := parser.crypter == nil && parser.trailer != nil && parser.trailer.Get("Encrypt") != nil
+	parser.repairRebuildXrefs(scan, encrypted)
+	return &parser.xrefs, nil
 }
 
 // Look for first sign of xref table from end of file.
@@ -217,12 +139,12 @@ func (parser *PdfParser) repairSeekXrefMarker() error {
 		b1 := make([]byte, buflen)
 		parser.rs.Read(b1)
 
-		common.Log.Trace("Looking for xref : \"%s\"", string(b1))
+		parser.log().Trace("Looking for xref : \"%s\"", string(b1))
 		ind := reXrefTableStart.FindAllStringIndex(string(b1), -1)
 		if ind != nil {
 			// Found it.
 			lastInd := ind[len(ind)-1]
-			common.Log.Trace("Ind: % d", ind)
+			parser.log().Trace("Ind: % d", ind)
 			parser.rs.Seek(-offset-buflen+int64(lastInd[0]), os.SEEK_END)
 			parser.reader = bufio.NewReader(parser.rs)
 			// Go past whitespace, finish at 'x'.
@@ -231,7 +153,7 @@ func (parser *PdfParser) repairSeekXrefMarker() error {
 				if err != nil {
 					return err
 				}
-				common.Log.Trace("B: %d %c", bb[0], bb[0])
+				parser.log().Trace("B: %d %c", bb[0], bb[0])
 				if !IsWhiteSpace(bb[0]) {
 					break
 				}
@@ -241,11 +163,11 @@ func (parser *PdfParser) repairSeekXrefMarker() error {
 			return nil
 		}
 
-		common.Log.Debug("Warning: EOF marker not found! - continue seeking")
+		parser.log().Debug("Warning: EOF marker not found! - continue seeking")
 		offset += buflen
 	}
 
-	common.Log.Debug("Error: Xref table marker was not found.")
+	parser.log().Debug("Error: Xref table marker was not found.")
 	return errors.New("xref not found ")
 }
 
@@ -285,3 +207,571 @@ func (parser *PdfParser) seekPdfVersionTopDown() (int, int, error) {
 
 	return 0, 0, errors.New("version not found")
 }
+
+// RepairType identifies the kind of repair applied by the parser to a damaged file.
+type RepairType int
+
+// Repair types reported in RepairWarning.
+const (
+	// RepairXrefLocated indicates that the startxref offset was invalid and the cross-reference
+	// section was located by searching the file.
+	RepairXrefLocated RepairType = iota
+	// RepairXrefRebuilt indicates that the cross-reference table was rebuilt by scanning the whole
+	// file for object definitions.
+	RepairXrefRebuilt
+	// RepairXrefRenumbered indicates that the cross-reference table had entries pointing to
+	// objects with a different object number, which were renumbered.
+	RepairXrefRenumbered
+	// RepairObjectRedefined indicates that an object was defined multiple times in the file. The last
+	// definition is used.
+	RepairObjectRedefined
+	// RepairTrailerRebuilt indicates that the trailer was missing or invalid and was reconstructed.
+	RepairTrailerRebuilt
+	// RepairCatalogLocated indicates that the document catalog was located by its /Type /Catalog entry.
+	RepairCatalogLocated
+	// RepairStreamLength indicates that a stream /Length entry was invalid and was corrected by
+	// locating the endstream keyword.
+	RepairStreamLength
+	// RepairDataSkipped indicates that data which could not be tokenized, such as an unterminated
+	// string or a stream without endstream keyword, was skipped up to the next object header while
+	// scanning the file.
+	RepairDataSkipped
+)
+
+// String returns a string describing the repair type.
+func (t RepairType) String() string {
+	switch t {
+	case RepairXrefLocated:
+		return "xref located"
+	case RepairXrefRebuilt:
+		return "xref rebuilt"
+	case RepairXrefRenumbered:
+		return "xref renumbered"
+	case RepairObjectRedefined:
+		return "object redefined"
+	case RepairTrailerRebuilt:
+		return "trailer rebuilt"
+	case RepairCatalogLocated:
+		return "catalog located"
+	case RepairStreamLength:
+		return "stream length"
+	case RepairDataSkipped:
+		return "data skipped"
+	}
+	return fmt.Sprintf("repair(%d)", int(t))
+}
+
+// RepairWarning describes a repair applied by the parser when loading a damaged file.
+type RepairWarning struct {
+	// Type of the repair.
+	Type RepairType
+
+	// ObjectNumber is the number of the object concerned by the repair, or 0 if the repair does not
+	// concern a specific object.
+	ObjectNumber int64
+
+	// Offset is the byte offset in the file where the problem was detected, or -1 if not applicable.
+	Offset int64
+
+	// Message describes the repair.
+	Message string
+}
+
+// String returns a string describing the repair.
+func (w RepairWarning) String() string {
+	var loc string
+	if w.ObjectNumber > 0 {
+		loc = fmt.Sprintf(" (object %d)", w.ObjectNumber)
+	}
+	if w.Offset >= 0 {
+		loc += fmt.Sprintf(" (offset %d)", w.Offset)
+	}
+	return fmt.Sprintf("%s%s: %s", w.Type, loc, w.Message)
+}
+
+// GetRepairWarnings returns the repairs applied by the parser so far. The list is empty if the
+// file was not damaged. Repairs can be applied both when the parser is created and when objects are
+// loaded.
+func (parser *PdfParser) GetRepairWarnings() []RepairWarning {
+	parser.lock.Lock()
+	defer parser.lock.Unlock()
+	return append([]RepairWarning(nil), parser.repairs...)
+}
+
+// addRepairWarning records a repair applied to the file.
+func (parser *PdfParser) addRepairWarning(typ RepairType, objNum, offset int64, format string, args ...interface{}) {
+	w := RepairWarning{
+		Type:         typ,
+		ObjectNumber: objNum,
+		Offset:       offset,
+		Message:      fmt.Sprintf(format, args...),
+	}
+	parser.repairs = append(parser.repairs, w)
+
+	code := common.DiagnosticXrefRepaired
+	if typ == RepairStreamLength {
+		code = common.DiagnosticStreamLength
+	}
+	parser.report(common.LogLevelWarning, code, objNum, "%s", w.Message)
+}
+
+// repairScan contains the results of a linear scan of the file.
+type repairScan struct {
+	// Last definition of each object.
+	xrefs map[int]XrefObject
+	// Numbers of object streams, in order of appearance.
+	objstms []int
+	// Number of the last object with /Type /Catalog (0 if not found).
+	catalog int
+	// Trailer dictionaries and cross-reference stream dictionaries, in order of appearance.
+	trailers []*PdfObjectDictionary
+}
+
+// repairScanObjects scans the whole file for "N G obj" object headers and trailer dictionaries.
+// The last definition of each object is used. When the data cannot be tokenized, the scan resumes
+// at the next object header.
+func (parser *PdfParser) repairScanObjects() (*repairScan, error) {
+	defer parser.SetFileOffset(0)
+
+	scan := &repairScan{
+		xrefs: map[int]XrefObject{},
+	}
+
+	// Offset of the lexer input in the file and offset following the last data read successfully.
+	var base, lastEnd int64
+	if _, err := parser.rs.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	lexer := NewLexer(parser.rs)
+
+	// Last two tokens if integers, for detecting object headers.
+	type intToken struct {
+		val    int64
+		offset int64
+		ok     bool
+	}
+	var prev [2]intToken
+
+	// resync restarts the lexer at the next object header following the data read successfully,
+	// after failing to read data with `err`. Returns false if there is no further object header.
+	resync := func(err error) (bool, error) {
+		prev = [2]intToken{}
+		offset, ferr := parser.repairFindObjectHeader(lastEnd)
+		if ferr != nil {
+			return false, ferr
+		}
+		if offset < 0 {
+			parser.addRepairWarning(RepairDataSkipped, 0, lastEnd,
+				"unreadable data skipped from offset %d to the end of the file: %v", lastEnd, err)
+			return false, nil
+		}
+		parser.addRepairWarning(RepairDataSkipped, 0, lastEnd,
+			"unreadable data skipped from offset %d to %d: %v", lastEnd, offset, err)
+		if _, err := parser.rs.Seek(offset, io.SeekStart); err != nil {
+			return false, err
+		}
+		base, lastEnd = offset, offset
+		lexer = NewLexer(parser.rs)
+		return true, nil
+	}
+
+	for {
+		tok, err := lexer.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			ok, err := resync(err)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				break
+			}
+			continue
+		}
+		tokOffset := base + tok.Offset
+		lastEnd = tokOffset + int64(len(tok.Raw))
+
+		switch {
+		case tok.Type == TokenObj && prev[0].ok && prev[1].ok:
+			objNum, genNum, offset := int(prev[0].val), int(prev[1].val), prev[0].offset
+			prev = [2]intToken{}
+			if objNum <= 0 || genNum < 0 {
+				continue
+			}
+			if _, has := scan.xrefs[objNum]; has {
+				parser.addRepairWarning(RepairObjectRedefined, int64(objNum), offset,
+					"object %d redefined, using last definition", objNum)
+			}
+			scan.xrefs[objNum] = XrefObject{
+				XType:        XrefTypeTableEntry,
+				ObjectNumber: objNum,
+				Generation:   genNum,
+				Offset:       offset,
+			}
+
+			// Check the type of the object for locating object streams, the catalog and
+			// cross-reference streams.
+			obj, err := lexer.NextObject()
+			if err != nil {
+				parser.log().Debug("Repair: failed to read object %d at offset %d: %v", objNum, offset, err)
+				ok, err := resync(err)
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					return scan, nil
+				}
+				continue
+			}
+			lastEnd = base + lexer.Offset()
+			dict, ok := obj.(*PdfObjectDictionary)
+			if !ok {
+				continue
+			}
+			name, _ := GetName(dict.Get("Type"))
+			if name == nil {
+				continue
+			}
+			switch *name {
+			case "Catalog":
+				scan.catalog = objNum
+			case "ObjStm":
+				scan.objstms = append(scan.objstms, objNum)
+			case "XRef":
+				scan.trailers = append(scan.trailers, dict)
+			}
+			continue
+		case tok.Is("trailer"):
+			obj, err := lexer.NextObject()
+			if err != nil {
+				parser.log().Debug("Repair: failed to read trailer at offset %d: %v", tokOffset, err)
+				ok, err := resync(err)
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					return scan, nil
+				}
+				continue
+			}
+			lastEnd = base + lexer.Offset()
+			if dict, ok := obj.(*PdfObjectDictionary); ok {
+				scan.trailers = append(scan.trailers, dict)
+			}
+			prev = [2]intToken{}
+			continue
+		}
+
+		val, isInt := tok.Int()
+		prev[0], prev[1] = prev[1], intToken{val: val, offset: tokOffset, ok: isInt}
+	}
+
+	return scan, nil
+}
+
+// repairSearchChunkSize is the size of the chunks read when searching the file for object headers.
+const repairSearchChunkSize = 64 * 1024
+
+// repairFindObjectHeader returns the offset of the first "N G obj" object header starting at or
+// after `offset`, or -1 if not found. The file is searched as bytes, regardless of the tokens
+// containing `offset`.
+func (parser *PdfParser) repairFindObjectHeader(offset int64) (int64, error) {
+	// Chunks overlap so that headers crossing a chunk boundary are found.
+	const overlap = 64
+	buf := make([]byte, repairSearchChunkSize)
+	for pos := offset; ; {
+		if _, err := parser.rs.Seek(pos, io.SeekStart); err != nil {
+			return -1, err
+		}
+		n, err := io.ReadFull(parser.rs, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return -1, err
+		}
+		data := buf[:n]
+		eof := n < len(buf)
+
+		for i := 0; ; {
+			j := bytes.Index(data[i:], []byte("obj"))
+			if j < 0 {
+				break
+			}
+			j += i
+			i = j + 1
+			if end := j + 3; end == len(data) && !eof {
+				// Keyword possibly cut, checked in the next chunk.
+				break
+			} else if end < len(data) && !IsWhiteSpace(data[end]) && !IsDelimiter(data[end]) {
+				continue
+			}
+			if start := objectHeaderStart(data, j); start >= 0 {
+				return pos + int64(start), nil
+			}
+		}
+		if eof {
+			return -1, nil
+		}
+		pos += int64(n - overlap)
+	}
+}
+
+// objectHeaderStart returns the index of the object number of the object header ending with the
+// obj keyword at index `j` of `data`, or -1 if the keyword is not preceded by an object number and
+// a generation number.
+func objectHeaderStart(data []byte, j int) int {
+	k := j
+	for field := 0; field < 2; field++ {
+		ws := k
+		for ws > 0 && IsWhiteSpace(data[ws-1]) {
+			ws--
+		}
+		digits := ws
+		for digits > 0 && IsDecimalDigit(data[digits-1]) {
+			digits--
+		}
+		if ws == k || digits == ws {
+			return -1
+		}
+		k = digits
+	}
+	if k > 0 && !IsWhiteSpace(data[k-1]) && !IsDelimiter(data[k-1]) {
+		return -1
+	}
+	return k
+}
+
+// repairRebuildXrefs rebuilds the cross-reference table from the objects found by scanning the file,
+// including the objects contained in object streams. Objects in object streams are indexed only when
+// the stream is not encrypted.
+func (parser *PdfParser) repairRebuildXrefs(scan *repairScan, encrypted bool) {
+	parser.xrefs = XrefTable{ObjectMap: scan.xrefs}
+	parser.resetObjectCache()
+
+	if encrypted && len(scan.objstms) > 0 {
+		parser.log().Debug("Repair: unable to index %d encrypted object streams", len(scan.objstms))
+		return
+	}
+
+	direct := map[int]XrefObject{}
+	for objNum, xref := range scan.xrefs {
+		direct[objNum] = xref
+	}
+	for _, osNum := range scan.objstms {
+		objstm, err := parser.loadObjectStream(osNum)
+		if err != nil {
+			parser.log().Debug("Repair: failed to load object stream %d: %v", osNum, err)
+			continue
+		}
+
+		osOffset := direct[osNum].Offset
+		for objNum := range objstm.offsets {
+			// Objects defined directly after the object stream take precedence.
+			if xref, has := direct[objNum]; has && xref.Offset > osOffset {
+				continue
+			}
+			parser.xrefs.ObjectMap[objNum] = XrefObject{
+				XType:        XrefTypeObjectStream,
+				ObjectNumber: objNum,
+				OsObjNumber:  osNum,
+			}
+		}
+	}
+}
+
+// repairReconstruct reconstructs the cross-reference table and the trailer of a file whose
+// cross-reference information is missing or corrupt, by scanning the whole file for object
+// definitions. If no usable trailer is found, the catalog is located by its /Type /Catalog entry.
+// Returns the trailer dictionary.
+func (parser *PdfParser) repairReconstruct() (*PdfObjectDictionary, error) {
+	if parser.repairsAttempted {
+		// Avoid multiple repairs (only try once).
+		return nil, fmt.Errorf("repair failed")
+	}
+	parser.repairsAttempted = true
+
+	fSize, err := parser.rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	parser.fileSize = fSize
+
+	scan, err := parser.repairScanObjects()
+	if err != nil {
+		return nil, err
+	}
+	if len(scan.xrefs) == 0 {
+		return nil, errors.New("repair: no objects found")
+	}
+	parser.addRepairWarning(RepairXrefRebuilt, 0, -1,
+		"cross-reference table rebuilt by scanning the file (%d objects)", len(scan.xrefs))
+
+	var trailer *PdfObjectDictionary
+	var encrypted bool
+	for i := len(scan.trailers) - 1; i >= 0; i-- {
+		if scan.trailers[i].Get("Encrypt") != nil {
+			encrypted = true
+		}
+		if trailer == nil && scan.trailers[i].Get("Root") != nil {
+			trailer = scan.trailers[i]
+		}
+	}
+	parser.repairRebuildXrefs(scan, encrypted)
+
+	// Check that the trailer references a catalog.
+	if trailer != nil {
+		if _, ok := GetDict(parser.repairResolve(trailer.Get("Root"))); !ok {
+			parser.log().Debug("Repair: trailer Root is not a valid dictionary")
+			trailer = nil
+		}
+	}
+
+	if trailer == nil {
+		catalog := scan.catalog
+		if catalog == 0 {
+			catalog = parser.repairFindCatalog()
+		}
+		if catalog == 0 {
+			return nil, errors.New("repair: catalog not found")
+		}
+		parser.addRepairWarning(RepairCatalogLocated, int64(catalog), -1,
+			"catalog located as object %d", catalog)
+
+		maxObjNum := 0
+		for objNum := range parser.xrefs.ObjectMap {
+			if objNum > maxObjNum {
+				maxObjNum = objNum
+			}
+		}
+
+		trailer = MakeDict()
+		trailer.Set("Size", MakeInteger(int64(maxObjNum+1)))
+		trailer.Set("Root", &PdfObjectReference{
+			ObjectNumber:     int64(catalog),
+			GenerationNumber: int64(parser.xrefs.ObjectMap[catalog].Generation),
+			parser:           parser,
+		})
+		// Keep the document information and identifiers from the last trailer defining them.
+		for _, key := range []PdfObjectName{"Info", "ID", "Encrypt"} {
+			for i := len(scan.trailers) - 1; i >= 0; i-- {
+				if val := scan.trailers[i].Get(key); val != nil {
+					trailer.Set(key, val)
+					break
+				}
+			}
+		}
+		parser.addRepairWarning(RepairTrailerRebuilt, 0, -1, "trailer reconstructed")
+	}
+
+	// Record the reconstructed file as a single revision.
+	rev := newPdfRevision(0)
+	rev.EndOffset = parser.fileSize
+	rev.Trailer = trailer
+	for objNum, xref := range parser.xrefs.ObjectMap {
+		rev.Entries[objNum] = xref
+	}
+	parser.revisions = []*PdfRevision{rev}
+	parser.finalizeRevisions()
+
+	parser.resetObjectCache()
+	return trailer, nil
+}
+
+// repairResolve resolves `obj` if it is a reference, returning the direct object.
+func (parser *PdfParser) repairResolve(obj PdfObject) PdfObject {
+	ref, ok := obj.(*PdfObjectReference)
+	if !ok {
+		return obj
+	}
+	resolved, _, err := parser.lookupByNumber(int(ref.ObjectNumber), false)
+	if err != nil {
+		return nil
+	}
+	return TraceToDirectObject(resolved)
+}
+
+// repairFindCatalog looks for the catalog among the objects contained in object streams.
+// Returns the object number of the catalog or 0 if not found.
+func (parser *PdfParser) repairFindCatalog() int {
+	var objNums []int
+	for objNum, xref := range parser.xrefs.ObjectMap {
+		if xref.XType == XrefTypeObjectStream {
+			objNums = append(objNums, objNum)
+		}
+	}
+	sort.Ints(objNums)
+
+	catalog := 0
+	for _, objNum := range objNums {
+		obj, _, err := parser.lookupByNumber(objNum, false)
+		if err != nil {
+			continue
+		}
+		dict, ok := GetDict(obj)
+		if !ok {
+			continue
+		}
+		if name, ok := GetName(dict.Get("Type")); ok && *name == "Catalog" {
+			catalog = objNum
+		}
+	}
+	return catalog
+}
+
+// repairStreamLength checks that the stream data starting at `offset` with length `length` is
+// followed by the endstream keyword. If not, the endstream keyword is searched for and the corrected
+// length is returned along with true.
+func (parser *PdfParser) repairStreamLength(offset, length int64) (int64, bool) {
+	endMarker := []byte("endstream")
+	objMarker := []byte("endobj")
+
+	// Check the bytes following the stream data.
+	end := offset + length
+	if end <= parser.fileSize {
+		n := minInt64(int64(len(endMarker)+16), parser.fileSize-end)
+		bb, err := parser.readBytesAt(end, n)
+		if err != nil {
+			return length, false
+		}
+		bb = bytes.TrimLeft(bb, "\x00\t\n\f\r ")
+		if bytes.HasPrefix(bb, endMarker) || bytes.HasPrefix(bb, objMarker) {
+			return length, false
+		}
+	}
+
+	// Search for the endstream keyword. The search stops at endobj in case the endstream keyword
+	// is missing.
+	const bufLen = 4096
+	var carry []byte
+	for pos := offset; pos < parser.fileSize; pos += bufLen {
+		bb, err := parser.readBytesAt(pos, minInt64(bufLen, parser.fileSize-pos))
+		if err != nil {
+			return length, false
+		}
+		buf := append(carry, bb...)
+		bufStart := pos - int64(len(carry))
+
+		i := bytes.Index(buf, endMarker)
+		if j := bytes.Index(buf, objMarker); j >= 0 && (i < 0 || j < i) {
+			return length, false
+		}
+		if i >= 0 {
+			data := buf[:i]
+			if n := len(data); n > 0 && data[n-1] == '\n' {
+				data = data[:n-1]
+			}
+			if n := len(data); n > 0 && data[n-1] == '\r' {
+				data = data[:n-1]
+			}
+			newLength := bufStart + int64(len(data)) - offset
+			if newLength < 0 {
+				return length, false
+			}
+			return newLength, true
+		}
+		if len(buf) >= len(endMarker) {
+			carry = append([]byte{}, buf[len(buf)-len(endMarker)+1:]...)
+		}
+	}
+	return length, false
+}
@@ -0,0 +1,221 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// repairTypes returns the types of the repairs applied by `parser`.
+func repairTypes(parser *PdfParser) []RepairType {
+	var types []RepairType
+	for _, w := range parser.GetRepairWarnings() {
+		types = append(types, w.Type)
+	}
+	return types
+}
+
+func TestRepairReconstructMissingXref(t *testing.T) {
+	// Truncated file: no xref table, no trailer. Object 3 is redefined and the stream Length is wrong.
+	data := []byte(`%PDF-1.4
+1 0 obj
+<< /Type /Catalog /Pages 2 0 R >>
+endobj
+2 0 obj
+<< /Type /Pages /Kids [] /Count 0 >>
+endobj
+3 0 obj
+(old)
+endobj
+4 0 obj
+<< /Length 100 >>
+stream
+BT /F1 12 Tf (Hello) Tj ET
+endstream
+endobj
+3 0 obj
+(new)
+endobj
+xref
+0 5
+0000000000 65535 f
+00000000`)
+
+	parser, err := NewParser(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	require.Equal(t, []RepairType{RepairObjectRedefined, RepairXrefRebuilt, RepairCatalogLocated, RepairTrailerRebuilt},
+		repairTypes(parser))
+	w := parser.GetRepairWarnings()[0]
+	require.Equal(t, int64(3), w.ObjectNumber)
+	require.Equal(t, int64(bytes.LastIndex(data, []byte("3 0 obj"))), w.Offset)
+
+	trailer := parser.GetTrailer()
+	root, ok := trailer.Get("Root").(*PdfObjectReference)
+	require.True(t, ok)
+	require.Equal(t, int64(1), root.ObjectNumber)
+	size, ok := GetIntVal(trailer.Get("Size"))
+	require.True(t, ok)
+	require.Equal(t, 5, size)
+
+	// Last definition is used.
+	obj, err := parser.LookupByNumber(3)
+	require.NoError(t, err)
+	str, ok := GetStringVal(obj)
+	require.True(t, ok)
+	require.Equal(t, "new", str)
+
+	// Stream length corrected.
+	obj, err = parser.LookupByNumber(4)
+	require.NoError(t, err)
+	stream, ok := obj.(*PdfObjectStream)
+	require.True(t, ok)
+	require.Equal(t, "BT /F1 12 Tf (Hello) Tj ET", string(stream.Stream))
+	length, ok := GetIntVal(stream.Get("Length"))
+	require.True(t, ok)
+	require.Equal(t, len(stream.Stream), length)
+
+	w = parser.GetRepairWarnings()[4]
+	require.Equal(t, RepairStreamLength, w.Type)
+	require.Equal(t, int64(4), w.ObjectNumber)
+
+	require.Len(t, parser.GetRevisions(), 1)
+}
+
+func TestRepairReconstructTrailer(t *testing.T) {
+	// Valid trailer, but xref offsets are garbage and startxref is missing.
+	data := []byte(`%PDF-1.4
+1 0 obj
+<< /Type /Catalog /Pages 2 0 R >>
+endobj
+2 0 obj
+<< /Type /Pages /Kids [] /Count 0 >>
+endobj
+3 0 obj
+<< /Producer (test) >>
+endobj
+xref
+0 4
+garbage
+trailer
+<< /Size 4 /Root 1 0 R /Info 3 0 R >>
+%%EOF
+`)
+
+	parser, err := NewParser(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, []RepairType{RepairXrefRebuilt}, repairTypes(parser))
+
+	infoRef, ok := parser.GetTrailer().Get("Info").(*PdfObjectReference)
+	require.True(t, ok)
+	obj, err := parser.LookupByReference(*infoRef)
+	require.NoError(t, err)
+	info, ok := GetDict(obj)
+	require.True(t, ok)
+	producer, ok := GetStringVal(info.Get("Producer"))
+	require.True(t, ok)
+	require.Equal(t, "test", producer)
+}
+
+func TestRepairReconstructObjectStream(t *testing.T) {
+	// Catalog contained in an (unfiltered) object stream, no trailer.
+	objstm := "1 0 2 34 << /Type /Catalog /Pages 2 0 R >>\n<< /Type /Pages /Kids [] /Count 0 >>"
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.5\n5 0 obj\n")
+	fmt.Fprintf(&buf, "<< /Type /ObjStm /N 2 /First 9 /Length %d >>\nstream\n", len(objstm))
+	buf.WriteString(objstm)
+	buf.WriteString("\nendstream\nendobj\n")
+
+	parser, err := NewParser(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, []RepairType{RepairXrefRebuilt, RepairCatalogLocated, RepairTrailerRebuilt},
+		repairTypes(parser))
+
+	root, ok := parser.GetTrailer().Get("Root").(*PdfObjectReference)
+	require.True(t, ok)
+	require.Equal(t, int64(1), root.ObjectNumber)
+
+	obj, err := parser.LookupByNumber(2)
+	require.NoError(t, err)
+	pages, ok := GetDict(obj)
+	require.True(t, ok)
+	name, ok := GetName(pages.Get("Type"))
+	require.True(t, ok)
+	require.Equal(t, PdfObjectName("Pages"), *name)
+}
+
+func TestRepairSkipUnreadableData(t *testing.T) {
+	testcases := []struct {
+		name    string
+		garbage string
+	}{
+		{"unbalanced string", "( unbalanced\n"},
+		{"stream without endstream", "5 0 obj\n<< /Length 4 >>\nstream\ndata\nendobj\n"},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			// No xref table. The objects following the garbage must still be found.
+			head := "%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n"
+			data := []byte(head + tcase.garbage + `2 0 obj
+<< /Type /Pages /Kids [] /Count 0 >>
+endobj
+3 0 obj
+(text)
+endobj
+`)
+
+			parser, err := NewParser(bytes.NewReader(data))
+			require.NoError(t, err)
+			require.Equal(t, []RepairType{RepairDataSkipped, RepairXrefRebuilt, RepairCatalogLocated,
+				RepairTrailerRebuilt}, repairTypes(parser))
+			w := parser.GetRepairWarnings()[0]
+			require.GreaterOrEqual(t, w.Offset, int64(len(head)-1))
+			require.Less(t, w.Offset, int64(len(head)+len(tcase.garbage)))
+
+			obj, err := parser.LookupByNumber(2)
+			require.NoError(t, err)
+			pages, ok := GetDict(obj)
+			require.True(t, ok)
+			count, ok := GetIntVal(pages.Get("Count"))
+			require.True(t, ok)
+			require.Equal(t, 0, count)
+
+			obj, err = parser.LookupByNumber(3)
+			require.NoError(t, err)
+			str, ok := GetStringVal(obj)
+			require.True(t, ok)
+			require.Equal(t, "text", str)
+		})
+	}
+}
+
+func TestRepairFindObjectHeader(t *testing.T) {
+	pad := strings.Repeat("x", repairSearchChunkSize-10)
+	testcases := []struct {
+		data     string
+		expected int64
+	}{
+		{"(garbage\n12 0 obj", 9},
+		{"endobj 12 0 objx 3 0 obj", 17},
+		{"1 0 endobj", -1},
+		{"x1 0 obj", -1},
+		{pad + " 7 0 obj", int64(len(pad)) + 1},
+		{pad + " 7 0 ob", -1},
+	}
+
+	for _, tcase := range testcases {
+		parser := &PdfParser{rs: bytes.NewReader([]byte(tcase.data))}
+		offset, err := parser.repairFindObjectHeader(0)
+		require.NoError(t, err)
+		require.Equal(t, tcase.expected, offset)
+	}
+}
@@ -0,0 +1,248 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+)
+
+// PdfRevision represents a single revision of a PDF document. The first revision (index 0) is the
+// original document body, and every following revision corresponds to an incremental update
+// appended to the file (section 7.5.6 Incremental Updates).
+// A revision is defined by its cross-reference section(s) and the trailer following them.
+type PdfRevision struct {
+	// Index of the revision within the document, 0 being the original document.
+	Index int
+
+	// StartXref is the byte offset of the cross-reference section of the revision, i.e. the value
+	// of the startxref keyword at the time the revision was written.
+	StartXref int64
+
+	// EndOffset is the byte offset immediately following the %%EOF marker of the revision.
+	// It is the size of the document as it was when the revision was written.
+	EndOffset int64
+
+	// Trailer is the trailer dictionary of the revision (or the cross-reference stream dictionary).
+	Trailer *PdfObjectDictionary
+
+	// XrefType indicates whether the revision uses an xref table or an xref stream.
+	XrefType xrefType
+
+	// Entries contains the in-use cross-reference entries defined by the revision.
+	Entries map[int]XrefObject
+
+	// Added lists the numbers of objects that were first defined in the revision.
+	Added []int
+
+	// Changed lists the numbers of objects that were defined in an earlier revision and are
+	// redefined in this revision.
+	Changed []int
+
+	// Freed lists the numbers of objects that were in use in an earlier revision and are marked
+	// as free in this revision.
+	Freed []int
+
+	// freed collects object numbers of free entries while the section is being parsed.
+	freed map[int]struct{}
+}
+
+// newPdfRevision returns a new revision for the cross-reference section at `startxref`.
+func newPdfRevision(startxref int64) *PdfRevision {
+	return &PdfRevision{
+		StartXref: startxref,
+		Entries:   map[int]XrefObject{},
+		freed:     map[int]struct{}{},
+	}
+}
+
+// setEntry records an in-use entry of the revision. Entries already recorded take precedence,
+// as they come from a more recent cross-reference section of the same revision.
+func (rev *PdfRevision) setEntry(xref XrefObject) {
+	if _, has := rev.Entries[xref.ObjectNumber]; has {
+		return
+	}
+	if _, has := rev.freed[xref.ObjectNumber]; has {
+		return
+	}
+	rev.Entries[xref.ObjectNumber] = xref
+}
+
+// setFree records a free entry of the revision.
+func (rev *PdfRevision) setFree(objNum int) {
+	if objNum == 0 {
+		// Object 0 is always the head of the free list.
+		return
+	}
+	if _, has := rev.Entries[objNum]; has {
+		return
+	}
+	rev.freed[objNum] = struct{}{}
+}
+
+// merge merges `older` cross-reference section into `rev`.
+func (rev *PdfRevision) merge(older *PdfRevision) {
+	for objNum, xref := range older.Entries {
+		xref.ObjectNumber = objNum
+		rev.setEntry(xref)
+	}
+	for objNum := range older.freed {
+		rev.setFree(objNum)
+	}
+	if older.EndOffset > rev.EndOffset {
+		rev.EndOffset = older.EndOffset
+	}
+}
+
+// GetRevisions returns the revisions of the document, ordered from the original document (index 0)
+// to the most recent incremental update.
+func (parser *PdfParser) GetRevisions() []*PdfRevision {
+	return parser.revisions
+}
+
+// GetRevisionReader returns a reader limited to the bytes of the document at revision `index`, i.e.
+// the file content up to and including the %%EOF marker of the revision. The returned reader can be
+// used to open the document as it was at that revision.
+// If the underlying reader does not implement io.ReaderAt, the revision content is read into memory.
+func (parser *PdfParser) GetRevisionReader(index int) (io.ReadSeeker, error) {
+	if index < 0 || index >= len(parser.revisions) {
+		return nil, errors.New("revision index out of range")
+	}
+	rev := parser.revisions[index]
+
+	if ra, ok := parser.rs.(io.ReaderAt); ok {
+		return io.NewSectionReader(ra, 0, rev.EndOffset), nil
+	}
+
+	data, err := parser.ReadBytesAt(0, rev.EndOffset)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// beginRevisionSection starts recording the cross-reference entries of the section located at `offset`.
+func (parser *PdfParser) beginRevisionSection(offset int64) {
+	parser.xrefSection = newPdfRevision(offset)
+}
+
+// endRevisionSection finishes recording the current cross-reference section, which ends at `offset`,
+// and adds it to the revisions being loaded. The sections are expected to be visited from the most
+// recent one following the Prev chain.
+func (parser *PdfParser) endRevisionSection(trailer *PdfObjectDictionary, offset int64) {
+	section := parser.xrefSection
+	parser.xrefSection = nil
+	if section == nil {
+		return
+	}
+	section.Trailer = trailer
+	section.EndOffset = parser.seekRevisionEnd(offset)
+
+	if n := len(parser.revisions); n > 0 {
+		newer := parser.revisions[n-1]
+		if section.StartXref > newer.StartXref {
+			// The Prev section is located after the section referencing it, as is the case for the
+			// first-page cross-reference section of linearized files. Both sections describe the
+			// same revision.
+			newer.merge(section)
+			return
+		}
+	}
+	parser.revisions = append(parser.revisions, section)
+}
+
+// finalizeRevisions orders the recorded revisions from oldest to newest and determines which
+// objects were added, changed or freed by each revision. Entries that are repeated with the same
+// location as in an earlier revision (as is common for hybrid-reference files) are not considered
+// as changed.
+func (parser *PdfParser) finalizeRevisions() {
+	revs := parser.revisions
+	for i, j := 0, len(revs)-1; i < j; i, j = i+1, j-1 {
+		revs[i], revs[j] = revs[j], revs[i]
+	}
+
+	inUse := map[int]XrefObject{}
+	for i, rev := range revs {
+		rev.Index = i
+		rev.Added = nil
+		rev.Changed = nil
+		rev.Freed = nil
+
+		objNums := make([]int, 0, len(rev.Entries))
+		for objNum := range rev.Entries {
+			objNums = append(objNums, objNum)
+		}
+		sort.Ints(objNums)
+		for _, objNum := range objNums {
+			xref := rev.Entries[objNum]
+			prev, has := inUse[objNum]
+			switch {
+			case !has:
+				rev.Added = append(rev.Added, objNum)
+			case prev != xref:
+				rev.Changed = append(rev.Changed, objNum)
+			}
+			inUse[objNum] = xref
+		}
+
+		for objNum := range rev.freed {
+			if _, has := inUse[objNum]; has {
+				rev.Freed = append(rev.Freed, objNum)
+				delete(inUse, objNum)
+			}
+		}
+		sort.Ints(rev.Freed)
+	}
+	parser.log().Trace("Loaded %d revisions", len(revs))
+}
+
+// seekRevisionEnd looks for the %%EOF marker following `offset` and returns the offset immediately
+// following it, including the end-of-line marker. If no marker is found, the file size is returned.
+func (parser *PdfParser) seekRevisionEnd(offset int64) int64 {
+	const bufLen = 1024
+	marker := []byte("%%EOF")
+
+	var carry []byte
+	pos := offset
+	for pos < parser.fileSize {
+		n := int64(bufLen)
+		if pos+n > parser.fileSize {
+			n = parser.fileSize - pos
+		}
+		bb, err := parser.readBytesAt(pos, n)
+		if err != nil {
+			break
+		}
+		buf := append(carry, bb...)
+		bufStart := pos - int64(len(carry))
+		if i := bytes.Index(buf, marker); i >= 0 {
+			end := bufStart + int64(i+len(marker))
+			tail, _ := parser.readBytesAt(end, minInt64(2, parser.fileSize-end))
+			if len(tail) > 0 && tail[0] == '\r' {
+				end++
+				tail = tail[1:]
+			}
+			if len(tail) > 0 && tail[0] == '\n' {
+				end++
+			}
+			return end
+		}
+		if len(buf) >= len(marker) {
+			carry = append([]byte{}, buf[len(buf)-len(marker)+1:]...)
+		}
+		pos += n
+	}
+	return parser.fileSize
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,108 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// appendRevision appends an incremental update to `data` containing `objects` (object number
+// to object body) and free entries for `freed`. Returns the updated data and the xref offset.
+func appendRevision(data []byte, objects map[int]string, freed []int, size int, prev int64) ([]byte, int64) {
+	var buf bytes.Buffer
+	buf.Write(data)
+
+	offsets := map[int]int64{}
+	for objNum := 1; objNum < size; objNum++ {
+		body, ok := objects[objNum]
+		if !ok {
+			continue
+		}
+		offsets[objNum] = int64(buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", objNum, body)
+	}
+
+	xrefOffset := int64(buf.Len())
+	buf.WriteString("xref\n0 1\n0000000000 65535 f \n")
+	for objNum := 1; objNum < size; objNum++ {
+		if offset, ok := offsets[objNum]; ok {
+			fmt.Fprintf(&buf, "%d 1\n%010d 00000 n \n", objNum, offset)
+		}
+	}
+	for _, objNum := range freed {
+		fmt.Fprintf(&buf, "%d 1\n0000000000 00001 f \n", objNum)
+	}
+	buf.WriteString("trailer\n")
+	if prev > 0 {
+		fmt.Fprintf(&buf, "<< /Size %d /Root 1 0 R /Prev %d >>\n", size, prev)
+	} else {
+		fmt.Fprintf(&buf, "<< /Size %d /Root 1 0 R >>\n", size)
+	}
+	fmt.Fprintf(&buf, "startxref\n%d\n%%%%EOF\n", xrefOffset)
+	return buf.Bytes(), xrefOffset
+}
+
+func TestParserRevisions(t *testing.T) {
+	data := []byte("%PDF-1.7\n")
+	data, xref1 := appendRevision(data, map[int]string{
+		1: "<< /Type /Catalog /Pages 2 0 R >>",
+		2: "<< /Type /Pages /Kids [] /Count 0 >>",
+		3: "(revision 1)",
+	}, nil, 4, 0)
+	rev1Size := int64(len(data))
+
+	data, xref2 := appendRevision(data, map[int]string{
+		2: "<< /Type /Pages /Kids [] /Count 0 /Changed true >>",
+		4: "(revision 2)",
+	}, []int{3}, 5, xref1)
+
+	parser, err := NewParser(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	revisions := parser.GetRevisions()
+	require.Len(t, revisions, 2)
+
+	rev := revisions[0]
+	require.Equal(t, 0, rev.Index)
+	require.Equal(t, xref1, rev.StartXref)
+	require.Equal(t, rev1Size, rev.EndOffset)
+	require.Equal(t, []int{1, 2, 3}, rev.Added)
+	require.Empty(t, rev.Changed)
+	require.Empty(t, rev.Freed)
+
+	rev = revisions[1]
+	require.Equal(t, 1, rev.Index)
+	require.Equal(t, xref2, rev.StartXref)
+	require.Equal(t, int64(len(data)), rev.EndOffset)
+	require.Equal(t, []int{4}, rev.Added)
+	require.Equal(t, []int{2}, rev.Changed)
+	require.Equal(t, []int{3}, rev.Freed)
+	size, ok := GetIntVal(rev.Trailer.Get("Size"))
+	require.True(t, ok)
+	require.Equal(t, 5, size)
+
+	// The first revision can be parsed on its own.
+	rs, err := parser.GetRevisionReader(0)
+	require.NoError(t, err)
+	revData, err := ioutil.ReadAll(rs)
+	require.NoError(t, err)
+	require.Equal(t, data[:rev1Size], revData)
+
+	rs, err = parser.GetRevisionReader(0)
+	require.NoError(t, err)
+	revParser, err := NewParser(rs)
+	require.NoError(t, err)
+	require.Len(t, revParser.GetRevisions(), 1)
+	require.Equal(t, []int{1, 2, 3}, revParser.GetObjectNums())
+
+	_, err = parser.GetRevisionReader(2)
+	require.Error(t, err)
+}
@@ -32,8 +32,8 @@ func newFilterV2(d FilterDict) (Filter, error) {
 	if d.Length%8 != 0 {
 		return nil, fmt.Errorf("crypt filter length not multiple of 8 (%d)", d.Length)
 	}
-	// Standard security handler expresses the length in multiples of 8 (16 means 128)
-	// We only deal with standard so far. (Public key not supported yet).
+	// Standard security handler expresses the length in multiples of 8 (16 means 128),
+	// the public-key security handler expresses it in bits.
 	if d.Length < 5 || d.Length > 16 {
 		if d.Length == 40 || d.Length == 64 || d.Length == 128 {
 			common.Log.Debug("STANDARD VIOLATION: Crypt Length appears to be in bits rather than bytes - assuming bits (%d)", d.Length)
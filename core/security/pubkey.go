@@ -0,0 +1,381 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package security
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/unidoc/unipdf/v3/common"
+)
+
+// Sub-filters of the public-key security handler (Adobe.PubSec).
+const (
+	// SubFilterPKCS7S3 stores the recipients in the encryption dictionary, RC4 encryption.
+	SubFilterPKCS7S3 = "adbe.pkcs7.s3"
+	// SubFilterPKCS7S4 stores the recipients in the encryption dictionary, RC4 encryption (up to 128 bits).
+	SubFilterPKCS7S4 = "adbe.pkcs7.s4"
+	// SubFilterPKCS7S5 stores the recipients in crypt filters (V=4 or V=5).
+	SubFilterPKCS7S5 = "adbe.pkcs7.s5"
+)
+
+// PermChangeEncryption is a permission of the public-key security handler, allowing to change the
+// encryption of the document and enabling all other permissions.
+const PermChangeEncryption = Permissions(1 << 1)
+
+// PubKeyRecipient is a recipient of a document encrypted with the public-key security handler.
+// The document can be opened with the private key matching the certificate, and the recipient is
+// then granted the specified permissions.
+type PubKeyRecipient struct {
+	Certificate *x509.Certificate
+	Permissions Permissions
+}
+
+// PubKeyHandler is an interface for public-key security handlers.
+type PubKeyHandler interface {
+	// GenerateParams generates the recipient envelopes of the encryption dictionary for the specified
+	// `recipients` and returns the file encryption key.
+	// It assumes that EncryptMetadata is already set.
+	GenerateParams(d *PubKeyEncryptDict, recipients []PubKeyRecipient) ([]byte, error)
+
+	// Authenticate looks for an envelope of the encryption dictionary addressed to the certificate
+	// `cert` and opens it with the private key `pkey` to calculate the file encryption key. It also
+	// returns the permissions granted to the recipient.
+	// In case of failed authentication, it returns empty key and zero permissions with no error.
+	Authenticate(d *PubKeyEncryptDict, cert *x509.Certificate, pkey crypto.PrivateKey) ([]byte, Permissions, error)
+}
+
+// PubKeyEncryptDict is a set of additional fields used in public-key encryption dictionaries.
+type PubKeyEncryptDict struct {
+	// Recipients are the DER-encoded PKCS#7 enveloped data objects, one for each set of recipients
+	// sharing the same permissions.
+	Recipients [][]byte
+
+	EncryptMetadata bool // Indicates whether the document-level metadata stream shall be encrypted.
+}
+
+// NewPubKeyHandler creates a new public-key security handler for file encryption keys of `length`
+// bytes. A length of 32 bytes (AESV3) uses SHA-256 for computing the key, SHA-1 is used otherwise.
+func NewPubKeyHandler(length int) PubKeyHandler {
+	return pubKeyHandler{Length: length}
+}
+
+var _ PubKeyHandler = pubKeyHandler{}
+
+// pubKeyHandler is the public-key security handler (7.6.5 Public-Key Security Handlers).
+type pubKeyHandler struct {
+	Length int
+}
+
+// seedLength is the length of the random seed shared by all envelopes of the document.
+const seedLength = 20
+
+// fileKey computes the file encryption key from the `seed` and the recipient envelopes.
+func (sh pubKeyHandler) fileKey(d *PubKeyEncryptDict, seed []byte) ([]byte, error) {
+	h := sha1.New()
+	if sh.Length == 32 {
+		h = sha256.New()
+	}
+	h.Write(seed)
+	for _, recipient := range d.Recipients {
+		h.Write(recipient)
+	}
+	if !d.EncryptMetadata {
+		h.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	}
+	key := h.Sum(nil)
+	if sh.Length <= 0 || sh.Length > len(key) {
+		return nil, fmt.Errorf("invalid key length %d", sh.Length)
+	}
+	return key[:sh.Length], nil
+}
+
+// GenerateParams implements PubKeyHandler interface.
+func (sh pubKeyHandler) GenerateParams(d *PubKeyEncryptDict, recipients []PubKeyRecipient) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients")
+	}
+	seed := make([]byte, seedLength)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+
+	// Recipients with the same permissions share an envelope.
+	var perms []Permissions
+	certs := map[Permissions][]*x509.Certificate{}
+	for _, r := range recipients {
+		if r.Certificate == nil {
+			return nil, errors.New("recipient certificate missing")
+		}
+		if _, has := certs[r.Permissions]; !has {
+			perms = append(perms, r.Permissions)
+		}
+		certs[r.Permissions] = append(certs[r.Permissions], r.Certificate)
+	}
+
+	d.Recipients = nil
+	for _, p := range perms {
+		content := make([]byte, seedLength+4)
+		copy(content, seed)
+		binary.BigEndian.PutUint32(content[seedLength:], uint32(p))
+		envelope, err := encryptEnvelope(content, certs[p])
+		if err != nil {
+			common.Log.Debug("ERROR: Failed to generate recipient envelope: %v", err)
+			return nil, err
+		}
+		d.Recipients = append(d.Recipients, envelope)
+	}
+	return sh.fileKey(d, seed)
+}
+
+// Authenticate implements PubKeyHandler interface.
+func (sh pubKeyHandler) Authenticate(d *PubKeyEncryptDict, cert *x509.Certificate,
+	pkey crypto.PrivateKey) ([]byte, Permissions, error) {
+	if cert == nil || pkey == nil {
+		return nil, 0, errors.New("certificate and private key required")
+	}
+	for i, recipient := range d.Recipients {
+		content, err := decryptEnvelope(recipient, cert, pkey)
+		if err == errNotEnvelope {
+			// Only the envelope addressed to the certificate is required to be valid.
+			common.Log.Debug("Skipping recipient %d: %v", i, err)
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if content == nil {
+			// Not addressed to the certificate.
+			continue
+		}
+		if len(content) < seedLength+4 {
+			return nil, 0, errInvalidField{Func: "Authenticate", Field: "Recipients", Exp: seedLength + 4, Got: len(content)}
+		}
+		perm := Permissions(binary.BigEndian.Uint32(content[seedLength:]))
+		key, err := sh.fileKey(d, content[:seedLength])
+		if err != nil {
+			return nil, 0, err
+		}
+		return key, perm, nil
+	}
+	common.Log.Trace("No recipient envelope for certificate %s", cert.Subject)
+	return nil, 0, nil
+}
+
+// PKCS#7 object identifiers (RFC 2315, RFC 3565, RFC 8017).
+var (
+	oidEnvelopedData  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+	oidData           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidRSAEncryption  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidDESCBC         = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 7}
+	oidDESEDE3CBC     = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	errNotEnvelope    = errors.New("recipient is not a PKCS#7 enveloped data object")
+	errUnsupportedKey = errors.New("unsupported recipient key: only RSA keys are supported")
+)
+
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type pkcs7EnvelopedData struct {
+	Version              int
+	RecipientInfos       []pkcs7RecipientInfo `asn1:"set"`
+	EncryptedContentInfo pkcs7EncryptedContentInfo
+}
+
+type pkcs7RecipientInfo struct {
+	Version                int
+	IssuerAndSerialNumber  pkcs7IssuerAndSerial
+	KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedKey           []byte
+}
+
+type pkcs7IssuerAndSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type pkcs7EncryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+// encryptEnvelope encrypts `content` for the `recipients` certificates as a PKCS#7 enveloped data
+// object, using AES-256-CBC for the content and RSA for the content encryption key.
+func encryptEnvelope(content []byte, recipients []*x509.Certificate) ([]byte, error) {
+	key := make([]byte, 32)
+	iv := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	block, err := newAESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	padLen := block.BlockSize() - len(content)%block.BlockSize()
+	data := append(append([]byte{}, content...), bytes.Repeat([]byte{byte(padLen)}, padLen)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+
+	ivParam, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	// Encrypted content as a constructed string made of a single octet string.
+	encrypted, err := asn1.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	ed := pkcs7EnvelopedData{
+		EncryptedContentInfo: pkcs7EncryptedContentInfo{
+			ContentType: oidData,
+			ContentEncryptionAlgorithm: pkix.AlgorithmIdentifier{
+				Algorithm:  oidAES256CBC,
+				Parameters: asn1.RawValue{FullBytes: ivParam},
+			},
+			EncryptedContent: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: encrypted},
+		},
+	}
+	for _, cert := range recipients {
+		pub, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, errUnsupportedKey
+		}
+		encKey, err := rsa.EncryptPKCS1v15(rand.Reader, pub, key)
+		if err != nil {
+			return nil, err
+		}
+		ed.RecipientInfos = append(ed.RecipientInfos, pkcs7RecipientInfo{
+			IssuerAndSerialNumber: pkcs7IssuerAndSerial{
+				Issuer:       asn1.RawValue{FullBytes: cert.RawIssuer},
+				SerialNumber: cert.SerialNumber,
+			},
+			KeyEncryptionAlgorithm: pkix.AlgorithmIdentifier{
+				Algorithm:  oidRSAEncryption,
+				Parameters: asn1.NullRawValue,
+			},
+			EncryptedKey: encKey,
+		})
+	}
+
+	inner, err := asn1.Marshal(ed)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs7ContentInfo{
+		ContentType: oidEnvelopedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: inner},
+	})
+}
+
+// decryptEnvelope decrypts the content of the PKCS#7 enveloped data object `envelope` using the
+// recipient certificate `cert` and private key `pkey`. Returns nil content if the envelope is not
+// addressed to the certificate.
+func decryptEnvelope(envelope []byte, cert *x509.Certificate, pkey crypto.PrivateKey) ([]byte, error) {
+	var info pkcs7ContentInfo
+	if _, err := asn1.Unmarshal(envelope, &info); err != nil {
+		return nil, errNotEnvelope
+	}
+	if !info.ContentType.Equal(oidEnvelopedData) {
+		return nil, errNotEnvelope
+	}
+	var ed pkcs7EnvelopedData
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &ed); err != nil {
+		return nil, errNotEnvelope
+	}
+
+	var ri *pkcs7RecipientInfo
+	for i := range ed.RecipientInfos {
+		ias := ed.RecipientInfos[i].IssuerAndSerialNumber
+		if ias.SerialNumber != nil && ias.SerialNumber.Cmp(cert.SerialNumber) == 0 &&
+			bytes.Equal(ias.Issuer.FullBytes, cert.RawIssuer) {
+			ri = &ed.RecipientInfos[i]
+			break
+		}
+	}
+	if ri == nil {
+		return nil, nil
+	}
+
+	priv, ok := pkey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errUnsupportedKey
+	}
+	key, err := rsa.DecryptPKCS1v15(rand.Reader, priv, ri.EncryptedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	eci := ed.EncryptedContentInfo
+	var block cipher.Block
+	alg := eci.ContentEncryptionAlgorithm.Algorithm
+	switch {
+	case alg.Equal(oidAES128CBC), alg.Equal(oidAES192CBC), alg.Equal(oidAES256CBC):
+		block, err = newAESCipher(key)
+	case alg.Equal(oidDESEDE3CBC):
+		block, err = des.NewTripleDESCipher(key)
+	case alg.Equal(oidDESCBC):
+		block, err = des.NewCipher(key)
+	default:
+		return nil, fmt.Errorf("unsupported content encryption algorithm %s", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(eci.ContentEncryptionAlgorithm.Parameters.FullBytes, &iv); err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, errors.New("invalid content encryption IV")
+	}
+
+	// Encrypted content is either primitive or constructed from octet strings.
+	data := eci.EncryptedContent.Bytes
+	if eci.EncryptedContent.IsCompound {
+		var buf bytes.Buffer
+		for rest := data; len(rest) > 0; {
+			var part []byte
+			if rest, err = asn1.Unmarshal(rest, &part); err != nil {
+				return nil, err
+			}
+			buf.Write(part)
+		}
+		data = buf.Bytes()
+	}
+	if len(data) == 0 || len(data)%block.BlockSize() != 0 {
+		return nil, errors.New("invalid encrypted content length")
+	}
+	content := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(content, data)
+
+	padLen := int(content[len(content)-1])
+	if padLen == 0 || padLen > block.BlockSize() {
+		return nil, errors.New("invalid content padding")
+	}
+	return content[:len(content)-padLen], nil
+}
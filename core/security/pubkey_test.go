@@ -0,0 +1,144 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package security
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// makeTestCertificate returns a self-signed certificate and its private key.
+func makeTestCertificate(t *testing.T, serial int64, name string) (*x509.Certificate, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestPubKeyHandler(t *testing.T) {
+	cert1, key1 := makeTestCertificate(t, 1, "Recipient 1")
+	cert2, key2 := makeTestCertificate(t, 2, "Recipient 2")
+	cert3, key3 := makeTestCertificate(t, 3, "Recipient 3")
+	other, otherKey := makeTestCertificate(t, 4, "Other")
+
+	recipients := []PubKeyRecipient{
+		{Certificate: cert1, Permissions: PermOwner},
+		{Certificate: cert2, Permissions: PermPrinting | PermFillForms},
+		{Certificate: cert3, Permissions: PermOwner},
+	}
+
+	for _, length := range []int{5, 16, 32} {
+		sh := NewPubKeyHandler(length)
+		for _, encMeta := range []bool{true, false} {
+			d := &PubKeyEncryptDict{EncryptMetadata: encMeta}
+			fkey, err := sh.GenerateParams(d, recipients)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(fkey) != length {
+				t.Fatalf("expected key length %d, got %d", length, len(fkey))
+			}
+			if len(d.Recipients) != 2 {
+				t.Fatalf("expected 2 envelopes, got %d", len(d.Recipients))
+			}
+
+			// Decode from a copy, as if read from the file.
+			dec := &PubKeyEncryptDict{EncryptMetadata: encMeta, Recipients: d.Recipients}
+			for i, c := range []struct {
+				cert *x509.Certificate
+				key  *rsa.PrivateKey
+				perm Permissions
+			}{
+				{cert1, key1, PermOwner},
+				{cert2, key2, PermPrinting | PermFillForms},
+				{cert3, key3, PermOwner},
+			} {
+				key, perm, err := sh.Authenticate(dec, c.cert, c.key)
+				if err != nil {
+					t.Fatalf("recipient %d: %v", i, err)
+				}
+				if !bytes.Equal(key, fkey) {
+					t.Fatalf("recipient %d: wrong file key", i)
+				}
+				if perm != c.perm {
+					t.Fatalf("recipient %d: expected permissions %#x, got %#x", i, c.perm, perm)
+				}
+			}
+
+			key, perm, err := sh.Authenticate(dec, other, otherKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key != nil || perm != 0 {
+				t.Fatal("unexpected authentication of a certificate that is not a recipient")
+			}
+
+			// The file key depends on the metadata encryption.
+			key, _, err = sh.Authenticate(&PubKeyEncryptDict{EncryptMetadata: !encMeta, Recipients: d.Recipients}, cert1, key1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Equal(key, fkey) {
+				t.Fatal("expected a different key when EncryptMetadata changes")
+			}
+		}
+	}
+
+	// Wrong private key for the certificate.
+	d := &PubKeyEncryptDict{EncryptMetadata: true}
+	if _, err := NewPubKeyHandler(16).GenerateParams(d, recipients[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := NewPubKeyHandler(16).Authenticate(d, cert1, key2); err == nil {
+		t.Fatal("expected error with a wrong private key")
+	}
+	if _, err := NewPubKeyHandler(16).GenerateParams(d, nil); err == nil {
+		t.Fatal("expected error without recipients")
+	}
+
+	// Invalid envelopes not addressed to the certificate are skipped.
+	notEnveloped, err := asn1.Marshal(pkcs7ContentInfo{ContentType: oidData})
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid := [][]byte{[]byte("corrupt"), notEnveloped}
+	d.Recipients = append(invalid, d.Recipients...)
+	key, perm, err := NewPubKeyHandler(16).Authenticate(d, cert1, key1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != 16 || perm != PermOwner {
+		t.Fatal("recipient not authenticated with invalid envelopes")
+	}
+	d.Recipients = invalid
+	key, _, err = NewPubKeyHandler(16).Authenticate(d, cert1, key1)
+	if err != nil || key != nil {
+		t.Fatalf("expected no recipient envelope, got key %x, error %v", key, err)
+	}
+}
@@ -73,7 +73,9 @@ func NewEncoderFromStream(streamObj *PdfObjectStream) (StreamEncoder, error) {
 	case StreamEncodingFilterNameJBIG2:
 		return newJBIG2DecoderFromStream(streamObj, nil)
 	case StreamEncodingFilterNameJPX:
-		return NewJPXEncoder(), nil
+		return newJPXEncoderFromStream(streamObj, nil)
+	case StreamEncodingFilterNameCrypt:
+		return newCryptEncoderFromStream(streamObj, nil), nil
 	}
 	common.Log.Debug("ERROR: Unsupported encoding method!")
 	return nil, fmt.Errorf("unsupported encoding method (%s)", *method)
@@ -42,6 +42,9 @@ func (parser *PdfParser) Inspect() (map[string]int, error) {
 
 // GetObjectNums returns a sorted list of object numbers of the PDF objects in the file.
 func (parser *PdfParser) GetObjectNums() []int {
+	parser.lock.Lock()
+	defer parser.lock.Unlock()
+
 	var objNums []int
 	for _, x := range parser.xrefs.ObjectMap {
 		objNums = append(objNums, x.ObjectNumber)
@@ -148,8 +151,8 @@ func getUniDocVersion() string {
  * Mostly for debugging purposes and inspecting odd PDF files.
  */
 func (parser *PdfParser) inspect() (map[string]int, error) {
-	common.Log.Trace("--------INSPECT ----------")
-	common.Log.Trace("Xref table:")
+	parser.log().Trace("--------INSPECT ----------")
+	parser.log().Trace("Xref table:")
 
 	objTypes := map[string]int{}
 	objCount := 0
@@ -168,26 +171,26 @@ func (parser *PdfParser) inspect() (map[string]int, error) {
 			continue
 		}
 		objCount++
-		common.Log.Trace("==========")
-		common.Log.Trace("Looking up object number: %d", xref.ObjectNumber)
+		parser.log().Trace("==========")
+		parser.log().Trace("Looking up object number: %d", xref.ObjectNumber)
 		o, err := parser.LookupByNumber(xref.ObjectNumber)
 		if err != nil {
-			common.Log.Trace("ERROR: Fail to lookup obj %d (%s)", xref.ObjectNumber, err)
+			parser.log().Trace("ERROR: Fail to lookup obj %d (%s)", xref.ObjectNumber, err)
 			failedCount++
 			continue
 		}
 
-		common.Log.Trace("obj: %s", o)
+		parser.log().Trace("obj: %s", o)
 
 		iobj, isIndirect := o.(*PdfIndirectObject)
 		if isIndirect {
-			common.Log.Trace("IND OOBJ %d: %s", xref.ObjectNumber, iobj)
+			parser.log().Trace("IND OOBJ %d: %s", xref.ObjectNumber, iobj)
 			dict, isDict := iobj.PdfObject.(*PdfObjectDictionary)
 			if isDict {
 				// Check if has Type parameter.
 				if ot, has := dict.Get("Type").(*PdfObjectName); has {
 					otype := string(*ot)
-					common.Log.Trace("---> Obj type: %s", otype)
+					parser.log().Trace("---> Obj type: %s", otype)
 					_, isDefined := objTypes[otype]
 					if isDefined {
 						objTypes[otype]++
@@ -197,7 +200,7 @@ func (parser *PdfParser) inspect() (map[string]int, error) {
 				} else if ot, has := dict.Get("Subtype").(*PdfObjectName); has {
 					// Check if subtype
 					otype := string(*ot)
-					common.Log.Trace("---> Obj subtype: %s", otype)
+					parser.log().Trace("---> Obj subtype: %s", otype)
 					_, isDefined := objTypes[otype]
 					if isDefined {
 						objTypes[otype]++
@@ -218,7 +221,7 @@ func (parser *PdfParser) inspect() (map[string]int, error) {
 			}
 		} else if sobj, isStream := o.(*PdfObjectStream); isStream {
 			if otype, ok := sobj.PdfObjectDictionary.Get("Type").(*PdfObjectName); ok {
-				common.Log.Trace("--> Stream object type: %s", *otype)
+				parser.log().Trace("--> Stream object type: %s", *otype)
 				k := string(*otype)
 				if _, isDefined := objTypes[k]; isDefined {
 					objTypes[k]++
@@ -232,34 +235,34 @@ func (parser *PdfParser) inspect() (map[string]int, error) {
 				ot, isName := dict.Get("Type").(*PdfObjectName)
 				if isName {
 					otype := string(*ot)
-					common.Log.Trace("--- obj type %s", otype)
+					parser.log().Trace("--- obj type %s", otype)
 					objTypes[otype]++
 				}
 			}
-			common.Log.Trace("DIRECT OBJ %d: %s", xref.ObjectNumber, o)
+			parser.log().Trace("DIRECT OBJ %d: %s", xref.ObjectNumber, o)
 		}
 
 		i++
 	}
-	common.Log.Trace("--------EOF INSPECT ----------")
-	common.Log.Trace("=======")
-	common.Log.Trace("Object count: %d", objCount)
-	common.Log.Trace("Failed lookup: %d", failedCount)
+	parser.log().Trace("--------EOF INSPECT ----------")
+	parser.log().Trace("=======")
+	parser.log().Trace("Object count: %d", objCount)
+	parser.log().Trace("Failed lookup: %d", failedCount)
 	for t, c := range objTypes {
-		common.Log.Trace("%s: %d", t, c)
+		parser.log().Trace("%s: %d", t, c)
 	}
-	common.Log.Trace("=======")
+	parser.log().Trace("=======")
 
 	if len(parser.xrefs.ObjectMap) < 1 {
-		common.Log.Debug("ERROR: This document is invalid (xref table missing!)")
+		parser.log().Debug("ERROR: This document is invalid (xref table missing!)")
 		return nil, fmt.Errorf("invalid document (xref table missing)")
 	}
 
 	fontObjs, ok := objTypes["Font"]
 	if !ok || fontObjs < 2 {
-		common.Log.Trace("This document is probably scanned!")
+		parser.log().Trace("This document is probably scanned!")
 	} else {
-		common.Log.Trace("This document is valid for extraction!")
+		parser.log().Trace("This document is valid for extraction!")
 	}
 
 	return objTypes, nil
@@ -398,8 +398,8 @@ func mergeContents(contents *contentstream.ContentStreamOperations, resources *m
 	// To properly add contents from a block, we need to handle the resources that the block is
 	// using and make sure it is accessible in the modified Page.
 	//
-	// Currently supporting: Font, XObject, Colormap, Pattern, Shading, GState resources
-	// from the block.
+	// Currently supporting: Font, XObject, Colormap, Pattern, Shading, GState and Properties
+	// resources from the block.
 	//
 
 	xobjectMap := map[core.PdfObjectName]core.PdfObjectName{}
@@ -408,6 +408,7 @@ func mergeContents(contents *contentstream.ContentStreamOperations, resources *m
 	patternMap := map[core.PdfObjectName]core.PdfObjectName{}
 	shadingMap := map[core.PdfObjectName]core.PdfObjectName{}
 	gstateMap := map[core.PdfObjectName]core.PdfObjectName{}
+	propertiesMap := map[core.PdfObjectName]core.PdfObjectName{}
 
 	for _, op := range *contentsToAdd {
 		switch op.Operand {
@@ -587,6 +588,42 @@ func mergeContents(contents *contentstream.ContentStreamOperations, resources *m
 					op.Params[0] = &useName
 				}
 			}
+		case "BDC":
+			// Marked content with a property list from the Properties resources, such as
+			// optional content.
+			if len(op.Params) == 2 {
+				if name, ok := op.Params[1].(*core.PdfObjectName); ok {
+					if _, processed := propertiesMap[*name]; !processed {
+						var useName core.PdfObjectName
+						// Process if not already processed.
+						props, found := resourcesToAdd.GetPropertyByName(*name)
+						if found {
+							useName = *name
+							for {
+								props2, found := resources.GetPropertyByName(useName)
+								if !found || props == props2 {
+									break
+								}
+								useName = useName + "0"
+							}
+
+							err := resources.SetPropertyByName(useName, props)
+							if err != nil {
+								common.Log.Debug("ERROR Set properties: %v", err)
+								return err
+							}
+
+							propertiesMap[*name] = useName
+						} else {
+							common.Log.Debug("Properties not found")
+						}
+					}
+
+					if useName, has := propertiesMap[*name]; has {
+						op.Params[1] = &useName
+					}
+				}
+			}
 		}
 
 		*contents = append(*contents, op)
@@ -66,9 +66,20 @@ type Creator struct {
 	// Page labels.
 	pageLabels core.PdfObject
 
+	// Optional content of the layers.
+	ocProperties *model.PdfOCProperties
+
 	// Optimizer.
 	optimizer model.Optimizer
 
+	// Logger receiving the log messages and the diagnostics of the creator and of its writer. The
+	// global common.Log is used if nil.
+	logger common.Logger
+
+	// Document information and XMP metadata.
+	info *model.PdfInfo
+	xmp  *model.XMPMetadata
+
 	// Fonts that have been enabled for subsetting prior to write.
 	subsetFonts []*model.PdfFont
 
@@ -93,7 +104,8 @@ func (c *Creator) SetOutlineTree(outlineTree *model.PdfOutlineTreeNode) {
 // SetPageLabels adds the specified page labels to the PDF file generated
 // by the creator. See section 12.4.2 "Page Labels" (p. 382 PDF32000_2008).
 // NOTE: for existing PDF files, the page label ranges object can be obtained
-// using the model.PDFReader's GetPageLabels method.
+// using the model.PDFReader's GetPageLabels method. New page labels can be
+// built with model.PdfPageLabels.
 func (c *Creator) SetPageLabels(pageLabels core.PdfObject) {
 	c.pageLabels = pageLabels
 }
@@ -166,11 +178,38 @@ func New() *Creator {
 	return c
 }
 
+// SetLogger sets the logger receiving the log messages and the diagnostics of the creator and of
+// the writer of the document, for example a common.Diagnostics recording them. The global
+// common.Log is used if `logger` is nil.
+func (c *Creator) SetLogger(logger common.Logger) {
+	c.logger = logger
+}
+
+// log returns the logger of the creator.
+func (c *Creator) log() common.Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+	return common.Log
+}
+
 // SetOptimizer sets the optimizer to optimize PDF before writing.
 func (c *Creator) SetOptimizer(optimizer model.Optimizer) {
 	c.optimizer = optimizer
 }
 
+// SetDocInfo sets the document information dictionary of the output file, instead of the entries
+// set with the model package-level functions such as model.SetPdfTitle.
+func (c *Creator) SetDocInfo(info *model.PdfInfo) {
+	c.info = info
+}
+
+// SetXMPMetadata sets the XMP metadata packet of the output file. The properties corresponding to
+// the document information dictionary are updated from it when writing.
+func (c *Creator) SetXMPMetadata(md *model.XMPMetadata) {
+	c.xmp = md
+}
+
 // GetOptimizer returns current PDF optimizer.
 func (c *Creator) GetOptimizer() model.Optimizer {
 	return c.optimizer
@@ -318,7 +357,7 @@ func (c *Creator) NewPage() *model.PdfPage {
 func (c *Creator) AddPage(page *model.PdfPage) error {
 	mbox, err := page.GetMediaBox()
 	if err != nil {
-		common.Log.Debug("Failed to get page mediabox: %v", err)
+		c.log().Debug("Failed to get page mediabox: %v", err)
 		return err
 	}
 
@@ -338,11 +377,11 @@ func (c *Creator) AddPage(page *model.PdfPage) error {
 func (c *Creator) RotateDeg(angleDeg int64) error {
 	page := c.getActivePage()
 	if page == nil {
-		common.Log.Debug("Fail to rotate: no page currently active")
+		c.log().Debug("Fail to rotate: no page currently active")
 		return errors.New("no page active")
 	}
 	if angleDeg%90 != 0 {
-		common.Log.Debug("ERROR: Page rotation angle not a multiple of 90")
+		c.log().Debug("ERROR: Page rotation angle not a multiple of 90")
 		return errors.New("range check error")
 	}
 
@@ -401,7 +440,7 @@ func (c *Creator) Finalize() error {
 		// Make an estimate of the number of pages.
 		blocks, _, err := c.toc.GeneratePageBlocks(c.context)
 		if err != nil {
-			common.Log.Debug("Failed to generate blocks: %v", err)
+			c.log().Debug("Failed to generate blocks: %v", err)
 			return err
 		}
 		genpages += len(blocks)
@@ -440,7 +479,7 @@ func (c *Creator) Finalize() error {
 
 		if c.genTableOfContentFunc != nil {
 			if err := c.genTableOfContentFunc(c.toc); err != nil {
-				common.Log.Debug("Error generating TOC: %v", err)
+				c.log().Debug("Error generating TOC: %v", err)
 				return err
 			}
 		}
@@ -485,7 +524,7 @@ func (c *Creator) Finalize() error {
 			if page := int(item.Dest.Page); page >= 0 && page < len(c.pages) {
 				item.Dest.PageObj = c.pages[page].GetPageAsIndirectObject()
 			} else {
-				common.Log.Debug("WARN: could not get page container for page %d", page)
+				c.log().Debug("WARN: could not get page container for page %d", page)
 			}
 
 			// Reverse the Y axis of the destination coordinates.
@@ -518,7 +557,7 @@ func (c *Creator) Finalize() error {
 			if tocPage >= 0 && tocPage < len(c.pages) {
 				dest.PageObj = c.pages[tocPage].GetPageAsIndirectObject()
 			} else {
-				common.Log.Debug("WARN: could not get page container for page %d", tocPage)
+				c.log().Debug("WARN: could not get page container for page %d", tocPage)
 			}
 			c.outline.Insert(0, model.NewOutlineItem("Table of Contents", dest))
 		}
@@ -541,7 +580,7 @@ func (c *Creator) Finalize() error {
 			headerBlock.SetPos(0, 0)
 
 			if err := c.Draw(headerBlock); err != nil {
-				common.Log.Debug("ERROR: drawing header: %v", err)
+				c.log().Debug("ERROR: drawing header: %v", err)
 				return err
 			}
 		}
@@ -560,7 +599,7 @@ func (c *Creator) Finalize() error {
 			footerBlock.SetPos(0, c.pageHeight-footerBlock.height)
 
 			if err := c.Draw(footerBlock); err != nil {
-				common.Log.Debug("ERROR: drawing footer: %v", err)
+				c.log().Debug("ERROR: drawing footer: %v", err)
 				return err
 			}
 		}
@@ -571,7 +610,7 @@ func (c *Creator) Finalize() error {
 			continue
 		}
 		if err := block.drawToPage(page); err != nil {
-			common.Log.Debug("ERROR: drawing page %d blocks: %v", idx+1, err)
+			c.log().Debug("ERROR: drawing page %d blocks: %v", idx+1, err)
 			return err
 		}
 	}
@@ -657,12 +696,19 @@ func (c *Creator) Write(ws io.Writer) error {
 
 	pdfWriter := model.NewPdfWriter()
 	pdfWriter.SetOptimizer(c.optimizer)
+	pdfWriter.SetLogger(c.logger)
+	if c.info != nil {
+		pdfWriter.SetDocInfo(c.info)
+	}
+	if c.xmp != nil {
+		pdfWriter.SetXMPMetadata(c.xmp)
+	}
 
 	// Form fields.
 	if c.acroForm != nil {
 		err := pdfWriter.SetForms(c.acroForm)
 		if err != nil {
-			common.Log.Debug("Failure: %v", err)
+			c.log().Debug("Failure: %v", err)
 			return err
 		}
 	}
@@ -677,7 +723,15 @@ func (c *Creator) Write(ws io.Writer) error {
 	// Page labels.
 	if c.pageLabels != nil {
 		if err := pdfWriter.SetPageLabels(c.pageLabels); err != nil {
-			common.Log.Debug("ERROR: Could not set page labels: %v", err)
+			c.log().Debug("ERROR: Could not set page labels: %v", err)
+			return err
+		}
+	}
+
+	// Layers.
+	if c.ocProperties != nil {
+		if err := pdfWriter.SetPdfOCProperties(c.ocProperties); err != nil {
+			c.log().Debug("ERROR: Could not set optional content properties: %v", err)
 			return err
 		}
 	}
@@ -686,7 +740,7 @@ func (c *Creator) Write(ws io.Writer) error {
 		for _, font := range c.subsetFonts {
 			err := font.SubsetRegistered()
 			if err != nil {
-				common.Log.Debug("ERROR: Could not subset font: %v", err)
+				c.log().Debug("ERROR: Could not subset font: %v", err)
 				return err
 			}
 		}
@@ -696,7 +750,7 @@ func (c *Creator) Write(ws io.Writer) error {
 	if c.pdfWriterAccessFunc != nil {
 		err := c.pdfWriterAccessFunc(&pdfWriter)
 		if err != nil {
-			common.Log.Debug("Failure: %v", err)
+			c.log().Debug("Failure: %v", err)
 			return err
 		}
 	}
@@ -704,7 +758,7 @@ func (c *Creator) Write(ws io.Writer) error {
 	for _, page := range c.pages {
 		err := pdfWriter.AddPage(page)
 		if err != nil {
-			common.Log.Error("Failed to add Page: %v", err)
+			c.log().Error("Failed to add Page: %v", err)
 			return err
 		}
 	}
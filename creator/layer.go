@@ -0,0 +1,98 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package creator
+
+import (
+	"fmt"
+
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// Layer is an optional content group of the document: the content drawn in the layer can be
+// shown or hidden by the viewers supporting layers.
+type Layer struct {
+	ocg *model.PdfOCG
+
+	// Name of the optional content group in the Properties resources of the pages.
+	resName core.PdfObjectName
+}
+
+// NewLayer creates a new layer named `name`, initially visible if `visible` is true. The layers
+// are listed in the order of creation.
+func (c *Creator) NewLayer(name string, visible bool) *Layer {
+	props := c.OCProperties()
+	ocg := model.NewPdfOCG(name)
+	props.AddOCG(ocg, visible)
+	return &Layer{
+		ocg:     ocg,
+		resName: core.PdfObjectName(fmt.Sprintf("OC%d", len(props.OCGs))),
+	}
+}
+
+// OCProperties returns the optional content properties of the document, holding the groups of
+// the layers. They can be modified to add alternate configurations or radio-button groups.
+func (c *Creator) OCProperties() *model.PdfOCProperties {
+	if c.ocProperties == nil {
+		c.ocProperties = model.NewPdfOCProperties()
+	}
+	return c.ocProperties
+}
+
+// OCG returns the optional content group of the layer.
+func (l *Layer) OCG() *model.PdfOCG {
+	return l.ocg
+}
+
+// Wrap returns a drawable which draws `d` in the layer: its content is marked as optional content
+// of the layer group and its annotations are shown with the layer.
+func (l *Layer) Wrap(d Drawable) Drawable {
+	return &layerDrawable{layer: l, drawable: d}
+}
+
+// layerDrawable is a drawable drawn in a layer.
+type layerDrawable struct {
+	layer    *Layer
+	drawable Drawable
+}
+
+// GeneratePageBlocks draws the wrapped drawable and marks the content of the blocks as optional
+// content of the layer.
+func (ld *layerDrawable) GeneratePageBlocks(ctx DrawContext) ([]*Block, DrawContext, error) {
+	blocks, ctx, err := ld.drawable.GeneratePageBlocks(ctx)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	ocg := ld.layer.ocg.ToPdfObject()
+	for _, blk := range blocks {
+		for _, annot := range blk.annotations {
+			if annot.OC == nil {
+				annot.OC = ocg
+			}
+		}
+		if len(*blk.contents) == 0 {
+			continue
+		}
+
+		err := blk.resources.SetPropertyByName(ld.layer.resName, ocg)
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		name := ld.layer.resName
+		ops := contentstream.ContentStreamOperations{{
+			Operand: "BDC",
+			Params:  []core.PdfObject{core.MakeName("OC"), &name},
+		}}
+		ops = append(ops, *blk.contents...)
+		ops = append(ops, &contentstream.ContentStreamOperation{Operand: "EMC"})
+		*blk.contents = ops
+	}
+
+	return blocks, ctx, nil
+}
@@ -8,6 +8,8 @@ package extractor
 import (
 	"fmt"
 
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/internal/transform"
 	"github.com/unidoc/unipdf/v3/model"
 )
 
@@ -18,6 +20,10 @@ type Extractor struct {
 	resources *model.PdfPageResources
 	mediaBox  model.PdfRectangle
 
+	// pageMatrix maps the user space of the page to the page as displayed, where the text is
+	// located. Identity if nil.
+	pageMatrix *transform.Matrix
+
 	// fontCache is a simple LRU cache that is used to prevent redundant constructions of PdfFonts
 	// from PDF objects. NOTE: This is not a conventional glyph cache. It only caches PdfFonts.
 	fontCache map[string]fontEntry
@@ -31,6 +37,22 @@ type Extractor struct {
 
 	// textCount is an incrementing number used to identify XYTest objects.
 	textCount int
+
+	// ocVisibility is the visibility of the optional content of the page. The content of hidden
+	// optional content groups is not extracted. All the content is visible if nil.
+	ocVisibility *model.OCVisibility
+
+	// logger receives the log messages and the diagnostics of the extraction. The global common.Log
+	// is used if nil.
+	logger common.Logger
+
+	// reportedFonts are the names of the fonts for which a diagnostic was reported, to report the
+	// fonts used several times only once.
+	reportedFonts map[string]struct{}
+
+	// fontErrors are the errors of the fonts which could not be loaded, to load and report them
+	// only once.
+	fontErrors map[string]error
 }
 
 // New returns an Extractor instance for extracting content from the input PDF page.
@@ -45,16 +67,30 @@ func New(page *model.PdfPage) (*Extractor, error) {
 	// fmt.Printf("%s\n", contents)
 	// fmt.Println("========================= ::: =========================")
 
-	mediaBox, err := page.GetMediaBox()
+	// The text is located in the page as displayed: the visible region of the page, rotated by
+	// its Rotate entry.
+	geom, err := page.GetGeometry()
 	if err != nil {
 		return nil, fmt.Errorf("extractor requires mediaBox. %v", err)
 	}
+	width, height := geom.Size()
+	pageMatrix := geom.DisplayMatrix()
+
+	logger := page.Logger()
+	ocVisibility, err := page.GetOCVisibility()
+	if err != nil {
+		logger.Debug("ERROR: Optional content properties: %v", err)
+	}
+
 	e := &Extractor{
-		contents:    contents,
-		resources:   page.Resources,
-		mediaBox:    *mediaBox,
-		fontCache:   map[string]fontEntry{},
-		formResults: map[string]textResult{},
+		contents:     contents,
+		resources:    page.Resources,
+		mediaBox:     model.PdfRectangle{Urx: width, Ury: height},
+		pageMatrix:   &pageMatrix,
+		fontCache:    map[string]fontEntry{},
+		formResults:  map[string]textResult{},
+		ocVisibility: ocVisibility,
+		logger:       logger,
 	}
 	return e, nil
 }
@@ -69,3 +105,34 @@ func NewFromContents(contents string, resources *model.PdfPageResources) (*Extra
 	}
 	return e, nil
 }
+
+// SetLogger sets the logger receiving the log messages and the diagnostics of the extraction, such
+// as the fonts missing from the resources. By default, the extractors created by New use the
+// logger of the page (see model.PdfPage.Logger), and the other ones the global common.Log.
+func (e *Extractor) SetLogger(logger common.Logger) {
+	e.logger = logger
+}
+
+// log returns the logger of the extractor.
+func (e *Extractor) log() common.Logger {
+	if e.logger != nil {
+		return e.logger
+	}
+	return common.Log
+}
+
+// reportFont reports a diagnostic with `code` for font `name`, once per font name.
+func (e *Extractor) reportFont(code common.DiagnosticCode, name string, format string, args ...interface{}) {
+	if _, ok := e.reportedFonts[name]; ok {
+		return
+	}
+	if e.reportedFonts == nil {
+		e.reportedFonts = map[string]struct{}{}
+	}
+	e.reportedFonts[name] = struct{}{}
+	common.ReportDiagnosticDepth(e.log(), 1, common.Diagnostic{
+		Level:   common.LogLevelWarning,
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
@@ -25,7 +25,8 @@ type ImageExtractOptions struct {
 // are not extracted.
 func (e *Extractor) ExtractPageImages(options *ImageExtractOptions) (*PageImages, error) {
 	ctx := &imageExtractContext{
-		options: options,
+		options:      options,
+		ocVisibility: e.ocVisibility,
 	}
 
 	err := ctx.extractContentStreamImages(e.contents, e.resources)
@@ -73,6 +74,9 @@ type imageExtractContext struct {
 
 	// Extract options.
 	options *ImageExtractOptions
+
+	// Visibility of the optional content: the images of hidden optional content are skipped.
+	ocVisibility *model.OCVisibility
 }
 
 type cachedImage struct {
@@ -95,8 +99,18 @@ func (ctx *imageExtractContext) extractContentStreamImages(contents string, reso
 	}
 
 	processor := contentstream.NewContentStreamProcessor(*operations)
+	ocState := ctx.ocVisibility.NewContentState()
 	processor.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
 		func(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState, resources *model.PdfPageResources) error {
+			switch op.Operand {
+			case "BMC", "BDC":
+				ocState.BeginMarkedContent(op.Params, resources)
+			case "EMC":
+				ocState.EndMarkedContent()
+			}
+			if ocState.Hidden() {
+				return nil
+			}
 			return ctx.processOperand(op, gs, resources)
 		})
 
@@ -127,7 +141,10 @@ func (ctx *imageExtractContext) processOperand(op *contentstream.ContentStreamOp
 			return errTypeCheck
 		}
 
-		_, xtype := resources.GetXObjectByName(*name)
+		stream, xtype := resources.GetXObjectByName(*name)
+		if stream != nil && !ctx.ocVisibility.IsVisible(stream.Get("OC")) {
+			return nil
+		}
 		switch xtype {
 		case model.XObjectTypeImage:
 			return ctx.extractXObjectImage(name, gs, resources)
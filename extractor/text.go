@@ -50,7 +50,11 @@ func (e *Extractor) ExtractTextWithStats() (extracted string, numChars int, numM
 // TODO(peterwilliams97): The stats complicate this function signature and aren't very useful.
 //                        Replace with a function like Extract() (*PageText, error)
 func (e *Extractor) ExtractPageText() (*PageText, int, int, error) {
-	pt, numChars, numMisses, err := e.extractPageText(e.contents, e.resources, transform.IdentityMatrix(), 0)
+	ctm := transform.IdentityMatrix()
+	if e.pageMatrix != nil {
+		ctm = *e.pageMatrix
+	}
+	pt, numChars, numMisses, err := e.extractPageText(e.contents, e.resources, ctm, 0)
 	if err != nil {
 		return nil, numChars, numMisses, err
 	}
@@ -66,7 +70,7 @@ func (e *Extractor) ExtractPageText() (*PageText, int, int, error) {
 func (e *Extractor) extractPageText(contents string, resources *model.PdfPageResources,
 	parentCTM transform.Matrix, level int) (
 	*PageText, int, int, error) {
-	common.Log.Trace("extractPageText: level=%d", level)
+	e.log().Trace("extractPageText: level=%d", level)
 	pageText := &PageText{pageSize: e.mediaBox}
 	state := newTextState(e.mediaBox)
 	var savedStates stateStack
@@ -75,23 +79,28 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 
 	if level > maxFormStack {
 		err := errors.New("form stack overflow")
-		common.Log.Debug("ERROR: extractPageText. recursion level=%d err=%v", level, err)
+		e.log().Debug("ERROR: extractPageText. recursion level=%d err=%v", level, err)
 		return pageText, state.numChars, state.numMisses, err
 	}
 
 	// Uncomment the following 3 statements to log the content stream.
-	// common.Log.Info("contents* %d -----------------------------", len(contents))
+	// e.log().Info("contents* %d -----------------------------", len(contents))
 	// fmt.Println(contents)
-	// common.Log.Info("contents+ -----------------------------")
+	// e.log().Info("contents+ -----------------------------")
 
 	cstreamParser := contentstream.NewContentStreamParser(contents)
 	operations, err := cstreamParser.Parse()
 	if err != nil {
-		common.Log.Debug("ERROR: extractPageText parse failed. err=%v", err)
+		common.ReportDiagnostic(e.log(), common.Diagnostic{
+			Level:   common.LogLevelWarning,
+			Code:    common.DiagnosticContentInvalid,
+			Message: fmt.Sprintf("unable to parse content stream: %v", err),
+		})
 		return pageText, state.numChars, state.numMisses, err
 	}
 
 	processor := contentstream.NewContentStreamProcessor(*operations)
+	ocState := e.ocVisibility.NewContentState()
 
 	processor.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
 		func(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState,
@@ -99,10 +108,17 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 			operand := op.Operand
 
 			if verboseGeom {
-				common.Log.Info("&&& op=%s", op)
+				e.log().Info("&&& op=%s", op)
 			}
 
+			// Text in hidden optional content moves the text location but is not extracted.
+			to.hidden = ocState.Hidden()
+
 			switch operand {
+			case "BMC", "BDC": // Begin marked content.
+				ocState.BeginMarkedContent(op.Params, resources)
+			case "EMC": // End marked content.
+				ocState.EndMarkedContent()
 			case "q": // Push current graphics state to the stack.
 				savedStates.push(&state)
 			case "Q": // Pop graphics state from the stack.
@@ -119,7 +135,7 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 				// before an ET. However, if that happens, all existing marks
 				// are added to the  page marks, in order to avoid losing content.
 				if inTextObj {
-					common.Log.Debug("BT called while in a text object")
+					e.log().Debug("BT called while in a text object")
 					pageText.marks = append(pageText.marks, to.marks...)
 				}
 				inTextObj = true
@@ -136,7 +152,7 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 				// does not change: the text matrices are discarded and all
 				// existing marks in the text object are added to the page marks.
 				if !inTextObj {
-					common.Log.Debug("ET called outside of a text object")
+					e.log().Debug("ET called outside of a text object")
 				}
 				inTextObj = false
 				pageText.marks = append(pageText.marks, to.marks...)
@@ -145,7 +161,7 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 				to.nextLine()
 			case "Td": // Move text location
 				if ok, err := to.checkOp(op, 2, true); !ok {
-					common.Log.Debug("ERROR: err=%v", err)
+					e.log().Debug("ERROR: err=%v", err)
 					return err
 				}
 				x, y, err := toFloatXY(op.Params)
@@ -155,52 +171,52 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 				to.moveText(x, y)
 			case "TD": // Move text location and set leading.
 				if ok, err := to.checkOp(op, 2, true); !ok {
-					common.Log.Debug("ERROR: err=%v", err)
+					e.log().Debug("ERROR: err=%v", err)
 					return err
 				}
 				x, y, err := toFloatXY(op.Params)
 				if err != nil {
-					common.Log.Debug("ERROR: err=%v", err)
+					e.log().Debug("ERROR: err=%v", err)
 					return err
 				}
 				to.moveTextSetLeading(x, y)
 			case "Tj": // Show text.
 				if ok, err := to.checkOp(op, 1, true); !ok {
-					common.Log.Debug("ERROR: Tj op=%s err=%v", op, err)
+					e.log().Debug("ERROR: Tj op=%s err=%v", op, err)
 					return err
 				}
 				charcodes, ok := core.GetStringBytes(op.Params[0])
 				if !ok {
-					common.Log.Debug("ERROR: Tj op=%s GetStringBytes failed", op)
+					e.log().Debug("ERROR: Tj op=%s GetStringBytes failed", op)
 					return core.ErrTypeError
 				}
 				return to.showText(charcodes)
 			case "TJ": // Show text with adjustable spacing.
 				if ok, err := to.checkOp(op, 1, true); !ok {
-					common.Log.Debug("ERROR: TJ err=%v", err)
+					e.log().Debug("ERROR: TJ err=%v", err)
 					return err
 				}
 				args, ok := core.GetArray(op.Params[0])
 				if !ok {
-					common.Log.Debug("ERROR: TJ op=%s GetArrayVal failed", op)
+					e.log().Debug("ERROR: TJ op=%s GetArrayVal failed", op)
 					return err
 				}
 				return to.showTextAdjusted(args)
 			case "'": // Move to next line and show text.
 				if ok, err := to.checkOp(op, 1, true); !ok {
-					common.Log.Debug("ERROR: ' err=%v", err)
+					e.log().Debug("ERROR: ' err=%v", err)
 					return err
 				}
 				charcodes, ok := core.GetStringBytes(op.Params[0])
 				if !ok {
-					common.Log.Debug("ERROR: ' op=%s GetStringBytes failed", op)
+					e.log().Debug("ERROR: ' op=%s GetStringBytes failed", op)
 					return core.ErrTypeError
 				}
 				to.nextLine()
 				return to.showText(charcodes)
 			case `"`: // Set word and character spacing, move to next line, and show text.
 				if ok, err := to.checkOp(op, 3, true); !ok {
-					common.Log.Debug("ERROR: \" err=%v", err)
+					e.log().Debug("ERROR: \" err=%v", err)
 					return err
 				}
 				x, y, err := toFloatXY(op.Params[:2])
@@ -209,7 +225,7 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 				}
 				charcodes, ok := core.GetStringBytes(op.Params[2])
 				if !ok {
-					common.Log.Debug("ERROR: \" op=%s GetStringBytes failed", op)
+					e.log().Debug("ERROR: \" op=%s GetStringBytes failed", op)
 					return core.ErrTypeError
 				}
 				to.setCharSpacing(x)
@@ -219,30 +235,30 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 			case "TL": // Set text leading.
 				y, err := floatParam(op)
 				if err != nil {
-					common.Log.Debug("ERROR: TL err=%v", err)
+					e.log().Debug("ERROR: TL err=%v", err)
 					return err
 				}
 				to.setTextLeading(y)
 			case "Tc": // Set character spacing.
 				y, err := floatParam(op)
 				if err != nil {
-					common.Log.Debug("ERROR: Tc err=%v", err)
+					e.log().Debug("ERROR: Tc err=%v", err)
 					return err
 				}
 				to.setCharSpacing(y)
 			case "Tf": // Set font.
 				if ok, err := to.checkOp(op, 2, true); !ok {
-					common.Log.Debug("ERROR: Tf err=%v", err)
+					e.log().Debug("ERROR: Tf err=%v", err)
 					return err
 				}
 				name, ok := core.GetNameVal(op.Params[0])
 				if !ok {
-					common.Log.Debug("ERROR: Tf op=%s GetNameVal failed", op)
+					e.log().Debug("ERROR: Tf op=%s GetNameVal failed", op)
 					return core.ErrTypeError
 				}
 				size, err := core.GetNumberAsFloat(op.Params[1])
 				if !ok {
-					common.Log.Debug("ERROR: Tf op=%s GetFloatVal failed. err=%v", op, err)
+					e.log().Debug("ERROR: Tf op=%s GetFloatVal failed. err=%v", op, err)
 					return err
 				}
 				err = to.setFont(name, size)
@@ -252,75 +268,75 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 				}
 			case "Tm": // Set text matrix.
 				if ok, err := to.checkOp(op, 6, true); !ok {
-					common.Log.Debug("ERROR: Tm err=%v", err)
+					e.log().Debug("ERROR: Tm err=%v", err)
 					return err
 				}
 				floats, err := core.GetNumbersAsFloat(op.Params)
 				if err != nil {
-					common.Log.Debug("ERROR: err=%v", err)
+					e.log().Debug("ERROR: err=%v", err)
 					return err
 				}
 				to.setTextMatrix(floats)
 			case "Tr": // Set text rendering mode.
 				if ok, err := to.checkOp(op, 1, true); !ok {
-					common.Log.Debug("ERROR: Tr err=%v", err)
+					e.log().Debug("ERROR: Tr err=%v", err)
 					return err
 				}
 				mode, ok := core.GetIntVal(op.Params[0])
 				if !ok {
-					common.Log.Debug("ERROR: Tr op=%s GetIntVal failed", op)
+					e.log().Debug("ERROR: Tr op=%s GetIntVal failed", op)
 					return core.ErrTypeError
 				}
 				to.setTextRenderMode(mode)
 			case "Ts": // Set text rise.
 				if ok, err := to.checkOp(op, 1, true); !ok {
-					common.Log.Debug("ERROR: Ts err=%v", err)
+					e.log().Debug("ERROR: Ts err=%v", err)
 					return err
 				}
 				y, err := core.GetNumberAsFloat(op.Params[0])
 				if err != nil {
-					common.Log.Debug("ERROR: err=%v", err)
+					e.log().Debug("ERROR: err=%v", err)
 					return err
 				}
 				to.setTextRise(y)
 			case "Tw": // Set word spacing.
 				if ok, err := to.checkOp(op, 1, true); !ok {
-					common.Log.Debug("ERROR: err=%v", err)
+					e.log().Debug("ERROR: err=%v", err)
 					return err
 				}
 				y, err := core.GetNumberAsFloat(op.Params[0])
 				if err != nil {
-					common.Log.Debug("ERROR: err=%v", err)
+					e.log().Debug("ERROR: err=%v", err)
 					return err
 				}
 				to.setWordSpacing(y)
 			case "Tz": // Set horizontal scaling.
 				if ok, err := to.checkOp(op, 1, true); !ok {
-					common.Log.Debug("ERROR: err=%v", err)
+					e.log().Debug("ERROR: err=%v", err)
 					return err
 				}
 				y, err := core.GetNumberAsFloat(op.Params[0])
 				if err != nil {
-					common.Log.Debug("ERROR: err=%v", err)
+					e.log().Debug("ERROR: err=%v", err)
 					return err
 				}
 				to.setHorizScaling(y)
 			case "Do":
 				// Handle XObjects by recursing through form XObjects.
 				if len(op.Params) == 0 {
-					common.Log.Debug("ERROR: expected XObject name operand for Do operator. Got %+v.", op.Params)
+					e.log().Debug("ERROR: expected XObject name operand for Do operator. Got %+v.", op.Params)
 					return core.ErrRangeError
 				}
 
 				// Get XObject name.
 				name, ok := core.GetName(op.Params[0])
 				if !ok {
-					common.Log.Debug("ERROR: invalid Do operator XObject name operand: %+v.", op.Params[0])
+					e.log().Debug("ERROR: invalid Do operator XObject name operand: %+v.", op.Params[0])
 					return core.ErrTypeError
 				}
 
-				_, xtype := resources.GetXObjectByName(*name)
-				if xtype != model.XObjectTypeForm {
+				stream, xtype := resources.GetXObjectByName(*name)
+				if xtype != model.XObjectTypeForm || to.hidden || !e.ocVisibility.IsVisible(stream.Get("OC")) {
 					break
 				}
 				// Only process each form once.
@@ -328,12 +344,12 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 				if !ok {
 					xform, err := resources.GetXObjectFormByName(*name)
 					if err != nil {
-						common.Log.Debug("ERROR: %v", err)
+						e.log().Debug("ERROR: %v", err)
 						return err
 					}
 					formContent, err := xform.GetContentStream()
 					if err != nil {
-						common.Log.Debug("ERROR: %v", err)
+						e.log().Debug("ERROR: %v", err)
 						return err
 					}
 					formResources := xform.Resources
@@ -344,7 +360,7 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 					tList, numChars, numMisses, err := e.extractPageText(string(formContent),
 						formResources, parentCTM.Mult(gs.CTM), level+1)
 					if err != nil {
-						common.Log.Debug("ERROR: %v", err)
+						e.log().Debug("ERROR: %v", err)
 						return err
 					}
 					formResult = textResult{*tList, numChars, numMisses}
@@ -368,7 +384,7 @@ func (e *Extractor) extractPageText(contents string, resources *model.PdfPageRes
 
 	err = processor.Process(resources)
 	if err != nil {
-		common.Log.Debug("ERROR: Processing: err=%v", err)
+		e.log().Debug("ERROR: Processing: err=%v", err)
 	}
 	return pageText, state.numChars, state.numMisses, err
 }
@@ -417,7 +433,7 @@ func (to *textObject) nextLine() {
 // in `f` (page 250).
 func (to *textObject) setTextMatrix(f []float64) {
 	if len(f) != 6 {
-		common.Log.Debug("ERROR: len(f) != 6 (%d)", len(f))
+		to.e.log().Debug("ERROR: len(f) != 6 (%d)", len(f))
 		return
 	}
 	a, b, c, d, tx, ty := f[0], f[1], f[2], f[3], f[4], f[5]
@@ -438,7 +454,7 @@ func (to *textObject) showTextAdjusted(args *core.PdfObjectArray) error {
 		case *core.PdfObjectFloat, *core.PdfObjectInteger:
 			x, err := core.GetNumberAsFloat(o)
 			if err != nil {
-				common.Log.Debug("ERROR: showTextAdjusted. Bad numerical arg. o=%s args=%+v", o, args)
+				to.e.log().Debug("ERROR: showTextAdjusted. Bad numerical arg. o=%s args=%+v", o, args)
 				return err
 			}
 			dx, dy := -x*0.001*to.state.tfs, 0.0
@@ -450,12 +466,12 @@ func (to *textObject) showTextAdjusted(args *core.PdfObjectArray) error {
 		case *core.PdfObjectString:
 			charcodes, ok := core.GetStringBytes(o)
 			if !ok {
-				common.Log.Trace("showTextAdjusted: Bad string arg. o=%s args=%+v", o, args)
+				to.e.log().Trace("showTextAdjusted: Bad string arg. o=%s args=%+v", o, args)
 				return core.ErrTypeError
 			}
 			to.renderText(charcodes)
 		default:
-			common.Log.Debug("ERROR: showTextAdjusted. Unexpected type (%T) args=%+v", o, args)
+			to.e.log().Debug("ERROR: showTextAdjusted. Unexpected type (%T) args=%+v", o, args)
 			return core.ErrTypeError
 		}
 	}
@@ -477,7 +493,7 @@ func (to *textObject) setCharSpacing(x float64) {
 	}
 	to.state.tc = x
 	if verboseGeom {
-		common.Log.Info("setCharSpacing: %.2f state=%s", x, to.state.String())
+		to.e.log().Info("setCharSpacing: %.2f state=%s", x, to.state.String())
 	}
 }
 
@@ -557,14 +573,14 @@ func (to *textObject) checkOp(op *contentstream.ContentStreamOperation, numParam
 				params = params[:numParams]
 			}
 		}
-		common.Log.Debug("%#q operand outside text. params=%+v", op.Operand, params)
+		to.e.log().Debug("%#q operand outside text. params=%+v", op.Operand, params)
 	}
 	if numParams >= 0 {
 		if len(op.Params) != numParams {
 			if hard {
 				err = errors.New("incorrect parameter count")
 			}
-			common.Log.Debug("ERROR: %#q should have %d input params, got %d %+v",
+			to.e.log().Debug("ERROR: %#q should have %d input params, got %d %+v",
 				op.Operand, numParams, len(op.Params), op.Params)
 			return false, err
 		}
@@ -681,6 +697,7 @@ type textObject struct {
 	tlm         transform.Matrix // Text line matrix. For the start of line pointer.
 	marks       []*textMark      // Text marks get written here.
 	invalidFont bool             // Flag that gets set true when we can't handle the current font.
+	hidden      bool             // Flag that gets set true in hidden optional content.
 }
 
 // newTextState returns a default textState.
@@ -729,14 +746,14 @@ func (to *textObject) getStrokeColor() color.Color {
 // are tracked in `to`.
 func (to *textObject) renderText(data []byte) error {
 	if to.invalidFont {
-		common.Log.Debug("renderText: Invalid font. Not processing.")
+		to.e.log().Debug("renderText: Invalid font. Not processing.")
 		return nil
 	}
 	font := to.getCurrentFont()
 	charcodes := font.BytesToCharcodes(data)
 	texts, numChars, numMisses := font.CharcodesToStrings(charcodes)
 	if numMisses > 0 {
-		common.Log.Debug("renderText: numChars=%d numMisses=%d", numChars, numMisses)
+		to.e.log().Debug("renderText: numChars=%d numMisses=%d", numChars, numMisses)
 	}
 
 	to.state.numChars += numChars
@@ -753,17 +770,17 @@ func (to *textObject) renderText(data []byte) error {
 		spaceMetrics, _ = model.DefaultFont().GetRuneMetrics(' ')
 	}
 	spaceWidth := spaceMetrics.Wx * glyphTextRatio
-	common.Log.Trace("spaceWidth=%.2f text=%q font=%s fontSize=%.2f", spaceWidth, texts, font, tfs)
+	to.e.log().Trace("spaceWidth=%.2f text=%q font=%s fontSize=%.2f", spaceWidth, texts, font, tfs)
 
 	stateMatrix := transform.NewMatrix(
 		tfs*th, 0,
 		0, tfs,
 		0, state.trise)
 	if verboseGeom {
-		common.Log.Info("renderText: %d codes=%+v texts=%q", len(charcodes), charcodes, texts)
+		to.e.log().Info("renderText: %d codes=%+v texts=%q", len(charcodes), charcodes, texts)
 	}
 
-	common.Log.Trace("renderText: %d codes=%+v runes=%q", len(charcodes), charcodes, len(texts))
+	to.e.log().Trace("renderText: %d codes=%+v runes=%q", len(charcodes), charcodes, len(texts))
 
 	fillColor := to.getFillColor()
 	strokeColor := to.getStrokeColor()
@@ -790,7 +807,7 @@ func (to *textObject) renderText(data []byte) error {
 
 		m, ok := font.GetCharMetrics(code)
 		if !ok {
-			common.Log.Debug("ERROR: No metric for code=%d r=0x%04x=%+q %s", code, r, r, font)
+			to.e.log().Debug("ERROR: No metric for code=%d r=0x%04x=%+q %s", code, r, r, font)
 			return fmt.Errorf("no char metrics: font=%s code=%d", font.String(), code)
 		}
 
@@ -802,8 +819,8 @@ func (to *textObject) renderText(data []byte) error {
 		t0 := transform.Point{X: (c.X*tfs + w) * th}
 		t := transform.Point{X: (c.X*tfs + state.tc + w) * th}
 		if verboseGeom {
-			common.Log.Info("tfs=%.2f tc=%.2f tw=%.2f th=%.2f", tfs, state.tc, state.tw, th)
-			common.Log.Info("dx,dy=%.3f t0=%.2f t=%.2f", c, t0, t)
+			to.e.log().Info("tfs=%.2f tc=%.2f tw=%.2f th=%.2f", tfs, state.tc, state.tw, th)
+			to.e.log().Info("dx,dy=%.3f t0=%.2f t=%.2f", c, t0, t)
 		}
 
 		// td, td0 are t, t0 in matrix form.
@@ -813,7 +830,7 @@ func (to *textObject) renderText(data []byte) error {
 		end := to.gs.CTM.Mult(to.tm).Mult(td0)
 
 		if verboseGeom {
-			common.Log.Info("end:\n\tCTM=%s\n\t tm=%s\n"+
+			to.e.log().Info("end:\n\tCTM=%s\n\t tm=%s\n"+
 				"\t td=%s xlat=%s\n"+
 				"\ttd0=%s\n\t → %s xlat=%s",
 				to.gs.CTM, to.tm,
@@ -832,13 +849,13 @@ func (to *textObject) renderText(data []byte) error {
 			strokeColor)
 
 		if !onPage {
-			common.Log.Debug("Text mark outside page. Skipping")
+			to.e.log().Debug("Text mark outside page. Skipping")
 			continue
 		}
 		if font == nil {
-			common.Log.Debug("ERROR: No font.")
+			to.e.log().Debug("ERROR: No font.")
 		} else if font.Encoder() == nil {
-			common.Log.Debug("ERROR: No encoding. font=%s", font)
+			to.e.log().Debug("ERROR: No encoding. font=%s", font)
 		} else {
 			// TODO: This lookup seems confusing. Went from bytes <-> charcodes already.
 			// NOTE: This is needed to register runes by the font encoder - for subsetting (optimization).
@@ -846,8 +863,10 @@ func (to *textObject) renderText(data []byte) error {
 				mark.original = string(original)
 			}
 		}
-		common.Log.Trace("i=%d code=%d mark=%s trm=%s", i, code, mark, trm)
-		to.marks = append(to.marks, &mark)
+		to.e.log().Trace("i=%d code=%d mark=%s trm=%s", i, code, mark, trm)
+		if !to.hidden {
+			to.marks = append(to.marks, &mark)
+		}
 
 		// update the text matrix by the displacement of the text location.
 		to.tm.Concat(td)
@@ -1148,7 +1167,7 @@ func (to *textObject) getCurrentFont() *model.PdfFont {
 		font = to.savedStates.top().tfont
 	}
 	if font == nil {
-		common.Log.Debug("ERROR: No font defined. Using default.")
+		to.e.log().Debug("ERROR: No font defined. Using default.")
 		return model.DefaultFont()
 	}
 	return font
@@ -1204,13 +1223,19 @@ const maxFontCache = 10
 // getFontDirect returns the font named `name` if it exists in the page's resources or an error if
 // it doesn't. Accesses page resources directly (not cached).
 func (to *textObject) getFontDirect(name string) (*model.PdfFont, error) {
+	if err, ok := to.e.fontErrors[name]; ok {
+		return nil, err
+	}
 	fontObj, err := to.getFontDict(name)
 	if err != nil {
 		return nil, err
 	}
-	font, err := model.NewPdfFontFromPdfObject(fontObj)
+	font, err := model.NewPdfFontFromPdfObjectWithLogger(fontObj, to.e.log())
 	if err != nil {
-		common.Log.Debug("getFontDirect: NewPdfFontFromPdfObject failed. name=%#q err=%v", name, err)
+		if to.e.fontErrors == nil {
+			to.e.fontErrors = map[string]error{}
+		}
+		to.e.fontErrors[name] = err
 	}
 	return font, err
 }
@@ -1220,12 +1245,12 @@ func (to *textObject) getFontDirect(name string) (*model.PdfFont, error) {
 func (to *textObject) getFontDict(name string) (fontObj core.PdfObject, err error) {
 	resources := to.resources
 	if resources == nil {
-		common.Log.Debug("getFontDict. No resources. name=%#q", name)
+		to.e.log().Debug("getFontDict. No resources. name=%#q", name)
 		return nil, nil
 	}
 	fontObj, found := resources.GetFontByName(core.PdfObjectName(name))
 	if !found {
-		common.Log.Debug("ERROR: getFontDict: Font not found: name=%#q", name)
+		to.e.reportFont(common.DiagnosticFontMissing, name, "font %#q not in resources", name)
 		return nil, errors.New("font not in resources")
 	}
 	return fontObj, nil
@@ -10,7 +10,6 @@ import (
 	"image/color"
 	"math"
 
-	"github.com/unidoc/unipdf/v3/common"
 	"github.com/unidoc/unipdf/v3/internal/transform"
 	"github.com/unidoc/unipdf/v3/model"
 )
@@ -73,7 +72,7 @@ func (to *textObject) newTextMark(text string, trm transform.Matrix, end transfo
 
 	clipped, onPage := rectIntersection(bbox, to.e.mediaBox)
 	if !onPage {
-		common.Log.Debug("Text mark outside page. bbox=%g mediaBox=%g text=%q",
+		to.e.log().Debug("Text mark outside page. bbox=%g mediaBox=%g text=%q",
 			bbox, to.e.mediaBox, text)
 	}
 	bbox = clipped
@@ -125,7 +124,7 @@ func (to *textObject) newTextMark(text string, trm transform.Matrix, end transfo
 		strokeColor:  strokeColor,
 	}
 	if verboseGeom {
-		common.Log.Info("newTextMark: start=%.2f end=%.2f %s", start, end, tm.String())
+		to.e.log().Info("newTextMark: start=%.2f end=%.2f %s", start, end, tm.String())
 	}
 	return tm, onPage
 }
@@ -6,6 +6,7 @@
 package extractor
 
 import (
+	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -22,6 +23,7 @@ import (
 	"unicode/utf8"
 
 	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
 	"github.com/unidoc/unipdf/v3/creator"
 	"github.com/unidoc/unipdf/v3/model"
 	"golang.org/x/text/unicode/norm"
@@ -141,6 +143,151 @@ func TestTextExtractionFragments(t *testing.T) {
 	}
 }
 
+// TestTextExtractionOptionalContent tests that the text of hidden layers is not extracted.
+func TestTextExtractionOptionalContent(t *testing.T) {
+	c := creator.New()
+	c.NewPage()
+	layers := []struct {
+		text    string
+		visible bool
+	}{
+		{"Shown layer", true},
+		{"Hidden layer", false},
+	}
+	for i, l := range layers {
+		p := c.NewParagraph(l.text)
+		p.SetPos(50, 50+50*float64(i))
+		if err := c.Draw(c.NewLayer(l.text, l.visible).Wrap(p)); err != nil {
+			t.Fatalf("Draw failed. err=%v", err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := c.Write(&buf); err != nil {
+		t.Fatalf("Write failed. err=%v", err)
+	}
+
+	pdfReader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewPdfReader failed. err=%v", err)
+	}
+	page, err := pdfReader.GetPage(1)
+	if err != nil {
+		t.Fatalf("GetPage failed. err=%v", err)
+	}
+	e, err := New(page)
+	if err != nil {
+		t.Fatalf("New failed. err=%v", err)
+	}
+	text, err := e.ExtractText()
+	if err != nil {
+		t.Fatalf("ExtractText failed. err=%v", err)
+	}
+	if !strings.Contains(text, "Shown layer") {
+		t.Fatalf("Text of shown layer not extracted. Got %q", text)
+	}
+	if strings.Contains(text, "Hidden layer") {
+		t.Fatalf("Text of hidden layer extracted. Got %q", text)
+	}
+}
+
+// TestTextExtractionRotatedPage tests that the text of rotated pages is located in the page as
+// displayed: text drawn upwards on a page rotated clockwise is horizontal.
+func TestTextExtractionRotatedPage(t *testing.T) {
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 200, Ury: 100}
+	rotate := int64(90)
+	page.Rotate = &rotate
+	page.Resources = model.NewPdfPageResources()
+	helvetica := model.NewStandard14FontMustCompile(model.HelveticaName)
+	page.Resources.SetFontByName("F1", helvetica.ToPdfObject())
+	if err := page.AddContentStreamByString("BT /F1 10 Tf 0 1 -1 0 50 20 Tm (Hi) Tj ET"); err != nil {
+		t.Fatalf("AddContentStreamByString failed. err=%v", err)
+	}
+
+	e, err := New(page)
+	if err != nil {
+		t.Fatalf("New failed. err=%v", err)
+	}
+	pageText, _, _, err := e.ExtractPageText()
+	if err != nil {
+		t.Fatalf("ExtractPageText failed. err=%v", err)
+	}
+	bbox, ok := pageText.Marks().BBox()
+	if !ok {
+		t.Fatalf("No text marks")
+	}
+	// The text starts at (50, 20) in user space: (20, 150) on the page rotated clockwise.
+	if math.Abs(bbox.Llx-20) > 1 || math.Abs(bbox.Lly-150) > 1 || math.Abs(bbox.Ury-160) > 1 {
+		t.Fatalf("Bad text location. bbox=%.2f", bbox)
+	}
+}
+
+// TestTextExtractionFontDiagnostics tests that the fonts which cannot be loaded are reported once
+// in the diagnostics of the reader of the page, with their object number and the page number.
+func TestTextExtractionFontDiagnostics(t *testing.T) {
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 200, Ury: 100}
+	page.Resources = model.NewPdfPageResources()
+	// The BaseFont entry is missing.
+	font := core.MakeDict()
+	font.Set("Type", core.MakeName("Font"))
+	font.Set("Subtype", core.MakeName("TrueType"))
+	page.Resources.SetFontByName("F1", core.MakeIndirectObject(font))
+	content := "BT /F1 10 Tf 20 20 Td (Hi) Tj ET BT /F1 10 Tf 20 50 Td (Ho) Tj ET"
+	if err := page.AddContentStreamByString(content); err != nil {
+		t.Fatalf("AddContentStreamByString failed. err=%v", err)
+	}
+	w := model.NewPdfWriter()
+	if err := w.AddPage(page); err != nil {
+		t.Fatalf("AddPage failed. err=%v", err)
+	}
+	var buf bytes.Buffer
+	if err := w.Write(&buf); err != nil {
+		t.Fatalf("Write failed. err=%v", err)
+	}
+
+	pdfReader, err := model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewPdfReader failed. err=%v", err)
+	}
+	page, err = pdfReader.GetPage(1)
+	if err != nil {
+		t.Fatalf("GetPage failed. err=%v", err)
+	}
+	fontObj, ok := page.Resources.GetFontByName("F1")
+	if !ok {
+		t.Fatalf("Font F1 not in resources")
+	}
+	ind, ok := fontObj.(*core.PdfIndirectObject)
+	if !ok {
+		t.Fatalf("Font F1 not an indirect object (%T)", fontObj)
+	}
+	e, err := New(page)
+	if err != nil {
+		t.Fatalf("New failed. err=%v", err)
+	}
+	// The extraction fails on the font, which is reported once however many times it is used.
+	for i := 0; i < 2; i++ {
+		if _, err := e.ExtractText(); err == nil {
+			t.Fatalf("ExtractText succeeded with an invalid font")
+		}
+	}
+
+	var found []common.Diagnostic
+	for _, d := range pdfReader.Diagnostics() {
+		if d.Code == common.DiagnosticFontInvalid {
+			found = append(found, d)
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("Expected 1 font diagnostic. Got %v", found)
+	}
+	if d := found[0]; d.ObjectNumber != ind.ObjectNumber || d.PageNumber != 1 {
+		t.Fatalf("Bad font diagnostic. object=%d page=%d expected object=%d page=1",
+			d.ObjectNumber, d.PageNumber, ind.ObjectNumber)
+	}
+}
+
 // TestTextExtractionFiles tests text extraction on a set of PDF files.
 // It checks for the existence of specified strings of words on specified pages.
 // We currently only check within lines as our line order is still improving.
@@ -8,14 +8,11 @@ package fdf
 import (
 	"bufio"
 	"bytes"
-	"encoding/hex"
 	"errors"
-	"fmt"
 	"io"
 	"regexp"
 	"sort"
 	"strconv"
-	"strings"
 
 	"github.com/unidoc/unipdf/v3/common"
 	"github.com/unidoc/unipdf/v3/core"
@@ -24,10 +21,6 @@ import (
 // Regular Expressions for parsing and identifying object signatures.
 var reFdfVersion = regexp.MustCompile(`%FDF-(\d)\.(\d)`)
 var reEOF = regexp.MustCompile("%%EOF")
-var reNumeric = regexp.MustCompile(`^[\+-.]*([0-9.]+)`)
-var reExponential = regexp.MustCompile(`^[\+-.]*([0-9.]+)e[\+-.]*([0-9.]+)`)
-var reReference = regexp.MustCompile(`^\s*(\d+)\s+(\d+)\s+R`)
-var reIndirectObject = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj`)
 
 // fdfParser parses a FDF file and provides access to the object structure of the FDF.
 type fdfParser struct {
@@ -37,538 +30,12 @@ type fdfParser struct {
 	objCache map[int64]core.PdfObject
 
 	rs       io.ReadSeeker
-	reader   *bufio.Reader
+	lexer    *core.Lexer
 	fileSize int64
 
 	trailerDict *core.PdfObjectDictionary
 }
 
-// Skip over any spaces.
-func (parser *fdfParser) skipSpaces() (int, error) {
-	cnt := 0
-	for {
-		b, err := parser.reader.ReadByte()
-		if err != nil {
-			return 0, err
-		}
-		if core.IsWhiteSpace(b) {
-			cnt++
-		} else {
-			parser.reader.UnreadByte()
-			break
-		}
-	}
-
-	return cnt, nil
-}
-
-// Skip over comments and spaces. Can handle multi-line comments.
-func (parser *fdfParser) skipComments() error {
-	if _, err := parser.skipSpaces(); err != nil {
-		return err
-	}
-
-	isFirst := true
-	for {
-		bb, err := parser.reader.Peek(1)
-		if err != nil {
-			common.Log.Debug("Error %s", err.Error())
-			return err
-		}
-		if isFirst && bb[0] != '%' {
-			// Not a comment clearly.
-			return nil
-		}
-		isFirst = false
-		if (bb[0] != '\r') && (bb[0] != '\n') {
-			parser.reader.ReadByte()
-		} else {
-			break
-		}
-	}
-
-	// Call recursively to handle multiline comments.
-	return parser.skipComments()
-}
-
-// Read a comment starting with '%'.
-func (parser *fdfParser) readComment() (string, error) {
-	var r bytes.Buffer
-
-	_, err := parser.skipSpaces()
-	if err != nil {
-		return r.String(), err
-	}
-
-	isFirst := true
-	for {
-		bb, err := parser.reader.Peek(1)
-		if err != nil {
-			common.Log.Debug("Error %s", err.Error())
-			return r.String(), err
-		}
-		if isFirst && bb[0] != '%' {
-			return r.String(), errors.New("comment should start with %")
-		}
-		isFirst = false
-		if (bb[0] != '\r') && (bb[0] != '\n') {
-			b, _ := parser.reader.ReadByte()
-			r.WriteByte(b)
-		} else {
-			break
-		}
-	}
-	return r.String(), nil
-}
-
-// Read a single line of text from current position.
-func (parser *fdfParser) readTextLine() (string, error) {
-	var r bytes.Buffer
-	for {
-		bb, err := parser.reader.Peek(1)
-		if err != nil {
-			common.Log.Debug("Error %s", err.Error())
-			return r.String(), err
-		}
-		if (bb[0] != '\r') && (bb[0] != '\n') {
-			b, _ := parser.reader.ReadByte()
-			r.WriteByte(b)
-		} else {
-			break
-		}
-	}
-	return r.String(), nil
-}
-
-// Parse a name starting with '/'.
-func (parser *fdfParser) parseName() (core.PdfObjectName, error) {
-	var r bytes.Buffer
-	nameStarted := false
-	for {
-		bb, err := parser.reader.Peek(1)
-		if err == io.EOF {
-			break // Can happen when loading from object stream.
-		}
-		if err != nil {
-			return core.PdfObjectName(r.String()), err
-		}
-
-		if !nameStarted {
-			// Should always start with '/', otherwise not valid.
-			if bb[0] == '/' {
-				nameStarted = true
-				parser.reader.ReadByte()
-			} else if bb[0] == '%' {
-				parser.readComment()
-				parser.skipSpaces()
-			} else {
-				common.Log.Debug("ERROR Name starting with %s (% x)", bb, bb)
-				return core.PdfObjectName(r.String()), fmt.Errorf("invalid name: (%c)", bb[0])
-			}
-		} else {
-			if core.IsWhiteSpace(bb[0]) {
-				break
-			} else if (bb[0] == '/') || (bb[0] == '[') || (bb[0] == '(') || (bb[0] == ']') || (bb[0] == '<') || (bb[0] == '>') {
-				break // Looks like start of next statement.
-			} else if bb[0] == '#' {
-				hexcode, err := parser.reader.Peek(3)
-				if err != nil {
-					return core.PdfObjectName(r.String()), err
-				}
-				parser.reader.Discard(3)
-
-				code, err := hex.DecodeString(string(hexcode[1:3]))
-				if err != nil {
-					return core.PdfObjectName(r.String()), err
-				}
-				r.Write(code)
-			} else {
-				b, _ := parser.reader.ReadByte()
-				r.WriteByte(b)
-			}
-		}
-	}
-	return core.PdfObjectName(r.String()), nil
-}
-
-// Numeric objects.
-// Section 7.3.3.
-// Integer or Float.
-//
-// An integer shall be written as one or more decimal digits optionally
-// preceded by a sign. The value shall be interpreted as a signed
-// decimal integer and shall be converted to an integer object.
-//
-// A real value shall be written as one or more decimal digits with an
-// optional sign and a leading, trailing, or embedded PERIOD (2Eh)
-// (decimal point). The value shall be interpreted as a real number
-// and shall be converted to a real object.
-//
-// Regarding exponential numbers: 7.3.3 Numeric Objects:
-// A conforming writer shall not use the PostScript syntax for numbers
-// with non-decimal radices (such as 16#FFFE) or in exponential format
-// (such as 6.02E23).
-// Nonetheless, we sometimes get numbers with exponential format, so
-// we will support it in the reader (no confusion with other types, so
-// no compromise).
-func (parser *fdfParser) parseNumber() (core.PdfObject, error) {
-	return core.ParseNumber(parser.reader)
-}
-
-// A string starts with '(' and ends with ')'.
-func (parser *fdfParser) parseString() (*core.PdfObjectString, error) {
-	parser.reader.ReadByte()
-
-	var r bytes.Buffer
-	count := 1
-	for {
-		bb, err := parser.reader.Peek(1)
-		if err != nil {
-			return core.MakeString(r.String()), err
-		}
-
-		if bb[0] == '\\' { // Escape sequence.
-			parser.reader.ReadByte() // Skip the escape \ byte.
-			b, err := parser.reader.ReadByte()
-			if err != nil {
-				return core.MakeString(r.String()), err
-			}
-
-			// Octal '\ddd' number (base 8).
-			if core.IsOctalDigit(b) {
-				bb, err := parser.reader.Peek(2)
-				if err != nil {
-					return core.MakeString(r.String()), err
-				}
-
-				var numeric []byte
-				numeric = append(numeric, b)
-				for _, val := range bb {
-					if core.IsOctalDigit(val) {
-						numeric = append(numeric, val)
-					} else {
-						break
-					}
-				}
-				parser.reader.Discard(len(numeric) - 1)
-
-				common.Log.Trace("Numeric string \"%s\"", numeric)
-				code, err := strconv.ParseUint(string(numeric), 8, 32)
-				if err != nil {
-					return core.MakeString(r.String()), err
-				}
-				r.WriteByte(byte(code))
-				continue
-			}
-
-			switch b {
-			case 'n':
-				r.WriteRune('\n')
-			case 'r':
-				r.WriteRune('\r')
-			case 't':
-				r.WriteRune('\t')
-			case 'b':
-				r.WriteRune('\b')
-			case 'f':
-				r.WriteRune('\f')
-			case '(':
-				r.WriteRune('(')
-			case ')':
-				r.WriteRune(')')
-			case '\\':
-				r.WriteRune('\\')
-			}
-
-			continue
-		} else if bb[0] == '(' {
-			count++
-		} else if bb[0] == ')' {
-			count--
-			if count == 0 {
-				parser.reader.ReadByte()
-				break
-			}
-		}
-
-		b, _ := parser.reader.ReadByte()
-		r.WriteByte(b)
-	}
-
-	return core.MakeString(r.String()), nil
-}
-
-// Starts with '<' ends with '>'.
-// Currently not converting the hex codes to characters.
-func (parser *fdfParser) parseHexString() (*core.PdfObjectString, error) {
-	parser.reader.ReadByte()
-
-	var r bytes.Buffer
-	for {
-		bb, err := parser.reader.Peek(1)
-		if err != nil {
-			return core.MakeHexString(""), err
-		}
-
-		if bb[0] == '>' {
-			parser.reader.ReadByte()
-			break
-		}
-
-		b, _ := parser.reader.ReadByte()
-		if !core.IsWhiteSpace(b) {
-			r.WriteByte(b)
-		}
-	}
-
-	if r.Len()%2 == 1 {
-		r.WriteRune('0')
-	}
-
-	buf, err := hex.DecodeString(r.String())
-	if err != nil {
-		common.Log.Debug("ERROR Parsing hex string: '%s' - returning an empty string", r.String())
-		return core.MakeHexString(""), nil
-	}
-	return core.MakeHexString(string(buf)), nil
-}
-
-// Starts with '[' ends with ']'.  Can contain any kinds of direct objects.
-func (parser *fdfParser) parseArray() (*core.PdfObjectArray, error) {
-	arr := core.MakeArray()
-
-	parser.reader.ReadByte()
-
-	for {
-		parser.skipSpaces()
-
-		bb, err := parser.reader.Peek(1)
-		if err != nil {
-			return arr, err
-		}
-
-		if bb[0] == ']' {
-			parser.reader.ReadByte()
-			break
-		}
-
-		obj, err := parser.parseObject()
-		if err != nil {
-			return arr, err
-		}
-		arr.Append(obj)
-	}
-
-	return arr, nil
-}
-
-// Parse bool object.
-func (parser *fdfParser) parseBool() (core.PdfObjectBool, error) {
-	bb, err := parser.reader.Peek(4)
-	if err != nil {
-		return core.PdfObjectBool(false), err
-	}
-	if (len(bb) >= 4) && (string(bb[:4]) == "true") {
-		parser.reader.Discard(4)
-		return core.PdfObjectBool(true), nil
-	}
-
-	bb, err = parser.reader.Peek(5)
-	if err != nil {
-		return core.PdfObjectBool(false), err
-	}
-	if (len(bb) >= 5) && (string(bb[:5]) == "false") {
-		parser.reader.Discard(5)
-		return core.PdfObjectBool(false), nil
-	}
-
-	return core.PdfObjectBool(false), errors.New("unexpected boolean string")
-}
-
-// Parse reference to an indirect object.
-func parseReference(refStr string) (core.PdfObjectReference, error) {
-	objref := core.PdfObjectReference{}
-
-	result := reReference.FindStringSubmatch(string(refStr))
-	if len(result) < 3 {
-		common.Log.Debug("Error parsing reference")
-		return objref, errors.New("unable to parse reference")
-	}
-
-	objNum, err := strconv.Atoi(result[1])
-	if err != nil {
-		common.Log.Debug("Error parsing object number '%s' - Using object num = 0", result[1])
-		return objref, nil
-	}
-	objref.ObjectNumber = int64(objNum)
-
-	genNum, err := strconv.Atoi(result[2])
-	if err != nil {
-		common.Log.Debug("Error parsing generation number '%s' - Using gen = 0", result[2])
-		return objref, nil
-	}
-	objref.GenerationNumber = int64(genNum)
-
-	return objref, nil
-}
-
-// Parse null object.
-func (parser *fdfParser) parseNull() (core.PdfObjectNull, error) {
-	_, err := parser.reader.Discard(4)
-	return core.PdfObjectNull{}, err
-}
-
-// Detect the signature at the current file position and parse
-// the corresponding object.
-func (parser *fdfParser) parseObject() (core.PdfObject, error) {
-	common.Log.Trace("Read direct object")
-	parser.skipSpaces()
-	for {
-		bb, err := parser.reader.Peek(2)
-		if err != nil {
-			return nil, err
-		}
-
-		common.Log.Trace("Peek string: %s", string(bb))
-		// Determine type.
-		if bb[0] == '/' {
-			name, err := parser.parseName()
-			common.Log.Trace("->Name: '%s'", name)
-			return &name, err
-		} else if bb[0] == '(' {
-			common.Log.Trace("->String!")
-			return parser.parseString()
-		} else if bb[0] == '[' {
-			common.Log.Trace("->Array!")
-			return parser.parseArray()
-		} else if (bb[0] == '<') && (bb[1] == '<') {
-			common.Log.Trace("->Dict!")
-			return parser.parseDict()
-		} else if bb[0] == '<' {
-			common.Log.Trace("->Hex string!")
-			return parser.parseHexString()
-		} else if bb[0] == '%' {
-			parser.readComment()
-			parser.skipSpaces()
-		} else {
-			common.Log.Trace("->Number or ref?")
-			// Reference or number?
-			// Let's peek farther to find out.
-			bb, _ = parser.reader.Peek(15)
-			peekStr := string(bb)
-			common.Log.Trace("Peek str: %s", peekStr)
-
-			if (len(peekStr) > 3) && (peekStr[:4] == "null") {
-				null, err := parser.parseNull()
-				return &null, err
-			} else if (len(peekStr) > 4) && (peekStr[:5] == "false") {
-				b, err := parser.parseBool()
-				return &b, err
-			} else if (len(peekStr) > 3) && (peekStr[:4] == "true") {
-				b, err := parser.parseBool()
-				return &b, err
-			}
-
-			// Match reference.
-			result1 := reReference.FindStringSubmatch(string(peekStr))
-			if len(result1) > 1 {
-				bb, _ = parser.reader.ReadBytes('R')
-				common.Log.Trace("-> !Ref: '%s'", string(bb[:]))
-				ref, err := parseReference(string(bb))
-				return &ref, err
-			}
-
-			result2 := reNumeric.FindStringSubmatch(string(peekStr))
-			if len(result2) > 1 {
-				// Number object.
-				common.Log.Trace("-> Number!")
-				return parser.parseNumber()
-			}
-
-			result2 = reExponential.FindStringSubmatch(string(peekStr))
-			if len(result2) > 1 {
-				// Number object (exponential)
-				common.Log.Trace("-> Exponential Number!")
-				common.Log.Trace("% s", result2)
-				return parser.parseNumber()
-			}
-
-			common.Log.Debug("ERROR Unknown (peek \"%s\")", peekStr)
-			return nil, errors.New("object parsing error - unexpected pattern")
-		}
-	}
-}
-
-// Reads and parses a FDF dictionary object enclosed with '<<' and '>>'
-func (parser *fdfParser) parseDict() (*core.PdfObjectDictionary, error) {
-	common.Log.Trace("Reading FDF Dict!")
-
-	dict := core.MakeDict()
-
-	// Pass the '<<'
-	c, _ := parser.reader.ReadByte()
-	if c != '<' {
-		return nil, errors.New("invalid dict")
-	}
-	c, _ = parser.reader.ReadByte()
-	if c != '<' {
-		return nil, errors.New("invalid dict")
-	}
-
-	for {
-		parser.skipSpaces()
-		parser.skipComments()
-
-		bb, err := parser.reader.Peek(2)
-		if err != nil {
-			return nil, err
-		}
-
-		common.Log.Trace("Dict peek: %s (% x)!", string(bb), string(bb))
-		if (bb[0] == '>') && (bb[1] == '>') {
-			common.Log.Trace("EOF dictionary")
-			parser.reader.ReadByte()
-			parser.reader.ReadByte()
-			break
-		}
-		common.Log.Trace("Parse the name!")
-
-		keyName, err := parser.parseName()
-		common.Log.Trace("Key: %s", keyName)
-		if err != nil {
-			common.Log.Debug("ERROR Returning name err %s", err)
-			return nil, err
-		}
-
-		if len(keyName) > 4 && keyName[len(keyName)-4:] == "null" {
-			// Some writers have a bug where the null is appended without
-			// space.  For example "\Boundsnull"
-			newKey := keyName[0 : len(keyName)-4]
-			common.Log.Debug("Taking care of null bug (%s)", keyName)
-			common.Log.Debug("New key \"%s\" = null", newKey)
-			parser.skipSpaces()
-			bb, _ := parser.reader.Peek(1)
-			if bb[0] == '/' {
-				dict.Set(newKey, core.MakeNull())
-				continue
-			}
-		}
-
-		parser.skipSpaces()
-
-		val, err := parser.parseObject()
-		if err != nil {
-			return nil, err
-		}
-		dict.Set(keyName, val)
-
-		common.Log.Trace("dict[%s] = %s", keyName, val.String())
-	}
-	common.Log.Trace("returning FDF Dict!")
-
-	return dict, nil
-}
-
 // Parse the FDF version from the beginning of the file.
 // Returns the major and minor parts of the version.
 // E.g. for "FDF-1.4" would return 1 and 4.
@@ -644,153 +111,118 @@ func (parser *fdfParser) seekToEOFMarker(fSize int64) error {
 	return errors.New("EOF not found")
 }
 
-// Parse an indirect object from the input stream. Can also be an object stream.
+// nextToken returns the next token, skipping comments.
+func (parser *fdfParser) nextToken() (core.Token, error) {
+	for {
+		tok, err := parser.lexer.Next()
+		if err != nil || tok.Type != core.TokenComment {
+			return tok, err
+		}
+	}
+}
+
+// Parse an indirect object from the input stream, starting with the object number token `tok`.
+// Can also be an object stream.
 // Returns the indirect object (*PdfIndirectObject) or the stream object (*PdfObjectStream).
-func (parser *fdfParser) parseIndirectObject() (core.PdfObject, error) {
+func (parser *fdfParser) parseIndirectObject(tok core.Token) (core.PdfObject, error) {
 	indirect := core.PdfIndirectObject{}
 
 	common.Log.Trace("-Read indirect obj")
-	bb, err := parser.reader.Peek(20)
+	on, ok := tok.Int()
+	if !ok {
+		common.Log.Debug("ERROR: Unable to find object signature (%s)", tok.Raw)
+		return &indirect, errors.New("unable to detect indirect object signature")
+	}
+	tok, err := parser.nextToken()
 	if err != nil {
-		common.Log.Debug("ERROR: Fail to read indirect obj")
 		return &indirect, err
 	}
-	common.Log.Trace("(indirect obj peek \"%s\"", string(bb))
-
-	indices := reIndirectObject.FindStringSubmatchIndex(string(bb))
-	if len(indices) < 6 {
-		common.Log.Debug("ERROR: Unable to find object signature (%s)", string(bb))
+	gn, ok := tok.Int()
+	if !ok {
+		common.Log.Debug("ERROR: Unable to find object signature (%d %s)", on, tok.Raw)
 		return &indirect, errors.New("unable to detect indirect object signature")
 	}
-	parser.reader.Discard(indices[0]) // Take care of any small offset.
-	common.Log.Trace("Offsets % d", indices)
+	tok, err = parser.nextToken()
+	if err != nil {
+		return &indirect, err
+	}
+	if tok.Type != core.TokenObj {
+		common.Log.Debug("ERROR: Unable to find object signature (%d %d %s)", on, gn, tok.Raw)
+		return &indirect, errors.New("unable to detect indirect object signature")
+	}
+	indirect.ObjectNumber = on
+	indirect.GenerationNumber = gn
 
-	// Read the object header.
-	hlen := indices[1] - indices[0]
-	hb := make([]byte, hlen)
-	_, err = parser.readAtLeast(hb, hlen)
+	tok, err = parser.nextToken()
 	if err != nil {
-		common.Log.Debug("ERROR: unable to read - %s", err)
-		return nil, err
+		return &indirect, err
 	}
-	common.Log.Trace("textline: %s", hb)
+	if tok.Type == core.TokenEndObj {
+		return &indirect, nil
+	}
+	indirect.PdfObject, err = parser.lexer.ReadObject(tok)
+	if err != nil {
+		return &indirect, err
+	}
+	common.Log.Trace("Parsed object ... finished.")
 
-	result := reIndirectObject.FindStringSubmatch(string(hb))
-	if len(result) < 3 {
-		common.Log.Debug("ERROR: Unable to find object signature (%s)", string(hb))
-		return &indirect, errors.New("unable to detect indirect object signature")
+	tok, err = parser.nextToken()
+	if err != nil {
+		return &indirect, err
+	}
+	switch tok.Type {
+	case core.TokenEndObj:
+		common.Log.Trace("Returning indirect!")
+		return &indirect, nil
+	case core.TokenStream:
+	default:
+		common.Log.Debug("ERROR: Expected endobj, got %s (%s)", tok.Type, tok.Raw)
+		return &indirect, errors.New("invalid indirect object: missing endobj")
 	}
 
-	on, _ := strconv.Atoi(result[1])
-	gn, _ := strconv.Atoi(result[2])
-	indirect.ObjectNumber = int64(on)
-	indirect.GenerationNumber = int64(gn)
+	dict, isDict := indirect.PdfObject.(*core.PdfObjectDictionary)
+	if !isDict {
+		return nil, errors.New("stream object missing dictionary")
+	}
+	common.Log.Trace("Stream dict %s", dict)
 
-	for {
-		bb, err := parser.reader.Peek(2)
-		if err != nil {
-			return &indirect, err
-		}
-		common.Log.Trace("Ind. peek: %s (% x)!", string(bb), string(bb))
-
-		if core.IsWhiteSpace(bb[0]) {
-			parser.skipSpaces()
-		} else if bb[0] == '%' {
-			parser.skipComments()
-		} else if (bb[0] == '<') && (bb[1] == '<') {
-			common.Log.Trace("Call ParseDict")
-			indirect.PdfObject, err = parser.parseDict()
-			common.Log.Trace("EOF Call ParseDict: %v", err)
-			if err != nil {
-				return &indirect, err
-			}
-			common.Log.Trace("Parsed dictionary... finished.")
-		} else if (bb[0] == '/') || (bb[0] == '(') || (bb[0] == '[') || (bb[0] == '<') {
-			indirect.PdfObject, err = parser.parseObject()
-			if err != nil {
-				return &indirect, err
-			}
-			common.Log.Trace("Parsed object ... finished.")
-		} else {
-			if bb[0] == 'e' {
-				lineStr, err := parser.readTextLine()
-				if err != nil {
-					return nil, err
-				}
-				if len(lineStr) >= 6 && lineStr[0:6] == "endobj" {
-					break
-				}
-			} else if bb[0] == 's' {
-				bb, _ = parser.reader.Peek(10)
-				if string(bb[:6]) == "stream" {
-					discardBytes := 6
-					if len(bb) > 6 {
-						if core.IsWhiteSpace(bb[discardBytes]) && bb[discardBytes] != '\r' && bb[discardBytes] != '\n' {
-							// If any other white space character... should not happen!
-							// Skip it..
-							common.Log.Debug("Non-conformant FDF not ending stream line properly with EOL marker")
-							discardBytes++
-						}
-						if bb[discardBytes] == '\r' {
-							discardBytes++
-							if bb[discardBytes] == '\n' {
-								discardBytes++
-							}
-						} else if bb[discardBytes] == '\n' {
-							discardBytes++
-						}
-					}
-
-					parser.reader.Discard(discardBytes)
-
-					dict, isDict := indirect.PdfObject.(*core.PdfObjectDictionary)
-					if !isDict {
-						return nil, errors.New("stream object missing dictionary")
-					}
-					common.Log.Trace("Stream dict %s", dict)
-
-					pstreamLength, ok := dict.Get("Length").(*core.PdfObjectInteger)
-					if !ok {
-						return nil, errors.New("stream length needs to be an integer")
-					}
-					streamLength := *pstreamLength
-					if streamLength < 0 {
-						return nil, errors.New("stream needs to be longer than 0")
-					}
-
-					// Make sure is less than actual file size.
-					if int64(streamLength) > parser.fileSize {
-						common.Log.Debug("ERROR: Stream length cannot be larger than file size")
-						return nil, errors.New("invalid stream length, larger than file size")
-					}
-
-					stream := make([]byte, streamLength)
-					_, err = parser.readAtLeast(stream, int(streamLength))
-					if err != nil {
-						common.Log.Debug("ERROR stream (%d): %X", len(stream), stream)
-						common.Log.Debug("ERROR: %v", err)
-						return nil, err
-					}
-
-					streamobj := core.PdfObjectStream{}
-					streamobj.Stream = stream
-					streamobj.PdfObjectDictionary = indirect.PdfObject.(*core.PdfObjectDictionary)
-					streamobj.ObjectNumber = indirect.ObjectNumber
-					streamobj.GenerationNumber = indirect.GenerationNumber
-
-					parser.skipSpaces()
-					parser.reader.Discard(9) // endstream
-					parser.skipSpaces()
-					return &streamobj, nil
-				}
-			}
+	pstreamLength, ok := dict.Get("Length").(*core.PdfObjectInteger)
+	if !ok {
+		return nil, errors.New("stream length needs to be an integer")
+	}
+	streamLength := int64(*pstreamLength)
+	if streamLength < 0 {
+		return nil, errors.New("stream needs to be longer than 0")
+	}
+
+	// Make sure is less than actual file size.
+	if streamLength > parser.fileSize {
+		common.Log.Debug("ERROR: Stream length cannot be larger than file size")
+		return nil, errors.New("invalid stream length, larger than file size")
+	}
+
+	stream, err := parser.lexer.ReadStream(streamLength)
+	if err != nil {
+		common.Log.Debug("ERROR: %v", err)
+		return nil, err
+	}
 
-			indirect.PdfObject, err = parser.parseObject()
-			return &indirect, err
+	streamobj := core.PdfObjectStream{}
+	streamobj.Stream = stream
+	streamobj.PdfObjectDictionary = dict
+	streamobj.ObjectNumber = indirect.ObjectNumber
+	streamobj.GenerationNumber = indirect.GenerationNumber
+
+	// Skip over endstream and endobj.
+	for _, typ := range []core.TokenType{core.TokenEndStream, core.TokenEndObj} {
+		tok, err := parser.lexer.Peek()
+		if err != nil || tok.Type != typ {
+			common.Log.Debug("Stream object %d missing %s", on, typ)
+			break
 		}
+		parser.lexer.Next()
 	}
-	common.Log.Trace("Returning indirect!")
-	return &indirect, nil
+	return &streamobj, nil
 }
 
 // newParserFromString parses an FDF from a string.
@@ -803,9 +235,6 @@ func newParserFromString(txt string) (*fdfParser, error) {
 	parser.rs = bufReader
 	parser.objCache = map[int64]core.PdfObject{}
 
-	bufferedReader := bufio.NewReader(bufReader)
-	parser.reader = bufferedReader
-
 	parser.fileSize = int64(len(txt))
 
 	return &parser, parser.parse()
@@ -847,6 +276,12 @@ func newParser(rs io.ReadSeeker) (*fdfParser, error) {
 	parser.rs = rs
 	parser.objCache = map[int64]core.PdfObject{}
 
+	fileSize, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	parser.fileSize = fileSize
+
 	// Read from top to bottom...
 	// 1. Get the version
 	// 2. Sequentially parse indirect objects, until does not match
@@ -882,37 +317,35 @@ func (parser *fdfParser) trace(obj core.PdfObject) core.PdfObject {
 
 // parse runs through the file and parses indirect objects and loads into cache.
 func (parser *fdfParser) parse() error {
-	// Go to beginning, reset reader.
+	// Go to beginning, reset lexer.
 	parser.rs.Seek(0, io.SeekStart)
-	parser.reader = bufio.NewReader(parser.rs)
+	parser.lexer = core.NewLexer(parser.rs)
 
 	// Parse indirect objects sequentially.
 	for {
-		parser.skipComments()
-
-		bb, err := parser.reader.Peek(20)
+		tok, err := parser.nextToken()
+		if err == io.EOF {
+			common.Log.Debug("Trailer not found")
+			break
+		}
 		if err != nil {
 			common.Log.Debug("ERROR: Fail to read indirect obj")
 			return err
 		}
 
-		if strings.HasPrefix(string(bb), "trailer") {
+		if tok.Is("trailer") {
 			// End
-			parser.reader.Discard(7)
-			parser.skipSpaces()
-			parser.skipComments()
-			trailerDict, _ := parser.parseDict()
-			parser.trailerDict = trailerDict
+			trailerDict, _ := parser.lexer.NextObject()
+			parser.trailerDict, _ = trailerDict.(*core.PdfObjectDictionary)
 			break
 		}
 
-		indices := reIndirectObject.FindStringSubmatchIndex(string(bb))
-		if len(indices) < 6 {
-			common.Log.Debug("ERROR: Unable to find object signature (%s)", string(bb))
+		if tok.Type != core.TokenNumber {
+			common.Log.Debug("ERROR: Unable to find object signature (%s)", tok.Raw)
 			return errors.New("unable to detect indirect object signature")
 		}
 
-		indObj, err := parser.parseIndirectObject()
+		indObj, err := parser.parseIndirectObject(tok)
 		if err != nil {
 			return err
 		}
@@ -936,14 +369,14 @@ func (parser *fdfParser) parse() error {
 func (parser *fdfParser) seekFdfVersionTopDown() (int, int, error) {
 	// Go to beginning, reset reader.
 	parser.rs.Seek(0, io.SeekStart)
-	parser.reader = bufio.NewReader(parser.rs)
+	reader := bufio.NewReader(parser.rs)
 
 	// Keep a running buffer of last bytes.
 	bufLen := 20
 	last := make([]byte, bufLen)
 
 	for {
-		b, err := parser.reader.ReadByte()
+		b, err := reader.ReadByte()
 		if err != nil {
 			if err == io.EOF {
 				break
@@ -87,3 +87,45 @@ func TestFdfExample1(t *testing.T) {
 	}
 
 }
+
+func TestFdfParsing(t *testing.T) {
+	testcases := []struct {
+		Content string
+		Valid   bool
+		Fields  int
+	}{
+		// Case 1. Trailer missing at the end of the file: the FDF dictionary is looked up in the
+		// objects.
+		{"%FDF-1.2\n1 0 obj\n<</FDF<</Fields[<</T(Field1)/V(Test1)>>]>>>>\nendobj\n", true, 1},
+		// Case 2. Stream object.
+		{"%FDF-1.2\n1 0 obj\n<</FDF<</Fields[<</T(Field1)/V 2 0 R>>]>>>>\nendobj\n" +
+			"2 0 obj\n<</Length 4>>\nstream\ndata\nendstream\nendobj\ntrailer\n<</Root 1 0 R>>\n%%EOF\n", true, 1},
+		// Case 3. Object truncated at the end of the file.
+		{"%FDF-1.2\n1 0 obj\n<</FDF<</Fields[", false, 0},
+		// Case 4. Invalid object signature.
+		{"%FDF-1.2\n1 0 xobj\n<<>>\nendobj\n", false, 0},
+	}
+
+	for i, tcase := range testcases {
+		parser, err := newParserFromString(tcase.Content)
+		if !tcase.Valid {
+			if err == nil {
+				t.Errorf("Case %d: expected error", i+1)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Case %d: error: %v", i+1, err)
+			continue
+		}
+		fdfDict, err := parser.Root()
+		if err != nil {
+			t.Errorf("Case %d: error: %v", i+1, err)
+			continue
+		}
+		fields, ok := fdfDict.Get("Fields").(*core.PdfObjectArray)
+		if !ok || fields.Len() != tcase.Fields {
+			t.Errorf("Case %d: incorrect fields %v", i+1, fdfDict.Get("Fields"))
+		}
+	}
+}
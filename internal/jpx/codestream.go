@@ -0,0 +1,765 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/unidoc/unipdf/v3/common"
+)
+
+// Marker codes of the codestream (Table A.2).
+const (
+	markerSOC = 0xFF4F
+	markerCAP = 0xFF50
+	markerSIZ = 0xFF51
+	markerCOD = 0xFF52
+	markerCOC = 0xFF53
+	markerTLM = 0xFF55
+	markerPLM = 0xFF57
+	markerPLT = 0xFF58
+	markerQCD = 0xFF5C
+	markerQCC = 0xFF5D
+	markerRGN = 0xFF5E
+	markerPOC = 0xFF5F
+	markerPPM = 0xFF60
+	markerPPT = 0xFF61
+	markerCRG = 0xFF63
+	markerCOM = 0xFF64
+	markerSOT = 0xFF90
+	markerSOP = 0xFF91
+	markerEPH = 0xFF92
+	markerSOD = 0xFF93
+	markerEOC = 0xFFD9
+)
+
+// Progression orders (Table A.16).
+const (
+	progressionLRCP = iota
+	progressionRLCP
+	progressionRPCL
+	progressionPCRL
+	progressionCPRL
+)
+
+// Code-block coding style flags (Table A.19).
+const (
+	cbBypass         = 0x01
+	cbReset          = 0x02
+	cbTermAll        = 0x04
+	cbCausal         = 0x08
+	cbPredictable    = 0x10
+	cbSegmentation   = 0x20
+	cbHighThroughput = 0x40
+)
+
+// Quantization styles (Table A.28).
+const (
+	quantNone = iota
+	quantScalarDerived
+	quantScalarExpounded
+)
+
+// Limits of the decoder, protecting against corrupted or malicious codestreams.
+const (
+	maxImageSamples = 1 << 28
+	maxComponents   = 16384
+	maxLevels       = 32
+)
+
+var (
+	errInvalidCodestream = errors.New("jpx: invalid codestream")
+	errUnexpectedEOF     = errors.New("jpx: unexpected end of data")
+)
+
+// component is the description of an image component in the SIZ marker segment (A.5.1).
+type component struct {
+	depth  int
+	signed bool
+	dx, dy int
+}
+
+// imageSize contains the image and tile size parameters of the SIZ marker segment (A.5.1).
+type imageSize struct {
+	x0, y0, x1, y1       int
+	tileX0, tileY0       int
+	tileWidth            int
+	tileHeight           int
+	components           []component
+	numXTiles, numYTiles int
+}
+
+// codingStyle contains the parameters of the COD marker segment which are common to all the
+// components of a tile (A.6.1).
+type codingStyle struct {
+	sop    bool
+	eph    bool
+	order  int
+	layers int
+	mct    int
+}
+
+// componentStyle contains the parameters of the COD and COC marker segments which are specific
+// to a component (A.6.1, A.6.2).
+type componentStyle struct {
+	levels     int
+	cbw, cbh   int
+	cbStyle    int
+	reversible bool
+	ppx, ppy   []int
+}
+
+// quantization contains the parameters of the QCD and QCC marker segments (A.6.4, A.6.5).
+type quantization struct {
+	style     int
+	guard     int
+	exponents []int
+	mantissas []int
+}
+
+// progressionChange is one progression of the POC marker segment (A.6.6).
+type progressionChange struct {
+	resStart, compStart int
+	layerEnd            int
+	resEnd, compEnd     int
+	order               int
+}
+
+// markers holds the coding parameters of the main header or of a tile-part header.
+type markers struct {
+	cod  *codingStyle
+	comp *componentStyle
+	coc  map[int]*componentStyle
+	qcd  *quantization
+	qcc  map[int]*quantization
+	rgn  map[int]int
+	poc  []progressionChange
+}
+
+func newMarkers() *markers {
+	return &markers{
+		coc: make(map[int]*componentStyle),
+		qcc: make(map[int]*quantization),
+		rgn: make(map[int]int),
+	}
+}
+
+// codestream is the parsed codestream, with the data of the tiles collected from the tile-parts.
+type codestream struct {
+	size  imageSize
+	main  *markers
+	tiles []*tileData
+
+	// ppm contains the packed packet headers of the main header (A.7.4), per tile-part in the
+	// order of the tile-parts in the codestream.
+	ppm [][]byte
+}
+
+// tileData contains the tile-part headers and the packet data of a tile.
+type tileData struct {
+	index   int
+	markers *markers
+	data    []byte
+	headers []byte
+	packed  bool
+	parts   int
+}
+
+// byteReader reads the big endian values of the marker segments.
+type byteReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *byteReader) remaining() int {
+	return len(r.data) - r.pos
+}
+
+func (r *byteReader) u8() (int, error) {
+	if r.pos+1 > len(r.data) {
+		return 0, errUnexpectedEOF
+	}
+	v := r.data[r.pos]
+	r.pos++
+	return int(v), nil
+}
+
+func (r *byteReader) u16() (int, error) {
+	if r.pos+2 > len(r.data) {
+		return 0, errUnexpectedEOF
+	}
+	v := binary.BigEndian.Uint16(r.data[r.pos:])
+	r.pos += 2
+	return int(v), nil
+}
+
+func (r *byteReader) u32() (int, error) {
+	if r.pos+4 > len(r.data) {
+		return 0, errUnexpectedEOF
+	}
+	v := binary.BigEndian.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return int(v), nil
+}
+
+func (r *byteReader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, errUnexpectedEOF
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+// segment reads the marker segment at the current position, following the marker code.
+func (r *byteReader) segment() ([]byte, error) {
+	length, err := r.u16()
+	if err != nil {
+		return nil, err
+	}
+	if length < 2 {
+		return nil, errInvalidCodestream
+	}
+	return r.bytes(length - 2)
+}
+
+// parseMainHeader parses the main header of the codestream `data` (A.4.1). Returns the reader
+// positioned at the first SOT marker.
+func parseMainHeader(data []byte) (*codestream, *byteReader, error) {
+	r := &byteReader{data: data}
+	soc, err := r.u16()
+	if err != nil || soc != markerSOC {
+		return nil, nil, errors.New("jpx: missing SOC marker")
+	}
+	marker, err := r.u16()
+	if err != nil || marker != markerSIZ {
+		return nil, nil, errors.New("jpx: missing SIZ marker")
+	}
+	seg, err := r.segment()
+	if err != nil {
+		return nil, nil, err
+	}
+	cs := &codestream{main: newMarkers()}
+	if err := cs.parseSIZ(seg); err != nil {
+		return nil, nil, err
+	}
+
+	var ppm [][]byte
+	for {
+		marker, err := r.u16()
+		if err != nil {
+			return nil, nil, err
+		}
+		if marker == markerSOT || marker == markerEOC {
+			r.pos -= 2
+			break
+		}
+		seg, err := r.segment()
+		if err != nil {
+			return nil, nil, err
+		}
+		switch marker {
+		case markerPPM:
+			if len(seg) < 1 {
+				return nil, nil, errInvalidCodestream
+			}
+			ppm = append(ppm, seg[1:])
+		case markerCAP:
+			return nil, nil, errors.New("jpx: high throughput codestreams are not supported")
+		default:
+			if err := cs.parseMarker(cs.main, marker, seg); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if cs.main.cod == nil || cs.main.qcd == nil {
+		return nil, nil, errors.New("jpx: missing COD or QCD marker")
+	}
+	if len(ppm) > 0 {
+		if err := cs.splitPPM(ppm); err != nil {
+			return nil, nil, err
+		}
+	}
+	return cs, r, nil
+}
+
+// parseSIZ parses the SIZ marker segment (A.5.1).
+func (cs *codestream) parseSIZ(seg []byte) error {
+	r := &byteReader{data: seg}
+	var vals [9]int
+	if _, err := r.u16(); err != nil {
+		return err
+	}
+	for i := 0; i < 8; i++ {
+		v, err := r.u32()
+		if err != nil {
+			return err
+		}
+		vals[i] = v
+	}
+	numComps, err := r.u16()
+	if err != nil {
+		return err
+	}
+	s := &cs.size
+	s.x1, s.y1, s.x0, s.y0 = vals[0], vals[1], vals[2], vals[3]
+	s.tileWidth, s.tileHeight, s.tileX0, s.tileY0 = vals[4], vals[5], vals[6], vals[7]
+	if s.x1 <= s.x0 || s.y1 <= s.y0 || s.tileWidth <= 0 || s.tileHeight <= 0 ||
+		s.tileX0 > s.x0 || s.tileY0 > s.y0 ||
+		s.tileX0+s.tileWidth <= s.x0 || s.tileY0+s.tileHeight <= s.y0 {
+		return fmt.Errorf("jpx: invalid image size %dx%d", s.x1-s.x0, s.y1-s.y0)
+	}
+	if numComps == 0 || numComps > maxComponents {
+		return fmt.Errorf("jpx: invalid number of components: %d", numComps)
+	}
+	if (s.x1-s.x0)*(s.y1-s.y0) > maxImageSamples {
+		return fmt.Errorf("jpx: image too large: %dx%d", s.x1-s.x0, s.y1-s.y0)
+	}
+	for i := 0; i < numComps; i++ {
+		ssiz, err := r.u8()
+		if err != nil {
+			return err
+		}
+		dx, err := r.u8()
+		if err != nil {
+			return err
+		}
+		dy, err := r.u8()
+		if err != nil {
+			return err
+		}
+		c := component{depth: ssiz&0x7F + 1, signed: ssiz&0x80 != 0, dx: dx, dy: dy}
+		if c.depth > 38 || dx == 0 || dy == 0 {
+			return fmt.Errorf("jpx: invalid component %d parameters", i)
+		}
+		s.components = append(s.components, c)
+	}
+	s.numXTiles = ceilDiv(s.x1-s.tileX0, s.tileWidth)
+	s.numYTiles = ceilDiv(s.y1-s.tileY0, s.tileHeight)
+	if s.numXTiles*s.numYTiles > 65535 {
+		return errors.New("jpx: too many tiles")
+	}
+	cs.tiles = make([]*tileData, s.numXTiles*s.numYTiles)
+	return nil
+}
+
+// compIndex reads a component index, which is coded on one byte when there are less than 257
+// components and on two bytes otherwise.
+func (cs *codestream) compIndex(r *byteReader) (int, error) {
+	var c int
+	var err error
+	if len(cs.size.components) < 257 {
+		c, err = r.u8()
+	} else {
+		c, err = r.u16()
+	}
+	if err != nil {
+		return 0, err
+	}
+	if c >= len(cs.size.components) {
+		return 0, fmt.Errorf("jpx: invalid component index %d", c)
+	}
+	return c, nil
+}
+
+// parseMarker parses a marker segment of the main header or of a tile-part header into `m`.
+// Unknown and informational marker segments are skipped.
+func (cs *codestream) parseMarker(m *markers, marker int, seg []byte) error {
+	r := &byteReader{data: seg}
+	switch marker {
+	case markerCOD:
+		scod, err := r.u8()
+		if err != nil {
+			return err
+		}
+		var sg [4]int
+		if sg[0], err = r.u8(); err != nil {
+			return err
+		}
+		if sg[1], err = r.u16(); err != nil {
+			return err
+		}
+		if sg[2], err = r.u8(); err != nil {
+			return err
+		}
+		if sg[0] > progressionCPRL || sg[1] == 0 {
+			return errors.New("jpx: invalid COD marker")
+		}
+		comp, err := parseComponentStyle(r, scod&1 != 0)
+		if err != nil {
+			return err
+		}
+		m.cod = &codingStyle{
+			sop:    scod&2 != 0,
+			eph:    scod&4 != 0,
+			order:  sg[0],
+			layers: sg[1],
+			mct:    sg[2],
+		}
+		m.comp = comp
+	case markerCOC:
+		c, err := cs.compIndex(r)
+		if err != nil {
+			return err
+		}
+		scoc, err := r.u8()
+		if err != nil {
+			return err
+		}
+		comp, err := parseComponentStyle(r, scoc&1 != 0)
+		if err != nil {
+			return err
+		}
+		m.coc[c] = comp
+	case markerQCD:
+		q, err := parseQuantization(r)
+		if err != nil {
+			return err
+		}
+		m.qcd = q
+	case markerQCC:
+		c, err := cs.compIndex(r)
+		if err != nil {
+			return err
+		}
+		q, err := parseQuantization(r)
+		if err != nil {
+			return err
+		}
+		m.qcc[c] = q
+	case markerRGN:
+		c, err := cs.compIndex(r)
+		if err != nil {
+			return err
+		}
+		style, err := r.u8()
+		if err != nil {
+			return err
+		}
+		shift, err := r.u8()
+		if err != nil {
+			return err
+		}
+		if style != 0 {
+			return fmt.Errorf("jpx: unsupported ROI style %d", style)
+		}
+		m.rgn[c] = shift
+	case markerPOC:
+		m.poc = nil
+		for r.remaining() > 0 {
+			var p progressionChange
+			var err error
+			if p.resStart, err = r.u8(); err != nil {
+				return err
+			}
+			if len(cs.size.components) < 257 {
+				p.compStart, err = r.u8()
+			} else {
+				p.compStart, err = r.u16()
+			}
+			if err != nil {
+				return err
+			}
+			if p.layerEnd, err = r.u16(); err != nil {
+				return err
+			}
+			if p.resEnd, err = r.u8(); err != nil {
+				return err
+			}
+			if len(cs.size.components) < 257 {
+				p.compEnd, err = r.u8()
+			} else {
+				p.compEnd, err = r.u16()
+			}
+			if err != nil {
+				return err
+			}
+			if p.compEnd == 0 {
+				p.compEnd = 256
+			}
+			if p.order, err = r.u8(); err != nil {
+				return err
+			}
+			if p.order > progressionCPRL {
+				return errors.New("jpx: invalid POC marker")
+			}
+			m.poc = append(m.poc, p)
+		}
+	case markerTLM, markerPLM, markerPLT, markerCRG, markerCOM:
+		// Informational, not required for decoding.
+	default:
+		common.Log.Trace("jpx: skipping marker %04X", marker)
+	}
+	return nil
+}
+
+// parseComponentStyle parses the SPcod and SPcoc parameters (Table A.15).
+func parseComponentStyle(r *byteReader, customPrecincts bool) (*componentStyle, error) {
+	var vals [5]int
+	for i := range vals {
+		v, err := r.u8()
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+	s := &componentStyle{
+		levels:     vals[0],
+		cbw:        vals[1] + 2,
+		cbh:        vals[2] + 2,
+		cbStyle:    vals[3],
+		reversible: vals[4] == 1,
+	}
+	if s.levels > maxLevels || s.cbw > 10 || s.cbh > 10 || s.cbw+s.cbh > 12 {
+		return nil, errors.New("jpx: invalid coding style parameters")
+	}
+	if s.cbStyle&cbHighThroughput != 0 {
+		return nil, errors.New("jpx: high throughput code-blocks are not supported")
+	}
+	s.ppx = make([]int, s.levels+1)
+	s.ppy = make([]int, s.levels+1)
+	for i := 0; i <= s.levels; i++ {
+		s.ppx[i], s.ppy[i] = 15, 15
+		if customPrecincts {
+			v, err := r.u8()
+			if err != nil {
+				return nil, err
+			}
+			s.ppx[i], s.ppy[i] = v&0xF, v>>4
+			if i > 0 && (s.ppx[i] == 0 || s.ppy[i] == 0) {
+				return nil, errors.New("jpx: invalid precinct size")
+			}
+		}
+	}
+	return s, nil
+}
+
+// parseQuantization parses the Sqcd and SPqcd parameters (Tables A.28-A.30).
+func parseQuantization(r *byteReader) (*quantization, error) {
+	sq, err := r.u8()
+	if err != nil {
+		return nil, err
+	}
+	q := &quantization{style: sq & 0x1F, guard: sq >> 5}
+	switch q.style {
+	case quantNone:
+		for r.remaining() > 0 {
+			v, _ := r.u8()
+			q.exponents = append(q.exponents, v>>3)
+			q.mantissas = append(q.mantissas, 0)
+		}
+	case quantScalarDerived, quantScalarExpounded:
+		for r.remaining() >= 2 {
+			v, _ := r.u16()
+			q.exponents = append(q.exponents, v>>11)
+			q.mantissas = append(q.mantissas, v&0x7FF)
+		}
+	default:
+		return nil, fmt.Errorf("jpx: invalid quantization style %d", q.style)
+	}
+	if len(q.exponents) == 0 {
+		return nil, errors.New("jpx: missing quantization parameters")
+	}
+	return q, nil
+}
+
+// splitPPM splits the packed packet headers of the PPM marker segments into the headers of each
+// tile-part (A.7.4). The Nppm lengths may span several marker segments.
+func (cs *codestream) splitPPM(segs [][]byte) error {
+	var all []byte
+	for _, seg := range segs {
+		all = append(all, seg...)
+	}
+	r := &byteReader{data: all}
+	for r.remaining() > 0 {
+		n, err := r.u32()
+		if err != nil {
+			return err
+		}
+		b, err := r.bytes(n)
+		if err != nil {
+			return err
+		}
+		cs.ppm = append(cs.ppm, b)
+	}
+	return nil
+}
+
+// parseTileParts parses the tile-parts of the codestream, starting at the position of `r`, and
+// collects the coding parameters and the packet data of each tile (A.4.2).
+func (cs *codestream) parseTileParts(r *byteReader) error {
+	partIndex := 0
+	for r.remaining() >= 2 {
+		start := r.pos
+		marker, _ := r.u16()
+		if marker == markerEOC {
+			break
+		}
+		if marker != markerSOT {
+			common.Log.Debug("jpx: expected SOT marker, got %04X", marker)
+			break
+		}
+		seg, err := r.segment()
+		if err != nil {
+			return err
+		}
+		sr := &byteReader{data: seg}
+		index, _ := sr.u16()
+		length, err := sr.u32()
+		if err != nil {
+			return err
+		}
+		partNum, err := sr.u8()
+		if err != nil {
+			return err
+		}
+		if index >= len(cs.tiles) {
+			return fmt.Errorf("jpx: invalid tile index %d", index)
+		}
+		end := len(r.data)
+		if length != 0 {
+			end = start + length
+			if end > len(r.data) {
+				common.Log.Debug("jpx: tile-part %d of tile %d is truncated", partNum, index)
+				end = len(r.data)
+			}
+		}
+
+		t := cs.tiles[index]
+		if t == nil {
+			t = &tileData{index: index, markers: newMarkers()}
+			cs.tiles[index] = t
+		}
+		var ppt [][]byte
+		for {
+			marker, err := r.u16()
+			if err != nil {
+				return err
+			}
+			if marker == markerSOD {
+				break
+			}
+			seg, err := r.segment()
+			if err != nil {
+				return err
+			}
+			if marker == markerPPT {
+				if len(seg) < 1 {
+					return errInvalidCodestream
+				}
+				ppt = append(ppt, seg[1:])
+				continue
+			}
+			if err := cs.parseMarker(t.markers, marker, seg); err != nil {
+				return err
+			}
+		}
+		if r.pos > end {
+			return errInvalidCodestream
+		}
+		if length == 0 {
+			// The last tile-part extends to the EOC marker.
+			if end-r.pos >= 2 && r.data[end-2] == 0xFF && r.data[end-1] == 0xD9 {
+				end -= 2
+			}
+		}
+		t.data = append(t.data, r.data[r.pos:end]...)
+		for _, b := range ppt {
+			t.headers = append(t.headers, b...)
+			t.packed = true
+		}
+		if cs.ppm != nil {
+			if partIndex < len(cs.ppm) {
+				t.headers = append(t.headers, cs.ppm[partIndex]...)
+			}
+			t.packed = true
+		}
+		t.parts++
+		partIndex++
+		r.pos = end
+	}
+	return nil
+}
+
+// codingStyle returns the coding style of tile `t`.
+func (cs *codestream) codingStyle(t *tileData) *codingStyle {
+	if t.markers.cod != nil {
+		return t.markers.cod
+	}
+	return cs.main.cod
+}
+
+// componentStyle returns the coding style of component `c` of tile `t`. The precedence of the
+// marker segments is: tile-part COC, tile-part COD, main COC, main COD.
+func (cs *codestream) componentStyle(t *tileData, c int) *componentStyle {
+	if s, ok := t.markers.coc[c]; ok {
+		return s
+	}
+	if t.markers.comp != nil {
+		return t.markers.comp
+	}
+	if s, ok := cs.main.coc[c]; ok {
+		return s
+	}
+	return cs.main.comp
+}
+
+// quantization returns the quantization parameters of component `c` of tile `t`, with the same
+// precedence as for the coding styles.
+func (cs *codestream) quantization(t *tileData, c int) *quantization {
+	if q, ok := t.markers.qcc[c]; ok {
+		return q
+	}
+	if t.markers.qcd != nil {
+		return t.markers.qcd
+	}
+	if q, ok := cs.main.qcc[c]; ok {
+		return q
+	}
+	return cs.main.qcd
+}
+
+// roiShift returns the region of interest shift of component `c` of tile `t`.
+func (cs *codestream) roiShift(t *tileData, c int) int {
+	if s, ok := t.markers.rgn[c]; ok {
+		return s
+	}
+	return cs.main.rgn[c]
+}
+
+// progressions returns the progressions of tile `t`, from the POC marker segments or from the
+// progression order of the coding style.
+func (cs *codestream) progressions(t *tileData) []progressionChange {
+	if len(t.markers.poc) > 0 {
+		return t.markers.poc
+	}
+	if len(cs.main.poc) > 0 {
+		return cs.main.poc
+	}
+	style := cs.codingStyle(t)
+	return []progressionChange{{
+		layerEnd: style.layers,
+		resEnd:   maxLevels + 1,
+		compEnd:  len(cs.size.components),
+		order:    style.order,
+	}}
+}
+
+// ceilDiv returns the ceiling of a/b, for b > 0.
+func ceilDiv(a, b int) int {
+	return -floorDiv(-a, b)
+}
+
+// floorDiv returns the floor of a/b, for b > 0.
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
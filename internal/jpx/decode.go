@@ -0,0 +1,491 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ColorSpace is the colour space of a decoded image, as specified in the JP2 file format.
+type ColorSpace int
+
+// Colour spaces of the decoded images.
+const (
+	// ColorSpaceUnknown is used for raw codestreams and for the colour spaces which are not
+	// supported. The colour space is then inferred from the number of channels.
+	ColorSpaceUnknown ColorSpace = iota
+	ColorSpaceGray
+	ColorSpaceRGB
+	ColorSpaceCMYK
+
+	// ColorSpaceICC is used for the images with an ICC profile.
+	ColorSpaceICC
+)
+
+// String returns the name of the colour space.
+func (cs ColorSpace) String() string {
+	switch cs {
+	case ColorSpaceGray:
+		return "Gray"
+	case ColorSpaceRGB:
+		return "RGB"
+	case ColorSpaceCMYK:
+		return "CMYK"
+	case ColorSpaceICC:
+		return "ICC"
+	}
+	return "Unknown"
+}
+
+// Options are the decoding options.
+type Options struct {
+	// IgnorePalette disables the application of the palette of a JP2 file, so that the decoded
+	// image contains the palette indices.
+	IgnorePalette bool
+}
+
+// Config contains the image parameters, which can be obtained without decoding the image.
+type Config struct {
+	Width, Height int
+
+	// NumChannels is the number of colour channels and Depth is the maximum bit depth of the
+	// channels.
+	NumChannels int
+	Depth       int
+
+	// HasAlpha is true if the image contains an opacity channel.
+	HasAlpha   bool
+	ColorSpace ColorSpace
+	ICCProfile []byte
+}
+
+// Channel is a channel of a decoded image.
+type Channel struct {
+	Depth  int
+	Signed bool
+
+	// Data contains the Width x Height samples of the channel, in row major order.
+	Data []int32
+}
+
+// Image is a decoded image.
+type Image struct {
+	Width, Height int
+
+	// Channels contains the colour channels in the order of the colour space.
+	Channels []*Channel
+
+	// Alpha is the opacity channel, or nil if the image doesn't contain an opacity channel.
+	// If Premultiplied is true, the colour channels have been premultiplied by the opacity.
+	Alpha         *Channel
+	Premultiplied bool
+
+	ColorSpace ColorSpace
+	ICCProfile []byte
+}
+
+// channelSource describes how a channel of the image is obtained from the codestream components.
+type channelSource struct {
+	comp   int
+	column int // palette column, -1 for the components which are used directly
+}
+
+// layout describes the channels of an image.
+type layout struct {
+	colors        []channelSource
+	alpha         *channelSource
+	premultiplied bool
+	colorSpace    ColorSpace
+	icc           []byte
+	ycc           bool
+}
+
+// DecodeConfig returns the parameters of the JPEG 2000 image `data` without decoding it.
+func DecodeConfig(data []byte, opts *Options) (*Config, error) {
+	f, err := parseFile(data)
+	if err != nil {
+		return nil, err
+	}
+	cs, _, err := parseMainHeader(f.codestream)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.checkHeader(cs); err != nil {
+		return nil, err
+	}
+	l, err := f.layout(cs, opts)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{
+		Width:       cs.size.x1 - cs.size.x0,
+		Height:      cs.size.y1 - cs.size.y0,
+		NumChannels: len(l.colors),
+		HasAlpha:    l.alpha != nil,
+		ColorSpace:  l.colorSpace,
+		ICCProfile:  l.icc,
+	}
+	for _, src := range l.colors {
+		if d := f.depth(cs, src); d > cfg.Depth {
+			cfg.Depth = d
+		}
+	}
+	return cfg, nil
+}
+
+// Decode decodes the JPEG 2000 image `data`, which is either a raw codestream or a JP2/JPX file.
+func Decode(data []byte, opts *Options) (*Image, error) {
+	f, err := parseFile(data)
+	if err != nil {
+		return nil, err
+	}
+	cs, r, err := parseMainHeader(f.codestream)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.checkHeader(cs); err != nil {
+		return nil, err
+	}
+	l, err := f.layout(cs, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, comp := range cs.size.components {
+		if comp.depth > 30 {
+			return nil, fmt.Errorf("jpx: unsupported bit depth %d", comp.depth)
+		}
+	}
+	if err := cs.parseTileParts(r); err != nil {
+		return nil, err
+	}
+	planes, err := cs.decodeComponents()
+	if err != nil {
+		return nil, err
+	}
+
+	img := &Image{
+		Width:         cs.size.x1 - cs.size.x0,
+		Height:        cs.size.y1 - cs.size.y0,
+		Premultiplied: l.premultiplied,
+		ColorSpace:    l.colorSpace,
+		ICCProfile:    l.icc,
+	}
+	for _, src := range l.colors {
+		img.Channels = append(img.Channels, f.channel(cs, planes, src))
+	}
+	if l.alpha != nil {
+		img.Alpha = f.channel(cs, planes, *l.alpha)
+	}
+	if l.ycc && len(img.Channels) == 3 {
+		yccToRGB(img.Channels)
+	}
+	return img, nil
+}
+
+// layout determines the channels of the image and its colour space, from the palette, component
+// mapping, channel definition and colour specification boxes of the file (I.5.3).
+func (f *jp2File) layout(cs *codestream, opts *Options) (*layout, error) {
+	numComps := len(cs.size.components)
+	var sources []channelSource
+	if f.palette != nil && f.mapping != nil && (opts == nil || !opts.IgnorePalette) {
+		for _, m := range f.mapping {
+			src := channelSource{comp: m.comp, column: -1}
+			if !m.direct {
+				if m.column >= len(f.palette.values) {
+					return nil, errors.New("jpx: invalid palette column")
+				}
+				src.column = m.column
+			}
+			sources = append(sources, src)
+		}
+	} else {
+		for c := 0; c < numComps; c++ {
+			sources = append(sources, channelSource{comp: c, column: -1})
+		}
+	}
+	for _, src := range sources {
+		if src.comp >= numComps {
+			return nil, fmt.Errorf("jpx: invalid component %d", src.comp)
+		}
+	}
+
+	l := &layout{}
+	if spec := f.colorSpecification(); spec != nil && (opts == nil || !opts.IgnorePalette || f.palette == nil) {
+		if spec.method == methodEnum {
+			switch spec.enum {
+			case enumGray:
+				l.colorSpace = ColorSpaceGray
+			case enumCMYK:
+				l.colorSpace = ColorSpaceCMYK
+			case enumSYCC, enumESYCC:
+				l.colorSpace = ColorSpaceRGB
+				l.ycc = true
+			default:
+				l.colorSpace = ColorSpaceRGB
+			}
+		} else {
+			l.colorSpace = ColorSpaceICC
+			l.icc = spec.icc
+		}
+	}
+
+	if f.channels == nil {
+		l.colors = sources
+		// A single extra channel, following the colour channels, is the opacity.
+		if n := l.numColors(); n > 0 && len(sources) == n+1 {
+			l.colors = sources[:n]
+			l.alpha = &sources[n]
+		}
+		return l, nil
+	}
+
+	colors := make([]*channelSource, len(sources))
+	for _, def := range f.channels {
+		if def.index >= len(sources) {
+			return nil, fmt.Errorf("jpx: invalid channel %d", def.index)
+		}
+		src := &sources[def.index]
+		switch def.typ {
+		case channelColor:
+			pos := def.assoc - 1
+			if def.assoc == 0 || def.assoc == 0xFFFF {
+				pos = def.index
+			}
+			if pos >= 0 && pos < len(colors) && colors[pos] == nil {
+				colors[pos] = src
+			}
+		case channelOpacity, channelPremultiplied:
+			if l.alpha == nil {
+				l.alpha = src
+				l.premultiplied = def.typ == channelPremultiplied
+			}
+		}
+	}
+	for _, src := range colors {
+		if src != nil {
+			l.colors = append(l.colors, *src)
+		}
+	}
+	if len(l.colors) == 0 {
+		return nil, errors.New("jpx: no colour channels")
+	}
+	return l, nil
+}
+
+// numColors returns the number of colour channels of the colour space, or 0 if unknown.
+func (l *layout) numColors() int {
+	switch l.colorSpace {
+	case ColorSpaceGray:
+		return 1
+	case ColorSpaceRGB:
+		return 3
+	case ColorSpaceCMYK:
+		return 4
+	case ColorSpaceICC:
+		return iccComponents(l.icc)
+	}
+	return 0
+}
+
+// depth returns the bit depth of the channel obtained from `src`.
+func (f *jp2File) depth(cs *codestream, src channelSource) int {
+	if src.column >= 0 {
+		return f.palette.depths[src.column]
+	}
+	return cs.size.components[src.comp].depth
+}
+
+// channel returns the channel obtained from `src`, upsampled to the reference grid.
+func (f *jp2File) channel(cs *codestream, planes []*plane, src channelSource) *Channel {
+	s := &cs.size
+	comp := s.components[src.comp]
+	p := planes[src.comp]
+	w, h := s.x1-s.x0, s.y1-s.y0
+	ch := &Channel{Depth: comp.depth, Signed: comp.signed, Data: make([]int32, w*h)}
+	var column []int32
+	if src.column >= 0 {
+		column = f.palette.values[src.column]
+		ch.Depth = f.palette.depths[src.column]
+		ch.Signed = f.palette.signed[src.column]
+	}
+	for y := 0; y < h; y++ {
+		py := minInt(maxInt((y+s.y0)/comp.dy-p.y0, 0), p.h-1)
+		row := p.data[py*p.w:]
+		out := ch.Data[y*w : (y+1)*w]
+		for x := range out {
+			px := minInt(maxInt((x+s.x0)/comp.dx-p.x0, 0), p.w-1)
+			v := row[px]
+			if column != nil {
+				v = column[minInt(maxInt(int(v), 0), len(column)-1)]
+			}
+			out[x] = v
+		}
+	}
+	return ch
+}
+
+// plane contains the decoded samples of a component.
+type plane struct {
+	x0, y0 int
+	w, h   int
+	data   []int32
+}
+
+// decodeComponents decodes the tiles of the codestream and returns the samples of the
+// components.
+func (cs *codestream) decodeComponents() ([]*plane, error) {
+	s := &cs.size
+	planes := make([]*plane, len(s.components))
+	for c, comp := range s.components {
+		p := &plane{
+			x0: ceilDiv(s.x0, comp.dx),
+			y0: ceilDiv(s.y0, comp.dy),
+		}
+		p.w = ceilDiv(s.x1, comp.dx) - p.x0
+		p.h = ceilDiv(s.y1, comp.dy) - p.y0
+		if p.w <= 0 || p.h <= 0 {
+			return nil, fmt.Errorf("jpx: empty component %d", c)
+		}
+		p.data = make([]int32, p.w*p.h)
+		planes[c] = p
+	}
+	for _, td := range cs.tiles {
+		if td == nil {
+			continue
+		}
+		if err := cs.decodeTile(td, planes); err != nil {
+			return nil, err
+		}
+	}
+	return planes, nil
+}
+
+// decodeTile decodes tile `td` into the component planes.
+func (cs *codestream) decodeTile(td *tileData, planes []*plane) error {
+	tl, err := cs.newTile(td)
+	if err != nil {
+		return err
+	}
+	d := &tileDecoder{cs: cs, td: td, tile: tl}
+	d.decodePackets()
+
+	t1 := &t1Decoder{}
+	for _, tc := range tl.comps {
+		t1.cbStyle = tc.style.cbStyle
+		for _, res := range tc.res {
+			for _, band := range res.bands {
+				for _, prec := range band.precincts {
+					for _, cb := range prec.blocks {
+						t1.decodeBlock(cb, band, tc.roiShift, tc.style.reversible)
+					}
+				}
+			}
+		}
+		tc.reconstruct()
+	}
+	if tl.style.mct != 0 {
+		if err := tl.inverseComponentTransform(); err != nil {
+			return err
+		}
+	}
+
+	// DC level shifting (G.1.2) of the unsigned components.
+	for c, tc := range tl.comps {
+		comp := cs.size.components[c]
+		p := planes[c]
+		w := tc.x1 - tc.x0
+		if w <= 0 || tc.y1 <= tc.y0 || tc.data == nil {
+			continue
+		}
+		var shift, min, max float64
+		if comp.signed {
+			min = -math.Ldexp(1, comp.depth-1)
+			max = -min - 1
+		} else {
+			shift = math.Ldexp(1, comp.depth-1)
+			max = math.Ldexp(1, comp.depth) - 1
+		}
+		for y := tc.y0; y < tc.y1; y++ {
+			src := tc.data[(y-tc.y0)*w : (y-tc.y0+1)*w]
+			dst := p.data[(y-p.y0)*p.w+tc.x0-p.x0:]
+			for x, v := range src {
+				f := math.Floor(float64(v) + shift + 0.5)
+				if f < min {
+					f = min
+				} else if f > max {
+					f = max
+				}
+				dst[x] = int32(f)
+			}
+		}
+	}
+	return nil
+}
+
+// inverseComponentTransform applies the inverse multiple component transformation to the first
+// three components of the tile (G.2, G.3).
+func (tl *tile) inverseComponentTransform() error {
+	if len(tl.comps) < 3 {
+		return errors.New("jpx: component transformation requires three components")
+	}
+	c0, c1, c2 := tl.comps[0], tl.comps[1], tl.comps[2]
+	n := len(c0.data)
+	if len(c1.data) != n || len(c2.data) != n {
+		return errors.New("jpx: component transformation with different component sizes")
+	}
+	if c0.style.reversible {
+		// Inverse reversible component transformation (G.2.2).
+		for i := 0; i < n; i++ {
+			y0, y1, y2 := c0.data[i], c1.data[i], c2.data[i]
+			g := y0 - float32(math.Floor(float64(y2+y1)/4))
+			c0.data[i], c1.data[i], c2.data[i] = y2+g, g, y1+g
+		}
+		return nil
+	}
+	// Inverse irreversible component transformation (G.3.2).
+	for i := 0; i < n; i++ {
+		y0, y1, y2 := c0.data[i], c1.data[i], c2.data[i]
+		c0.data[i] = y0 + 1.402*y2
+		c1.data[i] = y0 - 0.34413*y1 - 0.71414*y2
+		c2.data[i] = y0 + 1.772*y1
+	}
+	return nil
+}
+
+// yccToRGB converts the channels of an image in the sYCC colour space to sRGB.
+func yccToRGB(channels []*Channel) {
+	yc, cb, cr := channels[0], channels[1], channels[2]
+	max := float64(int(1)<<uint(yc.Depth) - 1)
+	offset := func(ch *Channel) float64 {
+		if ch.Signed {
+			return 0
+		}
+		return math.Ldexp(1, ch.Depth-1)
+	}
+	ob, or := offset(cb), offset(cr)
+	clamp := func(v float64) int32 {
+		v = math.Floor(v + 0.5)
+		if v < 0 {
+			return 0
+		}
+		if v > max {
+			return int32(max)
+		}
+		return int32(v)
+	}
+	for i := range yc.Data {
+		y := float64(yc.Data[i])
+		u := float64(cb.Data[i]) - ob
+		v := float64(cr.Data[i]) - or
+		yc.Data[i] = clamp(y + 1.402*v)
+		cb.Data[i] = clamp(y - 0.344136*u - 0.714136*v)
+		cr.Data[i] = clamp(y + 1.772*u)
+	}
+	cb.Depth, cb.Signed = yc.Depth, false
+	cr.Depth, cr.Signed = yc.Depth, false
+}
@@ -0,0 +1,565 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+import (
+	"image"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/unidoc/unipdf/v3/internal/jbig2/decoder/arithmetic"
+	"github.com/unidoc/unipdf/v3/internal/jbig2/reader"
+)
+
+// testPlanes returns `n` component planes of an image of size w x h with a smooth content and
+// some noise, with `depth` bits per sample.
+func testPlanes(n, w, h, depth int, seed int64) [][]int32 {
+	rnd := rand.New(rand.NewSource(seed))
+	max := float64(int(1)<<uint(depth) - 1)
+	planes := make([][]int32, n)
+	for c := range planes {
+		planes[c] = make([]int32, w*h)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				v := 0.5 + 0.3*math.Sin(float64(x+3*c)/5) + 0.15*math.Cos(float64(y*(c+1))/7)
+				v = v*max + rnd.Float64()*max/16
+				planes[c][y*w+x] = int32(math.Max(0, math.Min(max, v)))
+			}
+		}
+	}
+	return planes
+}
+
+// TestMQCoder checks the MQ decoder and the test encoder against the arithmetic decoder of the
+// JBIG2 package, which uses the same coder.
+func TestMQCoder(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	const numContexts = 4
+	var contexts [numContexts]mqContext
+	var enc mqEncoder
+	enc.init()
+	var ctxs, bits []int
+	for i := 0; i < 20000; i++ {
+		ctx := rnd.Intn(numContexts)
+		// Skewed probabilities so that the states move across the table.
+		bit := 0
+		if rnd.Intn(ctx+2) == 0 {
+			bit = 1
+		}
+		enc.encode(&contexts[ctx], bit)
+		ctxs = append(ctxs, ctx)
+		bits = append(bits, bit)
+	}
+	data := enc.flush()
+
+	var dec mqDecoder
+	dec.init(data)
+	var decContexts [numContexts]mqContext
+	// The reference decoder requires the end of the data to be marked.
+	ref, err := arithmetic.New(reader.New(append(data, 0xFF, 0xFF, 0xFF, 0xFF)))
+	if err != nil {
+		t.Fatalf("arithmetic.New: %v", err)
+	}
+	stats := arithmetic.NewStats(numContexts, 0)
+	for i, ctx := range ctxs {
+		if got := dec.decode(&decContexts[ctx]); got != bits[i] {
+			t.Fatalf("symbol %d: decoded %d, want %d", i, got, bits[i])
+		}
+		stats.SetIndex(int32(ctx))
+		got, err := ref.DecodeBit(stats)
+		if err != nil {
+			t.Fatalf("DecodeBit: %v", err)
+		}
+		if got != bits[i] {
+			t.Fatalf("symbol %d: reference decoded %d, want %d", i, got, bits[i])
+		}
+	}
+}
+
+// TestBandGeometry checks the bounds of the subbands of a tile-component with odd coordinates
+// against values computed with Equation B-15.
+func TestBandGeometry(t *testing.T) {
+	tc := &tileComponent{x0: 3, y0: 5, x1: 20, y1: 14, style: &componentStyle{
+		levels: 2, cbw: 6, cbh: 6, reversible: true,
+		ppx: []int{15, 15, 15}, ppy: []int{15, 15, 15},
+	}}
+	quant := &quantization{style: quantNone, guard: 2, exponents: make([]int, 7), mantissas: make([]int, 7)}
+	expected := [][][4]int{
+		// LL2.
+		{{1, 2, 5, 4}},
+		// HL2, LH2, HH2.
+		{{1, 2, 5, 4}, {1, 1, 5, 3}, {1, 1, 5, 3}},
+		// HL1, LH1, HH1.
+		{{1, 3, 10, 7}, {2, 2, 10, 7}, {1, 2, 10, 7}},
+	}
+	for r := 0; r <= 2; r++ {
+		res, err := tc.newResolution(r, 8, quant)
+		if err != nil {
+			t.Fatalf("newResolution: %v", err)
+		}
+		for i, b := range res.bands {
+			got := [4]int{b.x0, b.y0, b.x1, b.y1}
+			if got != expected[r][i] {
+				t.Errorf("resolution %d band %d: bounds %v, want %v", r, i, got, expected[r][i])
+			}
+		}
+	}
+}
+
+func TestDecodeReversible(t *testing.T) {
+	base := encodeParams{width: 37, height: 29, depth: 8, levels: 3, reversible: true}
+	testcases := []struct {
+		name  string
+		comps int
+		edit  func(p *encodeParams)
+	}{
+		{"default", 1, nil},
+		{"no decomposition", 1, func(p *encodeParams) { p.levels = 0 }},
+		{"offset", 1, func(p *encodeParams) { p.x0, p.y0 = 5, 3 }},
+		{"tiles", 1, func(p *encodeParams) { p.x0, p.y0, p.tileWidth, p.tileHeight = 3, 2, 16, 12 }},
+		{"single row tiles", 1, func(p *encodeParams) { p.x0, p.tileWidth, p.tileHeight = 1, 17, 1 }},
+		{"small code-blocks", 1, func(p *encodeParams) { p.cbw, p.cbh = 2, 3 }},
+		{"precincts", 3, func(p *encodeParams) { p.ppx, p.ppy = 3, 4 }},
+		{"layers", 1, func(p *encodeParams) { p.layers = 4 }},
+		{"16 bits", 1, func(p *encodeParams) { p.depth = 16 }},
+		{"2 bits", 1, func(p *encodeParams) { p.depth = 2 }},
+		{"rct", 3, func(p *encodeParams) { p.mct = true }},
+		{"subsampling", 3, func(p *encodeParams) { p.dx, p.dy = []int{1, 2, 3}, []int{1, 2, 1} }},
+		{"sop eph", 3, func(p *encodeParams) { p.sop, p.eph, p.layers = true, true, 2 }},
+		{"ppt", 2, func(p *encodeParams) { p.ppt, p.layers, p.tileWidth, p.tileHeight = true, 2, 20, 20 }},
+		{"ppt sop eph", 1, func(p *encodeParams) { p.ppt, p.sop, p.eph, p.layers = true, true, true, 2 }},
+		{"tile-parts", 2, func(p *encodeParams) { p.tileParts, p.layers = 3, 2 }},
+		{"bypass", 1, func(p *encodeParams) { p.cbStyle = cbBypass }},
+		{"bypass layers", 1, func(p *encodeParams) { p.cbStyle, p.layers, p.depth = cbBypass, 5, 12 }},
+		{"reset", 1, func(p *encodeParams) { p.cbStyle = cbReset }},
+		{"termall", 1, func(p *encodeParams) { p.cbStyle, p.layers = cbTermAll, 3 }},
+		{"causal", 1, func(p *encodeParams) { p.cbStyle = cbCausal }},
+		{"segmentation", 1, func(p *encodeParams) { p.cbStyle = cbSegmentation }},
+		{"all styles", 1, func(p *encodeParams) {
+			p.cbStyle = cbBypass | cbReset | cbTermAll | cbCausal | cbPredictable | cbSegmentation
+			p.layers = 2
+		}},
+		{"poc", 3, func(p *encodeParams) {
+			p.layers = 3
+			p.poc = []progressionChange{
+				{resStart: 0, compStart: 0, layerEnd: 2, resEnd: 2, compEnd: 3, order: progressionRLCP},
+				{resStart: 1, compStart: 1, layerEnd: 3, resEnd: 4, compEnd: 3, order: progressionCPRL},
+				{resStart: 0, compStart: 0, layerEnd: 3, resEnd: 4, compEnd: 3, order: progressionLRCP},
+			}
+		}},
+	}
+	orders := []int{progressionLRCP, progressionRLCP, progressionRPCL, progressionPCRL, progressionCPRL}
+	for _, tcase := range testcases {
+		for _, order := range orders {
+			p := base
+			p.order = order
+			if tcase.edit != nil {
+				tcase.edit(&p)
+			}
+			if p.ppx == 0 && order >= progressionRPCL {
+				// Position-driven progressions with several precincts.
+				p.ppx, p.ppy = 4, 3
+			}
+			planes := make([][]int32, tcase.comps)
+			for c := range planes {
+				dx, dy := 1, 1
+				if p.dx != nil {
+					dx, dy = p.dx[c], p.dy[c]
+				}
+				w := ceilDiv(p.x0+p.width, dx) - ceilDiv(p.x0, dx)
+				h := ceilDiv(p.y0+p.height, dy) - ceilDiv(p.y0, dy)
+				planes[c] = testPlanes(1, w, h, p.depth, int64(c))[0]
+			}
+			data, err := encodeImage(p, planes)
+			if err != nil {
+				t.Fatalf("%s order %d: encode: %v", tcase.name, order, err)
+			}
+			img, err := Decode(data, nil)
+			if err != nil {
+				t.Fatalf("%s order %d: decode: %v", tcase.name, order, err)
+			}
+			if img.Width != p.width || img.Height != p.height || len(img.Channels) != tcase.comps {
+				t.Fatalf("%s order %d: image %dx%d with %d channels", tcase.name, order, img.Width,
+					img.Height, len(img.Channels))
+			}
+			for c, ch := range img.Channels {
+				if ch.Depth != p.depth {
+					t.Fatalf("%s: depth %d, want %d", tcase.name, ch.Depth, p.depth)
+				}
+				dx, dy := 1, 1
+				if p.dx != nil {
+					dx, dy = p.dx[c], p.dy[c]
+				}
+				// The subsampled components are upsampled to the image size.
+				pw := ceilDiv(p.x0+p.width, dx) - ceilDiv(p.x0, dx)
+				for y := 0; y < p.height; y++ {
+					for x := 0; x < p.width; x++ {
+						cx := (p.x0+x)/dx - ceilDiv(p.x0, dx)
+						cy := (p.y0+y)/dy - ceilDiv(p.y0, dy)
+						want := planes[c][maxInt(cy, 0)*pw+maxInt(cx, 0)]
+						if got := ch.Data[y*p.width+x]; got != want {
+							t.Fatalf("%s order %d: component %d sample (%d, %d) = %d, want %d",
+								tcase.name, order, c, x, y, got, want)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeIrreversible(t *testing.T) {
+	testcases := []struct {
+		name    string
+		p       encodeParams
+		comps   int
+		maxDiff float64
+	}{
+		{"expounded", encodeParams{levels: 4, stepExp: 1}, 1, 2},
+		{"derived", encodeParams{levels: 3, stepExp: 1, derived: true}, 1, 8},
+		{"ict", encodeParams{levels: 2, stepExp: 1, mct: true, layers: 3}, 3, 3},
+		{"coarse", encodeParams{levels: 5, stepExp: -3}, 1, 24},
+	}
+	for _, tcase := range testcases {
+		p := tcase.p
+		p.width, p.height, p.depth = 41, 33, 8
+		planes := testPlanes(tcase.comps, p.width, p.height, p.depth, 7)
+		data, err := encodeImage(p, planes)
+		if err != nil {
+			t.Fatalf("%s: encode: %v", tcase.name, err)
+		}
+		img, err := Decode(data, nil)
+		if err != nil {
+			t.Fatalf("%s: decode: %v", tcase.name, err)
+		}
+		for c, ch := range img.Channels {
+			for i, v := range ch.Data {
+				if d := math.Abs(float64(v - planes[c][i])); d > tcase.maxDiff {
+					t.Fatalf("%s: component %d sample %d = %d, want %d", tcase.name, c, i, v, planes[c][i])
+				}
+			}
+		}
+	}
+}
+
+// TestDecodeTruncated checks that truncated codestreams are decoded at a lower quality.
+func TestDecodeTruncated(t *testing.T) {
+	p := encodeParams{width: 32, height: 32, depth: 8, levels: 2, reversible: true, layers: 4}
+	planes := testPlanes(1, p.width, p.height, p.depth, 3)
+	data, err := encodeImage(p, planes)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	for _, n := range []int{len(data) - 2, len(data) * 3 / 4, len(data) / 2} {
+		img, err := Decode(data[:n], nil)
+		if err != nil {
+			t.Fatalf("decode %d bytes: %v", n, err)
+		}
+		var sum float64
+		for i, v := range img.Channels[0].Data {
+			d := float64(v - planes[0][i])
+			sum += d * d
+		}
+		if rmse := math.Sqrt(sum / float64(len(planes[0]))); rmse > 40 {
+			t.Fatalf("decode %d bytes: RMSE %.1f", n, rmse)
+		}
+	}
+	if _, err := Decode(data[:20], nil); err == nil {
+		t.Fatalf("decoding a truncated main header should fail")
+	}
+}
+
+// jp2Box returns a box of type `typ` with content `content`.
+func jp2Box(typ string, content ...[]byte) []byte {
+	var b []byte
+	for _, c := range content {
+		b = append(b, c...)
+	}
+	return append(append(appendU32(nil, len(b)+8), typ...), b...)
+}
+
+// jp2ImageHeader returns the image header box, and the bits per component box if the depths vary,
+// of the codestream `codestream`, from its SIZ marker segment.
+func jp2ImageHeader(codestream []byte) []byte {
+	r := &byteReader{data: codestream[8:]}
+	var vals [4]int
+	for i := range vals {
+		vals[i], _ = r.u32()
+	}
+	numComps := int(codestream[40])<<8 | int(codestream[41])
+	depths := make([]byte, numComps)
+	for i := range depths {
+		depths[i] = codestream[42+3*i]
+	}
+	ihdr := appendU32(appendU32(nil, vals[1]-vals[3]), vals[0]-vals[2])
+	ihdr = appendU16(ihdr, numComps)
+	ihdr = append(ihdr, depths[0], 7, 0, 0)
+	for _, d := range depths {
+		if d != depths[0] {
+			ihdr[10] = 0xFF
+			return append(jp2Box("ihdr", ihdr), jp2Box("bpcc", depths)...)
+		}
+	}
+	return jp2Box("ihdr", ihdr)
+}
+
+// jp2Wrap returns a JP2 file with the header boxes `header` and the codestream `codestream`.
+func jp2Wrap(codestream []byte, header ...[]byte) []byte {
+	var data []byte
+	data = append(data, jp2Box("jP  ", []byte{0x0D, 0x0A, 0x87, 0x0A})...)
+	data = append(data, jp2Box("ftyp", []byte("jp2 \x00\x00\x00\x00jp2 "))...)
+	header = append([][]byte{jp2ImageHeader(codestream)}, header...)
+	data = append(data, jp2Box("jp2h", header...)...)
+	return append(data, jp2Box("jp2c", codestream)...)
+}
+
+func enumColr(enum int) []byte {
+	return jp2Box("colr", appendU32([]byte{methodEnum, 0, 0}, enum))
+}
+
+func TestDecodeJP2(t *testing.T) {
+	const w, h = 19, 13
+	encode := func(planes [][]int32, depth int) []byte {
+		p := encodeParams{width: w, height: h, depth: depth, levels: 2, reversible: true}
+		data, err := encodeImage(p, planes)
+		if err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+		return data
+	}
+	planes := testPlanes(4, w, h, 8, 5)
+	iccProfile := make([]byte, 128)
+	copy(iccProfile[16:], "RGB ")
+
+	cdef := func(defs ...int) []byte {
+		b := appendU16(nil, len(defs)/3)
+		for _, v := range defs {
+			b = appendU16(b, v)
+		}
+		return jp2Box("cdef", b)
+	}
+
+	testcases := []struct {
+		name       string
+		data       []byte
+		colorSpace ColorSpace
+		colors     []int
+		alpha      int
+	}{
+		{"codestream", encode(planes[:3], 8), ColorSpaceUnknown, []int{0, 1, 2}, -1},
+		{"gray", jp2Wrap(encode(planes[:1], 8), enumColr(enumGray)), ColorSpaceGray, []int{0}, -1},
+		{"gray alpha", jp2Wrap(encode(planes[:2], 8), enumColr(enumGray)), ColorSpaceGray, []int{0}, 1},
+		{"rgba", jp2Wrap(encode(planes, 8), enumColr(enumSRGB), cdef(0, 0, 1, 1, 0, 2, 2, 0, 3, 3, 1, 0)),
+			ColorSpaceRGB, []int{0, 1, 2}, 3},
+		{"reordered", jp2Wrap(encode(planes, 8), enumColr(enumSRGB), cdef(0, 1, 0, 1, 0, 3, 2, 0, 2, 3, 0, 1)),
+			ColorSpaceRGB, []int{3, 2, 1}, 0},
+		{"icc", jp2Wrap(encode(planes[:3], 8), jp2Box("colr", []byte{methodICC, 0, 0}, iccProfile)),
+			ColorSpaceICC, []int{0, 1, 2}, -1},
+		{"precedence", jp2Wrap(encode(planes[:4], 8), enumColr(enumSRGB),
+			jp2Box("colr", appendU32([]byte{methodEnum, 2, 0}, enumCMYK))), ColorSpaceCMYK, []int{0, 1, 2, 3}, -1},
+	}
+	for _, tcase := range testcases {
+		cfg, err := DecodeConfig(tcase.data, nil)
+		if err != nil {
+			t.Fatalf("%s: DecodeConfig: %v", tcase.name, err)
+		}
+		if cfg.Width != w || cfg.Height != h || cfg.NumChannels != len(tcase.colors) || cfg.Depth != 8 ||
+			cfg.HasAlpha != (tcase.alpha >= 0) || cfg.ColorSpace != tcase.colorSpace {
+			t.Fatalf("%s: unexpected config %+v", tcase.name, cfg)
+		}
+		img, err := Decode(tcase.data, nil)
+		if err != nil {
+			t.Fatalf("%s: Decode: %v", tcase.name, err)
+		}
+		if img.ColorSpace != tcase.colorSpace || len(img.Channels) != len(tcase.colors) {
+			t.Fatalf("%s: %s image with %d channels", tcase.name, img.ColorSpace, len(img.Channels))
+		}
+		if tcase.colorSpace == ColorSpaceICC && string(img.ICCProfile) != string(iccProfile) {
+			t.Fatalf("%s: missing ICC profile", tcase.name)
+		}
+		for i, c := range tcase.colors {
+			if string(int32Bytes(img.Channels[i].Data)) != string(int32Bytes(planes[c])) {
+				t.Fatalf("%s: channel %d differs from component %d", tcase.name, i, c)
+			}
+		}
+		if (img.Alpha != nil) != (tcase.alpha >= 0) {
+			t.Fatalf("%s: alpha %v", tcase.name, img.Alpha != nil)
+		}
+		if img.Alpha != nil && string(int32Bytes(img.Alpha.Data)) != string(int32Bytes(planes[tcase.alpha])) {
+			t.Fatalf("%s: alpha differs from component %d", tcase.name, tcase.alpha)
+		}
+	}
+}
+
+func TestDecodePalette(t *testing.T) {
+	const w, h = 11, 7
+	indices := make([]int32, w*h)
+	for i := range indices {
+		indices[i] = int32(i % 4)
+	}
+	p := encodeParams{width: w, height: h, depth: 2, levels: 1, reversible: true}
+	codestream, err := encodeImage(p, [][]int32{indices})
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	colors := [4][3]byte{{255, 0, 0}, {0, 255, 0}, {0, 0, 255}, {10, 20, 30}}
+	pclr := []byte{0, 4, 3, 7, 7, 7}
+	for _, c := range colors {
+		pclr = append(pclr, c[:]...)
+	}
+	cmap := []byte{0, 0, 1, 0, 0, 0, 1, 1, 0, 0, 1, 2}
+	data := jp2Wrap(codestream, enumColr(enumSRGB), jp2Box("pclr", pclr), jp2Box("cmap", cmap))
+
+	img, err := Decode(data, nil)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if img.ColorSpace != ColorSpaceRGB || len(img.Channels) != 3 {
+		t.Fatalf("%s image with %d channels", img.ColorSpace, len(img.Channels))
+	}
+	for i, idx := range indices {
+		for c := 0; c < 3; c++ {
+			if got := img.Channels[c].Data[i]; got != int32(colors[idx][c]) {
+				t.Fatalf("sample %d channel %d: %d, want %d", i, c, got, colors[idx][c])
+			}
+		}
+	}
+
+	img, err = Decode(data, &Options{IgnorePalette: true})
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(img.Channels) != 1 || img.Channels[0].Depth != 2 || img.ColorSpace != ColorSpaceUnknown {
+		t.Fatalf("%s image with %d channels", img.ColorSpace, len(img.Channels))
+	}
+	if string(int32Bytes(img.Channels[0].Data)) != string(int32Bytes(indices)) {
+		t.Fatalf("unexpected palette indices")
+	}
+}
+
+// TestDecodeKakadu decodes images encoded by Kakadu, from the test files of
+// github.com/gabriel-vasile/mimetype (MIT License), and compares them with reference renderings
+// made of the averages of the samples of `block` x `block` pixel blocks. The averages may differ by
+// `tolerance` for the irreversible transformations.
+func TestDecodeKakadu(t *testing.T) {
+	testcases := []struct {
+		path          string
+		width, height int
+		block         int
+		tolerance     int
+	}{
+		// Kakadu 3.2: JP2 file with an ICC profile, irreversible 9-7 wavelet, 12 quality layers.
+		{"testdata/kakadu-irreversible.jp2", 400, 300, 4, 1},
+		// Kakadu 6.4.1: JPX file with a reader requirements box, reversible 5-3 wavelet.
+		{"testdata/kakadu-reversible.jpf", 2717, 3701, 16, 0},
+	}
+	for _, tcase := range testcases {
+		data, err := ioutil.ReadFile(tcase.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := DecodeConfig(data, nil)
+		if err != nil {
+			t.Fatalf("%s: DecodeConfig: %v", tcase.path, err)
+		}
+		if cfg.Width != tcase.width || cfg.Height != tcase.height || cfg.NumChannels != 3 || cfg.Depth != 8 ||
+			cfg.HasAlpha {
+			t.Fatalf("%s: unexpected config %+v", tcase.path, cfg)
+		}
+		img, err := Decode(data, nil)
+		if err != nil {
+			t.Fatalf("%s: Decode: %v", tcase.path, err)
+		}
+
+		f, err := os.Open(tcase.path + ".png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref, err := png.Decode(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := tcase.block
+		if ref.Bounds() != image.Rect(0, 0, ceilDiv(img.Width, b), ceilDiv(img.Height, b)) {
+			t.Fatalf("%s: reference size %v", tcase.path, ref.Bounds())
+		}
+		for by := 0; by < ref.Bounds().Dy(); by++ {
+			for bx := 0; bx < ref.Bounds().Dx(); bx++ {
+				r, g, bl, _ := ref.At(bx, by).RGBA()
+				for c, want := range []uint32{r >> 8, g >> 8, bl >> 8} {
+					var sum, n int
+					for y := by * b; y < minInt((by+1)*b, img.Height); y++ {
+						for x := bx * b; x < minInt((bx+1)*b, img.Width); x++ {
+							sum += int(img.Channels[c].Data[y*img.Width+x])
+							n++
+						}
+					}
+					if got := (sum + n/2) / n; got < int(want)-tcase.tolerance || got > int(want)+tcase.tolerance {
+						t.Fatalf("%s: block (%d, %d) channel %d: average %d, want %d",
+							tcase.path, bx, by, c, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestImageHeader(t *testing.T) {
+	p := encodeParams{width: 9, height: 5, depth: 8, levels: 1, reversible: true}
+	codestream, err := encodeImage(p, testPlanes(3, p.width, p.height, p.depth, 2))
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	colr := enumColr(enumSRGB)
+	ihdr := func(h, w, nc int, bpc byte) []byte {
+		return jp2Box("ihdr", appendU16(appendU32(appendU32(nil, h), w), nc), []byte{bpc, 7, 0, 0})
+	}
+	box := func(header ...[]byte) []byte {
+		data := jp2Box("jP  ", []byte{0x0D, 0x0A, 0x87, 0x0A})
+		data = append(data, jp2Box("ftyp", []byte("jp2 \x00\x00\x00\x00jp2 "))...)
+		data = append(data, jp2Box("jp2h", header...)...)
+		return append(data, jp2Box("jp2c", codestream)...)
+	}
+
+	testcases := []struct {
+		name  string
+		data  []byte
+		valid bool
+	}{
+		{"valid", box(ihdr(5, 9, 3, 7), colr), true},
+		{"bits per component box", box(ihdr(5, 9, 3, 0xFF), jp2Box("bpcc", []byte{7, 7, 7}), colr), true},
+		{"missing", box(colr), false},
+		{"size", box(ihdr(9, 5, 3, 7), colr), false},
+		{"components", box(ihdr(5, 9, 4, 7), colr), false},
+		{"depth", box(ihdr(5, 9, 3, 11), colr), false},
+		{"signed", box(ihdr(5, 9, 3, 0x87), colr), false},
+		{"missing bits per component box", box(ihdr(5, 9, 3, 0xFF), colr), false},
+		{"bits per component box depth", box(ihdr(5, 9, 3, 0xFF), jp2Box("bpcc", []byte{7, 7, 3}), colr), false},
+	}
+	for _, tcase := range testcases {
+		_, errConfig := DecodeConfig(tcase.data, nil)
+		_, err := Decode(tcase.data, nil)
+		if tcase.valid && (errConfig != nil || err != nil) {
+			t.Fatalf("%s: %v, %v", tcase.name, errConfig, err)
+		}
+		if !tcase.valid && (errConfig == nil || err == nil) {
+			t.Fatalf("%s: invalid image header accepted", tcase.name)
+		}
+	}
+}
+
+func int32Bytes(values []int32) []byte {
+	var b []byte
+	for _, v := range values {
+		b = appendU32(b, int(v))
+	}
+	return b
+}
@@ -0,0 +1,16 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+// Package jpx implements a decoder for JPEG 2000 images, as used by the JPXDecode filter.
+// It decodes raw codestreams and codestreams wrapped in the JP2/JPX file format.
+//
+// All the comments reference to the 'ITU-T T.800 | ISO/IEC 15444-1 JPEG 2000 image coding system:
+// Core coding system' document, which can be obtained at: 'https://www.itu.int/rec/T-REC-T.800'.
+//
+// The decoder supports all the progression orders, progression order changes, tile-parts,
+// packed packet headers, precincts, all the code-block coding styles, the reversible 5-3 and
+// irreversible 9-7 wavelet transforms, the multiple component transformations, region of interest
+// (maxshift) decoding, palettes and channel definitions of the JP2 file format.
+package jpx
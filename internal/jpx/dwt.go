@@ -0,0 +1,153 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+import "math"
+
+// Lifting parameters of the irreversible 9-7 filter (Table F.4).
+const (
+	liftAlpha = -1.586134342059924
+	liftBeta  = -0.052980118572961
+	liftGamma = 0.882911075530934
+	liftDelta = 0.443506852043971
+	liftK     = 1.230174104914001
+)
+
+// dwtExtension is the number of samples of the periodic symmetric extension on each side of the
+// signal, which is sufficient for the four lifting steps of the 9-7 filter.
+const dwtExtension = 4
+
+// reconstruct reconstructs the samples of the tile-component from the coefficients of its
+// subbands, with the inverse discrete wavelet transformation (F.3).
+func (tc *tileComponent) reconstruct() {
+	data := tc.res[0].bands[0].coeffs
+	reversible := tc.style.reversible
+	var buf, col []float32
+
+	for r := 1; r < len(tc.res); r++ {
+		res, prev := tc.res[r], tc.res[r-1]
+		rw, rh := res.x1-res.x0, res.y1-res.y0
+		out := make([]float32, rw*rh)
+
+		// 2D_INTERLEAVE (F.3.3): the even samples are taken from the lowpass subbands and the odd
+		// samples from the highpass subbands, in each direction.
+		type source struct {
+			data      []float32
+			x0, y0, w int
+		}
+		sources := [4]source{{data, prev.x0, prev.y0, prev.x1 - prev.x0}}
+		for i, b := range res.bands {
+			sources[i+1] = source{b.coeffs, b.x0, b.y0, b.x1 - b.x0}
+		}
+		for y := res.y0; y < res.y1; y++ {
+			for x := res.x0; x < res.x1; x++ {
+				s := &sources[(y&1)<<1|x&1]
+				out[(y-res.y0)*rw+x-res.x0] = s.data[(y/2-s.y0)*s.w+x/2-s.x0]
+			}
+		}
+
+		// HOR_SR (F.3.4) and VER_SR (F.3.5).
+		if n := maxInt(rw, rh) + 2*dwtExtension; len(buf) < n {
+			buf = make([]float32, n)
+		}
+		for y := 0; y < rh; y++ {
+			synthesize(out[y*rw:(y+1)*rw], res.x0, reversible, buf)
+		}
+		if len(col) < rh {
+			col = make([]float32, rh)
+		}
+		for x := 0; x < rw; x++ {
+			for y := 0; y < rh; y++ {
+				col[y] = out[y*rw+x]
+			}
+			synthesize(col[:rh], res.y0, reversible, buf)
+			for y := 0; y < rh; y++ {
+				out[y*rw+x] = col[y]
+			}
+		}
+		data = out
+	}
+	tc.data = data
+}
+
+// synthesize performs the one-dimensional subband reconstruction 1D_SR (F.3.6) of the interleaved
+// signal `x`, which starts at index `i0`, in place. `buf` is a work buffer of at least
+// len(x)+2*dwtExtension samples.
+func synthesize(x []float32, i0 int, reversible bool, buf []float32) {
+	n := len(x)
+	if n == 0 {
+		return
+	}
+	if n == 1 {
+		if i0&1 == 1 {
+			x[0] /= 2
+		}
+		return
+	}
+
+	// 1D_EXTR: periodic symmetric extension (F.3.7).
+	e := dwtExtension
+	buf = buf[:n+2*e]
+	copy(buf[e:], x)
+	period := 2 * (n - 1)
+	for k := 1; k <= e; k++ {
+		buf[e-k] = x[mirror(-k, period, n)]
+		buf[e+n-1+k] = x[mirror(n-1+k, period, n)]
+	}
+
+	// The parity of the samples is that of their index in the signal.
+	start := i0 - e
+	first := func(even bool) int {
+		// The first index in [1, len(buf)-1) with the given parity.
+		j := 1
+		if ((start+j)&1 == 0) != even {
+			j++
+		}
+		return j
+	}
+	last := len(buf) - 1
+	if reversible {
+		// 1D_SR_53 (F.3.8.1).
+		for j := first(true); j < last; j += 2 {
+			buf[j] -= float32(math.Floor(float64(buf[j-1]+buf[j+1]+2) / 4))
+		}
+		for j := first(false); j < last; j += 2 {
+			buf[j] += float32(math.Floor(float64(buf[j-1]+buf[j+1]) / 2))
+		}
+	} else {
+		// 1D_SR_97 (F.3.8.2).
+		for j := range buf {
+			if (start+j)&1 == 0 {
+				buf[j] *= liftK
+			} else {
+				buf[j] *= 1 / liftK
+			}
+		}
+		lift := func(even bool, c float32) {
+			for j := first(even); j < last; j += 2 {
+				buf[j] -= c * (buf[j-1] + buf[j+1])
+			}
+		}
+		lift(true, liftDelta)
+		lift(false, liftGamma)
+		lift(true, liftBeta)
+		lift(false, liftAlpha)
+	}
+	copy(x, buf[e:e+n])
+}
+
+// mirror returns the index of sample `i` of a signal of length `n` extended periodically and
+// symmetrically, with period 2(n-1).
+func mirror(i, period, n int) int {
+	m := i % period
+	if m < 0 {
+		m += period
+	}
+	if m >= n {
+		m = period - m
+	}
+	return m
+}
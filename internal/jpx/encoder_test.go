@@ -0,0 +1,1343 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// This file contains a simple JPEG 2000 encoder, used to produce the codestreams for testing the
+// decoder.
+
+// encodeParams are the coding parameters of the test encoder.
+type encodeParams struct {
+	width, height int
+	x0, y0        int
+	depth         int
+	dx, dy        []int
+
+	tileWidth, tileHeight int
+	levels                int
+	reversible            bool
+	mct                   bool
+	cbw, cbh              int
+	ppx, ppy              int
+	layers                int
+	order                 int
+	poc                   []progressionChange
+	cbStyle               int
+	sop, eph              bool
+	ppt                   bool
+	tileParts             int
+
+	// stepExp is the difference between the exponent and the nominal dynamic range of the
+	// subbands, for the irreversible transformation.
+	stepExp int
+	derived bool
+}
+
+// encodeImage encodes the component planes `planes` with parameters `p` into a codestream.
+// The planes contain the samples of the components on their sampling grid.
+func encodeImage(p encodeParams, planes [][]int32) ([]byte, error) {
+	numComps := len(planes)
+	if p.tileWidth == 0 {
+		p.tileWidth, p.tileHeight = p.x0+p.width, p.y0+p.height
+	}
+	if p.layers == 0 {
+		p.layers = 1
+	}
+	if p.cbw == 0 {
+		p.cbw, p.cbh = 6, 6
+	}
+	if p.tileParts == 0 {
+		p.tileParts = 1
+	}
+	dx := func(c int) int {
+		if p.dx == nil {
+			return 1
+		}
+		return p.dx[c]
+	}
+	dy := func(c int) int {
+		if p.dy == nil {
+			return 1
+		}
+		return p.dy[c]
+	}
+
+	// The main header, without the quantization which depends on the coefficients.
+	var hdr []byte
+	hdr = appendU16(hdr, markerSOC)
+	siz := appendU16(nil, 0)
+	for _, v := range []int{p.x0 + p.width, p.y0 + p.height, p.x0, p.y0, p.tileWidth, p.tileHeight, 0, 0} {
+		siz = appendU32(siz, v)
+	}
+	siz = appendU16(siz, numComps)
+	for c := 0; c < numComps; c++ {
+		siz = append(siz, byte(p.depth-1), byte(dx(c)), byte(dy(c)))
+	}
+	hdr = appendSegment(hdr, markerSIZ, siz)
+
+	scod := 0
+	if p.ppx != 0 {
+		scod |= 1
+	}
+	if p.sop {
+		scod |= 2
+	}
+	if p.eph {
+		scod |= 4
+	}
+	mct := 0
+	if p.mct {
+		mct = 1
+	}
+	transform := 0
+	if p.reversible {
+		transform = 1
+	}
+	cod := []byte{byte(scod), byte(p.order)}
+	cod = appendU16(cod, p.layers)
+	cod = append(cod, byte(mct), byte(p.levels), byte(p.cbw-2), byte(p.cbh-2), byte(p.cbStyle), byte(transform))
+	if p.ppx != 0 {
+		for r := 0; r <= p.levels; r++ {
+			cod = append(cod, byte(p.ppy<<4|p.ppx))
+		}
+	}
+	hdr = appendSegment(hdr, markerCOD, cod)
+	// A placeholder QCD, replaced by the QCC marker segments of the components.
+	hdr = appendSegment(hdr, markerQCD, []byte{2<<5 | quantScalarDerived, 8 << 3, 0})
+	if len(p.poc) > 0 {
+		var poc []byte
+		for _, pc := range p.poc {
+			poc = append(poc, byte(pc.resStart), byte(pc.compStart))
+			poc = appendU16(poc, pc.layerEnd)
+			poc = append(poc, byte(pc.resEnd), byte(pc.compEnd), byte(pc.order))
+		}
+		hdr = appendSegment(hdr, markerPOC, poc)
+	}
+
+	// The decoder structures provide the geometry of the tiles.
+	cs, _, err := parseMainHeader(append(append([]byte{}, hdr...), 0xFF, 0xD9))
+	if err != nil {
+		return nil, err
+	}
+
+	var tilesData [][]byte
+	var quants [][]byte
+	for t := range cs.tiles {
+		td := &tileData{index: t, markers: newMarkers()}
+		tl, err := cs.newTile(td)
+		if err != nil {
+			return nil, err
+		}
+		// The tile-component samples with the DC level shift (G.1).
+		for c, tc := range tl.comps {
+			w := tc.x1 - tc.x0
+			pw := ceilDiv(p.x0+p.width, dx(c)) - ceilDiv(p.x0, dx(c))
+			px0, py0 := ceilDiv(p.x0, dx(c)), ceilDiv(p.y0, dy(c))
+			tc.data = make([]float32, w*(tc.y1-tc.y0))
+			for y := tc.y0; y < tc.y1; y++ {
+				for x := tc.x0; x < tc.x1; x++ {
+					v := planes[c][(y-py0)*pw+x-px0]
+					tc.data[(y-tc.y0)*w+x-tc.x0] = float32(v) - float32(int(1)<<uint(p.depth-1))
+				}
+			}
+		}
+		if p.mct {
+			forwardComponentTransform(tl, p.reversible)
+		}
+
+		enc := &tileEncoder{p: p, tile: tl}
+		var quant [][]byte
+		for c, tc := range tl.comps {
+			q, err := enc.encodeComponent(tc, p.depth)
+			if err != nil {
+				return nil, fmt.Errorf("component %d: %v", c, err)
+			}
+			quant = append(quant, q)
+		}
+		if t == 0 {
+			quants = quant
+		} else {
+			for c := range quant {
+				if string(quant[c]) != string(quants[c]) {
+					return nil, fmt.Errorf("different quantization of tile %d", t)
+				}
+			}
+		}
+		tileBytes, err := enc.writeTile(cs, t)
+		if err != nil {
+			return nil, err
+		}
+		tilesData = append(tilesData, tileBytes)
+	}
+
+	var out []byte
+	out = append(out, hdr...)
+	for c, q := range quants {
+		out = appendSegment(out, markerQCC, append([]byte{byte(c)}, q...))
+	}
+	for _, tileBytes := range tilesData {
+		out = append(out, tileBytes...)
+	}
+	return appendU16(out, markerEOC), nil
+}
+
+// forwardComponentTransform applies the forward multiple component transformation (G.2, G.3).
+func forwardComponentTransform(tl *tile, reversible bool) {
+	c0, c1, c2 := tl.comps[0].data, tl.comps[1].data, tl.comps[2].data
+	for i := range c0 {
+		r, g, b := float64(c0[i]), float64(c1[i]), float64(c2[i])
+		if reversible {
+			c0[i] = float32(math.Floor((r + 2*g + b) / 4))
+			c1[i] = float32(b - g)
+			c2[i] = float32(r - g)
+		} else {
+			c0[i] = float32(0.299*r + 0.587*g + 0.114*b)
+			c1[i] = float32(-0.16875*r - 0.33126*g + 0.5*b)
+			c2[i] = float32(0.5*r - 0.41869*g - 0.08131*b)
+		}
+	}
+}
+
+// blockCoding is the result of the coding of a code-block.
+type blockCoding struct {
+	numBitplanes int
+	passes       int
+
+	// The codeword segments and their number of passes.
+	segments    [][]byte
+	segPasses   []int
+	firstLayer  int
+	layerPasses []int
+	lblock      int
+}
+
+// tileEncoder encodes the tile-components and writes the packets of a tile.
+type tileEncoder struct {
+	p      encodeParams
+	tile   *tile
+	blocks map[*codeblock]*blockCoding
+	trees  map[*precinct][2]*tagTreeEncoder
+}
+
+// encodeComponent transforms, quantizes and codes the tile-component. Returns the SPqcc
+// quantization parameters of the component.
+func (enc *tileEncoder) encodeComponent(tc *tileComponent, depth int) ([]byte, error) {
+	p := enc.p
+	nl := tc.style.levels
+
+	// FDWT (F.4): the coefficients of each subband, in band coordinates.
+	w := tc.x1 - tc.x0
+	data := make([]float64, len(tc.data))
+	for i, v := range tc.data {
+		data[i] = float64(v)
+	}
+	x0, y0, cw := tc.x0, tc.y0, w
+	for r := nl; r >= 1; r-- {
+		res, next := tc.res[r], tc.res[r-1]
+		rw, rh := res.x1-res.x0, res.y1-res.y0
+		if res.x0 != x0 || res.y0 != y0 || rw != cw {
+			return nil, fmt.Errorf("unexpected resolution bounds")
+		}
+		col := make([]float64, rh)
+		for x := 0; x < rw; x++ {
+			for y := 0; y < rh; y++ {
+				col[y] = data[y*rw+x]
+			}
+			analyze(col, res.y0, p.reversible)
+			for y := 0; y < rh; y++ {
+				data[y*rw+x] = col[y]
+			}
+		}
+		for y := 0; y < rh; y++ {
+			analyze(data[y*rw:(y+1)*rw], res.x0, p.reversible)
+		}
+		// 2D_DEINTERLEAVE (F.4.5).
+		nw := next.x1 - next.x0
+		ll := make([]float64, nw*(next.y1-next.y0))
+		for y := res.y0; y < res.y1; y++ {
+			for x := res.x0; x < res.x1; x++ {
+				v := data[(y-res.y0)*rw+x-res.x0]
+				if x&1 == 0 && y&1 == 0 {
+					ll[(y/2-next.y0)*nw+x/2-next.x0] = v
+					continue
+				}
+				b := res.bands[(y&1)<<1|x&1-1]
+				b.coeffs[(y/2-b.y0)*(b.x1-b.x0)+x/2-b.x0] = float32(v)
+			}
+		}
+		data, x0, y0, cw = ll, next.x0, next.y0, nw
+	}
+	for i, v := range data {
+		tc.res[0].bands[0].coeffs[i] = float32(v)
+	}
+
+	// Quantization (E.1) and the number of guard bits required for the magnitudes.
+	type bandQuant struct {
+		band     *subband
+		exp      int
+		mant     int
+		maxValue int32
+		q        []int32
+	}
+	var quants []*bandQuant
+	for r, res := range tc.res {
+		for _, band := range res.bands {
+			gain := map[int]int{bandLL: 0, bandHL: 1, bandLH: 1, bandHH: 2}[band.orient]
+			bq := &bandQuant{band: band, exp: depth + gain}
+			delta := 1.0
+			if !p.reversible {
+				nb := nl - r + 1
+				if r == 0 {
+					nb = nl
+				}
+				bq.exp = depth + gain + p.stepExp
+				bq.mant = 100
+				if p.derived {
+					bq.exp = depth + p.stepExp - nl + nb
+					bq.mant = 100
+				}
+				if bq.exp < 0 {
+					bq.exp = 0
+				}
+				delta = math.Pow(2, float64(depth+gain-bq.exp)) * (1 + float64(bq.mant)/2048)
+			}
+			bq.q = make([]int32, len(band.coeffs))
+			for i, v := range band.coeffs {
+				m := math.Floor(math.Abs(float64(v)) / delta)
+				if p.reversible {
+					m = math.Abs(math.Round(float64(v)))
+				}
+				q := int32(m)
+				if q > bq.maxValue {
+					bq.maxValue = q
+				}
+				if v < 0 {
+					q = -q
+				}
+				bq.q[i] = q
+			}
+			quants = append(quants, bq)
+		}
+	}
+	guard := 1
+	for _, bq := range quants {
+		// Mb = G + exp - 1 must cover the magnitude bits.
+		if g := bitLength(bq.maxValue) - bq.exp + 1; g > guard {
+			guard = g
+		}
+	}
+	if guard > 7 {
+		return nil, fmt.Errorf("too many guard bits: %d", guard)
+	}
+	var spqcc []byte
+	switch {
+	case p.reversible:
+		spqcc = append(spqcc, byte(guard<<5|quantNone))
+		for _, bq := range quants {
+			spqcc = append(spqcc, byte(bq.exp<<3))
+		}
+	case p.derived:
+		spqcc = append(spqcc, byte(guard<<5|quantScalarDerived))
+		spqcc = appendU16(spqcc, quants[0].exp<<11|quants[0].mant)
+	default:
+		spqcc = append(spqcc, byte(guard<<5|quantScalarExpounded))
+		for _, bq := range quants {
+			spqcc = appendU16(spqcc, bq.exp<<11|bq.mant)
+		}
+	}
+
+	// Tier-1 coding of the code-blocks (Annex D) and the assignment of the passes to the layers.
+	if enc.blocks == nil {
+		enc.blocks = make(map[*codeblock]*blockCoding)
+	}
+	for _, bq := range quants {
+		band := bq.band
+		mb := guard + bq.exp - 1
+		bw := band.x1 - band.x0
+		for _, prec := range band.precincts {
+			for _, cb := range prec.blocks {
+				w, h := cb.x1-cb.x0, cb.y1-cb.y0
+				values := make([]int32, w*h)
+				for y := 0; y < h; y++ {
+					copy(values[y*w:(y+1)*w], bq.q[(cb.y0-band.y0+y)*bw+cb.x0-band.x0:])
+				}
+				coding := encodeBlock(values, w, h, band.orient, p.cbStyle)
+				if coding.numBitplanes > mb {
+					return nil, fmt.Errorf("code-block with %d > %d bit-planes", coding.numBitplanes, mb)
+				}
+				cb.zeroBitplanes = mb - coding.numBitplanes
+				coding.assignLayers(p.layers)
+				enc.blocks[cb] = coding
+			}
+		}
+	}
+	return spqcc, nil
+}
+
+// assignLayers distributes the coding passes of the code-block evenly to `layers` layers.
+func (bc *blockCoding) assignLayers(layers int) {
+	bc.firstLayer = layers
+	prev := 0
+	for l := 0; l < layers; l++ {
+		n := (bc.passes*(l+1)+layers-1)/layers - prev
+		if l == layers-1 {
+			n = bc.passes - prev
+		}
+		if n > 0 && bc.firstLayer == layers {
+			bc.firstLayer = l
+		}
+		bc.layerPasses = append(bc.layerPasses, n)
+		prev += n
+	}
+}
+
+// analyze performs the one-dimensional subband decomposition 1D_SD (F.4.8) of the signal `x`
+// which starts at index `i0`, in place.
+func analyze(x []float64, i0 int, reversible bool) {
+	n := len(x)
+	if n == 0 {
+		return
+	}
+	if n == 1 {
+		if i0&1 == 1 {
+			x[0] *= 2
+		}
+		return
+	}
+	const e = 4
+	buf := make([]float64, n+2*e)
+	for j := range buf {
+		// Periodic symmetric extension.
+		i := j - e
+		period := 2 * (n - 1)
+		for i < 0 || i >= n {
+			if i < 0 {
+				i = -i
+			}
+			if i >= n {
+				i = period - i
+			}
+		}
+		buf[j] = x[i]
+	}
+	odd := func(j int) bool {
+		return (i0-e+j)&1 == 1
+	}
+	step := func(wantOdd bool, f func(j int)) {
+		for j := 1; j < len(buf)-1; j++ {
+			if odd(j) == wantOdd {
+				f(j)
+			}
+		}
+	}
+	if reversible {
+		step(true, func(j int) { buf[j] -= math.Floor((buf[j-1] + buf[j+1]) / 2) })
+		step(false, func(j int) { buf[j] += math.Floor((buf[j-1] + buf[j+1] + 2) / 4) })
+	} else {
+		step(true, func(j int) { buf[j] += liftAlpha * (buf[j-1] + buf[j+1]) })
+		step(false, func(j int) { buf[j] += liftBeta * (buf[j-1] + buf[j+1]) })
+		step(true, func(j int) { buf[j] += liftGamma * (buf[j-1] + buf[j+1]) })
+		step(false, func(j int) { buf[j] += liftDelta * (buf[j-1] + buf[j+1]) })
+		for j := range buf {
+			if odd(j) {
+				buf[j] *= liftK
+			} else {
+				buf[j] /= liftK
+			}
+		}
+	}
+	copy(x, buf[e:e+n])
+}
+
+// writeTile writes the tile-parts of tile `t`, with the packets in progression order.
+func (enc *tileEncoder) writeTile(cs *codestream, t int) ([]byte, error) {
+	p := enc.p
+	var packets [][2][]byte
+	next := make(map[[3]int]int)
+	progressions := p.poc
+	if len(progressions) == 0 {
+		progressions = []progressionChange{{layerEnd: p.layers, resEnd: 33, compEnd: len(enc.tile.comps), order: p.order}}
+	}
+	for _, pc := range progressions {
+		err := enc.iteratePackets(cs, pc, func(l, r, c, prec int) error {
+			key := [3]int{c, r, prec}
+			if l < next[key] {
+				return nil
+			}
+			next[key] = l + 1
+			header, body := enc.encodePacket(l, r, c, prec, len(packets))
+			packets = append(packets, [2][]byte{header, body})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var out []byte
+	perPart := (len(packets) + p.tileParts - 1) / p.tileParts
+	for part := 0; part < p.tileParts; part++ {
+		first, last := part*perPart, minInt((part+1)*perPart, len(packets))
+		if first > last {
+			first = last
+		}
+		var header, body []byte
+		for _, pk := range packets[first:last] {
+			if p.ppt {
+				header = append(header, pk[0]...)
+			} else {
+				body = append(body, pk[0]...)
+			}
+			body = append(body, pk[1]...)
+		}
+		var tph []byte
+		if p.ppt {
+			tph = appendSegment(tph, markerPPT, append([]byte{0}, header...))
+		}
+		sot := appendU16(nil, t)
+		sot = appendU32(sot, 12+len(tph)+2+len(body))
+		sot = append(sot, byte(part), byte(p.tileParts))
+		out = appendSegment(out, markerSOT, sot)
+		out = append(out, tph...)
+		out = appendU16(out, markerSOD)
+		out = append(out, body...)
+	}
+	return out, nil
+}
+
+// iteratePackets calls `fn` for the packets of the progression `pc`, following the loops of the
+// progression orders (B.12.1).
+func (enc *tileEncoder) iteratePackets(cs *codestream, pc progressionChange,
+	fn func(l, r, c, p int) error) error {
+	tl := enc.tile
+	layers := minInt(pc.layerEnd, enc.p.layers)
+	maxRes := 0
+	for _, tc := range tl.comps {
+		if len(tc.res) > maxRes {
+			maxRes = len(tc.res)
+		}
+	}
+	maxRes = minInt(maxRes, pc.resEnd)
+	compEnd := minInt(pc.compEnd, len(tl.comps))
+
+	precincts := func(c, r int) int {
+		if r >= len(tl.comps[c].res) {
+			return 0
+		}
+		res := tl.comps[c].res[r]
+		return res.pw * res.ph
+	}
+	// position returns the precinct at the reference grid position (x, y), or -1 (B.12.1.3).
+	position := func(c, r, x, y int) int {
+		tc := tl.comps[c]
+		if r >= len(tc.res) {
+			return -1
+		}
+		res := tc.res[r]
+		if res.pw*res.ph == 0 {
+			return -1
+		}
+		comp := cs.size.components[c]
+		nl := len(tc.res) - 1
+		sx := comp.dx << uint(res.ppx+nl-r)
+		sy := comp.dy << uint(res.ppy+nl-r)
+		okY := y%sy == 0 || y == tl.y0 && (res.y0<<uint(nl-r))%(1<<uint(res.ppy+nl-r)) != 0
+		okX := x%sx == 0 || x == tl.x0 && (res.x0<<uint(nl-r))%(1<<uint(res.ppx+nl-r)) != 0
+		if !okX || !okY {
+			return -1
+		}
+		px := ceilDiv(x, comp.dx<<uint(nl-r))>>uint(res.ppx) - res.x0>>uint(res.ppx)
+		py := ceilDiv(y, comp.dy<<uint(nl-r))>>uint(res.ppy) - res.y0>>uint(res.ppy)
+		return py*res.pw + px
+	}
+
+	switch pc.order {
+	case progressionLRCP:
+		for l := 0; l < layers; l++ {
+			for r := pc.resStart; r < maxRes; r++ {
+				for c := pc.compStart; c < compEnd; c++ {
+					for k := 0; k < precincts(c, r); k++ {
+						if err := fn(l, r, c, k); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+	case progressionRLCP:
+		for r := pc.resStart; r < maxRes; r++ {
+			for l := 0; l < layers; l++ {
+				for c := pc.compStart; c < compEnd; c++ {
+					for k := 0; k < precincts(c, r); k++ {
+						if err := fn(l, r, c, k); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+	case progressionRPCL:
+		for r := pc.resStart; r < maxRes; r++ {
+			for y := tl.y0; y < tl.y1; y++ {
+				for x := tl.x0; x < tl.x1; x++ {
+					for c := pc.compStart; c < compEnd; c++ {
+						if k := position(c, r, x, y); k >= 0 {
+							for l := 0; l < layers; l++ {
+								if err := fn(l, r, c, k); err != nil {
+									return err
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	case progressionPCRL:
+		for y := tl.y0; y < tl.y1; y++ {
+			for x := tl.x0; x < tl.x1; x++ {
+				for c := pc.compStart; c < compEnd; c++ {
+					for r := pc.resStart; r < maxRes; r++ {
+						if k := position(c, r, x, y); k >= 0 {
+							for l := 0; l < layers; l++ {
+								if err := fn(l, r, c, k); err != nil {
+									return err
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	case progressionCPRL:
+		for c := pc.compStart; c < compEnd; c++ {
+			for y := tl.y0; y < tl.y1; y++ {
+				for x := tl.x0; x < tl.x1; x++ {
+					for r := pc.resStart; r < maxRes; r++ {
+						if k := position(c, r, x, y); k >= 0 {
+							for l := 0; l < layers; l++ {
+								if err := fn(l, r, c, k); err != nil {
+									return err
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// encodePacket encodes packet (l, r, c, prec) and returns its header and its body (B.9, B.10).
+func (enc *tileEncoder) encodePacket(l, r, c, prec, index int) ([]byte, []byte) {
+	p := enc.p
+	res := enc.tile.comps[c].res[r]
+	var body []byte
+	bw := &bitWriter{}
+	empty := true
+	for _, band := range res.bands {
+		for _, cb := range band.precincts[prec].blocks {
+			if enc.blocks[cb].layerPasses[l] > 0 {
+				empty = false
+			}
+		}
+	}
+	if empty {
+		bw.write(0, 1)
+	} else {
+		bw.write(1, 1)
+		for _, band := range res.bands {
+			pr := band.precincts[prec]
+			if len(pr.blocks) == 0 {
+				continue
+			}
+			incl, zbp := enc.tagTrees(pr)
+			for k, cb := range pr.blocks {
+				bc := enc.blocks[cb]
+				x, y := k%pr.nw, k/pr.nw
+				included := bc.layerPasses[l] > 0
+				if bc.firstLayer >= l {
+					incl.encode(bw, x, y, l+1)
+				} else if included {
+					bw.write(1, 1)
+				} else {
+					bw.write(0, 1)
+				}
+				if !included {
+					continue
+				}
+				if bc.firstLayer == l {
+					zbp.encode(bw, x, y, tagTreeUnknown)
+					bc.lblock = 3
+				}
+				writeNumPasses(bw, bc.layerPasses[l])
+
+				// The contributions of the layer to the codeword segments.
+				first := 0
+				for _, n := range bc.layerPasses[:l] {
+					first += n
+				}
+				var lengths, counts []int
+				start := 0
+				for s, segPasses := range bc.segPasses {
+					end := start + segPasses
+					lo, hi := maxInt(first, start), minInt(first+bc.layerPasses[l], end)
+					if lo < hi {
+						data := bc.segments[s]
+						b0 := len(data) * (lo - start) / segPasses
+						b1 := len(data) * (hi - start) / segPasses
+						body = append(body, data[b0:b1]...)
+						lengths = append(lengths, b1-b0)
+						counts = append(counts, hi-lo)
+					}
+					start = end
+				}
+				increment := 0
+				for i, length := range lengths {
+					for bitLength(int32(length)) > bc.lblock+increment+floorLog2(counts[i]) {
+						increment++
+					}
+				}
+				for i := 0; i < increment; i++ {
+					bw.write(1, 1)
+				}
+				bw.write(0, 1)
+				bc.lblock += increment
+				for i, length := range lengths {
+					bw.write(length, bc.lblock+floorLog2(counts[i]))
+				}
+			}
+		}
+	}
+	header := bw.flush()
+	if p.eph {
+		header = appendU16(header, markerEPH)
+	}
+	if p.sop {
+		sop := appendU16(nil, markerSOP)
+		sop = appendU16(sop, 4)
+		sop = appendU16(sop, index&0xFFFF)
+		if p.ppt {
+			body = append(sop, body...)
+		} else {
+			header = append(sop, header...)
+		}
+	}
+	return header, body
+}
+
+// tagTrees returns the inclusion and zero bit-plane tag trees of precinct `pr`, which are
+// created on first use.
+func (enc *tileEncoder) tagTrees(pr *precinct) (*tagTreeEncoder, *tagTreeEncoder) {
+	if enc.trees == nil {
+		enc.trees = make(map[*precinct][2]*tagTreeEncoder)
+	}
+	trees, ok := enc.trees[pr]
+	if !ok {
+		var incl, zbp []int
+		for _, cb := range pr.blocks {
+			incl = append(incl, enc.blocks[cb].firstLayer)
+			zbp = append(zbp, cb.zeroBitplanes)
+		}
+		trees = [2]*tagTreeEncoder{newTagTreeEncoder(pr.nw, pr.nh, incl), newTagTreeEncoder(pr.nw, pr.nh, zbp)}
+		enc.trees[pr] = trees
+	}
+	return trees[0], trees[1]
+}
+
+// writeNumPasses writes the number of coding passes (Table B.4).
+func writeNumPasses(bw *bitWriter, n int) {
+	switch {
+	case n == 1:
+		bw.write(0, 1)
+	case n == 2:
+		bw.write(2, 2)
+	case n <= 5:
+		bw.write(0xC|(n-3), 4)
+	case n <= 36:
+		bw.write(0x1E0|(n-6), 9)
+	default:
+		bw.write(0xFF80|(n-37), 16)
+	}
+}
+
+// bitWriter writes the bits of the packet headers with bit stuffing (B.10.1).
+type bitWriter struct {
+	out []byte
+	cur int
+	n   uint
+	max uint
+}
+
+func (bw *bitWriter) write(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if bw.max == 0 {
+			bw.max = 8
+		}
+		bw.cur = bw.cur<<1 | (v>>uint(i))&1
+		bw.n++
+		if bw.n == bw.max {
+			bw.emit()
+		}
+	}
+}
+
+func (bw *bitWriter) emit() {
+	bw.out = append(bw.out, byte(bw.cur))
+	bw.max = 8
+	if bw.cur == 0xFF {
+		bw.max = 7
+	}
+	bw.cur, bw.n = 0, 0
+}
+
+func (bw *bitWriter) flush() []byte {
+	if bw.n > 0 {
+		bw.cur <<= bw.max - bw.n
+		bw.emit()
+	}
+	if len(bw.out) > 0 && bw.out[len(bw.out)-1] == 0xFF {
+		bw.out = append(bw.out, 0)
+	}
+	return bw.out
+}
+
+// tagTreeEncoder encodes the values of a tag tree (B.10.2).
+type tagTreeEncoder struct {
+	levels []tagTreeEncoderLevel
+}
+
+type tagTreeEncoderLevel struct {
+	w, h  int
+	value []int
+	low   []int
+	known []bool
+}
+
+func newTagTreeEncoder(w, h int, values []int) *tagTreeEncoder {
+	tt := &tagTreeEncoder{}
+	level := tagTreeEncoderLevel{w: w, h: h, value: values}
+	for {
+		level.low = make([]int, len(level.value))
+		level.known = make([]bool, len(level.value))
+		tt.levels = append(tt.levels, level)
+		if level.w <= 1 && level.h <= 1 {
+			break
+		}
+		pw, ph := (level.w+1)/2, (level.h+1)/2
+		parent := tagTreeEncoderLevel{w: pw, h: ph, value: make([]int, pw*ph)}
+		for i := range parent.value {
+			parent.value[i] = math.MaxInt32
+		}
+		for y := 0; y < level.h; y++ {
+			for x := 0; x < level.w; x++ {
+				j := (y/2)*pw + x/2
+				if v := level.value[y*level.w+x]; v < parent.value[j] {
+					parent.value[j] = v
+				}
+			}
+		}
+		level = parent
+	}
+	return tt
+}
+
+func (tt *tagTreeEncoder) encode(bw *bitWriter, x, y, threshold int) {
+	low := 0
+	for i := len(tt.levels) - 1; i >= 0; i-- {
+		lv := &tt.levels[i]
+		j := (y>>uint(i))*lv.w + x>>uint(i)
+		if low > lv.low[j] {
+			lv.low[j] = low
+		} else {
+			low = lv.low[j]
+		}
+		for low < threshold {
+			if low >= lv.value[j] {
+				if !lv.known[j] {
+					bw.write(1, 1)
+					lv.known[j] = true
+				}
+				break
+			}
+			bw.write(0, 1)
+			low++
+		}
+		lv.low[j] = low
+	}
+}
+
+// encodeBlock codes the quantized coefficients `values` of a code-block (Annex D).
+func encodeBlock(values []int32, w, h, orient, cbStyle int) *blockCoding {
+	e := &t1Encoder{w: w, h: h, stride: w + 2, orient: orient, cbStyle: cbStyle}
+	e.flags = make([]uint8, e.stride*(h+2))
+	e.mags = make([]int32, e.stride*(h+2))
+	var max int32
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := values[y*w+x]
+			i := (y+1)*e.stride + x + 1
+			if v < 0 {
+				e.flags[i] |= flagNegative
+				v = -v
+			}
+			e.mags[i] = v
+			if v > max {
+				max = v
+			}
+		}
+	}
+	bc := &blockCoding{numBitplanes: bitLength(max)}
+	if bc.numBitplanes == 0 {
+		return bc
+	}
+	bc.passes = 3*bc.numBitplanes - 2
+	e.resetContexts()
+
+	// The codeword segments (D.4.1, Table D.8).
+	var segs [][2]int
+	switch {
+	case cbStyle&cbTermAll != 0:
+		for i := 0; i < bc.passes; i++ {
+			segs = append(segs, [2]int{i, i + 1})
+		}
+	case cbStyle&cbBypass != 0:
+		segs = append(segs, [2]int{0, minInt(10, bc.passes)})
+		for i := 10; i < bc.passes; {
+			n := 2
+			if (i-10)%3 == 2 {
+				n = 1
+			}
+			segs = append(segs, [2]int{i, minInt(i+n, bc.passes)})
+			i += n
+		}
+	default:
+		segs = append(segs, [2]int{0, bc.passes})
+	}
+
+	for _, seg := range segs {
+		raw := cbStyle&cbBypass != 0 && seg[0] >= 10 && seg[0]%3 != 0
+		if raw {
+			e.raw = rawEncoder{}
+		} else {
+			e.mq.init()
+		}
+		for i := seg[0]; i < seg[1]; i++ {
+			e.useRaw = raw
+			bitplane := bc.numBitplanes - 1 - (i+2)/3
+			switch i % 3 {
+			case 0:
+				e.cleanup(bitplane)
+			case 1:
+				e.significance(bitplane)
+			case 2:
+				e.refinement(bitplane)
+			}
+			if cbStyle&cbReset != 0 {
+				e.resetContexts()
+			}
+		}
+		if raw {
+			bc.segments = append(bc.segments, e.raw.flush())
+		} else {
+			bc.segments = append(bc.segments, e.mq.flush())
+		}
+		bc.segPasses = append(bc.segPasses, seg[1]-seg[0])
+	}
+	return bc
+}
+
+// t1Encoder is the coefficient bit modeling of the encoder.
+type t1Encoder struct {
+	w, h, stride int
+	orient       int
+	cbStyle      int
+	flags        []uint8
+	mags         []int32
+	contexts     [numContexts]mqContext
+	mq           mqEncoder
+	raw          rawEncoder
+	useRaw       bool
+}
+
+func (e *t1Encoder) resetContexts() {
+	for i := range e.contexts {
+		e.contexts[i] = mqContext{}
+	}
+	e.contexts[0].index = 4
+	e.contexts[ctxRunLength].index = 3
+	e.contexts[ctxUniform].index = 46
+}
+
+// zcTable contains the zero coding contexts of the LL and LH subbands, indexed by the number of
+// significant horizontal, vertical and diagonal neighbors (Table D.1).
+var zcTable = func() (t [3][3][5]int) {
+	for h := 0; h < 3; h++ {
+		for v := 0; v < 3; v++ {
+			for d := 0; d < 5; d++ {
+				var ctx int
+				switch h {
+				case 2:
+					ctx = 8
+				case 1:
+					ctx = 5
+					if v > 0 {
+						ctx = 7
+					} else if d > 0 {
+						ctx = 6
+					}
+				default:
+					ctx = []int{0, 3, 4}[v]
+					if v == 0 {
+						ctx = []int{0, 1, 2, 2, 2}[d]
+					}
+				}
+				t[h][v][d] = ctx
+			}
+		}
+	}
+	return t
+}()
+
+// hhTable contains the zero coding contexts of the HH subbands, indexed by the number of
+// significant diagonal neighbors and the number of significant horizontal and vertical neighbors.
+var hhTable = [5][5]int{
+	{0, 1, 2, 2, 2},
+	{3, 4, 5, 5, 5},
+	{6, 7, 7, 7, 7},
+	{8, 8, 8, 8, 8},
+	{8, 8, 8, 8, 8},
+}
+
+// signTable contains the sign coding contexts and XOR bits, indexed by the horizontal and
+// vertical contributions plus one (Table D.3).
+var signTable = [3][3][2]int{
+	{{13, 1}, {12, 1}, {11, 1}},
+	{{10, 1}, {9, 0}, {10, 0}},
+	{{11, 0}, {12, 0}, {13, 0}},
+}
+
+func (e *t1Encoder) sig(i int) int {
+	return int(e.flags[i] & flagSignificant)
+}
+
+func (e *t1Encoder) below(y int) bool {
+	return e.cbStyle&cbCausal == 0 || y%4 != 3
+}
+
+func (e *t1Encoder) zc(i, y int) int {
+	s := e.stride
+	h := e.sig(i-1) + e.sig(i+1)
+	v := e.sig(i - s)
+	d := e.sig(i-s-1) + e.sig(i-s+1)
+	if e.below(y) {
+		v += e.sig(i + s)
+		d += e.sig(i+s-1) + e.sig(i+s+1)
+	}
+	switch e.orient {
+	case bandHL:
+		return zcTable[v][h][d]
+	case bandHH:
+		return hhTable[d][h+v]
+	}
+	return zcTable[h][v][d]
+}
+
+func (e *t1Encoder) encodeBit(ctx, bit int) {
+	if e.useRaw {
+		e.raw.encode(bit)
+	} else {
+		e.mq.encode(&e.contexts[ctx], bit)
+	}
+}
+
+func (e *t1Encoder) encodeSign(i, y int) {
+	f := e.flags[i]
+	sign := int(f&flagNegative) >> 1
+	e.flags[i] |= flagSignificant
+	if e.useRaw {
+		e.raw.encode(sign)
+		return
+	}
+	contribution := func(j int) int {
+		if e.flags[j]&flagSignificant == 0 {
+			return 0
+		}
+		if e.flags[j]&flagNegative != 0 {
+			return -1
+		}
+		return 1
+	}
+	clamp := func(v int) int {
+		return maxInt(-1, minInt(1, v))
+	}
+	h := clamp(contribution(i-1) + contribution(i+1))
+	vc := contribution(i - e.stride)
+	if e.below(y) {
+		vc += contribution(i + e.stride)
+	}
+	v := clamp(vc)
+	entry := signTable[h+1][v+1]
+	e.mq.encode(&e.contexts[entry[0]], sign^entry[1])
+}
+
+func (e *t1Encoder) significance(bitplane int) {
+	for y0 := 0; y0 < e.h; y0 += 4 {
+		for x := 0; x < e.w; x++ {
+			for y := y0; y < y0+4 && y < e.h; y++ {
+				i := (y+1)*e.stride + x + 1
+				if e.flags[i]&flagSignificant != 0 {
+					continue
+				}
+				ctx := e.zc(i, y)
+				if ctx == 0 {
+					continue
+				}
+				bit := int(e.mags[i]>>uint(bitplane)) & 1
+				e.encodeBit(ctx, bit)
+				if bit == 1 {
+					e.encodeSign(i, y)
+				}
+				e.flags[i] |= flagVisited
+			}
+		}
+	}
+}
+
+func (e *t1Encoder) refinement(bitplane int) {
+	for y0 := 0; y0 < e.h; y0 += 4 {
+		for x := 0; x < e.w; x++ {
+			for y := y0; y < y0+4 && y < e.h; y++ {
+				i := (y+1)*e.stride + x + 1
+				f := e.flags[i]
+				if f&flagSignificant == 0 || f&flagVisited != 0 {
+					continue
+				}
+				ctx := 16
+				if f&flagRefined == 0 {
+					ctx = 14
+					s := e.stride
+					n := e.sig(i-1) + e.sig(i+1) + e.sig(i-s) + e.sig(i-s-1) + e.sig(i-s+1)
+					if e.below(y) {
+						n += e.sig(i+s) + e.sig(i+s-1) + e.sig(i+s+1)
+					}
+					if n > 0 {
+						ctx = 15
+					}
+				}
+				e.encodeBit(ctx, int(e.mags[i]>>uint(bitplane))&1)
+				e.flags[i] |= flagRefined
+			}
+		}
+	}
+}
+
+func (e *t1Encoder) cleanup(bitplane int) {
+	for y0 := 0; y0 < e.h; y0 += 4 {
+		for x := 0; x < e.w; x++ {
+			y := y0
+			if y0+4 <= e.h {
+				run := true
+				for k := y0; k < y0+4; k++ {
+					i := (k+1)*e.stride + x + 1
+					if e.flags[i]&(flagSignificant|flagVisited) != 0 || e.zc(i, k) != 0 {
+						run = false
+					}
+				}
+				if run {
+					first := -1
+					for k := 0; k < 4; k++ {
+						if e.mags[(y0+k+1)*e.stride+x+1]>>uint(bitplane)&1 == 1 {
+							first = k
+							break
+						}
+					}
+					if first < 0 {
+						e.mq.encode(&e.contexts[ctxRunLength], 0)
+						continue
+					}
+					e.mq.encode(&e.contexts[ctxRunLength], 1)
+					e.mq.encode(&e.contexts[ctxUniform], first>>1)
+					e.mq.encode(&e.contexts[ctxUniform], first&1)
+					y = y0 + first
+					e.encodeSign((y+1)*e.stride+x+1, y)
+					y++
+				}
+			}
+			for ; y < y0+4 && y < e.h; y++ {
+				i := (y+1)*e.stride + x + 1
+				if e.flags[i]&(flagSignificant|flagVisited) != 0 {
+					continue
+				}
+				bit := int(e.mags[i]>>uint(bitplane)) & 1
+				e.mq.encode(&e.contexts[e.zc(i, y)], bit)
+				if bit == 1 {
+					e.encodeSign(i, y)
+				}
+			}
+		}
+	}
+	for i := range e.flags {
+		e.flags[i] &^= flagVisited
+	}
+	if e.cbStyle&cbSegmentation != 0 {
+		for _, bit := range []int{1, 0, 1, 0} {
+			e.mq.encode(&e.contexts[ctxUniform], bit)
+		}
+	}
+}
+
+// mqEncoder is the MQ arithmetic encoder (C.2).
+type mqEncoder struct {
+	a   uint32
+	c   uint64
+	ct  int
+	buf []byte
+}
+
+func (mq *mqEncoder) init() {
+	mq.a = 0x8000
+	mq.c = 0
+	mq.ct = 12
+	mq.buf = []byte{0}
+}
+
+func (mq *mqEncoder) encode(cx *mqContext, d int) {
+	s := &mqStates[cx.index]
+	mq.a -= s.qe
+	if d == int(cx.mps) {
+		if mq.a&0x8000 != 0 {
+			mq.c += uint64(s.qe)
+			return
+		}
+		if mq.a < s.qe {
+			mq.a = s.qe
+		} else {
+			mq.c += uint64(s.qe)
+		}
+		cx.index = s.nmps
+	} else {
+		if mq.a < s.qe {
+			mq.c += uint64(s.qe)
+		} else {
+			mq.a = s.qe
+		}
+		if s.switchMPS {
+			cx.mps = 1 - cx.mps
+		}
+		cx.index = s.nlps
+	}
+	for {
+		mq.a <<= 1
+		mq.c <<= 1
+		mq.ct--
+		if mq.ct == 0 {
+			mq.byteOut()
+		}
+		if mq.a&0x8000 != 0 {
+			break
+		}
+	}
+}
+
+func (mq *mqEncoder) byteOut() {
+	last := len(mq.buf) - 1
+	if mq.buf[last] != 0xFF && mq.c >= 0x8000000 {
+		mq.buf[last]++
+		if mq.buf[last] == 0xFF {
+			mq.c &= 0x7FFFFFF
+		}
+	}
+	if mq.buf[last] == 0xFF {
+		mq.buf = append(mq.buf, byte(mq.c>>20))
+		mq.c &= 0xFFFFF
+		mq.ct = 7
+	} else {
+		mq.buf = append(mq.buf, byte(mq.c>>19))
+		mq.c &= 0x7FFFF
+		mq.ct = 8
+	}
+}
+
+// flush terminates the codeword (C.2.9).
+func (mq *mqEncoder) flush() []byte {
+	temp := mq.c + uint64(mq.a)
+	mq.c |= 0xFFFF
+	if mq.c >= temp {
+		mq.c -= 0x8000
+	}
+	mq.c <<= uint(mq.ct)
+	mq.byteOut()
+	mq.c <<= uint(mq.ct)
+	mq.byteOut()
+	out := mq.buf[1:]
+	if len(out) > 0 && out[len(out)-1] == 0xFF {
+		out = out[:len(out)-1]
+	}
+	return append([]byte{}, out...)
+}
+
+// rawEncoder writes the raw bits of the selective arithmetic coding bypass (D.6).
+type rawEncoder struct {
+	buf []byte
+	c   byte
+	n   uint
+	max uint
+}
+
+func (re *rawEncoder) encode(d int) {
+	if re.max == 0 {
+		re.max = 8
+	}
+	re.c = re.c<<1 | byte(d)
+	re.n++
+	if re.n == re.max {
+		re.buf = append(re.buf, re.c)
+		re.max = 8
+		if re.c == 0xFF {
+			re.max = 7
+		}
+		re.c, re.n = 0, 0
+	}
+}
+
+func (re *rawEncoder) flush() []byte {
+	if re.n > 0 {
+		re.c <<= re.max - re.n
+		re.buf = append(re.buf, re.c)
+	}
+	return re.buf
+}
+
+func bitLength(v int32) int {
+	n := 0
+	for v > 0 {
+		v >>= 1
+		n++
+	}
+	return n
+}
+
+func appendU16(b []byte, v int) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendU32(b []byte, v int) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(v))
+	return append(b, buf[:]...)
+}
+
+func appendSegment(b []byte, marker int, seg []byte) []byte {
+	b = appendU16(b, marker)
+	b = appendU16(b, len(seg)+2)
+	return append(b, seg...)
+}
@@ -0,0 +1,395 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Box types of the JP2 file format (Annex I) and of the JPX extended file format.
+const (
+	boxSignature           = 0x6A502020 // 'jP  '
+	boxHeader              = 0x6A703268 // 'jp2h'
+	boxImageHeader         = 0x69686472 // 'ihdr'
+	boxBitsPerComponent    = 0x62706363 // 'bpcc'
+	boxColorSpecification  = 0x636F6C72 // 'colr'
+	boxPalette             = 0x70636C72 // 'pclr'
+	boxComponentMapping    = 0x636D6170 // 'cmap'
+	boxChannelDefinition   = 0x63646566 // 'cdef'
+	boxCodestream          = 0x6A703263 // 'jp2c'
+	boxCodestreamHeader    = 0x6A706368 // 'jpch'
+	boxCompositingLayerHdr = 0x6A706C68 // 'jplh'
+)
+
+// Enumerated colour spaces of the colour specification box (I.5.3.3, Table M.25).
+const (
+	enumCMYK     = 12
+	enumSRGB     = 16
+	enumGray     = 17
+	enumSYCC     = 18
+	enumESRGB    = 20
+	enumROMMRGB  = 21
+	enumESYCC    = 24
+	methodEnum   = 1
+	methodICC    = 2
+	methodAnyICC = 3
+)
+
+// Channel types of the channel definition box (Table I.16).
+const (
+	channelColor         = 0
+	channelOpacity       = 1
+	channelPremultiplied = 2
+)
+
+// imageHeader is an image header box (I.5.3.1), with the bit depths of the bits per component box
+// (I.5.3.2) if they vary.
+type imageHeader struct {
+	width, height int
+	numComps      int
+
+	// bpc contains the depth and sign of each component, encoded as the Ssiz parameter of the
+	// SIZ marker segment, or is nil until the bits per component box is read.
+	bpc []byte
+}
+
+// colorSpecification is a colour specification box (I.5.3.3).
+type colorSpecification struct {
+	method     int
+	precedence int
+	enum       int
+	icc        []byte
+}
+
+// palette is a palette box (I.5.3.4).
+type palette struct {
+	entries int
+	depths  []int
+	signed  []bool
+
+	// values contains the entries of each column of the palette.
+	values [][]int32
+}
+
+// componentMapping is an entry of the component mapping box (I.5.3.5).
+type componentMapping struct {
+	comp   int
+	direct bool
+	column int
+}
+
+// channelDefinition is an entry of the channel definition box (I.5.3.6).
+type channelDefinition struct {
+	index int
+	typ   int
+	assoc int
+}
+
+// jp2File contains the boxes of a JP2 or JPX file which are relevant for decoding the image.
+type jp2File struct {
+	header     *imageHeader
+	colors     []colorSpecification
+	palette    *palette
+	mapping    []componentMapping
+	channels   []channelDefinition
+	codestream []byte
+}
+
+// isCodestream returns true if `data` is a raw codestream, starting with the SOC and SIZ markers.
+func isCodestream(data []byte) bool {
+	return len(data) >= 4 && data[0] == 0xFF && data[1] == 0x4F && data[2] == 0xFF && data[3] == 0x51
+}
+
+// parseFile parses `data` as a raw codestream or a JP2/JPX file.
+func parseFile(data []byte) (*jp2File, error) {
+	if isCodestream(data) {
+		return &jp2File{codestream: data}, nil
+	}
+	if len(data) < 12 || binary.BigEndian.Uint32(data[4:]) != boxSignature {
+		return nil, errors.New("jpx: not a JPEG 2000 file")
+	}
+	f := &jp2File{}
+	if err := f.parseBoxes(data); err != nil {
+		return nil, err
+	}
+	if f.codestream == nil {
+		return nil, errors.New("jpx: missing contiguous codestream box")
+	}
+	if f.header == nil {
+		return nil, errors.New("jpx: missing image header box")
+	}
+	if f.header.bpc == nil {
+		return nil, errors.New("jpx: missing bits per component box")
+	}
+	return f, nil
+}
+
+// checkHeader checks that the image header box of the file matches the SIZ marker segment of the
+// codestream `cs` (I.5.3.1).
+func (f *jp2File) checkHeader(cs *codestream) error {
+	h := f.header
+	if h == nil {
+		// Raw codestream.
+		return nil
+	}
+	s := &cs.size
+	if h.width != s.x1-s.x0 || h.height != s.y1-s.y0 {
+		return fmt.Errorf("jpx: image header size %dx%d does not match codestream size %dx%d",
+			h.width, h.height, s.x1-s.x0, s.y1-s.y0)
+	}
+	if h.numComps != len(s.components) || len(h.bpc) != len(s.components) {
+		return fmt.Errorf("jpx: image header has %d components, codestream %d",
+			h.numComps, len(s.components))
+	}
+	for i, comp := range s.components {
+		bpc := byte(comp.depth - 1)
+		if comp.signed {
+			bpc |= 0x80
+		}
+		if h.bpc[i] != bpc {
+			return fmt.Errorf("jpx: image header depth %#x of component %d does not match codestream %#x",
+				h.bpc[i], i, bpc)
+		}
+	}
+	return nil
+}
+
+// parseBoxes parses the boxes contained in `data`, recursively for the header superboxes.
+func (f *jp2File) parseBoxes(data []byte) error {
+	for len(data) >= 8 {
+		length := int(binary.BigEndian.Uint32(data))
+		typ := binary.BigEndian.Uint32(data[4:])
+		offset := 8
+		switch length {
+		case 0:
+			length = len(data)
+		case 1:
+			if len(data) < 16 {
+				return errUnexpectedEOF
+			}
+			xl := binary.BigEndian.Uint64(data[8:])
+			offset = 16
+			length = len(data) + 1
+			if xl <= uint64(len(data)) {
+				length = int(xl)
+			}
+		}
+		if length < offset || length > len(data) {
+			if typ != boxCodestream || length < offset {
+				return fmt.Errorf("jpx: invalid box length %d", length)
+			}
+			// Tolerate truncated codestreams.
+			length = len(data)
+		}
+		content := data[offset:length]
+		data = data[length:]
+
+		var err error
+		switch typ {
+		case boxHeader, boxCodestreamHeader, boxCompositingLayerHdr:
+			err = f.parseBoxes(content)
+		case boxImageHeader:
+			if f.header == nil {
+				f.header, err = parseImageHeader(content)
+			}
+		case boxBitsPerComponent:
+			if f.header != nil && f.header.bpc == nil {
+				if len(content) != f.header.numComps {
+					return errors.New("jpx: invalid bits per component box")
+				}
+				f.header.bpc = content
+			}
+		case boxColorSpecification:
+			err = f.parseColorSpecification(content)
+		case boxPalette:
+			if f.palette == nil {
+				f.palette, err = parsePalette(content)
+			}
+		case boxComponentMapping:
+			if f.mapping == nil {
+				f.mapping, err = parseComponentMapping(content)
+			}
+		case boxChannelDefinition:
+			if f.channels == nil {
+				f.channels, err = parseChannelDefinitions(content)
+			}
+		case boxCodestream:
+			if f.codestream == nil {
+				f.codestream = content
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseImageHeader(content []byte) (*imageHeader, error) {
+	if len(content) < 14 {
+		return nil, errUnexpectedEOF
+	}
+	h := &imageHeader{
+		height:   int(binary.BigEndian.Uint32(content)),
+		width:    int(binary.BigEndian.Uint32(content[4:])),
+		numComps: int(binary.BigEndian.Uint16(content[8:])),
+	}
+	if h.numComps == 0 {
+		return nil, errors.New("jpx: invalid image header")
+	}
+	// The depths are specified by the bits per component box if they vary.
+	if bpc := content[10]; bpc != 0xFF {
+		h.bpc = make([]byte, h.numComps)
+		for i := range h.bpc {
+			h.bpc[i] = bpc
+		}
+	}
+	return h, nil
+}
+
+func (f *jp2File) parseColorSpecification(content []byte) error {
+	if len(content) < 3 {
+		return errUnexpectedEOF
+	}
+	spec := colorSpecification{method: int(content[0]), precedence: int(int8(content[1]))}
+	switch spec.method {
+	case methodEnum:
+		if len(content) < 7 {
+			return errUnexpectedEOF
+		}
+		spec.enum = int(binary.BigEndian.Uint32(content[3:]))
+	case methodICC, methodAnyICC:
+		spec.icc = content[3:]
+	}
+	f.colors = append(f.colors, spec)
+	return nil
+}
+
+func parsePalette(content []byte) (*palette, error) {
+	r := &byteReader{data: content}
+	entries, err := r.u16()
+	if err != nil {
+		return nil, err
+	}
+	columns, err := r.u8()
+	if err != nil {
+		return nil, err
+	}
+	if entries == 0 || entries > 1024 || columns == 0 {
+		return nil, errors.New("jpx: invalid palette")
+	}
+	p := &palette{entries: entries, values: make([][]int32, columns)}
+	for i := 0; i < columns; i++ {
+		b, err := r.u8()
+		if err != nil {
+			return nil, err
+		}
+		p.depths = append(p.depths, b&0x7F+1)
+		p.signed = append(p.signed, b&0x80 != 0)
+		if p.depths[i] > 30 {
+			return nil, errors.New("jpx: unsupported palette depth")
+		}
+		p.values[i] = make([]int32, entries)
+	}
+	for e := 0; e < entries; e++ {
+		for i := 0; i < columns; i++ {
+			n := (p.depths[i] + 7) / 8
+			b, err := r.bytes(n)
+			if err != nil {
+				return nil, err
+			}
+			var v int64
+			for _, c := range b {
+				v = v<<8 | int64(c)
+			}
+			if p.signed[i] && v >= 1<<uint(p.depths[i]-1) {
+				v -= 1 << uint(p.depths[i])
+			}
+			p.values[i][e] = int32(v)
+		}
+	}
+	return p, nil
+}
+
+func parseComponentMapping(content []byte) ([]componentMapping, error) {
+	if len(content)%4 != 0 {
+		return nil, errors.New("jpx: invalid component mapping")
+	}
+	var mapping []componentMapping
+	for i := 0; i+4 <= len(content); i += 4 {
+		mapping = append(mapping, componentMapping{
+			comp:   int(binary.BigEndian.Uint16(content[i:])),
+			direct: content[i+2] == 0,
+			column: int(content[i+3]),
+		})
+	}
+	return mapping, nil
+}
+
+func parseChannelDefinitions(content []byte) ([]channelDefinition, error) {
+	r := &byteReader{data: content}
+	n, err := r.u16()
+	if err != nil {
+		return nil, err
+	}
+	defs := make([]channelDefinition, n)
+	for i := range defs {
+		var vals [3]int
+		for j := range vals {
+			if vals[j], err = r.u16(); err != nil {
+				return nil, err
+			}
+		}
+		defs[i] = channelDefinition{index: vals[0], typ: vals[1], assoc: vals[2]}
+	}
+	return defs, nil
+}
+
+// colorSpecification returns the colour specification to be used for the image: the supported
+// specification with the highest precedence, the first one of equal precedence.
+func (f *jp2File) colorSpecification() *colorSpecification {
+	var best *colorSpecification
+	for i := range f.colors {
+		spec := &f.colors[i]
+		switch spec.method {
+		case methodEnum:
+			switch spec.enum {
+			case enumCMYK, enumSRGB, enumGray, enumSYCC, enumESRGB, enumROMMRGB, enumESYCC:
+			default:
+				continue
+			}
+		case methodICC, methodAnyICC:
+			if iccComponents(spec.icc) == 0 {
+				continue
+			}
+		default:
+			continue
+		}
+		if best == nil || spec.precedence > best.precedence {
+			best = spec
+		}
+	}
+	return best
+}
+
+// iccComponents returns the number of components of the colour space of the ICC profile `icc`,
+// or 0 if it is not supported.
+func iccComponents(icc []byte) int {
+	if len(icc) < 128 {
+		return 0
+	}
+	switch string(icc[16:20]) {
+	case "GRAY":
+		return 1
+	case "RGB ":
+		return 3
+	case "CMYK":
+		return 4
+	}
+	return 0
+}
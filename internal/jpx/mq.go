@@ -0,0 +1,166 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+// mqState is a state of the probability estimation of the MQ-coder (Table C.2).
+type mqState struct {
+	qe         uint32
+	nmps, nlps uint8
+	switchMPS  bool
+}
+
+var mqStates = [47]mqState{
+	{0x5601, 1, 1, true}, {0x3401, 2, 6, false}, {0x1801, 3, 9, false}, {0x0AC1, 4, 12, false},
+	{0x0521, 5, 29, false}, {0x0221, 38, 33, false}, {0x5601, 7, 6, true}, {0x5401, 8, 14, false},
+	{0x4801, 9, 14, false}, {0x3801, 10, 14, false}, {0x3001, 11, 17, false}, {0x2401, 12, 18, false},
+	{0x1C01, 13, 20, false}, {0x1601, 29, 21, false}, {0x5601, 15, 14, true}, {0x5401, 16, 14, false},
+	{0x5101, 17, 15, false}, {0x4801, 18, 16, false}, {0x3801, 19, 17, false}, {0x3401, 20, 18, false},
+	{0x3001, 21, 19, false}, {0x2801, 22, 19, false}, {0x2401, 23, 20, false}, {0x2201, 24, 21, false},
+	{0x1C01, 25, 22, false}, {0x1801, 26, 23, false}, {0x1601, 27, 24, false}, {0x1401, 28, 25, false},
+	{0x1201, 29, 26, false}, {0x1101, 30, 27, false}, {0x0AC1, 31, 28, false}, {0x09C1, 32, 29, false},
+	{0x08A1, 33, 30, false}, {0x0521, 34, 31, false}, {0x0441, 35, 32, false}, {0x02A1, 36, 33, false},
+	{0x0221, 37, 34, false}, {0x0141, 38, 35, false}, {0x0111, 39, 36, false}, {0x0085, 40, 37, false},
+	{0x0049, 41, 38, false}, {0x0025, 42, 39, false}, {0x0015, 43, 40, false}, {0x0009, 44, 41, false},
+	{0x0005, 45, 42, false}, {0x0001, 45, 43, false}, {0x5601, 46, 46, false},
+}
+
+// mqContext is the state index and the more probable symbol of a context.
+type mqContext struct {
+	index uint8
+	mps   uint8
+}
+
+// mqDecoder is the MQ arithmetic decoder (C.3).
+type mqDecoder struct {
+	data []byte
+	pos  int
+	a, c uint32
+	ct   uint
+}
+
+// init initializes the decoder for the codeword segment `data` (C.3.5). The segment is
+// terminated with 0xFFFF, so that the decoder reads 1 bits past its end.
+func (mq *mqDecoder) init(data []byte) {
+	mq.data = append(append(mq.data[:0], data...), 0xFF, 0xFF)
+	mq.pos = 0
+	mq.c = uint32(mq.data[0]) << 16
+	mq.byteIn()
+	mq.c <<= 7
+	mq.ct -= 7
+	mq.a = 0x8000
+}
+
+// byteIn reads the next byte of the segment (C.3.4).
+func (mq *mqDecoder) byteIn() {
+	if mq.data[mq.pos] == 0xFF {
+		if mq.pos+1 >= len(mq.data) || mq.data[mq.pos+1] > 0x8F {
+			mq.c += 0xFF00
+			mq.ct = 8
+		} else {
+			mq.pos++
+			mq.c += uint32(mq.data[mq.pos]) << 9
+			mq.ct = 7
+		}
+	} else {
+		mq.pos++
+		mq.c += uint32(mq.data[mq.pos]) << 8
+		mq.ct = 8
+	}
+}
+
+// decode decodes a decision with context `cx` (C.3.2).
+func (mq *mqDecoder) decode(cx *mqContext) int {
+	s := &mqStates[cx.index]
+	d := int(cx.mps)
+	mq.a -= s.qe
+	if mq.c>>16 < s.qe {
+		// LPS exchange (C.3.2, Figure C.16).
+		if mq.a < s.qe {
+			cx.index = s.nmps
+		} else {
+			d = 1 - d
+			if s.switchMPS {
+				cx.mps = 1 - cx.mps
+			}
+			cx.index = s.nlps
+		}
+		mq.a = s.qe
+		mq.renormalize()
+	} else {
+		mq.c -= s.qe << 16
+		if mq.a&0x8000 == 0 {
+			// MPS exchange (Figure C.17).
+			if mq.a < s.qe {
+				d = 1 - d
+				if s.switchMPS {
+					cx.mps = 1 - cx.mps
+				}
+				cx.index = s.nlps
+			} else {
+				cx.index = s.nmps
+			}
+			mq.renormalize()
+		}
+	}
+	return d
+}
+
+// renormalize is the RENORMD procedure (Figure C.18).
+func (mq *mqDecoder) renormalize() {
+	for {
+		if mq.ct == 0 {
+			mq.byteIn()
+		}
+		mq.a <<= 1
+		mq.c <<= 1
+		mq.ct--
+		if mq.a&0x8000 != 0 {
+			break
+		}
+	}
+}
+
+// rawDecoder decodes the bits of the raw codeword segments of the selective arithmetic coding
+// bypass (D.6).
+type rawDecoder struct {
+	data []byte
+	pos  int
+	c    byte
+	ct   uint
+}
+
+func (rd *rawDecoder) init(data []byte) {
+	rd.data = data
+	rd.pos = 0
+	rd.c = 0
+	rd.ct = 0
+}
+
+// decode reads a bit. The segment is extended with 1 bits past its end.
+func (rd *rawDecoder) decode() int {
+	if rd.ct == 0 {
+		next := byte(0xFF)
+		if rd.pos < len(rd.data) {
+			next = rd.data[rd.pos]
+		}
+		if rd.c == 0xFF {
+			if next > 0x8F {
+				rd.c = 0xFF
+				rd.ct = 8
+			} else {
+				rd.c = next
+				rd.pos++
+				rd.ct = 7
+			}
+		} else {
+			rd.c = next
+			rd.pos++
+			rd.ct = 8
+		}
+	}
+	rd.ct--
+	return int(rd.c>>rd.ct) & 1
+}
@@ -0,0 +1,332 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+import (
+	"sort"
+
+	"github.com/unidoc/unipdf/v3/common"
+)
+
+// maxCodingPasses is the maximum number of coding passes of a code-block (Table B.4).
+const maxCodingPasses = 164
+
+// bitReader reads the bits of the packet headers, which are bit-stuffed after 0xFF bytes (B.10.1).
+type bitReader struct {
+	data []byte
+	pos  int
+	cur  byte
+	n    uint
+}
+
+// bit reads a single bit.
+func (br *bitReader) bit() (int, error) {
+	if br.n == 0 {
+		if br.pos >= len(br.data) {
+			return 0, errUnexpectedEOF
+		}
+		br.n = 8
+		if br.cur == 0xFF {
+			br.n = 7
+		}
+		br.cur = br.data[br.pos]
+		br.pos++
+	}
+	br.n--
+	return int(br.cur>>br.n) & 1, nil
+}
+
+// bits reads `n` bits as an unsigned value.
+func (br *bitReader) bits(n int) (int, error) {
+	v := 0
+	for i := 0; i < n; i++ {
+		bit, err := br.bit()
+		if err != nil {
+			return 0, err
+		}
+		v = v<<1 | bit
+	}
+	return v, nil
+}
+
+// align skips the remaining bits of the current byte. If the byte is 0xFF, the following stuffed
+// byte is skipped too.
+func (br *bitReader) align() {
+	if br.cur == 0xFF && br.pos < len(br.data) {
+		br.pos++
+	}
+	br.cur = 0
+	br.n = 0
+}
+
+// marker skips the marker `m` at the current byte position, if present.
+func (br *bitReader) marker(m int) {
+	if br.pos+2 <= len(br.data) && int(br.data[br.pos])<<8|int(br.data[br.pos+1]) == m {
+		br.pos += 2
+	}
+}
+
+// packet identifies a packet of a tile (B.9).
+type packet struct {
+	layer, res, comp, prec int
+
+	// The position of the precinct on the reference grid, used by the position driven progressions.
+	x, y int
+}
+
+// tileDecoder decodes the packets of a tile.
+type tileDecoder struct {
+	cs   *codestream
+	td   *tileData
+	tile *tile
+	body *bitReader
+	hdr  *bitReader
+
+	// next contains the index of the next layer of each precinct, per component and resolution.
+	next [][][]int
+}
+
+// decodePackets decodes the packets of the tile in the order of its progressions (B.12).
+// The decoding stops at the first truncated or corrupted packet, keeping the data decoded so far.
+func (d *tileDecoder) decodePackets() {
+	d.body = &bitReader{data: d.td.data}
+	if d.td.packed {
+		d.hdr = &bitReader{data: d.td.headers}
+	}
+	d.next = make([][][]int, len(d.tile.comps))
+	for c, tc := range d.tile.comps {
+		d.next[c] = make([][]int, len(tc.res))
+		for r, res := range tc.res {
+			d.next[c][r] = make([]int, res.pw*res.ph)
+		}
+	}
+
+	for _, pc := range d.cs.progressions(d.td) {
+		for _, p := range d.packets(pc) {
+			next := &d.next[p.comp][p.res][p.prec]
+			if p.layer < *next {
+				continue
+			}
+			*next = p.layer + 1
+			if err := d.decodePacket(p); err != nil {
+				common.Log.Debug("jpx: tile %d: packet (l=%d r=%d c=%d p=%d): %v",
+					d.td.index, p.layer, p.res, p.comp, p.prec, err)
+				return
+			}
+		}
+	}
+}
+
+// packets returns the packets of the progression `pc`, sorted in the order of its progression
+// (B.12.1).
+func (d *tileDecoder) packets(pc progressionChange) []packet {
+	layers := minInt(pc.layerEnd, d.tile.style.layers)
+	var packets []packet
+	for c := pc.compStart; c < pc.compEnd && c < len(d.tile.comps); c++ {
+		tc := d.tile.comps[c]
+		comp := d.cs.size.components[c]
+		nl := tc.style.levels
+		for r := pc.resStart; r < pc.resEnd && r <= nl; r++ {
+			res := tc.res[r]
+			for j := 0; j < res.ph; j++ {
+				y := maxInt(((res.py0+j)<<uint(res.ppy+nl-r))*comp.dy, d.tile.y0)
+				for i := 0; i < res.pw; i++ {
+					x := maxInt(((res.px0+i)<<uint(res.ppx+nl-r))*comp.dx, d.tile.x0)
+					for l := 0; l < layers; l++ {
+						packets = append(packets, packet{
+							layer: l, res: r, comp: c, prec: j*res.pw + i,
+							x: x, y: y,
+						})
+					}
+				}
+			}
+		}
+	}
+
+	var keys func(p *packet) [5]int
+	switch pc.order {
+	case progressionLRCP:
+		keys = func(p *packet) [5]int { return [5]int{p.layer, p.res, p.comp, p.prec} }
+	case progressionRLCP:
+		keys = func(p *packet) [5]int { return [5]int{p.res, p.layer, p.comp, p.prec} }
+	case progressionRPCL:
+		keys = func(p *packet) [5]int { return [5]int{p.res, p.y, p.x, p.comp, p.layer} }
+	case progressionPCRL:
+		keys = func(p *packet) [5]int { return [5]int{p.y, p.x, p.comp, p.res, p.layer} }
+	default:
+		keys = func(p *packet) [5]int { return [5]int{p.comp, p.y, p.x, p.res, p.layer} }
+	}
+	sort.SliceStable(packets, func(i, j int) bool {
+		ki, kj := keys(&packets[i]), keys(&packets[j])
+		for k := range ki {
+			if ki[k] != kj[k] {
+				return ki[k] < kj[k]
+			}
+		}
+		return false
+	})
+	return packets
+}
+
+// blockContribution is the data contributed by a packet to a codeword segment of a code-block.
+type blockContribution struct {
+	seg    *codewordSegment
+	length int
+}
+
+// decodePacket decodes the header and the body of packet `p` (B.10).
+func (d *tileDecoder) decodePacket(p packet) error {
+	style := d.tile.style
+	res := d.tile.comps[p.comp].res[p.res]
+	cbStyle := d.tile.comps[p.comp].style.cbStyle
+
+	if style.sop {
+		// Skip the SOP marker segment (A.8.1).
+		if b := d.body; b.pos+6 <= len(b.data) && b.data[b.pos] == 0xFF && b.data[b.pos+1] == 0x91 {
+			b.pos += 6
+		}
+	}
+	br := d.hdr
+	if br == nil {
+		br = d.body
+	}
+
+	present, err := br.bit()
+	if err != nil {
+		return err
+	}
+	var contribs []blockContribution
+	if present == 1 {
+		for _, band := range res.bands {
+			prec := band.precincts[p.prec]
+			for k, cb := range prec.blocks {
+				x, y := k%prec.nw, k/prec.nw
+				var included bool
+				if !cb.included {
+					included, err = prec.inclusion.decode(br, x, y, p.layer+1)
+				} else {
+					var bit int
+					bit, err = br.bit()
+					included = bit == 1
+				}
+				if err != nil {
+					return err
+				}
+				if !included {
+					continue
+				}
+				if !cb.included {
+					zbp, err := prec.zeroPlane.decodeValue(br, x, y)
+					if err != nil {
+						return err
+					}
+					cb.zeroBitplanes = zbp
+					cb.included = true
+					cb.lblock = 3
+				}
+				passes, err := decodeNumPasses(br)
+				if err != nil {
+					return err
+				}
+				for {
+					bit, err := br.bit()
+					if err != nil {
+						return err
+					}
+					if bit == 0 {
+						break
+					}
+					cb.lblock++
+				}
+				if cb.passes+passes > maxCodingPasses {
+					return errInvalidCodestream
+				}
+				for passes > 0 {
+					var seg *codewordSegment
+					if n := len(cb.segments); n > 0 && cb.segments[n-1].passes < cb.segments[n-1].maxPasses {
+						seg = cb.segments[n-1]
+					} else {
+						seg = &codewordSegment{maxPasses: segmentPasses(cbStyle, cb.passes)}
+						cb.segments = append(cb.segments, seg)
+					}
+					n := minInt(passes, seg.maxPasses-seg.passes)
+					length, err := br.bits(cb.lblock + floorLog2(n))
+					if err != nil {
+						return err
+					}
+					seg.passes += n
+					cb.passes += n
+					passes -= n
+					contribs = append(contribs, blockContribution{seg: seg, length: length})
+				}
+			}
+		}
+	}
+	br.align()
+	if style.eph {
+		br.marker(markerEPH)
+	}
+
+	for _, c := range contribs {
+		end := d.body.pos + c.length
+		if end > len(d.body.data) {
+			c.seg.data = append(c.seg.data, d.body.data[d.body.pos:]...)
+			d.body.pos = len(d.body.data)
+			return errUnexpectedEOF
+		}
+		c.seg.data = append(c.seg.data, d.body.data[d.body.pos:end]...)
+		d.body.pos = end
+	}
+	return nil
+}
+
+// decodeNumPasses decodes the number of coding passes of a code-block (Table B.4).
+func decodeNumPasses(br *bitReader) (int, error) {
+	if bit, err := br.bit(); err != nil || bit == 0 {
+		return 1, err
+	}
+	if bit, err := br.bit(); err != nil || bit == 0 {
+		return 2, err
+	}
+	v, err := br.bits(2)
+	if err != nil || v < 3 {
+		return 3 + v, err
+	}
+	v, err = br.bits(5)
+	if err != nil || v < 31 {
+		return 6 + v, err
+	}
+	v, err = br.bits(7)
+	return 37 + v, err
+}
+
+// segmentPasses returns the maximum number of coding passes of the codeword segment that starts
+// with coding pass `first` (D.4.1, Table D.8).
+func segmentPasses(cbStyle, first int) int {
+	if cbStyle&cbTermAll != 0 {
+		return 1
+	}
+	if cbStyle&cbBypass != 0 {
+		if first < 10 {
+			return 10 - first
+		}
+		if (first-10)%3 == 0 {
+			return 2
+		}
+		return 1
+	}
+	return maxCodingPasses
+}
+
+// floorLog2 returns the floor of the base 2 logarithm of n > 0.
+func floorLog2(n int) int {
+	l := 0
+	for n > 1 {
+		n >>= 1
+		l++
+	}
+	return l
+}
@@ -0,0 +1,398 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+// Context labels of the coefficient bit modeling (Annex D): 9 significance contexts, 5 sign
+// contexts, 3 magnitude refinement contexts, the run-length and the uniform contexts.
+const (
+	ctxSign       = 9
+	ctxRefinement = 14
+	ctxRunLength  = 17
+	ctxUniform    = 18
+	numContexts   = 19
+)
+
+// Coefficient state flags.
+const (
+	flagSignificant = 1 << iota
+	flagNegative
+	flagVisited
+	flagRefined
+)
+
+// Coding pass types (D.3).
+const (
+	passSignificance = iota
+	passRefinement
+	passCleanup
+)
+
+// t1Decoder decodes the code-blocks with the embedded block coding (Annex D).
+type t1Decoder struct {
+	w, h    int
+	orient  int
+	cbStyle int
+
+	// flags and magnitudes of the coefficients, with a border of one coefficient on each side.
+	stride int
+	flags  []uint8
+	mags   []int32
+
+	contexts [numContexts]mqContext
+	mq       mqDecoder
+	raw      rawDecoder
+	useRaw   bool
+}
+
+// resetContexts sets the contexts to their initial states (Table D.7).
+func (t *t1Decoder) resetContexts() {
+	for i := range t.contexts {
+		t.contexts[i] = mqContext{}
+	}
+	t.contexts[0].index = 4
+	t.contexts[ctxRunLength].index = 3
+	t.contexts[ctxUniform].index = 46
+}
+
+// decodeBlock decodes the code-block `cb` of subband `band` and stores the dequantized
+// coefficients into the subband.
+func (t *t1Decoder) decodeBlock(cb *codeblock, band *subband, roiShift int, reversible bool) {
+	t.w, t.h = cb.x1-cb.x0, cb.y1-cb.y0
+	t.orient = band.orient
+	t.stride = t.w + 2
+	size := t.stride * (t.h + 2)
+	if cap(t.flags) < size {
+		t.flags = make([]uint8, size)
+		t.mags = make([]int32, size)
+	}
+	t.flags = t.flags[:size]
+	t.mags = t.mags[:size]
+	for i := range t.flags {
+		t.flags[i] = 0
+		t.mags[i] = 0
+	}
+	t.resetContexts()
+
+	bitplane := band.numBitplanes - 1 - cb.zeroBitplanes
+	if bitplane < 0 || cb.passes == 0 {
+		return
+	}
+	passType := passCleanup
+	passIndex := 0
+	lowest := bitplane
+	for _, seg := range cb.segments {
+		if bitplane < 0 {
+			break
+		}
+		t.useRaw = t.cbStyle&cbBypass != 0 && passIndex >= 10 && passType != passCleanup
+		if t.useRaw {
+			t.raw.init(seg.data)
+		} else {
+			t.mq.init(seg.data)
+		}
+		for i := 0; i < seg.passes && bitplane >= 0; i++ {
+			switch passType {
+			case passSignificance:
+				t.significancePass(bitplane)
+			case passRefinement:
+				t.refinementPass(bitplane)
+			case passCleanup:
+				t.cleanupPass(bitplane)
+			}
+			lowest = bitplane
+			if t.cbStyle&cbReset != 0 {
+				t.resetContexts()
+			}
+			passIndex++
+			passType++
+			if passType > passCleanup {
+				passType = passSignificance
+				bitplane--
+			}
+		}
+	}
+
+	// Dequantization (E.1.1): the coefficients are reconstructed at the middle of the
+	// quantization interval of the decoded bit-planes.
+	bw := band.x1 - band.x0
+	for y := 0; y < t.h; y++ {
+		out := band.coeffs[(cb.y0-band.y0+y)*bw+cb.x0-band.x0:]
+		for x := 0; x < t.w; x++ {
+			i := (y+1)*t.stride + x + 1
+			mag := t.mags[i]
+			if mag == 0 {
+				out[x] = 0
+				continue
+			}
+			low := lowest
+			if roiShift > 0 {
+				if mag >= 1<<uint(roiShift) {
+					mag >>= uint(roiShift)
+					low -= roiShift
+				} else {
+					low = 0
+				}
+			}
+			v := float32(mag)
+			if low > 0 {
+				v += float32(int32(1) << uint(low-1))
+			} else if !reversible {
+				v += 0.5
+			}
+			if !reversible {
+				v *= band.delta
+			}
+			if t.flags[i]&flagNegative != 0 {
+				v = -v
+			}
+			out[x] = v
+		}
+	}
+}
+
+// neighbors returns the number of significant horizontal, vertical and diagonal neighbors of
+// the coefficient at index `i` in row `y` of the code-block.
+func (t *t1Decoder) neighbors(i, y int) (h, v, d int) {
+	f := t.flags
+	s := t.stride
+	sig := func(j int) int {
+		return int(f[j] & flagSignificant)
+	}
+	h = sig(i-1) + sig(i+1)
+	v = sig(i - s)
+	d = sig(i-s-1) + sig(i-s+1)
+	if t.cbStyle&cbCausal == 0 || y%4 != 3 {
+		v += sig(i + s)
+		d += sig(i+s-1) + sig(i+s+1)
+	}
+	return h, v, d
+}
+
+// significanceContext returns the zero coding context of the coefficient (Table D.1).
+func (t *t1Decoder) significanceContext(i, y int) int {
+	h, v, d := t.neighbors(i, y)
+	switch t.orient {
+	case bandHL:
+		h, v = v, h
+		fallthrough
+	case bandLL, bandLH:
+		switch {
+		case h == 2:
+			return 8
+		case h == 1 && v >= 1:
+			return 7
+		case h == 1 && d >= 1:
+			return 6
+		case h == 1:
+			return 5
+		case v == 2:
+			return 4
+		case v == 1:
+			return 3
+		case d >= 2:
+			return 2
+		case d == 1:
+			return 1
+		}
+		return 0
+	}
+	hv := h + v
+	switch {
+	case d >= 3:
+		return 8
+	case d == 2 && hv >= 1:
+		return 7
+	case d == 2:
+		return 6
+	case d == 1 && hv >= 2:
+		return 5
+	case d == 1 && hv == 1:
+		return 4
+	case d == 1:
+		return 3
+	case hv >= 2:
+		return 2
+	case hv == 1:
+		return 1
+	}
+	return 0
+}
+
+// signContext returns the sign coding context and the XOR bit of the coefficient (Tables D.2,
+// D.3).
+func (t *t1Decoder) signContext(i, y int) (int, int) {
+	contribution := func(j int) int {
+		f := t.flags[j]
+		if f&flagSignificant == 0 {
+			return 0
+		}
+		if f&flagNegative != 0 {
+			return -1
+		}
+		return 1
+	}
+	clamp := func(v int) int {
+		if v > 1 {
+			return 1
+		}
+		if v < -1 {
+			return -1
+		}
+		return v
+	}
+	h := clamp(contribution(i-1) + contribution(i+1))
+	vs := contribution(i - t.stride)
+	if t.cbStyle&cbCausal == 0 || y%4 != 3 {
+		vs += contribution(i + t.stride)
+	}
+	v := clamp(vs)
+
+	xor := 0
+	if h < 0 || h == 0 && v < 0 {
+		h, v = -h, -v
+		xor = 1
+	}
+	switch {
+	case h == 0 && v == 0:
+		return ctxSign, xor
+	case h == 0:
+		return ctxSign + 1, xor
+	}
+	return ctxSign + 3 + v, xor
+}
+
+// decodeSign decodes the sign of the coefficient at index `i`, which becomes significant.
+func (t *t1Decoder) decodeSign(i, y int) {
+	var sign int
+	if t.useRaw {
+		sign = t.raw.decode()
+	} else {
+		ctx, xor := t.signContext(i, y)
+		sign = t.mq.decode(&t.contexts[ctx]) ^ xor
+	}
+	t.flags[i] |= flagSignificant
+	if sign == 1 {
+		t.flags[i] |= flagNegative
+	}
+}
+
+// significancePass is the significance propagation decoding pass (D.3.1).
+func (t *t1Decoder) significancePass(bitplane int) {
+	one := int32(1) << uint(bitplane)
+	for y0 := 0; y0 < t.h; y0 += 4 {
+		for x := 0; x < t.w; x++ {
+			for y := y0; y < y0+4 && y < t.h; y++ {
+				i := (y+1)*t.stride + x + 1
+				if t.flags[i]&flagSignificant != 0 {
+					continue
+				}
+				ctx := t.significanceContext(i, y)
+				if ctx == 0 {
+					continue
+				}
+				var bit int
+				if t.useRaw {
+					bit = t.raw.decode()
+				} else {
+					bit = t.mq.decode(&t.contexts[ctx])
+				}
+				if bit == 1 {
+					t.decodeSign(i, y)
+					t.mags[i] |= one
+				}
+				t.flags[i] |= flagVisited
+			}
+		}
+	}
+}
+
+// refinementPass is the magnitude refinement decoding pass (D.3.3).
+func (t *t1Decoder) refinementPass(bitplane int) {
+	one := int32(1) << uint(bitplane)
+	for y0 := 0; y0 < t.h; y0 += 4 {
+		for x := 0; x < t.w; x++ {
+			for y := y0; y < y0+4 && y < t.h; y++ {
+				i := (y+1)*t.stride + x + 1
+				f := t.flags[i]
+				if f&flagSignificant == 0 || f&flagVisited != 0 {
+					continue
+				}
+				var bit int
+				if t.useRaw {
+					bit = t.raw.decode()
+				} else {
+					ctx := ctxRefinement + 2
+					if f&flagRefined == 0 {
+						ctx = ctxRefinement
+						if h, v, d := t.neighbors(i, y); h+v+d > 0 {
+							ctx++
+						}
+					}
+					bit = t.mq.decode(&t.contexts[ctx])
+				}
+				if bit == 1 {
+					t.mags[i] |= one
+				}
+				t.flags[i] |= flagRefined
+			}
+		}
+	}
+}
+
+// cleanupPass is the cleanup decoding pass (D.3.4), which also clears the visited flags.
+func (t *t1Decoder) cleanupPass(bitplane int) {
+	one := int32(1) << uint(bitplane)
+	for y0 := 0; y0 < t.h; y0 += 4 {
+		for x := 0; x < t.w; x++ {
+			y := y0
+			if y0+4 <= t.h && t.runLengthMode(x, y0) {
+				if t.mq.decode(&t.contexts[ctxRunLength]) == 0 {
+					continue
+				}
+				r := t.mq.decode(&t.contexts[ctxUniform]) << 1
+				r |= t.mq.decode(&t.contexts[ctxUniform])
+				y = y0 + r
+				i := (y+1)*t.stride + x + 1
+				t.decodeSign(i, y)
+				t.mags[i] |= one
+				y++
+			}
+			for ; y < y0+4 && y < t.h; y++ {
+				i := (y+1)*t.stride + x + 1
+				if t.flags[i]&(flagSignificant|flagVisited) != 0 {
+					continue
+				}
+				ctx := t.significanceContext(i, y)
+				if t.mq.decode(&t.contexts[ctx]) == 1 {
+					t.decodeSign(i, y)
+					t.mags[i] |= one
+				}
+			}
+		}
+	}
+	for i := range t.flags {
+		t.flags[i] &^= flagVisited
+	}
+	if t.cbStyle&cbSegmentation != 0 {
+		// The segmentation symbol 1010 (D.5).
+		for i := 0; i < 4; i++ {
+			t.mq.decode(&t.contexts[ctxUniform])
+		}
+	}
+}
+
+// runLengthMode returns true if the stripe column of four coefficients starting at row `y0`
+// is decoded in the run-length mode of the cleanup pass: all the coefficients are insignificant,
+// not visited and have insignificant neighbors.
+func (t *t1Decoder) runLengthMode(x, y0 int) bool {
+	for y := y0; y < y0+4; y++ {
+		i := (y+1)*t.stride + x + 1
+		if t.flags[i]&(flagSignificant|flagVisited) != 0 || t.significanceContext(i, y) != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+// tagTreeUnknown is the value of the tag tree nodes which are not decoded yet.
+const tagTreeUnknown = 1 << 30
+
+// tagTree is a tag tree (B.10.2), used for the code-block inclusion and the number of zero
+// bit-planes in the packet headers.
+type tagTree struct {
+	levels []tagTreeLevel
+}
+
+type tagTreeLevel struct {
+	w, h  int
+	nodes []tagTreeNode
+}
+
+type tagTreeNode struct {
+	value int
+	low   int
+}
+
+// newTagTree returns a tag tree with `w` x `h` leaves.
+func newTagTree(w, h int) *tagTree {
+	tt := &tagTree{}
+	for {
+		level := tagTreeLevel{w: w, h: h, nodes: make([]tagTreeNode, w*h)}
+		for i := range level.nodes {
+			level.nodes[i].value = tagTreeUnknown
+		}
+		tt.levels = append(tt.levels, level)
+		if w <= 1 && h <= 1 {
+			break
+		}
+		w = (w + 1) / 2
+		h = (h + 1) / 2
+	}
+	return tt
+}
+
+// decode decodes the value of the leaf (x, y) up to `threshold` with the bits read from `br`.
+// Returns true if the value of the leaf is smaller than the threshold.
+func (tt *tagTree) decode(br *bitReader, x, y, threshold int) (bool, error) {
+	path := make([]*tagTreeNode, len(tt.levels))
+	for i := range tt.levels {
+		level := &tt.levels[i]
+		path[i] = &level.nodes[(y>>uint(i))*level.w+(x>>uint(i))]
+	}
+	low := 0
+	for i := len(path) - 1; i >= 0; i-- {
+		node := path[i]
+		if low > node.low {
+			node.low = low
+		} else {
+			low = node.low
+		}
+		for low < threshold && low < node.value {
+			bit, err := br.bit()
+			if err != nil {
+				return false, err
+			}
+			if bit == 1 {
+				node.value = low
+			} else {
+				low++
+			}
+		}
+		node.low = low
+	}
+	return path[0].value < threshold, nil
+}
+
+// decodeValue decodes the complete value of the leaf (x, y).
+func (tt *tagTree) decodeValue(br *bitReader, x, y int) (int, error) {
+	if _, err := tt.decode(br, x, y, tagTreeUnknown); err != nil {
+		return 0, err
+	}
+	return tt.levels[0].nodes[y*tt.levels[0].w+x].value, nil
+}
@@ -0,0 +1,278 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package jpx
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Subband orientations (B.5).
+const (
+	bandLL = iota
+	bandHL
+	bandLH
+	bandHH
+)
+
+// tile is a tile of the image, decomposed into tile-components (B.3).
+type tile struct {
+	x0, y0, x1, y1 int
+	style          *codingStyle
+	comps          []*tileComponent
+}
+
+// tileComponent is a component of a tile, decomposed into resolution levels (B.5).
+type tileComponent struct {
+	x0, y0, x1, y1 int
+	style          *componentStyle
+	roiShift       int
+	res            []*resolution
+
+	// data contains the reconstructed samples of the tile-component.
+	data []float32
+}
+
+// resolution is a resolution level of a tile-component, partitioned into precincts (B.6).
+type resolution struct {
+	x0, y0, x1, y1 int
+	ppx, ppy       int
+
+	// The precinct partition of the resolution level: the index of the first precinct and the
+	// number of precincts in each direction.
+	px0, py0 int
+	pw, ph   int
+	bands    []*subband
+}
+
+// subband is a subband of a resolution level, partitioned into code-blocks (B.7).
+type subband struct {
+	orient         int
+	x0, y0, x1, y1 int
+
+	// cbw and cbh are the code-block size exponents of the subband.
+	cbw, cbh int
+
+	// numBitplanes is the number of magnitude bit-planes Mb (E.1), including the region of interest
+	// shift.
+	numBitplanes int
+	delta        float32
+	precincts    []*precinct
+
+	// coeffs contains the dequantized coefficients of the subband.
+	coeffs []float32
+}
+
+// precinct is the part of a precinct in a subband, with the code-blocks and the tag trees used in
+// the packet headers (B.10).
+type precinct struct {
+	nw, nh    int
+	blocks    []*codeblock
+	inclusion *tagTree
+	zeroPlane *tagTree
+}
+
+// codeblock is a code-block, with the coded data received in the packets.
+type codeblock struct {
+	x0, y0, x1, y1 int
+	included       bool
+	lblock         int
+	zeroBitplanes  int
+	passes         int
+	segments       []*codewordSegment
+}
+
+// codewordSegment is a codeword segment of a code-block (D.4.1), which may span several packets.
+type codewordSegment struct {
+	data      []byte
+	passes    int
+	maxPasses int
+}
+
+// newTile builds the structure of tile `t` of the codestream (B.3-B.7).
+func (cs *codestream) newTile(t *tileData) (*tile, error) {
+	s := &cs.size
+	p := t.index % s.numXTiles
+	q := t.index / s.numXTiles
+	tl := &tile{
+		x0:    maxInt(s.tileX0+p*s.tileWidth, s.x0),
+		y0:    maxInt(s.tileY0+q*s.tileHeight, s.y0),
+		x1:    minInt(s.tileX0+(p+1)*s.tileWidth, s.x1),
+		y1:    minInt(s.tileY0+(q+1)*s.tileHeight, s.y1),
+		style: cs.codingStyle(t),
+	}
+	for c, comp := range s.components {
+		style := cs.componentStyle(t, c)
+		quant := cs.quantization(t, c)
+		tc := &tileComponent{
+			x0:       ceilDiv(tl.x0, comp.dx),
+			y0:       ceilDiv(tl.y0, comp.dy),
+			x1:       ceilDiv(tl.x1, comp.dx),
+			y1:       ceilDiv(tl.y1, comp.dy),
+			style:    style,
+			roiShift: cs.roiShift(t, c),
+		}
+		for r := 0; r <= style.levels; r++ {
+			res, err := tc.newResolution(r, comp.depth, quant)
+			if err != nil {
+				return nil, err
+			}
+			tc.res = append(tc.res, res)
+		}
+		tl.comps = append(tl.comps, tc)
+	}
+	return tl, nil
+}
+
+// newResolution builds resolution level `r` of the tile-component, with its subbands, precincts
+// and code-blocks.
+func (tc *tileComponent) newResolution(r, depth int, quant *quantization) (*resolution, error) {
+	nl := tc.style.levels
+	scale := 1 << uint(nl-r)
+	res := &resolution{
+		x0:  ceilDiv(tc.x0, scale),
+		y0:  ceilDiv(tc.y0, scale),
+		x1:  ceilDiv(tc.x1, scale),
+		y1:  ceilDiv(tc.y1, scale),
+		ppx: tc.style.ppx[r],
+		ppy: tc.style.ppy[r],
+	}
+	if res.x1 > res.x0 && res.y1 > res.y0 {
+		res.px0 = res.x0 >> uint(res.ppx)
+		res.py0 = res.y0 >> uint(res.ppy)
+		res.pw = ceilDiv(res.x1, 1<<uint(res.ppx)) - res.px0
+		res.ph = ceilDiv(res.y1, 1<<uint(res.ppy)) - res.py0
+	}
+
+	orients := []int{bandLL}
+	if r > 0 {
+		orients = []int{bandHL, bandLH, bandHH}
+	}
+	for _, orient := range orients {
+		band := &subband{orient: orient}
+
+		// The decomposition level of the subband and its offsets (Equation B-15).
+		nb := nl - r + 1
+		if r == 0 {
+			nb = nl
+		}
+		var xo, yo int
+		if orient == bandHL || orient == bandHH {
+			xo = 1
+		}
+		if orient == bandLH || orient == bandHH {
+			yo = 1
+		}
+		bscale := 1 << uint(nb)
+		half := bscale >> 1
+		band.x0 = ceilDiv(tc.x0-half*xo, bscale)
+		band.y0 = ceilDiv(tc.y0-half*yo, bscale)
+		band.x1 = ceilDiv(tc.x1-half*xo, bscale)
+		band.y1 = ceilDiv(tc.y1-half*yo, bscale)
+
+		if err := band.setQuantization(quant, tc, r, nb, depth); err != nil {
+			return nil, err
+		}
+
+		// The precinct and code-block sizes in the subband (B.7).
+		bpx, bpy := res.ppx, res.ppy
+		if r > 0 {
+			bpx--
+			bpy--
+		}
+		band.cbw = minInt(tc.style.cbw, bpx)
+		band.cbh = minInt(tc.style.cbh, bpy)
+
+		if bw, bh := band.x1-band.x0, band.y1-band.y0; bw > 0 && bh > 0 {
+			band.coeffs = make([]float32, bw*bh)
+		}
+		for j := 0; j < res.ph; j++ {
+			for i := 0; i < res.pw; i++ {
+				prec := &precinct{}
+				x0 := maxInt((res.px0+i)<<uint(bpx), band.x0)
+				y0 := maxInt((res.py0+j)<<uint(bpy), band.y0)
+				x1 := minInt((res.px0+i+1)<<uint(bpx), band.x1)
+				y1 := minInt((res.py0+j+1)<<uint(bpy), band.y1)
+				if x1 > x0 && y1 > y0 {
+					cx0 := x0 >> uint(band.cbw)
+					cy0 := y0 >> uint(band.cbh)
+					prec.nw = ceilDiv(x1, 1<<uint(band.cbw)) - cx0
+					prec.nh = ceilDiv(y1, 1<<uint(band.cbh)) - cy0
+					for cy := 0; cy < prec.nh; cy++ {
+						for cx := 0; cx < prec.nw; cx++ {
+							prec.blocks = append(prec.blocks, &codeblock{
+								x0: maxInt((cx0+cx)<<uint(band.cbw), x0),
+								y0: maxInt((cy0+cy)<<uint(band.cbh), y0),
+								x1: minInt((cx0+cx+1)<<uint(band.cbw), x1),
+								y1: minInt((cy0+cy+1)<<uint(band.cbh), y1),
+							})
+						}
+					}
+					prec.inclusion = newTagTree(prec.nw, prec.nh)
+					prec.zeroPlane = newTagTree(prec.nw, prec.nh)
+				}
+				band.precincts = append(band.precincts, prec)
+			}
+		}
+		res.bands = append(res.bands, band)
+	}
+	return res, nil
+}
+
+// setQuantization sets the number of magnitude bit-planes and the quantization step size of the
+// subband (E.1.1).
+func (band *subband) setQuantization(quant *quantization, tc *tileComponent, r, nb, depth int) error {
+	nl := tc.style.levels
+	idx := 0
+	if r > 0 {
+		idx = 3*(r-1) + band.orient
+	}
+	var exp, mant int
+	if quant.style == quantScalarDerived {
+		// Equation E-5.
+		exp = quant.exponents[0] - nl + nb
+		mant = quant.mantissas[0]
+	} else {
+		if idx >= len(quant.exponents) {
+			return fmt.Errorf("jpx: missing quantization parameters for subband %d", idx)
+		}
+		exp = quant.exponents[idx]
+		mant = quant.mantissas[idx]
+	}
+	band.numBitplanes = quant.guard + exp - 1 + tc.roiShift
+	if band.numBitplanes > 31 {
+		return errors.New("jpx: unsupported number of bit-planes")
+	}
+
+	band.delta = 1
+	if !tc.style.reversible {
+		// The nominal dynamic range of the subband (Equation E-4) and the step size (Equation E-3).
+		gain := 0
+		switch band.orient {
+		case bandHL, bandLH:
+			gain = 1
+		case bandHH:
+			gain = 2
+		}
+		band.delta = float32(math.Pow(2, float64(depth+gain-exp)) * (1 + float64(mant)/2048))
+	}
+	return nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -35,6 +35,28 @@ func TestAngle(t *testing.T) {
 	}
 }
 
+// TestTransform tests the Matrix.Transform() function.
+func TestTransform(t *testing.T) {
+	tests := []struct {
+		params
+		x, y, xp, yp float64
+	}{
+		{params{1, 0, 0, 1, 10, 20}, 1, 2, 11, 22},
+		{params{2, 0, 0, 3, 0, 0}, 1, 2, 2, 6},
+		{params{0, 1, -1, 0, 0, 0}, 1, 0, 0, 1},
+		{params{0, -1, 1, 0, 0, 100}, 10, 20, 20, 90},
+	}
+	for _, test := range tests {
+		p := test.params
+		m := NewMatrix(p.a, p.b, p.c, p.d, p.tx, p.ty)
+		xp, yp := m.Transform(test.x, test.y)
+		if xp != test.xp || yp != test.yp {
+			t.Fatalf("Bad transform: m=%s (%g,%g) expected=(%g,%g) actual=(%g,%g)",
+				m, test.x, test.y, test.xp, test.yp, xp, yp)
+		}
+	}
+}
+
 type params struct{ a, b, c, d, tx, ty float64 }
 type angleCase struct {
 	params         // Affine transform.
@@ -7,7 +7,6 @@ package model
 
 import (
 	"fmt"
-	"github.com/unidoc/unipdf/v3/common"
 	"github.com/unidoc/unipdf/v3/core"
 )
 
@@ -668,12 +667,12 @@ func (r *PdfReader) newPdfActionFromIndirectObject(container *core.PdfIndirectOb
 	if obj := d.Get("Type"); obj != nil {
 		str, ok := obj.(*core.PdfObjectName)
 		if !ok {
-			common.Log.Trace("Incompatibility! Invalid type of Type (%T) - should be Name", obj)
+			r.log().Trace("Incompatibility! Invalid type of Type (%T) - should be Name", obj)
 		} else {
 			if *str != "Action" {
 				// Log a debug message.
 				// Not returning an error on this.
-				common.Log.Trace("Unsuspected Type != Action (%s)", *str)
+				r.log().Trace("Unsuspected Type != Action (%s)", *str)
 			}
 			action.Type = str
 		}
@@ -689,7 +688,7 @@ func (r *PdfReader) newPdfActionFromIndirectObject(container *core.PdfIndirectOb
 
 	actionName, ok := action.S.(*core.PdfObjectName)
 	if !ok {
-		common.Log.Debug("ERROR: Invalid S object type != name (%T)", action.S)
+		r.log().Debug("ERROR: Invalid S object type != name (%T)", action.S)
 		return nil, fmt.Errorf("invalid S object type != name (%T)", action.S)
 	}
 
@@ -841,7 +840,7 @@ func (r *PdfReader) newPdfActionFromIndirectObject(container *core.PdfIndirectOb
 		return action, nil
 	}
 
-	common.Log.Debug("ERROR: Ignoring unknown action: %s", actionType)
+	r.log().Debug("ERROR: Ignoring unknown action: %s", actionType)
 	return nil, nil
 }
 
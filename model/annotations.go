@@ -701,12 +701,12 @@ func (r *PdfReader) newPdfAnnotationFromIndirectObject(container *core.PdfIndire
 	if obj := d.Get("Type"); obj != nil {
 		str, ok := obj.(*core.PdfObjectName)
 		if !ok {
-			common.Log.Trace("Incompatibility! Invalid type of Type (%T) - should be Name", obj)
+			r.log().Trace("Incompatibility! Invalid type of Type (%T) - should be Name", obj)
 		} else {
 			if *str != "Annot" {
 				// Log a debug message.
 				// Not returning an error on this.
-				common.Log.Trace("Unsuspected Type != Annot (%s)", *str)
+				r.log().Trace("Unsuspected Type != Annot (%s)", *str)
 			}
 		}
 	}
@@ -761,13 +761,13 @@ func (r *PdfReader) newPdfAnnotationFromIndirectObject(container *core.PdfIndire
 
 	subtypeObj := d.Get("Subtype")
 	if subtypeObj == nil {
-		common.Log.Debug("WARNING: Compatibility issue - annotation Subtype missing - assuming no subtype")
+		r.log().Debug("WARNING: Compatibility issue - annotation Subtype missing - assuming no subtype")
 		annot.context = nil
 		return annot, nil
 	}
 	subtype, ok := subtypeObj.(*core.PdfObjectName)
 	if !ok {
-		common.Log.Debug("ERROR: Invalid Subtype object type != name (%T)", subtypeObj)
+		r.log().Debug("ERROR: Invalid Subtype object type != name (%T)", subtypeObj)
 		return nil, fmt.Errorf("invalid Subtype object type != name (%T)", subtypeObj)
 	}
 	switch *subtype {
@@ -802,9 +802,9 @@ func (r *PdfReader) newPdfAnnotationFromIndirectObject(container *core.PdfIndire
 		}
 		ctx.PdfAnnotation = annot
 		annot.context = ctx
-		common.Log.Trace("LINE ANNOTATION: annot (%T): %+v\n", annot, annot)
-		common.Log.Trace("LINE ANNOTATION: ctx (%T): %+v\n", ctx, ctx)
-		common.Log.Trace("LINE ANNOTATION Markup: ctx (%T): %+v\n", ctx.PdfAnnotationMarkup, ctx.PdfAnnotationMarkup)
+		r.log().Trace("LINE ANNOTATION: annot (%T): %+v\n", annot, annot)
+		r.log().Trace("LINE ANNOTATION: ctx (%T): %+v\n", ctx, ctx)
+		r.log().Trace("LINE ANNOTATION Markup: ctx (%T): %+v\n", ctx.PdfAnnotationMarkup, ctx.PdfAnnotationMarkup)
 
 		return annot, nil
 	case "Square":
@@ -1001,7 +1001,7 @@ func (r *PdfReader) newPdfAnnotationFromIndirectObject(container *core.PdfIndire
 		return annot, nil
 	}
 
-	common.Log.Debug("ERROR: Ignoring unknown annotation: %s", *subtype)
+	r.log().Debug("ERROR: Ignoring unknown annotation: %s", *subtype)
 	return nil, nil
 }
 
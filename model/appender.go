@@ -43,6 +43,27 @@ type PdfAppender struct {
 	// creation (NewPdfAppender).
 	traversed map[core.PdfObject]struct{}
 
+	// Document information and XMP metadata of the new revision.
+	info *PdfInfo
+	xmp  *XMPMetadata
+
+	// Embedded files added and removed, and portable collection of the new revision.
+	embeddedFiles        []*PdfEmbeddedFile
+	removedEmbeddedFiles map[string]struct{}
+	collection           *PdfCollection
+
+	// Structure tree of the new revision.
+	structTreeRoot *PdfStructTreeRoot
+
+	// Document security store of the new revision.
+	dss *PdfDSS
+
+	// Certification signature of the new revision, referenced by the Perms of the catalog.
+	certification *PdfSignature
+
+	// Signature left unsigned by WriteDeferred.
+	deferred *deferredWrite
+
 	prevRevisionSize int64
 	written          bool
 }
@@ -133,7 +154,8 @@ func NewPdfAppender(reader *PdfReader) (*PdfAppender, error) {
 	// of objects that have been changes. Objects from the original reader are not appended, only
 	// new objects that modify the PDF. The change detection check is not resource demanding. It
 	// only checks owners (source) of indirect objects.
-	a.roReader, err = NewPdfReader(a.rs)
+	// Its messages are logged without recording the diagnostics, already recorded by the reader.
+	a.roReader, err = NewPdfReaderWithOpts(a.rs, &ReaderOpts{Logger: reader.diagnostics.Logger()})
 	if err != nil {
 		return nil, err
 	}
@@ -170,7 +192,7 @@ func (a *PdfAppender) updateObjectsDeep(obj core.PdfObject, processed map[core.P
 
 	err := core.ResolveReferencesDeep(obj, a.traversed)
 	if err != nil {
-		common.Log.Debug("ERROR: %v", err)
+		a.log().Debug("ERROR: %v", err)
 	}
 
 	switch v := obj.(type) {
@@ -280,7 +302,7 @@ func (a *PdfAppender) mergeResources(dest, src core.PdfObject, resourcesRenameMa
 
 	destDict, ok := core.GetDict(dest)
 	if !ok {
-		common.Log.Error("Error resource is not a dictionary")
+		a.log().Error("Error resource is not a dictionary")
 		destDict = core.MakeDict()
 	}
 
@@ -408,9 +430,12 @@ func (a *PdfAppender) MergePageWith(pageNum int, page *PdfPage) error {
 }
 
 // AddPages adds pages to be appended to the end of the source PDF.
+// The StructParents entries of the pages, which refer to the structure tree of
+// their source documents, are removed.
 func (a *PdfAppender) AddPages(pages ...*PdfPage) {
 	for _, page := range pages {
 		page = page.Duplicate()
+		page.StructParents = nil
 		procPage(page)
 		a.pages = append(a.pages, page)
 	}
@@ -483,6 +508,18 @@ func (a *PdfAppender) Sign(pageNum int, field *PdfFieldSignature) error {
 	}
 	page := a.Reader.PageList[pageIndex]
 
+	// The signature and the appender are only changed once the signature can be added.
+	refs, err := a.signatureTransforms(field)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		signature.addReference(ref)
+	}
+	if signature.docMDP != 0 {
+		a.certification = signature
+	}
+
 	// Add signature field annotations to the page annotations.
 	field.P = page.ToPdfObject()
 	if field.T == nil || field.T.String() == "" {
@@ -511,6 +548,104 @@ func (a *PdfAppender) Sign(pageNum int, field *PdfFieldSignature) error {
 	return nil
 }
 
+// signatureTransforms returns the signature references to add to the Reference of the signature
+// of `field`: the DocMDP transform of a certification signature and the FieldMDP transform of the
+// lock of `field`. An error is returned if the signature cannot be added to the document.
+func (a *PdfAppender) signatureTransforms(field *PdfFieldSignature) ([]*core.PdfObjectDictionary, error) {
+	var refs []*core.PdfObjectDictionary
+	signature := field.V
+	if signature.docMDP != 0 {
+		if signature.docMDP < DocMDPNoChanges || signature.docMDP > DocMDPAnnotate {
+			return nil, fmt.Errorf("invalid DocMDP permission %d", signature.docMDP)
+		}
+		if a.certification != nil || a.Reader.certificationPermission() != 0 {
+			return nil, errors.New("document already certified")
+		}
+		// The signatures added by the appender are in its form, a new one if the original
+		// document has none.
+		for _, acroForm := range []*PdfAcroForm{a.Reader.AcroForm, a.acroForm} {
+			if acroForm == nil {
+				continue
+			}
+			for _, f := range acroForm.AllFields() {
+				if sigField, ok := f.GetContext().(*PdfFieldSignature); ok && sigField.V != nil {
+					return nil, errors.New("certification signature must be the first signature of the document")
+				}
+			}
+		}
+
+		params := core.MakeDict()
+		params.Set("P", core.MakeInteger(int64(signature.docMDP)))
+		refs = append(refs, makeSignatureReference("DocMDP", params))
+	}
+
+	if field.Lock != nil {
+		lock, ok := core.GetDict(field.Lock)
+		if !ok {
+			return nil, errors.New("signature field lock not a dictionary")
+		}
+		params := core.MakeDict()
+		params.Set("Action", lock.Get("Action"))
+		params.SetIfNotNil("Fields", lock.Get("Fields"))
+		refs = append(refs, makeSignatureReference("FieldMDP", params))
+	}
+	return refs, nil
+}
+
+// SetDocInfo sets the document information dictionary of the new revision. If the original
+// document has XMP metadata, it is updated to match unless set with SetXMPMetadata.
+func (a *PdfAppender) SetDocInfo(info *PdfInfo) {
+	a.info = info
+}
+
+// SetXMPMetadata sets the XMP metadata packet of the new revision. The properties corresponding to
+// the document information dictionary are updated from it in a copy of `md` when writing.
+func (a *PdfAppender) SetXMPMetadata(md *XMPMetadata) {
+	a.xmp = md
+}
+
+// AddEmbeddedFile adds file `f` to the embedded files of the document, replacing the file with the
+// same name.
+func (a *PdfAppender) AddEmbeddedFile(f *PdfEmbeddedFile) error {
+	if err := checkEmbeddedFile(f, a.embeddedFiles); err != nil {
+		return err
+	}
+	a.embeddedFiles = append(a.embeddedFiles, f)
+	return nil
+}
+
+// RemoveEmbeddedFile removes the file named `name` from the embedded files of the document.
+func (a *PdfAppender) RemoveEmbeddedFile(name string) {
+	for i, f := range a.embeddedFiles {
+		if f.Name == name {
+			a.embeddedFiles = append(a.embeddedFiles[:i], a.embeddedFiles[i+1:]...)
+			break
+		}
+	}
+	if a.removedEmbeddedFiles == nil {
+		a.removedEmbeddedFiles = map[string]struct{}{}
+	}
+	a.removedEmbeddedFiles[name] = struct{}{}
+}
+
+// SetCollection sets the portable collection (PDF portfolio) of the document.
+func (a *PdfAppender) SetCollection(collection *PdfCollection) {
+	a.collection = collection
+}
+
+// SetDSS sets the document security store of the new revision, holding the validation material
+// of the signatures. The signatures of the original document remain valid as the store is written
+// in an incremental update.
+func (a *PdfAppender) SetDSS(dss *PdfDSS) {
+	a.dss = dss
+}
+
+// SetStructTreeRoot sets the structure tree of the document, e.g. the tree of the Reader with
+// new elements. The content of the removed pages is removed from the tree when writing.
+func (a *PdfAppender) SetStructTreeRoot(root *PdfStructTreeRoot) {
+	a.structTreeRoot = root
+}
+
 // ReplaceAcroForm replaces the acrobat form. It appends a new form to the Pdf which
 // replaces the original AcroForm.
 func (a *PdfAppender) ReplaceAcroForm(acroForm *PdfAcroForm) {
@@ -520,6 +655,56 @@ func (a *PdfAppender) ReplaceAcroForm(acroForm *PdfAcroForm) {
 	a.acroForm = acroForm
 }
 
+// updateStructTree updates the structure tree of `catalog` if set with SetStructTreeRoot or if
+// pages with content in the tree of the original document have been removed.
+func (a *PdfAppender) updateStructTree(catalog *core.PdfObjectDictionary) error {
+	// Object numbers of the removed pages.
+	removedPages := map[int64]struct{}{}
+	for _, p := range a.roReader.PageList {
+		removedPages[p.GetPageAsIndirectObject().ObjectNumber] = struct{}{}
+	}
+	for _, p := range a.pages {
+		pageObj := p.GetPageAsIndirectObject()
+		if parser := pageObj.GetParser(); parser == a.roReader.parser || parser == a.Reader.parser {
+			delete(removedPages, pageObj.ObjectNumber)
+		}
+	}
+	removed := func(page *core.PdfIndirectObject) bool {
+		if parser := page.GetParser(); parser != a.roReader.parser && parser != a.Reader.parser {
+			return false
+		}
+		_, isRemoved := removedPages[page.ObjectNumber]
+		return isRemoved
+	}
+
+	root := a.structTreeRoot
+	if root == nil {
+		if len(removedPages) == 0 || catalog.Get("StructTreeRoot") == nil {
+			return nil
+		}
+		var err error
+		root, err = a.Reader.GetStructTreeRoot()
+		if err != nil {
+			return err
+		}
+		if !root.removePages(removed) {
+			return nil
+		}
+	} else {
+		root.removePages(removed)
+	}
+
+	obj := root.ToPdfObject()
+	catalog.Set("StructTreeRoot", obj)
+	a.updateObjectsDeep(obj, nil)
+	return nil
+}
+
+// log returns the logger of the appender: the logger of its reader.
+func (a *PdfAppender) log() common.Logger {
+	return a.Reader.log()
+}
+
 // Write writes the Appender output to io.Writer.
 // It can only be called once and further invocations will result in an error.
 func (a *PdfAppender) Write(w io.Writer) error {
@@ -528,6 +713,7 @@ func (a *PdfAppender) Write(w io.Writer) error {
 	}
 
 	writer := NewPdfWriter()
+	writer.SetLogger(a.log())
 
 	pagesDict, ok := core.GetDict(writer.pages)
 	if !ok {
@@ -554,7 +740,7 @@ func (a *PdfAppender) Write(w io.Writer) error {
 	}
 	catalog, ok := core.GetDict(catalogContainer)
 	if !ok {
-		common.Log.Debug("ERROR: Missing catalog: (root %q) (trailer %s)", catalogContainer, *trailer)
+		a.log().Debug("ERROR: Missing catalog: (root %q) (trailer %s)", catalogContainer, *trailer)
 		return errors.New("missing catalog")
 	}
 
@@ -565,11 +751,74 @@ func (a *PdfAppender) Write(w io.Writer) error {
 			writer.catalog.Set(key, obj)
 		}
 	}
+	// Update the destinations referring to moved pages by page index.
+	a.remapPageIndices(writer.catalog)
+
 	if a.acroForm != nil {
 		writer.catalog.Set("AcroForm", a.acroForm.ToPdfObject())
 		a.updateObjectsDeep(a.acroForm.ToPdfObject(), nil)
 	}
 
+	if len(a.embeddedFiles) > 0 || len(a.removedEmbeddedFiles) > 0 {
+		objects, err := updateEmbeddedFiles(writer.catalog, a.embeddedFiles, a.removedEmbeddedFiles)
+		if err != nil {
+			return err
+		}
+		for _, obj := range objects {
+			a.updateObjectsDeep(obj, nil)
+		}
+	}
+	if a.collection != nil {
+		collection := a.collection.ToPdfObject()
+		writer.catalog.Set("Collection", collection)
+		a.updateObjectsDeep(collection, nil)
+	}
+
+	if err := a.updateStructTree(writer.catalog); err != nil {
+		return err
+	}
+
+	if a.dss != nil {
+		dss := a.dss.ToPdfObject()
+		writer.catalog.Set("DSS", dss)
+		a.updateObjectsDeep(dss, nil)
+	}
+
+	if a.certification != nil {
+		perms := core.MakeDict()
+		if orig, ok := core.GetDict(core.ResolveReference(writer.catalog.Get("Perms"))); ok {
+			perms.Merge(orig)
+		}
+		perms.Set("DocMDP", a.certification.ToPdfObject())
+		writer.catalog.Set("Perms", perms)
+	}
+
+	if a.info != nil {
+		writer.SetDocInfo(a.info)
+	}
+	// Keep the XMP metadata of the original document in sync with the new information.
+	var xmp *XMPMetadata
+	if a.xmp != nil {
+		xmp = a.xmp.Copy()
+	} else if a.info != nil {
+		var err error
+		xmp, err = a.roReader.GetXMPMetadata()
+		if err != nil {
+			a.log().Debug("ERROR: Invalid original XMP metadata - not updated: %v", err)
+			xmp = nil
+		}
+	}
+	if xmp != nil {
+		info, err := NewPdfInfoFromObject(writer.infoObj)
+		if err != nil {
+			return err
+		}
+		xmp.SetPdfInfo(info)
+		metadata := xmp.ToPdfObject()
+		writer.catalog.Set("Metadata", metadata)
+		a.addNewObject(metadata)
+	}
+
 	a.addNewObject(writer.infoObj)
 	a.addNewObject(writer.root)
 
@@ -617,26 +866,26 @@ func (a *PdfAppender) Write(w io.Writer) error {
 		if pDict, ok := core.GetDict(obj); ok {
 			parent, hasParent := pDict.Get("Parent").(*core.PdfIndirectObject)
 			for hasParent {
-				common.Log.Trace("Page Parent: %T", parent)
+				a.log().Trace("Page Parent: %T", parent)
 				parentDict, ok := parent.PdfObject.(*core.PdfObjectDictionary)
 				if !ok {
 					return errors.New("invalid Parent object")
 				}
 				for _, field := range inheritedFields {
-					common.Log.Trace("Field %s", field)
+					a.log().Trace("Field %s", field)
 					if pDict.Get(field) != nil {
-						common.Log.Trace("- page has already")
+						a.log().Trace("- page has already")
 						continue
 					}
 
 					if obj := parentDict.Get(field); obj != nil {
 						// Parent has the field.  Inherit, pass to the new page.
-						common.Log.Trace("Inheriting field %s", field)
+						a.log().Trace("Inheriting field %s", field)
 						pDict.Set(field, obj)
 					}
 				}
 				parent, hasParent = parentDict.Get("Parent").(*core.PdfIndirectObject)
-				common.Log.Trace("Next parent: %T", parentDict.Get("Parent"))
+				a.log().Trace("Next parent: %T", parentDict.Get("Parent"))
 			}
 			pDict.Set("Parent", writer.pages)
 		}
@@ -662,6 +911,13 @@ func (a *PdfAppender) Write(w io.Writer) error {
 				if err != nil {
 					return err
 				}
+				if a.deferred != nil {
+					if byteRange.Len() > 0 {
+						return errors.New("deferred signing requires a single signature")
+					}
+					// The digest is signed externally.
+					digestWriters[handler] = a.deferred.digest
+				}
 				byteRange.Append(core.MakeInteger(0xfffff), core.MakeInteger(0xfffff))
 			}
 		}
@@ -789,7 +1045,9 @@ func (a *PdfAppender) Write(w io.Writer) error {
 			bufferOffset := int(sigDict.fileOffset - offset)
 			handler := *sigDict.handler
 			digest := digestWriters[handler]
-			if err := handler.Sign(sigDict.signature, digest); err != nil {
+			if a.deferred != nil {
+				a.deferred.byteRange = byteRange
+			} else if err := handler.Sign(sigDict.signature, digest); err != nil {
 				return err
 			}
 			sigDict.signature.ByteRange = byteRange
@@ -182,6 +182,54 @@ func TestAppenderRemovePage(t *testing.T) {
 	}
 }
 
+func TestAppenderRevisions(t *testing.T) {
+	f, err := os.Open(testPdf3pages)
+	require.NoError(t, err)
+	defer f.Close()
+
+	reader, err := model.NewPdfReader(f)
+	require.NoError(t, err)
+	origObjNums := reader.GetObjectNums()
+	origRevisions := reader.GetRevisions()
+	require.Len(t, origRevisions, 2)
+
+	appender, err := model.NewPdfAppender(reader)
+	require.NoError(t, err)
+	appender.RemovePage(1)
+
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, appender.Write(buf))
+
+	reader, err = model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	// The input already contains an incremental update, the appended revision is the third one.
+	revisions := reader.GetRevisions()
+	require.Len(t, revisions, 3)
+	require.Equal(t, origObjNums, revisions[0].Added)
+	require.Empty(t, revisions[1].Added)
+	require.Equal(t, origRevisions[1].StartXref, revisions[1].StartXref)
+	require.Len(t, revisions[2].Added, 2)
+	require.NotEmpty(t, revisions[2].Changed)
+	require.Equal(t, int64(buf.Len()), revisions[2].EndOffset)
+
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, 2, numPages)
+
+	// Load the revision prior to the update.
+	origReader, err := reader.GetRevision(1)
+	require.NoError(t, err)
+	require.Equal(t, origObjNums, origReader.GetObjectNums())
+	require.Len(t, origReader.GetRevisions(), 2)
+	numPages, err = origReader.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, 3, numPages)
+
+	_, err = reader.GetRevision(3)
+	require.Error(t, err)
+}
+
 func TestAppenderReplacePage(t *testing.T) {
 	f1, err := os.Open(testPdf3pages)
 	if err != nil {
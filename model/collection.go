@@ -0,0 +1,242 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// PdfCollectionView specifies how the files of a portable collection are initially presented.
+type PdfCollectionView string
+
+// Portable collection views.
+const (
+	// CollectionViewDetails presents the files in a table with the fields of the schema.
+	CollectionViewDetails PdfCollectionView = "D"
+	// CollectionViewTile presents the files as small icons with a subset of information.
+	CollectionViewTile PdfCollectionView = "T"
+	// CollectionViewHidden initially hides the collection user interface.
+	CollectionViewHidden PdfCollectionView = "H"
+)
+
+// Subtypes of the fields of a collection schema. The values of the fields of subtype S, D and N
+// are taken from the collection items of the files, the others from the files themselves.
+const (
+	CollectionFieldText         = "S"
+	CollectionFieldDate         = "D"
+	CollectionFieldNumber       = "N"
+	CollectionFieldFileName     = "F"
+	CollectionFieldDescription  = "Desc"
+	CollectionFieldModDate      = "ModDate"
+	CollectionFieldCreationDate = "CreationDate"
+	CollectionFieldSize         = "Size"
+)
+
+// PdfCollectionField represents a field of the schema of a portable collection (Table 156).
+type PdfCollectionField struct {
+	// Key is the key of the field in the schema and in the collection items of the files.
+	Key string
+
+	// Name is the name of the field presented to the user.
+	Name string
+
+	// Subtype is the type of the data of the field, one of the CollectionField constants.
+	Subtype string
+
+	// Order is the relative order of the field in the user interface.
+	Order int
+
+	// Hidden hides the field in the user interface.
+	Hidden bool
+
+	// Editable allows the user to edit the values of the field.
+	Editable bool
+}
+
+// PdfCollectionSort represents a sort key of a portable collection.
+type PdfCollectionSort struct {
+	// Key is the key of a field of the schema.
+	Key string
+
+	// Ascending sorts in ascending order, otherwise in descending order.
+	Ascending bool
+}
+
+// PdfCollection represents the collection dictionary of a portable collection, or PDF portfolio
+// (12.3.5 Collections). The files of the collection are the embedded files of the document.
+type PdfCollection struct {
+	Schema []*PdfCollectionField
+	Sort   []PdfCollectionSort
+
+	// View is the initial view of the collection, details by default.
+	View PdfCollectionView
+
+	// InitialDocument is the name of the embedded file initially presented, the document itself
+	// if empty.
+	InitialDocument string
+
+	container *core.PdfObjectDictionary
+}
+
+// NewPdfCollection returns a new empty collection.
+func NewPdfCollection() *PdfCollection {
+	return &PdfCollection{container: core.MakeDict()}
+}
+
+// AddField adds a field to the schema of the collection, ordered after the existing fields.
+func (c *PdfCollection) AddField(key, name, subtype string) *PdfCollectionField {
+	field := &PdfCollectionField{
+		Key:     key,
+		Name:    name,
+		Subtype: subtype,
+		Order:   len(c.Schema) + 1,
+	}
+	c.Schema = append(c.Schema, field)
+	return field
+}
+
+// newPdfCollectionFromObject loads a collection from collection dictionary `obj`.
+func newPdfCollectionFromObject(obj core.PdfObject) (*PdfCollection, error) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		common.Log.Debug("ERROR: Collection not a dictionary (%T)", obj)
+		return nil, ErrTypeCheck
+	}
+
+	c := &PdfCollection{container: dict}
+	if view, ok := core.GetNameVal(dict.Get("View")); ok {
+		c.View = PdfCollectionView(view)
+	}
+	if d, ok := core.GetString(dict.Get("D")); ok {
+		c.InitialDocument = d.Str()
+	}
+
+	if schema, ok := core.GetDict(dict.Get("Schema")); ok {
+		for _, key := range schema.Keys() {
+			fieldDict, ok := core.GetDict(schema.Get(key))
+			if !ok {
+				// E.g. the Type entry.
+				continue
+			}
+			field := &PdfCollectionField{Key: string(key)}
+			if name, ok := core.GetString(fieldDict.Get("N")); ok {
+				field.Name = name.Decoded()
+			}
+			field.Subtype, _ = core.GetNameVal(fieldDict.Get("Subtype"))
+			if order, ok := core.GetIntVal(fieldDict.Get("O")); ok {
+				field.Order = order
+			}
+			if v, ok := core.GetBoolVal(fieldDict.Get("V")); ok {
+				field.Hidden = !v
+			}
+			field.Editable, _ = core.GetBoolVal(fieldDict.Get("E"))
+			c.Schema = append(c.Schema, field)
+		}
+	}
+
+	if sort, ok := core.GetDict(dict.Get("Sort")); ok {
+		var keys, ascending []core.PdfObject
+		if arr, ok := core.GetArray(sort.Get("S")); ok {
+			keys = arr.Elements()
+		} else if sort.Get("S") != nil {
+			keys = []core.PdfObject{sort.Get("S")}
+		}
+		if arr, ok := core.GetArray(sort.Get("A")); ok {
+			ascending = arr.Elements()
+		} else if sort.Get("A") != nil {
+			ascending = []core.PdfObject{sort.Get("A")}
+		}
+		for i, obj := range keys {
+			key, ok := core.GetNameVal(obj)
+			if !ok {
+				continue
+			}
+			// Ascending by default, the last value applies to the following keys.
+			s := PdfCollectionSort{Key: key, Ascending: true}
+			if len(ascending) > 0 {
+				j := i
+				if j >= len(ascending) {
+					j = len(ascending) - 1
+				}
+				if asc, ok := core.GetBoolVal(ascending[j]); ok {
+					s.Ascending = asc
+				}
+			}
+			c.Sort = append(c.Sort, s)
+		}
+	}
+	return c, nil
+}
+
+// GetContainingPdfObject returns the collection dictionary.
+func (c *PdfCollection) GetContainingPdfObject() core.PdfObject {
+	return c.container
+}
+
+// ToPdfObject returns the collection dictionary.
+func (c *PdfCollection) ToPdfObject() core.PdfObject {
+	d := c.container
+	d.Clear()
+	d.Set("Type", core.MakeName("Collection"))
+
+	if len(c.Schema) > 0 {
+		schema := core.MakeDict()
+		schema.Set("Type", core.MakeName("CollectionSchema"))
+		for _, field := range c.Schema {
+			fieldDict := core.MakeDict()
+			fieldDict.Set("Type", core.MakeName("CollectionField"))
+			fieldDict.Set("Subtype", core.MakeName(field.Subtype))
+			fieldDict.Set("N", makeTextString(field.Name))
+			fieldDict.Set("O", core.MakeInteger(int64(field.Order)))
+			if field.Hidden {
+				fieldDict.Set("V", core.MakeBool(false))
+			}
+			if field.Editable {
+				fieldDict.Set("E", core.MakeBool(true))
+			}
+			schema.Set(core.PdfObjectName(field.Key), fieldDict)
+		}
+		d.Set("Schema", schema)
+	}
+
+	if len(c.Sort) > 0 {
+		sort := core.MakeDict()
+		sort.Set("Type", core.MakeName("CollectionSort"))
+		if len(c.Sort) == 1 {
+			sort.Set("S", core.MakeName(c.Sort[0].Key))
+			sort.Set("A", core.MakeBool(c.Sort[0].Ascending))
+		} else {
+			keys := core.MakeArray()
+			ascending := core.MakeArray()
+			for _, s := range c.Sort {
+				keys.Append(core.MakeName(s.Key))
+				ascending.Append(core.MakeBool(s.Ascending))
+			}
+			sort.Set("S", keys)
+			sort.Set("A", ascending)
+		}
+		d.Set("Sort", sort)
+	}
+
+	if c.View != "" {
+		d.Set("View", core.MakeName(string(c.View)))
+	}
+	if c.InitialDocument != "" {
+		d.Set("D", core.MakeString(c.InitialDocument))
+	}
+	return d
+}
+
+// GetCollection returns the collection of the document if it is a portable collection (PDF
+// portfolio), nil otherwise.
+func (r *PdfReader) GetCollection() (*PdfCollection, error) {
+	obj, err := r.getCatalogEntry("Collection")
+	if err != nil || obj == nil {
+		return nil, err
+	}
+	return newPdfCollectionFromObject(obj)
+}
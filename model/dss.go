@@ -0,0 +1,348 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// PdfVRI represents the validation related information of a signature in the document security
+// store (12.8.4.4 in PDF 2.0). The streams are shared with the DSS arrays.
+type PdfVRI struct {
+	Cert []*core.PdfObjectStream
+	OCSP []*core.PdfObjectStream
+	CRL  []*core.PdfObjectStream
+
+	// TU is the time at which the information was collected, unset if zero.
+	TU time.Time
+
+	// TS is the time-stamp token of the information, if any.
+	TS *core.PdfObjectStream
+}
+
+// PdfDSS represents the document security store of a document (12.8.4.3 in PDF 2.0), holding the
+// validation material of its signatures (certificates, OCSP responses and CRLs) for their
+// long-term validation. The streams contain the DER-encoded data.
+type PdfDSS struct {
+	Certs []*core.PdfObjectStream
+	OCSPs []*core.PdfObjectStream
+	CRLs  []*core.PdfObjectStream
+
+	// VRI maps the keys of the signatures (see VRIKey) to their validation related information.
+	VRI map[string]*PdfVRI
+
+	container *core.PdfIndirectObject
+
+	// streams maps the decoded data of the streams to them, to store the same data only once.
+	streams map[string]*core.PdfObjectStream
+}
+
+// NewPdfDSS returns a new empty document security store.
+func NewPdfDSS() *PdfDSS {
+	return &PdfDSS{
+		VRI:       map[string]*PdfVRI{},
+		container: core.MakeIndirectObject(core.MakeDict()),
+		streams:   map[string]*core.PdfObjectStream{},
+	}
+}
+
+// VRIKey returns the key of the validation related information of signature `sig` in the
+// document security store: the upper case hexadecimal SHA-1 digest of its Contents.
+func VRIKey(sig *PdfSignature) string {
+	if sig == nil || sig.Contents == nil {
+		return ""
+	}
+	digest := sha1.Sum(sig.Contents.Bytes())
+	return strings.ToUpper(hex.EncodeToString(digest[:]))
+}
+
+// newPdfDSSFromObject loads a document security store from the DSS dictionary `obj`, logging its
+// problems to `logger`.
+func newPdfDSSFromObject(obj core.PdfObject, logger common.Logger) (*PdfDSS, error) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		logger.Debug("ERROR: DSS not a dictionary (%T)", obj)
+		return nil, ErrTypeCheck
+	}
+
+	dss := NewPdfDSS()
+	if ind, ok := obj.(*core.PdfIndirectObject); ok {
+		dss.container = ind
+	}
+	var err error
+	if dss.Certs, err = dss.loadStreams(dict.Get("Certs"), logger); err != nil {
+		return nil, err
+	}
+	if dss.OCSPs, err = dss.loadStreams(dict.Get("OCSPs"), logger); err != nil {
+		return nil, err
+	}
+	if dss.CRLs, err = dss.loadStreams(dict.Get("CRLs"), logger); err != nil {
+		return nil, err
+	}
+
+	if vriDict, ok := core.GetDict(dict.Get("VRI")); ok {
+		for _, key := range vriDict.Keys() {
+			d, ok := core.GetDict(vriDict.Get(key))
+			if !ok {
+				logger.Debug("ERROR: VRI entry %s not a dictionary", key)
+				return nil, ErrTypeCheck
+			}
+			vri := &PdfVRI{}
+			if vri.Cert, err = dss.loadStreams(d.Get("Cert"), logger); err != nil {
+				return nil, err
+			}
+			if vri.OCSP, err = dss.loadStreams(d.Get("OCSP"), logger); err != nil {
+				return nil, err
+			}
+			if vri.CRL, err = dss.loadStreams(d.Get("CRL"), logger); err != nil {
+				return nil, err
+			}
+			if tu, ok := core.GetString(d.Get("TU")); ok {
+				if date, err := NewPdfDate(tu.Str()); err == nil {
+					vri.TU = date.ToGoTime()
+				}
+			}
+			vri.TS, _ = core.GetStream(d.Get("TS"))
+			dss.VRI[strings.ToUpper(string(key))] = vri
+		}
+	}
+	return dss, nil
+}
+
+// loadStreams returns the streams of the array `obj` and indexes their data.
+func (d *PdfDSS) loadStreams(obj core.PdfObject, logger common.Logger) ([]*core.PdfObjectStream, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	arr, ok := core.GetArray(obj)
+	if !ok {
+		logger.Debug("ERROR: DSS entry not an array (%T)", obj)
+		return nil, ErrTypeCheck
+	}
+	var streams []*core.PdfObjectStream
+	for _, o := range arr.Elements() {
+		stream, ok := core.GetStream(o)
+		if !ok {
+			logger.Debug("ERROR: DSS array element not a stream (%T)", o)
+			return nil, ErrTypeCheck
+		}
+		data, err := core.DecodeStream(stream)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := d.streams[string(data)]; !ok {
+			d.streams[string(data)] = stream
+		}
+		streams = append(streams, stream)
+	}
+	return streams, nil
+}
+
+// addStreams adds the streams of `data` to `streams` if not already present. It returns the
+// streams of `data`, the existing ones when the same data has already been stored.
+func (d *PdfDSS) addStreams(streams *[]*core.PdfObjectStream, data [][]byte) ([]*core.PdfObjectStream, error) {
+	var added []*core.PdfObjectStream
+	for _, b := range data {
+		stream, ok := d.streams[string(b)]
+		if !ok {
+			var err error
+			stream, err = core.MakeStream(b, core.NewFlateEncoder())
+			if err != nil {
+				return nil, err
+			}
+			d.streams[string(b)] = stream
+		}
+		if !containsStream(*streams, stream) {
+			*streams = append(*streams, stream)
+		}
+		added = append(added, stream)
+	}
+	return added, nil
+}
+
+// containsStream returns true if `streams` contains `stream`.
+func containsStream(streams []*core.PdfObjectStream, stream *core.PdfObjectStream) bool {
+	for _, s := range streams {
+		if s == stream {
+			return true
+		}
+	}
+	return false
+}
+
+// AddCerts adds the DER-encoded certificates `certs` to the store and returns their streams.
+func (d *PdfDSS) AddCerts(certs [][]byte) ([]*core.PdfObjectStream, error) {
+	return d.addStreams(&d.Certs, certs)
+}
+
+// AddOCSPs adds the DER-encoded OCSP responses `ocsps` to the store and returns their streams.
+func (d *PdfDSS) AddOCSPs(ocsps [][]byte) ([]*core.PdfObjectStream, error) {
+	return d.addStreams(&d.OCSPs, ocsps)
+}
+
+// AddCRLs adds the DER-encoded CRLs `crls` to the store and returns their streams.
+func (d *PdfDSS) AddCRLs(crls [][]byte) ([]*core.PdfObjectStream, error) {
+	return d.addStreams(&d.CRLs, crls)
+}
+
+// AddSignatureData adds the validation material of signature `sig`, the DER-encoded certificates
+// `certs`, OCSP responses `ocsps` and CRLs `crls`, to the store and to the validation related
+// information of the signature. The collection time of the information is set to the current time.
+func (d *PdfDSS) AddSignatureData(sig *PdfSignature, certs, ocsps, crls [][]byte) error {
+	key := VRIKey(sig)
+	if key == "" {
+		return errors.New("signature Contents not set")
+	}
+	vri := d.VRI[key]
+	if vri == nil {
+		vri = &PdfVRI{}
+		d.VRI[key] = vri
+	}
+
+	certStreams, err := d.AddCerts(certs)
+	if err != nil {
+		return err
+	}
+	ocspStreams, err := d.AddOCSPs(ocsps)
+	if err != nil {
+		return err
+	}
+	crlStreams, err := d.AddCRLs(crls)
+	if err != nil {
+		return err
+	}
+	for _, s := range certStreams {
+		if !containsStream(vri.Cert, s) {
+			vri.Cert = append(vri.Cert, s)
+		}
+	}
+	for _, s := range ocspStreams {
+		if !containsStream(vri.OCSP, s) {
+			vri.OCSP = append(vri.OCSP, s)
+		}
+	}
+	for _, s := range crlStreams {
+		if !containsStream(vri.CRL, s) {
+			vri.CRL = append(vri.CRL, s)
+		}
+	}
+	vri.TU = time.Now()
+	return nil
+}
+
+// decodeStreams returns the decoded data of `streams`.
+func decodeStreams(streams []*core.PdfObjectStream) ([][]byte, error) {
+	data := make([][]byte, 0, len(streams))
+	for _, stream := range streams {
+		b, err := core.DecodeStream(stream)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, b)
+	}
+	return data, nil
+}
+
+// GetCerts returns the DER-encoded certificates of the store.
+func (d *PdfDSS) GetCerts() ([][]byte, error) {
+	return decodeStreams(d.Certs)
+}
+
+// GetOCSPs returns the DER-encoded OCSP responses of the store.
+func (d *PdfDSS) GetOCSPs() ([][]byte, error) {
+	return decodeStreams(d.OCSPs)
+}
+
+// GetCRLs returns the DER-encoded CRLs of the store.
+func (d *PdfDSS) GetCRLs() ([][]byte, error) {
+	return decodeStreams(d.CRLs)
+}
+
+// GetContainingPdfObject returns the container of the DSS dictionary.
+func (d *PdfDSS) GetContainingPdfObject() core.PdfObject {
+	return d.container
+}
+
+// makeStreamArray returns an array of references to `streams`.
+func makeStreamArray(streams []*core.PdfObjectStream) *core.PdfObjectArray {
+	arr := core.MakeArray()
+	for _, stream := range streams {
+		arr.Append(stream)
+	}
+	return arr
+}
+
+// ToPdfObject returns the DSS dictionary in its indirect object container.
+func (d *PdfDSS) ToPdfObject() core.PdfObject {
+	dict, ok := d.container.PdfObject.(*core.PdfObjectDictionary)
+	if !ok {
+		dict = core.MakeDict()
+		d.container.PdfObject = dict
+	}
+	dict.Clear()
+	dict.Set("Type", core.MakeName("DSS"))
+	if len(d.Certs) > 0 {
+		dict.Set("Certs", makeStreamArray(d.Certs))
+	}
+	if len(d.OCSPs) > 0 {
+		dict.Set("OCSPs", makeStreamArray(d.OCSPs))
+	}
+	if len(d.CRLs) > 0 {
+		dict.Set("CRLs", makeStreamArray(d.CRLs))
+	}
+
+	if len(d.VRI) > 0 {
+		keys := make([]string, 0, len(d.VRI))
+		for key := range d.VRI {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		vriDict := core.MakeDict()
+		for _, key := range keys {
+			vri := d.VRI[key]
+			vd := core.MakeDict()
+			vd.Set("Type", core.MakeName("VRI"))
+			if len(vri.Cert) > 0 {
+				vd.Set("Cert", makeStreamArray(vri.Cert))
+			}
+			if len(vri.OCSP) > 0 {
+				vd.Set("OCSP", makeStreamArray(vri.OCSP))
+			}
+			if len(vri.CRL) > 0 {
+				vd.Set("CRL", makeStreamArray(vri.CRL))
+			}
+			if !vri.TU.IsZero() {
+				if date, err := NewPdfDateFromTime(vri.TU); err == nil {
+					vd.Set("TU", date.ToPdfObject())
+				}
+			}
+			if vri.TS != nil {
+				vd.Set("TS", vri.TS)
+			}
+			vriDict.Set(core.PdfObjectName(key), vd)
+		}
+		dict.Set("VRI", vriDict)
+	}
+	return d.container
+}
+
+// GetDSS returns the document security store of the document, nil if the document has none.
+func (r *PdfReader) GetDSS() (*PdfDSS, error) {
+	obj, err := r.getCatalogEntry("DSS")
+	if err != nil || obj == nil {
+		return nil, err
+	}
+	return newPdfDSSFromObject(obj, r.log())
+}
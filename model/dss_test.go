@@ -0,0 +1,58 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+func TestDSSRoundTrip(t *testing.T) {
+	sig1 := &PdfSignature{Contents: core.MakeHexString("signature 1")}
+	sig2 := &PdfSignature{Contents: core.MakeHexString("signature 2")}
+	require.Len(t, VRIKey(sig1), 40)
+	require.NotEqual(t, VRIKey(sig1), VRIKey(sig2))
+
+	dss := NewPdfDSS()
+	require.NoError(t, dss.AddSignatureData(sig1, [][]byte{[]byte("cert 1"), []byte("ca")},
+		[][]byte{[]byte("ocsp 1")}, nil))
+	require.NoError(t, dss.AddSignatureData(sig2, [][]byte{[]byte("cert 2"), []byte("ca")},
+		nil, [][]byte{[]byte("crl")}))
+	require.Error(t, dss.AddSignatureData(&PdfSignature{}, nil, nil, nil))
+
+	// The data shared by the signatures is stored once.
+	require.Len(t, dss.Certs, 3)
+	require.Len(t, dss.OCSPs, 1)
+	require.Len(t, dss.CRLs, 1)
+	require.Same(t, dss.VRI[VRIKey(sig1)].Cert[1], dss.VRI[VRIKey(sig2)].Cert[1])
+
+	loaded, err := newPdfDSSFromObject(dss.ToPdfObject(), nil)
+	require.NoError(t, err)
+	certs, err := loaded.GetCerts()
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("cert 1"), []byte("ca"), []byte("cert 2")}, certs)
+	crls, err := loaded.GetCRLs()
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("crl")}, crls)
+
+	require.Len(t, loaded.VRI, 2)
+	vri := loaded.VRI[VRIKey(sig2)]
+	require.NotNil(t, vri)
+	require.Len(t, vri.Cert, 2)
+	require.Empty(t, vri.OCSP)
+	require.Len(t, vri.CRL, 1)
+	require.WithinDuration(t, time.Now(), vri.TU, time.Minute)
+
+	// Adding data already stored reuses the loaded streams.
+	streams, err := loaded.AddCerts([][]byte{[]byte("ca")})
+	require.NoError(t, err)
+	require.Same(t, loaded.Certs[1], streams[0])
+	require.Len(t, loaded.Certs, 3)
+}
@@ -0,0 +1,395 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// AFRelationship specifies the relationship between an associated file and the PDF component
+// which refers to it (the AFRelationship entry of a file specification, PDF 2.0).
+type AFRelationship string
+
+// Associated file relationships.
+const (
+	AFRelationshipSource           AFRelationship = "Source"
+	AFRelationshipData             AFRelationship = "Data"
+	AFRelationshipAlternative      AFRelationship = "Alternative"
+	AFRelationshipSupplement       AFRelationship = "Supplement"
+	AFRelationshipEncryptedPayload AFRelationship = "EncryptedPayload"
+	AFRelationshipFormData         AFRelationship = "FormData"
+	AFRelationshipSchema           AFRelationship = "Schema"
+	AFRelationshipUnspecified      AFRelationship = "Unspecified"
+)
+
+// PdfEmbeddedFile represents a file embedded in a PDF document: a file specification with an
+// embedded file stream (7.11.4 Embedded File Streams), referred to by name in the EmbeddedFiles
+// name tree of the document.
+type PdfEmbeddedFile struct {
+	// Name is the key of the file in the EmbeddedFiles name tree.
+	Name string
+
+	// FileName is the file name of the file specification (F and UF entries).
+	FileName string
+
+	// Description is the description of the file (Desc entry).
+	Description string
+
+	// MimeType is the MIME type of the file (Subtype of the embedded file stream), e.g. text/xml.
+	MimeType string
+
+	CreationDate *PdfDate
+	ModDate      *PdfDate
+
+	// AFRelationship is the relationship of the file with the document. The files with a
+	// relationship are also added to the associated files (AF) of the document.
+	AFRelationship AFRelationship
+
+	// CollectionItem is the collection item dictionary (CI entry) with the values of the fields of
+	// the collection schema for the file, in portfolios.
+	CollectionItem *core.PdfObjectDictionary
+
+	size     int64
+	checkSum []byte
+	stream   *core.PdfObjectStream
+
+	container *core.PdfIndirectObject
+}
+
+// NewPdfEmbeddedFile returns a new embedded file named `name` with content `data`. The file name
+// is set to the name, the checksum and size are computed from the content.
+func NewPdfEmbeddedFile(name string, data []byte) (*PdfEmbeddedFile, error) {
+	f := &PdfEmbeddedFile{
+		Name:      name,
+		FileName:  name,
+		container: core.MakeIndirectObject(core.MakeDict()),
+	}
+	if err := f.SetContent(data); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// NewPdfEmbeddedFileFromFile returns a new embedded file with the content of the file at `path`.
+// The name is the base name of the file, the MIME type is detected from its extension and the
+// modification date is that of the file.
+func NewPdfEmbeddedFileFromFile(path string) (*PdfEmbeddedFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := NewPdfEmbeddedFile(filepath.Base(path), data)
+	if err != nil {
+		return nil, err
+	}
+	if mimeType := mime.TypeByExtension(filepath.Ext(path)); mimeType != "" {
+		// Drop parameters such as charset.
+		f.MimeType = strings.TrimSpace(strings.Split(mimeType, ";")[0])
+	}
+	if fi, err := os.Stat(path); err == nil {
+		if date, err := NewPdfDateFromTime(fi.ModTime()); err == nil {
+			f.ModDate = &date
+		}
+	}
+	return f, nil
+}
+
+// newPdfEmbeddedFileFromObject loads the embedded file named `name` from the file specification
+// `obj`.
+func newPdfEmbeddedFileFromObject(name string, obj core.PdfObject, logger common.Logger) (*PdfEmbeddedFile, error) {
+	container, ok := core.GetIndirect(core.ResolveReference(obj))
+	if !ok {
+		// Direct file specification.
+		container = core.MakeIndirectObject(obj)
+	}
+	dict, ok := core.GetDict(container)
+	if !ok {
+		logger.Debug("ERROR: Embedded file %q not a dictionary (%T)", name, obj)
+		return nil, ErrTypeCheck
+	}
+
+	f := &PdfEmbeddedFile{Name: name, container: container}
+	if uf, ok := core.GetString(dict.Get("UF")); ok {
+		f.FileName = uf.Decoded()
+	} else if fn, ok := core.GetString(dict.Get("F")); ok {
+		f.FileName = fn.Decoded()
+	}
+	if desc, ok := core.GetString(dict.Get("Desc")); ok {
+		f.Description = desc.Decoded()
+	}
+	if rel, ok := core.GetNameVal(dict.Get("AFRelationship")); ok {
+		f.AFRelationship = AFRelationship(rel)
+	}
+	f.CollectionItem, _ = core.GetDict(dict.Get("CI"))
+
+	ef, ok := core.GetDict(dict.Get("EF"))
+	if !ok {
+		logger.Debug("ERROR: Embedded file %q without EF", name)
+		return nil, errors.New("missing embedded file stream")
+	}
+	for _, key := range []core.PdfObjectName{"UF", "F", "DOS", "Mac", "Unix"} {
+		if stream, ok := core.GetStream(ef.Get(key)); ok {
+			f.stream = stream
+			break
+		}
+	}
+	if f.stream == nil {
+		logger.Debug("ERROR: Embedded file %q without stream", name)
+		return nil, errors.New("missing embedded file stream")
+	}
+
+	if subtype, ok := core.GetNameVal(f.stream.Get("Subtype")); ok {
+		f.MimeType = subtype
+	}
+	if params, ok := core.GetDict(f.stream.Get("Params")); ok {
+		if size, ok := core.GetIntVal(params.Get("Size")); ok {
+			f.size = int64(size)
+		}
+		if sum, ok := core.GetString(params.Get("CheckSum")); ok {
+			f.checkSum = sum.Bytes()
+		}
+		for _, key := range []core.PdfObjectName{"CreationDate", "ModDate"} {
+			str, ok := core.GetString(params.Get(key))
+			if !ok {
+				continue
+			}
+			date, err := NewPdfDate(str.Str())
+			if err != nil {
+				logger.Debug("Invalid embedded file %s (%v) - skipping", key, err)
+				continue
+			}
+			if key == "CreationDate" {
+				f.CreationDate = &date
+			} else {
+				f.ModDate = &date
+			}
+		}
+	}
+	return f, nil
+}
+
+// Content returns the decoded content of the file.
+func (f *PdfEmbeddedFile) Content() ([]byte, error) {
+	if f.stream == nil {
+		return nil, errors.New("missing embedded file stream")
+	}
+	return core.DecodeStream(f.stream)
+}
+
+// SetContent sets the content of the file, updating its size and checksum.
+func (f *PdfEmbeddedFile) SetContent(data []byte) error {
+	stream, err := core.MakeStream(data, core.NewFlateEncoder())
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum(data)
+	f.stream = stream
+	f.size = int64(len(data))
+	f.checkSum = sum[:]
+	return nil
+}
+
+// Size returns the size of the content of the file in bytes, 0 if not specified.
+func (f *PdfEmbeddedFile) Size() int64 {
+	return f.size
+}
+
+// CheckSum returns the MD5 checksum of the content of the file, nil if not specified.
+func (f *PdfEmbeddedFile) CheckSum() []byte {
+	return f.checkSum
+}
+
+// VerifyCheckSum returns true if the content of the file matches its checksum, or if the file has
+// no checksum.
+func (f *PdfEmbeddedFile) VerifyCheckSum() (bool, error) {
+	if f.checkSum == nil {
+		return true, nil
+	}
+	data, err := f.Content()
+	if err != nil {
+		return false, err
+	}
+	sum := md5.Sum(data)
+	return bytes.Equal(sum[:], f.checkSum), nil
+}
+
+// GetContainingPdfObject returns the file specification indirect object.
+func (f *PdfEmbeddedFile) GetContainingPdfObject() core.PdfObject {
+	return f.container
+}
+
+// ToPdfObject returns the file specification of the embedded file. The entries of the file
+// specification and of the embedded file stream which are not modelled are kept.
+func (f *PdfEmbeddedFile) ToPdfObject() core.PdfObject {
+	dict, ok := f.container.PdfObject.(*core.PdfObjectDictionary)
+	if !ok {
+		dict = core.MakeDict()
+		f.container.PdfObject = dict
+	}
+
+	dict.Set("Type", core.MakeName("Filespec"))
+	fileName := f.FileName
+	if fileName == "" {
+		fileName = f.Name
+	}
+	dict.Set("F", core.MakeString(fileName))
+	dict.Set("UF", makeTextString(fileName))
+	dict.Remove("Desc")
+	if f.Description != "" {
+		dict.Set("Desc", makeTextString(f.Description))
+	}
+	dict.Remove("AFRelationship")
+	if f.AFRelationship != "" {
+		dict.Set("AFRelationship", core.MakeName(string(f.AFRelationship)))
+	}
+	dict.Remove("CI")
+	if f.CollectionItem != nil {
+		dict.Set("CI", f.CollectionItem)
+	}
+
+	if f.stream != nil {
+		f.stream.Set("Type", core.MakeName("EmbeddedFile"))
+		f.stream.Remove("Subtype")
+		if f.MimeType != "" {
+			f.stream.Set("Subtype", core.MakeName(f.MimeType))
+		}
+		params, ok := core.GetDict(f.stream.Get("Params"))
+		if !ok {
+			params = core.MakeDict()
+		}
+		params.Remove("Size")
+		if f.size > 0 {
+			params.Set("Size", core.MakeInteger(f.size))
+		}
+		params.Remove("CheckSum")
+		if f.checkSum != nil {
+			params.Set("CheckSum", core.MakeHexString(string(f.checkSum)))
+		}
+		params.Remove("CreationDate")
+		if f.CreationDate != nil {
+			params.Set("CreationDate", f.CreationDate.ToPdfObject())
+		}
+		params.Remove("ModDate")
+		if f.ModDate != nil {
+			params.Set("ModDate", f.ModDate.ToPdfObject())
+		}
+		if len(params.Keys()) > 0 {
+			f.stream.Set("Params", params)
+		} else {
+			f.stream.Remove("Params")
+		}
+
+		ef := core.MakeDict()
+		ef.Set("F", f.stream)
+		ef.Set("UF", f.stream)
+		dict.Set("EF", ef)
+	}
+	return f.container
+}
+
+// GetEmbeddedFiles returns the files of the EmbeddedFiles name tree of the document, in the order
+// of their names.
+func (r *PdfReader) GetEmbeddedFiles() ([]*PdfEmbeddedFile, error) {
+	tree, err := r.embeddedFilesTree()
+	if err != nil {
+		return nil, err
+	}
+	var files []*PdfEmbeddedFile
+	tree.ForEach(func(name string, value core.PdfObject) bool {
+		f, err := newPdfEmbeddedFileFromObject(name, value, r.log())
+		if err != nil {
+			r.log().Debug("ERROR: Invalid embedded file %q - skipping: %v", name, err)
+			return true
+		}
+		files = append(files, f)
+		return true
+	})
+	return files, nil
+}
+
+// GetEmbeddedFile returns the file named `name` of the EmbeddedFiles name tree, or nil if the
+// document has no such file.
+func (r *PdfReader) GetEmbeddedFile(name string) (*PdfEmbeddedFile, error) {
+	tree, err := r.embeddedFilesTree()
+	if err != nil {
+		return nil, err
+	}
+	value, ok := tree.Get(name)
+	if !ok {
+		return nil, nil
+	}
+	return newPdfEmbeddedFileFromObject(name, value, r.log())
+}
+
+// embeddedFilesTree returns the EmbeddedFiles name tree of the document.
+func (r *PdfReader) embeddedFilesTree() (*PdfNameTree, error) {
+	if r.parser.GetCrypter() != nil && !r.parser.IsAuthenticated() {
+		return nil, ErrEncrypted
+	}
+	return getNamesTree(r.catalog, "EmbeddedFiles")
+}
+
+// updateEmbeddedFiles updates the EmbeddedFiles name tree of `catalog` with the files `added`,
+// replacing the files with the same names, and without the files `removed`. The added files with
+// a relationship are also added to the associated files of the catalog. The Names dictionary and
+// AF array are replaced by direct objects and returned, to be written.
+func updateEmbeddedFiles(catalog *core.PdfObjectDictionary, added []*PdfEmbeddedFile,
+	removed map[string]struct{}) ([]core.PdfObject, error) {
+	tree, err := getNamesTree(catalog, "EmbeddedFiles")
+	if err != nil {
+		return nil, err
+	}
+	for name := range removed {
+		tree.Remove(name)
+	}
+	var associated []core.PdfObject
+	for _, f := range added {
+		filespec := f.ToPdfObject()
+		tree.Set(f.Name, filespec)
+		if f.AFRelationship != "" {
+			associated = append(associated, filespec)
+		}
+	}
+	objects := []core.PdfObject{setNamesTree(catalog, "EmbeddedFiles", tree)}
+
+	if len(associated) > 0 {
+		af := core.MakeArray()
+		if orig, ok := core.GetArray(catalog.Get("AF")); ok {
+			af.Append(orig.Elements()...)
+		}
+		af.Append(associated...)
+		catalog.Set("AF", af)
+		objects = append(objects, af)
+	}
+	return objects, nil
+}
+
+// checkEmbeddedFile checks that `f` can be added to the files `files`.
+func checkEmbeddedFile(f *PdfEmbeddedFile, files []*PdfEmbeddedFile) error {
+	if f == nil || f.Name == "" {
+		return errors.New("embedded file without name")
+	}
+	if f.stream == nil {
+		return errors.New("missing embedded file stream")
+	}
+	for _, other := range files {
+		if other.Name == f.Name {
+			return fmt.Errorf("duplicate embedded file %q", f.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+const testInvoiceXML = `<?xml version="1.0" encoding="UTF-8"?><rsm:CrossIndustryInvoice/>`
+
+// writeEmbeddedFilesTestFile returns a document with an XML invoice and a text file embedded in a
+// portable collection.
+func writeEmbeddedFilesTestFile(t *testing.T) []byte {
+	invoice, err := NewPdfEmbeddedFile("factur-x.xml", []byte(testInvoiceXML))
+	require.NoError(t, err)
+	invoice.MimeType = "text/xml"
+	invoice.Description = "Factur-X invoice"
+	invoice.AFRelationship = AFRelationshipData
+	modDate, err := NewPdfDateFromTime(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	require.NoError(t, err)
+	invoice.ModDate = &modDate
+	invoice.CollectionItem = core.MakeDict()
+	invoice.CollectionItem.Set("Kind", core.MakeString("Invoice"))
+
+	notes, err := NewPdfEmbeddedFile("notes.txt", []byte("Notes"))
+	require.NoError(t, err)
+	notes.FileName = "Заметки.txt"
+
+	collection := NewPdfCollection()
+	collection.AddField("Kind", "Kind", CollectionFieldText)
+	collection.AddField("Size", "Size", CollectionFieldSize).Hidden = true
+	collection.Sort = []PdfCollectionSort{{Key: "Kind", Ascending: false}}
+	collection.View = CollectionViewTile
+	collection.InitialDocument = "factur-x.xml"
+
+	w := NewPdfWriter()
+	require.NoError(t, w.AddPage(newPdf20TestPage()))
+	require.NoError(t, w.AddEmbeddedFile(notes))
+	require.NoError(t, w.AddEmbeddedFile(invoice))
+	require.Error(t, w.AddEmbeddedFile(invoice))
+	w.SetCollection(collection)
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	return buf.Bytes()
+}
+
+func TestWriterEmbeddedFiles(t *testing.T) {
+	data := writeEmbeddedFilesTestFile(t)
+	r, err := NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, core.Version{Major: 1, Minor: 7}, r.PdfVersion())
+
+	files, err := r.GetEmbeddedFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	// Sorted by name.
+	invoice := files[0]
+	require.Equal(t, "factur-x.xml", invoice.Name)
+	require.Equal(t, "factur-x.xml", invoice.FileName)
+	require.Equal(t, "Factur-X invoice", invoice.Description)
+	require.Equal(t, "text/xml", invoice.MimeType)
+	require.Equal(t, AFRelationshipData, invoice.AFRelationship)
+	require.Equal(t, int64(len(testInvoiceXML)), invoice.Size())
+	require.NotNil(t, invoice.ModDate)
+	require.Equal(t, 2020, invoice.ModDate.ToGoTime().Year())
+	require.Nil(t, invoice.CreationDate)
+	require.Equal(t, "Invoice", invoice.CollectionItem.Get("Kind").(*core.PdfObjectString).Str())
+	content, err := invoice.Content()
+	require.NoError(t, err)
+	require.Equal(t, testInvoiceXML, string(content))
+	ok, err := invoice.VerifyCheckSum()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.Equal(t, "notes.txt", files[1].Name)
+	require.Equal(t, "Заметки.txt", files[1].FileName)
+
+	f, err := r.GetEmbeddedFile("notes.txt")
+	require.NoError(t, err)
+	require.NotNil(t, f)
+	f, err = r.GetEmbeddedFile("missing")
+	require.NoError(t, err)
+	require.Nil(t, f)
+
+	// Only the invoice is an associated file.
+	af, err := r.GetAssociatedFiles()
+	require.NoError(t, err)
+	afArr, ok := core.GetArray(af)
+	require.True(t, ok)
+	require.Equal(t, 1, afArr.Len())
+	require.Equal(t, invoice.GetContainingPdfObject(), afArr.Get(0))
+
+	collection, err := r.GetCollection()
+	require.NoError(t, err)
+	require.NotNil(t, collection)
+	require.Equal(t, CollectionViewTile, collection.View)
+	require.Equal(t, "factur-x.xml", collection.InitialDocument)
+	require.Equal(t, []PdfCollectionSort{{Key: "Kind", Ascending: false}}, collection.Sort)
+	require.Len(t, collection.Schema, 2)
+	fields := map[string]*PdfCollectionField{}
+	for _, field := range collection.Schema {
+		fields[field.Key] = field
+	}
+	require.Equal(t, &PdfCollectionField{Key: "Kind", Name: "Kind", Subtype: CollectionFieldText, Order: 1},
+		fields["Kind"])
+	require.True(t, fields["Size"].Hidden)
+}
+
+func TestEmbeddedFileCheckSum(t *testing.T) {
+	f, err := NewPdfEmbeddedFile("data.bin", []byte{1, 2, 3})
+	require.NoError(t, err)
+	ok, err := f.VerifyCheckSum()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Tampered content.
+	f.stream, err = core.MakeStream([]byte{1, 2, 4}, nil)
+	require.NoError(t, err)
+	ok, err = f.VerifyCheckSum()
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestAppenderEmbeddedFiles(t *testing.T) {
+	data := writeEmbeddedFilesTestFile(t)
+	r, err := NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	appender, err := NewPdfAppender(r)
+	require.NoError(t, err)
+
+	report, err := NewPdfEmbeddedFile("report.csv", []byte("a,b\n1,2\n"))
+	require.NoError(t, err)
+	report.MimeType = "text/csv"
+	require.NoError(t, appender.AddEmbeddedFile(report))
+	appender.RemoveEmbeddedFile("notes.txt")
+	var buf bytes.Buffer
+	require.NoError(t, appender.Write(&buf))
+
+	r, err = NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	files, err := r.GetEmbeddedFiles()
+	require.NoError(t, err)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	require.Equal(t, []string{"factur-x.xml", "report.csv"}, names)
+	content, err := files[1].Content()
+	require.NoError(t, err)
+	require.Equal(t, "a,b\n1,2\n", string(content))
+
+	// The collection of the original document is kept.
+	collection, err := r.GetCollection()
+	require.NoError(t, err)
+	require.NotNil(t, collection)
+}
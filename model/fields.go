@@ -458,6 +458,23 @@ func NewPdfFieldSignature(signature *PdfSignature) *PdfFieldSignature {
 	return field
 }
 
+// SetLock sets the fields locked when the signature field is signed: all the fields of the
+// document, the fields `fields` or all the fields except `fields`, depending on `action`.
+// PdfAppender.Sign adds the corresponding FieldMDP transform to the signature.
+func (sig *PdfFieldSignature) SetLock(action FieldMDPAction, fields ...string) {
+	lock := core.MakeDict()
+	lock.Set("Type", core.MakeName("SigFieldLock"))
+	lock.Set("Action", core.MakeName(string(action)))
+	if action != FieldMDPAll {
+		arr := core.MakeArray()
+		for _, name := range fields {
+			arr.Append(core.MakeString(name))
+		}
+		lock.Set("Fields", arr)
+	}
+	sig.Lock = core.MakeIndirectObject(lock)
+}
+
 // ToPdfObject returns an indirect object containing the signature field dictionary.
 func (sig *PdfFieldSignature) ToPdfObject() core.PdfObject {
 	// Set general field attributes.
@@ -631,7 +648,7 @@ func (r *PdfReader) newPdfFieldFromIndirectObject(container *core.PdfIndirectObj
 			ctx.PdfField = field
 			field.context = ctx
 		default:
-			common.Log.Debug("ERROR: Unsupported field type %s", *field.FT)
+			r.log().Debug("ERROR: Unsupported field type %s", *field.FT)
 			return nil, errors.New("unsupported field type")
 		}
 	}
@@ -685,7 +702,7 @@ func (r *PdfReader) newPdfFieldFromIndirectObject(container *core.PdfIndirectObj
 				if ok && stream.PdfObjectDictionary != nil {
 					nodeType, ok := core.GetNameVal(stream.Get("Type"))
 					if ok && nodeType == "Metadata" {
-						common.Log.Debug("ERROR: form field Kids array contains invalid Metadata stream. Skipping.")
+						r.log().Debug("ERROR: form field Kids array contains invalid Metadata stream. Skipping.")
 						continue
 					}
 				}
@@ -708,7 +725,7 @@ func (r *PdfReader) newPdfFieldFromIndirectObject(container *core.PdfIndirectObj
 			if name, has := core.GetName(dict.Get("Subtype")); has && !hasFT && *name == "Widget" {
 				annot, err := r.newPdfAnnotationFromIndirectObject(container)
 				if err != nil {
-					common.Log.Debug("Error loading widget annotation for field: %v", err)
+					r.log().Debug("Error loading widget annotation for field: %v", err)
 					return nil, err
 				}
 				wa, ok := annot.context.(*PdfAnnotationWidget)
@@ -720,7 +737,7 @@ func (r *PdfReader) newPdfFieldFromIndirectObject(container *core.PdfIndirectObj
 			} else {
 				childf, err := r.newPdfFieldFromIndirectObject(container, field)
 				if err != nil {
-					common.Log.Debug("Error loading child field: %v", err)
+					r.log().Debug("Error loading child field: %v", err)
 					return nil, err
 				}
 				field.Kids = append(field.Kids, childf)
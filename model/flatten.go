@@ -118,10 +118,10 @@ func (r *PdfReader) FlattenFields(allannots bool, appgen FieldAppearanceGenerato
 			xform, rect, err := getAnnotationActiveAppearance(annot)
 			if err != nil {
 				if !hasV {
-					common.Log.Trace("Field without V -> annotation without appearance stream - skipping over")
+					r.log().Trace("Field without V -> annotation without appearance stream - skipping over")
 					continue
 				}
-				common.Log.Debug("ERROR Annotation without appearance stream, err : %v - skipping over", err)
+				r.log().Debug("ERROR Annotation without appearance stream, err : %v - skipping over", err)
 				continue
 			}
 			if xform == nil {
@@ -51,7 +51,7 @@ func (font *PdfFont) SubsetRegistered() error {
 	case *pdfFontType0:
 		err := t.subsetRegistered()
 		if err != nil {
-			common.Log.Debug("Subset error: %v", err)
+			font.log().Debug("Subset error: %v", err)
 			return err
 		}
 		if t.container != nil {
@@ -61,7 +61,7 @@ func (font *PdfFont) SubsetRegistered() error {
 			t.ToPdfObject() // Forced update of object.
 		}
 	default:
-		common.Log.Debug("Font %T does not support subsetting", t)
+		font.log().Debug("Font %T does not support subsetting", t)
 	}
 	return nil
 }
@@ -108,7 +108,7 @@ func (font *PdfFont) FontDescriptor() *PdfFontDescriptor {
 	if d := font.context.getFontDescriptor(); d != nil {
 		return d
 	}
-	common.Log.Error("All fonts have a Descriptor. font=%s", font)
+	font.log().Error("All fonts have a Descriptor. font=%s", font)
 	return nil
 }
 
@@ -283,15 +283,40 @@ func GetAlphabet(text string) map[rune]int {
 // NewPdfFontFromPdfObject loads a PdfFont from the dictionary `fontObj`.  If there is a problem an
 // error is returned.
 func NewPdfFontFromPdfObject(fontObj core.PdfObject) (*PdfFont, error) {
-	return newPdfFontFromPdfObject(fontObj, true)
+	return newPdfFontFromPdfObject(fontObj, true, nil)
+}
+
+// NewPdfFontFromPdfObjectWithLogger loads a PdfFont from the dictionary `fontObj` like
+// NewPdfFontFromPdfObject, logging the problems with the font to `logger`, such as the logger of the
+// reader of the document (see PdfReader.Logger). If the font cannot be loaded, a DiagnosticFontInvalid
+// diagnostic is also reported to `logger`.
+func NewPdfFontFromPdfObjectWithLogger(fontObj core.PdfObject, logger common.Logger) (*PdfFont, error) {
+	font, err := newPdfFontFromPdfObject(fontObj, true, logger)
+	if err != nil {
+		var objNum int64
+		if obj, ok := fontObj.(*core.PdfIndirectObject); ok {
+			objNum = obj.ObjectNumber
+		}
+		common.ReportDiagnosticDepth(logger, 1, common.Diagnostic{
+			Level:        common.LogLevelWarning,
+			Code:         common.DiagnosticFontInvalid,
+			Message:      fmt.Sprintf("unable to load font: %v", err),
+			ObjectNumber: objNum,
+		})
+	}
+	return font, err
 }
 
 // newPdfFontFromPdfObject loads a PdfFont from the dictionary `fontObj`.  If there is a problem an
 // error is returned.
 // The allowType0 flag indicates whether loading Type0 font should be supported.  This is used to
 // avoid cyclical loading.
-func newPdfFontFromPdfObject(fontObj core.PdfObject, allowType0 bool) (*PdfFont, error) {
-	d, base, err := newFontBaseFieldsFromPdfObject(fontObj)
+// The problems with the font are logged to `logger`, or to common.Log if it is nil.
+func newPdfFontFromPdfObject(fontObj core.PdfObject, allowType0 bool, logger common.Logger) (*PdfFont, error) {
+	if logger == nil {
+		logger = common.Log
+	}
+	d, base, err := newFontBaseFieldsFromPdfObject(fontObj, logger)
 	if err != nil {
 		// In the case of not yet supported fonts, we attempt to return enough information in the
 		// font for the caller to see some font properties.
@@ -299,7 +324,7 @@ func newPdfFontFromPdfObject(fontObj core.PdfObject, allowType0 bool) (*PdfFont,
 		if err == ErrType3FontNotSupported || err == ErrType1CFontNotSupported {
 			simplefont, err2 := newSimpleFontFromPdfObject(d, base, nil)
 			if err2 != nil {
-				common.Log.Debug("ERROR: While loading simple font: font=%s err=%v", base, err2)
+				logger.Debug("ERROR: While loading simple font: font=%s err=%v", base, err2)
 				return nil, err
 			}
 			return &PdfFont{context: simplefont}, err
@@ -312,12 +337,12 @@ func newPdfFontFromPdfObject(fontObj core.PdfObject, allowType0 bool) (*PdfFont,
 	switch base.subtype {
 	case "Type0":
 		if !allowType0 {
-			common.Log.Debug("ERROR: Loading type0 not allowed. font=%s", base)
+			logger.Debug("ERROR: Loading type0 not allowed. font=%s", base)
 			return nil, errors.New("cyclical type0 loading")
 		}
 		type0font, err := newPdfFontType0FromPdfObject(d, base)
 		if err != nil {
-			common.Log.Debug("ERROR: While loading Type0 font. font=%s err=%v", base, err)
+			logger.Debug("ERROR: While loading Type0 font. font=%s err=%v", base, err)
 			return nil, err
 		}
 		font.context = type0font
@@ -329,10 +354,10 @@ func newPdfFontFromPdfObject(fontObj core.PdfObject, allowType0 bool) (*PdfFont,
 			font.context = &std
 
 			stdObj := core.TraceToDirectObject(std.ToPdfObject())
-			d14, stdBase, err := newFontBaseFieldsFromPdfObject(stdObj)
+			d14, stdBase, err := newFontBaseFieldsFromPdfObject(stdObj, logger)
 
 			if err != nil {
-				common.Log.Debug("ERROR: Bad Standard14\n\tfont=%s\n\tstd=%+v", base, std)
+				logger.Debug("ERROR: Bad Standard14\n\tfont=%s\n\tstd=%+v", base, std)
 				return nil, err
 			}
 
@@ -341,7 +366,7 @@ func newPdfFontFromPdfObject(fontObj core.PdfObject, allowType0 bool) (*PdfFont,
 			}
 			simplefont, err = newSimpleFontFromPdfObject(d14, stdBase, std.std14Encoder)
 			if err != nil {
-				common.Log.Debug("ERROR: Bad Standard14\n\tfont=%s\n\tstd=%+v", base, std)
+				logger.Debug("ERROR: Bad Standard14\n\tfont=%s\n\tstd=%+v", base, std)
 				return nil, err
 			}
 
@@ -350,7 +375,7 @@ func newPdfFontFromPdfObject(fontObj core.PdfObject, allowType0 bool) (*PdfFont,
 		} else {
 			simplefont, err = newSimpleFontFromPdfObject(d, base, nil)
 			if err != nil {
-				common.Log.Debug("ERROR: While loading simple font: font=%s err=%v", base, err)
+				logger.Debug("ERROR: While loading simple font: font=%s err=%v", base, err)
 				return nil, err
 			}
 		}
@@ -363,29 +388,29 @@ func newPdfFontFromPdfObject(fontObj core.PdfObject, allowType0 bool) (*PdfFont,
 		}
 		if builtin && simplefont.encoder == nil && simplefont.std14Encoder == nil {
 			// This is not possible.
-			common.Log.Error("simplefont=%s", simplefont)
-			common.Log.Error("fnt=%+v", fnt)
+			logger.Error("simplefont=%s", simplefont)
+			logger.Error("fnt=%+v", fnt)
 		}
 		if len(simplefont.charWidths) == 0 {
-			common.Log.Debug("ERROR: No widths. font=%s", simplefont)
+			logger.Debug("ERROR: No widths. font=%s", simplefont)
 		}
 		font.context = simplefont
 	case "CIDFontType0":
 		cidfont, err := newPdfCIDFontType0FromPdfObject(d, base)
 		if err != nil {
-			common.Log.Debug("ERROR: While loading cid font type0 font: %v", err)
+			logger.Debug("ERROR: While loading cid font type0 font: %v", err)
 			return nil, err
 		}
 		font.context = cidfont
 	case "CIDFontType2":
 		cidfont, err := newPdfCIDFontType2FromPdfObject(d, base)
 		if err != nil {
-			common.Log.Debug("ERROR: While loading cid font type2 font. font=%s err=%v", base, err)
+			logger.Debug("ERROR: While loading cid font type2 font. font=%s err=%v", base, err)
 			return nil, err
 		}
 		font.context = cidfont
 	default:
-		common.Log.Debug("ERROR: Unsupported font type: font=%s", base)
+		logger.Debug("ERROR: Unsupported font type: font=%s", base)
 		return nil, fmt.Errorf("unsupported font type: font=%s", base)
 	}
 
@@ -394,7 +419,7 @@ func newPdfFontFromPdfObject(fontObj core.PdfObject, allowType0 bool) (*PdfFont,
 
 // BytesToCharcodes converts the bytes in a PDF string to character codes.
 func (font *PdfFont) BytesToCharcodes(data []byte) []textencoding.CharCode {
-	common.Log.Trace("BytesToCharcodes: data=[% 02x]=%#q", data, data)
+	font.log().Trace("BytesToCharcodes: data=[% 02x]=%#q", data, data)
 	if type0, ok := font.context.(*pdfFontType0); ok && type0.codeToCID != nil {
 		if charcodes, ok := type0.bytesToCharcodes(data); ok {
 			return charcodes
@@ -408,7 +433,7 @@ func (font *PdfFont) BytesToCharcodes(data []byte) []textencoding.CharCode {
 			data = []byte{0, data[0]}
 		}
 		if len(data)%2 != 0 {
-			common.Log.Debug("ERROR: Padding data=%+v to even length", data)
+			font.log().Debug("ERROR: Padding data=%+v to even length", data)
 			data = append(data, 0)
 		}
 		for i := 0; i < len(data); i += 2 {
@@ -457,7 +482,7 @@ func (font *PdfFont) CharcodesToStrings(charcodes []textencoding.CharCode) ([]st
 			}
 		}
 
-		common.Log.Debug("ERROR: No rune. code=0x%04x charcodes=[% 04x] CID=%t\n"+
+		font.log().Debug("ERROR: No rune. code=0x%04x charcodes=[% 04x] CID=%t\n"+
 			"\tfont=%s\n\tencoding=%s",
 			code, charcodes, fontBase.isCIDFont(), font, encoder)
 		numMisses++
@@ -465,7 +490,7 @@ func (font *PdfFont) CharcodesToStrings(charcodes []textencoding.CharCode) ([]st
 	}
 
 	if numMisses != 0 {
-		common.Log.Debug("ERROR: Couldn't convert to unicode. Using input.\n"+
+		font.log().Debug("ERROR: Couldn't convert to unicode. Using input.\n"+
 			"\tnumChars=%d numMisses=%d\n"+
 			"\tfont=%s",
 			len(charcodes), numMisses, font)
@@ -530,13 +555,13 @@ func (font *PdfFont) RunesToCharcodeBytes(data []rune) ([]byte, int) {
 		}
 
 		if !encoded {
-			common.Log.Debug("ERROR: failed to map rune `%+q` to charcode", r)
+			font.log().Debug("ERROR: failed to map rune `%+q` to charcode", r)
 			numMisses++
 		}
 	}
 
 	if numMisses != 0 {
-		common.Log.Debug("ERROR: could not convert all runes to charcodes.\n"+
+		font.log().Debug("ERROR: could not convert all runes to charcodes.\n"+
 			"\tnumRunes=%d numMisses=%d\n"+
 			"\tfont=%s encoders=%+v", len(data), numMisses, font, encoders)
 	}
@@ -555,7 +580,7 @@ func (font *PdfFont) StringToCharcodeBytes(str string) ([]byte, int) {
 // ToPdfObject converts the PdfFont object to its PDF representation.
 func (font *PdfFont) ToPdfObject() core.PdfObject {
 	if font.context == nil {
-		common.Log.Debug("ERROR: font context is nil")
+		font.log().Debug("ERROR: font context is nil")
 		return core.MakeNull()
 	}
 	return font.context.ToPdfObject()
@@ -565,7 +590,7 @@ func (font *PdfFont) ToPdfObject() core.PdfObject {
 func (font *PdfFont) Encoder() textencoding.TextEncoder {
 	t := font.actualFont()
 	if t == nil {
-		common.Log.Debug("ERROR: Encoder not implemented for font type=%#T", font.context)
+		font.log().Debug("ERROR: Encoder not implemented for font type=%#T", font.context)
 		// TODO: Should we return a default encoding?
 		return nil
 	}
@@ -581,7 +606,7 @@ type CharMetrics = fonts.CharMetrics
 func (font *PdfFont) GetRuneMetrics(r rune) (CharMetrics, bool) {
 	t := font.actualFont()
 	if t == nil {
-		common.Log.Debug("ERROR: GetGlyphCharMetrics Not implemented for font type=%#T", font.context)
+		font.log().Debug("ERROR: GetGlyphCharMetrics Not implemented for font type=%#T", font.context)
 		return fonts.CharMetrics{}, false
 	}
 	if m, ok := t.GetRuneMetrics(r); ok {
@@ -591,7 +616,7 @@ func (font *PdfFont) GetRuneMetrics(r rune) (CharMetrics, bool) {
 		return fonts.CharMetrics{Wx: desc.missingWidth}, true
 	}
 
-	common.Log.Debug("GetGlyphCharMetrics: No metrics for font=%s", font)
+	font.log().Debug("GetGlyphCharMetrics: No metrics for font=%s", font)
 	return fonts.CharMetrics{}, false
 }
 
@@ -632,7 +657,7 @@ func (font *PdfFont) GetCharMetrics(code textencoding.CharCode) (CharMetrics, bo
 			return m, ok
 		}
 	default:
-		common.Log.Debug("ERROR: GetCharMetrics not implemented for font type=%T.", font.context)
+		font.log().Debug("ERROR: GetCharMetrics not implemented for font type=%T.", font.context)
 		return nometrics, false
 	}
 
@@ -640,7 +665,7 @@ func (font *PdfFont) GetCharMetrics(code textencoding.CharCode) (CharMetrics, bo
 		return fonts.CharMetrics{Wx: descriptor.missingWidth}, true
 	}
 
-	common.Log.Debug("GetCharMetrics: No metrics for font=%s", font)
+	font.log().Debug("GetCharMetrics: No metrics for font=%s", font)
 	return nometrics, false
 }
 
@@ -652,6 +677,14 @@ func (font PdfFont) actualFont() pdfFont {
 	return font.context
 }
 
+// log returns the logger of `font`: the logger of the reader it was loaded by, or common.Log.
+func (font *PdfFont) log() common.Logger {
+	if font.context == nil {
+		return common.Log
+	}
+	return font.context.baseFields().log()
+}
+
 // baseFields returns the fields of `font`.context that are common to all PDF fonts.
 func (font *PdfFont) baseFields() *fontCommon {
 	if font.context == nil {
@@ -677,6 +710,18 @@ type fontCommon struct {
 
 	// objectNumber helps us find the font in the PDF being processed. This helps with debugging.
 	objectNumber int64
+
+	// logger receives the log messages about the font, such as the logger of the reader it was
+	// loaded by. common.Log is used if it is nil.
+	logger common.Logger
+}
+
+// log returns the logger of `base`.
+func (base fontCommon) log() common.Logger {
+	if base.logger == nil {
+		return common.Log
+	}
+	return base.logger
 }
 
 // asPdfObjectDictionary returns `base` as a core.PdfObjectDictionary.
@@ -684,9 +729,9 @@ type fontCommon struct {
 // NOTE: The returned dict's "Subtype" field is set to `subtype` if `base` doesn't have a subtype.
 func (base fontCommon) asPdfObjectDictionary(subtype string) *core.PdfObjectDictionary {
 	if subtype != "" && base.subtype != "" && subtype != base.subtype {
-		common.Log.Debug("ERROR: asPdfObjectDictionary. Overriding subtype to %#q %s", subtype, base)
+		base.log().Debug("ERROR: asPdfObjectDictionary. Overriding subtype to %#q %s", subtype, base)
 	} else if subtype == "" && base.subtype == "" {
-		common.Log.Debug("ERROR: asPdfObjectDictionary no subtype. font=%s", base)
+		base.log().Debug("ERROR: asPdfObjectDictionary no subtype. font=%s", base)
 	} else if base.subtype == "" {
 		base.subtype = subtype
 	}
@@ -704,7 +749,7 @@ func (base fontCommon) asPdfObjectDictionary(subtype string) *core.PdfObjectDict
 	} else if base.toUnicodeCmap != nil {
 		o, err := base.toUnicodeCmap.Stream()
 		if err != nil {
-			common.Log.Debug("WARN: could not get CMap stream. err=%v", err)
+			base.log().Debug("WARN: could not get CMap stream. err=%v", err)
 		} else {
 			d.Set("ToUnicode", o)
 		}
@@ -738,22 +783,22 @@ func (base fontCommon) fontFlags() int {
 // isCIDFont returns true if `base` is a CID font.
 func (base fontCommon) isCIDFont() bool {
 	if base.subtype == "" {
-		common.Log.Debug("ERROR: isCIDFont. context is nil. font=%s", base)
+		base.log().Debug("ERROR: isCIDFont. context is nil. font=%s", base)
 	}
 	isCID := false
 	switch base.subtype {
 	case "Type0", "CIDFontType0", "CIDFontType2":
 		isCID = true
 	}
-	common.Log.Trace("isCIDFont: isCID=%t font=%s", isCID, base)
+	base.log().Trace("isCIDFont: isCID=%t font=%s", isCID, base)
 	return isCID
 }
 
 // newFontBaseFieldsFromPdfObject returns `fontObj` as a dictionary the common fields from that
 // dictionary in the fontCommon return.  If there is a problem an error is returned.
-// The fontCommon is the group of fields common to all PDF fonts.
-func newFontBaseFieldsFromPdfObject(fontObj core.PdfObject) (*core.PdfObjectDictionary, *fontCommon, error) {
-	font := &fontCommon{}
+// The fontCommon is the group of fields common to all PDF fonts. Its problems are logged to `logger`.
+func newFontBaseFieldsFromPdfObject(fontObj core.PdfObject, logger common.Logger) (*core.PdfObjectDictionary, *fontCommon, error) {
+	font := &fontCommon{logger: logger}
 
 	if obj, ok := fontObj.(*core.PdfIndirectObject); ok {
 		font.objectNumber = obj.ObjectNumber
@@ -761,23 +806,23 @@ func newFontBaseFieldsFromPdfObject(fontObj core.PdfObject) (*core.PdfObjectDict
 
 	d, ok := core.GetDict(fontObj)
 	if !ok {
-		common.Log.Debug("ERROR: Font not given by a dictionary (%T)", fontObj)
+		font.log().Debug("ERROR: Font not given by a dictionary (%T)", fontObj)
 		return nil, nil, ErrFontNotSupported
 	}
 
 	objtype, ok := core.GetNameVal(d.Get("Type"))
 	if !ok {
-		common.Log.Debug("ERROR: Font Incompatibility. Type (Required) missing")
+		font.log().Debug("ERROR: Font Incompatibility. Type (Required) missing")
 		return nil, nil, ErrRequiredAttributeMissing
 	}
 	if objtype != "Font" {
-		common.Log.Debug("ERROR: Font Incompatibility. Type=%q. Should be %q.", objtype, "Font")
+		font.log().Debug("ERROR: Font Incompatibility. Type=%q. Should be %q.", objtype, "Font")
 		return nil, nil, core.ErrTypeError
 	}
 
 	subtype, ok := core.GetNameVal(d.Get("Subtype"))
 	if !ok {
-		common.Log.Debug("ERROR: Font Incompatibility. Subtype (Required) missing")
+		font.log().Debug("ERROR: Font Incompatibility. Subtype (Required) missing")
 		return nil, nil, ErrRequiredAttributeMissing
 	}
 	font.subtype = subtype
@@ -788,22 +833,22 @@ func newFontBaseFieldsFromPdfObject(fontObj core.PdfObject) (*core.PdfObjectDict
 	}
 
 	if subtype == "Type3" {
-		common.Log.Debug("ERROR: Type 3 font not supported. d=%s", d)
+		font.log().Debug("ERROR: Type 3 font not supported. d=%s", d)
 		return d, font, ErrType3FontNotSupported
 	}
 
 	basefont, ok := core.GetNameVal(d.Get("BaseFont"))
 	if !ok {
-		common.Log.Debug("ERROR: Font Incompatibility. BaseFont (Required) missing")
+		font.log().Debug("ERROR: Font Incompatibility. BaseFont (Required) missing")
 		return d, font, ErrRequiredAttributeMissing
 	}
 	font.basefont = basefont
 
 	obj := d.Get("FontDescriptor")
 	if obj != nil {
-		fontDescriptor, err := newPdfFontDescriptorFromPdfObject(obj)
+		fontDescriptor, err := newPdfFontDescriptorFromPdfObject(obj, font.logger)
 		if err != nil {
-			common.Log.Debug("ERROR: Bad font descriptor. err=%v", err)
+			font.log().Debug("ERROR: Bad font descriptor. err=%v", err)
 			return d, font, err
 		}
 		font.fontDescriptor = fontDescriptor
@@ -827,7 +872,7 @@ func newFontBaseFieldsFromPdfObject(fontObj core.PdfObject) (*core.PdfObjectDict
 		if cmap.IsPredefinedCMap(cmapName) {
 			font.toUnicodeCmap, err = cmap.LoadPredefinedCMap(cmapName)
 			if err != nil {
-				common.Log.Debug("WARN: could not load predefined CMap %s: %v", cmapName, err)
+				font.log().Debug("WARN: could not load predefined CMap %s: %v", cmapName, err)
 			}
 		}
 	}
@@ -839,7 +884,7 @@ func newFontBaseFieldsFromPdfObject(fontObj core.PdfObject) (*core.PdfObjectDict
 func toUnicodeToCmap(toUnicode core.PdfObject, font *fontCommon) (*cmap.CMap, error) {
 	toUnicodeStream, ok := core.GetStream(toUnicode)
 	if !ok {
-		common.Log.Debug("ERROR: toUnicodeToCmap: Not a stream (%T)", toUnicode)
+		font.log().Debug("ERROR: toUnicodeToCmap: Not a stream (%T)", toUnicode)
 		return nil, core.ErrTypeError
 	}
 	data, err := core.DecodeStream(toUnicodeStream)
@@ -850,7 +895,7 @@ func toUnicodeToCmap(toUnicode core.PdfObject, font *fontCommon) (*cmap.CMap, er
 	cm, err := cmap.LoadCmapFromData(data, !font.isCIDFont())
 	if err != nil {
 		// Show the object number of the bad cmap to help with debugging.
-		common.Log.Debug("ERROR: ObjectNumber=%d err=%v", toUnicodeStream.ObjectNumber, err)
+		font.log().Debug("ERROR: ObjectNumber=%d err=%v", toUnicodeStream.ObjectNumber, err)
 	}
 	return cm, err
 }
@@ -945,8 +990,12 @@ func (desc *PdfFontDescriptor) String() string {
 }
 
 // newPdfFontDescriptorFromPdfObject loads the font descriptor from a core.PdfObject.  Can either be a
-// *PdfIndirectObject or a *core.PdfObjectDictionary.
-func newPdfFontDescriptorFromPdfObject(obj core.PdfObject) (*PdfFontDescriptor, error) {
+// *PdfIndirectObject or a *core.PdfObjectDictionary. Its problems are logged to `logger`, or to
+// common.Log if it is nil.
+func newPdfFontDescriptorFromPdfObject(obj core.PdfObject, logger common.Logger) (*PdfFontDescriptor, error) {
+	if logger == nil {
+		logger = common.Log
+	}
 	descriptor := &PdfFontDescriptor{}
 
 	obj = core.ResolveReference(obj)
@@ -957,25 +1006,25 @@ func newPdfFontDescriptorFromPdfObject(obj core.PdfObject) (*PdfFontDescriptor,
 
 	d, ok := core.GetDict(obj)
 	if !ok {
-		common.Log.Debug("ERROR: FontDescriptor not given by a dictionary (%T)", obj)
+		logger.Debug("ERROR: FontDescriptor not given by a dictionary (%T)", obj)
 		return nil, core.ErrTypeError
 	}
 
 	if obj := d.Get("FontName"); obj != nil {
 		descriptor.FontName = obj
 	} else {
-		common.Log.Debug("Incompatibility: FontName (Required) missing")
+		logger.Debug("Incompatibility: FontName (Required) missing")
 	}
 	fontname, _ := core.GetName(descriptor.FontName)
 
 	if obj := d.Get("Type"); obj != nil {
 		oname, is := obj.(*core.PdfObjectName)
 		if !is || string(*oname) != "FontDescriptor" {
-			common.Log.Debug("Incompatibility: Font descriptor Type invalid (%T) font=%q %T",
+			logger.Debug("Incompatibility: Font descriptor Type invalid (%T) font=%q %T",
 				obj, fontname, descriptor.FontName)
 		}
 	} else {
-		common.Log.Trace("Incompatibility: Type (Required) missing. font=%q %T",
+		logger.Trace("Incompatibility: Type (Required) missing. font=%q %T",
 			fontname, descriptor.FontName)
 	}
 
@@ -1020,7 +1069,7 @@ func newPdfFontDescriptorFromPdfObject(obj core.PdfObject) (*PdfFontDescriptor,
 		if err != nil {
 			return descriptor, err
 		}
-		common.Log.Trace("fontFile=%s", fontFile)
+		logger.Trace("fontFile=%s", fontFile)
 		descriptor.fontFile = fontFile
 	}
 	if descriptor.FontFile2 != nil {
@@ -1028,7 +1077,7 @@ func newPdfFontDescriptorFromPdfObject(obj core.PdfObject) (*PdfFontDescriptor,
 		if err != nil {
 			return descriptor, err
 		}
-		common.Log.Trace("fontFile2=%s", fontFile2.String())
+		logger.Trace("fontFile2=%s", fontFile2.String())
 		descriptor.fontFile2 = &fontFile2
 	}
 	return descriptor, nil
@@ -135,7 +135,7 @@ func (font *pdfFontType0) getFontDescriptor() *PdfFontDescriptor {
 // A bool flag is returned to indicate whether or not the entry was found.
 func (font pdfFontType0) GetRuneMetrics(r rune) (fonts.CharMetrics, bool) {
 	if font.DescendantFont == nil {
-		common.Log.Debug("ERROR: No descendant. font=%s", font)
+		font.log().Debug("ERROR: No descendant. font=%s", font)
 		return fonts.CharMetrics{}, false
 	}
 	return font.DescendantFont.GetRuneMetrics(r)
@@ -144,7 +144,7 @@ func (font pdfFontType0) GetRuneMetrics(r rune) (fonts.CharMetrics, bool) {
 // GetCharMetrics returns the char metrics for character code `code`.
 func (font pdfFontType0) GetCharMetrics(code textencoding.CharCode) (fonts.CharMetrics, bool) {
 	if font.DescendantFont == nil {
-		common.Log.Debug("ERROR: No descendant. font=%s", font)
+		font.log().Debug("ERROR: No descendant. font=%s", font)
 		return fonts.CharMetrics{}, false
 	}
 	return font.DescendantFont.GetCharMetrics(code)
@@ -201,35 +201,35 @@ func genSubsetTag() string {
 func (font *pdfFontType0) subsetRegistered() error {
 	cidfnt, ok := font.DescendantFont.context.(*pdfCIDFontType2)
 	if !ok {
-		common.Log.Debug("Font not supported for subsetting %T", font.DescendantFont)
+		font.log().Debug("Font not supported for subsetting %T", font.DescendantFont)
 		return nil
 	}
 	if cidfnt == nil {
 		return nil
 	}
 	if cidfnt.fontDescriptor == nil {
-		common.Log.Debug("Missing font descriptor")
+		font.log().Debug("Missing font descriptor")
 		return nil
 	}
 	if font.encoder == nil {
-		common.Log.Debug("No encoder - subsetting ignored")
+		font.log().Debug("No encoder - subsetting ignored")
 		return nil
 	}
 
 	stream, ok := core.GetStream(cidfnt.fontDescriptor.FontFile2)
 	if !ok {
-		common.Log.Debug("Embedded font object not found -- ABORT subsetting")
+		font.log().Debug("Embedded font object not found -- ABORT subsetting")
 		return errors.New("fontfile2 not found")
 	}
 	decoded, err := core.DecodeStream(stream)
 	if err != nil {
-		common.Log.Debug("Decode error: %v", err)
+		font.log().Debug("Decode error: %v", err)
 		return err
 	}
 
 	fnt, err := unitype.Parse(bytes.NewReader(decoded))
 	if err != nil {
-		common.Log.Debug("Error parsing %d byte font", len(stream.Stream))
+		font.log().Debug("Error parsing %d byte font", len(stream.Stream))
 		return err
 	}
 
@@ -241,7 +241,7 @@ func (font *pdfFontType0) subsetRegistered() error {
 		runes = tenc.RegisteredRunes()
 		subset, err = fnt.SubsetKeepRunes(runes)
 		if err != nil {
-			common.Log.Debug("ERROR: %v", err)
+			font.log().Debug("ERROR: %v", err)
 			return err
 		}
 		// Reduce the encoder also.
@@ -257,7 +257,7 @@ func (font *pdfFontType0) subsetRegistered() error {
 
 		subset, err = fnt.SubsetKeepIndices(indices)
 		if err != nil {
-			common.Log.Debug("ERROR: %v", err)
+			font.log().Debug("ERROR: %v", err)
 			return err
 		}
 	case textencoding.SimpleEncoder:
@@ -266,7 +266,7 @@ func (font *pdfFontType0) subsetRegistered() error {
 		for _, c := range charcodes {
 			r, ok := tenc.CharcodeToRune(c)
 			if !ok {
-				common.Log.Debug("ERROR: unable convert charcode to rune: %d", c)
+				font.log().Debug("ERROR: unable convert charcode to rune: %d", c)
 				continue
 			}
 			runes = append(runes, r)
@@ -278,7 +278,7 @@ func (font *pdfFontType0) subsetRegistered() error {
 	var buf bytes.Buffer
 	err = subset.Write(&buf)
 	if err != nil {
-		common.Log.Debug("ERROR: %v", err)
+		font.log().Debug("ERROR: %v", err)
 		return err
 	}
 
@@ -297,7 +297,7 @@ func (font *pdfFontType0) subsetRegistered() error {
 
 	stream, err = core.MakeStream(buf.Bytes(), core.NewFlateEncoder())
 	if err != nil {
-		common.Log.Debug("ERROR: %v", err)
+		font.log().Debug("ERROR: %v", err)
 		return err
 	}
 	stream.Set("Length1", core.MakeInteger(int64(buf.Len())))
@@ -360,16 +360,16 @@ func newPdfFontType0FromPdfObject(d *core.PdfObjectDictionary, base *fontCommon)
 	// DescendantFonts.
 	arr, ok := core.GetArray(d.Get("DescendantFonts"))
 	if !ok {
-		common.Log.Debug("ERROR: Invalid DescendantFonts - not an array %s", base)
+		base.log().Debug("ERROR: Invalid DescendantFonts - not an array %s", base)
 		return nil, core.ErrRangeError
 	}
 	if arr.Len() != 1 {
-		common.Log.Debug("ERROR: Array length != 1 (%d)", arr.Len())
+		base.log().Debug("ERROR: Array length != 1 (%d)", arr.Len())
 		return nil, core.ErrRangeError
 	}
-	df, err := newPdfFontFromPdfObject(arr.Get(0), false)
+	df, err := newPdfFontFromPdfObject(arr.Get(0), false, base.logger)
 	if err != nil {
-		common.Log.Debug("ERROR: Failed loading descendant font: err=%v %s", err, base)
+		base.log().Debug("ERROR: Failed loading descendant font: err=%v %s", err, base)
 		return nil, err
 	}
 
@@ -384,10 +384,10 @@ func newPdfFontType0FromPdfObject(d *core.PdfObjectDictionary, base *fontCommon)
 		} else if cmap.IsPredefinedCMap(encoderName) {
 			font.codeToCID, err = cmap.LoadPredefinedCMap(encoderName)
 			if err != nil {
-				common.Log.Debug("WARN: could not load predefined CMap %s: %v", encoderName, err)
+				base.log().Debug("WARN: could not load predefined CMap %s: %v", encoderName, err)
 			}
 		} else {
-			common.Log.Debug("Unhandled cmap %q", encoderName)
+			base.log().Debug("Unhandled cmap %q", encoderName)
 		}
 	}
 
@@ -473,7 +473,7 @@ func (font *pdfCIDFontType0) ToPdfObject() core.PdfObject {
 // or via indirect object). If a problem occurs with loading an error is returned.
 func newPdfCIDFontType0FromPdfObject(d *core.PdfObjectDictionary, base *fontCommon) (*pdfCIDFontType0, error) {
 	if base.subtype != "CIDFontType0" {
-		common.Log.Debug("ERROR: Font SubType != CIDFontType0. font=%s", base)
+		base.log().Debug("ERROR: Font SubType != CIDFontType0. font=%s", base)
 		return nil, core.ErrRangeError
 	}
 
@@ -482,7 +482,7 @@ func newPdfCIDFontType0FromPdfObject(d *core.PdfObjectDictionary, base *fontComm
 	// CIDSystemInfo.
 	obj, ok := core.GetDict(d.Get("CIDSystemInfo"))
 	if !ok {
-		common.Log.Debug("ERROR: CIDSystemInfo (Required) missing. font=%s", base)
+		base.log().Debug("ERROR: CIDSystemInfo (Required) missing. font=%s", base)
 		return nil, ErrRequiredAttributeMissing
 	}
 	font.CIDSystemInfo = obj
@@ -630,7 +630,7 @@ func (font *pdfCIDFontType2) ToPdfObject() core.PdfObject {
 // or via indirect object). If a problem occurs with loading, an error is returned.
 func newPdfCIDFontType2FromPdfObject(d *core.PdfObjectDictionary, base *fontCommon) (*pdfCIDFontType2, error) {
 	if base.subtype != "CIDFontType2" {
-		common.Log.Debug("ERROR: Font SubType != CIDFontType2. font=%s", base)
+		base.log().Debug("ERROR: Font SubType != CIDFontType2. font=%s", base)
 		return nil, core.ErrRangeError
 	}
 
@@ -639,7 +639,7 @@ func newPdfCIDFontType2FromPdfObject(d *core.PdfObjectDictionary, base *fontComm
 	// CIDSystemInfo.
 	obj, ok := core.GetDict(d.Get("CIDSystemInfo"))
 	if !ok {
-		common.Log.Debug("ERROR: CIDSystemInfo (Required) missing. font=%s", base)
+		base.log().Debug("ERROR: CIDSystemInfo (Required) missing. font=%s", base)
 		return nil, ErrRequiredAttributeMissing
 	}
 	font.CIDSystemInfo = obj
@@ -121,13 +121,13 @@ func (font pdfFontSimple) GetRuneMetrics(r rune) (fonts.CharMetrics, bool) {
 	}
 	encoder := font.Encoder()
 	if encoder == nil {
-		common.Log.Debug("No encoder for fonts=%s", font)
+		font.log().Debug("No encoder for fonts=%s", font)
 		return fonts.CharMetrics{}, false
 	}
 	code, found := encoder.RuneToCharcode(r)
 	if !found {
 		if r != ' ' {
-			common.Log.Trace("No charcode for rune=%v font=%s", r, font)
+			font.log().Trace("No charcode for rune=%v font=%s", r, font)
 		}
 		return fonts.CharMetrics{}, false
 	}
@@ -175,7 +175,7 @@ func newSimpleFontFromPdfObject(d *core.PdfObjectDictionary, base *fontCommon,
 
 		intVal, ok := core.GetIntVal(obj)
 		if !ok {
-			common.Log.Debug("ERROR: Invalid FirstChar type (%T)", obj)
+			base.log().Debug("ERROR: Invalid FirstChar type (%T)", obj)
 			return nil, core.ErrTypeError
 		}
 		firstChar := textencoding.CharCode(intVal)
@@ -187,7 +187,7 @@ func newSimpleFontFromPdfObject(d *core.PdfObjectDictionary, base *fontCommon,
 		font.LastChar = obj
 		intVal, ok = core.GetIntVal(obj)
 		if !ok {
-			common.Log.Debug("ERROR: Invalid LastChar type (%T)", obj)
+			base.log().Debug("ERROR: Invalid LastChar type (%T)", obj)
 			return nil, core.ErrTypeError
 		}
 		lastChar := textencoding.CharCode(intVal)
@@ -199,18 +199,18 @@ func newSimpleFontFromPdfObject(d *core.PdfObjectDictionary, base *fontCommon,
 
 			arr, ok := core.GetArray(obj)
 			if !ok {
-				common.Log.Debug("ERROR: Widths attribute != array (%T)", obj)
+				base.log().Debug("ERROR: Widths attribute != array (%T)", obj)
 				return nil, core.ErrTypeError
 			}
 
 			widths, err := arr.ToFloat64Array()
 			if err != nil {
-				common.Log.Debug("ERROR: converting widths to array")
+				base.log().Debug("ERROR: converting widths to array")
 				return nil, err
 			}
 
 			if len(widths) != int(lastChar-firstChar+1) {
-				common.Log.Debug("ERROR: Invalid widths length != %d (%d)",
+				base.log().Debug("ERROR: Invalid widths length != %d (%d)",
 					lastChar-firstChar+1, len(widths))
 				return nil, core.ErrRangeError
 			}
@@ -247,7 +247,7 @@ func (font *pdfFontSimple) addEncoding() error {
 	if font.Encoding != nil {
 		baseEncoderName, differences, err := font.getFontEncoding()
 		if err != nil {
-			common.Log.Debug("ERROR: BaseFont=%q Subtype=%q Encoding=%s (%T) err=%v", font.basefont,
+			font.log().Debug("ERROR: BaseFont=%q Subtype=%q Encoding=%s (%T) err=%v", font.basefont,
 				font.subtype, font.Encoding, font.Encoding, err)
 			return err
 		}
@@ -267,12 +267,12 @@ func (font *pdfFontSimple) addEncoding() error {
 			switch font.subtype {
 			case "Type1":
 				if descriptor.fontFile != nil && descriptor.fontFile.encoder != nil {
-					common.Log.Debug("Using fontFile")
+					font.log().Debug("Using fontFile")
 					encoder = descriptor.fontFile.encoder
 				}
 			case "TrueType":
 				if descriptor.fontFile2 != nil {
-					common.Log.Debug("Using FontFile2")
+					font.log().Debug("Using FontFile2")
 					enc, err := descriptor.fontFile2.MakeEncoder()
 					if err == nil {
 						encoder = enc
@@ -285,7 +285,7 @@ func (font *pdfFontSimple) addEncoding() error {
 	if encoder != nil {
 		// At the end, apply the differences.
 		if differences != nil {
-			common.Log.Trace("differences=%+v font=%s", differences, font.baseFields())
+			font.log().Trace("differences=%+v font=%s", differences, font.baseFields())
 			encoder = textencoding.ApplyDifferences(encoder, differences)
 		}
 		font.SetEncoder(encoder)
@@ -335,7 +335,7 @@ func (font *pdfFontSimple) getFontEncoding() (baseName string, differences map[t
 		if diffObj := encoding.Get("Differences"); diffObj != nil {
 			diffList, ok := core.GetArray(diffObj)
 			if !ok {
-				common.Log.Debug("ERROR: Bad font encoding dict=%+v Differences=%T",
+				font.log().Debug("ERROR: Bad font encoding dict=%+v Differences=%T",
 					encoding, encoding.Get("Differences"))
 				return "", nil, core.ErrTypeError
 			}
@@ -343,7 +343,7 @@ func (font *pdfFontSimple) getFontEncoding() (baseName string, differences map[t
 		}
 		return baseName, differences, err
 	default:
-		common.Log.Debug("ERROR: Encoding not a name or dict (%T) %s", font.Encoding, font.Encoding)
+		font.log().Debug("ERROR: Encoding not a name or dict (%T) %s", font.Encoding, font.Encoding)
 		return "", nil, core.ErrTypeError
 	}
 }
@@ -520,7 +520,7 @@ func (font *pdfFontSimple) updateStandard14Font() {
 	se, ok := font.Encoder().(textencoding.SimpleEncoder)
 	if !ok {
 		// This can't happen.
-		common.Log.Error("Wrong encoder type: %T. font=%s.", font.Encoder(), font)
+		font.log().Error("Wrong encoder type: %T. font=%s.", font.Encoder(), font)
 		return
 	}
 
@@ -99,17 +99,17 @@ func (r *PdfReader) newPdfAcroFormFromDict(container *core.PdfIndirectObject, d
 			container, isIndirect := core.GetIndirect(obj)
 			if !isIndirect {
 				if _, isNull := obj.(*core.PdfObjectNull); isNull {
-					common.Log.Trace("Skipping over null field")
+					r.log().Trace("Skipping over null field")
 					continue
 				}
-				common.Log.Debug("Field not contained in indirect object %T", obj)
+				r.log().Debug("Field not contained in indirect object %T", obj)
 				return nil, fmt.Errorf("field not in an indirect object")
 			}
 			field, err := r.newPdfFieldFromIndirectObject(container, nil)
 			if err != nil {
 				return nil, err
 			}
-			common.Log.Trace("AcroForm Field: %+v", *field)
+			r.log().Trace("AcroForm Field: %+v", *field)
 			fields = append(fields, field)
 		}
 		acroForm.Fields = &fields
@@ -120,7 +120,7 @@ func (r *PdfReader) newPdfAcroFormFromDict(container *core.PdfIndirectObject, d
 		if ok {
 			acroForm.NeedAppearances = val
 		} else {
-			common.Log.Debug("ERROR: NeedAppearances invalid (got %T)", obj)
+			r.log().Debug("ERROR: NeedAppearances invalid (got %T)", obj)
 		}
 	}
 
@@ -129,7 +129,7 @@ func (r *PdfReader) newPdfAcroFormFromDict(container *core.PdfIndirectObject, d
 		if ok {
 			acroForm.SigFlags = val
 		} else {
-			common.Log.Debug("ERROR: SigFlags invalid (got %T)", obj)
+			r.log().Debug("ERROR: SigFlags invalid (got %T)", obj)
 		}
 	}
 
@@ -138,7 +138,7 @@ func (r *PdfReader) newPdfAcroFormFromDict(container *core.PdfIndirectObject, d
 		if ok {
 			acroForm.CO = arr
 		} else {
-			common.Log.Debug("ERROR: CO invalid (got %T)", obj)
+			r.log().Debug("ERROR: CO invalid (got %T)", obj)
 		}
 	}
 
@@ -146,13 +146,13 @@ func (r *PdfReader) newPdfAcroFormFromDict(container *core.PdfIndirectObject, d
 		if d, ok := core.GetDict(obj); ok {
 			resources, err := NewPdfPageResourcesFromDict(d)
 			if err != nil {
-				common.Log.Error("Invalid DR: %v", err)
+				r.log().Error("Invalid DR: %v", err)
 				return nil, err
 			}
 
 			acroForm.DR = resources
 		} else {
-			common.Log.Debug("ERROR: DR invalid (got %T)", obj)
+			r.log().Debug("ERROR: DR invalid (got %T)", obj)
 		}
 	}
 
@@ -161,7 +161,7 @@ func (r *PdfReader) newPdfAcroFormFromDict(container *core.PdfIndirectObject, d
 		if ok {
 			acroForm.DA = str
 		} else {
-			common.Log.Debug("ERROR: DA invalid (got %T)", obj)
+			r.log().Debug("ERROR: DA invalid (got %T)", obj)
 		}
 	}
 
@@ -170,7 +170,7 @@ func (r *PdfReader) newPdfAcroFormFromDict(container *core.PdfIndirectObject, d
 		if ok {
 			acroForm.Q = val
 		} else {
-			common.Log.Debug("ERROR: Q invalid (got %T)", obj)
+			r.log().Debug("ERROR: Q invalid (got %T)", obj)
 		}
 	}
 
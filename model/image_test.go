@@ -9,9 +9,12 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
+	"io/ioutil"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
 )
 
 func TestImageResampling(t *testing.T) {
@@ -379,3 +382,49 @@ func BenchmarkImageCopyingDirect(b *testing.B) {
 		}
 	}
 }
+
+func TestXObjectImageJPX(t *testing.T) {
+	data, err := ioutil.ReadFile("./testdata/rgba.jp2")
+	require.NoError(t, err)
+
+	newStream := func(smaskInData int64) *core.PdfObjectStream {
+		dict := core.MakeDict()
+		dict.Set("Type", core.MakeName("XObject"))
+		dict.Set("Subtype", core.MakeName("Image"))
+		dict.Set("Width", core.MakeInteger(16))
+		dict.Set("Height", core.MakeInteger(12))
+		dict.Set("Filter", core.MakeName("JPXDecode"))
+		dict.Set("SMaskInData", core.MakeInteger(smaskInData))
+		return &core.PdfObjectStream{PdfObjectDictionary: dict, Stream: data}
+	}
+
+	// ColorSpace and BitsPerComponent are taken from the JPEG 2000 data.
+	ximg, err := NewXObjectImageFromStream(newStream(0))
+	require.NoError(t, err)
+	require.Equal(t, "DeviceRGB", ximg.ColorSpace.String())
+	require.Equal(t, int64(8), *ximg.BitsPerComponent)
+
+	img, err := ximg.ToImage()
+	require.NoError(t, err)
+	require.Equal(t, 3, img.ColorComponents)
+	require.Len(t, img.Data, 16*12*3)
+	require.False(t, img.hasAlpha)
+
+	goImg, err := img.ToGoImage()
+	require.NoError(t, err)
+	require.Equal(t, color.RGBA{R: 5 * 16, G: 7 * 20, B: 128, A: 255}, goImg.At(5, 7))
+
+	// The opacity channel is the soft mask with SMaskInData.
+	ximg, err = NewXObjectImageFromStream(newStream(1))
+	require.NoError(t, err)
+	img, err = ximg.ToImage()
+	require.NoError(t, err)
+	require.True(t, img.hasAlpha)
+	require.Equal(t, byte((5+7)*8), img.alphaData[7*16+5])
+
+	// The derived entries are not written to the image dictionary.
+	stream, ok := ximg.ToPdfObject().(*core.PdfObjectStream)
+	require.True(t, ok)
+	require.Nil(t, stream.Get("ColorSpace"))
+	require.Nil(t, stream.Get("BitsPerComponent"))
+}
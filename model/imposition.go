@@ -0,0 +1,243 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// NewXObjectFormFromPage returns a form XObject with the content and the resources of `page`, for
+// placing the page on other pages. The form shows the visible area of the page (its crop box or
+// media box) as displayed, rotated by the Rotate entry of the page, with its lower left corner at
+// the origin. Its size is returned with the form.
+func NewXObjectFormFromPage(page *PdfPage) (*XObjectForm, float64, float64, error) {
+	geom, err := page.GetGeometry()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	box := geom.CropBox
+	content, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	resources := page.Resources
+	if resources == nil {
+		resources, err = page.getParentResources()
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if resources == nil {
+			resources = NewPdfPageResources()
+		}
+	}
+
+	// The matrix rotates the visible area clockwise and moves it to the origin.
+	matrix, width, height := rotatedBoxMatrix(box, geom.Rotate)
+
+	xform := NewXObjectForm()
+	xform.FormType = core.MakeInteger(1)
+	xform.BBox = box.ToPdfObject()
+	xform.Matrix = core.MakeArrayFromFloats(matrix[:])
+	xform.Resources = resources
+	if err := xform.SetContentStream([]byte(content), core.NewFlateEncoder()); err != nil {
+		return nil, 0, 0, err
+	}
+	return xform, width, height, nil
+}
+
+// fitRect returns the scale and the position which fit a `width` by `height` area into `rect`,
+// centered and keeping the aspect ratio.
+func fitRect(width, height float64, rect PdfRectangle) (scale, x, y float64) {
+	scale = math.Min(rect.Width()/width, rect.Height()/height)
+	x = rect.Llx + (rect.Width()-scale*width)/2
+	y = rect.Lly + (rect.Height()-scale*height)/2
+	return scale, x, y
+}
+
+// formPlacement is a form XObject scaled and drawn at a position of a page.
+type formPlacement struct {
+	xform       *XObjectForm
+	scale, x, y float64
+}
+
+// newPageFromForms returns a new page of size `width` by `height` which draws the forms of
+// `placements`.
+func newPageFromForms(width, height float64, placements []formPlacement) (*PdfPage, error) {
+	page := NewPdfPage()
+	page.MediaBox = &PdfRectangle{Urx: width, Ury: height}
+	page.Resources = NewPdfPageResources()
+
+	var ops []string
+	for i, pl := range placements {
+		name := core.PdfObjectName(fmt.Sprintf("Pg%d", i))
+		if err := page.Resources.SetXObjectFormByName(name, pl.xform); err != nil {
+			return nil, err
+		}
+		ops = append(ops, "q",
+			fmt.Sprintf("%.4f 0 0 %.4f %.4f %.4f cm", pl.scale, pl.scale, pl.x, pl.y),
+			fmt.Sprintf("/%s Do", name),
+			"Q")
+	}
+	if err := page.AddContentStreamByString(strings.Join(ops, "\n")); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// ScalePageToFit returns a new page of size `width` by `height` showing `page` scaled to fit,
+// centered and keeping its aspect ratio. The content of the page is placed as a form XObject.
+func ScalePageToFit(page *PdfPage, width, height float64) (*PdfPage, error) {
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("invalid page size")
+	}
+	xform, formWidth, formHeight, err := NewXObjectFormFromPage(page)
+	if err != nil {
+		return nil, err
+	}
+	if formWidth <= 0 || formHeight <= 0 {
+		common.Log.Debug("ERROR: Empty page area (%.2f x %.2f)", formWidth, formHeight)
+		return nil, errors.New("empty page area")
+	}
+	scale, x, y := fitRect(formWidth, formHeight, PdfRectangle{Urx: width, Ury: height})
+	return newPageFromForms(width, height, []formPlacement{{xform: xform, scale: scale, x: x, y: y}})
+}
+
+// NUpOptions defines the layout of the sheets of an imposition: the source pages are placed on
+// a grid of cells, left to right and top to bottom, each scaled to fit its cell.
+type NUpOptions struct {
+	Columns int
+	Rows    int
+
+	// SheetWidth and SheetHeight are the size of the sheets. If not set, the cells have the size of
+	// the first page.
+	SheetWidth  float64
+	SheetHeight float64
+
+	// Margin is the space around the grid and Spacing the space between the cells.
+	Margin  float64
+	Spacing float64
+
+	// Booklet places the pages in booklet order, for sheets folded in the middle and nested: with
+	// 2 columns and 1 row, the sides of the sheets are printed in order and duplex. The pages are
+	// padded with blank pages to a multiple of 4.
+	Booklet bool
+}
+
+// NewNUpOptions returns the options of the `n`-up layouts with 2 (2x1), 4 (2x2), 6 (3x2), 8 (4x2),
+// 9 (3x3) and 16 (4x4) pages per sheet.
+func NewNUpOptions(n int) (*NUpOptions, error) {
+	grids := map[int][2]int{2: {2, 1}, 4: {2, 2}, 6: {3, 2}, 8: {4, 2}, 9: {3, 3}, 16: {4, 4}}
+	grid, ok := grids[n]
+	if !ok {
+		return nil, fmt.Errorf("unsupported %d-up layout", n)
+	}
+	return &NUpOptions{Columns: grid[0], Rows: grid[1]}, nil
+}
+
+// NewBookletOptions returns the options of a 2-up booklet.
+func NewBookletOptions() *NUpOptions {
+	return &NUpOptions{Columns: 2, Rows: 1, Booklet: true}
+}
+
+// bookletOrder returns the page indices of the sides of booklet sheets for `numPages` pages, two
+// per side, padded to a multiple of 4 with -1 for blank pages.
+func bookletOrder(numPages int) []int {
+	total := (numPages + 3) / 4 * 4
+	order := make([]int, 0, total)
+	for i := 0; i < total/4; i++ {
+		// Front: last and first remaining pages, back: the next ones.
+		order = append(order, total-1-2*i, 2*i, 2*i+1, total-2-2*i)
+	}
+	for i, index := range order {
+		if index >= numPages {
+			order[i] = -1
+		}
+	}
+	return order
+}
+
+// ImposePages places `pages` on sheets according to `opts` and returns the sheets as new pages.
+// The pages are placed as form XObjects, scaled to fit their cells.
+func ImposePages(pages []*PdfPage, opts *NUpOptions) ([]*PdfPage, error) {
+	if opts == nil || opts.Columns < 1 || opts.Rows < 1 {
+		return nil, errors.New("invalid imposition grid")
+	}
+	if opts.Booklet && (opts.Columns != 2 || opts.Rows != 1) {
+		return nil, errors.New("booklet requires 2 columns and 1 row")
+	}
+	if len(pages) == 0 {
+		return nil, nil
+	}
+
+	type form struct {
+		xform         *XObjectForm
+		width, height float64
+	}
+	forms := make([]form, len(pages))
+	for i, page := range pages {
+		xform, width, height, err := NewXObjectFormFromPage(page)
+		if err != nil {
+			return nil, err
+		}
+		if width <= 0 || height <= 0 {
+			common.Log.Debug("ERROR: Empty page area of page %d (%.2f x %.2f)", i+1, width, height)
+			return nil, errors.New("empty page area")
+		}
+		forms[i] = form{xform: xform, width: width, height: height}
+	}
+
+	cols, rows := float64(opts.Columns), float64(opts.Rows)
+	sheetWidth, sheetHeight := opts.SheetWidth, opts.SheetHeight
+	if sheetWidth <= 0 || sheetHeight <= 0 {
+		sheetWidth = cols*forms[0].width + (cols-1)*opts.Spacing + 2*opts.Margin
+		sheetHeight = rows*forms[0].height + (rows-1)*opts.Spacing + 2*opts.Margin
+	}
+	cellWidth := (sheetWidth - 2*opts.Margin - (cols-1)*opts.Spacing) / cols
+	cellHeight := (sheetHeight - 2*opts.Margin - (rows-1)*opts.Spacing) / rows
+	if cellWidth <= 0 || cellHeight <= 0 {
+		return nil, errors.New("sheet too small for margins and spacing")
+	}
+
+	order := make([]int, len(pages))
+	for i := range order {
+		order[i] = i
+	}
+	if opts.Booklet {
+		order = bookletOrder(len(pages))
+	}
+
+	perSheet := opts.Columns * opts.Rows
+	var sheets []*PdfPage
+	for start := 0; start < len(order); start += perSheet {
+		var placements []formPlacement
+		for cell := 0; cell < perSheet && start+cell < len(order); cell++ {
+			index := order[start+cell]
+			if index < 0 {
+				continue
+			}
+			col, row := cell%opts.Columns, cell/opts.Columns
+			llx := opts.Margin + float64(col)*(cellWidth+opts.Spacing)
+			lly := sheetHeight - opts.Margin - float64(row+1)*cellHeight - float64(row)*opts.Spacing
+			rect := PdfRectangle{Llx: llx, Lly: lly, Urx: llx + cellWidth, Ury: lly + cellHeight}
+
+			f := forms[index]
+			scale, x, y := fitRect(f.width, f.height, rect)
+			placements = append(placements, formPlacement{xform: f.xform, scale: scale, x: x, y: y})
+		}
+		sheet, err := newPageFromForms(sheetWidth, sheetHeight, placements)
+		if err != nil {
+			return nil, err
+		}
+		sheets = append(sheets, sheet)
+	}
+	return sheets, nil
+}
@@ -0,0 +1,285 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// PdfInfoTrapped specifies whether the document has been modified to include trapping
+// information (the Trapped entry of the document information dictionary).
+type PdfInfoTrapped string
+
+const (
+	// TrappedTrue indicates that the document has been fully trapped.
+	TrappedTrue PdfInfoTrapped = "True"
+	// TrappedFalse indicates that the document has not been trapped.
+	TrappedFalse PdfInfoTrapped = "False"
+	// TrappedUnknown indicates that it is not known whether the document has been trapped.
+	TrappedUnknown PdfInfoTrapped = "Unknown"
+)
+
+// pdfInfoStandardKeys are the keys of the document information dictionary defined by the
+// standard (14.3.3 Document Information Dictionary).
+var pdfInfoStandardKeys = map[core.PdfObjectName]bool{
+	"Title":        true,
+	"Author":       true,
+	"Subject":      true,
+	"Keywords":     true,
+	"Creator":      true,
+	"Producer":     true,
+	"CreationDate": true,
+	"ModDate":      true,
+	"Trapped":      true,
+}
+
+// PdfInfo represents a document information dictionary (14.3.3), with the standard entries and
+// custom entries. The text entries are decoded from and encoded to PDF text strings.
+type PdfInfo struct {
+	Title        string
+	Author       string
+	Subject      string
+	Keywords     string
+	Creator      string // The application which created the original document.
+	Producer     string // The application which converted the document to PDF.
+	CreationDate *PdfDate
+	ModifiedDate *PdfDate
+	Trapped      PdfInfoTrapped
+
+	// Custom entries, in order.
+	customKeys   []string
+	customValues map[string]string
+}
+
+// NewPdfInfo returns a new empty document information dictionary.
+func NewPdfInfo() *PdfInfo {
+	return &PdfInfo{customValues: map[string]string{}}
+}
+
+// NewPdfInfoFromObject loads a document information dictionary from `obj`.
+// Invalid entries are skipped.
+func NewPdfInfoFromObject(obj core.PdfObject) (*PdfInfo, error) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		common.Log.Debug("ERROR: Info not a dictionary (%T)", obj)
+		return nil, ErrTypeCheck
+	}
+
+	info := NewPdfInfo()
+	for _, key := range dict.Keys() {
+		val := core.TraceToDirectObject(dict.Get(key))
+		switch key {
+		case "CreationDate", "ModDate":
+			str, ok := core.GetString(val)
+			if !ok {
+				common.Log.Debug("Invalid Info %s (%T) - skipping", key, val)
+				continue
+			}
+			date, err := NewPdfDate(str.Str())
+			if err != nil {
+				common.Log.Debug("Invalid Info %s (%v) - skipping", key, err)
+				continue
+			}
+			if key == "CreationDate" {
+				info.CreationDate = &date
+			} else {
+				info.ModifiedDate = &date
+			}
+		case "Trapped":
+			switch t := val.(type) {
+			case *core.PdfObjectName:
+				info.Trapped = PdfInfoTrapped(*t)
+			case *core.PdfObjectBool:
+				// Some producers write booleans.
+				info.Trapped = TrappedFalse
+				if bool(*t) {
+					info.Trapped = TrappedTrue
+				}
+			default:
+				common.Log.Debug("Invalid Info Trapped (%T) - skipping", val)
+			}
+		default:
+			str, ok := core.GetString(val)
+			if !ok {
+				common.Log.Debug("Invalid Info %s (%T) - skipping", key, val)
+				continue
+			}
+			info.setText(key, str.Decoded())
+		}
+	}
+	return info, nil
+}
+
+// setText sets the text entry `key` to `value`.
+func (info *PdfInfo) setText(key core.PdfObjectName, value string) {
+	switch key {
+	case "Title":
+		info.Title = value
+	case "Author":
+		info.Author = value
+	case "Subject":
+		info.Subject = value
+	case "Keywords":
+		info.Keywords = value
+	case "Creator":
+		info.Creator = value
+	case "Producer":
+		info.Producer = value
+	default:
+		info.setCustom(string(key), value)
+	}
+}
+
+// SetCustomInfo sets the custom entry `name` to `value`. An empty value removes the entry.
+// The standard entries cannot be set as custom entries.
+func (info *PdfInfo) SetCustomInfo(name, value string) error {
+	if name == "" {
+		return errors.New("empty custom info name")
+	}
+	if pdfInfoStandardKeys[core.PdfObjectName(name)] {
+		return fmt.Errorf("%s is a standard info entry", name)
+	}
+	info.setCustom(name, value)
+	return nil
+}
+
+// setCustom sets the custom entry `name` to `value`, removing it if `value` is empty.
+func (info *PdfInfo) setCustom(name, value string) {
+	if info.customValues == nil {
+		info.customValues = map[string]string{}
+	}
+	_, exists := info.customValues[name]
+	if value == "" {
+		if exists {
+			delete(info.customValues, name)
+			for i, key := range info.customKeys {
+				if key == name {
+					info.customKeys = append(info.customKeys[:i], info.customKeys[i+1:]...)
+					break
+				}
+			}
+		}
+		return
+	}
+	if !exists {
+		info.customKeys = append(info.customKeys, name)
+	}
+	info.customValues[name] = value
+}
+
+// CustomInfo returns the value of the custom entry `name`, empty if not set.
+func (info *PdfInfo) CustomInfo(name string) string {
+	return info.customValues[name]
+}
+
+// CustomKeys returns the names of the custom entries.
+func (info *PdfInfo) CustomKeys() []string {
+	keys := make([]string, len(info.customKeys))
+	copy(keys, info.customKeys)
+	return keys
+}
+
+// SetCreationDate sets the creation date of the document.
+func (info *PdfInfo) SetCreationDate(t time.Time) {
+	date, _ := NewPdfDateFromTime(t)
+	info.CreationDate = &date
+}
+
+// SetModifiedDate sets the modification date of the document.
+func (info *PdfInfo) SetModifiedDate(t time.Time) {
+	date, _ := NewPdfDateFromTime(t)
+	info.ModifiedDate = &date
+}
+
+// Copy returns a copy of the document information dictionary.
+func (info *PdfInfo) Copy() *PdfInfo {
+	c := *info
+	c.customKeys = info.CustomKeys()
+	c.customValues = make(map[string]string, len(info.customValues))
+	for key, val := range info.customValues {
+		c.customValues[key] = val
+	}
+	if info.CreationDate != nil {
+		date := *info.CreationDate
+		c.CreationDate = &date
+	}
+	if info.ModifiedDate != nil {
+		date := *info.ModifiedDate
+		c.ModifiedDate = &date
+	}
+	return &c
+}
+
+// ToPdfObject returns the document information dictionary. Empty entries are omitted.
+func (info *PdfInfo) ToPdfObject() core.PdfObject {
+	dict := core.MakeDict()
+	texts := []struct {
+		key   core.PdfObjectName
+		value string
+	}{
+		{"Title", info.Title},
+		{"Author", info.Author},
+		{"Subject", info.Subject},
+		{"Keywords", info.Keywords},
+		{"Creator", info.Creator},
+		{"Producer", info.Producer},
+	}
+	for _, text := range texts {
+		if text.value != "" {
+			dict.Set(text.key, makeTextString(text.value))
+		}
+	}
+	if info.CreationDate != nil {
+		dict.Set("CreationDate", info.CreationDate.ToPdfObject())
+	}
+	if info.ModifiedDate != nil {
+		dict.Set("ModDate", info.ModifiedDate.ToPdfObject())
+	}
+	if info.Trapped != "" {
+		dict.Set("Trapped", core.MakeName(string(info.Trapped)))
+	}
+	for _, key := range info.customKeys {
+		dict.Set(core.PdfObjectName(key), makeTextString(info.customValues[key]))
+	}
+	return dict
+}
+
+// makeTextString returns a PDF text string of `s`, encoded in UTF-16BE unless `s` only contains
+// ASCII characters.
+func makeTextString(s string) *core.PdfObjectString {
+	for _, r := range s {
+		if r >= utf8.RuneSelf {
+			return core.MakeEncodedString(s, true)
+		}
+	}
+	return core.MakeString(s)
+}
+
+// GetPdfInfo returns the document information dictionary of the document, or nil if the document
+// has none.
+func (r *PdfReader) GetPdfInfo() (*PdfInfo, error) {
+	if r.parser.GetCrypter() != nil && !r.parser.IsAuthenticated() {
+		return nil, ErrEncrypted
+	}
+	trailer := r.parser.GetTrailer()
+	if trailer == nil {
+		return nil, errors.New("missing trailer")
+	}
+	obj := core.ResolveReference(trailer.Get("Info"))
+	if obj == nil {
+		return nil, nil
+	}
+	if _, isNull := obj.(*core.PdfObjectNull); isNull {
+		return nil, nil
+	}
+	return NewPdfInfoFromObject(obj)
+}
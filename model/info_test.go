@@ -0,0 +1,185 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+func TestPdfInfoFromObject(t *testing.T) {
+	dict := core.MakeDict()
+	dict.Set("Title", core.MakeEncodedString("Título", true))
+	dict.Set("Author", core.MakeString("John Doe"))
+	dict.Set("CreationDate", core.MakeString("D:20200102030405+01'00'"))
+	dict.Set("ModDate", core.MakeString("invalid"))
+	dict.Set("Trapped", core.MakeBool(true))
+	dict.Set("Department", core.MakeString("Sales"))
+	dict.Set("Count", core.MakeInteger(3))
+
+	info, err := NewPdfInfoFromObject(dict)
+	require.NoError(t, err)
+	require.Equal(t, "Título", info.Title)
+	require.Equal(t, "John Doe", info.Author)
+	require.NotNil(t, info.CreationDate)
+	expected := time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("", 3600))
+	require.True(t, expected.Equal(info.CreationDate.ToGoTime()))
+	require.Nil(t, info.ModifiedDate)
+	require.Equal(t, TrappedTrue, info.Trapped)
+	require.Equal(t, []string{"Department"}, info.CustomKeys())
+	require.Equal(t, "Sales", info.CustomInfo("Department"))
+
+	_, err = NewPdfInfoFromObject(core.MakeInteger(1))
+	require.Error(t, err)
+}
+
+func TestPdfInfoCustomInfo(t *testing.T) {
+	info := NewPdfInfo()
+	require.Error(t, info.SetCustomInfo("Title", "x"))
+	require.Error(t, info.SetCustomInfo("", "x"))
+	require.NoError(t, info.SetCustomInfo("B", "1"))
+	require.NoError(t, info.SetCustomInfo("A", "2"))
+	require.NoError(t, info.SetCustomInfo("C", "3"))
+	require.NoError(t, info.SetCustomInfo("A", ""))
+	require.Equal(t, []string{"B", "C"}, info.CustomKeys())
+
+	c := info.Copy()
+	require.NoError(t, c.SetCustomInfo("B", "changed"))
+	require.Equal(t, "1", info.CustomInfo("B"))
+}
+
+func TestWriterDocInfo(t *testing.T) {
+	SetPdfTitle("Global title")
+	defer SetPdfTitle("")
+
+	info := NewPdfInfo()
+	info.Title = "Документ"
+	info.Author = "Jane Doe"
+	info.Producer = "Test producer"
+	info.Trapped = TrappedFalse
+	info.SetCreationDate(time.Date(2021, 5, 6, 7, 8, 9, 0, time.UTC))
+	require.NoError(t, info.SetCustomInfo("Reference", "A-1"))
+
+	w := NewPdfWriter()
+	w.SetDocInfo(info)
+	require.NoError(t, w.AddPage(newPdf20TestPage()))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	r, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	readInfo, err := r.GetPdfInfo()
+	require.NoError(t, err)
+	require.NotNil(t, readInfo)
+	require.Equal(t, "Документ", readInfo.Title)
+	require.Equal(t, "Jane Doe", readInfo.Author)
+	require.Equal(t, "Test producer", readInfo.Producer)
+	require.Equal(t, "", readInfo.Creator)
+	require.Equal(t, TrappedFalse, readInfo.Trapped)
+	require.Equal(t, "A-1", readInfo.CustomInfo("Reference"))
+	require.NotNil(t, readInfo.CreationDate)
+	require.True(t, info.CreationDate.ToGoTime().Equal(readInfo.CreationDate.ToGoTime()))
+
+	// Writers without document information use the package-level values.
+	w = NewPdfWriter()
+	require.NoError(t, w.AddPage(newPdf20TestPage()))
+	buf.Reset()
+	require.NoError(t, w.Write(&buf))
+	r, err = NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	readInfo, err = r.GetPdfInfo()
+	require.NoError(t, err)
+	require.Equal(t, "Global title", readInfo.Title)
+}
+
+func TestWriterXMPMetadata(t *testing.T) {
+	info := NewPdfInfo()
+	info.Title = "Report"
+	info.Author = "Jane Doe"
+	info.SetModifiedDate(time.Date(2021, 5, 6, 7, 8, 9, 0, time.UTC))
+
+	w := NewPdfWriter()
+	w.SetDocInfo(info)
+	packet := NewXMPMetadata()
+	w.SetXMPMetadata(packet)
+	require.NoError(t, w.AddPage(newPdf20TestPage()))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	// The packet set is not changed by the write.
+	_, ok := packet.GetProperty(XMPNamespaceDC, "title")
+	require.False(t, ok)
+
+	r, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	md, err := r.GetXMPMetadata()
+	require.NoError(t, err)
+	require.NotNil(t, md)
+	xmpInfo := md.GetPdfInfo()
+	require.Equal(t, "Report", xmpInfo.Title)
+	require.Equal(t, "Jane Doe", xmpInfo.Author)
+	require.NotEmpty(t, xmpInfo.Producer)
+	require.NotNil(t, xmpInfo.ModifiedDate)
+	require.True(t, info.ModifiedDate.ToGoTime().Equal(xmpInfo.ModifiedDate.ToGoTime()))
+}
+
+func TestAppenderDocInfo(t *testing.T) {
+	// Original document with XMP metadata.
+	original := NewXMPMetadata()
+	original.SetProperty(XMPNamespaceXMP, "Label", "kept")
+	info := NewPdfInfo()
+	info.Title = "Original"
+	w := NewPdfWriter()
+	w.SetDocInfo(info)
+	w.SetXMPMetadata(original)
+	require.NoError(t, w.AddPage(newPdf20TestPage()))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	r, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	appender, err := NewPdfAppender(r)
+	require.NoError(t, err)
+	info.Title = "Updated"
+	appender.SetDocInfo(info)
+	var out bytes.Buffer
+	require.NoError(t, appender.Write(&out))
+
+	r, err = NewPdfReader(bytes.NewReader(out.Bytes()))
+	require.NoError(t, err)
+	readInfo, err := r.GetPdfInfo()
+	require.NoError(t, err)
+	require.Equal(t, "Updated", readInfo.Title)
+	md, err := r.GetXMPMetadata()
+	require.NoError(t, err)
+	require.Equal(t, "Updated", md.GetPdfInfo().Title)
+	label, ok := md.GetProperty(XMPNamespaceXMP, "Label")
+	require.True(t, ok)
+	require.Equal(t, "kept", label)
+	_, ok = original.GetProperty(XMPNamespaceDC, "title")
+	require.False(t, ok)
+
+	// Packet set for the new revision, not changed by the write.
+	appender, err = NewPdfAppender(r)
+	require.NoError(t, err)
+	appender.SetDocInfo(info)
+	packet := NewXMPMetadata()
+	appender.SetXMPMetadata(packet)
+	out.Reset()
+	require.NoError(t, appender.Write(&out))
+	r, err = NewPdfReader(bytes.NewReader(out.Bytes()))
+	require.NoError(t, err)
+	md, err = r.GetXMPMetadata()
+	require.NoError(t, err)
+	require.Equal(t, "Updated", md.GetPdfInfo().Title)
+	_, ok = packet.GetProperty(XMPNamespaceDC, "title")
+	require.False(t, ok)
+}
@@ -0,0 +1,690 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// FieldRenamePolicy returns the new partial name of top-level form field `name` of the document at
+// index `docIndex` (0-based) of a merge, when the name is used by a field of a previous document.
+type FieldRenamePolicy func(name string, docIndex int) string
+
+// RenameFieldWithSuffix is the default field rename policy of PdfMerger: it appends the number of
+// the document (1-based) to the name, as in "name_2".
+func RenameFieldWithSuffix(name string, docIndex int) string {
+	return fmt.Sprintf("%s_%d", name, docIndex+1)
+}
+
+// PdfMerger merges documents into one. The outlines of the documents are placed under an outline
+// item per document, and their form fields, named destinations, name trees, page labels and
+// optional content properties are merged. Identical fonts and images of the documents are
+// written once.
+//
+// The objects of the documents are shared with the merged document and updated in place: the
+// readers should not be used after merging.
+type PdfMerger struct {
+	// RenameField renames the top-level form fields whose names are used by the fields of the
+	// previous documents. RenameFieldWithSuffix is used if not set.
+	RenameField FieldRenamePolicy
+
+	docs []mergerDoc
+}
+
+// mergerDoc is a document to merge.
+type mergerDoc struct {
+	reader *PdfReader
+	title  string
+}
+
+// NewPdfMerger returns a new merger without documents.
+func NewPdfMerger() *PdfMerger {
+	return &PdfMerger{}
+}
+
+// AddReader adds the document of `reader` to the merge. `title` is the title of the outline item
+// of the document, the title of its document information if empty.
+func (m *PdfMerger) AddReader(reader *PdfReader, title string) error {
+	if reader.parser.GetCrypter() != nil && !reader.parser.IsAuthenticated() {
+		return ErrEncrypted
+	}
+	m.docs = append(m.docs, mergerDoc{reader: reader, title: title})
+	return nil
+}
+
+// uniqueName returns `name` of document `docIndex` renamed with the number of the document,
+// and a counter if needed, so that `taken` returns false for it.
+func uniqueName(name string, docIndex int, taken func(string) bool) string {
+	newName := fmt.Sprintf("%s_%d", name, docIndex+1)
+	for i := 2; taken(newName); i++ {
+		newName = fmt.Sprintf("%s_%d_%d", name, docIndex+1, i)
+	}
+	return newName
+}
+
+// Merge merges the documents and returns a writer with the merged document, to which further
+// changes can be made before writing.
+func (m *PdfMerger) Merge() (*PdfWriter, error) {
+	w := NewPdfWriter()
+	if len(m.docs) == 0 {
+		return &w, nil
+	}
+	renameField := m.RenameField
+	if renameField == nil {
+		renameField = RenameFieldWithSuffix
+	}
+
+	outline := NewPdfOutline()
+	var prevItem *PdfOutlineItem
+	var outlineCount int64
+
+	dests := NewPdfNameTree()
+	nameTrees := map[core.PdfObjectName]*PdfNameTree{}
+	var nameTreeKeys []core.PdfObjectName
+
+	var acroForm *PdfAcroForm
+	fieldNames := map[string]struct{}{}
+
+	var labels []*PdfPageLabels
+	hasLabels := false
+	ocProperties := &mergedOCProperties{}
+	dedup := newResourceDeduplicator()
+
+	offset := 0
+	for i, doc := range m.docs {
+		r := doc.reader
+		numPages := len(r.PageList)
+
+		// Page indices and destination names of the document in the merged document.
+		pageNums := make([]int, offset+numPages)
+		for j := 0; j < numPages; j++ {
+			pageNums[offset+j] = j + 1
+		}
+		remapper := newDestRemapper(numPages, pageNums)
+		remapper.names = map[string]string{}
+
+		// Named destinations, renamed if used by a previous document.
+		docDests, err := r.GetNamedDestinationsTree()
+		if err != nil {
+			return nil, err
+		}
+		newNames := map[string]struct{}{}
+		destTaken := func(name string) bool {
+			_, has := dests.Get(name)
+			_, docHas := docDests.Get(name)
+			_, newHas := newNames[name]
+			return has || docHas || newHas
+		}
+		for _, name := range docDests.Keys() {
+			if _, has := dests.Get(name); has {
+				newName := uniqueName(name, i, destTaken)
+				remapper.names[name] = newName
+				newNames[newName] = struct{}{}
+			}
+		}
+		docDests.ForEach(func(name string, value core.PdfObject) bool {
+			remapper.dest(value)
+			if newName, ok := remapper.names[name]; ok {
+				name = newName
+			}
+			dests.Set(name, value)
+			return true
+		})
+
+		// Other name trees of the Names dictionary, such as embedded files.
+		if names, ok := core.GetDict(r.catalog.Get("Names")); ok {
+			for _, key := range names.Keys() {
+				if key == "Dests" {
+					continue
+				}
+				docTree, err := getNamesTree(r.catalog, key)
+				if err != nil {
+					r.log().Debug("ERROR: Invalid %s name tree - skipping: %v", key, err)
+					continue
+				}
+				tree, ok := nameTrees[key]
+				if !ok {
+					tree = NewPdfNameTree()
+					nameTrees[key] = tree
+					nameTreeKeys = append(nameTreeKeys, key)
+				}
+				taken := func(name string) bool {
+					_, has := tree.Get(name)
+					return has
+				}
+				docTree.ForEach(func(name string, value core.PdfObject) bool {
+					if taken(name) {
+						name = uniqueName(name, i, taken)
+					}
+					tree.Set(name, value)
+					return true
+				})
+			}
+		}
+
+		// Pages.
+		for _, page := range r.PageList {
+			if err := dedup.page(page); err != nil {
+				return nil, err
+			}
+			if pDict, ok := core.GetDict(page.ToPdfObject()); ok {
+				remapper.annotations(pDict.Get("Annots"))
+			}
+			if err := w.AddPage(page); err != nil {
+				return nil, err
+			}
+		}
+
+		// Outline item of the document, with the outline of the document as children.
+		item, err := m.outlineItem(i, offset)
+		if err != nil {
+			return nil, err
+		}
+		item.Parent = &outline.PdfOutlineTreeNode
+		if prevItem == nil {
+			outline.First = &item.PdfOutlineTreeNode
+		} else {
+			prevItem.Next = &item.PdfOutlineTreeNode
+			item.Prev = &prevItem.PdfOutlineTreeNode
+		}
+		outline.Last = &item.PdfOutlineTreeNode
+		prevItem = item
+		remapper.outlineTree(item.First)
+		outlineCount++
+		if item.Count != nil && *item.Count > 0 {
+			outlineCount += *item.Count
+		}
+
+		// Form fields, renamed if used by a previous document.
+		if r.AcroForm != nil {
+			if acroForm == nil {
+				acroForm = NewPdfAcroForm()
+			}
+			if err := mergeAcroForm(acroForm, r.AcroForm, i, fieldNames, renameField, r.log()); err != nil {
+				return nil, err
+			}
+		}
+
+		docLabels, err := r.GetPdfPageLabels()
+		if err != nil {
+			r.log().Debug("ERROR: Invalid page labels - skipping: %v", err)
+			docLabels = nil
+		}
+		labels = append(labels, docLabels)
+		hasLabels = hasLabels || docLabels != nil
+
+		ocObj, err := r.GetOCProperties()
+		if err != nil {
+			return nil, err
+		}
+		ocProperties.add(ocObj, r.log())
+
+		offset += numPages
+	}
+
+	if outline.First != nil {
+		outline.Count = &outlineCount
+		w.AddOutlineTree(&outline.PdfOutlineTreeNode)
+	}
+	if err := w.SetNamedDestinationsTree(dests); err != nil {
+		return nil, err
+	}
+	for _, key := range nameTreeKeys {
+		if err := w.addObjects(setNamesTree(w.catalog, key, nameTrees[key])); err != nil {
+			return nil, err
+		}
+	}
+	if acroForm != nil {
+		if err := w.SetForms(acroForm); err != nil {
+			return nil, err
+		}
+	}
+	if hasLabels {
+		if err := w.SetPageLabels(mergePageLabels(m.docs, labels).ToPdfObject()); err != nil {
+			return nil, err
+		}
+	}
+	if obj := ocProperties.toPdfObject(); obj != nil {
+		if err := w.SetOCProperties(obj); err != nil {
+			return nil, err
+		}
+	}
+	return &w, nil
+}
+
+// Write merges the documents and writes the merged document to `w`.
+func (m *PdfMerger) Write(w io.Writer) error {
+	writer, err := m.Merge()
+	if err != nil {
+		return err
+	}
+	return writer.Write(w)
+}
+
+// outlineItem returns the outline item of document `docIndex`, whose first page is at page index
+// `offset` of the merged document, with the items of the outline of the document as children.
+func (m *PdfMerger) outlineItem(docIndex, offset int) (*PdfOutlineItem, error) {
+	doc := m.docs[docIndex]
+	title := doc.title
+	if title == "" {
+		info, err := doc.reader.GetPdfInfo()
+		if err != nil {
+			return nil, err
+		}
+		if info != nil {
+			title = info.Title
+		}
+	}
+	if title == "" {
+		title = fmt.Sprintf("Document %d", docIndex+1)
+	}
+
+	item := NewPdfOutlineItem()
+	item.Title = makeTextString(title)
+	if len(doc.reader.PageList) > 0 {
+		page := doc.reader.PageList[0].GetPageAsIndirectObject()
+		item.Dest = core.MakeArray(page, core.MakeName("Fit"))
+	} else {
+		item.Dest = core.MakeArray(core.MakeInteger(int64(offset)), core.MakeName("Fit"))
+	}
+
+	tree := doc.reader.GetOutlineTree()
+	if tree == nil || tree.First == nil {
+		return item, nil
+	}
+	item.First = tree.First
+	item.Last = tree.Last
+	var count int64
+	visited := map[*PdfOutlineTreeNode]struct{}{}
+	for node := tree.First; node != nil; {
+		if _, has := visited[node]; has {
+			break
+		}
+		visited[node] = struct{}{}
+		child, ok := node.context.(*PdfOutlineItem)
+		if !ok {
+			break
+		}
+		child.Parent = &item.PdfOutlineTreeNode
+		count++
+		if child.Count != nil && *child.Count > 0 {
+			count += *child.Count
+		}
+		node = child.Next
+	}
+	item.Count = &count
+	return item, nil
+}
+
+// mergeAcroForm merges the fields and the form attributes of `src`, of document `docIndex`, into
+// `dst`. The top-level fields whose names are in `fieldNames` are renamed by `renameField`. The
+// problems with `src` are logged to `logger`.
+func mergeAcroForm(dst, src *PdfAcroForm, docIndex int, fieldNames map[string]struct{},
+	renameField FieldRenamePolicy, logger common.Logger) error {
+	var fields []*PdfField
+	if dst.Fields != nil {
+		fields = *dst.Fields
+	}
+	var srcFields []*PdfField
+	if src.Fields != nil {
+		srcFields = *src.Fields
+	}
+	// The renamed fields take no name of the other fields of the document.
+	docNames := map[string]struct{}{}
+	for _, field := range srcFields {
+		if field.T != nil {
+			docNames[field.T.Decoded()] = struct{}{}
+		}
+	}
+	var newNames []string
+	for _, field := range srcFields {
+		fields = append(fields, field)
+		if field.T == nil {
+			continue
+		}
+		name := field.T.Decoded()
+		if _, has := fieldNames[name]; has {
+			newName := renameField(name, docIndex)
+			_, prevHas := fieldNames[newName]
+			_, docHas := docNames[newName]
+			if prevHas || docHas {
+				logger.Debug("ERROR: Field %q renamed to %q, used by another field", name, newName)
+				return fmt.Errorf("renamed field %q conflicts", newName)
+			}
+			logger.Trace("Renaming field %q to %q", name, newName)
+			field.T = makeTextString(newName)
+			docNames[newName] = struct{}{}
+			name = newName
+		}
+		newNames = append(newNames, name)
+	}
+	for _, name := range newNames {
+		fieldNames[name] = struct{}{}
+	}
+	dst.Fields = &fields
+
+	if src.NeedAppearances != nil && bool(*src.NeedAppearances) {
+		dst.NeedAppearances = core.MakeBool(true)
+	}
+	if src.SigFlags != nil {
+		flags := int64(*src.SigFlags)
+		if dst.SigFlags != nil {
+			flags |= int64(*dst.SigFlags)
+		}
+		dst.SigFlags = core.MakeInteger(flags)
+	}
+	if src.CO != nil {
+		if dst.CO == nil {
+			dst.CO = core.MakeArray()
+		}
+		dst.CO.Append(src.CO.Elements()...)
+	}
+	if dst.DA == nil {
+		dst.DA = src.DA
+	}
+	if dst.Q == nil {
+		dst.Q = src.Q
+	}
+	if src.DR != nil {
+		if dst.DR == nil {
+			dst.DR = NewPdfPageResources()
+		}
+		mergeResourceCategory(&dst.DR.Font, src.DR.Font)
+		mergeResourceCategory(&dst.DR.XObject, src.DR.XObject)
+		mergeResourceCategory(&dst.DR.ColorSpace, src.DR.ColorSpace)
+		mergeResourceCategory(&dst.DR.ExtGState, src.DR.ExtGState)
+	}
+	if src.XFA != nil {
+		logger.Debug("Form XFA cannot be merged - dropping")
+	}
+	return nil
+}
+
+// mergeResourceCategory adds the resources of resource dictionary `src` whose names are not in
+// `dst`, creating `dst` if needed.
+func mergeResourceCategory(dst *core.PdfObject, src core.PdfObject) {
+	srcDict, ok := core.GetDict(src)
+	if !ok {
+		return
+	}
+	dstDict, ok := core.GetDict(*dst)
+	if !ok {
+		dstDict = core.MakeDict()
+		*dst = dstDict
+	}
+	for _, key := range srcDict.Keys() {
+		if dstDict.Get(key) == nil {
+			dstDict.Set(key, srcDict.Get(key))
+		}
+	}
+}
+
+// mergePageLabels returns the page labels of the merged `docs` with page labels `labels`.
+// The pages of the documents without labels are labelled with their page numbers in their
+// documents.
+func mergePageLabels(docs []mergerDoc, labels []*PdfPageLabels) *PdfPageLabels {
+	merged := NewPdfPageLabels()
+	offset := 0
+	for i, doc := range docs {
+		var ranges []int
+		if labels[i] != nil {
+			ranges = labels[i].Ranges()
+		}
+		if len(ranges) == 0 || ranges[0] != 0 {
+			merged.Set(offset, PdfPageLabel{Style: PageLabelDecimal})
+		}
+		for _, start := range ranges {
+			if label, _, ok := labels[i].Get(start); ok {
+				merged.Set(offset+start, label)
+			}
+		}
+		offset += len(doc.reader.PageList)
+	}
+	return merged
+}
+
+// mergedOCProperties accumulates the optional content properties of merged documents: their
+// optional content groups and the entries of their default configurations.
+type mergedOCProperties struct {
+	ocgs    *core.PdfObjectArray
+	name    core.PdfObject
+	off     *core.PdfObjectArray
+	arrays  map[core.PdfObjectName]*core.PdfObjectArray
+	configs *core.PdfObjectArray
+}
+
+// mergedOCConfigArrays are the array entries of the default configurations which are merged.
+var mergedOCConfigArrays = []core.PdfObjectName{"ON", "Order", "RBGroups", "Locked", "AS"}
+
+// add adds the optional content properties `obj` of a document, logging its problems to `logger`.
+func (oc *mergedOCProperties) add(obj core.PdfObject, logger common.Logger) {
+	props, ok := core.GetDict(obj)
+	if !ok {
+		return
+	}
+	ocgs, ok := core.GetArray(props.Get("OCGs"))
+	if !ok {
+		logger.Debug("Optional content properties without OCGs - skipping")
+		return
+	}
+	if oc.ocgs == nil {
+		oc.ocgs = core.MakeArray()
+		oc.off = core.MakeArray()
+		oc.configs = core.MakeArray()
+		oc.arrays = map[core.PdfObjectName]*core.PdfObjectArray{}
+	}
+	oc.ocgs.Append(ocgs.Elements()...)
+	if configs, ok := core.GetArray(props.Get("Configs")); ok {
+		oc.configs.Append(configs.Elements()...)
+	}
+
+	config, ok := core.GetDict(props.Get("D"))
+	if !ok {
+		return
+	}
+	if oc.name == nil {
+		oc.name = config.Get("Name")
+	}
+	for _, key := range mergedOCConfigArrays {
+		arr, ok := core.GetArray(config.Get(key))
+		if !ok {
+			continue
+		}
+		if oc.arrays[key] == nil {
+			oc.arrays[key] = core.MakeArray()
+		}
+		oc.arrays[key].Append(arr.Elements()...)
+	}
+	if off, ok := core.GetArray(config.Get("OFF")); ok {
+		oc.off.Append(off.Elements()...)
+	}
+	// The merged configuration has the ON base state: the groups of a document with the OFF base
+	// state which are not turned on are turned off.
+	if baseState, _ := core.GetNameVal(config.Get("BaseState")); baseState == "OFF" {
+		on := map[core.PdfObject]struct{}{}
+		if arr, ok := core.GetArray(config.Get("ON")); ok {
+			for _, ocg := range arr.Elements() {
+				on[core.ResolveReference(ocg)] = struct{}{}
+			}
+		}
+		for _, ocg := range ocgs.Elements() {
+			if _, has := on[core.ResolveReference(ocg)]; !has {
+				oc.off.Append(ocg)
+			}
+		}
+	}
+}
+
+// toPdfObject returns the merged optional content properties, or nil if no document has optional
+// content.
+func (oc *mergedOCProperties) toPdfObject() core.PdfObject {
+	if oc.ocgs == nil {
+		return nil
+	}
+	config := core.MakeDict()
+	if oc.name != nil {
+		config.Set("Name", oc.name)
+	}
+	for _, key := range mergedOCConfigArrays {
+		if arr := oc.arrays[key]; arr != nil && arr.Len() > 0 {
+			config.Set(key, arr)
+		}
+	}
+	if oc.off.Len() > 0 {
+		config.Set("OFF", oc.off)
+	}
+	props := core.MakeDict()
+	props.Set("OCGs", oc.ocgs)
+	props.Set("D", config)
+	if oc.configs.Len() > 0 {
+		props.Set("Configs", oc.configs)
+	}
+	return props
+}
+
+// resourceDeduplicator replaces the fonts and images of page resources by the first identical
+// font or image found.
+type resourceDeduplicator struct {
+	byDigest map[string]core.PdfObject
+	forms    map[core.PdfObject]struct{}
+}
+
+// newResourceDeduplicator returns a new deduplicator.
+func newResourceDeduplicator() *resourceDeduplicator {
+	return &resourceDeduplicator{
+		byDigest: map[string]core.PdfObject{},
+		forms:    map[core.PdfObject]struct{}{},
+	}
+}
+
+// page deduplicates the fonts and images of the resources of `page`, and of the form XObjects
+// of the resources. The inherited resources of the page are set on the page.
+func (d *resourceDeduplicator) page(page *PdfPage) error {
+	if page.Resources == nil {
+		resources, err := page.getParentResources()
+		if err != nil {
+			return err
+		}
+		page.Resources = resources
+	}
+	if page.Resources == nil {
+		return nil
+	}
+	d.category(page.Resources.Font, false)
+	d.category(page.Resources.XObject, true)
+	return nil
+}
+
+// category deduplicates the resources of resource category dictionary `obj`, fonts or XObjects.
+func (d *resourceDeduplicator) category(obj core.PdfObject, xobjects bool) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return
+	}
+	for _, key := range dict.Keys() {
+		res := core.ResolveReference(dict.Get(key))
+		switch res.(type) {
+		case *core.PdfIndirectObject, *core.PdfObjectStream:
+		default:
+			// Only indirect resources can be shared.
+			continue
+		}
+		if xobjects {
+			stream, ok := core.GetStream(res)
+			if !ok {
+				continue
+			}
+			subtype, _ := core.GetNameVal(stream.Get("Subtype"))
+			if subtype == "Form" {
+				if _, has := d.forms[stream]; !has {
+					d.forms[stream] = struct{}{}
+					if resources, ok := core.GetDict(stream.Get("Resources")); ok {
+						d.category(resources.Get("Font"), false)
+						d.category(resources.Get("XObject"), true)
+					}
+				}
+				continue
+			}
+			if subtype != "Image" {
+				continue
+			}
+		}
+
+		digest := objectDigest(res)
+		if first, has := d.byDigest[digest]; has {
+			if first != res {
+				dict.Set(key, first)
+			}
+			continue
+		}
+		d.byDigest[digest] = res
+	}
+}
+
+// objectDigest returns the digest of the content of `obj`, including the objects it refers to,
+// independently of object numbers.
+func objectDigest(obj core.PdfObject) string {
+	var buf bytes.Buffer
+	visiting := map[core.PdfObject]struct{}{}
+
+	var write func(obj core.PdfObject)
+	write = func(obj core.PdfObject) {
+		obj = core.ResolveReference(obj)
+		switch t := obj.(type) {
+		case *core.PdfIndirectObject:
+			if _, has := visiting[t]; has {
+				buf.WriteString("@")
+				return
+			}
+			visiting[t] = struct{}{}
+			write(t.PdfObject)
+			delete(visiting, t)
+		case *core.PdfObjectStream:
+			if _, has := visiting[t]; has {
+				buf.WriteString("@")
+				return
+			}
+			visiting[t] = struct{}{}
+			write(t.PdfObjectDictionary)
+			fmt.Fprintf(&buf, "stream %d ", len(t.Stream))
+			buf.Write(t.Stream)
+			delete(visiting, t)
+		case *core.PdfObjectDictionary:
+			keys := t.Keys()
+			sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+			buf.WriteString("<<")
+			for _, key := range keys {
+				buf.WriteString(key.WriteString())
+				buf.WriteString(" ")
+				write(t.Get(key))
+			}
+			buf.WriteString(">>")
+		case *core.PdfObjectArray:
+			buf.WriteString("[")
+			for _, elem := range t.Elements() {
+				write(elem)
+				buf.WriteString(" ")
+			}
+			buf.WriteString("]")
+		case nil:
+			buf.WriteString("null")
+		default:
+			buf.WriteString(obj.WriteString())
+			buf.WriteString(" ")
+		}
+	}
+	write(obj)
+
+	sum := md5.Sum(buf.Bytes())
+	return string(sum[:])
+}
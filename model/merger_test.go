@@ -0,0 +1,207 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// writeMergerTestFile writes a document with a page of width `width` using the Helvetica font,
+// set up by `setup`.
+func writeMergerTestFile(t *testing.T, width float64, setup func(w *PdfWriter, page *PdfPage)) *PdfReader {
+	page := newPageOpsTestPage(width)
+	page.Resources = NewPdfPageResources()
+	font := NewStandard14FontMustCompile(HelveticaName)
+	require.NoError(t, page.Resources.SetFontByName("F1", font.ToPdfObject()))
+	require.NoError(t, page.AddContentStreamByString("BT /F1 12 Tf (Text) Tj ET"))
+
+	w := NewPdfWriter()
+	require.NoError(t, w.AddPage(page))
+	if setup != nil {
+		setup(&w, page)
+	}
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	r, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	return r
+}
+
+func TestMergerDestinations(t *testing.T) {
+	var readers []*PdfReader
+	for i := 0; i < 2; i++ {
+		w := NewPdfWriter()
+		writePageOpsTestFile(t, &w)
+		var buf bytes.Buffer
+		require.NoError(t, w.Write(&buf))
+		r, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		readers = append(readers, r)
+	}
+
+	merger := NewPdfMerger()
+	require.NoError(t, merger.AddReader(readers[0], ""))
+	require.NoError(t, merger.AddReader(readers[1], "Second"))
+	var buf bytes.Buffer
+	require.NoError(t, merger.Write(&buf))
+
+	r, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, r.PageList, 6)
+
+	outline, err := r.GetOutlines()
+	require.NoError(t, err)
+	require.Len(t, outline.Entries, 2)
+	expected := []struct {
+		title            string
+		page, third, one int64
+	}{
+		{"Document 1", 0, 2, 0},
+		{"Second", 3, 5, 3},
+	}
+	for i, exp := range expected {
+		item := outline.Entries[i]
+		require.Equal(t, exp.title, item.Title)
+		require.Equal(t, exp.page, item.Dest.Page)
+		require.Len(t, item.Entries, 2)
+		require.Equal(t, "Third", item.Entries[0].Title)
+		require.Equal(t, exp.third, item.Entries[0].Dest.Page)
+		require.Equal(t, exp.one, item.Entries[1].Dest.Page)
+	}
+
+	tree, err := r.GetNamedDestinationsTree()
+	require.NoError(t, err)
+	require.Equal(t, []string{"third", "third_2"}, tree.Keys())
+	dest, err := r.GetNamedDestination("third_2")
+	require.NoError(t, err)
+	require.Equal(t, int64(5), dest.Page)
+
+	annots, err := r.PageList[3].GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annots, 1)
+	link, ok := annots[0].GetContext().(*PdfAnnotationLink)
+	require.True(t, ok)
+	arr, ok := core.GetArray(link.Dest)
+	require.True(t, ok)
+	index, ok := core.GetIntVal(arr.Get(0))
+	require.True(t, ok)
+	require.Equal(t, 5, index)
+}
+
+func TestMergerForms(t *testing.T) {
+	openForm := func() *PdfReader {
+		f, err := os.Open("./testdata/OoPdfFormExample.pdf")
+		require.NoError(t, err)
+		defer f.Close()
+		r, err := NewPdfReader(f)
+		require.NoError(t, err)
+		return r
+	}
+
+	r := openForm()
+	var names []string
+	for _, field := range *r.AcroForm.Fields {
+		names = append(names, field.PartialName())
+	}
+	require.NotEmpty(t, names)
+
+	merger := NewPdfMerger()
+	merger.RenameField = func(name string, docIndex int) string {
+		return "copy." + name
+	}
+	require.NoError(t, merger.AddReader(r, ""))
+	require.NoError(t, merger.AddReader(openForm(), ""))
+	var buf bytes.Buffer
+	require.NoError(t, merger.Write(&buf))
+
+	r, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.NotNil(t, r.AcroForm)
+	fields := *r.AcroForm.Fields
+	require.Len(t, fields, 2*len(names))
+	for i, name := range names {
+		require.Equal(t, name, fields[i].PartialName())
+		require.Equal(t, "copy."+name, fields[len(names)+i].PartialName())
+	}
+
+	// A policy keeping the names fails.
+	merger = NewPdfMerger()
+	merger.RenameField = func(name string, docIndex int) string {
+		return name
+	}
+	require.NoError(t, merger.AddReader(openForm(), ""))
+	require.NoError(t, merger.AddReader(openForm(), ""))
+	_, err = merger.Merge()
+	require.Error(t, err)
+}
+
+func TestMergerResources(t *testing.T) {
+	r1 := writeMergerTestFile(t, 100, func(w *PdfWriter, page *PdfPage) {
+		labels := NewPdfPageLabels()
+		labels.Set(0, PdfPageLabel{Style: PageLabelLowerRoman})
+		require.NoError(t, w.SetPageLabels(labels.ToPdfObject()))
+	})
+	r2 := writeMergerTestFile(t, 200, func(w *PdfWriter, page *PdfPage) {
+		ocg := core.MakeIndirectObject(core.MakeDict())
+		ocg.PdfObject.(*core.PdfObjectDictionary).Set("Type", core.MakeName("OCG"))
+		ocg.PdfObject.(*core.PdfObjectDictionary).Set("Name", core.MakeString("Layer"))
+		config := core.MakeDict()
+		config.Set("BaseState", core.MakeName("OFF"))
+		config.Set("Order", core.MakeArray(ocg))
+		props := core.MakeDict()
+		props.Set("OCGs", core.MakeArray(ocg))
+		props.Set("D", config)
+		require.NoError(t, w.SetOCProperties(props))
+	})
+
+	merger := NewPdfMerger()
+	require.NoError(t, merger.AddReader(r1, ""))
+	require.NoError(t, merger.AddReader(r2, ""))
+	var buf bytes.Buffer
+	require.NoError(t, merger.Write(&buf))
+
+	r, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, r.PageList, 2)
+
+	// The identical fonts are written once.
+	var fontObjs []int64
+	for _, page := range r.PageList {
+		font, ok := page.Resources.GetFontByName("F1")
+		require.True(t, ok)
+		ind, ok := core.GetIndirect(font)
+		require.True(t, ok)
+		fontObjs = append(fontObjs, ind.ObjectNumber)
+	}
+	require.Equal(t, fontObjs[0], fontObjs[1])
+
+	labels, err := r.GetPdfPageLabels()
+	require.NoError(t, err)
+	require.NotNil(t, labels)
+	require.Equal(t, "i", labels.Label(0))
+	require.Equal(t, "1", labels.Label(1))
+
+	obj, err := r.GetOCProperties()
+	require.NoError(t, err)
+	props, ok := core.GetDict(obj)
+	require.True(t, ok)
+	ocgs, ok := core.GetArray(props.Get("OCGs"))
+	require.True(t, ok)
+	require.Equal(t, 1, ocgs.Len())
+	config, ok := core.GetDict(props.Get("D"))
+	require.True(t, ok)
+	off, ok := core.GetArray(config.Get("OFF"))
+	require.True(t, ok)
+	require.Equal(t, 1, off.Len())
+	require.Nil(t, config.Get("BaseState"))
+}
@@ -0,0 +1,68 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// GetNamedDestinationsTree returns the named destinations of the document (12.3.2.3 Named
+// Destinations): the Dests name tree of the Names dictionary, with the destinations of the Dests
+// dictionary of the catalog (PDF 1.1) which are not in the tree.
+// The values are destination arrays or dictionaries with the destination in their D entry.
+func (r *PdfReader) GetNamedDestinationsTree() (*PdfNameTree, error) {
+	if r.parser.GetCrypter() != nil && !r.parser.IsAuthenticated() {
+		return nil, ErrEncrypted
+	}
+	tree, err := getNamesTree(r.catalog, "Dests")
+	if err != nil {
+		return nil, err
+	}
+	if dests, ok := core.GetDict(r.catalog.Get("Dests")); ok {
+		for _, name := range dests.Keys() {
+			if _, has := tree.Get(string(name)); !has {
+				tree.Set(string(name), dests.Get(name))
+			}
+		}
+	}
+	return tree, nil
+}
+
+// GetNamedDestination returns the destination named `name`, or nil if the document has no such
+// destination.
+func (r *PdfReader) GetNamedDestination(name string) (*OutlineDest, error) {
+	tree, err := r.GetNamedDestinationsTree()
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := tree.Get(name)
+	if !ok {
+		return nil, nil
+	}
+	obj = core.TraceToDirectObject(obj)
+	if dict, ok := obj.(*core.PdfObjectDictionary); ok {
+		obj = core.TraceToDirectObject(dict.Get("D"))
+	}
+	dest, err := newOutlineDestFromPdfObject(obj, r)
+	if err != nil {
+		r.log().Debug("ERROR: Invalid named destination %q: %v", name, err)
+		return nil, err
+	}
+	return dest, nil
+}
+
+// SetNamedDestinationsTree sets the Dests name tree of the Names dictionary of the catalog, keeping
+// the other name trees of the dictionary. The values of the tree are destinations, such as the
+// PDF objects of OutlineDest values.
+func (w *PdfWriter) SetNamedDestinationsTree(tree *PdfNameTree) error {
+	if tree == nil {
+		return nil
+	}
+
+	w.log().Trace("Setting catalog Names Dests...")
+	names := setNamesTree(w.catalog, "Dests", tree)
+	return w.addObjects(names)
+}
@@ -0,0 +1,247 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// treeNodeSize is the maximum number of entries of a leaf node and of kids of an intermediate node
+// of the name and number trees written.
+const treeNodeSize = 64
+
+// treeEntry is a key-value entry of a name or number tree.
+type treeEntry struct {
+	key   core.PdfObject
+	value core.PdfObject
+}
+
+// walkTree calls `fn` for the entries of the name or number tree with root node `root`, where the
+// entries are in the `entriesKey` arrays (Names or Nums) of the nodes, in the order of the tree.
+func walkTree(root core.PdfObject, entriesKey core.PdfObjectName, fn func(key, value core.PdfObject)) error {
+	visited := map[core.PdfObject]struct{}{}
+
+	var walk func(node core.PdfObject) error
+	walk = func(node core.PdfObject) error {
+		node = core.ResolveReference(node)
+		if _, has := visited[node]; has {
+			common.Log.Debug("ERROR: Tree node visited twice")
+			return errors.New("tree cycle")
+		}
+		visited[node] = struct{}{}
+
+		dict, ok := core.GetDict(node)
+		if !ok {
+			common.Log.Debug("ERROR: Tree node not a dictionary (%T)", node)
+			return ErrTypeCheck
+		}
+		if entries, ok := core.GetArray(dict.Get(entriesKey)); ok {
+			for i := 0; i+1 < entries.Len(); i += 2 {
+				fn(entries.Get(i), entries.Get(i+1))
+			}
+		}
+		if kids, ok := core.GetArray(dict.Get("Kids")); ok {
+			for _, kid := range kids.Elements() {
+				if err := walk(kid); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return walk(root)
+}
+
+// splitEven splits `n` items into the ranges of the fewest groups of at most `size` items, with
+// sizes differing by at most one.
+func splitEven(n, size int) [][2]int {
+	count := (n + size - 1) / size
+	ranges := make([][2]int, 0, count)
+	start := 0
+	for i := 0; i < count; i++ {
+		end := start + n/count
+		if i < n%count {
+			end++
+		}
+		ranges = append(ranges, [2]int{start, end})
+		start = end
+	}
+	return ranges
+}
+
+// buildTree returns the root node of a balanced tree with the sorted entries `entries` in the
+// `entriesKey` arrays of the leaf nodes. The intermediate and leaf nodes are indirect objects with
+// the Limits of their keys.
+func buildTree(entries []treeEntry, entriesKey core.PdfObjectName) *core.PdfObjectDictionary {
+	makeEntries := func(entries []treeEntry) *core.PdfObjectArray {
+		arr := core.MakeArray()
+		for _, entry := range entries {
+			arr.Append(entry.key, entry.value)
+		}
+		return arr
+	}
+
+	root := core.MakeDict()
+	if len(entries) <= treeNodeSize {
+		root.Set(entriesKey, makeEntries(entries))
+		return root
+	}
+
+	type node struct {
+		obj         *core.PdfIndirectObject
+		first, last core.PdfObject
+	}
+	makeNode := func(dict *core.PdfObjectDictionary, first, last core.PdfObject) node {
+		dict.Set("Limits", core.MakeArray(first, last))
+		return node{obj: core.MakeIndirectObject(dict), first: first, last: last}
+	}
+
+	var nodes []node
+	for _, r := range splitEven(len(entries), treeNodeSize) {
+		leaf := core.MakeDict()
+		leaf.Set(entriesKey, makeEntries(entries[r[0]:r[1]]))
+		nodes = append(nodes, makeNode(leaf, entries[r[0]].key, entries[r[1]-1].key))
+	}
+	for len(nodes) > treeNodeSize {
+		var parents []node
+		for _, r := range splitEven(len(nodes), treeNodeSize) {
+			kids := core.MakeArray()
+			for _, kid := range nodes[r[0]:r[1]] {
+				kids.Append(kid.obj)
+			}
+			parent := core.MakeDict()
+			parent.Set("Kids", kids)
+			parents = append(parents, makeNode(parent, nodes[r[0]].first, nodes[r[1]-1].last))
+		}
+		nodes = parents
+	}
+
+	kids := core.MakeArray()
+	for _, kid := range nodes {
+		kids.Append(kid.obj)
+	}
+	root.Set("Kids", kids)
+	return root
+}
+
+// PdfNameTree represents a name tree (7.9.6 Name Trees): values keyed by strings, sorted in byte
+// order. The tree is balanced with the Limits of the nodes when written.
+type PdfNameTree struct {
+	keys   []string
+	values map[string]core.PdfObject
+}
+
+// NewPdfNameTree returns a new empty name tree.
+func NewPdfNameTree() *PdfNameTree {
+	return &PdfNameTree{values: map[string]core.PdfObject{}}
+}
+
+// NewPdfNameTreeFromObject loads the name tree with root node `obj`. Invalid entries are skipped,
+// the last value of a key found more than once is kept.
+func NewPdfNameTreeFromObject(obj core.PdfObject) (*PdfNameTree, error) {
+	tree := NewPdfNameTree()
+	err := walkTree(obj, "Names", func(key, value core.PdfObject) {
+		str, ok := core.GetString(key)
+		if !ok {
+			common.Log.Debug("Invalid name tree key (%T) - skipping", key)
+			return
+		}
+		tree.Set(str.Str(), value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// Len returns the number of entries of the tree.
+func (t *PdfNameTree) Len() int {
+	return len(t.keys)
+}
+
+// Keys returns the keys of the tree, in order.
+func (t *PdfNameTree) Keys() []string {
+	keys := make([]string, len(t.keys))
+	copy(keys, t.keys)
+	return keys
+}
+
+// Get returns the value of `key`.
+func (t *PdfNameTree) Get(key string) (core.PdfObject, bool) {
+	value, ok := t.values[key]
+	return value, ok
+}
+
+// Set sets the value of `key` to `value`, inserting the key if needed.
+func (t *PdfNameTree) Set(key string, value core.PdfObject) {
+	if _, has := t.values[key]; !has {
+		i := sort.SearchStrings(t.keys, key)
+		t.keys = append(t.keys, "")
+		copy(t.keys[i+1:], t.keys[i:])
+		t.keys[i] = key
+	}
+	t.values[key] = value
+}
+
+// Remove removes `key` from the tree. Returns true if the tree had the key.
+func (t *PdfNameTree) Remove(key string) bool {
+	if _, has := t.values[key]; !has {
+		return false
+	}
+	delete(t.values, key)
+	i := sort.SearchStrings(t.keys, key)
+	t.keys = append(t.keys[:i], t.keys[i+1:]...)
+	return true
+}
+
+// ForEach calls `fn` for the entries of the tree in key order, until `fn` returns false.
+func (t *PdfNameTree) ForEach(fn func(key string, value core.PdfObject) bool) {
+	for _, key := range t.Keys() {
+		if !fn(key, t.values[key]) {
+			return
+		}
+	}
+}
+
+// ToPdfObject returns the root node of the tree.
+func (t *PdfNameTree) ToPdfObject() core.PdfObject {
+	entries := make([]treeEntry, len(t.keys))
+	for i, key := range t.keys {
+		entries[i] = treeEntry{key: core.MakeString(key), value: t.values[key]}
+	}
+	return buildTree(entries, "Names")
+}
+
+// getNamesTree returns the name tree `key` of the Names dictionary of `catalog`, empty if not set.
+func getNamesTree(catalog *core.PdfObjectDictionary, key core.PdfObjectName) (*PdfNameTree, error) {
+	names, ok := core.GetDict(catalog.Get("Names"))
+	if !ok || names.Get(key) == nil {
+		return NewPdfNameTree(), nil
+	}
+	return NewPdfNameTreeFromObject(names.Get(key))
+}
+
+// setNamesTree sets the name tree `key` of the Names dictionary of `catalog` to `tree`, removing
+// it if the tree is empty. The Names dictionary is replaced by a direct copy, which is returned.
+func setNamesTree(catalog *core.PdfObjectDictionary, key core.PdfObjectName, tree *PdfNameTree) *core.PdfObjectDictionary {
+	names := core.MakeDict()
+	if orig, ok := core.GetDict(catalog.Get("Names")); ok {
+		for _, k := range orig.Keys() {
+			names.Set(k, orig.Get(k))
+		}
+	}
+	if tree.Len() > 0 {
+		names.Set(key, tree.ToPdfObject())
+	} else {
+		names.Remove(key)
+	}
+	catalog.Set("Names", names)
+	return names
+}
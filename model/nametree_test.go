@@ -0,0 +1,188 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// checkTreeNode checks the Limits and the depth of the nodes of a tree written by buildTree, and
+// returns the keys of its entries.
+func checkTreeNode(t *testing.T, node core.PdfObject, entriesKey core.PdfObjectName, isRoot bool,
+	depth int, leafDepths map[int]bool) []core.PdfObject {
+	dict, ok := core.GetDict(node)
+	require.True(t, ok)
+
+	var keys []core.PdfObject
+	if entries, ok := core.GetArray(dict.Get(entriesKey)); ok {
+		require.LessOrEqual(t, entries.Len(), 2*treeNodeSize)
+		for i := 0; i < entries.Len(); i += 2 {
+			keys = append(keys, entries.Get(i))
+		}
+		leafDepths[depth] = true
+	}
+	if kids, ok := core.GetArray(dict.Get("Kids")); ok {
+		require.LessOrEqual(t, kids.Len(), treeNodeSize)
+		for _, kid := range kids.Elements() {
+			_, ok := kid.(*core.PdfIndirectObject)
+			require.True(t, ok)
+			keys = append(keys, checkTreeNode(t, kid, entriesKey, false, depth+1, leafDepths)...)
+		}
+	}
+	if isRoot {
+		require.Nil(t, dict.Get("Limits"))
+	} else {
+		limits, ok := core.GetArray(dict.Get("Limits"))
+		require.True(t, ok)
+		require.Equal(t, keys[0].String(), limits.Get(0).String())
+		require.Equal(t, keys[len(keys)-1].String(), limits.Get(1).String())
+	}
+	return keys
+}
+
+func TestPdfNameTree(t *testing.T) {
+	tree := NewPdfNameTree()
+	tree.Set("b", core.MakeInteger(2))
+	tree.Set("a", core.MakeInteger(1))
+	tree.Set("c", core.MakeInteger(3))
+	tree.Set("b", core.MakeInteger(20))
+	require.Equal(t, []string{"a", "b", "c"}, tree.Keys())
+	val, ok := tree.Get("b")
+	require.True(t, ok)
+	require.Equal(t, "20", val.String())
+	require.True(t, tree.Remove("a"))
+	require.False(t, tree.Remove("a"))
+	_, ok = tree.Get("a")
+	require.False(t, ok)
+
+	var visited []string
+	tree.ForEach(func(key string, value core.PdfObject) bool {
+		visited = append(visited, key)
+		return false
+	})
+	require.Equal(t, []string{"b"}, visited)
+
+	// Small trees have a single node.
+	root, ok := core.GetDict(tree.ToPdfObject())
+	require.True(t, ok)
+	require.Nil(t, root.Get("Kids"))
+	require.Equal(t, "[(b) 20 (c) 3]", root.Get("Names").WriteString())
+}
+
+func TestPdfNameTreeBalanced(t *testing.T) {
+	const n = 5000
+	tree := NewPdfNameTree()
+	for i := n - 1; i >= 0; i-- {
+		tree.Set(fmt.Sprintf("name%05d", i), core.MakeInteger(int64(i)))
+	}
+	require.Equal(t, n, tree.Len())
+
+	root := tree.ToPdfObject()
+	leafDepths := map[int]bool{}
+	keys := checkTreeNode(t, root, "Names", true, 0, leafDepths)
+	require.Len(t, keys, n)
+	require.Equal(t, map[int]bool{2: true}, leafDepths)
+
+	loaded, err := NewPdfNameTreeFromObject(root)
+	require.NoError(t, err)
+	require.Equal(t, tree.Keys(), loaded.Keys())
+
+	// Removing entries rebalances the tree.
+	for i := 0; i < n-100; i++ {
+		require.True(t, loaded.Remove(fmt.Sprintf("name%05d", i)))
+	}
+	leafDepths = map[int]bool{}
+	keys = checkTreeNode(t, loaded.ToPdfObject(), "Names", true, 0, leafDepths)
+	require.Len(t, keys, 100)
+	require.Equal(t, map[int]bool{1: true}, leafDepths)
+}
+
+func TestPdfNumberTree(t *testing.T) {
+	tree := NewPdfNumberTree()
+	for _, key := range []int64{10, 0, 300, 5} {
+		tree.Set(key, core.MakeInteger(key*2))
+	}
+	require.Equal(t, []int64{0, 5, 10, 300}, tree.Keys())
+
+	floor, val, ok := tree.Floor(7)
+	require.True(t, ok)
+	require.Equal(t, int64(5), floor)
+	require.Equal(t, "10", val.String())
+	floor, _, ok = tree.Floor(300)
+	require.True(t, ok)
+	require.Equal(t, int64(300), floor)
+	_, _, ok = tree.Floor(-1)
+	require.False(t, ok)
+
+	for i := int64(1000); i < 1200; i++ {
+		tree.Set(i, core.MakeNull())
+	}
+	root := tree.ToPdfObject()
+	leafDepths := map[int]bool{}
+	keys := checkTreeNode(t, root, "Nums", true, 0, leafDepths)
+	require.Len(t, keys, 204)
+	require.Equal(t, map[int]bool{1: true}, leafDepths)
+
+	loaded, err := NewPdfNumberTreeFromObject(root)
+	require.NoError(t, err)
+	require.Equal(t, tree.Keys(), loaded.Keys())
+	require.True(t, loaded.Remove(5))
+	require.Equal(t, 203, loaded.Len())
+}
+
+func TestNamedDestinations(t *testing.T) {
+	page1 := newPdf20TestPage()
+	page2 := newPdf20TestPage()
+	w := NewPdfWriter()
+	require.NoError(t, w.AddPage(page1))
+	require.NoError(t, w.AddPage(page2))
+
+	dest := NewOutlineDest(1, 10, 20)
+	dest.PageObj = page2.GetPageAsIndirectObject()
+	tree := NewPdfNameTree()
+	tree.Set("chapter2", dest.ToPdfObject())
+	destDict := core.MakeDict()
+	destDict.Set("D", core.MakeArray(page1.GetPageAsIndirectObject(), core.MakeName("Fit")))
+	tree.Set("chapter1", destDict)
+
+	// The other name trees of the Names dictionary are kept.
+	f, err := NewPdfEmbeddedFile("data.txt", []byte("data"))
+	require.NoError(t, err)
+	require.NoError(t, w.AddEmbeddedFile(f))
+	require.NoError(t, w.SetNamedDestinationsTree(tree))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	r, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	dests, err := r.GetNamedDestinationsTree()
+	require.NoError(t, err)
+	require.Equal(t, []string{"chapter1", "chapter2"}, dests.Keys())
+
+	d, err := r.GetNamedDestination("chapter2")
+	require.NoError(t, err)
+	require.NotNil(t, d)
+	require.Equal(t, int64(1), d.Page)
+	require.Equal(t, "XYZ", d.Mode)
+	require.Equal(t, 10.0, d.X)
+	d, err = r.GetNamedDestination("chapter1")
+	require.NoError(t, err)
+	require.Equal(t, int64(0), d.Page)
+	require.Equal(t, "Fit", d.Mode)
+	d, err = r.GetNamedDestination("missing")
+	require.NoError(t, err)
+	require.Nil(t, d)
+
+	files, err := r.GetEmbeddedFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+}
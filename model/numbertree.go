@@ -0,0 +1,120 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"sort"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// PdfNumberTree represents a number tree (7.9.7 Number Trees): values keyed by integers, in
+// ascending order. The tree is balanced with the Limits of the nodes when written.
+type PdfNumberTree struct {
+	keys   []int64
+	values map[int64]core.PdfObject
+}
+
+// NewPdfNumberTree returns a new empty number tree.
+func NewPdfNumberTree() *PdfNumberTree {
+	return &PdfNumberTree{values: map[int64]core.PdfObject{}}
+}
+
+// NewPdfNumberTreeFromObject loads the number tree with root node `obj`. Invalid entries are
+// skipped, the last value of a key found more than once is kept.
+func NewPdfNumberTreeFromObject(obj core.PdfObject) (*PdfNumberTree, error) {
+	tree := NewPdfNumberTree()
+	err := walkTree(obj, "Nums", func(key, value core.PdfObject) {
+		num, ok := core.GetIntVal(key)
+		if !ok {
+			common.Log.Debug("Invalid number tree key (%T) - skipping", key)
+			return
+		}
+		tree.Set(int64(num), value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// Len returns the number of entries of the tree.
+func (t *PdfNumberTree) Len() int {
+	return len(t.keys)
+}
+
+// Keys returns the keys of the tree, in ascending order.
+func (t *PdfNumberTree) Keys() []int64 {
+	keys := make([]int64, len(t.keys))
+	copy(keys, t.keys)
+	return keys
+}
+
+// search returns the index of the first key not less than `key`.
+func (t *PdfNumberTree) search(key int64) int {
+	return sort.Search(len(t.keys), func(i int) bool { return t.keys[i] >= key })
+}
+
+// Get returns the value of `key`.
+func (t *PdfNumberTree) Get(key int64) (core.PdfObject, bool) {
+	value, ok := t.values[key]
+	return value, ok
+}
+
+// Floor returns the greatest key not greater than `key` and its value, for trees whose entries
+// apply to ranges of keys such as page labels.
+func (t *PdfNumberTree) Floor(key int64) (int64, core.PdfObject, bool) {
+	i := t.search(key)
+	if i < len(t.keys) && t.keys[i] == key {
+		return key, t.values[key], true
+	}
+	if i == 0 {
+		return 0, nil, false
+	}
+	floor := t.keys[i-1]
+	return floor, t.values[floor], true
+}
+
+// Set sets the value of `key` to `value`, inserting the key if needed.
+func (t *PdfNumberTree) Set(key int64, value core.PdfObject) {
+	if _, has := t.values[key]; !has {
+		i := t.search(key)
+		t.keys = append(t.keys, 0)
+		copy(t.keys[i+1:], t.keys[i:])
+		t.keys[i] = key
+	}
+	t.values[key] = value
+}
+
+// Remove removes `key` from the tree. Returns true if the tree had the key.
+func (t *PdfNumberTree) Remove(key int64) bool {
+	if _, has := t.values[key]; !has {
+		return false
+	}
+	delete(t.values, key)
+	i := t.search(key)
+	t.keys = append(t.keys[:i], t.keys[i+1:]...)
+	return true
+}
+
+// ForEach calls `fn` for the entries of the tree in key order, until `fn` returns false.
+func (t *PdfNumberTree) ForEach(fn func(key int64, value core.PdfObject) bool) {
+	for _, key := range t.Keys() {
+		if !fn(key, t.values[key]) {
+			return
+		}
+	}
+}
+
+// ToPdfObject returns the root node of the tree.
+func (t *PdfNumberTree) ToPdfObject() core.PdfObject {
+	entries := make([]treeEntry, len(t.keys))
+	for i, key := range t.keys {
+		entries[i] = treeEntry{key: core.MakeInteger(key), value: t.values[key]}
+	}
+	return buildTree(entries, "Nums")
+}
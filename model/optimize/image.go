@@ -27,6 +27,22 @@ type imageInfo struct {
 	Height           int
 	Stream           *core.PdfObjectStream
 	PPI              float64
+
+	// jpx is set for JPXDecode images, whose dictionary entries are updated for the new filter.
+	jpx bool
+}
+
+// jpxEncoder returns the JPX encoder of the JPXDecode image `stream`, or nil for other filters.
+func jpxEncoder(stream *core.PdfObjectStream) *core.JPXEncoder {
+	if name, ok := core.GetNameVal(stream.PdfObjectDictionary.Get("Filter")); !ok || name != core.StreamEncodingFilterNameJPX {
+		return nil
+	}
+	encoder, err := core.NewEncoderFromStream(stream)
+	if err != nil {
+		return nil
+	}
+	jpxEnc, _ := encoder.(*core.JPXEncoder)
+	return jpxEnc
 }
 
 // findImages returns images from objects.
@@ -50,13 +66,27 @@ func findImages(objects []core.PdfObject) []*imageInfo {
 			continue
 		}
 		img := &imageInfo{BitsPerComponent: 8, Stream: stream}
-		if img.ColorSpace, err = model.DetermineColorspaceNameFromPdfObject(stream.PdfObjectDictionary.Get("ColorSpace")); err != nil {
+		jpxEnc := jpxEncoder(stream)
+		if jpxEnc != nil && jpxEnc.SMaskInData > 0 {
+			common.Log.Debug("Optimization is not supported for JPX images with SMaskInData")
+			continue
+		}
+		if csObj := stream.PdfObjectDictionary.Get("ColorSpace"); csObj == nil && jpxEnc != nil {
+			// The colorspace of JPXDecode images is optional, taken from the JPEG 2000 data.
+			img.ColorSpace = jpxEnc.ColorSpace
+			img.jpx = true
+		} else if img.ColorSpace, err = model.DetermineColorspaceNameFromPdfObject(csObj); err != nil {
 			common.Log.Error("Error determine color space %s", err)
 			continue
 		}
 		if val, ok := core.GetIntVal(stream.PdfObjectDictionary.Get("BitsPerComponent")); ok {
 			img.BitsPerComponent = val
 		}
+		if jpxEnc != nil {
+			// JPXDecode images are decoded with 8 or 16 bits per component.
+			img.BitsPerComponent = jpxEnc.BitsPerComponent
+			img.jpx = true
+		}
 		if val, ok := core.GetIntVal(stream.PdfObjectDictionary.Get("Width")); ok {
 			img.Width = val
 		}
@@ -155,6 +185,11 @@ func (i *Image) Optimize(objects []core.PdfObject) (optimizedObjects []core.PdfO
 		newStream.Merge(stream.PdfObjectDictionary)
 		newStream.Merge(filter.MakeStreamDict())
 		newStream.Set("Length", core.MakeInteger(int64(len(streamData))))
+		if img.jpx {
+			newStream.Set("ColorSpace", core.MakeName(string(img.ColorSpace)))
+			newStream.Set("BitsPerComponent", core.MakeInteger(int64(img.BitsPerComponent)))
+			newStream.Remove("SMaskInData")
+		}
 		replaceTable[stream] = newStream
 		images[index].Stream = newStream
 	}
@@ -61,6 +61,12 @@ func scaleImage(stream *core.PdfObjectStream, scale float64) error {
 		return err
 	}
 
+	// JPXDecode images are written with the DCT encoder, as JPX encoding is not supported.
+	if _, isJPX := xImg.Filter.(*core.JPXEncoder); isJPX {
+		xImg.Filter = core.NewDCTEncoder()
+		xImg.SMaskInData = nil
+	}
+
 	// Update quality and predictor parameters. All other image parameters would be updated
 	// in the SetImage method of the *XObjectImage.
 	encoderParams := core.MakeDict()
@@ -0,0 +1,1038 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// PdfOCState is the state of optional content groups in configurations and usage dictionaries.
+type PdfOCState string
+
+// Optional content states.
+const (
+	// OCStateUnset means that the state is not specified.
+	OCStateUnset PdfOCState = ""
+	// OCStateOn means that the content is visible.
+	OCStateOn PdfOCState = "ON"
+	// OCStateOff means that the content is hidden.
+	OCStateOff PdfOCState = "OFF"
+	// OCStateUnchanged means that the states of the default configuration are kept (BaseState of
+	// alternate configurations only).
+	OCStateUnchanged PdfOCState = "Unchanged"
+)
+
+// PdfOCMDPolicy is the visibility policy of an optional content membership dictionary.
+type PdfOCMDPolicy string
+
+// Visibility policies of optional content membership dictionaries.
+const (
+	// OCMDAllOn makes the content visible if all the groups are ON.
+	OCMDAllOn PdfOCMDPolicy = "AllOn"
+	// OCMDAnyOn makes the content visible if any group is ON (default).
+	OCMDAnyOn PdfOCMDPolicy = "AnyOn"
+	// OCMDAnyOff makes the content visible if any group is OFF.
+	OCMDAnyOff PdfOCMDPolicy = "AnyOff"
+	// OCMDAllOff makes the content visible if all the groups are OFF.
+	OCMDAllOff PdfOCMDPolicy = "AllOff"
+)
+
+// PdfOCUsage represents an optional content usage dictionary (8.11.4.4 Usage and Usage Application
+// Dictionaries): how the content of a group is used, to set its state automatically with the usage
+// application dictionaries of a configuration.
+type PdfOCUsage struct {
+	// Creator is the application which created the group and CreatorSubtype the type of content,
+	// such as Artwork or Technical (CreatorInfo entry).
+	Creator        string
+	CreatorSubtype string
+
+	// Lang is the language of the content and LangPreferred indicates whether the group is ON when
+	// it matches the language of the system (Language entry).
+	Lang          string
+	LangPreferred bool
+
+	// ExportState is the state of the group when exported (Export entry).
+	ExportState PdfOCState
+
+	// ZoomMin and ZoomMax are the range of magnifications at which the group is ON, nil for no
+	// limit (Zoom entry).
+	ZoomMin *float64
+	ZoomMax *float64
+
+	// PrintSubtype is the kind of content, such as Trapping or Watermark, and PrintState the state
+	// of the group when printed (Print entry).
+	PrintSubtype string
+	PrintState   PdfOCState
+
+	// ViewState is the state of the group when the document is opened (View entry).
+	ViewState PdfOCState
+
+	// UserType is Ind, Ttl or Org and UserNames the names of the users for whom the content is
+	// intended (User entry).
+	UserType  string
+	UserNames []string
+
+	// PageElement is the type of page element of the content: HF, FG, BG or L (PageElement entry).
+	PageElement string
+}
+
+// newPdfOCUsageFromObject loads a usage dictionary.
+func newPdfOCUsageFromObject(obj core.PdfObject) *PdfOCUsage {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return nil
+	}
+	usage := &PdfOCUsage{}
+	if d, ok := core.GetDict(dict.Get("CreatorInfo")); ok {
+		usage.Creator = getTextString(d.Get("Creator"))
+		usage.CreatorSubtype, _ = core.GetNameVal(d.Get("Subtype"))
+	}
+	if d, ok := core.GetDict(dict.Get("Language")); ok {
+		usage.Lang = getTextString(d.Get("Lang"))
+		preferred, _ := core.GetNameVal(d.Get("Preferred"))
+		usage.LangPreferred = preferred == string(OCStateOn)
+	}
+	if d, ok := core.GetDict(dict.Get("Export")); ok {
+		usage.ExportState = getOCState(d.Get("ExportState"))
+	}
+	if d, ok := core.GetDict(dict.Get("Zoom")); ok {
+		if val, err := core.GetNumberAsFloat(d.Get("min")); err == nil {
+			usage.ZoomMin = &val
+		}
+		if val, err := core.GetNumberAsFloat(d.Get("max")); err == nil {
+			usage.ZoomMax = &val
+		}
+	}
+	if d, ok := core.GetDict(dict.Get("Print")); ok {
+		usage.PrintSubtype, _ = core.GetNameVal(d.Get("Subtype"))
+		usage.PrintState = getOCState(d.Get("PrintState"))
+	}
+	if d, ok := core.GetDict(dict.Get("View")); ok {
+		usage.ViewState = getOCState(d.Get("ViewState"))
+	}
+	if d, ok := core.GetDict(dict.Get("User")); ok {
+		usage.UserType, _ = core.GetNameVal(d.Get("Type"))
+		if arr, ok := core.GetArray(d.Get("Name")); ok {
+			for _, name := range arr.Elements() {
+				usage.UserNames = append(usage.UserNames, getTextString(name))
+			}
+		} else if name := getTextString(d.Get("Name")); name != "" {
+			usage.UserNames = []string{name}
+		}
+	}
+	if d, ok := core.GetDict(dict.Get("PageElement")); ok {
+		usage.PageElement, _ = core.GetNameVal(d.Get("Subtype"))
+	}
+	return usage
+}
+
+// getTextString returns the decoded text string `obj`, empty if not a string.
+func getTextString(obj core.PdfObject) string {
+	if str, ok := core.GetString(obj); ok {
+		return str.Decoded()
+	}
+	return ""
+}
+
+// getOCState returns the optional content state name `obj`.
+func getOCState(obj core.PdfObject) PdfOCState {
+	name, _ := core.GetNameVal(obj)
+	return PdfOCState(name)
+}
+
+// ToPdfObject returns the usage dictionary.
+func (u *PdfOCUsage) ToPdfObject() core.PdfObject {
+	dict := core.MakeDict()
+	if u.Creator != "" || u.CreatorSubtype != "" {
+		d := core.MakeDict()
+		d.Set("Creator", makeTextString(u.Creator))
+		d.Set("Subtype", core.MakeName(u.CreatorSubtype))
+		dict.Set("CreatorInfo", d)
+	}
+	if u.Lang != "" {
+		d := core.MakeDict()
+		d.Set("Lang", makeTextString(u.Lang))
+		if u.LangPreferred {
+			d.Set("Preferred", core.MakeName(string(OCStateOn)))
+		}
+		dict.Set("Language", d)
+	}
+	if u.ExportState != OCStateUnset {
+		d := core.MakeDict()
+		d.Set("ExportState", core.MakeName(string(u.ExportState)))
+		dict.Set("Export", d)
+	}
+	if u.ZoomMin != nil || u.ZoomMax != nil {
+		d := core.MakeDict()
+		if u.ZoomMin != nil {
+			d.Set("min", core.MakeFloat(*u.ZoomMin))
+		}
+		if u.ZoomMax != nil {
+			d.Set("max", core.MakeFloat(*u.ZoomMax))
+		}
+		dict.Set("Zoom", d)
+	}
+	if u.PrintSubtype != "" || u.PrintState != OCStateUnset {
+		d := core.MakeDict()
+		if u.PrintSubtype != "" {
+			d.Set("Subtype", core.MakeName(u.PrintSubtype))
+		}
+		if u.PrintState != OCStateUnset {
+			d.Set("PrintState", core.MakeName(string(u.PrintState)))
+		}
+		dict.Set("Print", d)
+	}
+	if u.ViewState != OCStateUnset {
+		d := core.MakeDict()
+		d.Set("ViewState", core.MakeName(string(u.ViewState)))
+		dict.Set("View", d)
+	}
+	if u.UserType != "" {
+		d := core.MakeDict()
+		d.Set("Type", core.MakeName(u.UserType))
+		names := core.MakeArray()
+		for _, name := range u.UserNames {
+			names.Append(makeTextString(name))
+		}
+		d.Set("Name", names)
+		dict.Set("User", d)
+	}
+	if u.PageElement != "" {
+		d := core.MakeDict()
+		d.Set("Subtype", core.MakeName(u.PageElement))
+		dict.Set("PageElement", d)
+	}
+	return dict
+}
+
+// PdfOCG represents an optional content group (8.11.2.1 Optional Content Groups): a collection of
+// content which can be shown or hidden, such as a layer.
+type PdfOCG struct {
+	// Name is the name of the group shown in the user interface.
+	Name string
+
+	// Intent is the intended use of the group, View and/or Design. View if empty.
+	Intent []string
+
+	Usage *PdfOCUsage
+
+	container *core.PdfIndirectObject
+}
+
+// NewPdfOCG returns a new optional content group named `name`.
+func NewPdfOCG(name string) *PdfOCG {
+	return &PdfOCG{Name: name, container: core.MakeIndirectObject(core.MakeDict())}
+}
+
+// GetContainingPdfObject returns the indirect object of the group, referred to by the content.
+func (g *PdfOCG) GetContainingPdfObject() core.PdfObject {
+	return g.container
+}
+
+// ToPdfObject returns the indirect object of the group, with the group dictionary updated. The
+// dictionary is updated in place, keeping its other entries.
+func (g *PdfOCG) ToPdfObject() core.PdfObject {
+	dict, ok := core.GetDict(g.container)
+	if !ok {
+		dict = core.MakeDict()
+		g.container.PdfObject = dict
+	}
+	dict.Set("Type", core.MakeName("OCG"))
+	dict.Set("Name", makeTextString(g.Name))
+	switch len(g.Intent) {
+	case 0:
+		dict.Remove("Intent")
+	case 1:
+		dict.Set("Intent", core.MakeName(g.Intent[0]))
+	default:
+		dict.Set("Intent", makeNameArray(g.Intent))
+	}
+	if g.Usage != nil {
+		dict.Set("Usage", g.Usage.ToPdfObject())
+	} else {
+		dict.Remove("Usage")
+	}
+	return g.container
+}
+
+// makeNameArray returns an array of the names `names`.
+func makeNameArray(names []string) *core.PdfObjectArray {
+	arr := core.MakeArray()
+	for _, name := range names {
+		arr.Append(core.MakeName(name))
+	}
+	return arr
+}
+
+// getNameList returns the names of name or name array `obj`.
+func getNameList(obj core.PdfObject) []string {
+	if name, ok := core.GetNameVal(obj); ok {
+		return []string{name}
+	}
+	arr, ok := core.GetArray(obj)
+	if !ok {
+		return nil
+	}
+	var names []string
+	for _, elem := range arr.Elements() {
+		if name, ok := core.GetNameVal(elem); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// PdfOCMD represents an optional content membership dictionary (8.11.2.2 Optional Content
+// Membership Dictionaries): the visibility of content depending on the states of several groups.
+type PdfOCMD struct {
+	OCGs []*PdfOCG
+
+	// P is the visibility policy applied to OCGs, AnyOn if empty.
+	P PdfOCMDPolicy
+
+	// VE is the visibility expression, an array of /And, /Or or /Not followed by groups and nested
+	// expressions. It takes precedence over OCGs and P if set.
+	VE core.PdfObject
+}
+
+// NewPdfOCMD returns a new membership dictionary of the groups `ocgs` with policy `policy`.
+func NewPdfOCMD(policy PdfOCMDPolicy, ocgs ...*PdfOCG) *PdfOCMD {
+	return &PdfOCMD{OCGs: ocgs, P: policy}
+}
+
+// ToPdfObject returns the membership dictionary.
+func (m *PdfOCMD) ToPdfObject() core.PdfObject {
+	dict := core.MakeDict()
+	dict.Set("Type", core.MakeName("OCMD"))
+	if len(m.OCGs) == 1 {
+		dict.Set("OCGs", m.OCGs[0].ToPdfObject())
+	} else if len(m.OCGs) > 1 {
+		dict.Set("OCGs", makeOCGArray(m.OCGs))
+	}
+	if m.P != "" {
+		dict.Set("P", core.MakeName(string(m.P)))
+	}
+	dict.SetIfNotNil("VE", m.VE)
+	return dict
+}
+
+// makeOCGArray returns an array of the groups `ocgs`.
+func makeOCGArray(ocgs []*PdfOCG) *core.PdfObjectArray {
+	arr := core.MakeArray()
+	for _, ocg := range ocgs {
+		arr.Append(ocg.ToPdfObject())
+	}
+	return arr
+}
+
+// PdfOCUsageApplication represents a usage application dictionary (8.11.4.4 Usage and Usage
+// Application Dictionaries): the groups whose state is set from the Category entries of their
+// usage dictionaries on the Event.
+type PdfOCUsageApplication struct {
+	// Event is View, Print or Export.
+	Event string
+
+	OCGs []*PdfOCG
+
+	// Category lists the entries of the usage dictionaries used, such as View, Print, Export or
+	// Zoom.
+	Category []string
+}
+
+// PdfOCOrderItem is an entry of the presentation order of the groups in the user interface. An
+// item is either a group, with its nested groups in Kids, or a collection of groups without a
+// group of its own, possibly labelled.
+type PdfOCOrderItem struct {
+	OCG   *PdfOCG
+	Label string
+	Kids  []*PdfOCOrderItem
+}
+
+// PdfOCConfig represents an optional content configuration dictionary (8.11.4.3 Optional Content
+// Configuration Dictionaries): the initial states of the groups and their presentation.
+type PdfOCConfig struct {
+	Name    string
+	Creator string
+
+	// BaseState is the state of the groups not listed in ON or OFF, ON if unset.
+	BaseState PdfOCState
+
+	// ON and OFF are the groups whose state is ON or OFF, overriding BaseState.
+	ON  []*PdfOCG
+	OFF []*PdfOCG
+
+	// Intent is the intents of the groups considered for visibility: View, Design or All. View if
+	// empty.
+	Intent []string
+
+	// AS sets the states of groups automatically from their usage.
+	AS []*PdfOCUsageApplication
+
+	// Order is the order of the groups in the user interface.
+	Order []*PdfOCOrderItem
+
+	// ListMode is AllPages or VisiblePages, the groups shown in the user interface.
+	ListMode string
+
+	// RBGroups are radio-button groups: at most one group of each can be ON at a time.
+	RBGroups [][]*PdfOCG
+
+	// Locked are the groups whose state cannot be changed in the user interface.
+	Locked []*PdfOCG
+}
+
+// NewPdfOCConfig returns a new configuration named `name` where all the groups are ON.
+func NewPdfOCConfig(name string) *PdfOCConfig {
+	return &PdfOCConfig{Name: name}
+}
+
+// SetState sets the state of group `ocg` in the configuration, adding it to the ON or OFF array.
+func (c *PdfOCConfig) SetState(ocg *PdfOCG, on bool) {
+	c.ON = removeOCG(c.ON, ocg)
+	c.OFF = removeOCG(c.OFF, ocg)
+	if on {
+		c.ON = append(c.ON, ocg)
+	} else {
+		c.OFF = append(c.OFF, ocg)
+	}
+}
+
+// removeOCG returns `ocgs` without `ocg`.
+func removeOCG(ocgs []*PdfOCG, ocg *PdfOCG) []*PdfOCG {
+	var kept []*PdfOCG
+	for _, g := range ocgs {
+		if g != ocg {
+			kept = append(kept, g)
+		}
+	}
+	return kept
+}
+
+// ToPdfObject returns the configuration dictionary.
+func (c *PdfOCConfig) ToPdfObject() core.PdfObject {
+	dict := core.MakeDict()
+	if c.Name != "" {
+		dict.Set("Name", makeTextString(c.Name))
+	}
+	if c.Creator != "" {
+		dict.Set("Creator", makeTextString(c.Creator))
+	}
+	if c.BaseState != OCStateUnset && c.BaseState != OCStateOn {
+		dict.Set("BaseState", core.MakeName(string(c.BaseState)))
+	}
+	if len(c.ON) > 0 {
+		dict.Set("ON", makeOCGArray(c.ON))
+	}
+	if len(c.OFF) > 0 {
+		dict.Set("OFF", makeOCGArray(c.OFF))
+	}
+	if len(c.Intent) > 0 {
+		dict.Set("Intent", makeNameArray(c.Intent))
+	}
+	if len(c.AS) > 0 {
+		as := core.MakeArray()
+		for _, app := range c.AS {
+			d := core.MakeDict()
+			d.Set("Event", core.MakeName(app.Event))
+			d.Set("OCGs", makeOCGArray(app.OCGs))
+			d.Set("Category", makeNameArray(app.Category))
+			as.Append(d)
+		}
+		dict.Set("AS", as)
+	}
+	if len(c.Order) > 0 {
+		dict.Set("Order", makeOCOrderArray(c.Order))
+	}
+	if c.ListMode != "" {
+		dict.Set("ListMode", core.MakeName(c.ListMode))
+	}
+	if len(c.RBGroups) > 0 {
+		groups := core.MakeArray()
+		for _, group := range c.RBGroups {
+			groups.Append(makeOCGArray(group))
+		}
+		dict.Set("RBGroups", groups)
+	}
+	if len(c.Locked) > 0 {
+		dict.Set("Locked", makeOCGArray(c.Locked))
+	}
+	return dict
+}
+
+// makeOCOrderArray returns the Order array of the items `items`: the groups are followed by the
+// arrays of their kids and the other items are arrays, starting with their label if any.
+func makeOCOrderArray(items []*PdfOCOrderItem) *core.PdfObjectArray {
+	arr := core.MakeArray()
+	for _, item := range items {
+		if item.OCG != nil {
+			arr.Append(item.OCG.ToPdfObject())
+			if len(item.Kids) > 0 {
+				arr.Append(makeOCOrderArray(item.Kids))
+			}
+			continue
+		}
+		kids := makeOCOrderArray(item.Kids)
+		if item.Label != "" {
+			kids = core.MakeArray(append([]core.PdfObject{makeTextString(item.Label)}, kids.Elements()...)...)
+		}
+		arr.Append(kids)
+	}
+	return arr
+}
+
+// PdfOCProperties represents the optional content properties dictionary of a document (8.11.4.2
+// Optional Content Properties Dictionary).
+type PdfOCProperties struct {
+	// OCGs are all the groups of the document.
+	OCGs []*PdfOCG
+
+	// D is the default configuration, and Configs the alternate configurations.
+	D       *PdfOCConfig
+	Configs []*PdfOCConfig
+}
+
+// NewPdfOCProperties returns new optional content properties without groups.
+func NewPdfOCProperties() *PdfOCProperties {
+	return &PdfOCProperties{D: NewPdfOCConfig("")}
+}
+
+// AddOCG adds group `ocg` to the document and to the Order of the default configuration, with
+// state ON if `visible` is true or OFF otherwise.
+func (p *PdfOCProperties) AddOCG(ocg *PdfOCG, visible bool) {
+	p.OCGs = append(p.OCGs, ocg)
+	if p.D == nil {
+		p.D = NewPdfOCConfig("")
+	}
+	p.D.Order = append(p.D.Order, &PdfOCOrderItem{OCG: ocg})
+	if !visible || p.D.BaseState == OCStateOff {
+		p.D.SetState(ocg, visible)
+	}
+}
+
+// ToPdfObject returns the optional content properties dictionary.
+func (p *PdfOCProperties) ToPdfObject() core.PdfObject {
+	dict := core.MakeDict()
+	dict.Set("OCGs", makeOCGArray(p.OCGs))
+	config := p.D
+	if config == nil {
+		config = NewPdfOCConfig("")
+	}
+	dict.Set("D", config.ToPdfObject())
+	if len(p.Configs) > 0 {
+		configs := core.MakeArray()
+		for _, c := range p.Configs {
+			configs.Append(c.ToPdfObject())
+		}
+		dict.Set("Configs", configs)
+	}
+	return dict
+}
+
+// ocLoader loads the optional content objects, keeping the identity of the groups.
+type ocLoader struct {
+	ocgs map[core.PdfObject]*PdfOCG
+}
+
+// newPdfOCPropertiesFromObject loads the optional content properties dictionary `obj`, logging its
+// problems to `logger`.
+func newPdfOCPropertiesFromObject(obj core.PdfObject, logger common.Logger) (*PdfOCProperties, error) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		logger.Debug("ERROR: OCProperties not a dictionary (%T)", obj)
+		return nil, ErrTypeCheck
+	}
+	l := &ocLoader{ocgs: map[core.PdfObject]*PdfOCG{}}
+	props := &PdfOCProperties{OCGs: l.loadOCGs(dict.Get("OCGs"))}
+	if d, ok := core.GetDict(dict.Get("D")); ok {
+		props.D = l.loadConfig(d)
+	} else {
+		logger.Debug("OCProperties without default configuration")
+		props.D = NewPdfOCConfig("")
+	}
+	if arr, ok := core.GetArray(dict.Get("Configs")); ok {
+		for _, elem := range arr.Elements() {
+			if d, ok := core.GetDict(elem); ok {
+				props.Configs = append(props.Configs, l.loadConfig(d))
+			}
+		}
+	}
+	return props, nil
+}
+
+// loadOCG loads the group `obj`. Returns nil if not a dictionary.
+func (l *ocLoader) loadOCG(obj core.PdfObject) *PdfOCG {
+	obj = core.ResolveReference(obj)
+	container, ok := core.GetIndirect(obj)
+	if !ok {
+		container = core.MakeIndirectObject(obj)
+	}
+	dict, ok := core.GetDict(container)
+	if !ok {
+		return nil
+	}
+	if ocg, ok := l.ocgs[dict]; ok {
+		return ocg
+	}
+	ocg := &PdfOCG{
+		Name:      getTextString(dict.Get("Name")),
+		Intent:    getNameList(dict.Get("Intent")),
+		Usage:     newPdfOCUsageFromObject(dict.Get("Usage")),
+		container: container,
+	}
+	l.ocgs[dict] = ocg
+	return ocg
+}
+
+// loadOCGs loads the groups of array `obj`.
+func (l *ocLoader) loadOCGs(obj core.PdfObject) []*PdfOCG {
+	arr, ok := core.GetArray(obj)
+	if !ok {
+		return nil
+	}
+	var ocgs []*PdfOCG
+	for _, elem := range arr.Elements() {
+		if ocg := l.loadOCG(elem); ocg != nil {
+			ocgs = append(ocgs, ocg)
+		}
+	}
+	return ocgs
+}
+
+// loadConfig loads configuration dictionary `dict`.
+func (l *ocLoader) loadConfig(dict *core.PdfObjectDictionary) *PdfOCConfig {
+	config := &PdfOCConfig{
+		Name:      getTextString(dict.Get("Name")),
+		Creator:   getTextString(dict.Get("Creator")),
+		BaseState: getOCState(dict.Get("BaseState")),
+		ON:        l.loadOCGs(dict.Get("ON")),
+		OFF:       l.loadOCGs(dict.Get("OFF")),
+		Intent:    getNameList(dict.Get("Intent")),
+		Order:     l.loadOrder(dict.Get("Order"), 0),
+		Locked:    l.loadOCGs(dict.Get("Locked")),
+	}
+	config.ListMode, _ = core.GetNameVal(dict.Get("ListMode"))
+	if arr, ok := core.GetArray(dict.Get("AS")); ok {
+		for _, elem := range arr.Elements() {
+			d, ok := core.GetDict(elem)
+			if !ok {
+				continue
+			}
+			app := &PdfOCUsageApplication{
+				OCGs:     l.loadOCGs(d.Get("OCGs")),
+				Category: getNameList(d.Get("Category")),
+			}
+			app.Event, _ = core.GetNameVal(d.Get("Event"))
+			config.AS = append(config.AS, app)
+		}
+	}
+	if arr, ok := core.GetArray(dict.Get("RBGroups")); ok {
+		for _, elem := range arr.Elements() {
+			if group := l.loadOCGs(elem); len(group) > 0 {
+				config.RBGroups = append(config.RBGroups, group)
+			}
+		}
+	}
+	return config
+}
+
+// maxOCOrderDepth limits the nesting of Order arrays.
+const maxOCOrderDepth = 32
+
+// loadOrder loads the items of Order array `obj`, at nesting depth `depth`.
+func (l *ocLoader) loadOrder(obj core.PdfObject, depth int) []*PdfOCOrderItem {
+	arr, ok := core.GetArray(obj)
+	if !ok || depth > maxOCOrderDepth {
+		return nil
+	}
+	var items []*PdfOCOrderItem
+	for _, elem := range arr.Elements() {
+		nested, ok := core.GetArray(elem)
+		if !ok {
+			if ocg := l.loadOCG(elem); ocg != nil {
+				items = append(items, &PdfOCOrderItem{OCG: ocg})
+			}
+			continue
+		}
+
+		// A labelled collection starts with its label, otherwise a nested array following a
+		// group holds its kids.
+		elems := nested.Elements()
+		if len(elems) > 0 {
+			if label, ok := core.GetString(elems[0]); ok {
+				items = append(items, &PdfOCOrderItem{
+					Label: label.Decoded(),
+					Kids:  l.loadOrder(core.MakeArray(elems[1:]...), depth+1),
+				})
+				continue
+			}
+		}
+		kids := l.loadOrder(nested, depth+1)
+		if n := len(items); n > 0 && items[n-1].OCG != nil && items[n-1].Kids == nil {
+			items[n-1].Kids = kids
+		} else {
+			items = append(items, &PdfOCOrderItem{Kids: kids})
+		}
+	}
+	return items
+}
+
+// GetPdfOCProperties returns the optional content properties of the document, or nil if the
+// document has no optional content.
+func (r *PdfReader) GetPdfOCProperties() (*PdfOCProperties, error) {
+	obj, err := r.getCatalogEntry("OCProperties")
+	if err != nil || obj == nil {
+		return nil, err
+	}
+	return newPdfOCPropertiesFromObject(obj, r.log())
+}
+
+// SetPdfOCProperties sets the optional content properties of the output file.
+func (w *PdfWriter) SetPdfOCProperties(props *PdfOCProperties) error {
+	if props == nil {
+		return nil
+	}
+	return w.SetOCProperties(props.ToPdfObject())
+}
+
+// OCVisibility holds the states of the optional content groups of a document in a configuration,
+// to decide whether optional content is visible.
+type OCVisibility struct {
+	states   map[*PdfOCG]bool
+	ocgs     map[core.PdfObject]*PdfOCG
+	rbGroups [][]*PdfOCG
+
+	// considered tells whether the intents of a group are in the intents of the configuration.
+	// The other groups are not considered for visibility: their content is visible.
+	considered func(ocg *PdfOCG) bool
+}
+
+// Visibility returns the states of the groups in configuration `config`, which must be the default
+// or an alternate configuration of the properties. The default configuration is used if nil.
+// Alternate configurations with BaseState Unchanged start from the states of the default
+// configuration.
+func (p *PdfOCProperties) Visibility(config *PdfOCConfig) *OCVisibility {
+	if config == nil {
+		config = p.D
+	}
+	if config == nil {
+		config = NewPdfOCConfig("")
+	}
+	v := &OCVisibility{
+		states:   map[*PdfOCG]bool{},
+		ocgs:     map[core.PdfObject]*PdfOCG{},
+		rbGroups: config.RBGroups,
+	}
+	add := func(ocg *PdfOCG) {
+		v.ocgs[ocg.container] = ocg
+		if dict, ok := core.GetDict(ocg.container); ok {
+			v.ocgs[dict] = ocg
+		}
+	}
+
+	base := config.BaseState
+	var unchanged *OCVisibility
+	if base == OCStateUnchanged && config != p.D && p.D != nil {
+		unchanged = p.Visibility(p.D)
+	}
+	for _, ocg := range p.OCGs {
+		add(ocg)
+		if unchanged != nil {
+			v.states[ocg] = unchanged.states[ocg]
+		} else {
+			v.states[ocg] = base != OCStateOff
+		}
+	}
+	for _, ocg := range config.ON {
+		add(ocg)
+		v.states[ocg] = true
+	}
+	for _, ocg := range config.OFF {
+		add(ocg)
+		v.states[ocg] = false
+	}
+
+	intents := map[string]bool{}
+	for _, intent := range config.Intent {
+		intents[intent] = true
+	}
+	if len(intents) == 0 {
+		intents["View"] = true
+	}
+	v.considered = func(ocg *PdfOCG) bool {
+		if intents["All"] {
+			return true
+		}
+		if len(ocg.Intent) == 0 {
+			return intents["View"]
+		}
+		for _, intent := range ocg.Intent {
+			if intent == "All" || intents[intent] {
+				return true
+			}
+		}
+		return false
+	}
+	return v
+}
+
+// ApplyUsage sets the states of the groups from their usage dictionaries for event `event` (View,
+// Print or Export), as listed by the usage application dictionaries of `config`. Only the View,
+// Print and Export categories are applied.
+func (v *OCVisibility) ApplyUsage(config *PdfOCConfig, event string) {
+	if v == nil || config == nil {
+		return
+	}
+	for _, app := range config.AS {
+		if app.Event != event {
+			continue
+		}
+		for _, ocg := range app.OCGs {
+			if ocg.Usage == nil {
+				continue
+			}
+			for _, category := range app.Category {
+				var state PdfOCState
+				switch category {
+				case "View":
+					state = ocg.Usage.ViewState
+				case "Print":
+					state = ocg.Usage.PrintState
+				case "Export":
+					state = ocg.Usage.ExportState
+				}
+				if state == OCStateOn || state == OCStateOff {
+					v.states[ocg] = state == OCStateOn
+				}
+			}
+		}
+	}
+}
+
+// IsOCGVisible returns true if the content of group `ocg` is visible.
+func (v *OCVisibility) IsOCGVisible(ocg *PdfOCG) bool {
+	if v == nil || ocg == nil || !v.considered(ocg) {
+		return true
+	}
+	on, ok := v.states[ocg]
+	return on || !ok
+}
+
+// SetOCGVisible sets the state of group `ocg`. Turning a group ON turns the other groups of its
+// radio-button groups OFF.
+func (v *OCVisibility) SetOCGVisible(ocg *PdfOCG, visible bool) {
+	if v == nil || ocg == nil {
+		return
+	}
+	if visible {
+		for _, group := range v.rbGroups {
+			if !containsOCG(group, ocg) {
+				continue
+			}
+			for _, other := range group {
+				v.states[other] = false
+			}
+		}
+	}
+	v.states[ocg] = visible
+	v.ocgs[ocg.container] = ocg
+	if dict, ok := core.GetDict(ocg.container); ok {
+		v.ocgs[dict] = ocg
+	}
+}
+
+// containsOCG returns true if `ocgs` contains `ocg`.
+func containsOCG(ocgs []*PdfOCG, ocg *PdfOCG) bool {
+	for _, g := range ocgs {
+		if g == ocg {
+			return true
+		}
+	}
+	return false
+}
+
+// maxOCExpressionDepth limits the nesting of visibility expressions.
+const maxOCExpressionDepth = 32
+
+// IsVisible returns true if content with optional content `oc` is visible: `oc` is the OC entry of
+// an XObject or an annotation, or the property list of optional content marked with the OC tag,
+// either a group or a membership dictionary. Content of unknown groups is visible.
+func (v *OCVisibility) IsVisible(oc core.PdfObject) bool {
+	if v == nil || oc == nil {
+		return true
+	}
+	dict, ok := core.GetDict(oc)
+	if !ok {
+		return true
+	}
+	if typ, _ := core.GetNameVal(dict.Get("Type")); typ != "OCMD" {
+		return v.isGroupVisible(dict)
+	}
+
+	// Membership dictionary.
+	if ve := dict.Get("VE"); ve != nil {
+		if visible, ok := v.evalExpression(ve, 0); ok {
+			return visible
+		}
+	}
+	var groups []*core.PdfObjectDictionary
+	if arr, ok := core.GetArray(dict.Get("OCGs")); ok {
+		for _, elem := range arr.Elements() {
+			if d, ok := core.GetDict(elem); ok {
+				groups = append(groups, d)
+			}
+		}
+	} else if d, ok := core.GetDict(dict.Get("OCGs")); ok {
+		groups = append(groups, d)
+	}
+	if len(groups) == 0 {
+		return true
+	}
+	policy, _ := core.GetNameVal(dict.Get("P"))
+	var numOn int
+	for _, d := range groups {
+		if v.isGroupVisible(d) {
+			numOn++
+		}
+	}
+	switch PdfOCMDPolicy(policy) {
+	case OCMDAllOn:
+		return numOn == len(groups)
+	case OCMDAnyOff:
+		return numOn < len(groups)
+	case OCMDAllOff:
+		return numOn == 0
+	default:
+		return numOn > 0
+	}
+}
+
+// isGroupVisible returns true if the content of group dictionary `dict` is visible.
+func (v *OCVisibility) isGroupVisible(dict *core.PdfObjectDictionary) bool {
+	ocg, ok := v.ocgs[dict]
+	if !ok {
+		return true
+	}
+	return v.IsOCGVisible(ocg)
+}
+
+// evalExpression evaluates visibility expression `ve`, at nesting depth `depth`. The bool flag
+// indicates whether the expression is valid.
+func (v *OCVisibility) evalExpression(ve core.PdfObject, depth int) (bool, bool) {
+	if depth > maxOCExpressionDepth {
+		return false, false
+	}
+	if dict, ok := core.GetDict(ve); ok {
+		return v.isGroupVisible(dict), true
+	}
+	arr, ok := core.GetArray(ve)
+	if !ok || arr.Len() < 2 {
+		return false, false
+	}
+	op, _ := core.GetNameVal(arr.Get(0))
+	var operands []bool
+	for _, elem := range arr.Elements()[1:] {
+		val, ok := v.evalExpression(elem, depth+1)
+		if !ok {
+			return false, false
+		}
+		operands = append(operands, val)
+	}
+	switch op {
+	case "Not":
+		return !operands[0], true
+	case "And":
+		for _, val := range operands {
+			if !val {
+				return false, true
+			}
+		}
+		return true, true
+	case "Or":
+		for _, val := range operands {
+			if val {
+				return true, true
+			}
+		}
+		return false, true
+	}
+	return false, false
+}
+
+// OCContentState tracks the visibility of the content of a content stream, through the nesting of
+// its marked-content sequences: content marked with the OC tag is hidden if its groups are not
+// visible.
+type OCContentState struct {
+	visibility *OCVisibility
+	hidden     []bool
+	numHidden  int
+}
+
+// NewContentState returns the visibility state at the start of a content stream. The state is
+// always visible if `v` is nil.
+func (v *OCVisibility) NewContentState() *OCContentState {
+	return &OCContentState{visibility: v}
+}
+
+// BeginMarkedContent starts a marked-content sequence with the operands `params` of a BMC or BDC
+// operator. The property lists referred to by name are looked up in `resources`.
+func (s *OCContentState) BeginMarkedContent(params []core.PdfObject, resources *PdfPageResources) {
+	hidden := false
+	if tag, ok := core.GetNameVal(firstObject(params)); ok && tag == "OC" && len(params) > 1 {
+		props := params[1]
+		if name, ok := core.GetName(props); ok {
+			props = nil
+			if resources != nil {
+				props, _ = resources.GetPropertyByName(*name)
+			}
+		}
+		hidden = props != nil && !s.visibility.IsVisible(props)
+	}
+	s.hidden = append(s.hidden, hidden)
+	if hidden {
+		s.numHidden++
+	}
+}
+
+// firstObject returns the first object of `objs`, nil if empty.
+func firstObject(objs []core.PdfObject) core.PdfObject {
+	if len(objs) == 0 {
+		return nil
+	}
+	return objs[0]
+}
+
+// EndMarkedContent ends the current marked-content sequence (EMC operator).
+func (s *OCContentState) EndMarkedContent() {
+	n := len(s.hidden)
+	if n == 0 {
+		common.Log.Debug("EMC without marked-content sequence")
+		return
+	}
+	if s.hidden[n-1] {
+		s.numHidden--
+	}
+	s.hidden = s.hidden[:n-1]
+}
+
+// Hidden returns true if the current content is hidden.
+func (s *OCContentState) Hidden() bool {
+	return s.numHidden > 0
+}
+
+// GetOCVisibility returns the visibility of the optional content of the page in the default
+// configuration of its document. Returns nil if the page was not loaded by a PdfReader or the
+// document has no optional content: a nil visibility shows all the content.
+func (p *PdfPage) GetOCVisibility() (*OCVisibility, error) {
+	if p.reader == nil {
+		return nil, nil
+	}
+	props, err := p.reader.GetPdfOCProperties()
+	if err != nil || props == nil {
+		return nil, err
+	}
+	return props.Visibility(nil), nil
+}
@@ -0,0 +1,168 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+func TestOCPropertiesRoundTrip(t *testing.T) {
+	background := NewPdfOCG("Background")
+	english := NewPdfOCG("English")
+	english.Usage = &PdfOCUsage{Lang: "en", LangPreferred: true, PrintState: OCStateOff}
+	french := NewPdfOCG("French")
+	design := NewPdfOCG("Design")
+	design.Intent = []string{"Design"}
+
+	props := NewPdfOCProperties()
+	props.AddOCG(background, true)
+	props.AddOCG(english, true)
+	props.AddOCG(french, false)
+	props.AddOCG(design, true)
+	props.D.Name = "Default"
+	props.D.Order = []*PdfOCOrderItem{
+		{OCG: background, Kids: []*PdfOCOrderItem{{OCG: design}}},
+		{Label: "Languages", Kids: []*PdfOCOrderItem{{OCG: english}, {OCG: french}}},
+	}
+	props.D.RBGroups = [][]*PdfOCG{{english, french}}
+	props.D.Locked = []*PdfOCG{background}
+	props.D.AS = []*PdfOCUsageApplication{{Event: "Print", OCGs: []*PdfOCG{english}, Category: []string{"Print"}}}
+	props.Configs = []*PdfOCConfig{{Name: "French", BaseState: OCStateUnchanged, ON: []*PdfOCG{french}, OFF: []*PdfOCG{english}}}
+
+	w := NewPdfWriter()
+	require.NoError(t, w.AddPage(newPageOpsTestPage(100)))
+	require.NoError(t, w.SetPdfOCProperties(props))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	r, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	loaded, err := r.GetPdfOCProperties()
+	require.NoError(t, err)
+	require.NotNil(t, loaded)
+	require.Len(t, loaded.OCGs, 4)
+	bg, en, fr, de := loaded.OCGs[0], loaded.OCGs[1], loaded.OCGs[2], loaded.OCGs[3]
+	require.Equal(t, "English", en.Name)
+	require.Equal(t, []string{"Design"}, de.Intent)
+	require.NotNil(t, en.Usage)
+	require.Equal(t, "en", en.Usage.Lang)
+	require.True(t, en.Usage.LangPreferred)
+	require.Equal(t, OCStateOff, en.Usage.PrintState)
+
+	config := loaded.D
+	require.Equal(t, "Default", config.Name)
+	require.Equal(t, []*PdfOCG{fr}, config.OFF)
+	require.Equal(t, []*PdfOCG{bg}, config.Locked)
+	require.Equal(t, [][]*PdfOCG{{en, fr}}, config.RBGroups)
+	require.Len(t, config.Order, 2)
+	require.Equal(t, bg, config.Order[0].OCG)
+	require.Equal(t, de, config.Order[0].Kids[0].OCG)
+	require.Equal(t, "Languages", config.Order[1].Label)
+	require.Len(t, config.Order[1].Kids, 2)
+	require.Len(t, config.AS, 1)
+	require.Equal(t, "Print", config.AS[0].Event)
+	require.Len(t, loaded.Configs, 1)
+	require.Equal(t, OCStateUnchanged, loaded.Configs[0].BaseState)
+
+	// Default configuration.
+	v := loaded.Visibility(nil)
+	require.True(t, v.IsOCGVisible(bg))
+	require.True(t, v.IsOCGVisible(en))
+	require.False(t, v.IsOCGVisible(fr))
+	require.True(t, v.IsVisible(en.GetContainingPdfObject()))
+	require.True(t, v.IsVisible(core.MakeDict()))
+
+	// Radio-button groups.
+	v.SetOCGVisible(fr, true)
+	require.True(t, v.IsOCGVisible(fr))
+	require.False(t, v.IsOCGVisible(en))
+
+	// Usage application on print.
+	v = loaded.Visibility(nil)
+	v.ApplyUsage(config, "Print")
+	require.False(t, v.IsOCGVisible(en))
+
+	// Alternate configuration based on the default one.
+	v = loaded.Visibility(loaded.Configs[0])
+	require.True(t, v.IsOCGVisible(bg))
+	require.False(t, v.IsOCGVisible(en))
+	require.True(t, v.IsOCGVisible(fr))
+
+	// Groups with other intents are visible unless the configuration intent includes them.
+	de.Intent = []string{"Design"}
+	config.OFF = append(config.OFF, de)
+	require.True(t, loaded.Visibility(nil).IsOCGVisible(de))
+	config.Intent = []string{"All"}
+	require.False(t, loaded.Visibility(nil).IsOCGVisible(de))
+}
+
+func TestOCMDVisibility(t *testing.T) {
+	on := NewPdfOCG("On")
+	off := NewPdfOCG("Off")
+	props := NewPdfOCProperties()
+	props.AddOCG(on, true)
+	props.AddOCG(off, false)
+	v := props.Visibility(nil)
+
+	policies := map[PdfOCMDPolicy]bool{
+		OCMDAnyOn:  true,
+		OCMDAllOn:  false,
+		OCMDAnyOff: true,
+		OCMDAllOff: false,
+	}
+	for policy, visible := range policies {
+		require.Equal(t, visible, v.IsVisible(NewPdfOCMD(policy, on, off).ToPdfObject()), policy)
+	}
+	require.False(t, v.IsVisible(NewPdfOCMD(OCMDAnyOn, off).ToPdfObject()))
+
+	ocmd := NewPdfOCMD(OCMDAllOn, on, off)
+	ocmd.VE = core.MakeArray(core.MakeName("And"), on.ToPdfObject(),
+		core.MakeArray(core.MakeName("Not"), off.ToPdfObject()))
+	require.True(t, v.IsVisible(ocmd.ToPdfObject()))
+	ocmd.VE = core.MakeArray(core.MakeName("Or"), off.ToPdfObject(),
+		core.MakeArray(core.MakeName("Not"), on.ToPdfObject()))
+	require.False(t, v.IsVisible(ocmd.ToPdfObject()))
+}
+
+func TestOCContentState(t *testing.T) {
+	on := NewPdfOCG("On")
+	off := NewPdfOCG("Off")
+	props := NewPdfOCProperties()
+	props.AddOCG(on, true)
+	props.AddOCG(off, false)
+
+	resources := NewPdfPageResources()
+	require.NoError(t, resources.SetPropertyByName("OC1", on.ToPdfObject()))
+	require.NoError(t, resources.SetPropertyByName("OC2", off.ToPdfObject()))
+
+	state := props.Visibility(nil).NewContentState()
+	state.BeginMarkedContent([]core.PdfObject{core.MakeName("OC"), core.MakeName("OC1")}, resources)
+	require.False(t, state.Hidden())
+	state.BeginMarkedContent([]core.PdfObject{core.MakeName("OC"), core.MakeName("OC2")}, resources)
+	require.True(t, state.Hidden())
+	state.BeginMarkedContent([]core.PdfObject{core.MakeName("Span")}, resources)
+	require.True(t, state.Hidden())
+	state.EndMarkedContent()
+	state.EndMarkedContent()
+	require.False(t, state.Hidden())
+	state.BeginMarkedContent([]core.PdfObject{core.MakeName("OC"), off.ToPdfObject()}, resources)
+	require.True(t, state.Hidden())
+	state.EndMarkedContent()
+	state.EndMarkedContent()
+	state.EndMarkedContent()
+	require.False(t, state.Hidden())
+
+	// A nil visibility shows all the content.
+	var v *OCVisibility
+	state = v.NewContentState()
+	state.BeginMarkedContent([]core.PdfObject{core.MakeName("OC"), core.MakeName("OC2")}, resources)
+	require.False(t, state.Hidden())
+}
@@ -54,6 +54,11 @@ type PdfPage struct {
 	VP                   core.PdfObject
 	Annots               core.PdfObject
 
+	// PDF 2.0 entries: output intents of the page, associated files and document part.
+	OutputIntents core.PdfObject
+	AF            core.PdfObject
+	DPart         core.PdfObject
+
 	annotations []*PdfAnnotation
 
 	// Primitive container.
@@ -148,6 +153,7 @@ func (r *PdfReader) newPdfPageFromDict(p *core.PdfObjectDictionary) (*PdfPage, e
 		}
 		page.Resources = resources
 	}
+	page.Resources.logger = r.log()
 
 	if obj := d.Get("MediaBox"); obj != nil {
 		boxArr, ok := core.GetArray(obj)
@@ -273,12 +279,35 @@ func (r *PdfReader) newPdfPageFromDict(p *core.PdfObjectDictionary) (*PdfPage, e
 	if obj := d.Get("Annots"); obj != nil {
 		page.Annots = obj
 	}
+	if obj := d.Get("OutputIntents"); obj != nil {
+		page.OutputIntents = obj
+	}
+	if obj := d.Get("AF"); obj != nil {
+		page.AF = obj
+	}
+	if obj := d.Get("DPart"); obj != nil {
+		page.DPart = obj
+	}
 
 	page.reader = r
 
 	return page, nil
 }
 
+// Logger returns the logger for the processing of the page, such as the extraction of its text: the
+// logger of the reader of the page, reporting the diagnostics for the page number, or the global
+// common.Log if the page was not loaded by a reader.
+func (p *PdfPage) Logger() common.Logger {
+	if p.reader == nil {
+		return common.Log
+	}
+	logger := p.reader.log()
+	if _, pageNum, err := p.reader.PageFromIndirectObject(p.primitive); err == nil {
+		return common.WithPage(logger, pageNum)
+	}
+	return logger
+}
+
 // GetAnnotations returns the list of page annotations for `page`. If not loaded attempts to load the
 // annotations, otherwise returns the loaded list.
 func (p *PdfPage) GetAnnotations() ([]*PdfAnnotation, error) {
@@ -489,6 +518,9 @@ func (p *PdfPage) GetPageDict() *core.PdfObjectDictionary {
 	d.SetIfNotNil("PresSteps", p.PresSteps)
 	d.SetIfNotNil("UserUnit", p.UserUnit)
 	d.SetIfNotNil("VP", p.VP)
+	d.SetIfNotNil("OutputIntents", p.OutputIntents)
+	d.SetIfNotNil("AF", p.AF)
+	d.SetIfNotNil("DPart", p.DPart)
 
 	if p.annotations != nil {
 		arr := core.MakeArray()
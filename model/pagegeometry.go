@@ -0,0 +1,383 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/internal/transform"
+)
+
+// PdfPageBox identifies a page boundary (14.11.2 Page Boundaries).
+type PdfPageBox string
+
+// Page boundaries.
+const (
+	// PageBoxMedia is the boundary of the physical medium.
+	PageBoxMedia PdfPageBox = "MediaBox"
+	// PageBoxCrop is the visible region of the page.
+	PageBoxCrop PdfPageBox = "CropBox"
+	// PageBoxBleed is the region to which the content is clipped in a production environment.
+	PageBoxBleed PdfPageBox = "BleedBox"
+	// PageBoxTrim is the intended dimensions of the finished page after trimming.
+	PageBoxTrim PdfPageBox = "TrimBox"
+	// PageBoxArt is the extent of the meaningful content of the page.
+	PageBoxArt PdfPageBox = "ArtBox"
+)
+
+// PdfPageGeometry is the effective geometry of a page: its boundaries with the inherited and
+// default values resolved, normalized and clipped to their parent boundaries as the spec defines
+// (14.11.2 Page Boundaries), its rotation and its user space unit.
+type PdfPageGeometry struct {
+	MediaBox PdfRectangle
+
+	// CropBox is the visible region of the page, clipped to the media box.
+	CropBox PdfRectangle
+
+	// BleedBox, TrimBox and ArtBox default to the crop box and are clipped to it.
+	BleedBox PdfRectangle
+	TrimBox  PdfRectangle
+	ArtBox   PdfRectangle
+
+	// Rotate is the clockwise rotation of the page when displayed: 0, 90, 180 or 270.
+	Rotate int64
+
+	// UserUnit is the size of the user space units in multiples of 1/72 inch.
+	UserUnit float64
+}
+
+// GetRotate returns the inheritable Rotate value of the page in degrees, normalized to 0, 90, 180
+// or 270.
+func (p *PdfPage) GetRotate() int64 {
+	var rotate int64
+	if p.Rotate != nil {
+		rotate = *p.Rotate
+	} else if val, ok := core.GetIntVal(p.getInheritedAttribute("Rotate")); ok {
+		rotate = int64(val)
+	}
+	rotate = (rotate%360 + 360) % 360
+	return rotate - rotate%90
+}
+
+// GetUserUnit returns the UserUnit value of the page, the size of its user space units in
+// multiples of 1/72 inch: 1 if not set or invalid.
+func (p *PdfPage) GetUserUnit() float64 {
+	unit, err := core.GetNumberAsFloat(core.TraceToDirectObject(p.UserUnit))
+	if err != nil || unit <= 0 {
+		return 1
+	}
+	return unit
+}
+
+// getInheritedAttribute returns the inheritable attribute `name` of the parent page tree nodes of
+// the page, nil if not found.
+func (p *PdfPage) getInheritedAttribute(name core.PdfObjectName) core.PdfObject {
+	visited := map[core.PdfObject]struct{}{}
+	node := p.Parent
+	for node != nil {
+		dict, ok := core.GetDict(node)
+		if !ok {
+			return nil
+		}
+		if _, ok := visited[dict]; ok {
+			common.Log.Debug("ERROR: Page tree loop")
+			return nil
+		}
+		visited[dict] = struct{}{}
+		if obj := dict.Get(name); obj != nil {
+			return obj
+		}
+		node = dict.Get("Parent")
+	}
+	return nil
+}
+
+// GetCropBox returns the inheritable crop box of the page, or its media box if not set. The box
+// is returned as set, use GetBox or GetGeometry for the effective visible region.
+func (p *PdfPage) GetCropBox() (*PdfRectangle, error) {
+	if p.CropBox != nil {
+		return p.CropBox, nil
+	}
+	if obj := p.getInheritedAttribute("CropBox"); obj != nil {
+		arr, ok := core.GetArray(obj)
+		if !ok {
+			return nil, errors.New("invalid crop box")
+		}
+		return NewPdfRectangle(*arr)
+	}
+	return p.GetMediaBox()
+}
+
+// GetBox returns the effective page boundary `box`, normalized and clipped to its parent
+// boundaries.
+func (p *PdfPage) GetBox(box PdfPageBox) (*PdfRectangle, error) {
+	geom, err := p.GetGeometry()
+	if err != nil {
+		return nil, err
+	}
+	var rect PdfRectangle
+	switch box {
+	case PageBoxMedia:
+		rect = geom.MediaBox
+	case PageBoxCrop:
+		rect = geom.CropBox
+	case PageBoxBleed:
+		rect = geom.BleedBox
+	case PageBoxTrim:
+		rect = geom.TrimBox
+	case PageBoxArt:
+		rect = geom.ArtBox
+	default:
+		return nil, fmt.Errorf("unknown page box %s", box)
+	}
+	return &rect, nil
+}
+
+// GetGeometry returns the effective geometry of the page.
+func (p *PdfPage) GetGeometry() (*PdfPageGeometry, error) {
+	mediaBox, err := p.GetMediaBox()
+	if err != nil {
+		return nil, err
+	}
+	cropBox, err := p.GetCropBox()
+	if err != nil {
+		return nil, err
+	}
+
+	geom := &PdfPageGeometry{
+		MediaBox: normalizeRect(*mediaBox),
+		Rotate:   p.GetRotate(),
+		UserUnit: p.GetUserUnit(),
+	}
+	geom.CropBox = clipBox(PageBoxCrop, cropBox, geom.MediaBox)
+	geom.BleedBox = clipBox(PageBoxBleed, p.BleedBox, geom.CropBox)
+	geom.TrimBox = clipBox(PageBoxTrim, p.TrimBox, geom.CropBox)
+	geom.ArtBox = clipBox(PageBoxArt, p.ArtBox, geom.CropBox)
+	return geom, nil
+}
+
+// clipBox returns page boundary `box` named `name` clipped to its parent boundary `parent`, or
+// `parent` if not set or outside of it.
+func clipBox(name PdfPageBox, box *PdfRectangle, parent PdfRectangle) PdfRectangle {
+	if box == nil {
+		return parent
+	}
+	clipped, ok := intersectRects(normalizeRect(*box), parent)
+	if !ok {
+		common.Log.Debug("%s %v outside of %v. Using %v", name, *box, parent, parent)
+		return parent
+	}
+	return clipped
+}
+
+// normalizeRect returns `rect` with its lower left corner before its upper right corner.
+func normalizeRect(rect PdfRectangle) PdfRectangle {
+	return PdfRectangle{
+		Llx: math.Min(rect.Llx, rect.Urx),
+		Lly: math.Min(rect.Lly, rect.Ury),
+		Urx: math.Max(rect.Llx, rect.Urx),
+		Ury: math.Max(rect.Lly, rect.Ury),
+	}
+}
+
+// intersectRects returns the intersection of the normalized rectangles `a` and `b`. The bool flag
+// is false if it is empty.
+func intersectRects(a, b PdfRectangle) (PdfRectangle, bool) {
+	rect := PdfRectangle{
+		Llx: math.Max(a.Llx, b.Llx),
+		Lly: math.Max(a.Lly, b.Lly),
+		Urx: math.Min(a.Urx, b.Urx),
+		Ury: math.Min(a.Ury, b.Ury),
+	}
+	return rect, rect.Llx < rect.Urx && rect.Lly < rect.Ury
+}
+
+// transformRect returns the bounding box of `rect` transformed by matrix `m` (a b c d e f).
+func transformRect(rect PdfRectangle, m [6]float64) PdfRectangle {
+	apply := func(x, y float64) (float64, float64) {
+		return m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]
+	}
+	x1, y1 := apply(rect.Llx, rect.Lly)
+	x2, y2 := apply(rect.Urx, rect.Ury)
+	x3, y3 := apply(rect.Llx, rect.Ury)
+	x4, y4 := apply(rect.Urx, rect.Lly)
+	return PdfRectangle{
+		Llx: math.Min(math.Min(x1, x2), math.Min(x3, x4)),
+		Lly: math.Min(math.Min(y1, y2), math.Min(y3, y4)),
+		Urx: math.Max(math.Max(x1, x2), math.Max(x3, x4)),
+		Ury: math.Max(math.Max(y1, y2), math.Max(y3, y4)),
+	}
+}
+
+// rotatedBoxMatrix returns the matrix (a b c d e f) which rotates `box` clockwise by `rotate`
+// degrees (0, 90, 180 or 270) and moves it to the origin, with the size of the rotated box.
+func rotatedBoxMatrix(box PdfRectangle, rotate int64) ([6]float64, float64, float64) {
+	width, height := box.Width(), box.Height()
+	switch rotate {
+	case 90:
+		return [6]float64{0, -1, 1, 0, -box.Lly, box.Urx}, height, width
+	case 180:
+		return [6]float64{-1, 0, 0, -1, box.Urx, box.Ury}, width, height
+	case 270:
+		return [6]float64{0, 1, -1, 0, box.Ury, -box.Llx}, height, width
+	}
+	return [6]float64{1, 0, 0, 1, -box.Llx, -box.Lly}, width, height
+}
+
+// Size returns the size of the page as displayed, rotated, in units of 1/72 inch.
+func (g *PdfPageGeometry) Size() (width, height float64) {
+	_, width, height = rotatedBoxMatrix(g.CropBox, g.Rotate)
+	return width * g.UserUnit, height * g.UserUnit
+}
+
+// DisplayMatrix returns the matrix which maps the user space of the page to the page as displayed:
+// the crop box rotated by Rotate, with its lower left corner at the origin, the y axis pointing
+// up and units of 1/72 inch.
+func (g *PdfPageGeometry) DisplayMatrix() transform.Matrix {
+	m, _, _ := rotatedBoxMatrix(g.CropBox, g.Rotate)
+	u := g.UserUnit
+	return transform.NewMatrix(u*m[0], u*m[1], u*m[2], u*m[3], u*m[4], u*m[5])
+}
+
+// DeviceMatrix returns the matrix which maps the user space of the page to a device space with
+// the origin at the upper left corner of the page as displayed, the y axis pointing down and
+// `scale` device units per 1/72 inch, such as raster images.
+func (g *PdfPageGeometry) DeviceMatrix(scale float64) transform.Matrix {
+	_, height := g.Size()
+	flip := transform.NewMatrix(scale, 0, 0, -scale, 0, scale*height)
+	return flip.Mult(g.DisplayMatrix())
+}
+
+// Crop crops the page to `box`, in the default user space of the page. The content is moved so
+// that the lower left corner of the box is at the origin of the new media box, and clipped to the
+// box, by prefixing it with a cm operator. The other page boundaries and the annotations are moved
+// with the content and the rotation of the page is kept.
+func (p *PdfPage) Crop(box PdfRectangle) error {
+	geom, err := p.GetGeometry()
+	if err != nil {
+		return err
+	}
+	visible, ok := intersectRects(normalizeRect(box), geom.MediaBox)
+	if !ok {
+		return errors.New("crop box outside of the page")
+	}
+	m := [6]float64{1, 0, 0, 1, -visible.Llx, -visible.Lly}
+	return p.transformContent(geom, visible, m, visible.Width(), visible.Height(), geom.Rotate)
+}
+
+// Resize scales the page to the size `width` by `height` when displayed, in user space units. The
+// visible content of the page is scaled to fit and centered, keeping its aspect ratio, by
+// prefixing it with a cm operator. The other page boundaries and the annotations are scaled with
+// the content and the rotation of the page is kept.
+func (p *PdfPage) Resize(width, height float64) error {
+	if width <= 0 || height <= 0 {
+		return errors.New("invalid page size")
+	}
+	geom, err := p.GetGeometry()
+	if err != nil {
+		return err
+	}
+	if geom.Rotate == 90 || geom.Rotate == 270 {
+		width, height = height, width
+	}
+	box := geom.CropBox
+	if box.Width() <= 0 || box.Height() <= 0 {
+		return errors.New("empty page area")
+	}
+	scale, x, y := fitRect(box.Width(), box.Height(), PdfRectangle{Urx: width, Ury: height})
+	m := [6]float64{scale, 0, 0, scale, x - scale*box.Llx, y - scale*box.Lly}
+	return p.transformContent(geom, box, m, width, height, geom.Rotate)
+}
+
+// RotateBy rotates the page clockwise by `angle` degrees, a multiple of 90. The rotation of the
+// page is applied to the content by prefixing it with a cm operator and the Rotate entry is set to
+// 0: the page is displayed with the same orientation by all viewers and processors. The page
+// boundaries and the annotations are rotated with the content.
+func (p *PdfPage) RotateBy(angle int64) error {
+	if angle%90 != 0 {
+		return errors.New("rotation angle not a multiple of 90")
+	}
+	geom, err := p.GetGeometry()
+	if err != nil {
+		return err
+	}
+	rotate := ((geom.Rotate+angle)%360 + 360) % 360
+	m, width, height := rotatedBoxMatrix(geom.CropBox, rotate)
+	return p.transformContent(geom, geom.CropBox, m, width, height, 0)
+}
+
+// transformContent transforms the content of the page by matrix `m`, clipped to `clip` in the
+// current user space, with a new media box of size `width` by `height` and rotation `rotate`.
+func (p *PdfPage) transformContent(geom *PdfPageGeometry, clip PdfRectangle, m [6]float64,
+	width, height float64, rotate int64) error {
+	media := PdfRectangle{Urx: width, Ury: height}
+	prefix, err := core.MakeStream([]byte(fmt.Sprintf("q\n%.4f %.4f %.4f %.4f %.4f %.4f cm\n"+
+		"%.4f %.4f %.4f %.4f re W n\n", m[0], m[1], m[2], m[3], m[4], m[5],
+		clip.Llx, clip.Lly, clip.Width(), clip.Height())), core.NewFlateEncoder())
+	if err != nil {
+		return err
+	}
+	suffix, err := core.MakeStream([]byte("\nQ\n"), core.NewFlateEncoder())
+	if err != nil {
+		return err
+	}
+	contents := core.MakeArray(prefix)
+	if p.Contents != nil {
+		if arr, ok := core.GetArray(p.Contents); ok {
+			for _, elem := range arr.Elements() {
+				contents.Append(elem)
+			}
+		} else {
+			contents.Append(p.Contents)
+		}
+	}
+	contents.Append(suffix)
+
+	// Page boundaries.
+	transformBox := func(name PdfPageBox, box *PdfRectangle, effective PdfRectangle) *PdfRectangle {
+		if box == nil {
+			return nil
+		}
+		rect, ok := intersectRects(transformRect(effective, m), media)
+		if !ok {
+			common.Log.Debug("%s outside of the transformed page. Removed", name)
+			return nil
+		}
+		return &rect
+	}
+	annotations, err := p.GetAnnotations()
+	if err != nil {
+		return err
+	}
+
+	p.Contents = contents
+	p.MediaBox = &media
+	p.CropBox = nil
+	p.BleedBox = transformBox(PageBoxBleed, p.BleedBox, geom.BleedBox)
+	p.TrimBox = transformBox(PageBoxTrim, p.TrimBox, geom.TrimBox)
+	p.ArtBox = transformBox(PageBoxArt, p.ArtBox, geom.ArtBox)
+	p.Rotate = &rotate
+
+	// Annotations.
+	for _, annot := range annotations {
+		arr, ok := core.GetArray(annot.Rect)
+		if !ok {
+			continue
+		}
+		rect, err := NewPdfRectangle(*arr)
+		if err != nil {
+			common.Log.Debug("ERROR: Invalid annotation rectangle: %v", err)
+			continue
+		}
+		transformed := transformRect(normalizeRect(*rect), m)
+		annot.Rect = transformed.ToPdfObject()
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+func TestPageGeometry(t *testing.T) {
+	parent := core.MakeDict()
+	parent.Set("MediaBox", core.MakeArrayFromFloats([]float64{0, 0, 600, 800}))
+	parent.Set("CropBox", core.MakeArrayFromFloats([]float64{500, 700, -10, -10}))
+	parent.Set("Rotate", core.MakeInteger(-270))
+
+	page := NewPdfPage()
+	page.Parent = parent
+	page.BleedBox = &PdfRectangle{Llx: 400, Lly: 600, Urx: 550, Ury: 750}
+	page.ArtBox = &PdfRectangle{Llx: 550, Lly: 750, Urx: 600, Ury: 800}
+	page.UserUnit = core.MakeFloat(2)
+
+	geom, err := page.GetGeometry()
+	require.NoError(t, err)
+	crop := PdfRectangle{Urx: 500, Ury: 700}
+	require.Equal(t, PdfRectangle{Urx: 600, Ury: 800}, geom.MediaBox)
+	require.Equal(t, crop, geom.CropBox)
+	require.Equal(t, PdfRectangle{Llx: 400, Lly: 600, Urx: 500, Ury: 700}, geom.BleedBox)
+	require.Equal(t, crop, geom.TrimBox)
+	require.Equal(t, crop, geom.ArtBox)
+	require.Equal(t, int64(90), geom.Rotate)
+	require.Equal(t, 2.0, geom.UserUnit)
+
+	box, err := page.GetBox(PageBoxBleed)
+	require.NoError(t, err)
+	require.Equal(t, geom.BleedBox, *box)
+	_, err = page.GetBox("Box")
+	require.Error(t, err)
+
+	width, height := geom.Size()
+	require.Equal(t, 1400.0, width)
+	require.Equal(t, 1000.0, height)
+
+	// The page is rotated clockwise: the upper left corner of the crop box is at the upper right
+	// corner of the displayed page and its lower left corner at the upper left corner.
+	m := geom.DisplayMatrix()
+	x, y := m.Transform(0, 700)
+	require.Equal(t, []float64{1400, 1000}, []float64{x, y})
+	m = geom.DeviceMatrix(0.5)
+	x, y = m.Transform(0, 0)
+	require.Equal(t, []float64{0, 0}, []float64{x, y})
+	x, y = m.Transform(500, 0)
+	require.Equal(t, []float64{0, 500}, []float64{x, y})
+}
+
+// newPageGeometryTestPage returns a page 200 by 100 points with a link annotation.
+func newPageGeometryTestPage(t *testing.T) *PdfPage {
+	page := newPageOpsTestPage(200)
+	page.MediaBox.Ury = 100
+	require.NoError(t, page.AddContentStreamByString("0 0 10 10 re f"))
+	link := NewPdfAnnotationLink()
+	link.Rect = core.MakeArrayFromFloats([]float64{10, 10, 20, 20})
+	page.AddAnnotation(link.PdfAnnotation)
+	return page
+}
+
+// checkPageGeometryTestPage checks the media box, the content and the annotation rectangle of
+// `page`.
+func checkPageGeometryTestPage(t *testing.T, page *PdfPage, mediaBox, annotRect PdfRectangle,
+	cm string) {
+	require.Equal(t, mediaBox, *page.MediaBox)
+	content, err := page.GetAllContentStreams()
+	require.NoError(t, err)
+	require.Contains(t, content, cm+" cm")
+	require.Contains(t, content, "0 0 10 10 re f")
+	annots, err := page.GetAnnotations()
+	require.NoError(t, err)
+	arr, ok := core.GetArray(annots[0].Rect)
+	require.True(t, ok)
+	rect, err := NewPdfRectangle(*arr)
+	require.NoError(t, err)
+	require.Equal(t, annotRect, *rect)
+}
+
+func TestPageCropResizeRotate(t *testing.T) {
+	page := newPageGeometryTestPage(t)
+	require.NoError(t, page.Crop(PdfRectangle{Llx: 5, Urx: 150, Ury: 200}))
+	checkPageGeometryTestPage(t, page, PdfRectangle{Urx: 145, Ury: 100},
+		PdfRectangle{Llx: 5, Lly: 10, Urx: 15, Ury: 20},
+		"1.0000 0.0000 0.0000 1.0000 -5.0000 -0.0000")
+	require.Error(t, page.Crop(PdfRectangle{Llx: 300, Urx: 400, Ury: 100}))
+
+	page = newPageGeometryTestPage(t)
+	require.NoError(t, page.Resize(400, 400))
+	checkPageGeometryTestPage(t, page, PdfRectangle{Urx: 400, Ury: 400},
+		PdfRectangle{Llx: 20, Lly: 120, Urx: 40, Ury: 140},
+		"2.0000 0.0000 0.0000 2.0000 0.0000 100.0000")
+	require.Error(t, page.Resize(0, 400))
+
+	page = newPageGeometryTestPage(t)
+	rotate := int64(180)
+	page.Rotate = &rotate
+	require.NoError(t, page.RotateBy(-90))
+	require.Equal(t, int64(0), *page.Rotate)
+	checkPageGeometryTestPage(t, page, PdfRectangle{Urx: 100, Ury: 200},
+		PdfRectangle{Llx: 10, Lly: 180, Urx: 20, Ury: 190},
+		"0.0000 -1.0000 1.0000 0.0000 -0.0000 200.0000")
+	require.Error(t, page.RotateBy(45))
+
+	// The transformed page is written with its content.
+	w := NewPdfWriter()
+	require.NoError(t, w.AddPage(page))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	r, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	geom, err := r.PageList[0].GetGeometry()
+	require.NoError(t, err)
+	require.Equal(t, PdfRectangle{Urx: 100, Ury: 200}, geom.CropBox)
+	require.Equal(t, int64(0), geom.Rotate)
+}
@@ -0,0 +1,204 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// PdfPageLabelStyle is the numbering style of the numeric portion of page labels.
+type PdfPageLabelStyle string
+
+// Page label numbering styles.
+const (
+	// PageLabelNone labels the pages with the prefix only.
+	PageLabelNone PdfPageLabelStyle = ""
+	// PageLabelDecimal numbers the pages with decimal arabic numerals.
+	PageLabelDecimal PdfPageLabelStyle = "D"
+	// PageLabelUpperRoman numbers the pages with uppercase roman numerals.
+	PageLabelUpperRoman PdfPageLabelStyle = "R"
+	// PageLabelLowerRoman numbers the pages with lowercase roman numerals.
+	PageLabelLowerRoman PdfPageLabelStyle = "r"
+	// PageLabelUpperLetters numbers the pages with uppercase letters: A to Z, then AA to ZZ...
+	PageLabelUpperLetters PdfPageLabelStyle = "A"
+	// PageLabelLowerLetters numbers the pages with lowercase letters: a to z, then aa to zz...
+	PageLabelLowerLetters PdfPageLabelStyle = "a"
+)
+
+// PdfPageLabel represents a page label dictionary (12.4.2 Page Labels), the labelling of a range of
+// pages.
+type PdfPageLabel struct {
+	Style  PdfPageLabelStyle
+	Prefix string
+
+	// Start is the value of the numeric portion of the first page label of the range, 1 if 0.
+	Start int
+}
+
+// newPdfPageLabelFromObject loads a page label dictionary.
+func newPdfPageLabelFromObject(obj core.PdfObject) (PdfPageLabel, bool) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		return PdfPageLabel{}, false
+	}
+	var label PdfPageLabel
+	if style, ok := core.GetNameVal(dict.Get("S")); ok {
+		label.Style = PdfPageLabelStyle(style)
+	}
+	if prefix, ok := core.GetString(dict.Get("P")); ok {
+		label.Prefix = prefix.Decoded()
+	}
+	if start, ok := core.GetIntVal(dict.Get("St")); ok {
+		label.Start = start
+	}
+	return label, true
+}
+
+// ToPdfObject returns the page label dictionary.
+func (l PdfPageLabel) ToPdfObject() core.PdfObject {
+	dict := core.MakeDict()
+	dict.Set("Type", core.MakeName("PageLabel"))
+	if l.Style != PageLabelNone {
+		dict.Set("S", core.MakeName(string(l.Style)))
+	}
+	if l.Prefix != "" {
+		dict.Set("P", makeTextString(l.Prefix))
+	}
+	if l.Start > 1 {
+		dict.Set("St", core.MakeInteger(int64(l.Start)))
+	}
+	return dict
+}
+
+// Format returns the label of the page at offset `offset` in the range of the label.
+func (l PdfPageLabel) Format(offset int) string {
+	start := l.Start
+	if start < 1 {
+		start = 1
+	}
+	n := start + offset
+	switch l.Style {
+	case PageLabelDecimal:
+		return l.Prefix + strconv.Itoa(n)
+	case PageLabelUpperRoman:
+		return l.Prefix + formatRoman(n)
+	case PageLabelLowerRoman:
+		return l.Prefix + strings.ToLower(formatRoman(n))
+	case PageLabelUpperLetters:
+		return l.Prefix + formatLetters(n)
+	case PageLabelLowerLetters:
+		return l.Prefix + strings.ToLower(formatLetters(n))
+	}
+	return l.Prefix
+}
+
+// formatRoman returns uppercase roman numeral `n`.
+func formatRoman(n int) string {
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+	var b strings.Builder
+	for i, v := range values {
+		for n >= v {
+			b.WriteString(symbols[i])
+			n -= v
+		}
+	}
+	return b.String()
+}
+
+// formatLetters returns the uppercase letters label of `n`: A to Z for 1 to 26, AA to ZZ for 27 to
+// 52 and so on.
+func formatLetters(n int) string {
+	if n < 1 {
+		return ""
+	}
+	letter := string(rune('A' + (n-1)%26))
+	return strings.Repeat(letter, (n-1)/26+1)
+}
+
+// PdfPageLabels represents the page labels of a document: the number tree of the PageLabels entry
+// of the catalog, keyed by the indices of the first pages of the ranges.
+type PdfPageLabels struct {
+	tree *PdfNumberTree
+}
+
+// NewPdfPageLabels returns new empty page labels.
+func NewPdfPageLabels() *PdfPageLabels {
+	return &PdfPageLabels{tree: NewPdfNumberTree()}
+}
+
+// NewPdfPageLabelsFromObject loads page labels from number tree `obj`.
+func NewPdfPageLabelsFromObject(obj core.PdfObject) (*PdfPageLabels, error) {
+	tree, err := NewPdfNumberTreeFromObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &PdfPageLabels{tree: tree}, nil
+}
+
+// Set sets the label of the range of pages starting at page index `pageIndex` (0-based) to
+// `label`. The range ends before the next range.
+func (pl *PdfPageLabels) Set(pageIndex int, label PdfPageLabel) {
+	pl.tree.Set(int64(pageIndex), label.ToPdfObject())
+}
+
+// Remove removes the range starting at page index `pageIndex`, extending the previous range.
+func (pl *PdfPageLabels) Remove(pageIndex int) bool {
+	return pl.tree.Remove(int64(pageIndex))
+}
+
+// Ranges returns the page indices of the first pages of the ranges, in order.
+func (pl *PdfPageLabels) Ranges() []int {
+	keys := pl.tree.Keys()
+	indices := make([]int, len(keys))
+	for i, key := range keys {
+		indices[i] = int(key)
+	}
+	return indices
+}
+
+// Get returns the label of the range which contains page index `pageIndex` and the page index of
+// its first page. Returns false if the page is in no range.
+func (pl *PdfPageLabels) Get(pageIndex int) (PdfPageLabel, int, bool) {
+	start, obj, ok := pl.tree.Floor(int64(pageIndex))
+	if !ok {
+		return PdfPageLabel{}, 0, false
+	}
+	label, ok := newPdfPageLabelFromObject(obj)
+	if !ok {
+		common.Log.Debug("Invalid page label (%T)", obj)
+		return PdfPageLabel{}, 0, false
+	}
+	return label, int(start), true
+}
+
+// Label returns the label of page index `pageIndex` (0-based). Pages in no range are labelled
+// with their decimal page numbers.
+func (pl *PdfPageLabels) Label(pageIndex int) string {
+	label, start, ok := pl.Get(pageIndex)
+	if !ok {
+		return strconv.Itoa(pageIndex + 1)
+	}
+	return label.Format(pageIndex - start)
+}
+
+// ToPdfObject returns the number tree of the page labels.
+func (pl *PdfPageLabels) ToPdfObject() core.PdfObject {
+	return pl.tree.ToPdfObject()
+}
+
+// GetPdfPageLabels returns the page labels of the document, or nil if the document has none.
+func (r *PdfReader) GetPdfPageLabels() (*PdfPageLabels, error) {
+	obj, err := r.getCatalogEntry("PageLabels")
+	if err != nil || obj == nil {
+		return nil, err
+	}
+	return NewPdfPageLabelsFromObject(obj)
+}
@@ -0,0 +1,77 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageLabelFormat(t *testing.T) {
+	testcases := []struct {
+		label    PdfPageLabel
+		offset   int
+		expected string
+	}{
+		{PdfPageLabel{Style: PageLabelDecimal}, 0, "1"},
+		{PdfPageLabel{Style: PageLabelDecimal, Start: 10, Prefix: "A-"}, 2, "A-12"},
+		{PdfPageLabel{Style: PageLabelLowerRoman}, 3, "iv"},
+		{PdfPageLabel{Style: PageLabelUpperRoman, Start: 1990}, 0, "MCMXC"},
+		{PdfPageLabel{Style: PageLabelUpperLetters}, 25, "Z"},
+		{PdfPageLabel{Style: PageLabelUpperLetters}, 26, "AA"},
+		{PdfPageLabel{Style: PageLabelLowerLetters}, 53, "bbb"},
+		{PdfPageLabel{Prefix: "Cover"}, 1, "Cover"},
+	}
+	for _, tcase := range testcases {
+		require.Equal(t, tcase.expected, tcase.label.Format(tcase.offset))
+	}
+}
+
+func TestPdfPageLabels(t *testing.T) {
+	// Example of 12.4.2: i, ii, iii, iv, 1, 2, ... A-8, A-9.
+	labels := NewPdfPageLabels()
+	labels.Set(0, PdfPageLabel{Style: PageLabelLowerRoman})
+	labels.Set(4, PdfPageLabel{Style: PageLabelDecimal})
+	labels.Set(7, PdfPageLabel{Style: PageLabelDecimal, Prefix: "A-", Start: 8})
+
+	w := NewPdfWriter()
+	for i := 0; i < 9; i++ {
+		require.NoError(t, w.AddPage(newPdf20TestPage()))
+	}
+	require.NoError(t, w.SetPageLabels(labels.ToPdfObject()))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	r, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	loaded, err := r.GetPdfPageLabels()
+	require.NoError(t, err)
+	require.NotNil(t, loaded)
+	require.Equal(t, []int{0, 4, 7}, loaded.Ranges())
+
+	var actual []string
+	for i := 0; i < 9; i++ {
+		actual = append(actual, loaded.Label(i))
+	}
+	require.Equal(t, []string{"i", "ii", "iii", "iv", "1", "2", "3", "A-8", "A-9"}, actual)
+
+	label, start, ok := loaded.Get(8)
+	require.True(t, ok)
+	require.Equal(t, 7, start)
+	require.Equal(t, PdfPageLabel{Style: PageLabelDecimal, Prefix: "A-", Start: 8}, label)
+
+	// Removing a range extends the previous one.
+	require.True(t, loaded.Remove(4))
+	require.Equal(t, "vii", loaded.Label(6))
+
+	// Pages before the first range.
+	labels = NewPdfPageLabels()
+	labels.Set(2, PdfPageLabel{Style: PageLabelDecimal})
+	require.Equal(t, "2", labels.Label(1))
+	require.Equal(t, "1", labels.Label(2))
+}
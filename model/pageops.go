@@ -0,0 +1,430 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// checkPageOrder checks that `pageNums` is a permutation of the page numbers 1 to `numPages`.
+func checkPageOrder(numPages int, pageNums []int) error {
+	if len(pageNums) != numPages {
+		return fmt.Errorf("page order has %d pages, document has %d", len(pageNums), numPages)
+	}
+	seen := make([]bool, numPages)
+	for _, pageNum := range pageNums {
+		if pageNum < 1 || pageNum > numPages {
+			return fmt.Errorf("page %d not found", pageNum)
+		}
+		if seen[pageNum-1] {
+			return fmt.Errorf("page %d found twice in page order", pageNum)
+		}
+		seen[pageNum-1] = true
+	}
+	return nil
+}
+
+// movedPageOrder returns the page order of a document of `numPages` pages where page `fromPageNum`
+// is moved to position `toPageNum`.
+func movedPageOrder(numPages, fromPageNum, toPageNum int) ([]int, error) {
+	if fromPageNum < 1 || fromPageNum > numPages {
+		return nil, fmt.Errorf("page %d not found", fromPageNum)
+	}
+	if toPageNum < 1 || toPageNum > numPages {
+		return nil, fmt.Errorf("invalid page position %d", toPageNum)
+	}
+	var pageNums []int
+	for pageNum := 1; pageNum <= numPages; pageNum++ {
+		if pageNum != fromPageNum {
+			pageNums = append(pageNums, pageNum)
+		}
+	}
+	toIndex := toPageNum - 1
+	pageNums = append(pageNums, 0)
+	copy(pageNums[toIndex+1:], pageNums[toIndex:])
+	pageNums[toIndex] = fromPageNum
+	return pageNums, nil
+}
+
+// destRemapper updates the destinations which refer to pages by page index (0-based) after the
+// pages of a document are reordered, and the references to renamed named destinations.
+// Destinations referring to page objects remain valid and are not changed.
+type destRemapper struct {
+	// newIndex is the new index of the page at each original index, -1 for removed pages.
+	newIndex []int
+	// names maps the renamed named destinations to their new names.
+	names   map[string]string
+	visited map[core.PdfObject]struct{}
+	changed bool
+}
+
+// newDestRemapper returns a remapper for the pages of page order `pageNums`: the original
+// page numbers of the pages in their new order.
+func newDestRemapper(numPages int, pageNums []int) *destRemapper {
+	newIndex := make([]int, numPages)
+	for i := range newIndex {
+		newIndex[i] = -1
+	}
+	for i, pageNum := range pageNums {
+		if pageNum >= 1 && pageNum <= numPages {
+			newIndex[pageNum-1] = i
+		}
+	}
+	return &destRemapper{newIndex: newIndex, visited: map[core.PdfObject]struct{}{}}
+}
+
+// isIdentity returns true if no page changes index.
+func (m *destRemapper) isIdentity() bool {
+	for i, newIndex := range m.newIndex {
+		if newIndex != i {
+			return false
+		}
+	}
+	return true
+}
+
+// visit resolves `obj` and returns it, or nil if it was visited before.
+func (m *destRemapper) visit(obj core.PdfObject) core.PdfObject {
+	obj = core.ResolveReference(obj)
+	if obj == nil {
+		return nil
+	}
+	if _, has := m.visited[obj]; has {
+		return nil
+	}
+	m.visited[obj] = struct{}{}
+	return obj
+}
+
+// dest updates destination `obj`: an explicit destination array, a dictionary with the
+// destination in its D entry, as found in the named destinations, or the name of a destination.
+func (m *destRemapper) dest(obj core.PdfObject) {
+	obj = m.visit(obj)
+	switch t := core.TraceToDirectObject(obj).(type) {
+	case *core.PdfObjectDictionary:
+		m.dest(t.Get("D"))
+	case *core.PdfObjectString:
+		if name, ok := m.names[t.Str()]; ok {
+			*t = *core.MakeString(name)
+			m.changed = true
+		}
+	case *core.PdfObjectName:
+		if name, ok := m.names[string(*t)]; ok {
+			*t = core.PdfObjectName(name)
+			m.changed = true
+		}
+	case *core.PdfObjectArray:
+		if t.Len() == 0 || m.newIndex == nil {
+			return
+		}
+		index, ok := t.Get(0).(*core.PdfObjectInteger)
+		if !ok {
+			return
+		}
+		if *index < 0 || int(*index) >= len(m.newIndex) {
+			common.Log.Debug("Destination page index %d out of range - not updated", *index)
+			return
+		}
+		newIndex := m.newIndex[*index]
+		if newIndex < 0 || newIndex == int(*index) {
+			return
+		}
+		t.Set(0, core.MakeInteger(int64(newIndex)))
+		m.changed = true
+	}
+}
+
+// action updates the destinations of the GoTo actions of action `obj` and of the actions following
+// it.
+func (m *destRemapper) action(obj core.PdfObject) {
+	obj = m.visit(obj)
+	switch t := core.TraceToDirectObject(obj).(type) {
+	case *core.PdfObjectDictionary:
+		if s, _ := core.GetNameVal(t.Get("S")); s == string(ActionTypeGoTo) {
+			m.dest(t.Get("D"))
+		}
+		m.action(t.Get("Next"))
+	case *core.PdfObjectArray:
+		for _, next := range t.Elements() {
+			m.action(next)
+		}
+	}
+}
+
+// additionalActions updates the actions of additional-actions dictionary `obj`.
+func (m *destRemapper) additionalActions(obj core.PdfObject) {
+	aa, ok := core.GetDict(obj)
+	if !ok {
+		return
+	}
+	for _, key := range aa.Keys() {
+		m.action(aa.Get(key))
+	}
+}
+
+// annotations updates the destinations and actions of the annotations of the Annots array `obj` of a
+// page, such as those of links and form field widgets.
+func (m *destRemapper) annotations(obj core.PdfObject) {
+	annots, ok := core.GetArray(obj)
+	if !ok {
+		return
+	}
+	for _, annot := range annots.Elements() {
+		dict, ok := core.GetDict(m.visit(annot))
+		if !ok {
+			continue
+		}
+		m.dest(dict.Get("Dest"))
+		m.action(dict.Get("A"))
+		m.additionalActions(dict.Get("AA"))
+	}
+}
+
+// outlines updates the destinations and actions of the outline items starting at outline item
+// dictionary `obj` and of their descendants.
+func (m *destRemapper) outlines(obj core.PdfObject) {
+	for {
+		dict, ok := core.GetDict(m.visit(obj))
+		if !ok {
+			return
+		}
+		m.dest(dict.Get("Dest"))
+		m.action(dict.Get("A"))
+		m.outlines(dict.Get("First"))
+		obj = dict.Get("Next")
+	}
+}
+
+// outlineTree updates the destinations and actions of the items of outline tree `node`.
+func (m *destRemapper) outlineTree(node *PdfOutlineTreeNode) {
+	visited := map[*PdfOutlineTreeNode]struct{}{}
+	var walk func(node *PdfOutlineTreeNode)
+	walk = func(node *PdfOutlineTreeNode) {
+		for node != nil {
+			if _, has := visited[node]; has {
+				return
+			}
+			visited[node] = struct{}{}
+
+			item, ok := node.context.(*PdfOutlineItem)
+			if !ok {
+				walk(node.First)
+				return
+			}
+			m.dest(item.Dest)
+			m.action(item.A)
+			walk(item.First)
+			node = item.Next
+		}
+	}
+	walk(node)
+}
+
+// catalog updates the open action, the outlines and the named destinations of `catalog`.
+func (m *destRemapper) catalog(catalog *core.PdfObjectDictionary) {
+	switch core.TraceToDirectObject(catalog.Get("OpenAction")).(type) {
+	case *core.PdfObjectArray:
+		m.dest(catalog.Get("OpenAction"))
+	case *core.PdfObjectDictionary:
+		m.action(catalog.Get("OpenAction"))
+	}
+	if outlines, ok := core.GetDict(catalog.Get("Outlines")); ok {
+		m.outlines(outlines.Get("First"))
+	}
+	if dests, ok := core.GetDict(catalog.Get("Dests")); ok {
+		for _, name := range dests.Keys() {
+			m.dest(dests.Get(name))
+		}
+	}
+	if names, ok := core.GetDict(catalog.Get("Names")); ok && names.Get("Dests") != nil {
+		err := walkTree(names.Get("Dests"), "Names", func(key, value core.PdfObject) {
+			m.dest(value)
+		})
+		if err != nil {
+			common.Log.Debug("ERROR: Invalid named destinations - not updated: %v", err)
+		}
+	}
+}
+
+// getPageKids returns the Kids array of the Pages dictionary of the writer.
+func (w *PdfWriter) getPageKids() (*core.PdfObjectArray, error) {
+	pagesDict, ok := core.GetDict(w.pages)
+	if !ok {
+		return nil, errors.New("invalid Pages obj (not a dict)")
+	}
+	kids, ok := core.GetArray(pagesDict.Get("Kids"))
+	if !ok {
+		return nil, errors.New("invalid Pages Kids obj (not an array)")
+	}
+	return kids, nil
+}
+
+// InsertPage inserts `page` at page number `pageNum`, from 1 to the number of pages plus one
+// (appending the page). The following pages are moved after it.
+func (w *PdfWriter) InsertPage(pageNum int, page *PdfPage) error {
+	kids, err := w.getPageKids()
+	if err != nil {
+		return err
+	}
+	numPages := kids.Len()
+	if pageNum < 1 || pageNum > numPages+1 {
+		return fmt.Errorf("invalid page position %d", pageNum)
+	}
+	if err := w.AddPage(page); err != nil {
+		return err
+	}
+	return w.MovePage(numPages+1, pageNum)
+}
+
+// MovePage moves page `fromPageNum` to position `toPageNum`, shifting the pages in between.
+// The destinations referring to the pages by page index are updated.
+func (w *PdfWriter) MovePage(fromPageNum, toPageNum int) error {
+	kids, err := w.getPageKids()
+	if err != nil {
+		return err
+	}
+	pageNums, err := movedPageOrder(kids.Len(), fromPageNum, toPageNum)
+	if err != nil {
+		return err
+	}
+	return w.ReorderPages(pageNums)
+}
+
+// ReorderPages reorders the pages of the writer: `pageNums` lists the current page numbers of all
+// the pages in their new order. The destinations of the outlines, the named destinations, the
+// link annotations and the form field actions which refer to the pages by page index are updated.
+// Those referring to page objects remain valid.
+func (w *PdfWriter) ReorderPages(pageNums []int) error {
+	kids, err := w.getPageKids()
+	if err != nil {
+		return err
+	}
+	if err := checkPageOrder(kids.Len(), pageNums); err != nil {
+		return err
+	}
+
+	pages := kids.Elements()
+	reordered := make([]core.PdfObject, len(pageNums))
+	for i, pageNum := range pageNums {
+		reordered[i] = pages[pageNum-1]
+	}
+	kids.Clear()
+	kids.Append(reordered...)
+
+	m := newDestRemapper(len(pages), pageNums)
+	if m.isIdentity() {
+		return nil
+	}
+	m.catalog(w.catalog)
+	m.outlineTree(w.outlineTree)
+	for _, page := range reordered {
+		if pDict, ok := core.GetDict(page); ok {
+			m.annotations(pDict.Get("Annots"))
+		}
+	}
+	return nil
+}
+
+// InsertPage inserts `page` at page number `pageNum`, from 1 to the number of pages plus one
+// (appending the page). As with AddPages, the StructParents entry of the page is removed.
+func (a *PdfAppender) InsertPage(pageNum int, page *PdfPage) error {
+	if pageNum < 1 || pageNum > len(a.pages)+1 {
+		return fmt.Errorf("invalid page position %d", pageNum)
+	}
+	page = page.Duplicate()
+	page.StructParents = nil
+	procPage(page)
+
+	pageIndex := pageNum - 1
+	a.pages = append(a.pages, nil)
+	copy(a.pages[pageIndex+1:], a.pages[pageIndex:])
+	a.pages[pageIndex] = page
+	return nil
+}
+
+// MovePage moves page `fromPageNum` to position `toPageNum`, shifting the pages in between.
+func (a *PdfAppender) MovePage(fromPageNum, toPageNum int) error {
+	pageNums, err := movedPageOrder(len(a.pages), fromPageNum, toPageNum)
+	if err != nil {
+		return err
+	}
+	return a.ReorderPages(pageNums)
+}
+
+// ReorderPages reorders the pages: `pageNums` lists the current page numbers of all the pages in
+// their new order.
+// The destinations which refer to the original pages by page index are updated when writing, as
+// after the insertion and removal of pages.
+func (a *PdfAppender) ReorderPages(pageNums []int) error {
+	if err := checkPageOrder(len(a.pages), pageNums); err != nil {
+		return err
+	}
+	reordered := make([]*PdfPage, len(pageNums))
+	for i, pageNum := range pageNums {
+		reordered[i] = a.pages[pageNum-1]
+	}
+	a.pages = reordered
+	return nil
+}
+
+// remapPageIndices updates the destinations of the original document which refer to its pages by
+// page index, when the pages have moved. The updated entries of the original catalog are set in
+// `catalog` and the updated pages replace the original pages.
+func (a *PdfAppender) remapPageIndices(catalog *core.PdfObjectDictionary) {
+	origIndex := map[*PdfPage]int{}
+	for i, p := range a.roReader.PageList {
+		origIndex[p] = i
+	}
+	for i, p := range a.Reader.PageList {
+		origIndex[p] = i
+	}
+	var pageNums []int
+	for _, p := range a.pages {
+		if i, ok := origIndex[p]; ok {
+			pageNums = append(pageNums, i+1)
+		} else {
+			pageNums = append(pageNums, 0)
+		}
+	}
+
+	m := newDestRemapper(len(a.roReader.PageList), pageNums)
+	if m.isIdentity() {
+		return
+	}
+
+	m.catalog(a.Reader.catalog)
+	if m.changed {
+		for _, key := range []core.PdfObjectName{"OpenAction", "Outlines", "Dests", "Names"} {
+			if obj := core.ResolveReference(a.Reader.catalog.Get(key)); obj != nil {
+				catalog.Set(key, obj)
+				a.updateObjectsDeep(obj, nil)
+			}
+		}
+	}
+
+	for i, p := range a.pages {
+		origIndex, ok := origIndex[p]
+		if !ok {
+			continue
+		}
+		// Update the modifiable copy of the page.
+		page := a.Reader.PageList[origIndex]
+		pDict, ok := core.GetDict(page.ToPdfObject())
+		if !ok {
+			continue
+		}
+		m.changed = false
+		m.annotations(pDict.Get("Annots"))
+		if m.changed {
+			a.pages[i] = page
+			a.UpdatePage(page)
+		}
+	}
+}
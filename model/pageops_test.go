@@ -0,0 +1,203 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// newPageOpsTestPage returns an empty page `width` points wide, to identify the pages by width.
+func newPageOpsTestPage(width float64) *PdfPage {
+	page := NewPdfPage()
+	page.MediaBox = &PdfRectangle{Urx: width, Ury: 500}
+	return page
+}
+
+// writePageOpsTestFile adds 3 pages 100, 200 and 300 points wide to `w` with an outline, a named
+// destination and a link on the first page referring to the pages by index.
+func writePageOpsTestFile(t *testing.T, w *PdfWriter) {
+	for i := 1; i <= 3; i++ {
+		page := newPageOpsTestPage(float64(100 * i))
+		if i == 1 {
+			link := NewPdfAnnotationLink()
+			link.Rect = core.MakeArray(core.MakeInteger(0), core.MakeInteger(0),
+				core.MakeInteger(10), core.MakeInteger(10))
+			link.Dest = core.MakeArray(core.MakeInteger(2), core.MakeName("Fit"))
+			page.AddAnnotation(link.PdfAnnotation)
+		}
+		require.NoError(t, w.AddPage(page))
+	}
+
+	outline := NewOutline()
+	outline.Add(NewOutlineItem("Third", NewOutlineDest(2, 0, 0)))
+	outline.Add(NewOutlineItem("First", NewOutlineDest(0, 0, 0)))
+	w.AddOutlineTree(&outline.ToPdfOutline().PdfOutlineTreeNode)
+
+	dests := NewPdfNameTree()
+	dests.Set("third", core.MakeArray(core.MakeInteger(2), core.MakeName("Fit")))
+	require.NoError(t, w.SetNamedDestinationsTree(dests))
+}
+
+// checkPageOpsTestFile checks the page widths of `data` and that the destinations of the test file
+// refer to the pages 100 and 300 points wide at `firstIndex` and `thirdIndex`.
+func checkPageOpsTestFile(t *testing.T, data []byte, widths []float64, firstIndex, thirdIndex int64) {
+	r, err := NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, r.PageList, len(widths))
+	for i, page := range r.PageList {
+		box, err := page.GetMediaBox()
+		require.NoError(t, err)
+		require.Equal(t, widths[i], box.Width(), "page %d", i+1)
+	}
+
+	outline, err := r.GetOutlines()
+	require.NoError(t, err)
+	require.Len(t, outline.Entries, 2)
+	require.Equal(t, thirdIndex, outline.Entries[0].Dest.Page)
+	require.Equal(t, firstIndex, outline.Entries[1].Dest.Page)
+
+	dest, err := r.GetNamedDestination("third")
+	require.NoError(t, err)
+	require.NotNil(t, dest)
+	require.Equal(t, thirdIndex, dest.Page)
+
+	annots, err := r.PageList[firstIndex].GetAnnotations()
+	require.NoError(t, err)
+	require.Len(t, annots, 1)
+	link, ok := annots[0].GetContext().(*PdfAnnotationLink)
+	require.True(t, ok)
+	arr, ok := core.GetArray(link.Dest)
+	require.True(t, ok)
+	index, ok := core.GetIntVal(arr.Get(0))
+	require.True(t, ok)
+	require.Equal(t, thirdIndex, int64(index))
+}
+
+func TestMovedPageOrder(t *testing.T) {
+	pageNums, err := movedPageOrder(4, 4, 1)
+	require.NoError(t, err)
+	require.Equal(t, []int{4, 1, 2, 3}, pageNums)
+
+	pageNums, err = movedPageOrder(4, 1, 3)
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 3, 1, 4}, pageNums)
+
+	_, err = movedPageOrder(4, 5, 1)
+	require.Error(t, err)
+	_, err = movedPageOrder(4, 1, 0)
+	require.Error(t, err)
+
+	require.NoError(t, checkPageOrder(3, []int{3, 1, 2}))
+	require.Error(t, checkPageOrder(3, []int{3, 1}))
+	require.Error(t, checkPageOrder(3, []int{3, 1, 1}))
+	require.Error(t, checkPageOrder(3, []int{3, 1, 4}))
+}
+
+func TestWriterPageOperations(t *testing.T) {
+	w := NewPdfWriter()
+	writePageOpsTestFile(t, &w)
+
+	require.NoError(t, w.MovePage(3, 1))
+	require.NoError(t, w.InsertPage(2, newPageOpsTestPage(400)))
+	require.Error(t, w.InsertPage(6, newPageOpsTestPage(500)))
+	require.Error(t, w.ReorderPages([]int{1, 1, 2, 3}))
+
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	checkPageOpsTestFile(t, buf.Bytes(), []float64{300, 400, 100, 200}, 2, 0)
+}
+
+func TestAppenderPageOperations(t *testing.T) {
+	w := NewPdfWriter()
+	writePageOpsTestFile(t, &w)
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+
+	r, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	appender, err := NewPdfAppender(r)
+	require.NoError(t, err)
+	require.NoError(t, appender.ReorderPages([]int{3, 1, 2}))
+	require.NoError(t, appender.InsertPage(4, newPageOpsTestPage(400)))
+	require.Error(t, appender.MovePage(5, 1))
+
+	buf.Reset()
+	require.NoError(t, appender.Write(&buf))
+	checkPageOpsTestFile(t, buf.Bytes(), []float64{300, 100, 200, 400}, 1, 0)
+}
+
+func TestBookletOrder(t *testing.T) {
+	require.Equal(t, []int{3, 0, 1, 2}, bookletOrder(4))
+	require.Equal(t, []int{-1, 0, 1, -1, -1, 2, 3, 4}, bookletOrder(5))
+}
+
+func TestImposePages(t *testing.T) {
+	var pages []*PdfPage
+	for i := 0; i < 5; i++ {
+		pages = append(pages, newPdf20TestPage())
+	}
+
+	opts, err := NewNUpOptions(4)
+	require.NoError(t, err)
+	sheets, err := ImposePages(pages, opts)
+	require.NoError(t, err)
+	require.Len(t, sheets, 2)
+	require.Equal(t, PdfRectangle{Urx: 1190, Ury: 1684}, *sheets[0].MediaBox)
+	content, err := sheets[0].GetAllContentStreams()
+	require.NoError(t, err)
+	require.Equal(t, 4, strings.Count(content, " Do"))
+	require.Contains(t, content, "1.0000 0 0 1.0000 595.0000 0.0000 cm")
+	content, err = sheets[1].GetAllContentStreams()
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(content, " Do"))
+	require.Contains(t, content, "1.0000 0 0 1.0000 0.0000 842.0000 cm")
+
+	sheets, err = ImposePages(pages, NewBookletOptions())
+	require.NoError(t, err)
+	require.Len(t, sheets, 4)
+	content, err = sheets[0].GetAllContentStreams()
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(content, " Do"))
+
+	_, err = ImposePages(pages, &NUpOptions{Columns: 2, Rows: 2, Booklet: true})
+	require.Error(t, err)
+	_, err = NewNUpOptions(5)
+	require.Error(t, err)
+}
+
+func TestScalePageToFit(t *testing.T) {
+	page := NewPdfPage()
+	page.MediaBox = &PdfRectangle{Urx: 200, Ury: 100}
+	rotate := int64(90)
+	page.Rotate = &rotate
+
+	xform, width, height, err := NewXObjectFormFromPage(page)
+	require.NoError(t, err)
+	require.Equal(t, 100.0, width)
+	require.Equal(t, 200.0, height)
+	matrix, ok := core.GetArray(xform.Matrix)
+	require.True(t, ok)
+	vals, err := matrix.ToFloat64Array()
+	require.NoError(t, err)
+	require.Equal(t, []float64{0, -1, 1, 0, 0, 200}, vals)
+
+	scaled, err := ScalePageToFit(page, 200, 200)
+	require.NoError(t, err)
+	require.Equal(t, PdfRectangle{Urx: 200, Ury: 200}, *scaled.MediaBox)
+	content, err := scaled.GetAllContentStreams()
+	require.NoError(t, err)
+	require.Contains(t, content, "1.0000 0 0 1.0000 50.0000 0.0000 cm")
+	require.True(t, scaled.HasXObjectByName("Pg0"))
+
+	_, err = ScalePageToFit(page, 0, 100)
+	require.Error(t, err)
+}
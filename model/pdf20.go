@@ -0,0 +1,243 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// Pdf20Deprecation describes the use of a feature deprecated in PDF 2.0 (ISO 32000-2).
+type Pdf20Deprecation struct {
+	// ObjectNumber is the number of the object which uses the feature, 0 for the trailer.
+	ObjectNumber int64
+
+	// Description describes the deprecated feature.
+	Description string
+}
+
+// String returns a string describing the deprecation.
+func (d Pdf20Deprecation) String() string {
+	if d.ObjectNumber == 0 {
+		return d.Description
+	}
+	return fmt.Sprintf("object %d: %s", d.ObjectNumber, d.Description)
+}
+
+// pdf20InfoKeys are the document information entries which are not deprecated in PDF 2.0.
+var pdf20InfoKeys = map[core.PdfObjectName]bool{
+	"CreationDate": true,
+	"ModDate":      true,
+}
+
+// parseVersion parses a PDF version name such as 1.7 or 2.0.
+func parseVersion(s string) (core.Version, bool) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 2 {
+		return core.Version{}, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return core.Version{}, false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return core.Version{}, false
+	}
+	return core.Version{Major: major, Minor: minor}, true
+}
+
+// versionLess returns true if version `a` is earlier than version `b`.
+func versionLess(a, b core.Version) bool {
+	return a.Major < b.Major || a.Major == b.Major && a.Minor < b.Minor
+}
+
+// infoDeprecations returns the deprecated entries of the document information dictionary `info`.
+func infoDeprecations(info *core.PdfObjectDictionary) []Pdf20Deprecation {
+	if info == nil {
+		return nil
+	}
+	var deprecations []Pdf20Deprecation
+	for _, key := range info.Keys() {
+		if !pdf20InfoKeys[key] {
+			deprecations = append(deprecations, Pdf20Deprecation{
+				Description: fmt.Sprintf("document information entry %s", key),
+			})
+		}
+	}
+	return deprecations
+}
+
+// pdf20Info returns a copy of the document information dictionary `info` without the entries
+// deprecated in PDF 2.0.
+func pdf20Info(info *core.PdfObjectDictionary) *core.PdfObjectDictionary {
+	filtered := core.MakeDict()
+	for _, key := range info.Keys() {
+		if pdf20InfoKeys[key] {
+			filtered.Set(key, info.Get(key))
+		}
+	}
+	return filtered
+}
+
+// objectDeprecations returns the features deprecated in PDF 2.0 used by the indirect or stream
+// objects `objects`. Direct objects are checked recursively, references are not followed.
+func objectDeprecations(objects []core.PdfObject) []Pdf20Deprecation {
+	var deprecations []Pdf20Deprecation
+	for _, obj := range objects {
+		var objNum int64
+		var dict *core.PdfObjectDictionary
+		var isStream bool
+		switch t := obj.(type) {
+		case *core.PdfIndirectObject:
+			objNum = t.ObjectNumber
+			if arr, ok := t.PdfObject.(*core.PdfObjectArray); ok {
+				for _, elem := range arr.Elements() {
+					if d, ok := elem.(*core.PdfObjectDictionary); ok {
+						deprecations = append(deprecations, dictDeprecations(objNum, d, false)...)
+					}
+				}
+				continue
+			}
+			dict, _ = t.PdfObject.(*core.PdfObjectDictionary)
+		case *core.PdfObjectStream:
+			objNum = t.ObjectNumber
+			dict = t.PdfObjectDictionary
+			isStream = true
+		}
+		if dict != nil {
+			deprecations = append(deprecations, dictDeprecations(objNum, dict, isStream)...)
+		}
+	}
+	return deprecations
+}
+
+// dictDeprecations returns the features deprecated in PDF 2.0 used by dictionary `dict` of object
+// `objNum` and its direct dictionaries.
+func dictDeprecations(objNum int64, dict *core.PdfObjectDictionary, isStream bool) []Pdf20Deprecation {
+	var descriptions []string
+
+	typ, _ := core.GetNameVal(dict.Get("Type"))
+	subtype, _ := core.GetNameVal(dict.Get("Subtype"))
+	switch {
+	case typ == "Catalog":
+		if dict.Get("NeedsRendering") != nil {
+			descriptions = append(descriptions, "NeedsRendering entry of the catalog")
+		}
+	case typ == "Font":
+		if dict.Get("Name") != nil {
+			descriptions = append(descriptions, "Name entry of a font")
+		}
+	case isStream && subtype == "PS":
+		descriptions = append(descriptions, "PostScript XObject")
+	case isStream && (subtype == "Image" || subtype == "Form"):
+		if dict.Get("Name") != nil {
+			descriptions = append(descriptions, "Name entry of an XObject")
+		}
+		if dict.Get("OPI") != nil {
+			descriptions = append(descriptions, "OPI dictionary of an XObject")
+		}
+	case typ == "Annot" || dict.Get("Rect") != nil && subtype != "":
+		switch subtype {
+		case "Movie", "Sound", "TrapNet":
+			descriptions = append(descriptions, fmt.Sprintf("%s annotation", subtype))
+		}
+	}
+	if s, ok := core.GetNameVal(dict.Get("S")); ok && (typ == "" || typ == "Action") {
+		switch s {
+		case "Movie", "Sound":
+			descriptions = append(descriptions, fmt.Sprintf("%s action", s))
+		}
+	}
+	if dict.Get("Fields") != nil && dict.Get("XFA") != nil {
+		descriptions = append(descriptions, "XFA form")
+	}
+
+	var deprecations []Pdf20Deprecation
+	for _, description := range descriptions {
+		deprecations = append(deprecations, Pdf20Deprecation{ObjectNumber: objNum, Description: description})
+	}
+
+	// Direct dictionaries, such as actions of annotations.
+	for _, key := range dict.Keys() {
+		switch t := dict.Get(key).(type) {
+		case *core.PdfObjectDictionary:
+			deprecations = append(deprecations, dictDeprecations(objNum, t, false)...)
+		case *core.PdfObjectArray:
+			for _, elem := range t.Elements() {
+				if d, ok := elem.(*core.PdfObjectDictionary); ok {
+					deprecations = append(deprecations, dictDeprecations(objNum, d, false)...)
+				}
+			}
+		}
+	}
+	return deprecations
+}
+
+// GetPdf20Deprecations returns the features deprecated in PDF 2.0 which are used by the document,
+// such as document information entries other than CreationDate and ModDate, XFA forms, PostScript
+// XObjects and Movie and Sound annotations.
+func (r *PdfReader) GetPdf20Deprecations() ([]Pdf20Deprecation, error) {
+	if r.parser.GetCrypter() != nil && !r.parser.IsAuthenticated() {
+		return nil, ErrEncrypted
+	}
+	var deprecations []Pdf20Deprecation
+	if trailer := r.parser.GetTrailer(); trailer != nil {
+		info, _ := core.GetDict(core.ResolveReference(trailer.Get("Info")))
+		deprecations = append(deprecations, infoDeprecations(info)...)
+	}
+
+	objNums := r.GetObjectNums()
+	sort.Ints(objNums)
+	var objects []core.PdfObject
+	for _, objNum := range objNums {
+		obj, err := r.GetIndirectObjectByNumber(objNum)
+		if err != nil {
+			r.log().Debug("ERROR: Failed to load object %d: %v", objNum, err)
+			continue
+		}
+		objects = append(objects, obj)
+	}
+	return append(deprecations, objectDeprecations(objects)...), nil
+}
+
+// checkPdf20 checks that the output file can be written as a PDF 2.0 file. Encryption must use
+// AES-256 (V 5, R 6 for the standard security handler). In strict mode the deprecated entries of
+// the document information dictionary are not written (see pdf20Info) and the use of other
+// deprecated features is an error, otherwise they are only logged.
+func (w *PdfWriter) checkPdf20() error {
+	if w.encryptDict != nil {
+		v, _ := core.GetIntVal(w.encryptDict.Get("V"))
+		filter, _ := core.GetNameVal(w.encryptDict.Get("Filter"))
+		r, _ := core.GetIntVal(w.encryptDict.Get("R"))
+		if v != 5 || filter == "Standard" && r != 6 {
+			w.log().Debug("ERROR: PDF 2.0 encryption V=%d R=%d", v, r)
+			return fmt.Errorf("%v: encryption other than AES-256", ErrDeprecatedPdf20)
+		}
+	}
+
+	// Number the objects for reporting.
+	w.updateObjectNumbers()
+	deprecations := objectDeprecations(w.objects)
+	if len(deprecations) == 0 {
+		return nil
+	}
+	if !w.strictPdf20 {
+		for _, d := range deprecations {
+			w.log().Debug("Deprecated in PDF 2.0: %s", d)
+		}
+		return nil
+	}
+	descriptions := make([]string, len(deprecations))
+	for i, d := range deprecations {
+		descriptions[i] = d.String()
+	}
+	return fmt.Errorf("%v: %s", ErrDeprecatedPdf20, strings.Join(descriptions, ", "))
+}
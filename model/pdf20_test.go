@@ -0,0 +1,225 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// newPdf20TestPage returns an empty page with the A4 media box.
+func newPdf20TestPage() *PdfPage {
+	page := NewPdfPage()
+	page.MediaBox = &PdfRectangle{Urx: 595, Ury: 842}
+	return page
+}
+
+func TestWriterPdf20(t *testing.T) {
+	page1 := newPdf20TestPage()
+	page2 := newPdf20TestPage()
+	outputIntent := core.MakeDict()
+	outputIntent.Set("Type", core.MakeName("OutputIntent"))
+	outputIntent.Set("S", core.MakeName("GTS_PDFX"))
+	outputIntent.Set("OutputConditionIdentifier", core.MakeString("FOGRA39"))
+	page1.OutputIntents = core.MakeArray(core.MakeIndirectObject(outputIntent))
+
+	fileSpec := core.MakeDict()
+	fileSpec.Set("Type", core.MakeName("Filespec"))
+	fileSpec.Set("F", core.MakeString("data.csv"))
+	fileSpec.Set("AFRelationship", core.MakeName("Data"))
+	page2.AF = core.MakeArray(core.MakeIndirectObject(fileSpec))
+
+	// Document part hierarchy with a part for each page.
+	dpartRoot := core.MakeIndirectObject(core.MakeDict())
+	rootNode := core.MakeIndirectObject(core.MakeDict())
+	parts := core.MakeArray()
+	for _, page := range []*PdfPage{page1, page2} {
+		part := core.MakeDict()
+		part.Set("Type", core.MakeName("DPart"))
+		part.Set("Parent", rootNode)
+		part.Set("Start", page.ToPdfObject())
+		partObj := core.MakeIndirectObject(part)
+		parts.Append(partObj)
+		page.DPart = partObj
+	}
+	rootDict := rootNode.PdfObject.(*core.PdfObjectDictionary)
+	rootDict.Set("Type", core.MakeName("DPart"))
+	rootDict.Set("Parent", dpartRoot)
+	rootDict.Set("DParts", core.MakeArray(parts))
+	dpartRootDict := dpartRoot.PdfObject.(*core.PdfObjectDictionary)
+	dpartRootDict.Set("Type", core.MakeName("DPartRoot"))
+	dpartRootDict.Set("DPartRootNode", rootNode)
+
+	w := NewPdfWriter()
+	w.SetVersion(2, 0)
+	require.NoError(t, w.AddPage(page1))
+	require.NoError(t, w.AddPage(page2))
+	require.NoError(t, w.SetOutputIntents(core.MakeArray(core.MakeIndirectObject(outputIntent))))
+	require.NoError(t, w.SetAssociatedFiles(core.MakeArray(core.MakeIndirectObject(fileSpec))))
+	require.NoError(t, w.SetDPartRoot(dpartRoot))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	require.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-2.0\n")))
+
+	r, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, core.Version{Major: 2, Minor: 0}, r.PdfVersion())
+
+	outputIntents, err := r.GetOutputIntents()
+	require.NoError(t, err)
+	arr, ok := core.GetArray(outputIntents)
+	require.True(t, ok)
+	require.Equal(t, 1, arr.Len())
+	af, err := r.GetAssociatedFiles()
+	require.NoError(t, err)
+	require.NotNil(t, af)
+
+	root, err := r.GetDPartRoot()
+	require.NoError(t, err)
+	rootDict, ok = core.GetDict(root)
+	require.True(t, ok)
+	require.Equal(t, "DPartRoot", rootDict.Get("Type").String())
+	rootNodeDict, ok := core.GetDict(rootDict.Get("DPartRootNode"))
+	require.True(t, ok)
+	dparts, ok := core.GetArray(rootNodeDict.Get("DParts"))
+	require.True(t, ok)
+	partArr, ok := core.GetArray(dparts.Get(0))
+	require.True(t, ok)
+	require.Equal(t, 2, partArr.Len())
+
+	for i := 1; i <= 2; i++ {
+		page, err := r.GetPage(i)
+		require.NoError(t, err)
+		require.NotNil(t, page.DPart)
+		part, ok := core.GetDict(page.DPart)
+		require.True(t, ok)
+		start, ok := core.GetIndirect(part.Get("Start"))
+		require.True(t, ok)
+		require.Equal(t, page.GetContainingPdfObject(), start)
+		require.Equal(t, partArr.Get(i-1), page.DPart)
+	}
+	page, err := r.GetPage(1)
+	require.NoError(t, err)
+	require.NotNil(t, page.OutputIntents)
+	require.Nil(t, page.AF)
+	page, err = r.GetPage(2)
+	require.NoError(t, err)
+	require.NotNil(t, page.AF)
+}
+
+func TestWriterPdf20Encryption(t *testing.T) {
+	// Only AES-256 is allowed.
+	for _, algo := range []EncryptionAlgorithm{RC4_128bit, AES_128bit} {
+		w := NewPdfWriter()
+		w.SetVersion(2, 0)
+		err := w.Encrypt([]byte("user"), []byte("owner"), &EncryptOptions{Algorithm: algo})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), ErrDeprecatedPdf20.Error())
+	}
+
+	// Version set after the encryption.
+	w := NewPdfWriter()
+	require.NoError(t, w.AddPage(newPdf20TestPage()))
+	require.NoError(t, w.Encrypt([]byte("user"), []byte("owner"), &EncryptOptions{Algorithm: AES_128bit}))
+	w.SetVersion(2, 0)
+	var buf bytes.Buffer
+	require.Error(t, w.Write(&buf))
+
+	// The encryption does not downgrade the version.
+	w = NewPdfWriter()
+	w.SetVersion(1, 7)
+	require.NoError(t, w.AddPage(newPdf20TestPage()))
+	require.NoError(t, w.Encrypt([]byte("user"), []byte("owner"), &EncryptOptions{Algorithm: AES_128bit}))
+	buf.Reset()
+	require.NoError(t, w.Write(&buf))
+	require.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-1.7\n")))
+
+	w = NewPdfWriter()
+	w.SetVersion(2, 0)
+	require.NoError(t, w.AddPage(newPdf20TestPage()))
+	require.NoError(t, w.Encrypt([]byte("user"), []byte("owner"), &EncryptOptions{Algorithm: AES_256bit}))
+	buf.Reset()
+	require.NoError(t, w.Write(&buf))
+	require.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-2.0\n")))
+	require.Contains(t, buf.String(), "/R 6")
+
+	r, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	ok, err := r.Decrypt([]byte("owner"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Contains(t, r.GetEncryptionMethod(), "AESV3")
+}
+
+func TestWriterPdf20Strict(t *testing.T) {
+	write := func(strict bool, font *core.PdfObjectDictionary) ([]byte, error) {
+		page := newPdf20TestPage()
+		if font != nil {
+			page.Resources.SetFontByName("F1", core.MakeIndirectObject(font))
+		}
+		w := NewPdfWriter()
+		w.SetVersion(2, 0)
+		w.SetStrictPdf20(strict)
+		require.NoError(t, w.AddPage(page))
+		var buf bytes.Buffer
+		err := w.Write(&buf)
+		return buf.Bytes(), err
+	}
+	deprecations := func(data []byte) []string {
+		r, err := NewPdfReader(bytes.NewReader(data))
+		require.NoError(t, err)
+		deprecations, err := r.GetPdf20Deprecations()
+		require.NoError(t, err)
+		var descriptions []string
+		for _, d := range deprecations {
+			descriptions = append(descriptions, d.Description)
+		}
+		return descriptions
+	}
+
+	// The document information entries written by default are deprecated.
+	data, err := write(false, nil)
+	require.NoError(t, err)
+	require.Contains(t, deprecations(data), "document information entry Producer")
+
+	// Not written in strict mode.
+	data, err = write(true, nil)
+	require.NoError(t, err)
+	require.Empty(t, deprecations(data))
+
+	font := core.MakeDict()
+	font.Set("Type", core.MakeName("Font"))
+	font.Set("Subtype", core.MakeName("Type1"))
+	font.Set("BaseFont", core.MakeName("Helvetica"))
+	font.Set("Name", core.MakeName("F1"))
+	data, err = write(false, font)
+	require.NoError(t, err)
+	require.Contains(t, deprecations(data), "Name entry of a font")
+
+	_, err = write(true, font)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Name entry of a font")
+}
+
+func TestWriterPdf20StrictKeepsInfo(t *testing.T) {
+	w := NewPdfWriter()
+	w.SetVersion(2, 0)
+	w.SetStrictPdf20(true)
+	require.NoError(t, w.AddPage(newPdf20TestPage()))
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	require.NotContains(t, buf.String(), "/Producer")
+
+	// The deprecated entries are filtered when writing, the document information of the writer is
+	// unchanged.
+	info, ok := core.GetDict(w.infoObj)
+	require.True(t, ok)
+	require.NotNil(t, info.Get("Producer"))
+}
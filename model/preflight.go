@@ -0,0 +1,765 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model/internal/fonts"
+)
+
+// PreflightSeverity is the severity of a problem found by Preflight.
+type PreflightSeverity int
+
+// Severities of the problems found by Preflight, from the least to the most severe.
+const (
+	// PreflightSeverityInfo is the severity of the remarks which do not make the document invalid.
+	PreflightSeverityInfo PreflightSeverity = iota
+	// PreflightSeverityWarning is the severity of the problems which conforming readers are
+	// expected to tolerate, such as missing annotation appearances.
+	PreflightSeverityWarning
+	// PreflightSeverityError is the severity of the structural violations of the PDF specification.
+	PreflightSeverityError
+)
+
+// String returns the name of the severity.
+func (s PreflightSeverity) String() string {
+	switch s {
+	case PreflightSeverityInfo:
+		return "info"
+	case PreflightSeverityWarning:
+		return "warning"
+	case PreflightSeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+// MarshalText returns the name of the severity, as used in the JSON form of the reports.
+func (s PreflightSeverity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText sets the severity from its name.
+func (s *PreflightSeverity) UnmarshalText(text []byte) error {
+	for _, sev := range []PreflightSeverity{PreflightSeverityInfo, PreflightSeverityWarning, PreflightSeverityError} {
+		if sev.String() == string(text) {
+			*s = sev
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid preflight severity %q", text)
+}
+
+// PreflightCode identifies the kind of problem found by Preflight.
+type PreflightCode string
+
+// Codes of the problems found by Preflight.
+const (
+	// PreflightFileInvalid indicates that the file could not be parsed at all.
+	PreflightFileInvalid PreflightCode = "file-invalid"
+	// PreflightRepaired indicates that the parser had to repair the file to load it (see
+	// core.RepairWarning). The stream length repairs are reported as PreflightStreamLength.
+	PreflightRepaired PreflightCode = "repaired"
+	// PreflightXrefOffset indicates that a cross-reference entry does not point to the object it
+	// describes.
+	PreflightXrefOffset PreflightCode = "xref-offset"
+	// PreflightObjectInvalid indicates that an object could not be loaded.
+	PreflightObjectInvalid PreflightCode = "object-invalid"
+	// PreflightBrokenReference indicates a reference to an object which is not defined.
+	PreflightBrokenReference PreflightCode = "broken-reference"
+	// PreflightInvalidType indicates an object of the wrong type or a dictionary with a wrong
+	// Type entry.
+	PreflightInvalidType PreflightCode = "invalid-type"
+	// PreflightStreamLength indicates a missing or wrong stream Length entry.
+	PreflightStreamLength PreflightCode = "stream-length"
+	// PreflightMissingKey indicates that a required dictionary entry is missing.
+	PreflightMissingKey PreflightCode = "missing-key"
+	// PreflightInvalidValue indicates a dictionary entry with an invalid value.
+	PreflightInvalidValue PreflightCode = "invalid-value"
+	// PreflightFontWidths indicates a simple font without glyph widths or with inconsistent widths.
+	PreflightFontWidths PreflightCode = "font-widths"
+	// PreflightAnnotationAppearance indicates an annotation without appearance stream.
+	PreflightAnnotationAppearance PreflightCode = "annotation-appearance"
+)
+
+// PreflightIssue describes a problem found by Preflight.
+type PreflightIssue struct {
+	// Severity is the severity of the problem.
+	Severity PreflightSeverity `json:"severity"`
+
+	// Code identifies the kind of problem.
+	Code PreflightCode `json:"code"`
+
+	// Message describes the problem.
+	Message string `json:"message"`
+
+	// ObjectNumber and GenerationNumber identify the indirect object concerned, or the indirect
+	// object containing the direct object concerned. ObjectNumber is 0 if the problem does not
+	// concern an object, or concerns the trailer.
+	ObjectNumber     int64 `json:"object,omitempty"`
+	GenerationNumber int64 `json:"generation,omitempty"`
+
+	// PageNumber is the number of the page concerned (starting from 1), or 0 if the problem does
+	// not concern a specific page.
+	PageNumber int `json:"page,omitempty"`
+
+	// Key is the dictionary entry concerned, if any.
+	Key string `json:"key,omitempty"`
+}
+
+// String returns a string describing the issue.
+func (i PreflightIssue) String() string {
+	var loc string
+	if i.PageNumber > 0 {
+		loc += fmt.Sprintf(" (page %d)", i.PageNumber)
+	}
+	if i.ObjectNumber > 0 {
+		loc += fmt.Sprintf(" (object %d %d R)", i.ObjectNumber, i.GenerationNumber)
+	}
+	if i.Key != "" {
+		loc += fmt.Sprintf(" (/%s)", i.Key)
+	}
+	return fmt.Sprintf("%s %s%s: %s", i.Severity, i.Code, loc, i.Message)
+}
+
+// PreflightReport lists the problems found by Preflight. It can be serialized with encoding/json.
+type PreflightReport struct {
+	Issues []PreflightIssue `json:"issues"`
+}
+
+// HasErrors returns true if the report contains issues of severity PreflightSeverityError.
+func (r *PreflightReport) HasErrors() bool {
+	return len(r.Filter(PreflightSeverityError)) > 0
+}
+
+// Filter returns the issues of the report which are at least as severe as `severity`.
+func (r *PreflightReport) Filter(severity PreflightSeverity) []PreflightIssue {
+	var issues []PreflightIssue
+	for _, issue := range r.Issues {
+		if issue.Severity >= severity {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// PreflightOpts defines options for Preflight.
+type PreflightOpts struct {
+	// Password is the password used to decrypt encrypted documents. The empty user password is
+	// tried if nil.
+	Password []byte
+
+	// Logger receives the log messages of the parsing. The global common.Log is used if nil.
+	Logger common.Logger
+}
+
+// Preflight checks the structure of the PDF document read from `rs` and reports the violations of
+// the PDF specification: broken references, dictionaries with wrong Type entries, invalid stream
+// lengths, missing required entries, fonts without widths, annotations without appearance streams
+// and cross-reference entries not matching the objects in the file. The repairs needed to load a
+// damaged file are reported as well.
+// The document is parsed independently of any PdfReader, from the raw objects of the file, so that
+// the problems fixed up when loading the document model are reported too. The default options are
+// used if `opts` is nil.
+// An error is returned only if the document could not be checked, e.g. if it is encrypted and
+// `opts` does not provide a valid password. A file which cannot be parsed at all results in a report
+// with a single PreflightFileInvalid issue.
+func Preflight(rs io.ReadSeeker, opts *PreflightOpts) (*PreflightReport, error) {
+	if opts == nil {
+		opts = &PreflightOpts{}
+	}
+	report := &PreflightReport{}
+
+	parser, err := core.NewParserWithLogger(rs, opts.Logger)
+	if err != nil {
+		report.Issues = append(report.Issues, PreflightIssue{
+			Severity: PreflightSeverityError,
+			Code:     PreflightFileInvalid,
+			Message:  fmt.Sprintf("unable to parse file: %v", err),
+		})
+		return report, nil
+	}
+	isEncrypted, err := parser.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+	if isEncrypted {
+		auth, err := parser.Decrypt(opts.Password)
+		if err != nil {
+			return nil, err
+		}
+		if !auth {
+			return nil, errors.New("preflight of encrypted document: invalid password")
+		}
+	}
+
+	p := &preflighter{
+		parser:       parser,
+		rs:           rs,
+		report:       report,
+		checkedFonts: map[int64]bool{},
+		checkedRes:   map[int64]bool{},
+		pageNodes:    map[int64]bool{},
+	}
+	p.checkXrefOffsets()
+	p.checkObjects()
+	p.checkDocument()
+
+	for _, w := range parser.GetRepairWarnings() {
+		issue := PreflightIssue{
+			Severity:     PreflightSeverityError,
+			Code:         PreflightRepaired,
+			Message:      w.String(),
+			ObjectNumber: w.ObjectNumber,
+		}
+		if w.Type == core.RepairStreamLength {
+			issue.Code = PreflightStreamLength
+			issue.Key = "Length"
+		}
+		p.add(issue)
+	}
+	return report, nil
+}
+
+// preflighter holds the state of a Preflight check.
+type preflighter struct {
+	parser *core.PdfParser
+	rs     io.ReadSeeker
+	report *PreflightReport
+
+	// Object numbers of the fonts and resource dictionaries checked, to report their problems once.
+	checkedFonts map[int64]bool
+	checkedRes   map[int64]bool
+	// Object numbers of the page tree nodes visited, to detect loops.
+	pageNodes map[int64]bool
+	// Number of pages found so far.
+	pageNum int
+}
+
+// add adds `issue` to the report.
+func (p *preflighter) add(issue PreflightIssue) {
+	p.report.Issues = append(p.report.Issues, issue)
+}
+
+// addf adds an issue with `severity` and `code` for the object `loc` to the report.
+func (p *preflighter) addf(severity PreflightSeverity, code PreflightCode, loc preflightLoc, key string,
+	format string, args ...interface{}) {
+	p.add(PreflightIssue{
+		Severity:         severity,
+		Code:             code,
+		Message:          fmt.Sprintf(format, args...),
+		ObjectNumber:     loc.objNum,
+		GenerationNumber: loc.genNum,
+		PageNumber:       loc.pageNum,
+		Key:              key,
+	})
+}
+
+// preflightLoc is the location of a checked object: the indirect object containing it and the page
+// using it.
+type preflightLoc struct {
+	objNum  int64
+	genNum  int64
+	pageNum int
+}
+
+// locate returns the location of `obj` used by an object at `loc`: the location of the indirect
+// object referenced by `obj`, if any, or `loc`.
+func (loc preflightLoc) locate(obj core.PdfObject) preflightLoc {
+	switch t := obj.(type) {
+	case *core.PdfObjectReference:
+		return preflightLoc{objNum: t.ObjectNumber, genNum: t.GenerationNumber, pageNum: loc.pageNum}
+	case *core.PdfIndirectObject:
+		return preflightLoc{objNum: t.ObjectNumber, genNum: t.GenerationNumber, pageNum: loc.pageNum}
+	case *core.PdfObjectStream:
+		return preflightLoc{objNum: t.ObjectNumber, genNum: t.GenerationNumber, pageNum: loc.pageNum}
+	}
+	return loc
+}
+
+// reObjectHeader matches the header of an indirect object.
+var reObjectHeader = regexp.MustCompile(`^\s*(\d+)\s+(\d+)\s+obj`)
+
+// checkXrefOffsets checks that the cross-reference table entries point to the objects they
+// describe. It is done before loading the objects, as loading objects at wrong offsets makes the
+// parser rebuild the cross-reference table.
+func (p *preflighter) checkXrefOffsets() {
+	xrefs := p.parser.GetXrefTable()
+	for _, objNum := range sortedXrefNums(xrefs) {
+		xref := xrefs.ObjectMap[objNum]
+		loc := preflightLoc{objNum: int64(objNum), genNum: int64(xref.Generation)}
+		if xref.XType == core.XrefTypeObjectStream {
+			if _, ok := xrefs.ObjectMap[xref.OsObjNumber]; !ok {
+				p.addf(PreflightSeverityError, PreflightXrefOffset, loc, "",
+					"cross-reference entry points to undefined object stream %d", xref.OsObjNumber)
+			}
+			continue
+		}
+
+		bb, err := p.parser.ReadBytesAt(xref.Offset, 32)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			p.addf(PreflightSeverityError, PreflightXrefOffset, loc, "",
+				"unable to read object at offset %d: %v", xref.Offset, err)
+			continue
+		}
+		m := reObjectHeader.FindSubmatch(bb)
+		if m == nil {
+			p.addf(PreflightSeverityError, PreflightXrefOffset, loc, "",
+				"cross-reference offset %d does not point to an object", xref.Offset)
+			continue
+		}
+		num, _ := strconv.Atoi(string(m[1]))
+		gen, _ := strconv.Atoi(string(m[2]))
+		if num != objNum || gen != xref.Generation {
+			p.addf(PreflightSeverityError, PreflightXrefOffset, loc, "",
+				"cross-reference offset %d points to object %d %d", xref.Offset, num, gen)
+		}
+	}
+}
+
+// sortedXrefNums returns the object numbers of the cross-reference table `xrefs`, sorted.
+func sortedXrefNums(xrefs core.XrefTable) []int {
+	nums := make([]int, 0, len(xrefs.ObjectMap))
+	for num := range xrefs.ObjectMap {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// checkObjects loads all the objects of the cross-reference table and checks their references and
+// their stream lengths.
+func (p *preflighter) checkObjects() {
+	for _, objNum := range sortedXrefNums(p.parser.GetXrefTable()) {
+		obj, err := p.parser.LookupByNumber(objNum)
+		if err != nil {
+			p.addf(PreflightSeverityError, PreflightObjectInvalid, preflightLoc{objNum: int64(objNum)}, "",
+				"unable to load object: %v", err)
+			continue
+		}
+		loc := preflightLoc{}.locate(obj)
+		if loc.objNum == 0 {
+			loc.objNum = int64(objNum)
+		}
+		p.checkReferences(obj, loc, "")
+		if stream, ok := obj.(*core.PdfObjectStream); ok {
+			p.checkStreamLength(stream, loc)
+		}
+	}
+	if trailer := p.parser.GetTrailer(); trailer != nil {
+		p.checkReferences(trailer, preflightLoc{}, "")
+	}
+}
+
+// checkReferences checks the references contained by `obj`, located at `loc` under the dictionary
+// entry `key`. References are not followed.
+func (p *preflighter) checkReferences(obj core.PdfObject, loc preflightLoc, key string) {
+	switch t := obj.(type) {
+	case *core.PdfObjectReference:
+		xref, ok := p.parser.GetXrefTable().ObjectMap[int(t.ObjectNumber)]
+		if !ok {
+			p.addf(PreflightSeverityError, PreflightBrokenReference, loc, key,
+				"reference to undefined object %d %d R", t.ObjectNumber, t.GenerationNumber)
+		} else if xref.XType == core.XrefTypeTableEntry && int64(xref.Generation) != t.GenerationNumber {
+			p.addf(PreflightSeverityError, PreflightBrokenReference, loc, key,
+				"reference %d %d R does not match generation %d", t.ObjectNumber, t.GenerationNumber,
+				xref.Generation)
+		}
+	case *core.PdfIndirectObject:
+		p.checkReferences(t.PdfObject, loc, key)
+	case *core.PdfObjectStream:
+		p.checkReferences(t.PdfObjectDictionary, loc, key)
+	case *core.PdfObjectDictionary:
+		for _, k := range t.Keys() {
+			p.checkReferences(t.Get(k), loc, string(k))
+		}
+	case *core.PdfObjectArray:
+		for _, elem := range t.Elements() {
+			p.checkReferences(elem, loc, key)
+		}
+	}
+}
+
+// checkStreamLength checks the Length entry of `stream`.
+func (p *preflighter) checkStreamLength(stream *core.PdfObjectStream, loc preflightLoc) {
+	lengthObj := stream.Get("Length")
+	if lengthObj == nil {
+		p.addf(PreflightSeverityError, PreflightMissingKey, loc, "Length", "stream without Length")
+		return
+	}
+	length, ok := core.GetIntVal(lengthObj)
+	if !ok || length < 0 {
+		p.addf(PreflightSeverityError, PreflightStreamLength, loc, "Length",
+			"invalid stream Length %s", core.TraceToDirectObject(lengthObj))
+		return
+	}
+	// The lengths of the decrypted streams differ from the lengths of their encrypted data.
+	if p.parser.GetCrypter() == nil && length != len(stream.Stream) {
+		p.addf(PreflightSeverityError, PreflightStreamLength, loc, "Length",
+			"stream Length %d does not match the %d bytes of data", length, len(stream.Stream))
+	}
+}
+
+// checkDocument checks the trailer, the catalog and the page tree.
+func (p *preflighter) checkDocument() {
+	trailer := p.parser.GetTrailer()
+	if trailer == nil {
+		p.addf(PreflightSeverityError, PreflightMissingKey, preflightLoc{}, "", "missing trailer")
+		return
+	}
+	if trailer.Get("Size") == nil {
+		p.addf(PreflightSeverityError, PreflightMissingKey, preflightLoc{}, "Size", "trailer without Size")
+	}
+	rootObj := trailer.Get("Root")
+	if rootObj == nil {
+		p.addf(PreflightSeverityError, PreflightMissingKey, preflightLoc{}, "Root", "trailer without Root")
+		return
+	}
+	loc := preflightLoc{}.locate(rootObj)
+	catalog, ok := core.GetDict(rootObj)
+	if !ok {
+		p.addf(PreflightSeverityError, PreflightInvalidType, loc, "", "catalog is not a dictionary")
+		return
+	}
+	p.checkType(catalog, loc, "Catalog", true)
+
+	pagesObj := catalog.Get("Pages")
+	if pagesObj == nil {
+		p.addf(PreflightSeverityError, PreflightMissingKey, loc, "Pages", "catalog without Pages")
+		return
+	}
+	p.checkPageNode(pagesObj, loc, nil, nil, true)
+}
+
+// checkType checks the Type entry of `dict`, located at `loc`, against `typ`. A missing Type entry
+// is reported if `required` is true.
+func (p *preflighter) checkType(dict *core.PdfObjectDictionary, loc preflightLoc, typ string, required bool) bool {
+	name, ok := core.GetName(dict.Get("Type"))
+	if !ok {
+		if required {
+			p.addf(PreflightSeverityError, PreflightMissingKey, loc, "Type", "missing /Type /%s", typ)
+		}
+		return !required
+	}
+	if string(*name) != typ {
+		p.addf(PreflightSeverityError, PreflightInvalidType, loc, "Type", "/Type is /%s instead of /%s", *name, typ)
+		return false
+	}
+	return true
+}
+
+// checkPageNode checks the page tree node `obj`, referenced from `parentLoc`, with inherited
+// `resources` and `mediaBox`. `root` is true for the root of the page tree.
+func (p *preflighter) checkPageNode(obj core.PdfObject, parentLoc preflightLoc, resources, mediaBox core.PdfObject,
+	root bool) {
+	loc := parentLoc.locate(obj)
+	loc.pageNum = 0
+	if loc.objNum > 0 && loc.objNum != parentLoc.objNum {
+		if p.pageNodes[loc.objNum] {
+			p.addf(PreflightSeverityError, PreflightInvalidValue, loc, "Kids", "loop in the page tree")
+			return
+		}
+		p.pageNodes[loc.objNum] = true
+	}
+	node, ok := core.GetDict(obj)
+	if !ok {
+		p.addf(PreflightSeverityError, PreflightInvalidType, parentLoc, "", "page tree node is not a dictionary")
+		return
+	}
+	if r := node.Get("Resources"); r != nil {
+		resources = r
+	}
+	if mb := node.Get("MediaBox"); mb != nil {
+		mediaBox = mb
+	}
+	if !root && node.Get("Parent") == nil {
+		p.addf(PreflightSeverityError, PreflightMissingKey, loc, "Parent", "page tree node without Parent")
+	}
+
+	typ, _ := core.GetNameVal(node.Get("Type"))
+	if typ == "" {
+		p.addf(PreflightSeverityError, PreflightMissingKey, loc, "Type", "page tree node without Type")
+		if node.Get("Kids") != nil {
+			typ = "Pages"
+		} else {
+			typ = "Page"
+		}
+	}
+	switch {
+	case typ == "Pages":
+		p.checkPagesNode(node, loc, resources, mediaBox)
+	case typ == "Page" && !root:
+		p.pageNum++
+		loc.pageNum = p.pageNum
+		p.checkPage(node, loc, resources, mediaBox)
+	default:
+		p.addf(PreflightSeverityError, PreflightInvalidType, loc, "Type", "page tree node with /Type /%s", typ)
+	}
+}
+
+// checkPagesNode checks the intermediate page tree node `node`.
+func (p *preflighter) checkPagesNode(node *core.PdfObjectDictionary, loc preflightLoc, resources,
+	mediaBox core.PdfObject) {
+	if _, ok := core.GetIntVal(node.Get("Count")); !ok {
+		p.addf(PreflightSeverityError, PreflightMissingKey, loc, "Count", "pages node without valid Count")
+	}
+	kids, ok := core.GetArray(node.Get("Kids"))
+	if !ok {
+		p.addf(PreflightSeverityError, PreflightMissingKey, loc, "Kids", "pages node without valid Kids")
+		return
+	}
+	for _, kid := range kids.Elements() {
+		p.checkPageNode(kid, loc, resources, mediaBox, false)
+	}
+}
+
+// checkPage checks the page `page` with its inherited `resources` and `mediaBox`.
+func (p *preflighter) checkPage(page *core.PdfObjectDictionary, loc preflightLoc, resources,
+	mediaBox core.PdfObject) {
+	if mediaBox == nil {
+		p.addf(PreflightSeverityError, PreflightMissingKey, loc, "MediaBox", "page without MediaBox")
+	} else if !isPreflightRect(mediaBox) {
+		p.addf(PreflightSeverityError, PreflightInvalidValue, loc, "MediaBox", "MediaBox is not a rectangle")
+	}
+	if resources == nil {
+		p.addf(PreflightSeverityError, PreflightMissingKey, loc, "Resources", "page without Resources")
+	} else {
+		p.checkResources(resources, loc)
+	}
+
+	if annotsObj := page.Get("Annots"); annotsObj != nil {
+		annots, ok := core.GetArray(annotsObj)
+		if !ok {
+			p.addf(PreflightSeverityError, PreflightInvalidType, loc, "Annots", "Annots is not an array")
+			return
+		}
+		for _, annot := range annots.Elements() {
+			p.checkAnnotation(annot, loc)
+		}
+	}
+}
+
+// isPreflightRect returns true if `obj` is an array of 4 numbers.
+func isPreflightRect(obj core.PdfObject) bool {
+	arr, ok := core.GetArray(obj)
+	if !ok || arr.Len() != 4 {
+		return false
+	}
+	_, err := arr.ToFloat64Array()
+	return err == nil
+}
+
+// checkResources checks the fonts and XObjects of the resource dictionary `obj` used at `parentLoc`.
+func (p *preflighter) checkResources(obj core.PdfObject, parentLoc preflightLoc) {
+	loc := parentLoc.locate(obj)
+	if loc.objNum > 0 && loc.objNum != parentLoc.objNum {
+		if p.checkedRes[loc.objNum] {
+			return
+		}
+		p.checkedRes[loc.objNum] = true
+	}
+	resources, ok := core.GetDict(obj)
+	if !ok {
+		p.addf(PreflightSeverityError, PreflightInvalidType, loc, "Resources", "Resources is not a dictionary")
+		return
+	}
+
+	if fontsObj := resources.Get("Font"); fontsObj != nil {
+		fontDict, ok := core.GetDict(fontsObj)
+		if !ok {
+			p.addf(PreflightSeverityError, PreflightInvalidType, loc, "Font", "Font resources are not a dictionary")
+		} else {
+			for _, name := range fontDict.Keys() {
+				p.checkFont(fontDict.Get(name), loc, name)
+			}
+		}
+	}
+
+	if xobjsObj := resources.Get("XObject"); xobjsObj != nil {
+		xobjDict, ok := core.GetDict(xobjsObj)
+		if !ok {
+			p.addf(PreflightSeverityError, PreflightInvalidType, loc, "XObject", "XObject resources are not a dictionary")
+		} else {
+			for _, name := range xobjDict.Keys() {
+				p.checkXObject(xobjDict.Get(name), loc, name)
+			}
+		}
+	}
+}
+
+// checkXObject checks the XObject `obj`, used under `name` in resources located at `parentLoc`.
+func (p *preflighter) checkXObject(obj core.PdfObject, parentLoc preflightLoc, name core.PdfObjectName) {
+	loc := parentLoc.locate(obj)
+	stream, ok := core.GetStream(obj)
+	if !ok {
+		p.addf(PreflightSeverityError, PreflightInvalidType, parentLoc, string(name), "XObject %s is not a stream", name)
+		return
+	}
+	p.checkType(stream.PdfObjectDictionary, loc, "XObject", false)
+	subtype, ok := core.GetNameVal(stream.Get("Subtype"))
+	if !ok {
+		p.addf(PreflightSeverityError, PreflightMissingKey, loc, "Subtype", "XObject %s without Subtype", name)
+		return
+	}
+	switch subtype {
+	case "Form":
+		if res := stream.Get("Resources"); res != nil {
+			p.checkResources(res, loc)
+		}
+	case "Image", "PS":
+	default:
+		p.addf(PreflightSeverityError, PreflightInvalidValue, loc, "Subtype", "invalid XObject Subtype /%s", subtype)
+	}
+}
+
+// checkFont checks the font `obj`, used under `name` in resources located at `parentLoc`.
+func (p *preflighter) checkFont(obj core.PdfObject, parentLoc preflightLoc, name core.PdfObjectName) {
+	loc := parentLoc.locate(obj)
+	if loc.objNum > 0 && loc.objNum != parentLoc.objNum {
+		if p.checkedFonts[loc.objNum] {
+			return
+		}
+		p.checkedFonts[loc.objNum] = true
+	}
+	font, ok := core.GetDict(obj)
+	if !ok {
+		p.addf(PreflightSeverityError, PreflightInvalidType, parentLoc, string(name), "font %s is not a dictionary", name)
+		return
+	}
+	p.checkType(font, loc, "Font", true)
+
+	subtype, ok := core.GetNameVal(font.Get("Subtype"))
+	if !ok {
+		p.addf(PreflightSeverityError, PreflightMissingKey, loc, "Subtype", "font %s without Subtype", name)
+		return
+	}
+	baseFont, hasBaseFont := core.GetNameVal(font.Get("BaseFont"))
+	if !hasBaseFont && subtype != "Type3" {
+		p.addf(PreflightSeverityError, PreflightMissingKey, loc, "BaseFont", "font %s without BaseFont", name)
+	}
+
+	switch subtype {
+	case "Type1", "MMType1", "TrueType", "Type3":
+		// The standard 14 fonts can omit their widths and descriptors (deprecated since PDF 1.5).
+		isStd := subtype == "Type1" && fonts.IsStdFont(fonts.StdFontName(baseFont))
+		p.checkFontWidths(font, loc, name, isStd)
+		if subtype != "Type3" && !isStd && font.Get("FontDescriptor") == nil {
+			p.addf(PreflightSeverityError, PreflightMissingKey, loc, "FontDescriptor",
+				"font %s without FontDescriptor", name)
+		}
+	case "Type0":
+		descendants, ok := core.GetArray(font.Get("DescendantFonts"))
+		if !ok || descendants.Len() != 1 {
+			p.addf(PreflightSeverityError, PreflightMissingKey, loc, "DescendantFonts",
+				"composite font %s without a single descendant font", name)
+			return
+		}
+		p.checkCIDFont(descendants.Get(0), loc, name)
+	default:
+		p.addf(PreflightSeverityError, PreflightInvalidValue, loc, "Subtype", "invalid font Subtype /%s", subtype)
+	}
+}
+
+// checkFontWidths checks the widths of the simple font `font`. The widths of the standard 14 fonts
+// (`isStd`) are optional.
+func (p *preflighter) checkFontWidths(font *core.PdfObjectDictionary, loc preflightLoc, name core.PdfObjectName,
+	isStd bool) {
+	widthsObj := font.Get("Widths")
+	if widthsObj == nil {
+		if isStd {
+			p.addf(PreflightSeverityInfo, PreflightFontWidths, loc, "Widths",
+				"standard font %s without Widths, the built-in metrics are used", name)
+		} else {
+			p.addf(PreflightSeverityError, PreflightFontWidths, loc, "Widths", "font %s without Widths", name)
+		}
+		return
+	}
+	widths, ok := core.GetArray(widthsObj)
+	if !ok {
+		p.addf(PreflightSeverityError, PreflightFontWidths, loc, "Widths", "font %s Widths is not an array", name)
+		return
+	}
+	firstChar, ok1 := core.GetIntVal(font.Get("FirstChar"))
+	lastChar, ok2 := core.GetIntVal(font.Get("LastChar"))
+	if !ok1 || !ok2 {
+		p.addf(PreflightSeverityError, PreflightMissingKey, loc, "FirstChar",
+			"font %s with Widths but without valid FirstChar and LastChar", name)
+		return
+	}
+	if n := lastChar - firstChar + 1; n != widths.Len() {
+		p.addf(PreflightSeverityError, PreflightFontWidths, loc, "Widths",
+			"font %s has %d widths for %d character codes", name, widths.Len(), n)
+	}
+}
+
+// checkCIDFont checks the descendant font `obj` of the composite font `name`.
+func (p *preflighter) checkCIDFont(obj core.PdfObject, parentLoc preflightLoc, name core.PdfObjectName) {
+	loc := parentLoc.locate(obj)
+	font, ok := core.GetDict(obj)
+	if !ok {
+		p.addf(PreflightSeverityError, PreflightInvalidType, parentLoc, "DescendantFonts",
+			"descendant font of %s is not a dictionary", name)
+		return
+	}
+	p.checkType(font, loc, "Font", true)
+	subtype, _ := core.GetNameVal(font.Get("Subtype"))
+	if subtype != "CIDFontType0" && subtype != "CIDFontType2" {
+		p.addf(PreflightSeverityError, PreflightInvalidValue, loc, "Subtype",
+			"invalid descendant font Subtype /%s", subtype)
+	}
+	if font.Get("FontDescriptor") == nil {
+		p.addf(PreflightSeverityError, PreflightMissingKey, loc, "FontDescriptor",
+			"descendant font of %s without FontDescriptor", name)
+	}
+}
+
+// checkAnnotation checks the annotation `obj` of the page at `parentLoc`.
+func (p *preflighter) checkAnnotation(obj core.PdfObject, parentLoc preflightLoc) {
+	loc := parentLoc.locate(obj)
+	annot, ok := core.GetDict(obj)
+	if !ok {
+		p.addf(PreflightSeverityError, PreflightInvalidType, parentLoc, "Annots", "annotation is not a dictionary")
+		return
+	}
+	p.checkType(annot, loc, "Annot", false)
+	subtype, ok := core.GetNameVal(annot.Get("Subtype"))
+	if !ok {
+		p.addf(PreflightSeverityError, PreflightMissingKey, loc, "Subtype", "annotation without Subtype")
+	}
+	rectObj := annot.Get("Rect")
+	if rectObj == nil {
+		p.addf(PreflightSeverityError, PreflightMissingKey, loc, "Rect", "annotation without Rect")
+		return
+	}
+	if !isPreflightRect(rectObj) {
+		p.addf(PreflightSeverityError, PreflightInvalidValue, loc, "Rect", "annotation Rect is not a rectangle")
+		return
+	}
+
+	// Popup and link annotations, and the annotations with a null area, have no appearance.
+	switch subtype {
+	case "Popup", "Link", "Projection":
+		return
+	}
+	rect, _ := core.GetArray(rectObj)
+	r, _ := rect.ToFloat64Array()
+	if r[0] == r[2] || r[1] == r[3] {
+		return
+	}
+	ap, ok := core.GetDict(annot.Get("AP"))
+	if !ok || ap.Get("N") == nil {
+		p.addf(PreflightSeverityWarning, PreflightAnnotationAppearance, loc, "AP",
+			"%s annotation without normal appearance stream", subtype)
+	}
+}
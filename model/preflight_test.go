@@ -0,0 +1,141 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// makePreflightTestPdf returns a PDF file with the objects `objects` (numbered from 1). The offset
+// of the objects listed in `wrongOffsets` is shifted in the cross-reference table.
+func makePreflightTestPdf(objects []string, wrongOffsets ...int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objects)+1)
+	for i, obj := range objects {
+		offsets[i+1] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	for _, num := range wrongOffsets {
+		offsets[num] += 3
+	}
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f\r\n", len(objects)+1)
+	for _, offset := range offsets[1:] {
+		fmt.Fprintf(&buf, "%010d 00000 n\r\n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xrefOffset)
+	return buf.Bytes()
+}
+
+// findPreflightIssue returns the first issue of `report` with `code` for object `objNum`.
+func findPreflightIssue(report *PreflightReport, code PreflightCode, objNum int64) (PreflightIssue, bool) {
+	for _, issue := range report.Issues {
+		if issue.Code == code && issue.ObjectNumber == objNum {
+			return issue, true
+		}
+	}
+	return PreflightIssue{}, false
+}
+
+func TestPreflightValid(t *testing.T) {
+	data := makePreflightTestPdf([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /MediaBox [0 0 612 792] >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R /Annots [6 0 R] >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Length 8 >>\nstream\nBT ET q\n\nendstream",
+		"<< /Type /Annot /Subtype /Link /Rect [0 0 10 10] >>",
+	})
+
+	report, err := Preflight(bytes.NewReader(data), nil)
+	require.NoError(t, err)
+	require.False(t, report.HasErrors(), "%v", report.Issues)
+	require.Empty(t, report.Filter(PreflightSeverityWarning))
+
+	// The widths of the standard font are optional.
+	require.Len(t, report.Issues, 1)
+	require.Equal(t, PreflightSeverityInfo, report.Issues[0].Severity)
+	require.Equal(t, PreflightFontWidths, report.Issues[0].Code)
+	require.Equal(t, int64(4), report.Issues[0].ObjectNumber)
+	require.Equal(t, 1, report.Issues[0].PageNumber)
+}
+
+func TestPreflightInvalid(t *testing.T) {
+	data := makePreflightTestPdf([]string{
+		"<< /Type /Catalog /Pages 2 0 R /Outlines 20 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 7 0 R] /Count 2 /MediaBox [0 0 612 792] >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 5 0 R /Annots [6 0 R] >>",
+		"<< /Type /FontDescriptor /Subtype /TrueType /BaseFont /Arial-Custom >>",
+		"<< /Length 30 >>\nstream\nBT ET\nendstream",
+		"<< /Type /Annot /Subtype /Square /Rect [0 0 10 10] >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 8 0 R >> >> >>",
+		"<< /Type /Font /Subtype /TrueType /BaseFont /Custom /FontDescriptor 9 0 R " +
+			"/FirstChar 32 /LastChar 34 /Widths [500 500] >>",
+		"<< /Type /FontDescriptor /FontName /Custom >>",
+	}, 9)
+
+	report, err := Preflight(bytes.NewReader(data), nil)
+	require.NoError(t, err)
+	require.True(t, report.HasErrors())
+
+	expected := []struct {
+		code    PreflightCode
+		objNum  int64
+		pageNum int
+		key     string
+	}{
+		{PreflightBrokenReference, 1, 0, "Outlines"},
+		{PreflightXrefOffset, 9, 0, ""},
+		{PreflightStreamLength, 5, 0, "Length"},
+		{PreflightMissingKey, 3, 1, "Resources"},
+		{PreflightAnnotationAppearance, 6, 1, "AP"},
+		{PreflightFontWidths, 8, 2, "Widths"},
+	}
+	for _, exp := range expected {
+		issue, ok := findPreflightIssue(report, exp.code, exp.objNum)
+		require.True(t, ok, "missing %s for object %d in %v", exp.code, exp.objNum, report.Issues)
+		require.Equal(t, exp.pageNum, issue.PageNumber, issue.String())
+		require.Equal(t, exp.key, issue.Key, issue.String())
+	}
+	issue, _ := findPreflightIssue(report, PreflightAnnotationAppearance, 6)
+	require.Equal(t, PreflightSeverityWarning, issue.Severity)
+
+	// Invalid Type of a font dictionary, used on a page.
+	data = makePreflightTestPdf([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 4 0 R >> >> >>",
+		"<< /Type /FontDescriptor /Subtype /Type1 /BaseFont /Helvetica >>",
+	})
+	report, err = Preflight(bytes.NewReader(data), nil)
+	require.NoError(t, err)
+	issue, ok := findPreflightIssue(report, PreflightInvalidType, 4)
+	require.True(t, ok, "%v", report.Issues)
+	require.Equal(t, "Type", issue.Key)
+	require.Equal(t, 1, issue.PageNumber)
+}
+
+func TestPreflightReportJSON(t *testing.T) {
+	report, err := Preflight(bytes.NewReader([]byte("not a pdf")), nil)
+	require.NoError(t, err)
+	require.Len(t, report.Issues, 1)
+	require.Equal(t, PreflightFileInvalid, report.Issues[0].Code)
+
+	data, err := json.Marshal(report)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"severity":"error"`)
+	require.Contains(t, string(data), `"code":"file-invalid"`)
+
+	var decoded PreflightReport
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, report.Issues, decoded.Issues)
+}
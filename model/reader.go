@@ -6,10 +6,14 @@
 package model
 
 import (
+	"crypto"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
+	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/unidoc/unipdf/v3/common"
 	"github.com/unidoc/unipdf/v3/core"
@@ -39,6 +43,9 @@ type PdfReader struct {
 	// For tracking traversal (cache).
 	traversed map[core.PdfObject]struct{}
 	rs        io.ReadSeeker
+
+	// Records the diagnostics of the reader and of its parser.
+	diagnostics *common.Diagnostics
 }
 
 // NewPdfReader returns a new PdfReader for an input io.ReadSeeker interface. Can be used to read PDF from
@@ -47,34 +54,7 @@ type PdfReader struct {
 // Alternatively a lazy-loading reader can be created with NewPdfReaderLazy which loads only references,
 // and references are loaded from disk into memory on an as-needed basis.
 func NewPdfReader(rs io.ReadSeeker) (*PdfReader, error) {
-	pdfReader := &PdfReader{
-		rs:           rs,
-		traversed:    map[core.PdfObject]struct{}{},
-		modelManager: newModelManager(),
-		isLazy:       false,
-	}
-
-	// Create the parser, loads the cross reference table and trailer.
-	parser, err := core.NewParser(rs)
-	if err != nil {
-		return nil, err
-	}
-	pdfReader.parser = parser
-
-	isEncrypted, err := pdfReader.IsEncrypted()
-	if err != nil {
-		return nil, err
-	}
-
-	// Load pdf doc structure if not encrypted.
-	if !isEncrypted {
-		err = pdfReader.loadStructure()
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	return pdfReader, nil
+	return NewPdfReaderWithOpts(rs, nil)
 }
 
 // NewPdfReaderLazy creates a new PdfReader for `rs` in lazy-loading mode. The difference
@@ -83,15 +63,36 @@ func NewPdfReader(rs io.ReadSeeker) (*PdfReader, error) {
 // Note that it may make sense to use the lazy-load reader when processing only parts of files,
 // rather than loading entire file into memory. Example: splitting a few pages from a large PDF file.
 func NewPdfReaderLazy(rs io.ReadSeeker) (*PdfReader, error) {
+	return NewPdfReaderWithOpts(rs, &ReaderOpts{LazyLoad: true})
+}
+
+// ReaderOpts defines options for creating a PdfReader.
+type ReaderOpts struct {
+	// LazyLoad enables the lazy-loading mode (see NewPdfReaderLazy).
+	LazyLoad bool
+
+	// Logger receives the log messages and the diagnostics of the reader and of its parser. The
+	// global common.Log is used if nil. The diagnostics are recorded by the reader in any case and
+	// can be retrieved with Diagnostics.
+	Logger common.Logger
+}
+
+// NewPdfReaderWithOpts creates a new PdfReader for `rs` with options `opts`. The default options
+// are used if `opts` is nil.
+func NewPdfReaderWithOpts(rs io.ReadSeeker, opts *ReaderOpts) (*PdfReader, error) {
+	if opts == nil {
+		opts = &ReaderOpts{}
+	}
 	pdfReader := &PdfReader{
 		rs:           rs,
 		traversed:    map[core.PdfObject]struct{}{},
 		modelManager: newModelManager(),
-		isLazy:       true,
+		isLazy:       opts.LazyLoad,
+		diagnostics:  common.NewDiagnostics(opts.Logger),
 	}
 
 	// Create the parser, loads the cross reference table and trailer.
-	parser, err := core.NewParser(rs)
+	parser, err := core.NewParserWithLogger(rs, pdfReader.diagnostics)
 	if err != nil {
 		return nil, err
 	}
@@ -113,9 +114,52 @@ func NewPdfReaderLazy(rs io.ReadSeeker) (*PdfReader, error) {
 	return pdfReader, nil
 }
 
-// PdfVersion returns version of the PDF file.
+// Logger returns the logger of the reader, which records the diagnostics of the reader and
+// forwards the log messages to the logger of the reader options or to the global common.Log.
+func (r *PdfReader) Logger() common.Logger {
+	return r.log()
+}
+
+// Diagnostics returns the diagnostics reported so far while loading the document, such as the
+// repairs of a damaged file and the objects which could not be loaded, including the diagnostics
+// reported by the processing of its pages with the reader logger (see PdfPage.Logger).
+func (r *PdfReader) Diagnostics() []common.Diagnostic {
+	if r.diagnostics == nil {
+		return nil
+	}
+	return r.diagnostics.Diagnostics()
+}
+
+// log returns the logger of the reader.
+func (r *PdfReader) log() common.Logger {
+	if r.diagnostics == nil {
+		return common.Log
+	}
+	return r.diagnostics
+}
+
+// report reports a diagnostic with `code` concerning object `objNum` to the logger of the reader.
+func (r *PdfReader) report(code common.DiagnosticCode, objNum int64, format string, args ...interface{}) {
+	common.ReportDiagnosticDepth(r.log(), 1, common.Diagnostic{
+		Level:        common.LogLevelWarning,
+		Code:         code,
+		Message:      fmt.Sprintf(format, args...),
+		ObjectNumber: objNum,
+	})
+}
+
+// PdfVersion returns version of the PDF file: the version of the file header, or the Version
+// entry of the catalog if later.
 func (r *PdfReader) PdfVersion() core.Version {
-	return r.parser.PdfVersion()
+	version := r.parser.PdfVersion()
+	if r.catalog != nil {
+		if name, ok := core.GetNameVal(r.catalog.Get("Version")); ok {
+			if v, ok := parseVersion(name); ok && versionLess(version, v) {
+				version = v
+			}
+		}
+	}
+	return version
 }
 
 // IsEncrypted returns true if the PDF file is encrypted.
@@ -143,13 +187,45 @@ func (r *PdfReader) Decrypt(password []byte) (bool, error) {
 
 	err = r.loadStructure()
 	if err != nil {
-		common.Log.Debug("ERROR: Fail to load structure (%s)", err)
+		r.log().Debug("ERROR: Fail to load structure (%s)", err)
+		return false, err
+	}
+
+	return true, nil
+}
+
+// DecryptWithCertificate decrypts a PDF file encrypted for a list of recipients with the public-key
+// security handler, using the recipient certificate `cert` and its private key `pkey`.
+// Returns true if successful, false if the document is not encrypted for the certificate.
+func (r *PdfReader) DecryptWithCertificate(cert *x509.Certificate, pkey crypto.PrivateKey) (bool, error) {
+	success, err := r.parser.DecryptWithCertificate(cert, pkey)
+	if err != nil {
+		return false, err
+	}
+	if !success {
+		return false, nil
+	}
+
+	err = r.loadStructure()
+	if err != nil {
+		r.log().Debug("ERROR: Fail to load structure (%s)", err)
 		return false, err
 	}
 
 	return true, nil
 }
 
+// GetAccessPermissions returns the access permissions of an encrypted document: the permissions granted
+// to the authenticated recipient for documents encrypted for certificates, or the user access permissions
+// for documents encrypted with a password. Unencrypted documents grant all permissions.
+func (r *PdfReader) GetAccessPermissions() security.Permissions {
+	crypter := r.parser.GetCrypter()
+	if crypter == nil {
+		return security.PermOwner
+	}
+	return crypter.GetAccessPermissions()
+}
+
 // CheckAccessRights checks access rights and permissions for a specified password.  If either user/owner
 // password is specified,  full rights are granted, otherwise the access rights are specified by the
 // Permissions flag.
@@ -179,20 +255,20 @@ func (r *PdfReader) loadStructure() error {
 	}
 	oc, err := r.parser.LookupByReference(*root)
 	if err != nil {
-		common.Log.Debug("ERROR: Failed to read root element catalog: %s", err)
+		r.log().Debug("ERROR: Failed to read root element catalog: %s", err)
 		return err
 	}
 	pcatalog, ok := oc.(*core.PdfIndirectObject)
 	if !ok {
-		common.Log.Debug("ERROR: Missing catalog: (root %q) (trailer %s)", oc, *trailerDict)
+		r.log().Debug("ERROR: Missing catalog: (root %q) (trailer %s)", oc, *trailerDict)
 		return errors.New("missing catalog")
 	}
 	catalog, ok := (*pcatalog).PdfObject.(*core.PdfObjectDictionary)
 	if !ok {
-		common.Log.Debug("ERROR: Invalid catalog (%s)", pcatalog.PdfObject)
+		r.log().Debug("ERROR: Invalid catalog (%s)", pcatalog.PdfObject)
 		return errors.New("invalid catalog")
 	}
-	common.Log.Trace("Catalog: %s", catalog)
+	r.log().Trace("Catalog: %s", catalog)
 
 	// Pages.
 	pagesRef, ok := catalog.Get("Pages").(*core.PdfObjectReference)
@@ -201,27 +277,27 @@ func (r *PdfReader) loadStructure() error {
 	}
 	op, err := r.parser.LookupByReference(*pagesRef)
 	if err != nil {
-		common.Log.Debug("ERROR: Failed to read pages")
+		r.log().Debug("ERROR: Failed to read pages")
 		return err
 	}
 	ppages, ok := op.(*core.PdfIndirectObject)
 	if !ok {
-		common.Log.Debug("ERROR: Pages object invalid")
-		common.Log.Debug("op: %p", ppages)
+		r.log().Debug("ERROR: Pages object invalid")
+		r.log().Debug("op: %p", ppages)
 		return errors.New("pages object invalid")
 	}
 	pages, ok := ppages.PdfObject.(*core.PdfObjectDictionary)
 	if !ok {
-		common.Log.Debug("ERROR: Pages object invalid (%s)", ppages)
+		r.log().Debug("ERROR: Pages object invalid (%s)", ppages)
 		return errors.New("pages object invalid")
 	}
 	pageCount, ok := core.GetInt(pages.Get("Count"))
 	if !ok {
-		common.Log.Debug("ERROR: Pages count object invalid")
+		r.log().Debug("ERROR: Pages count object invalid")
 		return errors.New("pages count invalid")
 	}
 	if _, ok = core.GetName(pages.Get("Type")); !ok {
-		common.Log.Debug("Pages dict Type field not set. Setting Type to Pages.")
+		r.log().Debug("Pages dict Type field not set. Setting Type to Pages.")
 		pages.Set("Type", core.MakeName("Pages"))
 	}
 
@@ -237,15 +313,15 @@ func (r *PdfReader) loadStructure() error {
 	if err != nil {
 		return err
 	}
-	common.Log.Trace("---")
-	common.Log.Trace("TOC")
-	common.Log.Trace("Pages")
-	common.Log.Trace("%d: %s", len(r.pageList), r.pageList)
+	r.log().Trace("---")
+	r.log().Trace("TOC")
+	r.log().Trace("Pages")
+	r.log().Trace("%d: %s", len(r.pageList), r.pageList)
 
 	// Outlines.
 	r.outlineTree, err = r.loadOutlines()
 	if err != nil {
-		common.Log.Debug("ERROR: Failed to build outline tree (%s)", err)
+		r.log().Debug("ERROR: Failed to build outline tree (%s)", err)
 		return err
 	}
 
@@ -270,24 +346,24 @@ func (r *PdfReader) loadOutlines() (*PdfOutlineTreeNode, error) {
 		return nil, nil
 	}
 
-	common.Log.Trace("-Has outlines")
+	r.log().Trace("-Has outlines")
 	// Trace references to the object.
 	outlineRootObj := core.ResolveReference(outlinesObj)
-	common.Log.Trace("Outline root: %v", outlineRootObj)
+	r.log().Trace("Outline root: %v", outlineRootObj)
 
 	if isNull := core.IsNullObject(outlineRootObj); isNull {
-		common.Log.Trace("Outline root is null - no outlines")
+		r.log().Trace("Outline root is null - no outlines")
 		return nil, nil
 	}
 
 	outlineRoot, ok := outlineRootObj.(*core.PdfIndirectObject)
 	if !ok {
 		if _, ok := core.GetDict(outlineRootObj); !ok {
-			common.Log.Debug("Invalid outline root - skipping")
+			r.log().Debug("Invalid outline root - skipping")
 			return nil, nil
 		}
 
-		common.Log.Debug("Outline root is a dict. Should be an indirect object")
+		r.log().Debug("Outline root is a dict. Should be an indirect object")
 		outlineRoot = core.MakeIndirectObject(outlineRootObj)
 	}
 
@@ -296,13 +372,13 @@ func (r *PdfReader) loadOutlines() (*PdfOutlineTreeNode, error) {
 		return nil, errors.New("outline indirect object should contain a dictionary")
 	}
 
-	common.Log.Trace("Outline root dict: %v", dict)
+	r.log().Trace("Outline root dict: %v", dict)
 
 	outlineTree, _, err := r.buildOutlineTree(outlineRoot, nil, nil, nil)
 	if err != nil {
 		return nil, err
 	}
-	common.Log.Trace("Resulting outline tree: %v", outlineTree)
+	r.log().Trace("Resulting outline tree: %v", outlineTree)
 
 	return outlineTree, nil
 }
@@ -327,7 +403,7 @@ func (r *PdfReader) buildOutlineTree(obj core.PdfObject, parent *PdfOutlineTreeN
 	if !ok {
 		return nil, nil, errors.New("not a dictionary object")
 	}
-	common.Log.Trace("build outline tree: dict: %v (%v) p: %p", dict, container, container)
+	r.log().Trace("build outline tree: dict: %v (%v) p: %p", dict, container, container)
 
 	if obj := dict.Get("Title"); obj != nil {
 		// Outline item has a title. (required)
@@ -344,7 +420,7 @@ func (r *PdfReader) buildOutlineTree(obj core.PdfObject, parent *PdfOutlineTreeN
 			if !core.IsNullObject(firstObj) {
 				first, last, err := r.buildOutlineTree(firstObj, &outlineItem.PdfOutlineTreeNode, nil, visited)
 				if err != nil {
-					common.Log.Debug("DEBUG: could not build outline item tree: %v. Skipping node children.", err)
+					r.log().Debug("DEBUG: could not build outline item tree: %v. Skipping node children.", err)
 				} else {
 					outlineItem.First = first
 					outlineItem.Last = last
@@ -358,7 +434,7 @@ func (r *PdfReader) buildOutlineTree(obj core.PdfObject, parent *PdfOutlineTreeN
 			if !core.IsNullObject(nextObj) {
 				next, last, err := r.buildOutlineTree(nextObj, parent, &outlineItem.PdfOutlineTreeNode, visited)
 				if err != nil {
-					common.Log.Debug("DEBUG: could not build outline tree for Next node: %v. Skipping node.", err)
+					r.log().Debug("DEBUG: could not build outline tree for Next node: %v. Skipping node.", err)
 				} else {
 					outlineItem.Next = next
 					return &outlineItem.PdfOutlineTreeNode, last, nil
@@ -383,7 +459,7 @@ func (r *PdfReader) buildOutlineTree(obj core.PdfObject, parent *PdfOutlineTreeN
 		if _, isNull := firstObjDirect.(*core.PdfObjectNull); !isNull && firstObjDirect != nil {
 			first, last, err := r.buildOutlineTree(firstObj, &outline.PdfOutlineTreeNode, nil, visited)
 			if err != nil {
-				common.Log.Debug("DEBUG: could not build outline tree: %v. Skipping node children.", err)
+				r.log().Debug("DEBUG: could not build outline tree: %v. Skipping node children.", err)
 			} else {
 				outline.First = first
 				outline.Last = last
@@ -414,7 +490,7 @@ func (r *PdfReader) GetOutlinesFlattened() ([]*PdfOutlineTreeNode, []string, err
 			return
 		}
 		if node.context == nil {
-			common.Log.Debug("ERROR: Missing node.context") // Should not happen ever.
+			r.log().Debug("ERROR: Missing node.context") // Should not happen ever.
 			return
 		}
 
@@ -457,7 +533,7 @@ func (r *PdfReader) GetOutlines() (*Outline, error) {
 			return
 		}
 		if node.context == nil {
-			common.Log.Debug("ERROR: missing outline entry context")
+			r.log().Debug("ERROR: missing outline entry context")
 			return
 		}
 
@@ -478,7 +554,7 @@ func (r *PdfReader) GetOutlines() (*Outline, error) {
 				if d, err := newOutlineDestFromPdfObject(destObj, r); err == nil {
 					dest = *d
 				} else {
-					common.Log.Debug("WARN: could not parse outline dest (%v): %v", destObj, err)
+					r.log().Debug("WARN: could not parse outline dest (%v): %v", destObj, err)
 				}
 			}
 
@@ -539,14 +615,14 @@ func (r *PdfReader) RepairAcroForm(opts *AcroFormRepairOptions) error {
 					if err == nil {
 						break
 					}
-					common.Log.Debug("WARN: could not parse form field %+v: %v", parentObj, err)
+					r.log().Debug("WARN: could not parse form field %+v: %v", parentObj, err)
 				}
 				if t.container != nil {
 					field, err = r.newPdfFieldFromIndirectObject(t.container, nil)
 					if err == nil {
 						break
 					}
-					common.Log.Debug("WARN: could not parse form field %+v: %v", t.container, err)
+					r.log().Debug("WARN: could not parse form field %+v: %v", t.container, err)
 				}
 			}
 			if field == nil {
@@ -627,25 +703,25 @@ func (r *PdfReader) loadForms() (*PdfAcroForm, error) {
 
 	obj = core.TraceToDirectObject(obj)
 	if core.IsNullObject(obj) {
-		common.Log.Trace("Acroform is a null object (empty)\n")
+		r.log().Trace("Acroform is a null object (empty)\n")
 		return nil, nil
 	}
 
 	formsDict, ok := core.GetDict(obj)
 	if !ok {
-		common.Log.Debug("Invalid AcroForm entry %T", obj)
-		common.Log.Debug("Does not have forms")
+		r.log().Debug("Invalid AcroForm entry %T", obj)
+		r.log().Debug("Does not have forms")
 		return nil, fmt.Errorf("invalid acroform entry %T", obj)
 	}
-	common.Log.Trace("Has Acro forms")
+	r.log().Trace("Has Acro forms")
 	// Load it.
 
 	// Ensure we have access to everything.
-	common.Log.Trace("Traverse the Acroforms structure")
+	r.log().Trace("Traverse the Acroforms structure")
 	if !r.isLazy {
 		err := r.traverseObjectData(formsDict)
 		if err != nil {
-			common.Log.Debug("ERROR: Unable to traverse AcroForms (%s)", err)
+			r.log().Debug("ERROR: Unable to traverse AcroForms (%s)", err)
 			return nil, err
 		}
 	}
@@ -674,7 +750,7 @@ func (r *PdfReader) buildPageList(node *core.PdfIndirectObject, parent *core.Pdf
 	}
 
 	if _, alreadyTraversed := traversedPageNodes[node]; alreadyTraversed {
-		common.Log.Debug("Cyclic recursion, skipping (%v)", node.ObjectNumber)
+		r.report(common.DiagnosticPageInvalid, node.ObjectNumber, "cyclic page tree, skipping node")
 		return nil
 	}
 	traversedPageNodes[node] = struct{}{}
@@ -690,11 +766,12 @@ func (r *PdfReader) buildPageList(node *core.PdfIndirectObject, parent *core.Pdf
 			return errors.New("node missing Type (Required)")
 		}
 
-		common.Log.Debug("ERROR: node missing Type, but has Kids. Assuming Pages node.")
+		r.report(common.DiagnosticPageInvalid, node.ObjectNumber,
+			"node missing Type, but has Kids. Assuming Pages node")
 		objType = core.MakeName("Pages")
 		nodeDict.Set("Type", objType)
 	}
-	common.Log.Trace("buildPageList node type: %s (%+v)", *objType, node)
+	r.log().Trace("buildPageList node type: %s (%+v)", *objType, node)
 	if *objType == "Page" {
 		p, err := r.newPdfPageFromDict(nodeDict)
 		if err != nil {
@@ -712,7 +789,7 @@ func (r *PdfReader) buildPageList(node *core.PdfIndirectObject, parent *core.Pdf
 		return nil
 	}
 	if *objType != "Pages" {
-		common.Log.Debug("ERROR: Table of content containing non Page/Pages object! (%s)", objType)
+		r.log().Debug("ERROR: Table of content containing non Page/Pages object! (%s)", objType)
 		return errors.New("table of content containing non Page/Pages object")
 	}
 
@@ -731,7 +808,7 @@ func (r *PdfReader) buildPageList(node *core.PdfIndirectObject, parent *core.Pdf
 
 	kidsObj, err := r.parser.Resolve(nodeDict.Get("Kids"))
 	if err != nil {
-		common.Log.Debug("ERROR: Failed loading Kids object")
+		r.log().Debug("ERROR: Failed loading Kids object")
 		return err
 	}
 
@@ -747,11 +824,11 @@ func (r *PdfReader) buildPageList(node *core.PdfIndirectObject, parent *core.Pdf
 			return errors.New("invalid Kids indirect object")
 		}
 	}
-	common.Log.Trace("Kids: %s", kids)
+	r.log().Trace("Kids: %s", kids)
 	for idx, child := range kids.Elements() {
 		child, ok := core.GetIndirect(child)
 		if !ok {
-			common.Log.Debug("ERROR: Page not indirect object - (%s)", child)
+			r.log().Debug("ERROR: Page not indirect object - (%s)", child)
 			return errors.New("page not indirect object")
 		}
 		kids.Set(idx, child)
@@ -772,22 +849,6 @@ func (r *PdfReader) GetNumPages() (int, error) {
 	return len(r.pageList), nil
 }
 
-// Resolves a reference, returning the object and indicates whether or not
-// it was cached.
-func (r *PdfReader) resolveReference(ref *core.PdfObjectReference) (core.PdfObject, bool, error) {
-	cachedObj, isCached := r.parser.ObjCache[int(ref.ObjectNumber)]
-	if !isCached {
-		common.Log.Trace("Reader Lookup ref: %s", ref)
-		obj, err := r.parser.LookupByReference(*ref)
-		if err != nil {
-			return nil, false, err
-		}
-		r.parser.ObjCache[int(ref.ObjectNumber)] = obj
-		return obj, false, nil
-	}
-	return cachedObj, true, nil
-}
-
 /*
  * Recursively traverse through the page object data and look up
  * references to indirect objects.
@@ -828,7 +889,80 @@ func (r *PdfReader) GetPage(pageNumber int) (*PdfPage, error) {
 	return page, nil
 }
 
-// GetOCProperties returns the optional content properties PdfObject.
+// SetObjectCachePolicy limits the memory used by the cache of objects loaded by the underlying parser.
+// Least recently used objects are evicted from the cache and loaded again from the file when needed.
+// As a reader created with NewPdfReader loads the entire document structure into memory, the policy is
+// mostly useful with lazy-loading readers (NewPdfReaderLazy). See core.ObjectCachePolicy.
+func (r *PdfReader) SetObjectCachePolicy(policy core.ObjectCachePolicy) {
+	r.parser.SetObjectCachePolicy(policy)
+}
+
+// ProcessPages calls `fn` for each page of the document, using a pool of `workers` goroutines.
+// If `workers` is not positive, the number of CPUs is used. The pages are dispatched in order, but
+// `fn` may be called concurrently and complete in any order, so it must be safe for concurrent use.
+// Processing stops at the first error returned by `fn`, which is then returned.
+//
+// Object lookups of the underlying parser are synchronized, which makes it possible to process the
+// pages of lazily loaded documents in parallel as well.
+func (r *PdfReader) ProcessPages(workers int, fn func(pageNum int, page *PdfPage) error) error {
+	numPages, err := r.GetNumPages()
+	if err != nil {
+		return err
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > numPages {
+		workers = numPages
+	}
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	pageNums := make(chan int)
+	done := make(chan struct{})
+	setErr := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			close(done)
+		})
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pageNum := range pageNums {
+				page, err := r.GetPage(pageNum)
+				if err == nil {
+					err = fn(pageNum, page)
+				}
+				if err != nil {
+					r.log().Debug("ERROR: processing page %d: %v", pageNum, err)
+					setErr(err)
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for pageNum := 1; pageNum <= numPages; pageNum++ {
+		select {
+		case pageNums <- pageNum:
+		case <-done:
+			break dispatch
+		}
+	}
+	close(pageNums)
+	wg.Wait()
+
+	return firstErr
+}
+
+// GetOCProperties returns the optional content properties PdfObject. Use GetPdfOCProperties for
+// the typed optional content properties.
 func (r *PdfReader) GetOCProperties() (core.PdfObject, error) {
 	dict := r.catalog
 	obj := dict.Get("OCProperties")
@@ -848,7 +982,8 @@ func (r *PdfReader) GetOCProperties() (core.PdfObject, error) {
 	return obj, nil
 }
 
-// GetNamedDestinations returns the Names entry in the PDF catalog.
+// GetNamedDestinations returns the Names entry in the PDF catalog. Use
+// GetNamedDestinationsTree for the named destinations name tree.
 // See section 12.3.2.3 "Named Destinations" (p. 367 PDF32000_2008).
 func (r *PdfReader) GetNamedDestinations() (core.PdfObject, error) {
 	obj := core.ResolveReference(r.catalog.Get("Names"))
@@ -867,7 +1002,8 @@ func (r *PdfReader) GetNamedDestinations() (core.PdfObject, error) {
 	return obj, nil
 }
 
-// GetPageLabels returns the PageLabels entry in the PDF catalog.
+// GetPageLabels returns the PageLabels entry in the PDF catalog. Use
+// GetPdfPageLabels for the page labels model.
 // See section 12.4.2 "Page Labels" (p. 382 PDF32000_2008).
 func (r *PdfReader) GetPageLabels() (core.PdfObject, error) {
 	obj := core.ResolveReference(r.catalog.Get("PageLabels"))
@@ -886,6 +1022,42 @@ func (r *PdfReader) GetPageLabels() (core.PdfObject, error) {
 	return obj, nil
 }
 
+// GetOutputIntents returns the OutputIntents entry in the PDF catalog.
+// See section 14.11.5 "Output Intents" (PDF32000_2008).
+func (r *PdfReader) GetOutputIntents() (core.PdfObject, error) {
+	return r.getCatalogEntry("OutputIntents")
+}
+
+// GetAssociatedFiles returns the AF entry in the PDF catalog, the files associated with the
+// document (PDF 2.0).
+func (r *PdfReader) GetAssociatedFiles() (core.PdfObject, error) {
+	return r.getCatalogEntry("AF")
+}
+
+// GetDPartRoot returns the DPartRoot entry in the PDF catalog, the root of the document part
+// hierarchy (PDF 2.0).
+func (r *PdfReader) GetDPartRoot() (core.PdfObject, error) {
+	return r.getCatalogEntry("DPartRoot")
+}
+
+// getCatalogEntry returns the entry `key` of the PDF catalog with its references resolved.
+func (r *PdfReader) getCatalogEntry(key core.PdfObjectName) (core.PdfObject, error) {
+	obj := core.ResolveReference(r.catalog.Get(key))
+	if obj == nil {
+		return nil, nil
+	}
+
+	// Resolve references.
+	if !r.isLazy {
+		err := r.traverseObjectData(obj)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return obj, nil
+}
+
 // Inspect inspects the object types, subtypes and content in the PDF file returning a map of
 // object type to number of instances of each.
 func (r *PdfReader) Inspect() (map[string]int, error) {
@@ -916,3 +1088,65 @@ func (r *PdfReader) GetTrailer() (*core.PdfObjectDictionary, error) {
 
 	return trailerDict, nil
 }
+
+// GetRevisions returns the revisions of the PDF document, ordered from the original document
+// (index 0) to the most recent incremental update. Each revision lists the objects that it added,
+// changed or freed, along with its trailer and startxref offset.
+func (r *PdfReader) GetRevisions() []*core.PdfRevision {
+	return r.parser.GetRevisions()
+}
+
+// GetRevision returns a PdfReader for the document as it was at revision `index`, where 0 is the
+// original document. See GetRevisions for the revisions available.
+// The returned reader uses the same loading mode (lazy or not) as `r`. If the document is encrypted,
+// the returned reader needs to be decrypted separately.
+func (r *PdfReader) GetRevision(index int) (*PdfReader, error) {
+	revisions := r.parser.GetRevisions()
+	if index < 0 || index >= len(revisions) {
+		return nil, fmt.Errorf("invalid revision index %d (revisions: %d)", index, len(revisions))
+	}
+
+	rs, err := r.parser.GetRevisionReader(index)
+	if err != nil {
+		return nil, err
+	}
+	if r.isLazy {
+		return NewPdfReaderLazy(rs)
+	}
+	return NewPdfReader(rs)
+}
+
+// GetRepairWarnings returns the repairs applied by the parser when loading a damaged document,
+// such as a reconstructed cross-reference table or corrected stream lengths.
+func (r *PdfReader) GetRepairWarnings() []core.RepairWarning {
+	return r.parser.GetRepairWarnings()
+}
+
+// IsLinearized returns true if the document is linearized (Annex F Linearized PDF), i.e. starts with
+// a linearization parameter dictionary whose file length matches the actual file length. A
+// linearized document that was modified by an incremental update is not considered linearized.
+func (r *PdfReader) IsLinearized() bool {
+	return r.parser.IsLinearized()
+}
+
+// GetLinearizationParams returns the linearization parameters of the document, or nil if the
+// document does not start with a linearization parameter dictionary.
+func (r *PdfReader) GetLinearizationParams() *core.LinearizationParams {
+	return r.parser.GetLinearizationParams()
+}
+
+// GetHintTables returns the decoded hint tables of the primary hint stream of a linearized document.
+func (r *PdfReader) GetHintTables() (*core.HintTables, error) {
+	return r.parser.GetHintTables()
+}
+
+// ValidateLinearization checks the linearization parameters and hint tables of the document against
+// the actual file structure and page tree. An error describing the first inconsistency found is
+// returned, or nil if the document is properly linearized.
+func (r *PdfReader) ValidateLinearization() error {
+	pageObjNums := make([]int, len(r.pageList))
+	for i, page := range r.pageList {
+		pageObjNums[i] = int(page.ObjectNumber)
+	}
+	return r.parser.ValidateLinearization(pageObjNums)
+}
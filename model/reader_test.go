@@ -7,6 +7,8 @@ package model
 
 import (
 	"bytes"
+	"errors"
+	"io/ioutil"
 	"os"
 	"testing"
 
@@ -47,3 +49,172 @@ func TestReaderLazy(t *testing.T) {
 	err = writer.Write(&buf)
 	require.NoError(t, err)
 }
+
+func TestReaderProcessPages(t *testing.T) {
+	f, err := os.Open(`./testdata/pages3.pdf`)
+	require.NoError(t, err)
+	defer f.Close()
+
+	reader, err := NewPdfReaderLazy(f)
+	require.NoError(t, err)
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+
+	var expected []string
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		require.NoError(t, err)
+		str, err := page.GetAllContentStreams()
+		require.NoError(t, err)
+		expected = append(expected, str)
+	}
+
+	// Fresh reader, so that objects are loaded concurrently.
+	f2, err := os.Open(`./testdata/pages3.pdf`)
+	require.NoError(t, err)
+	defer f2.Close()
+	reader, err = NewPdfReaderLazy(f2)
+	require.NoError(t, err)
+
+	contents := make([]string, numPages)
+	err = reader.ProcessPages(0, func(pageNum int, page *PdfPage) error {
+		str, err := page.GetAllContentStreams()
+		contents[pageNum-1] = str
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, expected, contents)
+
+	// The first error stops processing.
+	errStop := errors.New("stop")
+	err = reader.ProcessPages(1, func(pageNum int, page *PdfPage) error {
+		if pageNum == 2 {
+			return errStop
+		}
+		return nil
+	})
+	require.Equal(t, errStop, err)
+}
+
+func TestReaderObjectCachePolicy(t *testing.T) {
+	f, err := os.Open(`./testdata/pages3.pdf`)
+	require.NoError(t, err)
+	defer f.Close()
+
+	reader, err := NewPdfReaderLazy(f)
+	require.NoError(t, err)
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+
+	var expected []string
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		require.NoError(t, err)
+		str, err := page.GetAllContentStreams()
+		require.NoError(t, err)
+		expected = append(expected, str)
+	}
+
+	// Content streams are loaded again after being evicted.
+	reader.SetObjectCachePolicy(core.ObjectCachePolicy{MaxObjects: 2})
+	for i := 0; i < 2; i++ {
+		for j := 1; j <= numPages; j++ {
+			page, err := reader.GetPage(j)
+			require.NoError(t, err)
+			str, err := page.GetAllContentStreams()
+			require.NoError(t, err)
+			require.Equal(t, expected[j-1], str)
+		}
+	}
+}
+
+func TestReaderLinearized(t *testing.T) {
+	testcases := []struct {
+		path                string
+		numPages            int
+		firstPageOffset     int64
+		firstPage           core.PageOffsetHint
+		numFirstPageEntries int
+		numGroups           int
+	}{
+		// Acrobat Distiller 7, cross-reference tables.
+		{
+			path:            "./testdata/lorem.pdf",
+			numPages:        1,
+			firstPageOffset: 972,
+			firstPage: core.PageOffsetHint{
+				NumObjects: 27, Length: 71732, SharedObjects: []int{0, 0, 0, 0},
+			},
+			numFirstPageEntries: 27,
+			numGroups:           27,
+		},
+		// Adobe PDF Library 9, cross-reference streams.
+		{
+			path:            "./testdata/SampleSignedPDFDocument.pdf",
+			numPages:        1,
+			firstPageOffset: 675,
+			firstPage: core.PageOffsetHint{
+				NumObjects: 19, Length: 265196, SharedObjects: []int{0, 0, 0, 0, 0, 0, 0, 0, 0},
+			},
+			numFirstPageEntries: 19,
+			numGroups:           19,
+		},
+		// Cross-reference and object streams, shared objects section.
+		{
+			path:            "./testdata/advancedform.pdf",
+			numPages:        12,
+			firstPageOffset: 888,
+			firstPage: core.PageOffsetHint{
+				NumObjects: 29, Length: 108499, SharedObjects: make([]int, 12), ContentLength: 108044,
+			},
+			numFirstPageEntries: 29,
+			numGroups:           57,
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.path, func(t *testing.T) {
+			data, err := ioutil.ReadFile(tcase.path)
+			require.NoError(t, err)
+			reader, err := NewPdfReader(bytes.NewReader(data))
+			require.NoError(t, err)
+			require.True(t, reader.IsLinearized())
+			params := reader.GetLinearizationParams()
+			require.Equal(t, int64(len(data)), params.FileLength)
+			require.Equal(t, tcase.numPages, params.NumPages)
+
+			tables, err := reader.GetHintTables()
+			require.NoError(t, err)
+			require.Len(t, tables.PageOffsets.Pages, tcase.numPages)
+			require.Equal(t, tcase.firstPageOffset, tables.PageOffsets.FirstPageOffset)
+			require.Equal(t, tcase.firstPage, tables.PageOffsets.Pages[0])
+			require.Equal(t, tcase.numFirstPageEntries, tables.SharedObjects.NumFirstPageEntries)
+			require.Len(t, tables.SharedObjects.Groups, tcase.numGroups)
+			require.NoError(t, reader.ValidateLinearization())
+
+			// Updated document.
+			reader, err = NewPdfReader(bytes.NewReader(append(data, '\n')))
+			require.NoError(t, err)
+			require.False(t, reader.IsLinearized())
+			require.Error(t, reader.ValidateLinearization())
+		})
+	}
+
+	// Pages and shared objects section of a multi-page document.
+	f, err := os.Open("./testdata/advancedform.pdf")
+	require.NoError(t, err)
+	defer f.Close()
+	reader, err := NewPdfReader(f)
+	require.NoError(t, err)
+	tables, err := reader.GetHintTables()
+	require.NoError(t, err)
+	require.Equal(t, core.PageOffsetHint{
+		NumObjects:    5,
+		Length:        8326,
+		SharedObjects: []int{5, 4, 6, 7, 55, 17, 18, 19, 20, 21, 22, 23},
+		ContentLength: 7871,
+	}, tables.PageOffsets.Pages[1])
+	require.Equal(t, 40, tables.SharedObjects.FirstObjectNumber)
+	require.Equal(t, int64(167798), tables.SharedObjects.FirstObjectOffset)
+	require.Equal(t, core.SharedObjectHint{Length: 2371, NumObjects: 1}, tables.SharedObjects.Groups[56])
+}
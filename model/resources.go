@@ -29,6 +29,10 @@ type PdfPageResources struct {
 
 	// Loaded objects.
 	colorspace *PdfPageResourcesColorspaces
+
+	// logger receives the log messages about the resources, such as the logger of the reader of the
+	// page. common.Log is used if it is nil.
+	logger common.Logger
 }
 
 // NewPdfPageResources returns a new PdfPageResources object.
@@ -38,6 +42,14 @@ func NewPdfPageResources() *PdfPageResources {
 	return r
 }
 
+// log returns the logger of the resources.
+func (r *PdfPageResources) log() common.Logger {
+	if r.logger == nil {
+		return common.Log
+	}
+	return r.logger
+}
+
 // NewPdfPageResourcesFromDict creates and returns a new PdfPageResources object
 // from the input dictionary.
 func NewPdfPageResourcesFromDict(dict *core.PdfObjectDictionary) (*PdfPageResources, error) {
@@ -127,7 +139,7 @@ func (r *PdfPageResources) AddExtGState(gsName core.PdfObjectName, gsDict core.P
 	obj := r.ExtGState
 	dict, ok := core.TraceToDirectObject(obj).(*core.PdfObjectDictionary)
 	if !ok {
-		common.Log.Debug("ExtGState type error (got %T/%T)", obj, core.TraceToDirectObject(obj))
+		r.log().Debug("ExtGState type error (got %T/%T)", obj, core.TraceToDirectObject(obj))
 		return core.ErrTypeError
 	}
 
@@ -144,7 +156,7 @@ func (r *PdfPageResources) GetExtGState(keyName core.PdfObjectName) (core.PdfObj
 
 	dict, ok := core.TraceToDirectObject(r.ExtGState).(*core.PdfObjectDictionary)
 	if !ok {
-		common.Log.Debug("ERROR: Invalid ExtGState entry - not a dict (got %T)", r.ExtGState)
+		r.log().Debug("ERROR: Invalid ExtGState entry - not a dict (got %T)", r.ExtGState)
 		return nil, false
 	}
 	if obj := dict.Get(keyName); obj != nil {
@@ -160,6 +172,41 @@ func (r *PdfPageResources) HasExtGState(keyName core.PdfObjectName) bool {
 	return has
 }
 
+// GetPropertyByName gets the property list specified by keyName, referred to by marked-content
+// operators such as BDC. Returns a bool indicating whether it was found or not.
+func (r *PdfPageResources) GetPropertyByName(keyName core.PdfObjectName) (core.PdfObject, bool) {
+	if r.Properties == nil {
+		return nil, false
+	}
+
+	dict, ok := core.TraceToDirectObject(r.Properties).(*core.PdfObjectDictionary)
+	if !ok {
+		r.log().Debug("ERROR: Invalid Properties entry - not a dict (got %T)", r.Properties)
+		return nil, false
+	}
+	if obj := dict.Get(keyName); obj != nil {
+		return obj, true
+	}
+
+	return nil, false
+}
+
+// SetPropertyByName sets the property list specified by keyName.
+func (r *PdfPageResources) SetPropertyByName(keyName core.PdfObjectName, props core.PdfObject) error {
+	if r.Properties == nil {
+		r.Properties = core.MakeDict()
+	}
+
+	dict, ok := core.TraceToDirectObject(r.Properties).(*core.PdfObjectDictionary)
+	if !ok {
+		r.log().Debug("Invalid Properties, got %T", r.Properties)
+		return core.ErrTypeError
+	}
+
+	dict.Set(keyName, props)
+	return nil
+}
+
 // GetShadingByName gets the shading specified by keyName. Returns nil if not existing.
 // The bool flag indicated whether it was found or not.
 func (r *PdfPageResources) GetShadingByName(keyName core.PdfObjectName) (*PdfShading, bool) {
@@ -169,13 +216,13 @@ func (r *PdfPageResources) GetShadingByName(keyName core.PdfObjectName) (*PdfSha
 
 	shadingDict, ok := core.TraceToDirectObject(r.Shading).(*core.PdfObjectDictionary)
 	if !ok {
-		common.Log.Debug("ERROR: Invalid Shading entry - not a dict (got %T)", r.Shading)
+		r.log().Debug("ERROR: Invalid Shading entry - not a dict (got %T)", r.Shading)
 		return nil, false
 	}
 	if obj := shadingDict.Get(keyName); obj != nil {
 		shading, err := newPdfShadingFromPdfObject(obj)
 		if err != nil {
-			common.Log.Debug("ERROR: failed to load pdf shading: %v", err)
+			r.log().Debug("ERROR: failed to load pdf shading: %v", err)
 			return nil, false
 		}
 		return shading, true
@@ -208,13 +255,13 @@ func (r *PdfPageResources) GetPatternByName(keyName core.PdfObjectName) (*PdfPat
 
 	patternDict, ok := core.TraceToDirectObject(r.Pattern).(*core.PdfObjectDictionary)
 	if !ok {
-		common.Log.Debug("ERROR: Invalid Pattern entry - not a dict (got %T)", r.Pattern)
+		r.log().Debug("ERROR: Invalid Pattern entry - not a dict (got %T)", r.Pattern)
 		return nil, false
 	}
 	if obj := patternDict.Get(keyName); obj != nil {
 		pattern, err := newPdfPatternFromPdfObject(obj)
 		if err != nil {
-			common.Log.Debug("ERROR: failed to load pdf pattern: %v", err)
+			r.log().Debug("ERROR: failed to load pdf pattern: %v", err)
 			return nil, false
 		}
 
@@ -248,7 +295,7 @@ func (r *PdfPageResources) GetFontByName(keyName core.PdfObjectName) (core.PdfOb
 
 	fontDict, has := core.TraceToDirectObject(r.Font).(*core.PdfObjectDictionary)
 	if !has {
-		common.Log.Debug("ERROR: Font not a dictionary! (got %T)", core.TraceToDirectObject(r.Font))
+		r.log().Debug("ERROR: Font not a dictionary! (got %T)", core.TraceToDirectObject(r.Font))
 		return nil, false
 	}
 	if obj := fontDict.Get(keyName); obj != nil {
@@ -273,7 +320,7 @@ func (r *PdfPageResources) SetFontByName(keyName core.PdfObjectName, obj core.Pd
 
 	fontDict, has := core.TraceToDirectObject(r.Font).(*core.PdfObjectDictionary)
 	if !has {
-		common.Log.Debug("ERROR: Font not a dictionary! (got %T)", core.TraceToDirectObject(r.Font))
+		r.log().Debug("ERROR: Font not a dictionary! (got %T)", core.TraceToDirectObject(r.Font))
 		return core.ErrTypeError
 	}
 
@@ -285,7 +332,7 @@ func (r *PdfPageResources) SetFontByName(keyName core.PdfObjectName, obj core.Pd
 func (r *PdfPageResources) GetColorspaceByName(keyName core.PdfObjectName) (PdfColorspace, bool) {
 	colorspace, err := r.GetColorspaces()
 	if err != nil {
-		common.Log.Debug("ERROR getting colorsprace: %v", err)
+		r.log().Debug("ERROR getting colorsprace: %v", err)
 		return nil, false
 	}
 
@@ -305,7 +352,7 @@ func (r *PdfPageResources) GetColorspaceByName(keyName core.PdfObjectName) (PdfC
 func (r *PdfPageResources) HasColorspaceByName(keyName core.PdfObjectName) bool {
 	colorspace, err := r.GetColorspaces()
 	if err != nil {
-		common.Log.Debug("ERROR getting colorsprace: %v", err)
+		r.log().Debug("ERROR getting colorsprace: %v", err)
 		return false
 	}
 	if colorspace == nil {
@@ -320,7 +367,7 @@ func (r *PdfPageResources) HasColorspaceByName(keyName core.PdfObjectName) bool
 func (r *PdfPageResources) SetColorspaceByName(keyName core.PdfObjectName, cs PdfColorspace) error {
 	colorspace, err := r.GetColorspaces()
 	if err != nil {
-		common.Log.Debug("ERROR getting colorsprace: %v", err)
+		r.log().Debug("ERROR getting colorsprace: %v", err)
 		return err
 	}
 	if colorspace == nil {
@@ -372,21 +419,21 @@ func (r *PdfPageResources) GetXObjectByName(keyName core.PdfObjectName) (*core.P
 
 	xresDict, has := core.TraceToDirectObject(r.XObject).(*core.PdfObjectDictionary)
 	if !has {
-		common.Log.Debug("ERROR: XObject not a dictionary! (got %T)", core.TraceToDirectObject(r.XObject))
+		r.log().Debug("ERROR: XObject not a dictionary! (got %T)", core.TraceToDirectObject(r.XObject))
 		return nil, XObjectTypeUndefined
 	}
 
 	if obj := xresDict.Get(keyName); obj != nil {
 		stream, ok := core.GetStream(obj)
 		if !ok {
-			common.Log.Debug("XObject not pointing to a stream %T", obj)
+			r.log().Debug("XObject not pointing to a stream %T", obj)
 			return nil, XObjectTypeUndefined
 		}
 		dict := stream.PdfObjectDictionary
 
 		name, ok := core.TraceToDirectObject(dict.Get("Subtype")).(*core.PdfObjectName)
 		if !ok {
-			common.Log.Debug("XObject Subtype not a Name, dict: %s", dict.String())
+			r.log().Debug("XObject Subtype not a Name, dict: %s", dict.String())
 			return nil, XObjectTypeUndefined
 		}
 
@@ -397,7 +444,7 @@ func (r *PdfPageResources) GetXObjectByName(keyName core.PdfObjectName) (*core.P
 		} else if *name == "PS" {
 			return stream, XObjectTypePS
 		} else {
-			common.Log.Debug("XObject Subtype not known (%s)", *name)
+			r.log().Debug("XObject Subtype not known (%s)", *name)
 			return nil, XObjectTypeUndefined
 		}
 	} else {
@@ -415,7 +462,7 @@ func (r *PdfPageResources) SetXObjectByName(keyName core.PdfObjectName, stream *
 	obj := core.TraceToDirectObject(r.XObject)
 	xresDict, has := obj.(*core.PdfObjectDictionary)
 	if !has {
-		common.Log.Debug("Invalid XObject, got %T/%T", r.XObject, obj)
+		r.log().Debug("Invalid XObject, got %T/%T", r.XObject, obj)
 		return errors.New("type check error")
 	}
 
@@ -465,6 +512,9 @@ func (r *PdfPageResources) GetXObjectFormByName(keyName core.PdfObjectName) (*XO
 	if err != nil {
 		return nil, err
 	}
+	if xform.Resources != nil {
+		xform.Resources.logger = r.logger
+	}
 
 	return xform, nil
 }
@@ -0,0 +1,498 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package sighandler
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/unidoc/pkcs7"
+)
+
+// Object identifiers of the CMS content types and attributes (RFC 5652, RFC 5035, RFC 3161).
+var (
+	oidSignedData                  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidData                        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidTSTInfo                     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidAttributeContentType        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttributeMessageDigest      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttributeSigningTime        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidAttributeSigningCertV2      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 47}
+	oidAttributeSignatureTimestamp = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 14}
+	oidAttributeRevocationInfo     = asn1.ObjectIdentifier{1, 2, 840, 113583, 1, 1, 8}
+
+	oidEncryptionAlgorithmRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidSignatureECDSASHA256   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidSignatureECDSASHA384   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidSignatureECDSASHA512   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+)
+
+// cmsContentInfo is the CMS ContentInfo structure.
+type cmsContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+// cmsEncapContentInfo is the CMS EncapsulatedContentInfo structure.
+type cmsEncapContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+// cmsSignedData is the CMS SignedData structure. The certificates, CRLs and attributes are kept as
+// raw values to preserve their encoding.
+type cmsSignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo cmsEncapContentInfo
+	Certificates     asn1.RawValue   `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue   `asn1:"optional,tag:1"`
+	SignerInfos      []cmsSignerInfo `asn1:"set"`
+}
+
+// cmsSignerInfo is the CMS SignerInfo structure, with a signer identified by issuer and serial
+// number.
+type cmsSignerInfo struct {
+	Version            int
+	SID                cmsIssuerAndSerial
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+// cmsIssuerAndSerial is the CMS IssuerAndSerialNumber structure.
+type cmsIssuerAndSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+// cmsAttribute is a CMS attribute with its values.
+type cmsAttribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+// essCertIDv2 is the ESSCertIDv2 structure of the signing-certificate-v2 attribute (RFC 5035). The
+// hash algorithm is omitted when it is SHA-256, its default value.
+type essCertIDv2 struct {
+	HashAlgorithm pkix.AlgorithmIdentifier `asn1:"optional"`
+	CertHash      []byte
+	IssuerSerial  essIssuerSerial `asn1:"optional"`
+}
+
+// essIssuerSerial is the IssuerSerial structure of ESSCertIDv2.
+type essIssuerSerial struct {
+	Issuer       []asn1.RawValue
+	SerialNumber *big.Int
+}
+
+// signingCertificateV2 is the value of the signing-certificate-v2 attribute.
+type signingCertificateV2 struct {
+	Certs []essCertIDv2
+}
+
+// cmsSignature is a parsed CMS signature with a single signer.
+type cmsSignature struct {
+	signedData   cmsSignedData
+	signerInfo   cmsSignerInfo
+	certificates []*x509.Certificate
+	signedAttrs  []cmsAttribute
+	unsignedAttr []cmsAttribute
+}
+
+// hashOID returns the object identifier of the digest algorithm `hash`.
+func hashOID(hash crypto.Hash) (asn1.ObjectIdentifier, error) {
+	switch hash {
+	case crypto.SHA1:
+		return pkcs7.OIDDigestAlgorithmSHA1, nil
+	case crypto.SHA256:
+		return pkcs7.OIDDigestAlgorithmSHA256, nil
+	case crypto.SHA384:
+		return pkcs7.OIDDigestAlgorithmSHA384, nil
+	case crypto.SHA512:
+		return pkcs7.OIDDigestAlgorithmSHA512, nil
+	}
+	return nil, fmt.Errorf("unsupported digest algorithm %v", hash)
+}
+
+// signatureAlgorithm returns the CMS signature algorithm identifier for `key` with digest `hash`.
+func signatureAlgorithm(key crypto.PublicKey, hash crypto.Hash) (pkix.AlgorithmIdentifier, error) {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return pkix.AlgorithmIdentifier{Algorithm: oidEncryptionAlgorithmRSA, Parameters: asn1.NullRawValue}, nil
+	case *ecdsa.PublicKey:
+		switch hash {
+		case crypto.SHA256:
+			return pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSASHA256}, nil
+		case crypto.SHA384:
+			return pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSASHA384}, nil
+		case crypto.SHA512:
+			return pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSASHA512}, nil
+		}
+	}
+	return pkix.AlgorithmIdentifier{}, fmt.Errorf("unsupported signature key %T with digest %v", key, hash)
+}
+
+// x509SignatureAlgorithm returns the x509 signature algorithm used to check a signature made with
+// `key` and digest `hash`.
+func x509SignatureAlgorithm(key crypto.PublicKey, hash crypto.Hash) (x509.SignatureAlgorithm, error) {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		switch hash {
+		case crypto.SHA1:
+			return x509.SHA1WithRSA, nil
+		case crypto.SHA256:
+			return x509.SHA256WithRSA, nil
+		case crypto.SHA384:
+			return x509.SHA384WithRSA, nil
+		case crypto.SHA512:
+			return x509.SHA512WithRSA, nil
+		}
+	case *ecdsa.PublicKey:
+		switch hash {
+		case crypto.SHA1:
+			return x509.ECDSAWithSHA1, nil
+		case crypto.SHA256:
+			return x509.ECDSAWithSHA256, nil
+		case crypto.SHA384:
+			return x509.ECDSAWithSHA384, nil
+		case crypto.SHA512:
+			return x509.ECDSAWithSHA512, nil
+		}
+	}
+	return x509.UnknownSignatureAlgorithm, fmt.Errorf("unsupported signature key %T with digest %v", key, hash)
+}
+
+// makeAttribute returns the CMS attribute `typ` with the DER-encoded value `value`.
+func makeAttribute(typ asn1.ObjectIdentifier, value []byte) cmsAttribute {
+	return cmsAttribute{Type: typ, Values: []asn1.RawValue{{FullBytes: value}}}
+}
+
+// marshalAttributes returns the DER encoding of the SET OF attributes `attrs`, sorted as required
+// by DER.
+func marshalAttributes(attrs []cmsAttribute) ([]byte, error) {
+	encoded := make([][]byte, len(attrs))
+	for i, attr := range attrs {
+		data, err := asn1.Marshal(attr)
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = data
+	}
+	// The elements of a DER SET OF are sorted by their encodings.
+	for i := 1; i < len(encoded); i++ {
+		for j := i; j > 0 && bytes.Compare(encoded[j], encoded[j-1]) < 0; j-- {
+			encoded[j], encoded[j-1] = encoded[j-1], encoded[j]
+		}
+	}
+	return asn1.Marshal(asn1.RawValue{
+		Class:      asn1.ClassUniversal,
+		Tag:        asn1.TagSet,
+		IsCompound: true,
+		Bytes:      bytes.Join(encoded, nil),
+	})
+}
+
+// implicitTag returns the DER encoding `der` with its tag replaced by the context-specific
+// constructed tag `tag`, as for IMPLICIT tagging.
+func implicitTag(der []byte, tag int) asn1.RawValue {
+	var raw asn1.RawValue
+	asn1.Unmarshal(der, &raw)
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: tag, IsCompound: true, Bytes: raw.Bytes}
+}
+
+// parseAttributes parses the IMPLICIT tagged SET OF attributes `raw`. It returns the attributes
+// and their DER encoding as a SET OF, which is the data signed for the signed attributes.
+func parseAttributes(raw asn1.RawValue) ([]cmsAttribute, []byte, error) {
+	if len(raw.FullBytes) == 0 {
+		return nil, nil, nil
+	}
+	der, err := asn1.Marshal(asn1.RawValue{
+		Class:      asn1.ClassUniversal,
+		Tag:        asn1.TagSet,
+		IsCompound: true,
+		Bytes:      raw.Bytes,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	var attrs []cmsAttribute
+	if _, err := asn1.UnmarshalWithParams(der, &attrs, "set"); err != nil {
+		return nil, nil, err
+	}
+	return attrs, der, nil
+}
+
+// findAttribute returns the first value of the attribute `typ` of `attrs`.
+func findAttribute(attrs []cmsAttribute, typ asn1.ObjectIdentifier) (asn1.RawValue, bool) {
+	for _, attr := range attrs {
+		if attr.Type.Equal(typ) && len(attr.Values) > 0 {
+			return attr.Values[0], true
+		}
+	}
+	return asn1.RawValue{}, false
+}
+
+// makeSigningCertificateV2 returns the DER-encoded value of the signing-certificate-v2 attribute
+// identifying `cert`.
+func makeSigningCertificateV2(cert *x509.Certificate) ([]byte, error) {
+	issuer, err := asn1.Marshal(asn1.RawValue{
+		Class:      asn1.ClassContextSpecific,
+		Tag:        4,
+		IsCompound: true,
+		Bytes:      cert.RawIssuer,
+	})
+	if err != nil {
+		return nil, err
+	}
+	certHash := sha256.Sum256(cert.Raw)
+	return asn1.Marshal(signingCertificateV2{
+		Certs: []essCertIDv2{{
+			CertHash: certHash[:],
+			IssuerSerial: essIssuerSerial{
+				Issuer:       []asn1.RawValue{{FullBytes: issuer}},
+				SerialNumber: cert.SerialNumber,
+			},
+		}},
+	})
+}
+
+// checkSigningCertificateV2 checks that the signing-certificate-v2 attribute value `value`
+// identifies `cert`.
+func checkSigningCertificateV2(value asn1.RawValue, cert *x509.Certificate) error {
+	var scv2 signingCertificateV2
+	if _, err := asn1.Unmarshal(value.FullBytes, &scv2); err != nil {
+		return err
+	}
+	if len(scv2.Certs) == 0 {
+		return errors.New("empty signing-certificate-v2 attribute")
+	}
+	hash := crypto.SHA256
+	if alg := scv2.Certs[0].HashAlgorithm.Algorithm; len(alg) > 0 {
+		var err error
+		if hash, err = getHashForOID(alg); err != nil {
+			return err
+		}
+	}
+	h := hash.New()
+	h.Write(cert.Raw)
+	if !bytes.Equal(h.Sum(nil), scv2.Certs[0].CertHash) {
+		return errors.New("signing-certificate-v2 attribute does not match the signer certificate")
+	}
+	return nil
+}
+
+// cmsSigner describes the signer of a CMS signature to create.
+type cmsSigner struct {
+	certificate *x509.Certificate
+	chain       []*x509.Certificate
+	signer      crypto.Signer
+	hash        crypto.Hash
+}
+
+// sign returns a detached CMS SignedData signature of the data with digest `digest`, with the
+// signed attributes `signedAttrs` in addition to the content type and message digest. The signature
+// value is passed to `unsigned`, if not nil, to compute the unsigned attributes.
+func (s *cmsSigner) sign(digest []byte, signedAttrs []cmsAttribute,
+	unsigned func(signature []byte) ([]cmsAttribute, error)) ([]byte, error) {
+	contentType, err := asn1.Marshal(oidData)
+	if err != nil {
+		return nil, err
+	}
+	messageDigest, err := asn1.Marshal(digest)
+	if err != nil {
+		return nil, err
+	}
+	attrs := append([]cmsAttribute{
+		makeAttribute(oidAttributeContentType, contentType),
+		makeAttribute(oidAttributeMessageDigest, messageDigest),
+	}, signedAttrs...)
+	attrsDER, err := marshalAttributes(attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	h := s.hash.New()
+	h.Write(attrsDER)
+	signature, err := s.signer.Sign(rand.Reader, h.Sum(nil), s.hash)
+	if err != nil {
+		return nil, err
+	}
+	return s.assemble(attrsDER, signature, unsigned)
+}
+
+// assemble returns the CMS SignedData signature with the signed attributes `attrsDER`, the
+// signature value `signature` and the unsigned attributes computed by `unsigned`.
+func (s *cmsSigner) assemble(attrsDER, signature []byte,
+	unsigned func(signature []byte) ([]cmsAttribute, error)) ([]byte, error) {
+	digestAlg, err := hashOID(s.hash)
+	if err != nil {
+		return nil, err
+	}
+	sigAlg, err := signatureAlgorithm(s.certificate.PublicKey, s.hash)
+	if err != nil {
+		return nil, err
+	}
+	signerInfo := cmsSignerInfo{
+		Version: 1,
+		SID: cmsIssuerAndSerial{
+			Issuer:       asn1.RawValue{FullBytes: s.certificate.RawIssuer},
+			SerialNumber: s.certificate.SerialNumber,
+		},
+		DigestAlgorithm:    pkix.AlgorithmIdentifier{Algorithm: digestAlg, Parameters: asn1.NullRawValue},
+		SignedAttrs:        implicitTag(attrsDER, 0),
+		SignatureAlgorithm: sigAlg,
+		Signature:          signature,
+	}
+	if unsigned != nil {
+		unsignedAttrs, err := unsigned(signature)
+		if err != nil {
+			return nil, err
+		}
+		if len(unsignedAttrs) > 0 {
+			der, err := marshalAttributes(unsignedAttrs)
+			if err != nil {
+				return nil, err
+			}
+			signerInfo.UnsignedAttrs = implicitTag(der, 1)
+		}
+	}
+
+	var certs []byte
+	for _, cert := range append([]*x509.Certificate{s.certificate}, s.chain...) {
+		certs = append(certs, cert.Raw...)
+	}
+	signedData := cmsSignedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{signerInfo.DigestAlgorithm},
+		EncapContentInfo: cmsEncapContentInfo{EContentType: oidData},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certs},
+		SignerInfos:      []cmsSignerInfo{signerInfo},
+	}
+	return marshalContentInfo(signedData)
+}
+
+// marshalContentInfo returns the DER-encoded ContentInfo of `signedData`.
+func marshalContentInfo(signedData cmsSignedData) ([]byte, error) {
+	inner, err := asn1.Marshal(signedData)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(cmsContentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: inner},
+	})
+}
+
+// parseCMSSignature parses the DER-encoded CMS SignedData `data`, possibly followed by padding
+// zeros, with a single signer.
+func parseCMSSignature(data []byte) (*cmsSignature, error) {
+	var ci cmsContentInfo
+	if _, err := asn1.Unmarshal(data, &ci); err != nil {
+		return nil, err
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("unsupported CMS content type %v", ci.ContentType)
+	}
+	var sd cmsSignedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, err
+	}
+	if len(sd.SignerInfos) != 1 {
+		return nil, fmt.Errorf("CMS signature with %d signers", len(sd.SignerInfos))
+	}
+	sig := &cmsSignature{signedData: sd, signerInfo: sd.SignerInfos[0]}
+	if len(sd.Certificates.Bytes) > 0 {
+		certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		sig.certificates = certs
+	}
+	var err error
+	if sig.signedAttrs, _, err = parseAttributes(sig.signerInfo.SignedAttrs); err != nil {
+		return nil, err
+	}
+	if sig.unsignedAttr, _, err = parseAttributes(sig.signerInfo.UnsignedAttrs); err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
+// hash returns the digest algorithm of the signature.
+func (s *cmsSignature) hash() (crypto.Hash, error) {
+	return getHashForOID(s.signerInfo.DigestAlgorithm.Algorithm)
+}
+
+// signerCertificate returns the certificate of the signer of the signature.
+func (s *cmsSignature) signerCertificate() (*x509.Certificate, error) {
+	for _, cert := range s.certificates {
+		if cert.SerialNumber.Cmp(s.signerInfo.SID.SerialNumber) == 0 &&
+			bytes.Equal(cert.RawIssuer, s.signerInfo.SID.Issuer.FullBytes) {
+			return cert, nil
+		}
+	}
+	return nil, errors.New("signer certificate not found")
+}
+
+// verify verifies that the signature signs the data with digest `digest`. The signer certificate
+// is returned.
+func (s *cmsSignature) verify(digest []byte) (*x509.Certificate, error) {
+	cert, err := s.signerCertificate()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := s.hash()
+	if err != nil {
+		return nil, err
+	}
+	alg, err := x509SignatureAlgorithm(cert.PublicKey, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, attrsDER, err := parseAttributes(s.signerInfo.SignedAttrs)
+	if err != nil {
+		return nil, err
+	}
+	if attrs == nil {
+		// Signature of the data itself.
+		return cert, cert.CheckSignature(alg, digest, s.signerInfo.Signature)
+	}
+	value, ok := findAttribute(attrs, oidAttributeMessageDigest)
+	if !ok {
+		return nil, errors.New("message-digest attribute not found")
+	}
+	var messageDigest []byte
+	if _, err := asn1.Unmarshal(value.FullBytes, &messageDigest); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(messageDigest, digest) {
+		return nil, errors.New("message digest mismatch")
+	}
+	return cert, cert.CheckSignature(alg, attrsDER, s.signerInfo.Signature)
+}
+
+// timestampToken returns the signature time-stamp token of the signature, if any.
+func (s *cmsSignature) timestampToken() ([]byte, bool) {
+	value, ok := findAttribute(s.unsignedAttr, oidAttributeSignatureTimestamp)
+	if !ok {
+		return nil, false
+	}
+	return value.FullBytes, true
+}
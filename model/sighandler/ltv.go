@@ -0,0 +1,367 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package sighandler
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/unidoc/pkcs7"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// maxChainLength is the maximum length of the certificate chains built from the certificates of
+// the signatures.
+const maxChainLength = 16
+
+// CRLFetcher retrieves the certificate revocation lists of certificates.
+type CRLFetcher interface {
+	// FetchCRL returns the DER-encoded CRL covering `cert`, issued by `issuer`. It returns nil if
+	// no CRL is available for the certificate.
+	FetchCRL(cert, issuer *x509.Certificate) ([]byte, error)
+}
+
+// CRLFetcherFunc is a function implementing the CRLFetcher interface.
+type CRLFetcherFunc func(cert, issuer *x509.Certificate) ([]byte, error)
+
+// FetchCRL calls f(cert, issuer).
+func (f CRLFetcherFunc) FetchCRL(cert, issuer *x509.Certificate) ([]byte, error) {
+	return f(cert, issuer)
+}
+
+// OCSPFetcher retrieves the OCSP responses of certificates.
+type OCSPFetcher interface {
+	// FetchOCSP returns the DER-encoded OCSP response for `cert`, issued by `issuer`. It returns
+	// nil if no OCSP responder is available for the certificate.
+	FetchOCSP(cert, issuer *x509.Certificate) ([]byte, error)
+}
+
+// OCSPFetcherFunc is a function implementing the OCSPFetcher interface.
+type OCSPFetcherFunc func(cert, issuer *x509.Certificate) ([]byte, error)
+
+// FetchOCSP calls f(cert, issuer).
+func (f OCSPFetcherFunc) FetchOCSP(cert, issuer *x509.Certificate) ([]byte, error) {
+	return f(cert, issuer)
+}
+
+// HTTPRevocationFetcher retrieves the CRLs and OCSP responses of certificates from the HTTP
+// distribution points and OCSP responders listed in the certificates.
+type HTTPRevocationFetcher struct {
+	// HTTPClient is the client used for the requests. http.DefaultClient is used if nil.
+	HTTPClient *http.Client
+}
+
+// NewHTTPRevocationFetcher returns a new HTTP revocation information fetcher.
+func NewHTTPRevocationFetcher() *HTTPRevocationFetcher {
+	return &HTTPRevocationFetcher{}
+}
+
+func (f *HTTPRevocationFetcher) client() *http.Client {
+	if f.HTTPClient != nil {
+		return f.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// readResponse returns the body of the HTTP response `resp`.
+func readResponse(resp *http.Response) ([]byte, error) {
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http status code not ok (got %d)", resp.StatusCode)
+	}
+	return body, nil
+}
+
+// FetchCRL downloads the CRL of `cert` from its first HTTP distribution point.
+func (f *HTTPRevocationFetcher) FetchCRL(cert, issuer *x509.Certificate) ([]byte, error) {
+	for _, url := range cert.CRLDistributionPoints {
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			continue
+		}
+		resp, err := f.client().Get(url)
+		if err != nil {
+			return nil, err
+		}
+		data, err := readResponse(resp)
+		if err != nil {
+			return nil, err
+		}
+		crl, err := x509.ParseDERCRL(data)
+		if err != nil {
+			return nil, err
+		}
+		if err := issuer.CheckCRLSignature(crl); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+	return nil, nil
+}
+
+// FetchOCSP requests the status of `cert` from its first OCSP responder.
+func (f *HTTPRevocationFetcher) FetchOCSP(cert, issuer *x509.Certificate) ([]byte, error) {
+	if len(cert.OCSPServer) == 0 {
+		return nil, nil
+	}
+	req, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.client().Post(cert.OCSPServer[0], "application/ocsp-request", bytes.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+	data, err := readResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ocsp.ParseResponseForCert(data, cert, issuer); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// LTVOpts contains the options for collecting the validation material of signatures.
+type LTVOpts struct {
+	// Certificates are certificates used to build the certificate chains in addition to the
+	// certificates embedded in the signatures, e.g. intermediate and root certificates.
+	Certificates []*x509.Certificate
+
+	// CRLFetcher retrieves the CRLs of the certificates. The HTTPRevocationFetcher is used if nil.
+	CRLFetcher CRLFetcher
+
+	// OCSPFetcher retrieves the OCSP responses of the certificates. The HTTPRevocationFetcher is
+	// used if nil.
+	OCSPFetcher OCSPFetcher
+}
+
+// AddLTV collects the validation material of all the signatures of the document of `appender`
+// (certificate chains, CRLs and OCSP responses) and adds it to the document security store of the
+// new revision, with the validation related information of each signature. The existing store of
+// the document is kept. The signatures of the document remain valid as the store is written in an
+// incremental update, which makes PAdESBaselineT signatures PAdESBaselineLT. For
+// PAdESBaselineLTA, the appender is also signed with a document time-stamp covering the store.
+// If `opts` is nil, the defaults are used.
+func AddLTV(appender *model.PdfAppender, opts *LTVOpts) error {
+	if opts == nil {
+		opts = &LTVOpts{}
+	}
+	crlFetcher, ocspFetcher := opts.CRLFetcher, opts.OCSPFetcher
+	if crlFetcher == nil {
+		crlFetcher = NewHTTPRevocationFetcher()
+	}
+	if ocspFetcher == nil {
+		ocspFetcher = NewHTTPRevocationFetcher()
+	}
+
+	reader := appender.Reader
+	dss, err := reader.GetDSS()
+	if err != nil {
+		return err
+	}
+	if dss == nil {
+		dss = model.NewPdfDSS()
+	}
+
+	if reader.AcroForm != nil {
+		for _, field := range reader.AcroForm.AllFields() {
+			sigField, ok := field.GetContext().(*model.PdfFieldSignature)
+			if !ok || sigField.V == nil {
+				continue
+			}
+			sig := sigField.V
+			signers, certs, err := signatureCertificates(sig)
+			if err != nil {
+				return fmt.Errorf("signature %s: %v", field.PartialName(), err)
+			}
+			certs = append(certs, opts.Certificates...)
+
+			collector := &ltvCollector{crlFetcher: crlFetcher, ocspFetcher: ocspFetcher}
+			for _, signer := range signers {
+				if err := collector.addChain(buildChain(signer, certs)); err != nil {
+					return fmt.Errorf("signature %s: %v", field.PartialName(), err)
+				}
+			}
+			if err := dss.AddSignatureData(sig, collector.certs, collector.ocsps, collector.crls); err != nil {
+				return err
+			}
+		}
+	}
+
+	appender.SetDSS(dss)
+	return nil
+}
+
+// ltvCollector collects the validation material of certificate chains.
+type ltvCollector struct {
+	crlFetcher  CRLFetcher
+	ocspFetcher OCSPFetcher
+
+	certs [][]byte
+	ocsps [][]byte
+	crls  [][]byte
+}
+
+// addData appends `data` to `list` if not already present.
+func addData(list [][]byte, data []byte) [][]byte {
+	for _, d := range list {
+		if bytes.Equal(d, data) {
+			return list
+		}
+	}
+	return append(list, data)
+}
+
+// addChain collects the certificates of `chain` and the revocation information of each
+// certificate issued by the next certificate of the chain.
+func (c *ltvCollector) addChain(chain []*x509.Certificate) error {
+	for i, cert := range chain {
+		c.certs = addData(c.certs, cert.Raw)
+		if i+1 >= len(chain) {
+			break
+		}
+		issuer := chain[i+1]
+
+		ocspData, err := c.ocspFetcher.FetchOCSP(cert, issuer)
+		if err != nil {
+			return fmt.Errorf("OCSP of %s: %v", cert.Subject.CommonName, err)
+		}
+		if len(ocspData) > 0 {
+			c.ocsps = addData(c.ocsps, ocspData)
+			// The certificate of a delegated OCSP responder is needed to validate the response.
+			if resp, err := ocsp.ParseResponse(ocspData, issuer); err == nil && resp.Certificate != nil {
+				c.certs = addData(c.certs, resp.Certificate.Raw)
+			}
+		}
+
+		crlData, err := c.crlFetcher.FetchCRL(cert, issuer)
+		if err != nil {
+			return fmt.Errorf("CRL of %s: %v", cert.Subject.CommonName, err)
+		}
+		if len(crlData) > 0 {
+			c.crls = addData(c.crls, crlData)
+		}
+	}
+	return nil
+}
+
+// isSelfSigned returns true if `cert` is a self-signed certificate.
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil
+}
+
+// buildChain returns the certificate chain of `cert` built from the certificates `certs`, up to a
+// self-signed certificate or the last issuer found.
+func buildChain(cert *x509.Certificate, certs []*x509.Certificate) []*x509.Certificate {
+	chain := []*x509.Certificate{cert}
+	for len(chain) < maxChainLength {
+		last := chain[len(chain)-1]
+		if isSelfSigned(last) {
+			break
+		}
+		var issuer *x509.Certificate
+		for _, c := range certs {
+			if bytes.Equal(c.RawSubject, last.RawIssuer) && last.CheckSignatureFrom(c) == nil {
+				issuer = c
+				break
+			}
+		}
+		if issuer == nil {
+			break
+		}
+		for _, c := range chain {
+			if c.Equal(issuer) {
+				return chain
+			}
+		}
+		chain = append(chain, issuer)
+	}
+	return chain
+}
+
+// signatureCertificates returns the signer certificates of signature `sig`, the signer and the
+// signers of its time-stamp tokens, and all the certificates embedded in the signature.
+func signatureCertificates(sig *model.PdfSignature) ([]*x509.Certificate, []*x509.Certificate, error) {
+	if sig.SubFilter != nil && *sig.SubFilter == "adbe.x509.rsa_sha1" {
+		certs, err := parseCertEntry(sig.Cert)
+		if err != nil {
+			return nil, nil, err
+		}
+		return certs[:1], certs, nil
+	}
+	if sig.Contents == nil {
+		return nil, nil, errors.New("signature Contents not set")
+	}
+
+	cms, err := parseCMSSignature(sig.Contents.Bytes())
+	if err != nil {
+		// Signatures not DER-encoded.
+		p7, err := pkcs7.Parse(sig.Contents.Bytes())
+		if err != nil {
+			return nil, nil, err
+		}
+		signer := p7.GetOnlySigner()
+		if signer == nil {
+			return nil, nil, errors.New("signer certificate not found")
+		}
+		return []*x509.Certificate{signer}, p7.Certificates, nil
+	}
+
+	signer, err := cms.signerCertificate()
+	if err != nil {
+		return nil, nil, err
+	}
+	signers := []*x509.Certificate{signer}
+	certs := cms.certificates
+	if data, ok := cms.timestampToken(); ok {
+		if token, err := parseCMSSignature(data); err == nil {
+			if tsaCert, err := token.signerCertificate(); err == nil {
+				signers = append(signers, tsaCert)
+				certs = append(certs, token.certificates...)
+			}
+		}
+	}
+	return signers, certs, nil
+}
+
+// parseCertEntry returns the certificates of the Cert entry `obj` of a signature dictionary.
+func parseCertEntry(obj core.PdfObject) ([]*x509.Certificate, error) {
+	var certData []byte
+	switch certObj := obj.(type) {
+	case *core.PdfObjectString:
+		certData = certObj.Bytes()
+	case *core.PdfObjectArray:
+		for _, o := range certObj.Elements() {
+			certStr, ok := core.GetString(o)
+			if !ok {
+				return nil, fmt.Errorf("invalid certificate object type in signature certificate chain: %T", o)
+			}
+			certData = append(certData, certStr.Bytes()...)
+		}
+	default:
+		return nil, fmt.Errorf("invalid signature certificate object type: %T", obj)
+	}
+	certs, err := x509.ParseCertificates(certData)
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no signature certificates found")
+	}
+	return certs, nil
+}
@@ -0,0 +1,82 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package sighandler
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// DigestSigner is implemented by the signature handlers able to sign the digest of a document
+// written by the first phase of a deferred signing (see model.PdfAppender.WriteDeferred), for
+// instance in the remote service holding the signing key.
+type DigestSigner interface {
+	// SignDigest returns the DER-encoded detached CMS signature of the document digest `digest`
+	// computed with `hash`.
+	SignDigest(digest []byte, hash crypto.Hash) ([]byte, error)
+}
+
+// deferredSignature is the handler reserving the Contents of a signature created externally.
+type deferredSignature struct {
+	subFilter     string
+	signatureSize int
+}
+
+// NewDeferredSignature creates a new Adobe.PPKLite signature handler of sub-filter `subFilter`,
+// adbe.pkcs7.detached or ETSI.CAdES.detached, for a deferred signing: the handler only reserves
+// `signatureSize` bytes for the signature (8192 if 0), to be set by
+// model.CompleteDeferredSignature once created externally. It cannot validate signatures.
+func NewDeferredSignature(subFilter string, signatureSize int) (model.SignatureHandler, error) {
+	switch subFilter {
+	case "adbe.pkcs7.detached", "ETSI.CAdES.detached":
+	default:
+		return nil, fmt.Errorf("unsupported deferred signature sub-filter %q", subFilter)
+	}
+	if signatureSize <= 0 {
+		signatureSize = 8192
+	}
+	return &deferredSignature{subFilter: subFilter, signatureSize: signatureSize}, nil
+}
+
+// InitSignature initialises the PdfSignature.
+func (a *deferredSignature) InitSignature(sig *model.PdfSignature) error {
+	handler := *a
+	sig.Handler = &handler
+	sig.Filter = core.MakeName("Adobe.PPKLite")
+	sig.SubFilter = core.MakeName(a.subFilter)
+	sig.Reference = nil
+	sig.Contents = core.MakeHexString(string(make([]byte, a.signatureSize)))
+	return nil
+}
+
+// NewDigest creates a new digest. The digest of the document is computed by
+// model.PdfAppender.WriteDeferred, the data written to the handler digest is discarded.
+func (a *deferredSignature) NewDigest(sig *model.PdfSignature) (model.Hasher, error) {
+	return ioutil.Discard, nil
+}
+
+// Validate is not supported by the handler.
+func (a *deferredSignature) Validate(sig *model.PdfSignature, digest model.Hasher) (model.SignatureValidationResult, error) {
+	return model.SignatureValidationResult{}, errors.New("deferred signature handler cannot validate signatures")
+}
+
+// Sign keeps the reserved Contents of the signature.
+func (a *deferredSignature) Sign(sig *model.PdfSignature, digest model.Hasher) error {
+	if sig.Contents == nil {
+		return errors.New("signature Contents not reserved")
+	}
+	return nil
+}
+
+// IsApplicable returns false, the handler cannot validate signatures.
+func (a *deferredSignature) IsApplicable(sig *model.PdfSignature) bool {
+	return false
+}
@@ -0,0 +1,277 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package sighandler
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"hash"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// PAdESLevel represents a PAdES baseline signature level (ETSI EN 319 142-1).
+type PAdESLevel int
+
+// PAdES baseline signature levels.
+const (
+	// PAdESBaselineB is the basic level: a CAdES detached signature with the signing certificate
+	// bound by the signed attributes.
+	PAdESBaselineB PAdESLevel = iota
+
+	// PAdESBaselineT adds a signature time-stamp proving the existence of the signature at a time.
+	PAdESBaselineT
+
+	// PAdESBaselineLT adds the validation material of the signature (certificates, CRLs and OCSP
+	// responses) to the document security store in an incremental update after signing.
+	PAdESBaselineLT
+
+	// PAdESBaselineLTA adds a document time-stamp covering the validation material in a further
+	// incremental update.
+	PAdESBaselineLTA
+)
+
+// String returns the name of the level as in the PAdES specification.
+func (l PAdESLevel) String() string {
+	switch l {
+	case PAdESBaselineB:
+		return "B-B"
+	case PAdESBaselineT:
+		return "B-T"
+	case PAdESBaselineLT:
+		return "B-LT"
+	case PAdESBaselineLTA:
+		return "B-LTA"
+	}
+	return fmt.Sprintf("PAdESLevel(%d)", int(l))
+}
+
+// PAdESOpts contains the options of the PAdES signature handler.
+type PAdESOpts struct {
+	// Hash is the digest algorithm of the signature: crypto.SHA256 (default), crypto.SHA384 or
+	// crypto.SHA512.
+	Hash crypto.Hash
+
+	// Chain is the certificate chain of the signing certificate (without the signing certificate),
+	// embedded in the signature.
+	Chain []*x509.Certificate
+
+	// Level is the baseline level of the signature. The time-stamp authority is required from
+	// PAdESBaselineT. The handler only creates the signature: the document security store of
+	// levels PAdESBaselineLT and PAdESBaselineLTA, and the document time-stamp of
+	// PAdESBaselineLTA, are added in incremental updates after signing.
+	Level PAdESLevel
+
+	// TSA issues the signature time-stamps.
+	TSA TimestampAuthority
+
+	// SignatureSize is the number of bytes reserved for the signature in the document. Defaults to
+	// 8192, or 16384 with a signature time-stamp.
+	SignatureSize int
+}
+
+// Default sizes reserved for the PAdES signatures.
+const (
+	padesSignatureSize          = 8192
+	padesTimestampSignatureSize = 16384
+)
+
+// padES is the ETSI.CAdES.detached (PAdES) signature handler.
+type padES struct {
+	signer      crypto.Signer
+	certificate *x509.Certificate
+	opts        PAdESOpts
+}
+
+// NewPAdES creates a new Adobe.PPKLite ETSI.CAdES.detached signature handler, signing with
+// `signer`, the key of `certificate`. The signer and certificate may be nil for the signature
+// validation. If `opts` is nil, the defaults are used: a PAdESBaselineB signature with SHA-256.
+func NewPAdES(signer crypto.Signer, certificate *x509.Certificate, opts *PAdESOpts) (model.SignatureHandler, error) {
+	if opts == nil {
+		opts = &PAdESOpts{}
+	}
+	handler := &padES{
+		signer:      signer,
+		certificate: certificate,
+		opts:        *opts,
+	}
+	if handler.opts.Hash == 0 {
+		handler.opts.Hash = crypto.SHA256
+	}
+	switch handler.opts.Hash {
+	case crypto.SHA256, crypto.SHA384, crypto.SHA512:
+	default:
+		return nil, fmt.Errorf("unsupported PAdES digest algorithm %v", handler.opts.Hash)
+	}
+	if handler.opts.Level >= PAdESBaselineT && handler.opts.TSA == nil {
+		return nil, fmt.Errorf("PAdES level %s requires a time-stamp authority", handler.opts.Level)
+	}
+	if handler.opts.SignatureSize <= 0 {
+		handler.opts.SignatureSize = padesSignatureSize
+		if handler.opts.TSA != nil {
+			handler.opts.SignatureSize = padesTimestampSignatureSize
+		}
+	}
+	return handler, nil
+}
+
+// InitSignature initialises the PdfSignature.
+func (a *padES) InitSignature(sig *model.PdfSignature) error {
+	if a.certificate == nil {
+		return errors.New("certificate must not be nil")
+	}
+	if a.signer == nil {
+		return errors.New("signer must not be nil")
+	}
+
+	handler := *a
+	sig.Handler = &handler
+	sig.Filter = core.MakeName("Adobe.PPKLite")
+	sig.SubFilter = core.MakeName("ETSI.CAdES.detached")
+	sig.Reference = nil
+
+	// The Contents are only reserved: the signature is computed when the document is written.
+	sig.Contents = core.MakeHexString(string(make([]byte, handler.opts.SignatureSize)))
+	return nil
+}
+
+// NewDigest creates a new digest. For the signature validation, the digest algorithm is read
+// from the signature Contents.
+func (a *padES) NewDigest(sig *model.PdfSignature) (model.Hasher, error) {
+	if sig.Contents != nil {
+		if cms, err := parseCMSSignature(sig.Contents.Bytes()); err == nil {
+			h, err := cms.hash()
+			if err != nil {
+				return nil, err
+			}
+			return h.New(), nil
+		}
+	}
+	if a.signer == nil {
+		return nil, errors.New("invalid signature Contents")
+	}
+	// Signing: the Contents are only reserved.
+	return a.opts.Hash.New(), nil
+}
+
+// Validate validates PdfSignature.
+func (a *padES) Validate(sig *model.PdfSignature, digest model.Hasher) (model.SignatureValidationResult, error) {
+	h, ok := digest.(hash.Hash)
+	if !ok {
+		return model.SignatureValidationResult{}, errors.New("hash type error: not a hash.Hash")
+	}
+	cms, err := parseCMSSignature(sig.Contents.Bytes())
+	if err != nil {
+		return model.SignatureValidationResult{}, err
+	}
+
+	result := model.SignatureValidationResult{IsSigned: true, Certificates: cms.certificates}
+	if _, ok := findAttribute(cms.signedAttrs, oidAttributeSigningTime); ok {
+		result.Errors = append(result.Errors, "signing-time attribute not allowed in PAdES signatures")
+	}
+	cert, err := cms.verify(h.Sum(nil))
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result, nil
+	}
+	result.SignerCertificate = cert
+	if value, ok := findAttribute(cms.signedAttrs, oidAttributeSigningCertV2); !ok {
+		result.Errors = append(result.Errors, "signing-certificate-v2 attribute not found")
+	} else if err := checkSigningCertificateV2(value, cert); err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+
+	if data, ok := cms.timestampToken(); ok {
+		token, err := parseTimestampToken(data)
+		if err == nil {
+			err = token.verifyImprint(cms.signerInfo.Signature)
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("invalid signature time-stamp: %v", err))
+			return result, nil
+		}
+		result.GeneralizedTime = token.info.GeneralizedTime
+	}
+
+	result.IsVerified = len(result.Errors) == 0
+	return result, nil
+}
+
+// Sign sets the Contents fields for the PdfSignature.
+func (a *padES) Sign(sig *model.PdfSignature, digest model.Hasher) error {
+	h, ok := digest.(hash.Hash)
+	if !ok {
+		return errors.New("hash type error: not a hash.Hash")
+	}
+	data, err := a.signDigest(h.Sum(nil))
+	if err != nil {
+		return err
+	}
+	if len(data) > a.opts.SignatureSize {
+		return fmt.Errorf("signature size %d exceeds the reserved size %d", len(data), a.opts.SignatureSize)
+	}
+
+	contents := make([]byte, a.opts.SignatureSize)
+	copy(contents, data)
+	sig.Contents = core.MakeHexString(string(contents))
+	return nil
+}
+
+// signDigest returns the CMS signature of the document digest `digest`.
+func (a *padES) signDigest(digest []byte) ([]byte, error) {
+	signingCert, err := makeSigningCertificateV2(a.certificate)
+	if err != nil {
+		return nil, err
+	}
+	signer := &cmsSigner{
+		certificate: a.certificate,
+		chain:       a.opts.Chain,
+		signer:      a.signer,
+		hash:        a.opts.Hash,
+	}
+	var unsigned func(signature []byte) ([]cmsAttribute, error)
+	if a.opts.TSA != nil {
+		unsigned = a.timestampAttributes
+	}
+	return signer.sign(digest, []cmsAttribute{
+		makeAttribute(oidAttributeSigningCertV2, signingCert),
+	}, unsigned)
+}
+
+// SignDigest returns the ETSI.CAdES.detached signature of the document digest `digest` computed
+// with `hash`, time-stamped if the handler has a time-stamp authority.
+func (a *padES) SignDigest(digest []byte, hash crypto.Hash) ([]byte, error) {
+	if a.certificate == nil || a.signer == nil {
+		return nil, errors.New("certificate and signer must not be nil")
+	}
+	if hash != a.opts.Hash {
+		return nil, fmt.Errorf("digest algorithm %v does not match the handler algorithm %v", hash, a.opts.Hash)
+	}
+	return a.signDigest(digest)
+}
+
+// timestampAttributes returns the unsigned attributes with the time-stamp token of the signature
+// value `signature`.
+func (a *padES) timestampAttributes(signature []byte) ([]cmsAttribute, error) {
+	h := a.opts.Hash.New()
+	h.Write(signature)
+	token, err := a.opts.TSA.Timestamp(h.Sum(nil), a.opts.Hash)
+	if err != nil {
+		return nil, err
+	}
+	return []cmsAttribute{makeAttribute(oidAttributeSignatureTimestamp, token)}, nil
+}
+
+// IsApplicable returns true if the signature handler is applicable for the PdfSignature.
+func (a *padES) IsApplicable(sig *model.PdfSignature) bool {
+	if sig == nil || sig.Filter == nil || sig.SubFilter == nil {
+		return false
+	}
+	return (*sig.Filter == "Adobe.PPKMS" || *sig.Filter == "Adobe.PPKLite") && *sig.SubFilter == "ETSI.CAdES.detached"
+}
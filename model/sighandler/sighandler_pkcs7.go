@@ -7,10 +7,13 @@ package sighandler
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/asn1"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/unidoc/pkcs7"
 
@@ -123,8 +126,10 @@ func (a *adobePKCS7Detached) Validate(sig *model.PdfSignature, digest model.Hash
 	}
 
 	return model.SignatureValidationResult{
-		IsSigned:   true,
-		IsVerified: true,
+		IsSigned:          true,
+		IsVerified:        true,
+		SignerCertificate: p7.GetOnlySigner(),
+		Certificates:      p7.Certificates,
 	}, nil
 }
 
@@ -167,6 +172,24 @@ func (a *adobePKCS7Detached) Sign(sig *model.PdfSignature, digest model.Hasher)
 	return nil
 }
 
+// SignDigest returns the adbe.pkcs7.detached signature of the document digest `digest` computed
+// with `hash`, with the content type, message digest and signing time signed attributes.
+func (a *adobePKCS7Detached) SignDigest(digest []byte, hash crypto.Hash) ([]byte, error) {
+	if a.certificate == nil || a.privateKey == nil {
+		return nil, errors.New("certificate and privateKey must not be nil")
+	}
+	signingTime, err := asn1.Marshal(time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	signer := &cmsSigner{
+		certificate: a.certificate,
+		signer:      a.privateKey,
+		hash:        hash,
+	}
+	return signer.sign(digest, []cmsAttribute{makeAttribute(oidAttributeSigningTime, signingTime)}, nil)
+}
+
 // IsApplicable returns true if the signature handler is applicable for the PdfSignature
 func (a *adobePKCS7Detached) IsApplicable(sig *model.PdfSignature) bool {
 	if sig == nil || sig.Filter == nil || sig.SubFilter == nil {
@@ -132,7 +132,12 @@ func (a *adobeX509RSASHA1) Validate(sig *model.PdfSignature, digest model.Hasher
 	if err := rsa.VerifyPKCS1v15(certificate.PublicKey.(*rsa.PublicKey), ha, h.Sum(nil), sigHash); err != nil {
 		return model.SignatureValidationResult{}, err
 	}
-	return model.SignatureValidationResult{IsSigned: true, IsVerified: true}, nil
+	return model.SignatureValidationResult{
+		IsSigned:          true,
+		IsVerified:        true,
+		SignerCertificate: certificate,
+		Certificates:      []*x509.Certificate{certificate},
+	}, nil
 }
 
 // Sign sets the Contents fields for the PdfSignature.
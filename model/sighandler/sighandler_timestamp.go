@@ -14,12 +14,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"net/http"
 	"time"
 
 	"github.com/unidoc/pkcs7"
-	"github.com/unidoc/timestamp"
 	"github.com/unidoc/unipdf/v3/core"
 	"github.com/unidoc/unipdf/v3/model"
 )
@@ -28,8 +25,15 @@ import (
 type docTimeStamp struct {
 	timestampServerURL string
 	hashAlgorithm      crypto.Hash
+	authority          TimestampAuthority
+	signatureSize      int
 }
 
+// docTimeStampSizeMargin is the number of bytes reserved for the time-stamp tokens in addition to
+// the size of the token issued for the signature initialization, as the size of the tokens of an
+// authority varies slightly.
+const docTimeStampSizeMargin = 1024
+
 // NewDocTimeStamp creates a new DocTimeStamp signature handler.
 // The timestampServerURL parameter can be empty string for the signature validation.
 // The hashAlgorithm parameter can be crypto.SHA1, crypto.SHA256, crypto.SHA384, crypto.SHA512.
@@ -40,10 +44,24 @@ func NewDocTimeStamp(timestampServerURL string, hashAlgorithm crypto.Hash) (mode
 	}, nil
 }
 
+// NewDocTimeStampWithAuthority creates a new DocTimeStamp signature handler requesting the
+// time-stamp tokens from `authority`.
+// The hashAlgorithm parameter can be crypto.SHA1, crypto.SHA256, crypto.SHA384, crypto.SHA512.
+func NewDocTimeStampWithAuthority(authority TimestampAuthority, hashAlgorithm crypto.Hash) (model.SignatureHandler, error) {
+	if authority == nil {
+		return nil, errors.New("time-stamp authority must not be nil")
+	}
+	return &docTimeStamp{
+		authority:     authority,
+		hashAlgorithm: hashAlgorithm,
+	}, nil
+}
+
 // InitSignature initialises the PdfSignature.
 func (a *docTimeStamp) InitSignature(sig *model.PdfSignature) error {
 	handler := *a
 	sig.Handler = &handler
+	sig.Type = core.MakeName("DocTimeStamp")
 	sig.Filter = core.MakeName("Adobe.PPKLite")
 	sig.SubFilter = core.MakeName("ETSI.RFC3161")
 	sig.Reference = nil
@@ -52,7 +70,12 @@ func (a *docTimeStamp) InitSignature(sig *model.PdfSignature) error {
 		return err
 	}
 	digest.Write([]byte("calculate the Contents field size"))
-	return handler.Sign(sig, digest)
+	if err := handler.Sign(sig, digest); err != nil {
+		return err
+	}
+	handler.signatureSize = len(sig.Contents.Bytes()) + docTimeStampSizeMargin
+	sig.Contents = core.MakeHexString(string(make([]byte, handler.signatureSize)))
+	return nil
 }
 
 func (a *docTimeStamp) getCertificate(sig *model.PdfSignature) (*x509.Certificate, error) {
@@ -144,9 +167,11 @@ func (a *docTimeStamp) Validate(sig *model.PdfSignature, digest model.Hasher) (m
 	h.Write(buffer.Bytes())
 	sm := h.Sum(nil)
 	res := model.SignatureValidationResult{
-		IsSigned:        true,
-		IsVerified:      bytes.Equal(sm, tsInfo.MessageImprint.HashedMessage),
-		GeneralizedTime: tsInfo.GeneralizedTime,
+		IsSigned:          true,
+		IsVerified:        bytes.Equal(sm, tsInfo.MessageImprint.HashedMessage),
+		GeneralizedTime:   tsInfo.GeneralizedTime,
+		SignerCertificate: p7.GetOnlySigner(),
+		Certificates:      p7.Certificates,
 	}
 	return res, nil
 }
@@ -160,45 +185,30 @@ func (a *docTimeStamp) Sign(sig *model.PdfSignature, digest model.Hasher) error
 		return err
 	}
 
-	s := h.Sum(nil)
-	r := timestamp.Request{
-		HashAlgorithm:   a.hashAlgorithm,
-		HashedMessage:   s,
-		Certificates:    true,
-		Extensions:      nil,
-		ExtraExtensions: nil,
+	authority := a.authority
+	if authority == nil {
+		authority = NewTimestampClient(a.timestampServerURL)
 	}
-	data, err := r.Marshal()
+	token, err := authority.Timestamp(h.Sum(nil), a.hashAlgorithm)
 	if err != nil {
 		return err
 	}
 
-	resp, err := http.Post(a.timestampServerURL, "application/timestamp-query", bytes.NewBuffer(data))
-	if err != nil {
-		return err
+	// Pad the token to the size reserved for the Contents, which may have been reserved by a copy
+	// of the handler.
+	size := a.signatureSize
+	if sig.Contents != nil && len(sig.Contents.Bytes()) > size {
+		size = len(sig.Contents.Bytes())
 	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("http status code not ok (got %d)", resp.StatusCode)
-	}
-
-	var ci struct {
-		Version asn1.RawValue
-		Content asn1.RawValue
-	}
-
-	_, err = asn1.Unmarshal(body, &ci)
-	if err != nil {
-		return err
+	if size > 0 {
+		if len(token) > size {
+			return fmt.Errorf("time-stamp token size %d exceeds the reserved size %d", len(token), size)
+		}
+		data := make([]byte, size)
+		copy(data, token)
+		token = data
 	}
-
-	sig.Contents = core.MakeHexString(string(ci.Content.FullBytes))
+	sig.Contents = core.MakeHexString(string(token))
 	return nil
 }
 
@@ -0,0 +1,194 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package sighandler
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/unidoc/timestamp"
+)
+
+// TimestampAuthority issues RFC 3161 time-stamp tokens.
+type TimestampAuthority interface {
+	// Timestamp returns a DER-encoded time-stamp token for the data with digest `digest`
+	// computed with `hash`. The token is a CMS SignedData with TSTInfo content.
+	Timestamp(digest []byte, hash crypto.Hash) ([]byte, error)
+}
+
+// timestampResponse is the TimeStampResp structure of RFC 3161.
+type timestampResponse struct {
+	Status struct {
+		Status       int
+		StatusString asn1.RawValue  `asn1:"optional"`
+		FailInfo     asn1.BitString `asn1:"optional"`
+	}
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+// parseTimestampResponse returns the time-stamp token of the DER-encoded time-stamp response
+// `data`.
+func parseTimestampResponse(data []byte) ([]byte, error) {
+	var resp timestampResponse
+	if _, err := asn1.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	// The request is granted with status 0 (granted) or 1 (grantedWithMods).
+	if resp.Status.Status > 1 {
+		return nil, fmt.Errorf("time-stamp request rejected (status %d)", resp.Status.Status)
+	}
+	if len(resp.TimeStampToken.FullBytes) == 0 {
+		return nil, errors.New("no time-stamp token in time-stamp response")
+	}
+	return resp.TimeStampToken.FullBytes, nil
+}
+
+// TimestampClient is an RFC 3161 time-stamp authority client over HTTP.
+type TimestampClient struct {
+	// URL is the address of the time-stamp server.
+	URL string
+
+	// HTTPClient is the client used for the requests. http.DefaultClient is used if nil.
+	HTTPClient *http.Client
+}
+
+// NewTimestampClient returns a new time-stamp authority client for the server at `url`.
+func NewTimestampClient(url string) *TimestampClient {
+	return &TimestampClient{URL: url}
+}
+
+// Timestamp requests a time-stamp token for the data with digest `digest` computed with `hash`.
+func (c *TimestampClient) Timestamp(digest []byte, hash crypto.Hash) ([]byte, error) {
+	r := timestamp.Request{
+		HashAlgorithm: hash,
+		HashedMessage: digest,
+		Certificates:  true,
+	}
+	data, err := r.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Post(c.URL, "application/timestamp-query", bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http status code not ok (got %d)", resp.StatusCode)
+	}
+	return parseTimestampResponse(body)
+}
+
+// LocalTimestampAuthority is a time-stamp authority issuing the tokens in process, with its own
+// certificate and key. It stands in for a time-stamp server in tests and closed environments.
+type LocalTimestampAuthority struct {
+	certificate *x509.Certificate
+	signer      crypto.Signer
+
+	// Policy is the TSA policy of the issued tokens.
+	Policy asn1.ObjectIdentifier
+
+	// Now returns the time of the issued tokens. time.Now is used if nil.
+	Now func() time.Time
+}
+
+// NewLocalTimestampAuthority returns a new local time-stamp authority signing the tokens with
+// `signer`, the key of `certificate`. The certificate should have the time-stamping extended key
+// usage.
+func NewLocalTimestampAuthority(certificate *x509.Certificate, signer crypto.Signer) *LocalTimestampAuthority {
+	return &LocalTimestampAuthority{
+		certificate: certificate,
+		signer:      signer,
+		Policy:      asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 22234, 2, 1, 1},
+	}
+}
+
+// Timestamp issues a time-stamp token for the data with digest `digest` computed with `hash`.
+func (a *LocalTimestampAuthority) Timestamp(digest []byte, hash crypto.Hash) ([]byte, error) {
+	now := time.Now
+	if a.Now != nil {
+		now = a.Now
+	}
+	ts := timestamp.Timestamp{
+		HashAlgorithm:     hash,
+		HashedMessage:     digest,
+		Time:              now().UTC(),
+		Policy:            a.Policy,
+		AddTSACertificate: true,
+	}
+	data, err := ts.CreateResponse(a.certificate, a.signer)
+	if err != nil {
+		return nil, err
+	}
+	return parseTimestampResponse(data)
+}
+
+// timestampToken is a parsed time-stamp token.
+type timestampToken struct {
+	signature *cmsSignature
+	info      timestampInfo
+	hash      crypto.Hash
+}
+
+// parseTimestampToken parses and verifies the signature of the time-stamp token `data`.
+func parseTimestampToken(data []byte) (*timestampToken, error) {
+	sig, err := parseCMSSignature(data)
+	if err != nil {
+		return nil, err
+	}
+	if !sig.signedData.EncapContentInfo.EContentType.Equal(oidTSTInfo) {
+		return nil, errors.New("time-stamp token content is not a TSTInfo")
+	}
+	var content []byte
+	if _, err := asn1.Unmarshal(sig.signedData.EncapContentInfo.EContent.Bytes, &content); err != nil {
+		return nil, err
+	}
+	sigHash, err := sig.hash()
+	if err != nil {
+		return nil, err
+	}
+	h := sigHash.New()
+	h.Write(content)
+	if _, err := sig.verify(h.Sum(nil)); err != nil {
+		return nil, err
+	}
+
+	token := &timestampToken{signature: sig}
+	if _, err := asn1.Unmarshal(content, &token.info); err != nil {
+		return nil, err
+	}
+	if token.hash, err = getHashForOID(token.info.MessageImprint.HashAlgorithm.Algorithm); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// verifyImprint checks that the token time-stamps `data`.
+func (t *timestampToken) verifyImprint(data []byte) error {
+	h := t.hash.New()
+	h.Write(data)
+	if !bytes.Equal(h.Sum(nil), t.info.MessageImprint.HashedMessage) {
+		return errors.New("time-stamp message imprint mismatch")
+	}
+	return nil
+}
@@ -10,7 +10,6 @@ import (
 	"errors"
 	"time"
 
-	"github.com/unidoc/unipdf/v3/common"
 	"github.com/unidoc/unipdf/v3/core"
 )
 
@@ -102,6 +101,9 @@ type PdfSignature struct {
 	PropBuild    *core.PdfObjectDictionary
 	PropAuthTime *core.PdfObjectInteger
 	PropAuthType *core.PdfObjectName
+
+	// docMDP is the DocMDP permission of a certification signature, 0 for an approval signature.
+	docMDP DocMDPPermission
 }
 
 // NewPdfSignature creates a new PdfSignature object.
@@ -150,6 +152,34 @@ func (sig *PdfSignature) SetLocation(location string) {
 	sig.Location = core.MakeString(location)
 }
 
+// SetDocMDPPermission makes the signature a certification signature of the document, permitting
+// the changes of `perm` after it. The DocMDP transform is added to the Reference of the signature
+// by PdfAppender.Sign, which also references the signature from the Perms of the catalog.
+func (sig *PdfSignature) SetDocMDPPermission(perm DocMDPPermission) {
+	sig.docMDP = perm
+}
+
+// makeSignatureReference returns a signature reference dictionary of transform `method` with the
+// transform parameters `params` (Table 253 - Entries in a signature reference dictionary).
+func makeSignatureReference(method core.PdfObjectName, params *core.PdfObjectDictionary) *core.PdfObjectDictionary {
+	params.Set("Type", core.MakeName("TransformParams"))
+	params.Set("V", core.MakeName("1.2"))
+
+	ref := core.MakeDict()
+	ref.Set("Type", core.MakeName("SigRef"))
+	ref.Set("TransformMethod", core.MakeName(string(method)))
+	ref.Set("TransformParams", params)
+	return ref
+}
+
+// addReference adds the signature reference dictionary `ref` to the Reference of the signature.
+func (sig *PdfSignature) addReference(ref *core.PdfObjectDictionary) {
+	if sig.Reference == nil {
+		sig.Reference = core.MakeArray()
+	}
+	sig.Reference.Append(ref)
+}
+
 // Initialize initializes the PdfSignature.
 func (sig *PdfSignature) Initialize() error {
 	if sig.Handler == nil {
@@ -191,7 +221,7 @@ func (sig *PdfSignature) ToPdfObject() core.PdfObject {
 func (r *PdfReader) newPdfSignatureFromIndirect(container *core.PdfIndirectObject) (*PdfSignature, error) {
 	dict, ok := container.PdfObject.(*core.PdfObjectDictionary)
 	if !ok {
-		common.Log.Debug("ERROR: Signature container not containing a dictionary")
+		r.log().Debug("ERROR: Signature container not containing a dictionary")
 		return nil, ErrTypeCheck
 	}
 
@@ -207,7 +237,7 @@ func (r *PdfReader) newPdfSignatureFromIndirect(container *core.PdfIndirectObjec
 
 	sig.Filter, ok = core.GetName(dict.Get("Filter"))
 	if !ok {
-		common.Log.Error("ERROR: Signature Filter attribute invalid or missing")
+		r.log().Error("ERROR: Signature Filter attribute invalid or missing")
 		return nil, ErrInvalidAttribute
 	}
 
@@ -215,7 +245,7 @@ func (r *PdfReader) newPdfSignatureFromIndirect(container *core.PdfIndirectObjec
 
 	sig.Contents, ok = core.GetString(dict.Get("Contents"))
 	if !ok {
-		common.Log.Error("ERROR: Signature contents missing")
+		r.log().Error("ERROR: Signature contents missing")
 		return nil, ErrInvalidAttribute
 	}
 
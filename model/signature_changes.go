@@ -0,0 +1,623 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// DocMDPPermission is the access permission of a certification signature, the changes allowed
+// after the signature (Table 257 - Entries in the DocMDP transform parameters dictionary).
+type DocMDPPermission int
+
+// DocMDP access permissions.
+const (
+	// DocMDPNoChanges permits no change to the document.
+	DocMDPNoChanges DocMDPPermission = 1
+
+	// DocMDPFillForms permits filling in forms, instantiating page templates and signing.
+	DocMDPFillForms DocMDPPermission = 2
+
+	// DocMDPAnnotate permits annotation creation, deletion and modification in addition to the
+	// changes permitted by DocMDPFillForms.
+	DocMDPAnnotate DocMDPPermission = 3
+)
+
+// FieldMDPAction specifies the fields locked by a FieldMDP signature (Table 258).
+type FieldMDPAction string
+
+// FieldMDP actions.
+const (
+	// FieldMDPAll locks all the fields of the document.
+	FieldMDPAll FieldMDPAction = "All"
+
+	// FieldMDPInclude locks the listed fields only.
+	FieldMDPInclude FieldMDPAction = "Include"
+
+	// FieldMDPExclude locks all the fields except the listed ones.
+	FieldMDPExclude FieldMDPAction = "Exclude"
+)
+
+// SignatureChangeKind classifies a change made to a document after a signature.
+type SignatureChangeKind string
+
+// Kinds of changes made after a signature.
+const (
+	// SignatureChangeStructure is a change of the document structure without visible effect,
+	// such as cross-reference streams, new objects not yet used or the form dictionary.
+	SignatureChangeStructure SignatureChangeKind = "structure"
+
+	// SignatureChangeMetadata is a change of the document information or XMP metadata.
+	SignatureChangeMetadata SignatureChangeKind = "metadata"
+
+	// SignatureChangeDSS is a change of the document security store.
+	SignatureChangeDSS SignatureChangeKind = "dss"
+
+	// SignatureChangeTimestamp is the addition of a document time-stamp.
+	SignatureChangeTimestamp SignatureChangeKind = "timestamp"
+
+	// SignatureChangeSignature is the addition of a signature.
+	SignatureChangeSignature SignatureChangeKind = "signature"
+
+	// SignatureChangeFormFill is a change of the value or the appearance of a form field.
+	SignatureChangeFormFill SignatureChangeKind = "form-fill"
+
+	// SignatureChangeAnnotation is the creation, deletion or modification of an annotation.
+	SignatureChangeAnnotation SignatureChangeKind = "annotation"
+
+	// SignatureChangeFormField is the creation of a form field other than a signature field.
+	SignatureChangeFormField SignatureChangeKind = "form-field"
+
+	// SignatureChangeOther is any other change, such as a change of the page contents.
+	SignatureChangeOther SignatureChangeKind = "other"
+)
+
+// permission returns the lowest DocMDP permission allowing changes of kind `k`, or 0 if the
+// changes are never allowed after a signature.
+func (k SignatureChangeKind) permission() DocMDPPermission {
+	switch k {
+	case SignatureChangeStructure, SignatureChangeMetadata, SignatureChangeDSS, SignatureChangeTimestamp:
+		return DocMDPNoChanges
+	case SignatureChangeSignature, SignatureChangeFormFill:
+		return DocMDPFillForms
+	case SignatureChangeAnnotation:
+		return DocMDPAnnotate
+	}
+	return 0
+}
+
+// SignatureChange represents a change made to a document by an incremental update after a
+// signature.
+type SignatureChange struct {
+	// Revision is the index of the revision making the change.
+	Revision int
+
+	// ObjectNumber is the number of the object changed.
+	ObjectNumber int64
+
+	Kind SignatureChangeKind
+
+	// Permitted is true if the change is permitted by the DocMDP and FieldMDP rules applying to
+	// the signature.
+	Permitted bool
+
+	Description string
+}
+
+// String returns a description of the change.
+func (c SignatureChange) String() string {
+	permitted := "not permitted"
+	if c.Permitted {
+		permitted = "permitted"
+	}
+	return fmt.Sprintf("revision %d: object %d: %s (%s): %s", c.Revision, c.ObjectNumber, c.Kind, permitted,
+		c.Description)
+}
+
+// fieldLock specifies the fields locked by a FieldMDP transform or a signature field lock.
+type fieldLock struct {
+	action FieldMDPAction
+	fields map[string]struct{}
+}
+
+// isLocked returns true if the field with the fully qualified name `name` is locked.
+func (l *fieldLock) isLocked(name string) bool {
+	_, listed := l.fields[name]
+	switch l.action {
+	case FieldMDPAll:
+		return true
+	case FieldMDPInclude:
+		return listed
+	case FieldMDPExclude:
+		return !listed
+	}
+	return false
+}
+
+// newFieldLock returns the fields locked by the FieldMDP transform parameters or field lock
+// dictionary `dict`.
+func newFieldLock(dict *core.PdfObjectDictionary) *fieldLock {
+	action, _ := core.GetNameVal(dict.Get("Action"))
+	lock := &fieldLock{action: FieldMDPAction(action), fields: map[string]struct{}{}}
+	if arr, ok := core.GetArray(dict.Get("Fields")); ok {
+		for _, obj := range arr.Elements() {
+			if name, ok := core.GetString(obj); ok {
+				lock.fields[name.Decoded()] = struct{}{}
+			}
+		}
+	}
+	return lock
+}
+
+// signatureTransforms returns the DocMDP permission (0 if none) and the FieldMDP field locks of
+// the signature references `refs`.
+func signatureTransforms(refs *core.PdfObjectArray) (DocMDPPermission, []*fieldLock) {
+	if refs == nil {
+		return 0, nil
+	}
+	var perm DocMDPPermission
+	var locks []*fieldLock
+	for _, obj := range refs.Elements() {
+		ref, ok := core.GetDict(core.ResolveReference(obj))
+		if !ok {
+			continue
+		}
+		method, _ := core.GetNameVal(ref.Get("TransformMethod"))
+		params, _ := core.GetDict(core.ResolveReference(ref.Get("TransformParams")))
+		switch method {
+		case "DocMDP":
+			perm = DocMDPFillForms
+			if params != nil {
+				if p, ok := core.GetIntVal(params.Get("P")); ok && p >= 1 && p <= 3 {
+					perm = DocMDPPermission(p)
+				}
+			}
+		case "FieldMDP":
+			if params != nil {
+				locks = append(locks, newFieldLock(params))
+			}
+		}
+	}
+	return perm, locks
+}
+
+// revisionParser returns a parser of the document as it was at revision `index`.
+func (r *PdfReader) revisionParser(index int) (*core.PdfParser, error) {
+	rs, err := r.parser.GetRevisionReader(index)
+	if err != nil {
+		return nil, err
+	}
+	parser, err := core.NewParserWithLogger(rs, r.log())
+	if err != nil {
+		return nil, err
+	}
+	if encrypted, _ := parser.IsEncrypted(); encrypted {
+		if ok, err := parser.Decrypt([]byte("")); err != nil || !ok {
+			return nil, errors.New("cannot decrypt revision")
+		}
+	}
+	return parser, nil
+}
+
+// changeClassifier classifies the objects changed by a revision by comparing them with the
+// previous revision.
+type changeClassifier struct {
+	prev *core.PdfParser
+	cur  *core.PdfParser
+
+	// Object numbers of the catalog, form dictionary and document information of the revision,
+	// and of the objects of its document security store and XMP metadata. The catalog of the
+	// previous revision may be a different object.
+	catalog     int64
+	prevCatalog int64
+	acroForm    int64
+	info        int64
+	dss         map[int64]struct{}
+	metadata    int64
+}
+
+// newChangeClassifier returns a classifier of the changes of revision `cur` after revision
+// `prev`.
+func newChangeClassifier(prev, cur *core.PdfParser) *changeClassifier {
+	c := &changeClassifier{prev: prev, cur: cur, dss: map[int64]struct{}{}}
+	if trailer := prev.GetTrailer(); trailer != nil {
+		c.prevCatalog = referenceNumber(trailer.Get("Root"))
+	}
+	trailer := cur.GetTrailer()
+	if trailer == nil {
+		return c
+	}
+	if ref, ok := trailer.Get("Info").(*core.PdfObjectReference); ok {
+		c.info = ref.ObjectNumber
+	}
+	ref, ok := trailer.Get("Root").(*core.PdfObjectReference)
+	if !ok {
+		return c
+	}
+	c.catalog = ref.ObjectNumber
+	catalog, ok := core.GetDict(c.lookup(cur, ref.ObjectNumber))
+	if !ok {
+		return c
+	}
+	if ref, ok := catalog.Get("AcroForm").(*core.PdfObjectReference); ok {
+		c.acroForm = ref.ObjectNumber
+	}
+	if ref, ok := catalog.Get("Metadata").(*core.PdfObjectReference); ok {
+		c.metadata = ref.ObjectNumber
+	}
+	c.collectReferences(catalog.Get("DSS"), c.dss, 0)
+	return c
+}
+
+// lookup returns the direct object number `objNum` of `parser`, nil if not found.
+func (c *changeClassifier) lookup(parser *core.PdfParser, objNum int64) core.PdfObject {
+	obj, err := parser.LookupByNumber(int(objNum))
+	if err != nil {
+		return nil
+	}
+	if ind, ok := obj.(*core.PdfIndirectObject); ok {
+		return ind.PdfObject
+	}
+	return obj
+}
+
+// collectReferences collects the numbers of the objects referenced by `obj` in the current
+// revision, recursively.
+func (c *changeClassifier) collectReferences(obj core.PdfObject, refs map[int64]struct{}, depth int) {
+	if depth > 10 {
+		return
+	}
+	switch t := obj.(type) {
+	case *core.PdfObjectReference:
+		if _, ok := refs[t.ObjectNumber]; ok {
+			return
+		}
+		refs[t.ObjectNumber] = struct{}{}
+		c.collectReferences(c.lookup(c.cur, t.ObjectNumber), refs, depth+1)
+	case *core.PdfObjectArray:
+		for _, o := range t.Elements() {
+			c.collectReferences(o, refs, depth+1)
+		}
+	case *core.PdfObjectDictionary:
+		for _, key := range t.Keys() {
+			c.collectReferences(t.Get(key), refs, depth+1)
+		}
+	case *core.PdfObjectStream:
+		c.collectReferences(t.PdfObjectDictionary, refs, depth+1)
+	}
+}
+
+// changedKeys returns the keys of the dictionaries `prev` and `cur` with different values.
+func changedKeys(prev, cur *core.PdfObjectDictionary) []string {
+	var keys []string
+	for _, key := range prev.Keys() {
+		v := cur.Get(key)
+		if v == nil || v.WriteString() != prev.Get(key).WriteString() {
+			keys = append(keys, string(key))
+		}
+	}
+	for _, key := range cur.Keys() {
+		if prev.Get(key) == nil {
+			keys = append(keys, string(key))
+		}
+	}
+	return keys
+}
+
+// onlyKeys returns true if all the keys `keys` are in `allowed`.
+func onlyKeys(keys []string, allowed ...string) bool {
+	for _, key := range keys {
+		found := false
+		for _, a := range allowed {
+			if key == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// dictOf returns the dictionary of `obj`, a dictionary or a stream.
+func dictOf(obj core.PdfObject) (*core.PdfObjectDictionary, bool) {
+	if stream, ok := obj.(*core.PdfObjectStream); ok {
+		return stream.PdfObjectDictionary, true
+	}
+	return core.GetDict(obj)
+}
+
+// fieldName returns the fully qualified name of the field of the field or widget dictionary
+// `dict` in the current revision.
+func (c *changeClassifier) fieldName(dict *core.PdfObjectDictionary) string {
+	var parts []string
+	for depth := 0; dict != nil && depth < 32; depth++ {
+		if t, ok := core.GetString(dict.Get("T")); ok {
+			parts = append([]string{t.Decoded()}, parts...)
+		}
+		ref, ok := dict.Get("Parent").(*core.PdfObjectReference)
+		if !ok {
+			break
+		}
+		dict, _ = core.GetDict(c.lookup(c.cur, ref.ObjectNumber))
+	}
+	return strings.Join(parts, ".")
+}
+
+// fieldType returns the field type of the field or widget dictionary `dict`, inherited from its
+// parents.
+func (c *changeClassifier) fieldType(dict *core.PdfObjectDictionary) string {
+	for depth := 0; dict != nil && depth < 32; depth++ {
+		if ft, ok := core.GetNameVal(dict.Get("FT")); ok {
+			return ft
+		}
+		ref, ok := dict.Get("Parent").(*core.PdfObjectReference)
+		if !ok {
+			break
+		}
+		dict, _ = core.GetDict(c.lookup(c.cur, ref.ObjectNumber))
+	}
+	return ""
+}
+
+// isSignatureValue returns true if `dict` is a signature dictionary and whether it is a document
+// time-stamp.
+func isSignatureValue(dict *core.PdfObjectDictionary) (isSig, isTimestamp bool) {
+	typ, _ := core.GetNameVal(dict.Get("Type"))
+	subFilter, _ := core.GetNameVal(dict.Get("SubFilter"))
+	isTimestamp = typ == "DocTimeStamp" || subFilter == "ETSI.RFC3161"
+	isSig = typ == "Sig" || isTimestamp || (dict.Get("ByteRange") != nil && dict.Get("Contents") != nil)
+	return isSig, isTimestamp
+}
+
+// classify classifies the change of object `objNum`, `added` or changed by the current
+// revision. For field value changes, the name of the field is returned.
+func (c *changeClassifier) classify(objNum int64, added bool) (SignatureChangeKind, string, string) {
+	obj := c.lookup(c.cur, objNum)
+	dict, ok := dictOf(obj)
+	if !ok {
+		if added {
+			return SignatureChangeStructure, "new object", ""
+		}
+		return SignatureChangeOther, "object changed", ""
+	}
+
+	typ, _ := core.GetNameVal(dict.Get("Type"))
+	subtype, _ := core.GetNameVal(dict.Get("Subtype"))
+	_, isDSS := c.dss[objNum]
+	switch {
+	case typ == "XRef" || typ == "ObjStm":
+		return SignatureChangeStructure, "cross-reference or object stream", ""
+	case isDSS:
+		return SignatureChangeDSS, "document security store", ""
+	case objNum == c.info || objNum == c.metadata:
+		return SignatureChangeMetadata, "document metadata", ""
+	}
+	if isSig, isTimestamp := isSignatureValue(dict); isSig {
+		switch {
+		case !added:
+			return SignatureChangeOther, "signature dictionary changed", ""
+		case isTimestamp:
+			return SignatureChangeTimestamp, "document time-stamp", ""
+		}
+		return SignatureChangeSignature, "signature value", ""
+	}
+
+	prev, hasPrev := dictOf(c.lookup(c.prev, objNum))
+	if _, isStream := obj.(*core.PdfObjectStream); hasPrev && !isStream && len(changedKeys(prev, dict)) == 0 {
+		return SignatureChangeStructure, "object rewritten unchanged", ""
+	}
+	switch {
+	case objNum == c.catalog:
+		prev, ok := core.GetDict(c.lookup(c.prev, c.prevCatalog))
+		if !ok {
+			return SignatureChangeOther, "catalog replaced", ""
+		}
+		keys := changedKeys(prev, dict)
+		if onlyKeys(keys, "AcroForm", "DSS", "Metadata", "Extensions") {
+			return SignatureChangeStructure, "catalog", ""
+		}
+		return SignatureChangeOther, fmt.Sprintf("catalog changed: %s", strings.Join(keys, ", ")), ""
+	case objNum == c.acroForm:
+		if !hasPrev {
+			return SignatureChangeStructure, "form dictionary", ""
+		}
+		keys := changedKeys(prev, dict)
+		if onlyKeys(keys, "Fields", "SigFlags", "NeedAppearances", "DR", "DA") {
+			return SignatureChangeStructure, "form dictionary", ""
+		}
+		return SignatureChangeOther, fmt.Sprintf("form dictionary changed: %s", strings.Join(keys, ", ")), ""
+	case typ == "Page" && hasPrev:
+		return c.classifyPage(prev, dict)
+	}
+
+	isWidget := subtype == "Widget"
+	isField := dict.Get("FT") != nil || (isWidget && dict.Get("Parent") != nil) ||
+		(dict.Get("Kids") != nil && dict.Get("T") != nil)
+	if isWidget || isField {
+		ft := c.fieldType(dict)
+		name := c.fieldName(dict)
+		if added || !hasPrev {
+			if ft == "Sig" {
+				if v, ok := core.GetDict(c.lookup(c.cur, referenceNumber(dict.Get("V")))); ok {
+					if _, isTimestamp := isSignatureValue(v); isTimestamp {
+						return SignatureChangeTimestamp, fmt.Sprintf("time-stamp field %q", name), ""
+					}
+				}
+				return SignatureChangeSignature, fmt.Sprintf("signature field %q", name), ""
+			}
+			if isWidget && !isField {
+				return SignatureChangeAnnotation, "widget annotation", ""
+			}
+			return SignatureChangeFormField, fmt.Sprintf("form field %q", name), ""
+		}
+		keys := changedKeys(prev, dict)
+		if onlyKeys(keys, "V", "AP", "AS", "Kids") {
+			if ft == "Sig" {
+				return SignatureChangeSignature, fmt.Sprintf("signature field %q signed", name), name
+			}
+			return SignatureChangeFormFill, fmt.Sprintf("form field %q filled", name), name
+		}
+		return SignatureChangeAnnotation, fmt.Sprintf("form field %q changed: %s", name, strings.Join(keys, ", ")), name
+	}
+
+	if typ == "Annot" || (dict.Get("Rect") != nil && subtype != "" && typ != "XObject") {
+		return SignatureChangeAnnotation, fmt.Sprintf("%s annotation", subtype), ""
+	}
+	if added {
+		// New objects only take effect through changed objects, classified separately.
+		return SignatureChangeStructure, "new object", ""
+	}
+	if typ == "XObject" && subtype == "Form" && c.isAppearance(objNum) {
+		return SignatureChangeFormFill, "appearance stream", ""
+	}
+	return SignatureChangeOther, "object changed", ""
+}
+
+// classifyPage classifies the change of page `cur`, `prev` in the previous revision.
+func (c *changeClassifier) classifyPage(prev, cur *core.PdfObjectDictionary) (SignatureChangeKind, string, string) {
+	keys := changedKeys(prev, cur)
+	if !onlyKeys(keys, "Annots") {
+		return SignatureChangeOther, fmt.Sprintf("page changed: %s", strings.Join(keys, ", ")), ""
+	}
+	prevAnnots := c.annotationNumbers(c.prev, prev)
+	for objNum := range prevAnnots {
+		if _, ok := c.annotationNumbers(c.cur, cur)[objNum]; !ok {
+			return SignatureChangeAnnotation, "page annotation removed", ""
+		}
+	}
+	// The annotations added are classified separately.
+	return SignatureChangeStructure, "page annotations", ""
+}
+
+// annotationNumbers returns the object numbers of the annotations of `page`.
+func (c *changeClassifier) annotationNumbers(parser *core.PdfParser, page *core.PdfObjectDictionary) map[int64]struct{} {
+	nums := map[int64]struct{}{}
+	annots := page.Get("Annots")
+	if ref, ok := annots.(*core.PdfObjectReference); ok {
+		annots = c.lookup(parser, ref.ObjectNumber)
+	}
+	if arr, ok := core.GetArray(annots); ok {
+		for _, obj := range arr.Elements() {
+			if ref, ok := obj.(*core.PdfObjectReference); ok {
+				nums[ref.ObjectNumber] = struct{}{}
+			}
+		}
+	}
+	return nums
+}
+
+// isAppearance returns true if object `objNum` is only an appearance stream of the widget
+// annotations of the form of the current revision.
+func (c *changeClassifier) isAppearance(objNum int64) bool {
+	acroForm, ok := core.GetDict(c.lookup(c.cur, c.acroForm))
+	if !ok {
+		return false
+	}
+	refs := map[int64]struct{}{}
+	if fields, ok := core.GetArray(c.resolve(acroForm.Get("Fields"))); ok {
+		for _, obj := range fields.Elements() {
+			c.collectAppearances(obj, refs, 0)
+		}
+	}
+	_, ok = refs[objNum]
+	return ok
+}
+
+// collectAppearances collects the object numbers of the appearance streams of the field `obj`
+// and its kids.
+func (c *changeClassifier) collectAppearances(obj core.PdfObject, refs map[int64]struct{}, depth int) {
+	dict, ok := core.GetDict(c.resolve(obj))
+	if !ok || depth > 32 {
+		return
+	}
+	if ap, ok := core.GetDict(c.resolve(dict.Get("AP"))); ok {
+		c.collectReferences(ap, refs, 0)
+	}
+	if kids, ok := core.GetArray(c.resolve(dict.Get("Kids"))); ok {
+		for _, kid := range kids.Elements() {
+			c.collectAppearances(kid, refs, depth+1)
+		}
+	}
+}
+
+// resolve returns the object referenced by `obj` in the current revision, `obj` itself if not a
+// reference.
+func (c *changeClassifier) resolve(obj core.PdfObject) core.PdfObject {
+	if ref, ok := obj.(*core.PdfObjectReference); ok {
+		return c.lookup(c.cur, ref.ObjectNumber)
+	}
+	return obj
+}
+
+// referenceNumber returns the number of the object referenced by `obj`, 0 if not a reference.
+func referenceNumber(obj core.PdfObject) int64 {
+	if ref, ok := obj.(*core.PdfObjectReference); ok {
+		return ref.ObjectNumber
+	}
+	return 0
+}
+
+// signatureChanges returns the changes made by the revisions following revision `signed`,
+// checked against the DocMDP permission `perm` and the field locks `locks`.
+func (r *PdfReader) signatureChanges(signed int, perm DocMDPPermission, locks []*fieldLock) ([]SignatureChange, error) {
+	revisions := r.GetRevisions()
+	if signed+1 >= len(revisions) {
+		return nil, nil
+	}
+	prev, err := r.revisionParser(signed)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []SignatureChange
+	for index := signed + 1; index < len(revisions); index++ {
+		cur, err := r.revisionParser(index)
+		if err != nil {
+			return nil, err
+		}
+		c := newChangeClassifier(prev, cur)
+		rev := revisions[index]
+		add := func(objNum int64, kind SignatureChangeKind, desc, field string) {
+			permitted := kind.permission() != 0 && kind.permission() <= perm
+			if permitted && field != "" {
+				for _, lock := range locks {
+					if lock.isLocked(field) {
+						permitted = false
+						desc += " (locked)"
+						break
+					}
+				}
+			}
+			changes = append(changes, SignatureChange{
+				Revision:     index,
+				ObjectNumber: objNum,
+				Kind:         kind,
+				Permitted:    permitted,
+				Description:  desc,
+			})
+		}
+		for _, objNum := range rev.Added {
+			kind, desc, field := c.classify(int64(objNum), true)
+			add(int64(objNum), kind, desc, field)
+		}
+		for _, objNum := range rev.Changed {
+			kind, desc, field := c.classify(int64(objNum), false)
+			add(int64(objNum), kind, desc, field)
+		}
+		for _, objNum := range rev.Freed {
+			add(int64(objNum), SignatureChangeOther, "object deleted", "")
+		}
+		prev = cur
+	}
+	return changes, nil
+}
@@ -0,0 +1,142 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// DeferredSignature is the result of the first phase of a deferred signing, the document being
+// written with the Contents of its signature reserved (see PdfAppender.WriteDeferred).
+type DeferredSignature struct {
+	// Digest is the digest of the data covered by the ByteRange of the signature, computed with
+	// Hash, to sign externally.
+	Digest []byte
+	Hash   crypto.Hash
+
+	// State is the opaque state of the signature, to pass to CompleteDeferredSignature with the
+	// signature created externally.
+	State []byte
+}
+
+// deferredWrite tracks the signature left unsigned while writing a document with WriteDeferred.
+type deferredWrite struct {
+	digest    hash.Hash
+	byteRange *core.PdfObjectArray
+}
+
+// deferredSignatureState is the state of a deferred signature, marshalled to JSON.
+type deferredSignatureState struct {
+	ByteRange []int64 `json:"byteRange"`
+	Hash      uint    `json:"hash"`
+	Digest    []byte  `json:"digest"`
+}
+
+// WriteDeferred writes the document to `w` like Write, for a deferred signing of its signature: the
+// Contents reserved by the handler of the signature are left as is, and the digest of the data
+// covered by the ByteRange, computed with `hash`, is returned to be signed externally. The
+// signature is then set with CompleteDeferredSignature, without rewriting the document.
+// The document must have a single new signature, whose handler reserves the Contents on
+// initialization (see sighandler.NewDeferredSignature).
+func (a *PdfAppender) WriteDeferred(w io.Writer, hash crypto.Hash) (*DeferredSignature, error) {
+	if !hash.Available() {
+		return nil, fmt.Errorf("digest algorithm %v not available", hash)
+	}
+	a.deferred = &deferredWrite{digest: hash.New()}
+	defer func() {
+		a.deferred = nil
+	}()
+	if err := a.Write(w); err != nil {
+		return nil, err
+	}
+	if a.deferred.byteRange == nil {
+		return nil, errors.New("no signature to sign")
+	}
+
+	state := deferredSignatureState{
+		Hash:   uint(hash),
+		Digest: a.deferred.digest.Sum(nil),
+	}
+	for _, obj := range a.deferred.byteRange.Elements() {
+		val, err := core.GetNumberAsInt64(obj)
+		if err != nil {
+			return nil, err
+		}
+		state.ByteRange = append(state.ByteRange, val)
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	return &DeferredSignature{
+		Digest: state.Digest,
+		Hash:   hash,
+		State:  data,
+	}, nil
+}
+
+// CompleteDeferredSignature writes the DER-encoded PKCS#7/CMS signature `signature`, created
+// externally, to the Contents of the document `doc` written by PdfAppender.WriteDeferred with the
+// state `state`. Only the Contents are written: the document must be unchanged since it has been
+// written and the signature must fit in the reserved Contents.
+func CompleteDeferredSignature(doc interface {
+	io.ReaderAt
+	io.WriterAt
+}, state []byte, signature []byte) error {
+	var st deferredSignatureState
+	if err := json.Unmarshal(state, &st); err != nil {
+		return fmt.Errorf("invalid deferred signature state: %v", err)
+	}
+	hash := crypto.Hash(st.Hash)
+	if len(st.ByteRange) != 4 || !hash.Available() {
+		return errors.New("invalid deferred signature state")
+	}
+
+	// The data signed must be unchanged.
+	h := hash.New()
+	for i := 0; i < len(st.ByteRange); i += 2 {
+		n, err := io.Copy(h, io.NewSectionReader(doc, st.ByteRange[i], st.ByteRange[i+1]))
+		if err != nil {
+			return err
+		}
+		if n != st.ByteRange[i+1] {
+			return errors.New("document shorter than the signature ByteRange")
+		}
+	}
+	if !bytes.Equal(h.Sum(nil), st.Digest) {
+		return errors.New("document does not match the deferred signature state")
+	}
+
+	gapStart := st.ByteRange[0] + st.ByteRange[1]
+	gap := make([]byte, st.ByteRange[2]-gapStart)
+	if _, err := doc.ReadAt(gap, gapStart); err != nil {
+		return err
+	}
+	if len(gap) < 2 || gap[0] != '<' || gap[len(gap)-1] != '>' {
+		return errors.New("signature Contents placeholder not found")
+	}
+	for _, b := range gap[1 : len(gap)-1] {
+		if b != '0' {
+			return errors.New("signature Contents already set")
+		}
+	}
+
+	encoded := []byte(hex.EncodeToString(signature))
+	if len(encoded) > len(gap)-2 {
+		return fmt.Errorf("signature size %d exceeds the reserved size %d", len(signature), (len(gap)-2)/2)
+	}
+	_, err := doc.WriteAt(encoded, gapStart+1)
+	return err
+}
@@ -0,0 +1,134 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+	"github.com/unidoc/unipdf/v3/model/sighandler"
+)
+
+// writeDeferredTestPdf writes the PDF file `data` with a signature of sub-filter `subFilter` to
+// sign later to `path`.
+func writeDeferredTestPdf(t *testing.T, data []byte, subFilter, path string) *model.DeferredSignature {
+	reader, err := model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	appender, err := model.NewPdfAppender(reader)
+	require.NoError(t, err)
+
+	handler, err := sighandler.NewDeferredSignature(subFilter, 0)
+	require.NoError(t, err)
+	signature := model.NewPdfSignature(handler)
+	signature.SetName("Test Signer")
+	require.NoError(t, signature.Initialize())
+	sigField := model.NewPdfFieldSignature(signature)
+	sigField.T = core.MakeString("Signature1")
+	sigField.Rect = core.MakeArray(core.MakeInteger(0), core.MakeInteger(0), core.MakeInteger(0), core.MakeInteger(0))
+	require.NoError(t, appender.Sign(1, sigField))
+
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	deferred, err := appender.WriteDeferred(f, crypto.SHA256)
+	require.NoError(t, err)
+	return deferred
+}
+
+// completeDeferredTestPdf sets the signature `signature` to the PDF file `path` written by
+// WriteDeferred with `state`.
+func completeDeferredTestPdf(path string, state, signature []byte) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return model.CompleteDeferredSignature(f, state, signature)
+}
+
+func TestAppenderSignDeferred(t *testing.T) {
+	data, err := ioutil.ReadFile(testPdfFile1)
+	require.NoError(t, err)
+	pki := newTestPKI(t, "rsa")
+	roots := x509.NewCertPool()
+	roots.AddCert(pki.caCert)
+
+	pkcs7Signer, err := sighandler.NewAdobePKCS7Detached(pki.key.(*rsa.PrivateKey), pki.cert)
+	require.NoError(t, err)
+	padesSigner, err := sighandler.NewPAdES(pki.key, pki.cert, &sighandler.PAdESOpts{
+		Chain: []*x509.Certificate{pki.caCert},
+	})
+	require.NoError(t, err)
+
+	testcases := []struct {
+		subFilter string
+		signer    model.SignatureHandler
+	}{
+		{"adbe.pkcs7.detached", pkcs7Signer},
+		{"ETSI.CAdES.detached", padesSigner},
+	}
+	dir, err := ioutil.TempDir("", "deferred")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, tcase := range testcases {
+		t.Run(tcase.subFilter, func(t *testing.T) {
+			path := filepath.Join(dir, tcase.subFilter+".pdf")
+			deferred := writeDeferredTestPdf(t, data, tcase.subFilter, path)
+			require.Equal(t, crypto.SHA256, deferred.Hash)
+			require.Len(t, deferred.Digest, crypto.SHA256.Size())
+			unsigned, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+
+			// Signed by the external service.
+			signature, err := tcase.signer.(sighandler.DigestSigner).SignDigest(deferred.Digest, deferred.Hash)
+			require.NoError(t, err)
+
+			// A changed document is not signed.
+			changed := filepath.Join(dir, "changed.pdf")
+			require.NoError(t, ioutil.WriteFile(changed, bytes.Replace(unsigned, []byte("Test Signer"), []byte("Test Forger"), 1), 0644))
+			require.Error(t, completeDeferredTestPdf(changed, deferred.State, signature))
+
+			// The signature must fit in the Contents.
+			require.Error(t, completeDeferredTestPdf(path, deferred.State, make([]byte, 8193)))
+
+			require.NoError(t, completeDeferredTestPdf(path, deferred.State, signature))
+			signed, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+
+			// Only the Contents are written.
+			require.Len(t, signed, len(unsigned))
+			start := bytes.Index(unsigned, []byte("/Contents <")) + len("/Contents <")
+			require.Equal(t, unsigned[:start], signed[:start])
+			end := start + bytes.IndexByte(unsigned[start:], '>')
+			require.Equal(t, unsigned[end:], signed[end:])
+
+			results := validateTestPdfWithOpts(t, signed, &model.SignatureValidationOpts{TrustedRoots: roots})
+			require.Len(t, results, 1)
+			require.Empty(t, results[0].Errors)
+			require.True(t, results[0].IsVerified)
+			require.True(t, results[0].IsTrusted)
+			require.True(t, results[0].CoversWholeFile)
+
+			// The Contents are set once.
+			require.Error(t, completeDeferredTestPdf(path, deferred.State, signature))
+		})
+	}
+
+	// A digest signed with another algorithm is rejected.
+	_, err = padesSigner.(sighandler.DigestSigner).SignDigest(make([]byte, 48), crypto.SHA384)
+	require.Error(t, err)
+}
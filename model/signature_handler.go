@@ -7,12 +7,9 @@ package model
 
 import (
 	"bytes"
+	"crypto/x509"
 	"fmt"
-	"io"
 	"time"
-
-	"github.com/unidoc/unipdf/v3/common"
-	"github.com/unidoc/unipdf/v3/core"
 )
 
 // Hasher is the interface that wraps the basic Write method.
@@ -45,12 +42,37 @@ type SignatureValidationResult struct {
 	Location    string
 	ContactInfo string
 
-	// TODO(gunnsth): Add more fields such as ability to access the certificate information (name, CN, etc).
-	// TODO: Also add flags to indicate whether the signature covers the entire file, or the entire portion of
-	// a revision (if incremental updates used).
-
 	// GeneralizedTime is the time at which the time-stamp token has been created by the TSA (RFC 3161).
 	GeneralizedTime time.Time
+
+	// SignerCertificate is the certificate of the signer, nil if not found.
+	SignerCertificate *x509.Certificate
+
+	// Certificates are the certificates embedded in the signature.
+	Certificates []*x509.Certificate
+
+	// SigningTime is the time at which the certificates have been verified: the time of the
+	// time-stamp token if any, the time of the validation otherwise.
+	SigningTime time.Time
+
+	// Chain is the certificate chain verified from the signer certificate to a trusted root,
+	// nil if not verified.
+	Chain []*x509.Certificate
+
+	// Revision is the index of the revision of the document covered by the signature, -1 if the
+	// signature does not cover a revision.
+	Revision int
+
+	// CoversWholeFile is true if the signature covers the whole file, i.e. the document has not been
+	// updated after the signature.
+	CoversWholeFile bool
+
+	// DocMDPPermission is the DocMDP permission applying to the signature, from its own DocMDP
+	// transform or the certification signature of the document, 0 if none.
+	DocMDPPermission DocMDPPermission
+
+	// Modifications are the changes made to the document after the revision signed.
+	Modifications []SignatureChange
 }
 
 func (v SignatureValidationResult) String() string {
@@ -95,116 +117,22 @@ func (v SignatureValidationResult) String() string {
 	if !v.GeneralizedTime.IsZero() {
 		buf.WriteString(fmt.Sprintf("GeneralizedTime: %s\n", v.GeneralizedTime.String()))
 	}
-	return buf.String()
-}
-
-// ValidateSignatures validates digital signatures in the document.
-func (r *PdfReader) ValidateSignatures(handlers []SignatureHandler) ([]SignatureValidationResult, error) {
-	if r.AcroForm == nil {
-		return nil, nil
+	if v.SignerCertificate != nil {
+		buf.WriteString(fmt.Sprintf("Signer: %s\n", v.SignerCertificate.Subject.String()))
 	}
-	if r.AcroForm.Fields == nil {
-		return nil, nil
+	if v.Revision >= 0 {
+		buf.WriteString(fmt.Sprintf("Revision: %d\n", v.Revision))
 	}
-	type sigFieldPair struct {
-		sig     *PdfSignature
-		field   *PdfField
-		handler SignatureHandler
+	if v.CoversWholeFile {
+		buf.WriteString("Coverage: Signature covers the whole file\n")
+	} else {
+		buf.WriteString(fmt.Sprintf("Coverage: Document modified after signing (%d changes)\n", len(v.Modifications)))
 	}
-
-	var pairs []*sigFieldPair
-	for _, f := range r.AcroForm.AllFields() {
-		if f.V == nil {
-			continue
-		}
-		if d, found := core.GetDict(f.V); found {
-			if name, ok := core.GetNameVal(d.Get("Type")); ok && name == "Sig" {
-				ind, found := core.GetIndirect(f.V)
-				if !found {
-					common.Log.Debug("ERROR: Signature container is nil")
-					return nil, ErrTypeCheck
-				}
-
-				sig, err := r.newPdfSignatureFromIndirect(ind)
-				if err != nil {
-					return nil, err
-				}
-
-				// Search for an appropriate handler.
-				var sigHandler SignatureHandler
-				for _, handler := range handlers {
-					if handler.IsApplicable(sig) {
-						sigHandler = handler
-						break
-					}
-				}
-
-				pairs = append(pairs, &sigFieldPair{
-					sig:     sig,
-					field:   f,
-					handler: sigHandler,
-				})
-			}
-		}
+	if v.DocMDPPermission > 0 {
+		buf.WriteString(fmt.Sprintf("DocMDP permission: %d\n", v.DocMDPPermission))
 	}
-
-	var results []SignatureValidationResult
-	for _, pair := range pairs {
-		defaultResult := SignatureValidationResult{
-			IsSigned: true,
-			Fields:   []*PdfField{pair.field},
-		}
-		if pair.handler == nil {
-			defaultResult.Errors = append(defaultResult.Errors, "handler not set")
-			results = append(results, defaultResult)
-			continue
-		}
-		digest, err := pair.handler.NewDigest(pair.sig)
-		if err != nil {
-			defaultResult.Errors = append(defaultResult.Errors, "digest error", err.Error())
-			results = append(results, defaultResult)
-			continue
-		}
-		byteRange := pair.sig.ByteRange
-		if byteRange == nil {
-			defaultResult.Errors = append(defaultResult.Errors, "ByteRange not set")
-			results = append(results, defaultResult)
-			continue
-		}
-
-		for i := 0; i < byteRange.Len(); i = i + 2 {
-			start, _ := core.GetNumberAsInt64(byteRange.Get(i))
-			ln, _ := core.GetIntVal(byteRange.Get(i + 1))
-			if _, err := r.rs.Seek(start, io.SeekStart); err != nil {
-				return nil, err
-			}
-			data := make([]byte, ln)
-			if _, err := r.rs.Read(data); err != nil {
-				return nil, err
-			}
-			digest.Write(data)
-		}
-
-		result, err := pair.handler.Validate(pair.sig, digest)
-		if err != nil {
-			return nil, err
-		}
-
-		result.Name = pair.sig.Name.Decoded()
-		result.Reason = pair.sig.Reason.Decoded()
-		if pair.sig.M != nil {
-			sigDate, err := NewPdfDate(pair.sig.M.String())
-			if err != nil {
-				result.Errors = append(result.Errors, err.Error())
-				continue
-			}
-			result.Date = sigDate
-		}
-		result.ContactInfo = pair.sig.ContactInfo.Decoded()
-		result.Location = pair.sig.Location.Decoded()
-
-		result.Fields = defaultResult.Fields
-		results = append(results, result)
+	for _, err := range v.Errors {
+		buf.WriteString(fmt.Sprintf("Error: %s\n", err))
 	}
-	return results, nil
+	return buf.String()
 }
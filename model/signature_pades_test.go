@@ -0,0 +1,401 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+	"github.com/unidoc/unipdf/v3/model/sighandler"
+)
+
+// testPKI contains certificates generated for the signature tests, valid at the time of the test.
+type testPKI struct {
+	caKey   crypto.Signer
+	caCert  *x509.Certificate
+	key     crypto.Signer
+	cert    *x509.Certificate
+	tsaKey  crypto.Signer
+	tsaCert *x509.Certificate
+}
+
+// newTestCertificate returns a certificate for `key` issued from `template` by `issuer` with
+// `issuerKey`, or self-signed if `issuer` is nil.
+func newTestCertificate(t *testing.T, template *x509.Certificate, key crypto.Signer,
+	issuer *x509.Certificate, issuerKey crypto.Signer) *x509.Certificate {
+	if template.SerialNumber == nil {
+		serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+		require.NoError(t, err)
+		template.SerialNumber = serial
+	}
+	if template.NotBefore.IsZero() {
+		template.NotBefore = time.Now().Add(-time.Hour)
+		template.NotAfter = time.Now().Add(24 * time.Hour)
+	}
+	if issuer == nil {
+		issuer, issuerKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, key.Public(), issuerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+// newTestPKI returns a CA with a signing certificate for a key of type `keyType` ("rsa" or
+// "ecdsa") and a time-stamping certificate.
+func newTestPKI(t *testing.T, keyType string) *testPKI {
+	newKey := func(keyType string) crypto.Signer {
+		if keyType == "ecdsa" {
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			require.NoError(t, err)
+			return key
+		}
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		return key
+	}
+
+	pki := &testPKI{caKey: newKey("rsa"), key: newKey(keyType), tsaKey: newKey("rsa")}
+	pki.caCert = newTestCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "UniPDF Test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}, pki.caKey, nil, nil)
+	pki.cert = newTestCertificate(t, &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "UniPDF Test Signer"},
+		KeyUsage: x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment,
+	}, pki.key, pki.caCert, pki.caKey)
+	pki.tsaCert = newTestCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "UniPDF Test TSA"},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}, pki.tsaKey, pki.caCert, pki.caKey)
+	return pki
+}
+
+// signTestPdf signs the first page of the PDF file `data` with `handler` and returns the signed
+// file.
+func signTestPdf(t *testing.T, data []byte, handler model.SignatureHandler) []byte {
+	return signTestPdfField(t, data, handler, "Signature1")
+}
+
+// signTestPdfField signs the first page of the PDF file `data` with `handler` in a new signature
+// field named `name` and returns the signed file.
+func signTestPdfField(t *testing.T, data []byte, handler model.SignatureHandler, name string) []byte {
+	return signTestPdfFieldWith(t, data, handler, name, nil)
+}
+
+// signTestPdfFieldWith is signTestPdfField with the signature and its field set up by `setup`
+// before signing, if not nil.
+func signTestPdfFieldWith(t *testing.T, data []byte, handler model.SignatureHandler, name string,
+	setup func(sig *model.PdfSignature, field *model.PdfFieldSignature)) []byte {
+	reader, err := model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	appender, err := model.NewPdfAppender(reader)
+	require.NoError(t, err)
+
+	signature := model.NewPdfSignature(handler)
+	signature.SetName("Test Signer")
+	signature.SetReason("Test")
+	signature.SetDate(time.Now(), "")
+	require.NoError(t, signature.Initialize())
+
+	sigField := model.NewPdfFieldSignature(signature)
+	sigField.T = core.MakeString(name)
+	sigField.Rect = core.MakeArray(core.MakeInteger(0), core.MakeInteger(0), core.MakeInteger(0), core.MakeInteger(0))
+	if setup != nil {
+		setup(signature, sigField)
+	}
+	require.NoError(t, appender.Sign(1, sigField))
+
+	var buf bytes.Buffer
+	require.NoError(t, appender.Write(&buf))
+	return buf.Bytes()
+}
+
+// validateTestPdf returns the validation results of the signatures of the PDF file `data`.
+func validateTestPdf(t *testing.T, data []byte) []model.SignatureValidationResult {
+	return validateTestPdfWithOpts(t, data, nil)
+}
+
+// validateTestPdfWithOpts returns the validation results of the signatures of the PDF file
+// `data` with the options `opts`.
+func validateTestPdfWithOpts(t *testing.T, data []byte, opts *model.SignatureValidationOpts) []model.SignatureValidationResult {
+	reader, err := model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	padesHandler, err := sighandler.NewPAdES(nil, nil, nil)
+	require.NoError(t, err)
+	pkcs7Handler, err := sighandler.NewAdobePKCS7Detached(nil, nil)
+	require.NoError(t, err)
+	timestampHandler, err := sighandler.NewDocTimeStamp("", 0)
+	require.NoError(t, err)
+
+	handlers := []model.SignatureHandler{padesHandler, pkcs7Handler, timestampHandler}
+	results, err := reader.ValidateSignaturesWithOpts(handlers, opts)
+	require.NoError(t, err)
+	return results
+}
+
+func TestAppenderSignPAdES(t *testing.T) {
+	data, err := ioutil.ReadFile(testPdfFile1)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name    string
+		keyType string
+		hash    crypto.Hash
+	}{
+		{"rsa-sha256", "rsa", crypto.SHA256},
+		{"rsa-sha384", "rsa", crypto.SHA384},
+		{"ecdsa-sha512", "ecdsa", crypto.SHA512},
+	}
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			pki := newTestPKI(t, tcase.keyType)
+			handler, err := sighandler.NewPAdES(pki.key, pki.cert, &sighandler.PAdESOpts{
+				Hash:  tcase.hash,
+				Chain: []*x509.Certificate{pki.caCert},
+			})
+			require.NoError(t, err)
+
+			signed := signTestPdf(t, data, handler)
+			require.Contains(t, string(signed), "/SubFilter /ETSI.CAdES.detached")
+
+			results := validateTestPdf(t, signed)
+			require.Len(t, results, 1)
+			require.True(t, results[0].IsSigned)
+			require.True(t, results[0].IsVerified, "%v", results[0].Errors)
+			require.True(t, results[0].GeneralizedTime.IsZero())
+			require.Equal(t, "Test Signer", results[0].Name)
+
+			// Altering the signed data invalidates the signature.
+			tampered := bytes.Replace(signed, []byte("/Reason (Test)"), []byte("/Reason (Tost)"), 1)
+			require.NotEqual(t, signed, tampered)
+			results = validateTestPdf(t, tampered)
+			require.Len(t, results, 1)
+			require.False(t, results[0].IsVerified)
+			require.NotEmpty(t, results[0].Errors)
+		})
+	}
+}
+
+func TestAppenderSignPAdESTimestamp(t *testing.T) {
+	data, err := ioutil.ReadFile(testPdfFile1)
+	require.NoError(t, err)
+
+	pki := newTestPKI(t, "rsa")
+	tsa := sighandler.NewLocalTimestampAuthority(pki.tsaCert, pki.tsaKey)
+	tsaTime := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	tsa.Now = func() time.Time { return tsaTime }
+
+	// The time-stamp authority is required from level B-T.
+	_, err = sighandler.NewPAdES(pki.key, pki.cert, &sighandler.PAdESOpts{Level: sighandler.PAdESBaselineT})
+	require.Error(t, err)
+
+	handler, err := sighandler.NewPAdES(pki.key, pki.cert, &sighandler.PAdESOpts{
+		Level: sighandler.PAdESBaselineT,
+		TSA:   tsa,
+	})
+	require.NoError(t, err)
+
+	signed := signTestPdf(t, data, handler)
+	results := validateTestPdf(t, signed)
+	require.Len(t, results, 1)
+	require.True(t, results[0].IsVerified, "%v", results[0].Errors)
+	require.True(t, tsaTime.Equal(results[0].GeneralizedTime), "%v", results[0].GeneralizedTime)
+
+	// The reserved size must hold the signature.
+	handler, err = sighandler.NewPAdES(pki.key, pki.cert, &sighandler.PAdESOpts{
+		Level:         sighandler.PAdESBaselineT,
+		TSA:           tsa,
+		SignatureSize: 1024,
+	})
+	require.NoError(t, err)
+	reader, err := model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	appender, err := model.NewPdfAppender(reader)
+	require.NoError(t, err)
+	signature := model.NewPdfSignature(handler)
+	require.NoError(t, signature.Initialize())
+	require.NoError(t, appender.Sign(1, model.NewPdfFieldSignature(signature)))
+	require.Error(t, appender.Write(&bytes.Buffer{}))
+}
+
+func TestAppenderDocTimeStampLocalAuthority(t *testing.T) {
+	data, err := ioutil.ReadFile(testPdfFile1)
+	require.NoError(t, err)
+
+	pki := newTestPKI(t, "rsa")
+	handler, err := sighandler.NewDocTimeStampWithAuthority(
+		sighandler.NewLocalTimestampAuthority(pki.tsaCert, pki.tsaKey), crypto.SHA256)
+	require.NoError(t, err)
+
+	signed := signTestPdf(t, data, handler)
+	require.Contains(t, string(signed), "/Type /DocTimeStamp")
+
+	results := validateTestPdf(t, signed)
+	require.Len(t, results, 1)
+	require.True(t, results[0].IsVerified, "%v", results[0].Errors)
+	require.False(t, results[0].GeneralizedTime.IsZero())
+}
+
+// writeRevocationFiles writes a CRL of the test CA and an OCSP response for each certificate of
+// `certs` to `dir`, as fetched from the distribution points by the HTTP fetchers.
+func writeRevocationFiles(t *testing.T, pki *testPKI, dir string, certs ...*x509.Certificate) {
+	now := time.Now()
+	crl, err := pki.caCert.CreateCRL(rand.Reader, pki.caKey, nil, now, now.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ca.crl"), crl, 0644))
+
+	for _, cert := range certs {
+		resp, err := ocsp.CreateResponse(pki.caCert, pki.caCert, ocsp.Response{
+			Status:       ocsp.Good,
+			SerialNumber: cert.SerialNumber,
+			ThisUpdate:   now,
+			NextUpdate:   now.Add(24 * time.Hour),
+		}, pki.caKey)
+		require.NoError(t, err)
+		name := fmt.Sprintf("%s.ocsp", cert.SerialNumber)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), resp, 0644))
+	}
+}
+
+func TestAppenderPAdESLTV(t *testing.T) {
+	data, err := ioutil.ReadFile(testPdfFile1)
+	require.NoError(t, err)
+
+	pki := newTestPKI(t, "rsa")
+	tsa := sighandler.NewLocalTimestampAuthority(pki.tsaCert, pki.tsaKey)
+	handler, err := sighandler.NewPAdES(pki.key, pki.cert, &sighandler.PAdESOpts{
+		Level: sighandler.PAdESBaselineLTA,
+		TSA:   tsa,
+	})
+	require.NoError(t, err)
+	signed := signTestPdf(t, data, handler)
+
+	dir, err := ioutil.TempDir("", "unipdf-ltv")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeRevocationFiles(t, pki, dir, pki.cert, pki.tsaCert)
+
+	// Fetchers supplying the revocation information from the files.
+	var fetched []string
+	opts := &sighandler.LTVOpts{
+		// The CA certificate is not embedded in the signature.
+		Certificates: []*x509.Certificate{pki.caCert},
+		CRLFetcher: sighandler.CRLFetcherFunc(func(cert, issuer *x509.Certificate) ([]byte, error) {
+			require.True(t, issuer.Equal(pki.caCert))
+			fetched = append(fetched, "crl "+cert.Subject.CommonName)
+			return ioutil.ReadFile(filepath.Join(dir, "ca.crl"))
+		}),
+		OCSPFetcher: sighandler.OCSPFetcherFunc(func(cert, issuer *x509.Certificate) ([]byte, error) {
+			fetched = append(fetched, "ocsp "+cert.Subject.CommonName)
+			return ioutil.ReadFile(filepath.Join(dir, fmt.Sprintf("%s.ocsp", cert.SerialNumber)))
+		}),
+	}
+
+	// B-LT: the validation material in an incremental update.
+	reader, err := model.NewPdfReader(bytes.NewReader(signed))
+	require.NoError(t, err)
+	appender, err := model.NewPdfAppender(reader)
+	require.NoError(t, err)
+	require.NoError(t, sighandler.AddLTV(appender, opts))
+	var buf bytes.Buffer
+	require.NoError(t, appender.Write(&buf))
+	ltv := buf.Bytes()
+	require.Equal(t, signed, ltv[:len(signed)])
+	require.ElementsMatch(t, []string{
+		"ocsp UniPDF Test Signer", "crl UniPDF Test Signer",
+		"ocsp UniPDF Test TSA", "crl UniPDF Test TSA",
+	}, fetched)
+
+	reader, err = model.NewPdfReader(bytes.NewReader(ltv))
+	require.NoError(t, err)
+	dss, err := reader.GetDSS()
+	require.NoError(t, err)
+	require.NotNil(t, dss)
+	certs, err := dss.GetCerts()
+	require.NoError(t, err)
+	require.ElementsMatch(t, [][]byte{pki.cert.Raw, pki.caCert.Raw, pki.tsaCert.Raw}, certs)
+	require.Len(t, dss.OCSPs, 2)
+	require.Len(t, dss.CRLs, 1)
+
+	sigField, ok := reader.AcroForm.AllFields()[0].GetContext().(*model.PdfFieldSignature)
+	require.True(t, ok)
+	vri := dss.VRI[model.VRIKey(sigField.V)]
+	require.NotNil(t, vri)
+	require.Len(t, vri.Cert, 3)
+	require.Len(t, vri.OCSP, 2)
+	require.Len(t, vri.CRL, 1)
+
+	results := validateTestPdf(t, ltv)
+	require.Len(t, results, 1)
+	require.True(t, results[0].IsVerified, "%v", results[0].Errors)
+
+	// B-LTA: a document time-stamp covering the validation material, in the same revision.
+	reader, err = model.NewPdfReader(bytes.NewReader(signed))
+	require.NoError(t, err)
+	appender, err = model.NewPdfAppender(reader)
+	require.NoError(t, err)
+	require.NoError(t, sighandler.AddLTV(appender, opts))
+	timestampHandler, err := sighandler.NewDocTimeStampWithAuthority(tsa, crypto.SHA256)
+	require.NoError(t, err)
+	signature := model.NewPdfSignature(timestampHandler)
+	require.NoError(t, signature.Initialize())
+	sigField = model.NewPdfFieldSignature(signature)
+	sigField.T = core.MakeString("Timestamp")
+	require.NoError(t, appender.Sign(1, sigField))
+	buf.Reset()
+	require.NoError(t, appender.Write(&buf))
+	lta := buf.Bytes()
+
+	results = validateTestPdf(t, lta)
+	require.Len(t, results, 2)
+	for _, result := range results {
+		require.True(t, result.IsVerified, "%v", result.Errors)
+		require.False(t, result.GeneralizedTime.IsZero())
+	}
+	reader, err = model.NewPdfReader(bytes.NewReader(lta))
+	require.NoError(t, err)
+	dss, err = reader.GetDSS()
+	require.NoError(t, err)
+	require.Len(t, dss.VRI, 1)
+
+	// The validation material of the document time-stamp in a further revision keeps the store.
+	appender, err = model.NewPdfAppender(reader)
+	require.NoError(t, err)
+	require.NoError(t, sighandler.AddLTV(appender, opts))
+	buf.Reset()
+	require.NoError(t, appender.Write(&buf))
+	reader, err = model.NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	dss, err = reader.GetDSS()
+	require.NoError(t, err)
+	require.Len(t, dss.VRI, 2)
+	require.Len(t, dss.Certs, 3)
+	require.Len(t, validateTestPdf(t, buf.Bytes()), 2)
+}
@@ -0,0 +1,420 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// SignatureValidationOpts contains the options of the validation of the signatures of a document.
+type SignatureValidationOpts struct {
+	// TrustedRoots are the trusted root certificates. The certificate chains of the signers are
+	// only built and verified when set, and the signatures are not trusted otherwise.
+	TrustedRoots *x509.CertPool
+
+	// Intermediates are additional intermediate certificates used to build the certificate chains,
+	// along with the certificates embedded in the signatures and the document security store.
+	Intermediates []*x509.Certificate
+}
+
+// maxRevisionEndGap is the maximum number of whitespace bytes between the end of the data signed
+// and the end of a revision for the signature to cover the revision.
+const maxRevisionEndGap = 16
+
+// ValidateSignatures validates digital signatures in the document.
+// The certificate chains of the signers are not verified, see ValidateSignaturesWithOpts.
+func (r *PdfReader) ValidateSignatures(handlers []SignatureHandler) ([]SignatureValidationResult, error) {
+	return r.ValidateSignaturesWithOpts(handlers, nil)
+}
+
+// ValidateSignaturesWithOpts validates the digital signatures of the document with `handlers`,
+// returning one result per signature. Besides the cryptographic validation of the signatures by
+// the handlers, it checks the ByteRange of the signatures, the revision they cover, the
+// certificate chains of the signers against the trusted roots of `opts` at the time of signing
+// and the changes made to the document after the signatures, according to their DocMDP and
+// FieldMDP transforms. The problems found are reported in the Errors of the results.
+func (r *PdfReader) ValidateSignaturesWithOpts(handlers []SignatureHandler, opts *SignatureValidationOpts) ([]SignatureValidationResult, error) {
+	if r.AcroForm == nil {
+		return nil, nil
+	}
+	if r.AcroForm.Fields == nil {
+		return nil, nil
+	}
+	type sigFieldPair struct {
+		sig     *PdfSignature
+		field   *PdfField
+		handler SignatureHandler
+	}
+
+	var pairs []*sigFieldPair
+	for _, f := range r.AcroForm.AllFields() {
+		if f.V == nil {
+			continue
+		}
+		if d, found := core.GetDict(f.V); found {
+			if name, ok := core.GetNameVal(d.Get("Type")); ok && (name == "Sig" || name == "DocTimeStamp") {
+				ind, found := core.GetIndirect(f.V)
+				if !found {
+					r.log().Debug("ERROR: Signature container is nil")
+					return nil, ErrTypeCheck
+				}
+
+				sig, err := r.newPdfSignatureFromIndirect(ind)
+				if err != nil {
+					return nil, err
+				}
+
+				// Search for an appropriate handler.
+				var sigHandler SignatureHandler
+				for _, handler := range handlers {
+					if handler.IsApplicable(sig) {
+						sigHandler = handler
+						break
+					}
+				}
+
+				pairs = append(pairs, &sigFieldPair{
+					sig:     sig,
+					field:   f,
+					handler: sigHandler,
+				})
+			}
+		}
+	}
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+
+	size, err := r.rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	v := &signatureValidator{
+		reader:        r,
+		opts:          opts,
+		size:          size,
+		intermediates: r.dssCertificates(),
+		certification: r.certificationPermission(),
+	}
+	if opts != nil {
+		v.intermediates = append(v.intermediates, opts.Intermediates...)
+	}
+
+	var results []SignatureValidationResult
+	for _, pair := range pairs {
+		results = append(results, v.validate(pair.sig, pair.field, pair.handler))
+	}
+	return results, nil
+}
+
+// signatureValidator validates the signatures of a document.
+type signatureValidator struct {
+	reader *PdfReader
+	opts   *SignatureValidationOpts
+
+	// size is the size of the document.
+	size int64
+
+	// intermediates are the certificates of the document security store and of the options.
+	intermediates []*x509.Certificate
+
+	// certification is the DocMDP permission of the certification signature of the document,
+	// 0 if not certified.
+	certification DocMDPPermission
+}
+
+// validate validates signature `sig` of `field` with `handler`.
+func (v *signatureValidator) validate(sig *PdfSignature, field *PdfField, handler SignatureHandler) SignatureValidationResult {
+	result := SignatureValidationResult{
+		IsSigned: true,
+		Revision: -1,
+	}
+	if handler == nil {
+		result.Errors = append(result.Errors, "handler not set")
+		v.setInfo(&result, sig, field)
+		return result
+	}
+	digest, err := handler.NewDigest(sig)
+	if err != nil {
+		result.Errors = append(result.Errors, "digest error", err.Error())
+		v.setInfo(&result, sig, field)
+		return result
+	}
+	signedEnd, err := v.digestByteRange(sig, digest)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		v.setInfo(&result, sig, field)
+		return result
+	}
+
+	res, err := handler.Validate(sig, digest)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("signature verification failed: %v", err))
+	} else {
+		result = res
+		result.Revision = -1
+	}
+	v.setInfo(&result, sig, field)
+
+	result.SigningTime = result.GeneralizedTime
+	if result.SigningTime.IsZero() {
+		result.SigningTime = time.Now()
+	}
+	v.verifyCertificates(&result, sig)
+	v.checkCoverage(&result, sig, field, signedEnd)
+	return result
+}
+
+// setInfo sets the information of the signature dictionary `sig` of `field` to `result`.
+func (v *signatureValidator) setInfo(result *SignatureValidationResult, sig *PdfSignature, field *PdfField) {
+	result.Fields = []*PdfField{field}
+	result.Name = sig.Name.Decoded()
+	result.Reason = sig.Reason.Decoded()
+	result.ContactInfo = sig.ContactInfo.Decoded()
+	result.Location = sig.Location.Decoded()
+	if sig.M != nil {
+		sigDate, err := NewPdfDate(sig.M.String())
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		} else {
+			result.Date = sigDate
+		}
+	}
+}
+
+// digestByteRange checks the ByteRange of signature `sig` and writes the data it covers to
+// `digest`. The ranges must be ascending, start at the beginning of the file and end within it,
+// and the gaps between them must be hexadecimal strings, one of which being the Contents of the
+// signature. It returns the offset of the end of the data signed.
+func (v *signatureValidator) digestByteRange(sig *PdfSignature, digest Hasher) (int64, error) {
+	if sig.ByteRange == nil {
+		return 0, errors.New("ByteRange not set")
+	}
+	n := sig.ByteRange.Len()
+	if n < 4 || n%2 != 0 {
+		return 0, fmt.Errorf("invalid ByteRange length %d", n)
+	}
+	ranges := make([]int64, n)
+	for i, obj := range sig.ByteRange.Elements() {
+		val, err := core.GetNumberAsInt64(obj)
+		if err != nil || val < 0 {
+			return 0, fmt.Errorf("invalid ByteRange value %s", obj)
+		}
+		ranges[i] = val
+	}
+	if ranges[0] != 0 {
+		return 0, errors.New("ByteRange does not start at the beginning of the file")
+	}
+
+	contentsFound := false
+	for i := 0; i < n; i += 2 {
+		if ranges[i]+ranges[i+1] > v.size {
+			return 0, errors.New("ByteRange exceeds the file size")
+		}
+		if i == 0 {
+			continue
+		}
+		gapStart := ranges[i-2] + ranges[i-1]
+		if ranges[i] <= gapStart {
+			return 0, errors.New("ByteRange ranges not ascending or not separated by the signature contents")
+		}
+		gap, err := v.reader.parser.ReadBytesAt(gapStart, ranges[i]-gapStart)
+		if err != nil {
+			return 0, err
+		}
+		contents, err := decodeContentsGap(gap)
+		if err != nil {
+			return 0, err
+		}
+		if sig.Contents != nil && bytes.Equal(contents, sig.Contents.Bytes()) {
+			contentsFound = true
+		}
+	}
+	if !contentsFound {
+		return 0, errors.New("signature Contents not excluded by the ByteRange")
+	}
+
+	for i := 0; i < n; i += 2 {
+		data, err := v.reader.parser.ReadBytesAt(ranges[i], ranges[i+1])
+		if err != nil {
+			return 0, err
+		}
+		if _, err := digest.Write(data); err != nil {
+			return 0, err
+		}
+	}
+	return ranges[n-2] + ranges[n-1], nil
+}
+
+// decodeContentsGap decodes the hexadecimal string `gap` excluded from the data signed.
+func decodeContentsGap(gap []byte) ([]byte, error) {
+	if len(gap) < 2 || gap[0] != '<' || gap[len(gap)-1] != '>' {
+		return nil, errors.New("ByteRange gap is not a hexadecimal string")
+	}
+	digits := make([]byte, 0, len(gap)-2)
+	for _, b := range gap[1 : len(gap)-1] {
+		if !core.IsWhiteSpace(b) {
+			digits = append(digits, b)
+		}
+	}
+	contents := make([]byte, hex.DecodedLen(len(digits)))
+	if _, err := hex.Decode(contents, digits); err != nil {
+		return nil, errors.New("ByteRange gap is not a hexadecimal string")
+	}
+	return contents, nil
+}
+
+// verifyCertificates checks the key usage of the signer certificate of `result` and, if trusted
+// roots are set, verifies its certificate chain at the signing time.
+func (v *signatureValidator) verifyCertificates(result *SignatureValidationResult, sig *PdfSignature) {
+	cert := result.SignerCertificate
+	if cert == nil {
+		return
+	}
+	keyUsageValid := true
+	if cert.KeyUsage != 0 && cert.KeyUsage&(x509.KeyUsageDigitalSignature|x509.KeyUsageContentCommitment) == 0 {
+		keyUsageValid = false
+		result.Errors = append(result.Errors, "signer certificate key usage does not allow signatures")
+	}
+	if v.opts == nil || v.opts.TrustedRoots == nil {
+		return
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range result.Certificates {
+		intermediates.AddCert(c)
+	}
+	for _, c := range v.intermediates {
+		intermediates.AddCert(c)
+	}
+	usage := x509.ExtKeyUsageAny
+	if isDocTimeStamp(sig) {
+		usage = x509.ExtKeyUsageTimeStamping
+	}
+	chains, err := cert.Verify(x509.VerifyOptions{
+		Roots:         v.opts.TrustedRoots,
+		Intermediates: intermediates,
+		CurrentTime:   result.SigningTime,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	})
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("certificate chain verification failed: %v", err))
+		return
+	}
+	result.Chain = chains[0]
+	result.IsTrusted = keyUsageValid
+}
+
+// isDocTimeStamp returns true if `sig` is a document time-stamp.
+func isDocTimeStamp(sig *PdfSignature) bool {
+	return (sig.Type != nil && *sig.Type == "DocTimeStamp") ||
+		(sig.SubFilter != nil && *sig.SubFilter == "ETSI.RFC3161")
+}
+
+// checkCoverage determines the revision covered by signature `sig` of `field`, whose data signed
+// ends at `signedEnd`, and checks the changes made to the document by the following revisions.
+func (v *signatureValidator) checkCoverage(result *SignatureValidationResult, sig *PdfSignature, field *PdfField,
+	signedEnd int64) {
+	result.CoversWholeFile = v.sameOffset(signedEnd, v.size)
+	for i, rev := range v.reader.GetRevisions() {
+		if v.sameOffset(signedEnd, rev.EndOffset) {
+			result.Revision = i
+			break
+		}
+	}
+	if result.Revision < 0 {
+		result.Errors = append(result.Errors, "signed data does not end at the end of a revision")
+		return
+	}
+
+	perm, locks := signatureTransforms(sig.Reference)
+	if sigField, ok := field.GetContext().(*PdfFieldSignature); ok && sigField.Lock != nil && len(locks) == 0 {
+		// Lock of a field signed without the FieldMDP transform.
+		if lock, ok := core.GetDict(sigField.Lock); ok {
+			locks = append(locks, newFieldLock(lock))
+		}
+	}
+	if v.certification != 0 && (perm == 0 || v.certification < perm) {
+		perm = v.certification
+	}
+	result.DocMDPPermission = perm
+	if perm == 0 {
+		perm = DocMDPAnnotate
+	}
+	changes, err := v.reader.signatureChanges(result.Revision, perm, locks)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("cannot check the changes after the signature: %v", err))
+		return
+	}
+	result.Modifications = changes
+	for _, change := range changes {
+		if !change.Permitted {
+			result.Errors = append(result.Errors, fmt.Sprintf("change not permitted after signature: %s", change))
+		}
+	}
+}
+
+// sameOffset returns true if the offsets `a` and `b` only differ by a few whitespace bytes.
+func (v *signatureValidator) sameOffset(a, b int64) bool {
+	if a > b {
+		a, b = b, a
+	}
+	if a == b {
+		return true
+	}
+	if b-a > maxRevisionEndGap || b > v.size {
+		return false
+	}
+	data, err := v.reader.parser.ReadBytesAt(a, b-a)
+	if err != nil {
+		return false
+	}
+	return len(bytes.TrimLeft(data, "\x00\t\n\f\r ")) == 0
+}
+
+// dssCertificates returns the certificates of the document security store of the document.
+func (r *PdfReader) dssCertificates() []*x509.Certificate {
+	dss, err := r.GetDSS()
+	if err != nil || dss == nil {
+		return nil
+	}
+	data, err := dss.GetCerts()
+	if err != nil {
+		return nil
+	}
+	var certs []*x509.Certificate
+	for _, der := range data {
+		if cert, err := x509.ParseCertificate(der); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+	return certs
+}
+
+// certificationPermission returns the DocMDP permission of the certification signature of the
+// document, referenced by the DocMDP entry of the Perms dictionary of the catalog, 0 if the
+// document is not certified.
+func (r *PdfReader) certificationPermission() DocMDPPermission {
+	perms, ok := core.GetDict(core.ResolveReference(r.catalog.Get("Perms")))
+	if !ok {
+		return 0
+	}
+	sigDict, ok := core.GetDict(core.ResolveReference(perms.Get("DocMDP")))
+	if !ok {
+		return 0
+	}
+	refs, _ := core.GetArray(core.ResolveReference(sigDict.Get("Reference")))
+	perm, _ := signatureTransforms(refs)
+	return perm
+}
@@ -0,0 +1,455 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model_test
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+	"github.com/unidoc/unipdf/v3/model/sighandler"
+)
+
+// appendTestRevision appends a revision to the PDF file `data` with the changes made by `update`.
+func appendTestRevision(t *testing.T, data []byte, update func(reader *model.PdfReader, appender *model.PdfAppender)) []byte {
+	reader, err := model.NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	appender, err := model.NewPdfAppender(reader)
+	require.NoError(t, err)
+	update(reader, appender)
+
+	var buf bytes.Buffer
+	require.NoError(t, appender.Write(&buf))
+	return buf.Bytes()
+}
+
+// signTestPAdES signs the PDF file `data` in the signature field `name` with a PAdES B-B signature
+// of the signer of `pki`.
+func signTestPAdES(t *testing.T, data []byte, pki *testPKI, name string) []byte {
+	return signTestPAdESWith(t, data, pki, name, nil)
+}
+
+// signTestPAdESWith is signTestPAdES with the signature and its field set up by `setup`.
+func signTestPAdESWith(t *testing.T, data []byte, pki *testPKI, name string,
+	setup func(sig *model.PdfSignature, field *model.PdfFieldSignature)) []byte {
+	handler, err := sighandler.NewPAdES(pki.key, pki.cert, &sighandler.PAdESOpts{
+		Chain: []*x509.Certificate{pki.caCert},
+	})
+	require.NoError(t, err)
+	return signTestPdfFieldWith(t, data, handler, name, setup)
+}
+
+// fillTestField appends a revision to the PDF file `data` setting the value of the text field
+// `name` to `value`.
+func fillTestField(t *testing.T, data []byte, name, value string) []byte {
+	return appendTestRevision(t, data, func(reader *model.PdfReader, appender *model.PdfAppender) {
+		for _, field := range reader.AcroForm.AllFields() {
+			if fullName, err := field.FullName(); err == nil && fullName == name {
+				field.V = core.MakeString(value)
+				appender.UpdateObject(field.ToPdfObject())
+				return
+			}
+		}
+		t.Fatalf("field %q not found", name)
+	})
+}
+
+// changeKinds returns the kinds of the changes of `result` and whether they are permitted.
+func changeKinds(result model.SignatureValidationResult) map[model.SignatureChangeKind]bool {
+	kinds := map[model.SignatureChangeKind]bool{}
+	for _, change := range result.Modifications {
+		permitted, found := kinds[change.Kind]
+		kinds[change.Kind] = change.Permitted && (permitted || !found)
+	}
+	return kinds
+}
+
+func TestValidateSignaturesTrust(t *testing.T) {
+	data, err := ioutil.ReadFile(testPdfFile1)
+	require.NoError(t, err)
+	pki := newTestPKI(t, "rsa")
+	signed := signTestPAdES(t, data, pki, "Signature1")
+
+	roots := x509.NewCertPool()
+	roots.AddCert(pki.caCert)
+	results := validateTestPdfWithOpts(t, signed, &model.SignatureValidationOpts{TrustedRoots: roots})
+	require.Len(t, results, 1)
+	result := results[0]
+	require.Empty(t, result.Errors)
+	require.True(t, result.IsVerified)
+	require.True(t, result.IsTrusted)
+	require.Equal(t, pki.cert.Raw, result.SignerCertificate.Raw)
+	require.Len(t, result.Chain, 2)
+	require.Equal(t, pki.caCert.Raw, result.Chain[1].Raw)
+	require.Equal(t, 1, result.Revision)
+	require.True(t, result.CoversWholeFile)
+	require.Empty(t, result.Modifications)
+
+	// Without trusted roots, the chain is not verified.
+	results = validateTestPdf(t, signed)
+	require.Empty(t, results[0].Errors)
+	require.True(t, results[0].IsVerified)
+	require.False(t, results[0].IsTrusted)
+	require.Nil(t, results[0].Chain)
+
+	// Signer issued by an untrusted CA.
+	other := newTestPKI(t, "rsa")
+	roots = x509.NewCertPool()
+	roots.AddCert(other.caCert)
+	results = validateTestPdfWithOpts(t, signed, &model.SignatureValidationOpts{TrustedRoots: roots})
+	require.True(t, results[0].IsVerified)
+	require.False(t, results[0].IsTrusted)
+	require.Len(t, results[0].Errors, 1)
+	require.Contains(t, results[0].Errors[0], "certificate chain")
+}
+
+func TestValidateSignaturesSigningTime(t *testing.T) {
+	data, err := ioutil.ReadFile(testPdfFile1)
+	require.NoError(t, err)
+
+	// The signer certificate has expired an hour ago and was valid at the time of the time-stamp.
+	pki := newTestPKI(t, "rsa")
+	now := time.Now()
+	pki.caCert = newTestCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "UniPDF Test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		NotBefore:             now.Add(-4 * time.Hour),
+		NotAfter:              now.Add(time.Hour),
+	}, pki.caKey, nil, nil)
+	pki.cert = newTestCertificate(t, &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "UniPDF Test Signer"},
+		KeyUsage:  x509.KeyUsageDigitalSignature,
+		NotBefore: now.Add(-3 * time.Hour),
+		NotAfter:  now.Add(-time.Hour),
+	}, pki.key, pki.caCert, pki.caKey)
+	tsa := sighandler.NewLocalTimestampAuthority(pki.tsaCert, pki.tsaKey)
+	tsa.Now = func() time.Time { return now.Add(-2 * time.Hour) }
+
+	roots := x509.NewCertPool()
+	roots.AddCert(pki.caCert)
+	opts := &model.SignatureValidationOpts{TrustedRoots: roots}
+
+	handler, err := sighandler.NewPAdES(pki.key, pki.cert, &sighandler.PAdESOpts{
+		Level: sighandler.PAdESBaselineT,
+		TSA:   tsa,
+	})
+	require.NoError(t, err)
+	results := validateTestPdfWithOpts(t, signTestPdf(t, data, handler), opts)
+	require.Len(t, results, 1)
+	require.Empty(t, results[0].Errors)
+	require.True(t, results[0].IsTrusted)
+	require.True(t, results[0].SigningTime.Equal(results[0].GeneralizedTime))
+
+	// Without time-stamp, the certificate is verified at the current time.
+	results = validateTestPdfWithOpts(t, signTestPAdES(t, data, pki, "Signature1"), opts)
+	require.True(t, results[0].IsVerified)
+	require.False(t, results[0].IsTrusted)
+	require.Len(t, results[0].Errors, 1)
+	require.Contains(t, results[0].Errors[0], "expired")
+}
+
+func TestValidateSignaturesByteRange(t *testing.T) {
+	data, err := ioutil.ReadFile(testPdfFile1)
+	require.NoError(t, err)
+	signed := signTestPAdES(t, data, newTestPKI(t, "rsa"), "Signature1")
+
+	byteRangeRe := regexp.MustCompile(`/ByteRange \[(\d+) (\d+) (\d+) (\d+)\] *`)
+	loc := byteRangeRe.FindSubmatchIndex(signed)
+	require.NotNil(t, loc)
+	var ranges [4]int
+	for i := range ranges {
+		ranges[i], err = strconv.Atoi(string(signed[loc[2+2*i]:loc[3+2*i]]))
+		require.NoError(t, err)
+	}
+	require.Equal(t, 0, ranges[0])
+	require.Equal(t, len(signed), ranges[2]+ranges[3])
+
+	testcases := []struct {
+		name     string
+		ranges   [4]int
+		expected string
+	}{
+		{"not at start", [4]int{1, ranges[1] - 1, ranges[2], ranges[3]}, "beginning of the file"},
+		{"beyond end", [4]int{0, ranges[1], ranges[2], ranges[3] + 10}, "file size"},
+		{"overlap", [4]int{0, ranges[1], ranges[1] - 10, ranges[3] + 10}, "not ascending"},
+		{"gap not contents", [4]int{0, ranges[1] - 2, ranges[2], ranges[3]}, "hexadecimal string"},
+		{"partial gap", [4]int{0, ranges[1], ranges[2] - 2, ranges[3] + 2}, "hexadecimal string"},
+	}
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			r := tcase.ranges
+			byteRange := fmt.Sprintf("/ByteRange [%d %d %d %d]", r[0], r[1], r[2], r[3])
+			reserved := loc[1] - loc[0]
+			require.True(t, len(byteRange) <= reserved)
+
+			tampered := append([]byte{}, signed...)
+			copy(tampered[loc[0]:loc[1]], byteRange+string(bytes.Repeat([]byte(" "), reserved-len(byteRange))))
+			results := validateTestPdf(t, tampered)
+			require.Len(t, results, 1)
+			require.False(t, results[0].IsVerified)
+			require.Len(t, results[0].Errors, 1)
+			require.Contains(t, results[0].Errors[0], tcase.expected)
+		})
+	}
+}
+
+func TestValidateSignaturesModifications(t *testing.T) {
+	data, err := ioutil.ReadFile(testPdfFile1)
+	require.NoError(t, err)
+	pki := newTestPKI(t, "rsa")
+	signed := signTestPAdES(t, data, pki, "Signature1")
+
+	// Signature added in a new revision.
+	signed2 := signTestPAdES(t, signed, pki, "Signature2")
+	results := validateTestPdf(t, signed2)
+	require.Len(t, results, 2)
+	for _, result := range results {
+		require.Empty(t, result.Errors)
+		require.True(t, result.IsVerified)
+	}
+	require.Equal(t, 1, results[0].Revision)
+	require.False(t, results[0].CoversWholeFile)
+	require.NotEmpty(t, results[0].Modifications)
+	kinds := map[model.SignatureChangeKind]bool{}
+	for _, change := range results[0].Modifications {
+		require.True(t, change.Permitted, change.String())
+		require.Equal(t, 2, change.Revision)
+		kinds[change.Kind] = true
+	}
+	require.True(t, kinds[model.SignatureChangeSignature])
+	require.Equal(t, 2, results[1].Revision)
+	require.True(t, results[1].CoversWholeFile)
+	require.Empty(t, results[1].Modifications)
+
+	// Annotation added.
+	annotated := appendTestRevision(t, signed, func(reader *model.PdfReader, appender *model.PdfAppender) {
+		page, err := reader.GetPage(1)
+		require.NoError(t, err)
+		annot := model.NewPdfAnnotationText()
+		annot.Rect = core.MakeArray(core.MakeInteger(10), core.MakeInteger(10), core.MakeInteger(30), core.MakeInteger(30))
+		annot.Contents = core.MakeString("Comment")
+		page.AddAnnotation(annot.PdfAnnotation)
+		appender.UpdatePage(page)
+	})
+	results = validateTestPdf(t, annotated)
+	require.Len(t, results, 1)
+	require.Empty(t, results[0].Errors)
+	require.True(t, results[0].IsVerified)
+	require.False(t, results[0].CoversWholeFile)
+	kinds = map[model.SignatureChangeKind]bool{}
+	for _, change := range results[0].Modifications {
+		kinds[change.Kind] = true
+	}
+	require.True(t, kinds[model.SignatureChangeAnnotation])
+
+	// Page content changed.
+	changed := appendTestRevision(t, signed, func(reader *model.PdfReader, appender *model.PdfAppender) {
+		page, err := reader.GetPage(1)
+		require.NoError(t, err)
+		require.NoError(t, page.AddContentStreamByString("BT /F1 12 Tf 10 10 Td (Changed) Tj ET"))
+		appender.UpdatePage(page)
+	})
+	results = validateTestPdf(t, changed)
+	require.Len(t, results, 1)
+	require.True(t, results[0].IsVerified)
+	require.Equal(t, 1, results[0].Revision)
+	require.NotEmpty(t, results[0].Errors)
+	found := false
+	for _, change := range results[0].Modifications {
+		if change.Kind == model.SignatureChangeOther {
+			require.False(t, change.Permitted)
+			found = true
+		}
+	}
+	require.True(t, found, "%v", results[0].Modifications)
+}
+
+func TestAppenderSignCertification(t *testing.T) {
+	data, err := ioutil.ReadFile(testPdfAcroFormFile1)
+	require.NoError(t, err)
+	pki := newTestPKI(t, "rsa")
+	certify := func(perm model.DocMDPPermission) func(*model.PdfSignature, *model.PdfFieldSignature) {
+		return func(sig *model.PdfSignature, _ *model.PdfFieldSignature) {
+			sig.SetDocMDPPermission(perm)
+		}
+	}
+
+	certified := signTestPAdESWith(t, data, pki, "Certification", certify(model.DocMDPFillForms))
+	require.Contains(t, string(certified), "/TransformMethod /DocMDP")
+	results := validateTestPdf(t, certified)
+	require.Len(t, results, 1)
+	require.Empty(t, results[0].Errors)
+	require.Equal(t, model.DocMDPFillForms, results[0].DocMDPPermission)
+
+	reader, err := model.NewPdfReader(bytes.NewReader(certified))
+	require.NoError(t, err)
+	trailer, err := reader.GetTrailer()
+	require.NoError(t, err)
+	catalog, ok := core.GetDict(core.ResolveReference(trailer.Get("Root")))
+	require.True(t, ok)
+	perms, ok := core.GetDict(core.ResolveReference(catalog.Get("Perms")))
+	require.True(t, ok)
+	require.NotNil(t, perms.Get("DocMDP"))
+
+	// Form filling and signing are permitted.
+	filled := fillTestField(t, certified, "Given Name Text Box", "John")
+	results = validateTestPdf(t, filled)
+	require.Len(t, results, 1)
+	require.Empty(t, results[0].Errors)
+	require.True(t, changeKinds(results[0])[model.SignatureChangeFormFill])
+
+	signed := signTestPAdES(t, filled, pki, "Approval")
+	results = validateTestPdf(t, signed)
+	require.Len(t, results, 2)
+	for _, result := range results {
+		require.Empty(t, result.Errors)
+		require.Equal(t, model.DocMDPFillForms, result.DocMDPPermission)
+	}
+
+	// Annotations are not.
+	annotated := appendTestRevision(t, certified, func(reader *model.PdfReader, appender *model.PdfAppender) {
+		page, err := reader.GetPage(1)
+		require.NoError(t, err)
+		annot := model.NewPdfAnnotationText()
+		annot.Rect = core.MakeArray(core.MakeInteger(10), core.MakeInteger(10), core.MakeInteger(30), core.MakeInteger(30))
+		page.AddAnnotation(annot.PdfAnnotation)
+		appender.UpdatePage(page)
+	})
+	results = validateTestPdf(t, annotated)
+	require.True(t, results[0].IsVerified)
+	require.NotEmpty(t, results[0].Errors)
+	permitted, found := changeKinds(results[0])[model.SignatureChangeAnnotation]
+	require.True(t, found)
+	require.False(t, permitted)
+
+	// No change at all is permitted.
+	locked := signTestPAdESWith(t, data, pki, "Certification", certify(model.DocMDPNoChanges))
+	results = validateTestPdf(t, fillTestField(t, locked, "Given Name Text Box", "John"))
+	require.Equal(t, model.DocMDPNoChanges, results[0].DocMDPPermission)
+	require.NotEmpty(t, results[0].Errors)
+	require.False(t, changeKinds(results[0])[model.SignatureChangeFormFill])
+
+	// The certification signature must be the first one.
+	for _, doc := range [][]byte{certified, signTestPAdES(t, data, pki, "Approval")} {
+		reader, err := model.NewPdfReader(bytes.NewReader(doc))
+		require.NoError(t, err)
+		appender, err := model.NewPdfAppender(reader)
+		require.NoError(t, err)
+		handler, err := sighandler.NewPAdES(pki.key, pki.cert, nil)
+		require.NoError(t, err)
+		signature := model.NewPdfSignature(handler)
+		require.NoError(t, signature.Initialize())
+		signature.SetDocMDPPermission(model.DocMDPAnnotate)
+		field := model.NewPdfFieldSignature(signature)
+		field.T = core.MakeString("Certification2")
+		require.Error(t, appender.Sign(1, field))
+	}
+}
+
+func TestAppenderSignCertificationFailure(t *testing.T) {
+	data, err := ioutil.ReadFile(testPdfFile1)
+	require.NoError(t, err)
+	pki := newTestPKI(t, "rsa")
+	newAppender := func() *model.PdfAppender {
+		reader, err := model.NewPdfReader(bytes.NewReader(data))
+		require.NoError(t, err)
+		appender, err := model.NewPdfAppender(reader)
+		require.NoError(t, err)
+		return appender
+	}
+	newField := func(name string, perm model.DocMDPPermission) *model.PdfFieldSignature {
+		handler, err := sighandler.NewPAdES(pki.key, pki.cert, &sighandler.PAdESOpts{
+			Chain: []*x509.Certificate{pki.caCert},
+		})
+		require.NoError(t, err)
+		signature := model.NewPdfSignature(handler)
+		require.NoError(t, signature.Initialize())
+		if perm != 0 {
+			signature.SetDocMDPPermission(perm)
+		}
+		field := model.NewPdfFieldSignature(signature)
+		field.T = core.MakeString(name)
+		field.Rect = core.MakeArray(core.MakeInteger(0), core.MakeInteger(0), core.MakeInteger(0), core.MakeInteger(0))
+		return field
+	}
+
+	// Signing a missing page changes neither the signature nor the document.
+	appender := newAppender()
+	require.Error(t, appender.Sign(2, newField("Certification", model.DocMDPFillForms)))
+	var buf bytes.Buffer
+	require.NoError(t, appender.Write(&buf))
+	require.NotContains(t, buf.String(), "/DocMDP")
+
+	// The signature can then be added to an existing page.
+	appender = newAppender()
+	field := newField("Certification", model.DocMDPFillForms)
+	require.Error(t, appender.Sign(2, field))
+	require.Nil(t, field.V.Reference)
+	require.NoError(t, appender.Sign(1, field))
+	require.Equal(t, 1, field.V.Reference.Len())
+	buf.Reset()
+	require.NoError(t, appender.Write(&buf))
+	results := validateTestPdf(t, buf.Bytes())
+	require.Len(t, results, 1)
+	require.Empty(t, results[0].Errors)
+	require.Equal(t, model.DocMDPFillForms, results[0].DocMDPPermission)
+
+	// The certification signature must be the first one, also when the original document has no
+	// form for the signatures added by the appender.
+	appender = newAppender()
+	require.NoError(t, appender.Sign(1, newField("Approval", 0)))
+	field = newField("Certification", model.DocMDPFillForms)
+	require.Error(t, appender.Sign(1, field))
+	require.Nil(t, field.V.Reference)
+}
+
+func TestAppenderSignFieldLock(t *testing.T) {
+	data, err := ioutil.ReadFile(testPdfAcroFormFile1)
+	require.NoError(t, err)
+	pki := newTestPKI(t, "rsa")
+
+	signed := signTestPAdESWith(t, data, pki, "Signature1",
+		func(_ *model.PdfSignature, field *model.PdfFieldSignature) {
+			field.SetLock(model.FieldMDPInclude, "Given Name Text Box")
+		})
+	require.Contains(t, string(signed), "/TransformMethod /FieldMDP")
+	require.Contains(t, string(signed), "/Type /SigFieldLock")
+	results := validateTestPdf(t, signed)
+	require.Len(t, results, 1)
+	require.Empty(t, results[0].Errors)
+
+	// Filling a field not locked is permitted.
+	results = validateTestPdf(t, fillTestField(t, signed, "Family Name Text Box", "Smith"))
+	require.Empty(t, results[0].Errors)
+	require.True(t, changeKinds(results[0])[model.SignatureChangeFormFill])
+
+	// Filling the locked field is not.
+	results = validateTestPdf(t, fillTestField(t, signed, "Given Name Text Box", "John"))
+	require.True(t, results[0].IsVerified)
+	require.Len(t, results[0].Errors, 1)
+	require.Contains(t, results[0].Errors[0], "locked")
+
+	// All the fields locked.
+	signed = signTestPAdESWith(t, data, pki, "Signature1",
+		func(_ *model.PdfSignature, field *model.PdfFieldSignature) {
+			field.SetLock(model.FieldMDPAll)
+		})
+	results = validateTestPdf(t, fillTestField(t, signed, "Family Name Text Box", "Smith"))
+	require.NotEmpty(t, results[0].Errors)
+	require.False(t, changeKinds(results[0])[model.SignatureChangeFormFill])
+}
@@ -0,0 +1,766 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// PdfMarkInfo represents the mark information dictionary of a document (14.7.1 General).
+type PdfMarkInfo struct {
+	// Marked indicates that the document conforms to the tagged PDF conventions.
+	Marked bool
+
+	// UserProperties indicates the presence of structure elements with user properties.
+	UserProperties bool
+
+	// Suspects indicates the presence of tag suspects.
+	Suspects bool
+}
+
+// newPdfMarkInfoFromObject loads a mark information dictionary, logging its problems to `logger`.
+func newPdfMarkInfoFromObject(obj core.PdfObject, logger common.Logger) (*PdfMarkInfo, error) {
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		logger.Debug("ERROR: MarkInfo not a dictionary (%T)", obj)
+		return nil, ErrTypeCheck
+	}
+	info := &PdfMarkInfo{}
+	info.Marked, _ = core.GetBoolVal(dict.Get("Marked"))
+	info.UserProperties, _ = core.GetBoolVal(dict.Get("UserProperties"))
+	info.Suspects, _ = core.GetBoolVal(dict.Get("Suspects"))
+	return info, nil
+}
+
+// ToPdfObject returns the mark information dictionary.
+func (m *PdfMarkInfo) ToPdfObject() core.PdfObject {
+	dict := core.MakeDict()
+	dict.Set("Marked", core.MakeBool(m.Marked))
+	if m.UserProperties {
+		dict.Set("UserProperties", core.MakeBool(true))
+	}
+	if m.Suspects {
+		dict.Set("Suspects", core.MakeBool(true))
+	}
+	return dict
+}
+
+// PdfStructKid represents a kid of a structure element (14.7.2 Structure Hierarchy): either a
+// structure element, a marked-content sequence (MCR) or a PDF object such as an annotation (OBJR).
+type PdfStructKid struct {
+	// Elem is the structure element kid, nil for content kids.
+	Elem *PdfStructElem
+
+	// Obj is the object of an object reference (OBJR), nil for other kids.
+	Obj core.PdfObject
+
+	// MCID is the marked-content identifier of a marked-content reference.
+	MCID int
+
+	// Page is the page of the content, the page of the parent element if nil.
+	Page *core.PdfIndirectObject
+
+	// PageNumber is the number of the page of the content (1-based), resolved when loaded by a
+	// PdfReader. 0 if unknown.
+	PageNumber int
+
+	// Stm is the content stream containing the marked-content sequence if other than the content
+	// stream of the page, such as a form XObject, and StmOwn the owner of the stream.
+	Stm    core.PdfObject
+	StmOwn core.PdfObject
+}
+
+// IsMarkedContent returns true if the kid is a marked-content reference.
+func (k *PdfStructKid) IsMarkedContent() bool {
+	return k.Elem == nil && k.Obj == nil
+}
+
+// PdfStructElem represents a structure element of the structure tree of a tagged document
+// (14.7.2 Structure Hierarchy).
+type PdfStructElem struct {
+	// S is the structure type, a standard type or a type mapped by the role map of the tree.
+	S string
+
+	// ID is the element identifier, in the IDTree of the tree.
+	ID string
+
+	// Page is the page on which the element is rendered (Pg entry), if any.
+	Page *core.PdfIndirectObject
+
+	Kids []*PdfStructKid
+
+	// Attributes are the attribute objects, with their owner in their O entry.
+	Attributes []*core.PdfObjectDictionary
+
+	// Classes are the attribute classes of the element, in the ClassMap of the tree.
+	Classes []string
+
+	T          string // Title.
+	Lang       string // Language.
+	Alt        string // Alternate description.
+	E          string // Expanded form of an abbreviation.
+	ActualText string // Replacement text.
+
+	parent    *PdfStructElem
+	container *core.PdfIndirectObject
+}
+
+// NewPdfStructElem returns a new structure element of structure type `structType`.
+func NewPdfStructElem(structType string) *PdfStructElem {
+	return &PdfStructElem{S: structType, container: core.MakeIndirectObject(core.MakeDict())}
+}
+
+// Parent returns the parent element, nil for the kids of the tree root.
+func (e *PdfStructElem) Parent() *PdfStructElem {
+	return e.parent
+}
+
+// AddKid appends structure element `elem` to the kids of the element.
+func (e *PdfStructElem) AddKid(elem *PdfStructElem) {
+	elem.parent = e
+	e.Kids = append(e.Kids, &PdfStructKid{Elem: elem})
+}
+
+// AddMarkedContent appends the marked-content sequence with identifier `mcid` of the contents of
+// `page` to the kids of the element.
+func (e *PdfStructElem) AddMarkedContent(page *PdfPage, mcid int) {
+	e.Kids = append(e.Kids, &PdfStructKid{MCID: mcid, Page: page.GetPageAsIndirectObject()})
+}
+
+// AddObjectReference appends object `obj` of `page`, such as an annotation, to the kids of the
+// element.
+func (e *PdfStructElem) AddObjectReference(page *PdfPage, obj core.PdfObject) {
+	kid := &PdfStructKid{Obj: obj}
+	if page != nil {
+		kid.Page = page.GetPageAsIndirectObject()
+	}
+	e.Kids = append(e.Kids, kid)
+}
+
+// GetAttribute returns the attribute `key` of owner `owner` (e.g. Layout or Table), or nil if not
+// set.
+func (e *PdfStructElem) GetAttribute(owner, key string) core.PdfObject {
+	for _, attrs := range e.Attributes {
+		if o, _ := core.GetNameVal(attrs.Get("O")); o == owner {
+			if val := attrs.Get(core.PdfObjectName(key)); val != nil {
+				return val
+			}
+		}
+	}
+	return nil
+}
+
+// SetAttribute sets the attribute `key` of owner `owner` to `value`.
+func (e *PdfStructElem) SetAttribute(owner, key string, value core.PdfObject) {
+	for _, attrs := range e.Attributes {
+		if o, _ := core.GetNameVal(attrs.Get("O")); o == owner {
+			attrs.Set(core.PdfObjectName(key), value)
+			return
+		}
+	}
+	attrs := core.MakeDict()
+	attrs.Set("O", core.MakeName(owner))
+	attrs.Set(core.PdfObjectName(key), value)
+	e.Attributes = append(e.Attributes, attrs)
+}
+
+// GetContainingPdfObject returns the indirect object of the element.
+func (e *PdfStructElem) GetContainingPdfObject() core.PdfObject {
+	return e.container
+}
+
+// PdfStructTreeRoot represents the structure tree root of a tagged document (14.7.2 Structure
+// Hierarchy). The parent tree, which maps the content to the structure elements, and the ID tree
+// are built from the elements when written.
+type PdfStructTreeRoot struct {
+	Kids []*PdfStructElem
+
+	// RoleMap maps the nonstandard structure types to other structure types.
+	RoleMap map[string]string
+
+	// ClassMap maps the attribute class names to attribute objects.
+	ClassMap *core.PdfObjectDictionary
+
+	container *core.PdfIndirectObject
+}
+
+// NewPdfStructTreeRoot returns a new empty structure tree root.
+func NewPdfStructTreeRoot() *PdfStructTreeRoot {
+	return &PdfStructTreeRoot{
+		RoleMap:   map[string]string{},
+		container: core.MakeIndirectObject(core.MakeDict()),
+	}
+}
+
+// AddKid appends structure element `elem` to the kids of the root.
+func (root *PdfStructTreeRoot) AddKid(elem *PdfStructElem) {
+	elem.parent = nil
+	root.Kids = append(root.Kids, elem)
+}
+
+// ResolveRole returns the structure type which `structType` is mapped to by the role map,
+// following the mappings transitively. Returns `structType` if not mapped.
+func (root *PdfStructTreeRoot) ResolveRole(structType string) string {
+	visited := map[string]bool{structType: true}
+	for {
+		mapped, ok := root.RoleMap[structType]
+		if !ok || visited[mapped] {
+			return structType
+		}
+		visited[mapped] = true
+		structType = mapped
+	}
+}
+
+// Elements returns the structure elements of the tree, in depth-first order.
+func (root *PdfStructTreeRoot) Elements() []*PdfStructElem {
+	var elems []*PdfStructElem
+	var walk func(elem *PdfStructElem)
+	walk = func(elem *PdfStructElem) {
+		elems = append(elems, elem)
+		for _, kid := range elem.Kids {
+			if kid.Elem != nil {
+				walk(kid.Elem)
+			}
+		}
+	}
+	for _, elem := range root.Kids {
+		walk(elem)
+	}
+	return elems
+}
+
+// GetContainingPdfObject returns the indirect object of the structure tree root.
+func (root *PdfStructTreeRoot) GetContainingPdfObject() core.PdfObject {
+	return root.container
+}
+
+// structTreeLoader loads a structure tree.
+type structTreeLoader struct {
+	reader  *PdfReader
+	logger  common.Logger
+	visited map[core.PdfObject]struct{}
+}
+
+// newPdfStructTreeRootFromObject loads the structure tree with root `obj`. The pages are resolved
+// with `reader` if not nil.
+func newPdfStructTreeRootFromObject(obj core.PdfObject, reader *PdfReader) (*PdfStructTreeRoot, error) {
+	loader := &structTreeLoader{reader: reader, logger: common.Log, visited: map[core.PdfObject]struct{}{}}
+	if reader != nil {
+		loader.logger = reader.log()
+	}
+	container, ok := core.GetIndirect(core.ResolveReference(obj))
+	if !ok {
+		container = core.MakeIndirectObject(obj)
+	}
+	dict, ok := core.GetDict(container)
+	if !ok {
+		loader.logger.Debug("ERROR: StructTreeRoot not a dictionary (%T)", obj)
+		return nil, ErrTypeCheck
+	}
+
+	root := &PdfStructTreeRoot{RoleMap: map[string]string{}, container: container}
+	if roleMap, ok := core.GetDict(dict.Get("RoleMap")); ok {
+		for _, key := range roleMap.Keys() {
+			if mapped, ok := core.GetNameVal(roleMap.Get(key)); ok {
+				root.RoleMap[string(key)] = mapped
+			}
+		}
+	}
+	root.ClassMap, _ = core.GetDict(dict.Get("ClassMap"))
+
+	for _, kid := range structKidObjects(dict.Get("K")) {
+		elem, err := loader.loadElem(kid, nil)
+		if err != nil {
+			return nil, err
+		}
+		if elem != nil {
+			root.Kids = append(root.Kids, elem)
+		}
+	}
+	return root, nil
+}
+
+// structKidObjects returns the kid objects of the K entry `k`, a single kid or an array.
+func structKidObjects(k core.PdfObject) []core.PdfObject {
+	if k == nil {
+		return nil
+	}
+	if arr, ok := core.GetArray(k); ok {
+		return arr.Elements()
+	}
+	return []core.PdfObject{k}
+}
+
+// loadElem loads the structure element `obj` with parent `parent`. Returns nil for invalid
+// elements.
+func (l *structTreeLoader) loadElem(obj core.PdfObject, parent *PdfStructElem) (*PdfStructElem, error) {
+	resolved := core.ResolveReference(obj)
+	if _, has := l.visited[resolved]; has {
+		l.logger.Debug("ERROR: Structure element visited twice")
+		return nil, errors.New("structure tree cycle")
+	}
+	l.visited[resolved] = struct{}{}
+
+	container, ok := core.GetIndirect(resolved)
+	if !ok {
+		container = core.MakeIndirectObject(resolved)
+	}
+	dict, ok := core.GetDict(container)
+	if !ok {
+		l.logger.Debug("Invalid structure element (%T) - skipping", obj)
+		return nil, nil
+	}
+
+	elem := &PdfStructElem{parent: parent, container: container}
+	elem.S, _ = core.GetNameVal(dict.Get("S"))
+	if id, ok := core.GetString(dict.Get("ID")); ok {
+		elem.ID = id.Str()
+	}
+	elem.Page, _ = core.GetIndirect(dict.Get("Pg"))
+	texts := []struct {
+		key   core.PdfObjectName
+		value *string
+	}{
+		{"T", &elem.T},
+		{"Lang", &elem.Lang},
+		{"Alt", &elem.Alt},
+		{"E", &elem.E},
+		{"ActualText", &elem.ActualText},
+	}
+	for _, text := range texts {
+		if str, ok := core.GetString(dict.Get(text.key)); ok {
+			*text.value = str.Decoded()
+		}
+	}
+	for _, attr := range structKidObjects(dict.Get("A")) {
+		switch t := core.TraceToDirectObject(attr).(type) {
+		case *core.PdfObjectDictionary:
+			elem.Attributes = append(elem.Attributes, t)
+		case *core.PdfObjectStream:
+			elem.Attributes = append(elem.Attributes, t.PdfObjectDictionary)
+		}
+	}
+	for _, class := range structKidObjects(dict.Get("C")) {
+		if name, ok := core.GetNameVal(class); ok {
+			elem.Classes = append(elem.Classes, name)
+		}
+	}
+
+	for _, kidObj := range structKidObjects(dict.Get("K")) {
+		kid, err := l.loadKid(kidObj, elem)
+		if err != nil {
+			return nil, err
+		}
+		if kid != nil {
+			elem.Kids = append(elem.Kids, kid)
+		}
+	}
+	return elem, nil
+}
+
+// loadKid loads kid `obj` of structure element `elem`. Returns nil for invalid kids.
+func (l *structTreeLoader) loadKid(obj core.PdfObject, elem *PdfStructElem) (*PdfStructKid, error) {
+	if mcid, ok := core.GetIntVal(obj); ok {
+		kid := &PdfStructKid{MCID: mcid}
+		l.setPage(kid, nil, elem)
+		return kid, nil
+	}
+
+	dict, ok := core.GetDict(obj)
+	if !ok {
+		l.logger.Debug("Invalid structure element kid (%T) - skipping", obj)
+		return nil, nil
+	}
+	typ, _ := core.GetNameVal(dict.Get("Type"))
+	switch typ {
+	case "MCR":
+		mcid, ok := core.GetIntVal(dict.Get("MCID"))
+		if !ok {
+			l.logger.Debug("Invalid MCR without MCID - skipping")
+			return nil, nil
+		}
+		kid := &PdfStructKid{MCID: mcid, Stm: dict.Get("Stm"), StmOwn: dict.Get("StmOwn")}
+		l.setPage(kid, dict.Get("Pg"), elem)
+		return kid, nil
+	case "OBJR":
+		kid := &PdfStructKid{Obj: dict.Get("Obj")}
+		if kid.Obj == nil {
+			l.logger.Debug("Invalid OBJR without Obj - skipping")
+			return nil, nil
+		}
+		l.setPage(kid, dict.Get("Pg"), elem)
+		return kid, nil
+	}
+
+	child, err := l.loadElem(obj, elem)
+	if err != nil || child == nil {
+		return nil, err
+	}
+	return &PdfStructKid{Elem: child}, nil
+}
+
+// setPage sets the page of content kid `kid` to `pg` or to the page of element `elem` or its
+// ancestors, and resolves the page number.
+func (l *structTreeLoader) setPage(kid *PdfStructKid, pg core.PdfObject, elem *PdfStructElem) {
+	kid.Page, _ = core.GetIndirect(pg)
+	for e := elem; kid.Page == nil && e != nil; e = e.parent {
+		kid.Page = e.Page
+	}
+	if kid.Page == nil || l.reader == nil {
+		return
+	}
+	if _, pageNum, err := l.reader.PageFromIndirectObject(kid.Page); err == nil {
+		kid.PageNumber = pageNum
+	}
+}
+
+// structTreeWriter builds the parent tree of a structure tree being written.
+type structTreeWriter struct {
+	// Structure elements of the marked content of the content streams (pages and streams).
+	owners  []core.PdfObject
+	content map[core.PdfObject][]core.PdfObject
+	// Structure elements of the objects referenced by OBJR.
+	objects []core.PdfObject
+	objElem map[core.PdfObject]core.PdfObject
+
+	ids *PdfNameTree
+}
+
+// ToPdfObject returns the indirect object of the structure tree root. The parent tree is built
+// and the StructParents and StructParent entries of the pages, content streams and objects of the
+// content of the tree are set, reusing the existing keys.
+func (root *PdfStructTreeRoot) ToPdfObject() core.PdfObject {
+	dict, ok := root.container.PdfObject.(*core.PdfObjectDictionary)
+	if !ok {
+		dict = core.MakeDict()
+		root.container.PdfObject = dict
+	}
+	sw := &structTreeWriter{
+		content: map[core.PdfObject][]core.PdfObject{},
+		objElem: map[core.PdfObject]core.PdfObject{},
+		ids:     NewPdfNameTree(),
+	}
+
+	kids := core.MakeArray()
+	for _, elem := range root.Kids {
+		elem.parent = nil
+		kids.Append(sw.writeElem(elem, root.container))
+	}
+
+	dict.Set("Type", core.MakeName("StructTreeRoot"))
+	dict.Set("K", kids)
+	dict.Remove("RoleMap")
+	if len(root.RoleMap) > 0 {
+		var keys []string
+		for key := range root.RoleMap {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		roleMap := core.MakeDict()
+		for _, key := range keys {
+			roleMap.Set(core.PdfObjectName(key), core.MakeName(root.RoleMap[key]))
+		}
+		dict.Set("RoleMap", roleMap)
+	}
+	dict.Remove("ClassMap")
+	if root.ClassMap != nil {
+		dict.Set("ClassMap", root.ClassMap)
+	}
+	dict.Remove("IDTree")
+	if sw.ids.Len() > 0 {
+		dict.Set("IDTree", sw.ids.ToPdfObject())
+	}
+
+	parentTree, nextKey := sw.parentTree()
+	dict.Set("ParentTree", core.MakeIndirectObject(parentTree.ToPdfObject()))
+	dict.Set("ParentTreeNextKey", core.MakeInteger(nextKey))
+	return root.container
+}
+
+// writeElem updates the dictionary of element `elem` with parent object `parent` and returns its
+// indirect object.
+func (sw *structTreeWriter) writeElem(elem *PdfStructElem, parent core.PdfObject) core.PdfObject {
+	if elem.container == nil {
+		elem.container = core.MakeIndirectObject(core.MakeDict())
+	}
+	dict, ok := elem.container.PdfObject.(*core.PdfObjectDictionary)
+	if !ok {
+		dict = core.MakeDict()
+		elem.container.PdfObject = dict
+	}
+
+	dict.Set("Type", core.MakeName("StructElem"))
+	dict.Set("S", core.MakeName(elem.S))
+	dict.Set("P", parent)
+	dict.Remove("ID")
+	if elem.ID != "" {
+		dict.Set("ID", core.MakeString(elem.ID))
+		sw.ids.Set(elem.ID, elem.container)
+	}
+	dict.Remove("Pg")
+	if elem.Page != nil {
+		dict.Set("Pg", elem.Page)
+	}
+	texts := []struct {
+		key   core.PdfObjectName
+		value string
+	}{
+		{"T", elem.T},
+		{"Lang", elem.Lang},
+		{"Alt", elem.Alt},
+		{"E", elem.E},
+		{"ActualText", elem.ActualText},
+	}
+	for _, text := range texts {
+		dict.Remove(text.key)
+		if text.value != "" {
+			dict.Set(text.key, makeTextString(text.value))
+		}
+	}
+	dict.Remove("A")
+	switch len(elem.Attributes) {
+	case 0:
+	case 1:
+		dict.Set("A", elem.Attributes[0])
+	default:
+		attrs := core.MakeArray()
+		for _, a := range elem.Attributes {
+			attrs.Append(a)
+		}
+		dict.Set("A", attrs)
+	}
+	dict.Remove("C")
+	switch len(elem.Classes) {
+	case 0:
+	case 1:
+		dict.Set("C", core.MakeName(elem.Classes[0]))
+	default:
+		classes := core.MakeArray()
+		for _, c := range elem.Classes {
+			classes.Append(core.MakeName(c))
+		}
+		dict.Set("C", classes)
+	}
+
+	kids := core.MakeArray()
+	for _, kid := range elem.Kids {
+		switch {
+		case kid.Elem != nil:
+			kid.Elem.parent = elem
+			kids.Append(sw.writeElem(kid.Elem, elem.container))
+		case kid.Obj != nil:
+			objr := core.MakeDict()
+			objr.Set("Type", core.MakeName("OBJR"))
+			if kid.Page != nil {
+				objr.Set("Pg", kid.Page)
+			}
+			objr.Set("Obj", kid.Obj)
+			kids.Append(objr)
+			sw.addObject(kid.Obj, elem.container)
+		default:
+			page := kid.Page
+			if page == nil {
+				page = elem.Page
+			}
+			if kid.Stm == nil && page != nil && page == elem.Page {
+				kids.Append(core.MakeInteger(int64(kid.MCID)))
+			} else {
+				mcr := core.MakeDict()
+				mcr.Set("Type", core.MakeName("MCR"))
+				if page != nil {
+					mcr.Set("Pg", page)
+				}
+				if kid.Stm != nil {
+					mcr.Set("Stm", kid.Stm)
+				}
+				if kid.StmOwn != nil {
+					mcr.Set("StmOwn", kid.StmOwn)
+				}
+				mcr.Set("MCID", core.MakeInteger(int64(kid.MCID)))
+				kids.Append(mcr)
+			}
+			owner := kid.Stm
+			if owner == nil {
+				owner = page
+			}
+			if owner != nil {
+				sw.addContent(owner, kid.MCID, elem.container)
+			} else {
+				common.Log.Debug("Marked-content reference without page - not in parent tree")
+			}
+		}
+	}
+	dict.Set("K", kids)
+	return elem.container
+}
+
+// addContent records that the marked-content sequence `mcid` of the content of `owner` belongs
+// to element `elem`.
+func (sw *structTreeWriter) addContent(owner core.PdfObject, mcid int, elem core.PdfObject) {
+	owner = core.ResolveReference(owner)
+	elems, has := sw.content[owner]
+	if !has {
+		sw.owners = append(sw.owners, owner)
+	}
+	for len(elems) <= mcid {
+		elems = append(elems, core.MakeNull())
+	}
+	elems[mcid] = elem
+	sw.content[owner] = elems
+}
+
+// addObject records that object `obj` belongs to element `elem`.
+func (sw *structTreeWriter) addObject(obj core.PdfObject, elem core.PdfObject) {
+	obj = core.ResolveReference(obj)
+	if _, has := sw.objElem[obj]; !has {
+		sw.objects = append(sw.objects, obj)
+	}
+	sw.objElem[obj] = elem
+}
+
+// parentTree returns the parent tree of the content and objects of the tree and the next key,
+// setting the StructParents entries of the content owners and StructParent entries of the
+// objects. Existing keys are kept unless used more than once.
+func (sw *structTreeWriter) parentTree() (*PdfNumberTree, int64) {
+	tree := NewPdfNumberTree()
+	var pending []core.PdfObject
+	for _, owners := range [][]core.PdfObject{sw.owners, sw.objects} {
+		for _, obj := range owners {
+			key := structParentsKey(obj)
+			if _, taken := tree.Get(key); key < 0 || taken {
+				pending = append(pending, obj)
+				continue
+			}
+			tree.Set(key, sw.parentTreeValue(obj))
+		}
+	}
+
+	var next int64
+	if keys := tree.Keys(); len(keys) > 0 {
+		next = keys[len(keys)-1] + 1
+	}
+	for _, obj := range pending {
+		setStructParentsKey(obj, next, sw.isObject(obj))
+		tree.Set(next, sw.parentTreeValue(obj))
+		next++
+	}
+	return tree, next
+}
+
+// isObject returns true if `obj` is an object referenced by OBJR rather than a content owner.
+func (sw *structTreeWriter) isObject(obj core.PdfObject) bool {
+	_, isContent := sw.content[obj]
+	return !isContent
+}
+
+// parentTreeValue returns the parent tree value of `obj`: the array of the elements of the marked
+// content of a content owner, or the element of an object.
+func (sw *structTreeWriter) parentTreeValue(obj core.PdfObject) core.PdfObject {
+	if elems, ok := sw.content[obj]; ok {
+		return core.MakeIndirectObject(core.MakeArray(elems...))
+	}
+	return sw.objElem[obj]
+}
+
+// structParentsKey returns the StructParents or StructParent key of `obj`, -1 if not set.
+func structParentsKey(obj core.PdfObject) int64 {
+	dict, ok := core.GetDict(obj)
+	if stream, isStream := obj.(*core.PdfObjectStream); isStream {
+		dict, ok = stream.PdfObjectDictionary, true
+	}
+	if !ok {
+		return -1
+	}
+	for _, key := range []core.PdfObjectName{"StructParents", "StructParent"} {
+		if val, ok := core.GetIntVal(dict.Get(key)); ok && val >= 0 {
+			return int64(val)
+		}
+	}
+	return -1
+}
+
+// setStructParentsKey sets the StructParent entry of an object or the StructParents entry of a
+// content owner `obj` to `key`.
+func setStructParentsKey(obj core.PdfObject, key int64, isObject bool) {
+	dict, ok := core.GetDict(obj)
+	if stream, isStream := obj.(*core.PdfObjectStream); isStream {
+		dict, ok = stream.PdfObjectDictionary, true
+	}
+	if !ok {
+		common.Log.Debug("ERROR: Cannot set StructParents of %T", obj)
+		return
+	}
+	if isObject {
+		dict.Set("StructParent", core.MakeInteger(key))
+	} else {
+		dict.Set("StructParents", core.MakeInteger(key))
+	}
+}
+
+// removePages removes the content of the pages for which `removed` returns true from the tree,
+// and the elements left without kids. Returns true if the tree changed.
+func (root *PdfStructTreeRoot) removePages(removed func(page *core.PdfIndirectObject) bool) bool {
+	changed := false
+	var prune func(elem *PdfStructElem) bool
+	prune = func(elem *PdfStructElem) bool {
+		if elem.Page != nil && removed(elem.Page) {
+			elem.Page = nil
+		}
+		hadKids := len(elem.Kids) > 0
+		var kept []*PdfStructKid
+		for _, kid := range elem.Kids {
+			switch {
+			case kid.Elem != nil:
+				if !prune(kid.Elem) {
+					continue
+				}
+			case kid.Page != nil && removed(kid.Page):
+				changed = true
+				continue
+			}
+			kept = append(kept, kid)
+		}
+		elem.Kids = kept
+		if hadKids && len(kept) == 0 {
+			changed = true
+			return false
+		}
+		return true
+	}
+
+	var kept []*PdfStructElem
+	for _, elem := range root.Kids {
+		if prune(elem) {
+			kept = append(kept, elem)
+		}
+	}
+	root.Kids = kept
+	return changed
+}
+
+// GetStructTreeRoot returns the structure tree of the document, or nil if the document is not
+// tagged. The pages of the content of the tree are resolved to page numbers.
+func (r *PdfReader) GetStructTreeRoot() (*PdfStructTreeRoot, error) {
+	obj, err := r.getCatalogEntry("StructTreeRoot")
+	if err != nil || obj == nil {
+		return nil, err
+	}
+	return newPdfStructTreeRootFromObject(obj, r)
+}
+
+// GetMarkInfo returns the mark information dictionary of the document, or nil if not set.
+func (r *PdfReader) GetMarkInfo() (*PdfMarkInfo, error) {
+	obj, err := r.getCatalogEntry("MarkInfo")
+	if err != nil || obj == nil {
+		return nil, err
+	}
+	return newPdfMarkInfoFromObject(obj, r.log())
+}
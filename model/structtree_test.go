@@ -0,0 +1,206 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// writeTaggedTestFile returns a tagged document of two pages: a heading and a link on the first
+// page, and a figure on the second.
+func writeTaggedTestFile(t *testing.T) []byte {
+	page1 := newPdf20TestPage()
+	page2 := newPdf20TestPage()
+	link := NewPdfAnnotationLink()
+	link.Rect = core.MakeArray(core.MakeInteger(10), core.MakeInteger(10), core.MakeInteger(50), core.MakeInteger(20))
+	page1.AddAnnotation(link.PdfAnnotation)
+
+	root := NewPdfStructTreeRoot()
+	root.RoleMap["Heading"] = "Title"
+	root.RoleMap["Title"] = "H1"
+	doc := NewPdfStructElem("Document")
+	doc.Lang = "en-US"
+	root.AddKid(doc)
+
+	heading := NewPdfStructElem("Heading")
+	heading.ID = "h1"
+	heading.Page = page1.GetPageAsIndirectObject()
+	heading.AddMarkedContent(page1, 0)
+	heading.AddMarkedContent(page1, 2)
+	doc.AddKid(heading)
+
+	linkElem := NewPdfStructElem("Link")
+	linkElem.AddMarkedContent(page1, 1)
+	linkElem.AddObjectReference(page1, link.GetContainingPdfObject())
+	doc.AddKid(linkElem)
+
+	figure := NewPdfStructElem("Figure")
+	figure.Alt = "Chart of the results"
+	figure.SetAttribute("Layout", "Placement", core.MakeName("Block"))
+	figure.SetAttribute("Layout", "BBox", core.MakeArray(core.MakeInteger(0), core.MakeInteger(0),
+		core.MakeInteger(100), core.MakeInteger(100)))
+	figure.AddMarkedContent(page2, 0)
+	doc.AddKid(figure)
+
+	w := NewPdfWriter()
+	require.NoError(t, w.AddPage(page1))
+	require.NoError(t, w.AddPage(page2))
+	w.SetStructTreeRoot(root)
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	return buf.Bytes()
+}
+
+func TestWriterStructTree(t *testing.T) {
+	data := writeTaggedTestFile(t)
+	r, err := NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	markInfo, err := r.GetMarkInfo()
+	require.NoError(t, err)
+	require.Equal(t, &PdfMarkInfo{Marked: true}, markInfo)
+
+	root, err := r.GetStructTreeRoot()
+	require.NoError(t, err)
+	require.NotNil(t, root)
+	require.Equal(t, "H1", root.ResolveRole("Heading"))
+	require.Equal(t, "P", root.ResolveRole("P"))
+
+	elems := root.Elements()
+	require.Len(t, elems, 4)
+	doc, heading, link, figure := elems[0], elems[1], elems[2], elems[3]
+	require.Equal(t, "Document", doc.S)
+	require.Equal(t, "en-US", doc.Lang)
+	require.Nil(t, doc.Parent())
+	require.Equal(t, doc, heading.Parent())
+
+	require.Equal(t, "h1", heading.ID)
+	require.Len(t, heading.Kids, 2)
+	for i, mcid := range []int{0, 2} {
+		kid := heading.Kids[i]
+		require.True(t, kid.IsMarkedContent())
+		require.Equal(t, mcid, kid.MCID)
+		require.Equal(t, 1, kid.PageNumber)
+	}
+
+	require.Len(t, link.Kids, 2)
+	require.Equal(t, 1, link.Kids[0].PageNumber)
+	require.False(t, link.Kids[1].IsMarkedContent())
+	annotDict, ok := core.GetDict(link.Kids[1].Obj)
+	require.True(t, ok)
+	require.Equal(t, "Link", annotDict.Get("Subtype").String())
+
+	require.Equal(t, "Chart of the results", figure.Alt)
+	require.Equal(t, "Block", figure.GetAttribute("Layout", "Placement").String())
+	require.Nil(t, figure.GetAttribute("Table", "Placement"))
+	require.Equal(t, 2, figure.Kids[0].PageNumber)
+
+	// Parent tree: the elements of the marked content of the pages and of the annotation.
+	rootDict, ok := core.GetDict(root.GetContainingPdfObject())
+	require.True(t, ok)
+	parentTree, err := NewPdfNumberTreeFromObject(rootDict.Get("ParentTree"))
+	require.NoError(t, err)
+	require.Equal(t, 3, parentTree.Len())
+	nextKey, _ := core.GetIntVal(rootDict.Get("ParentTreeNextKey"))
+	require.Equal(t, 3, nextKey)
+
+	page1, err := r.GetPage(1)
+	require.NoError(t, err)
+	pageKey, ok := core.GetIntVal(page1.StructParents)
+	require.True(t, ok)
+	val, ok := parentTree.Get(int64(pageKey))
+	require.True(t, ok)
+	arr, ok := core.GetArray(val)
+	require.True(t, ok)
+	require.Equal(t, 3, arr.Len())
+	require.Equal(t, heading.GetContainingPdfObject(), core.ResolveReference(arr.Get(0)))
+	require.Equal(t, link.GetContainingPdfObject(), core.ResolveReference(arr.Get(1)))
+	require.Equal(t, heading.GetContainingPdfObject(), core.ResolveReference(arr.Get(2)))
+
+	key, ok := core.GetIntVal(annotDict.Get("StructParent"))
+	require.True(t, ok)
+	val, ok = parentTree.Get(int64(key))
+	require.True(t, ok)
+	require.Equal(t, link.GetContainingPdfObject(), core.ResolveReference(val))
+
+	// Rewriting the loaded tree keeps the keys.
+	var pages []*PdfPage
+	for i := 1; i <= 2; i++ {
+		page, err := r.GetPage(i)
+		require.NoError(t, err)
+		pages = append(pages, page)
+	}
+	w := NewPdfWriter()
+	for _, page := range pages {
+		require.NoError(t, w.AddPage(page))
+	}
+	w.SetStructTreeRoot(root)
+	var buf bytes.Buffer
+	require.NoError(t, w.Write(&buf))
+	r, err = NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	root, err = r.GetStructTreeRoot()
+	require.NoError(t, err)
+	require.Len(t, root.Elements(), 4)
+	page1, err = r.GetPage(1)
+	require.NoError(t, err)
+	newKey, _ := core.GetIntVal(page1.StructParents)
+	require.Equal(t, pageKey, newKey)
+}
+
+func TestAppenderStructTree(t *testing.T) {
+	data := writeTaggedTestFile(t)
+
+	// Removing the second page removes the figure.
+	r, err := NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	appender, err := NewPdfAppender(r)
+	require.NoError(t, err)
+	appender.RemovePage(2)
+	var buf bytes.Buffer
+	require.NoError(t, appender.Write(&buf))
+
+	r, err = NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	numPages, err := r.GetNumPages()
+	require.NoError(t, err)
+	require.Equal(t, 1, numPages)
+	root, err := r.GetStructTreeRoot()
+	require.NoError(t, err)
+	var types []string
+	for _, elem := range root.Elements() {
+		types = append(types, elem.S)
+	}
+	require.Equal(t, []string{"Document", "Heading", "Link"}, types)
+
+	// Added pages do not refer to the structure of their documents.
+	r, err = NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	other, err := NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	page, err := other.GetPage(1)
+	require.NoError(t, err)
+	require.NotNil(t, page.StructParents)
+	appender, err = NewPdfAppender(r)
+	require.NoError(t, err)
+	appender.AddPages(page)
+	buf.Reset()
+	require.NoError(t, appender.Write(&buf))
+
+	r, err = NewPdfReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	page, err = r.GetPage(3)
+	require.NoError(t, err)
+	require.Nil(t, page.StructParents)
+	root, err = r.GetStructTreeRoot()
+	require.NoError(t, err)
+	require.Len(t, root.Elements(), 4)
+}
@@ -97,9 +97,15 @@ func SetPdfModifiedDate(modifiedDate time.Time) {
 }
 
 func getPdfProducer() string {
+	return getPdfProducerOrDefault(pdfProducer)
+}
+
+// getPdfProducerOrDefault returns `producer` if set and allowed by the license, otherwise the
+// default producer.
+func getPdfProducerOrDefault(producer string) string {
 	licenseKey := license.GetLicenseKey()
-	if len(pdfProducer) > 0 && (licenseKey.IsLicensed() || flag.Lookup("test.v") != nil) {
-		return pdfProducer
+	if len(producer) > 0 && (licenseKey.IsLicensed() || flag.Lookup("test.v") != nil) {
+		return producer
 	}
 
 	// Return default.
@@ -141,6 +147,16 @@ type PdfWriter struct {
 	catalog     *core.PdfObjectDictionary
 	fields      []core.PdfObject
 	infoObj     *core.PdfIndirectObject
+	xmp         *XMPMetadata
+
+	// Embedded files added and removed, and portable collection.
+	embeddedFiles        []*PdfEmbeddedFile
+	removedEmbeddedFiles map[string]struct{}
+	collection           *PdfCollection
+
+	// Logical structure.
+	structTreeRoot *PdfStructTreeRoot
+	markInfo       *PdfMarkInfo
 
 	// `writer` is the buffered writer for writing, `writePos` tracks the current writing
 	// position, needed to generate cross-reference tables, `werr` is the first error
@@ -159,6 +175,9 @@ type PdfWriter struct {
 	majorVersion int
 	minorVersion int
 
+	// Reject the features deprecated in PDF 2.0 when writing a PDF 2.0 file.
+	strictPdf20 bool
+
 	// Force whether or not to use cross reference streams.
 	// Otherwise is used/not used depending on the PDF version (1.5 and above).
 	useCrossReferenceStream *bool
@@ -174,6 +193,7 @@ type PdfWriter struct {
 	acroForm *PdfAcroForm
 
 	optimizer              Optimizer
+	linearized             bool
 	crossReferenceMap      map[int]crossReference
 	writeOffset            int64 // used by PdfAppender
 	ObjNumOffset           int
@@ -186,6 +206,10 @@ type PdfWriter struct {
 
 	// Cache of objects traversed while resolving references.
 	traversed map[core.PdfObject]struct{}
+
+	// Logger receiving the log messages and the diagnostics of the writer. The global common.Log is
+	// used if nil.
+	logger common.Logger
 }
 
 // NewPdfWriter initializes a new PdfWriter.
@@ -378,7 +402,7 @@ func (w *PdfWriter) copyObject(obj core.PdfObject,
 			sigObj.Set(key, w.copyObject(t.Get(key), objectToObjectCopyMap, skipMap, skip))
 		}
 	default:
-		common.Log.Info("TODO(a5i): implement copyObject for %+v", obj)
+		w.log().Info("TODO(a5i): implement copyObject for %+v", obj)
 	}
 
 	if skipUnusedPages && skip {
@@ -418,25 +442,51 @@ func (w *PdfWriter) copyObjects() {
 			if objCopy, has := objectToObjectCopyMap[obj]; has {
 				appendReplaceMap[objCopy] = replaceNum
 			} else {
-				common.Log.Debug("ERROR: append mode - object copy not in map")
+				w.log().Debug("ERROR: append mode - object copy not in map")
 			}
 		}
 		w.appendReplaceMap = appendReplaceMap
 	}
 }
 
+// SetLogger sets the logger receiving the log messages and the diagnostics of the writer, for
+// example a common.Diagnostics recording them. The global common.Log is used if `logger` is nil.
+func (w *PdfWriter) SetLogger(logger common.Logger) {
+	w.logger = logger
+}
+
+// log returns the logger of the writer.
+func (w *PdfWriter) log() common.Logger {
+	if w.logger != nil {
+		return w.logger
+	}
+	return common.Log
+}
+
 // SetVersion sets the PDF version of the output file.
+// PDF 2.0 files can only be encrypted with AES-256 (see Encrypt) and the use of deprecated features
+// can be rejected with SetStrictPdf20.
 func (w *PdfWriter) SetVersion(majorVersion, minorVersion int) {
 	w.majorVersion = majorVersion
 	w.minorVersion = minorVersion
 }
 
-// SetOCProperties sets the optional content properties.
+// SetStrictPdf20 sets the strict mode for writing PDF 2.0 files. In strict mode, the document
+// information entries other than CreationDate and ModDate are not written and Write fails if the
+// document uses other features deprecated in PDF 2.0, such as XFA forms, PostScript XObjects and
+// Movie and Sound annotations. Otherwise the deprecated features are written and logged.
+// The strict mode has no effect on files with earlier versions.
+func (w *PdfWriter) SetStrictPdf20(strict bool) {
+	w.strictPdf20 = strict
+}
+
+// SetOCProperties sets the optional content properties. Use SetPdfOCProperties to set typed
+// optional content properties.
 func (w *PdfWriter) SetOCProperties(ocProperties core.PdfObject) error {
 	dict := w.catalog
 
 	if ocProperties != nil {
-		common.Log.Trace("Setting OC Properties...")
+		w.log().Trace("Setting OC Properties...")
 		dict.Set("OCProperties", ocProperties)
 		// Any risk of infinite loops?
 		return w.addObjects(ocProperties)
@@ -445,30 +495,128 @@ func (w *PdfWriter) SetOCProperties(ocProperties core.PdfObject) error {
 	return nil
 }
 
-// SetNamedDestinations sets the Names entry in the PDF catalog.
+// SetNamedDestinations sets the Names entry in the PDF catalog. Use
+// SetNamedDestinationsTree to set the Dests name tree only.
 // See section 12.3.2.3 "Named Destinations" (p. 367 PDF32000_2008).
 func (w *PdfWriter) SetNamedDestinations(names core.PdfObject) error {
 	if names == nil {
 		return nil
 	}
 
-	common.Log.Trace("Setting catalog Names...")
+	w.log().Trace("Setting catalog Names...")
 	w.catalog.Set("Names", names)
 	return w.addObjects(names)
 }
 
-// SetPageLabels sets the PageLabels entry in the PDF catalog.
+// SetPageLabels sets the PageLabels entry in the PDF catalog, e.g. the number
+// tree of PdfPageLabels.
 // See section 12.4.2 "Page Labels" (p. 382 PDF32000_2008).
 func (w *PdfWriter) SetPageLabels(pageLabels core.PdfObject) error {
 	if pageLabels == nil {
 		return nil
 	}
 
-	common.Log.Trace("Setting catalog PageLabels...")
+	w.log().Trace("Setting catalog PageLabels...")
 	w.catalog.Set("PageLabels", pageLabels)
 	return w.addObjects(pageLabels)
 }
 
+// SetOutputIntents sets the OutputIntents entry in the PDF catalog, the array of output intent
+// dictionaries describing the colour characteristics of the output devices.
+// See section 14.11.5 "Output Intents" (PDF32000_2008).
+func (w *PdfWriter) SetOutputIntents(outputIntents core.PdfObject) error {
+	if outputIntents == nil {
+		return nil
+	}
+
+	w.log().Trace("Setting catalog OutputIntents...")
+	w.catalog.Set("OutputIntents", outputIntents)
+	return w.addObjects(outputIntents)
+}
+
+// SetAssociatedFiles sets the AF entry in the PDF catalog, the array of file specifications of
+// the files associated with the document (PDF 2.0).
+func (w *PdfWriter) SetAssociatedFiles(af core.PdfObject) error {
+	if af == nil {
+		return nil
+	}
+
+	w.log().Trace("Setting catalog AF...")
+	w.catalog.Set("AF", af)
+	return w.addObjects(af)
+}
+
+// SetDPartRoot sets the DPartRoot entry in the PDF catalog, the root of the document part
+// hierarchy (PDF 2.0). The document parts refer to the pages of the output file, which should be
+// added with AddPage, and the pages refer to their document parts with the DPart entry.
+func (w *PdfWriter) SetDPartRoot(dpartRoot core.PdfObject) error {
+	if dpartRoot == nil {
+		return nil
+	}
+
+	w.log().Trace("Setting catalog DPartRoot...")
+	w.catalog.Set("DPartRoot", dpartRoot)
+	return w.addObjects(dpartRoot)
+}
+
+// SetDocInfo sets the document information dictionary of the output file to `info`, replacing the
+// entries set with the package-level functions such as SetPdfTitle. As with SetPdfProducer, the
+// Producer entry is only used for licensed copies.
+func (w *PdfWriter) SetDocInfo(info *PdfInfo) {
+	info = info.Copy()
+	info.Producer = getPdfProducerOrDefault(info.Producer)
+	w.infoObj.PdfObject = info.ToPdfObject()
+}
+
+// SetXMPMetadata sets the XMP metadata packet of the output file, written as the Metadata stream
+// of the catalog. The properties corresponding to the document information dictionary are updated
+// from it in a copy of `md` when writing.
+func (w *PdfWriter) SetXMPMetadata(md *XMPMetadata) {
+	w.xmp = md
+}
+
+// AddEmbeddedFile adds file `f` to the EmbeddedFiles name tree of the output file, replacing a
+// file with the same name in the Names dictionary set with SetNamedDestinations.
+func (w *PdfWriter) AddEmbeddedFile(f *PdfEmbeddedFile) error {
+	if err := checkEmbeddedFile(f, w.embeddedFiles); err != nil {
+		return err
+	}
+	w.embeddedFiles = append(w.embeddedFiles, f)
+	return nil
+}
+
+// RemoveEmbeddedFile removes the file named `name` from the embedded files of the output file.
+func (w *PdfWriter) RemoveEmbeddedFile(name string) {
+	for i, f := range w.embeddedFiles {
+		if f.Name == name {
+			w.embeddedFiles = append(w.embeddedFiles[:i], w.embeddedFiles[i+1:]...)
+			break
+		}
+	}
+	if w.removedEmbeddedFiles == nil {
+		w.removedEmbeddedFiles = map[string]struct{}{}
+	}
+	w.removedEmbeddedFiles[name] = struct{}{}
+}
+
+// SetCollection makes the output file a portable collection (PDF portfolio) of its embedded files.
+// The version of the output file is raised to 1.7 if lower.
+func (w *PdfWriter) SetCollection(collection *PdfCollection) {
+	w.collection = collection
+}
+
+// SetStructTreeRoot sets the structure tree of the output file, for tagged documents. Unless set
+// with SetMarkInfo, the mark information of the output file is set to indicate a tagged document.
+// The pages of the content of the tree must be added to the writer.
+func (w *PdfWriter) SetStructTreeRoot(root *PdfStructTreeRoot) {
+	w.structTreeRoot = root
+}
+
+// SetMarkInfo sets the mark information dictionary of the output file.
+func (w *PdfWriter) SetMarkInfo(info *PdfMarkInfo) {
+	w.markInfo = info
+}
+
 // SetOptimizer sets the optimizer to optimize PDF before writing.
 func (w *PdfWriter) SetOptimizer(optimizer Optimizer) {
 	w.optimizer = optimizer
@@ -479,6 +627,13 @@ func (w *PdfWriter) GetOptimizer() Optimizer {
 	return w.optimizer
 }
 
+// SetLinearized sets whether the output file is linearized (optimized for incremental access, also
+// known as fast web view). Linearized files are written with cross-reference tables, the objects of
+// object streams being written as regular objects.
+func (w *PdfWriter) SetLinearized(linearized bool) {
+	w.linearized = linearized
+}
+
 func (w *PdfWriter) hasObject(obj core.PdfObject) bool {
 	_, found := w.objectsMap[obj]
 	return found
@@ -491,7 +646,7 @@ func (w *PdfWriter) addObject(obj core.PdfObject) bool {
 	if !hasObj {
 		err := core.ResolveReferencesDeep(obj, w.traversed)
 		if err != nil {
-			common.Log.Debug("ERROR: %v - skipping", err)
+			w.log().Debug("ERROR: %v - skipping", err)
 		}
 
 		w.objects = append(w.objects, obj)
@@ -503,12 +658,12 @@ func (w *PdfWriter) addObject(obj core.PdfObject) bool {
 }
 
 func (w *PdfWriter) addObjects(obj core.PdfObject) error {
-	common.Log.Trace("Adding objects!")
+	w.log().Trace("Adding objects!")
 
 	if io, isIndirectObj := obj.(*core.PdfIndirectObject); isIndirectObj {
-		common.Log.Trace("Indirect")
-		common.Log.Trace("- %s (%p)", obj, io)
-		common.Log.Trace("- %s", io.PdfObject)
+		w.log().Trace("Indirect")
+		w.log().Trace("- %s (%p)", obj, io)
+		w.log().Trace("- %s", io.PdfObject)
 		if w.addObject(io) {
 			err := w.addObjects(io.PdfObject)
 			if err != nil {
@@ -519,8 +674,8 @@ func (w *PdfWriter) addObjects(obj core.PdfObject) error {
 	}
 
 	if so, isStreamObj := obj.(*core.PdfObjectStream); isStreamObj {
-		common.Log.Trace("Stream")
-		common.Log.Trace("- %s %p", obj, obj)
+		w.log().Trace("Stream")
+		w.log().Trace("- %s %p", obj, obj)
 		if w.addObject(so) {
 			err := w.addObjects(so.PdfObjectDictionary)
 			if err != nil {
@@ -531,8 +686,8 @@ func (w *PdfWriter) addObjects(obj core.PdfObject) error {
 	}
 
 	if dict, isDict := obj.(*core.PdfObjectDictionary); isDict {
-		common.Log.Trace("Dict")
-		common.Log.Trace("- %s", obj)
+		w.log().Trace("Dict")
+		w.log().Trace("- %s", obj)
 		for _, k := range dict.Keys() {
 			v := core.ResolveReference(dict.Get(k))
 			if k != "Parent" {
@@ -547,7 +702,7 @@ func (w *PdfWriter) addObjects(obj core.PdfObject) error {
 				}
 
 				if hasObj := w.hasObject(v); !hasObj {
-					common.Log.Debug("Parent obj not added yet!! %T %p %v", v, v, v)
+					w.log().Debug("Parent obj not added yet!! %T %p %v", v, v, v)
 					w.pendingObjects[v] = append(w.pendingObjects[v], dict)
 					// Although it is missing at this point, it could be added later...
 				}
@@ -557,7 +712,7 @@ func (w *PdfWriter) addObjects(obj core.PdfObject) error {
 					// Could refer to somewhere outside of the scope of the output doc.
 					// Should be done by the reader already.
 					// -> ERROR.
-					common.Log.Debug("ERROR: Parent is a reference object - Cannot be in writer (needs to be resolved)")
+					w.log().Debug("ERROR: Parent is a reference object - Cannot be in writer (needs to be resolved)")
 					return fmt.Errorf("parent is a reference object - Cannot be in writer (needs to be resolved) - %s", parentObj)
 				}
 			}
@@ -566,8 +721,8 @@ func (w *PdfWriter) addObjects(obj core.PdfObject) error {
 	}
 
 	if arr, isArray := obj.(*core.PdfObjectArray); isArray {
-		common.Log.Trace("Array")
-		common.Log.Trace("- %s", obj)
+		w.log().Trace("Array")
+		w.log().Trace("- %s", obj)
 		if arr == nil {
 			return errors.New("array is nil")
 		}
@@ -582,7 +737,7 @@ func (w *PdfWriter) addObjects(obj core.PdfObject) error {
 
 	if _, isReference := obj.(*core.PdfObjectReference); isReference {
 		// Should never be a reference, should already be resolved.
-		common.Log.Debug("ERROR: Cannot be a reference - got %#v!", obj)
+		w.log().Debug("ERROR: Cannot be a reference - got %#v!", obj)
 		return errors.New("reference not allowed")
 	}
 
@@ -594,15 +749,15 @@ func (w *PdfWriter) AddPage(page *PdfPage) error {
 	procPage(page)
 	obj := page.ToPdfObject()
 
-	common.Log.Trace("==========")
-	common.Log.Trace("Appending to page list %T", obj)
+	w.log().Trace("==========")
+	w.log().Trace("Appending to page list %T", obj)
 
 	pageObj, ok := core.GetIndirect(obj)
 	if !ok {
 		return errors.New("page should be an indirect object")
 	}
-	common.Log.Trace("%s", pageObj)
-	common.Log.Trace("%s", pageObj.PdfObject)
+	w.log().Trace("%s", pageObj)
+	w.log().Trace("%s", pageObj.PdfObject)
 
 	pDict, ok := core.GetDict(pageObj.PdfObject)
 	if !ok {
@@ -621,31 +776,31 @@ func (w *PdfWriter) AddPage(page *PdfPage) error {
 	// Copy inherited fields if missing.
 	inheritedFields := []core.PdfObjectName{"Resources", "MediaBox", "CropBox", "Rotate"}
 	parent, hasParent := core.GetIndirect(pDict.Get("Parent"))
-	common.Log.Trace("Page Parent: %T (%v)", pDict.Get("Parent"), hasParent)
+	w.log().Trace("Page Parent: %T (%v)", pDict.Get("Parent"), hasParent)
 	for hasParent {
-		common.Log.Trace("Page Parent: %T", parent)
+		w.log().Trace("Page Parent: %T", parent)
 		parentDict, ok := core.GetDict(parent.PdfObject)
 		if !ok {
 			return errors.New("invalid Parent object")
 		}
 		for _, field := range inheritedFields {
-			common.Log.Trace("Field %s", field)
+			w.log().Trace("Field %s", field)
 			if pDict.Get(field) != nil {
-				common.Log.Trace("- page has already")
+				w.log().Trace("- page has already")
 				continue
 			}
 
 			if obj := parentDict.Get(field); obj != nil {
 				// Parent has the field.  Inherit, pass to the new page.
-				common.Log.Trace("Inheriting field %s", field)
+				w.log().Trace("Inheriting field %s", field)
 				pDict.Set(field, obj)
 			}
 		}
 		parent, hasParent = core.GetIndirect(parentDict.Get("Parent"))
-		common.Log.Trace("Next parent: %T", parentDict.Get("Parent"))
+		w.log().Trace("Next parent: %T", parentDict.Get("Parent"))
 	}
 
-	common.Log.Trace("Traversal done")
+	w.log().Trace("Traversal done")
 
 	// Update the dictionary.
 	// Reuses the input object, updating the fields.
@@ -720,7 +875,7 @@ func (w *PdfWriter) AddOutlineTree(outlineTree *PdfOutlineTreeNode) {
 // Look for a specific key.  Returns a list of entries.
 // What if something appears on many pages?
 func (w *PdfWriter) seekByName(obj core.PdfObject, followKeys []string, key string) ([]core.PdfObject, error) {
-	common.Log.Trace("Seek by name.. %T", obj)
+	w.log().Trace("Seek by name.. %T", obj)
 	var list []core.PdfObject
 
 	if io, isIndirectObj := obj.(*core.PdfIndirectObject); isIndirectObj {
@@ -732,7 +887,7 @@ func (w *PdfWriter) seekByName(obj core.PdfObject, followKeys []string, key stri
 	}
 
 	if dict, isDict := obj.(*core.PdfObjectDictionary); isDict {
-		common.Log.Trace("Dict")
+		w.log().Trace("Dict")
 		for _, k := range dict.Keys() {
 			v := dict.Get(k)
 			if string(k) == key {
@@ -740,7 +895,7 @@ func (w *PdfWriter) seekByName(obj core.PdfObject, followKeys []string, key stri
 			}
 			for _, followKey := range followKeys {
 				if string(k) == followKey {
-					common.Log.Trace("Follow key %s", followKey)
+					w.log().Trace("Follow key %s", followKey)
 					items, err := w.seekByName(v, followKeys, key)
 					if err != nil {
 						return list, err
@@ -764,9 +919,22 @@ func (w *PdfWriter) SetForms(form *PdfAcroForm) error {
 	return nil
 }
 
+// indirectContent returns the content written for the indirect object `obj`. In strict PDF 2.0
+// mode, the document information dictionary is written without its deprecated entries, leaving the
+// dictionary of the writer unchanged.
+func (w *PdfWriter) indirectContent(obj *core.PdfIndirectObject) core.PdfObject {
+	if obj != w.infoObj || !w.strictPdf20 || w.majorVersion < 2 {
+		return obj.PdfObject
+	}
+	if info, ok := obj.PdfObject.(*core.PdfObjectDictionary); ok {
+		return pdf20Info(info)
+	}
+	return obj.PdfObject
+}
+
 // writeObject writes out an indirect / stream object.
 func (w *PdfWriter) writeObject(num int, obj core.PdfObject) {
-	common.Log.Trace("Write obj #%d\n", num)
+	w.log().Trace("Write obj #%d\n", num)
 
 	if pobj, isIndirect := obj.(*core.PdfIndirectObject); isIndirect {
 		w.crossReferenceMap[num] = crossReference{Type: 1, Offset: w.writePos, Generation: pobj.GenerationNumber}
@@ -775,10 +943,10 @@ func (w *PdfWriter) writeObject(num int, obj core.PdfObject) {
 			sDict.fileOffset = w.writePos + int64(len(outStr))
 		}
 		if pobj.PdfObject == nil {
-			common.Log.Debug("Error: indirect object's PdfObject should never be nil - setting to PdfObjectNull")
+			w.log().Debug("Error: indirect object's PdfObject should never be nil - setting to PdfObjectNull")
 			pobj.PdfObject = core.MakeNull()
 		}
-		outStr += pobj.PdfObject.WriteString()
+		outStr += w.indirectContent(pobj).WriteString()
 		outStr += "\nendobj\n"
 		w.writeString(outStr)
 		return
@@ -807,10 +975,10 @@ func (w *PdfWriter) writeObject(num int, obj core.PdfObject) {
 		for index, obj := range ostreams.Elements() {
 			io, isIndirect := obj.(*core.PdfIndirectObject)
 			if !isIndirect {
-				common.Log.Debug("ERROR: Object streams N %d contains non indirect pdf object %v", num, obj)
+				w.log().Debug("ERROR: Object streams N %d contains non indirect pdf object %v", num, obj)
 				continue
 			}
-			data := io.PdfObject.WriteString() + " "
+			data := w.indirectContent(io).WriteString() + " "
 			objData = objData + data
 			offsets = append(offsets, fmt.Sprintf("%d %d", io.ObjectNumber, offset))
 			w.crossReferenceMap[int(io.ObjectNumber)] = crossReference{Type: 2, ObjectNumber: num, Index: index}
@@ -869,7 +1037,7 @@ func (w *PdfWriter) updateObjectNumbers() {
 			o.ObjectNumber = objNum
 			o.GenerationNumber = 0
 		default:
-			common.Log.Debug("ERROR: Unknown type %T - skipping", o)
+			w.log().Debug("ERROR: Unknown type %T - skipping", o)
 			continue
 		}
 
@@ -899,6 +1067,19 @@ func (w *PdfWriter) updateObjectNumbers() {
 type EncryptOptions struct {
 	Permissions security.Permissions
 	Algorithm   EncryptionAlgorithm
+
+	// The following options leave parts of the document in clear text. They apply to the AES
+	// algorithms only, which use crypt filters.
+
+	// UnencryptedStreams leaves streams unencrypted (except embedded files, see below).
+	UnencryptedStreams bool
+	// UnencryptedStrings leaves strings unencrypted.
+	UnencryptedStrings bool
+	// UnencryptedEmbeddedFiles leaves embedded file streams unencrypted.
+	UnencryptedEmbeddedFiles bool
+	// UnencryptedMetadata leaves the XMP metadata streams unencrypted, so that they can be read
+	// without the password.
+	UnencryptedMetadata bool
 }
 
 // EncryptionAlgorithm is used in EncryptOptions to change the default algorithm used to encrypt the document.
@@ -913,6 +1094,21 @@ const (
 	AES_256bit
 )
 
+// setCryptMetadata sets the document-level metadata stream of the catalog to the crypter, so that it
+// is left unencrypted when EncryptMetadata is false.
+func (w *PdfWriter) setCryptMetadata() {
+	if w.crypter == nil || w.root == nil {
+		return
+	}
+	catalog, ok := core.GetDict(w.root)
+	if !ok {
+		return
+	}
+	if stream, ok := core.GetStream(catalog.Get("Metadata")); ok {
+		w.crypter.SetDocumentMetadata(stream)
+	}
+}
+
 // Encrypt encrypts the output file with a specified user/owner password.
 func (w *PdfWriter) Encrypt(userPass, ownerPass []byte, options *EncryptOptions) error {
 	algo := RC4_128bit
@@ -935,12 +1131,82 @@ func (w *PdfWriter) Encrypt(userPass, ownerPass []byte, options *EncryptOptions)
 	default:
 		return fmt.Errorf("unsupported algorithm: %v", options.Algorithm)
 	}
-	crypter, info, err := core.PdfCryptNewEncrypt(cf, userPass, ownerPass, perm)
+	if w.majorVersion >= 2 && algo != AES_256bit {
+		return fmt.Errorf("%v: encryption other than AES-256", ErrDeprecatedPdf20)
+	}
+	var cryptOpts *core.CryptOptions
+	if options != nil {
+		cryptOpts = &core.CryptOptions{
+			UnencryptedStreams:       options.UnencryptedStreams,
+			UnencryptedStrings:       options.UnencryptedStrings,
+			UnencryptedEmbeddedFiles: options.UnencryptedEmbeddedFiles,
+			UnencryptedMetadata:      options.UnencryptedMetadata,
+		}
+	}
+	crypter, info, err := core.PdfCryptNewEncryptWithOptions(cf, userPass, ownerPass, perm, cryptOpts)
 	if err != nil {
 		return err
 	}
+	w.setCrypter(crypter, info)
+	return nil
+}
+
+// PubKeyEncryptOptions represents options for encrypting an output PDF for a list of recipients
+// with the public-key security handler.
+type PubKeyEncryptOptions struct {
+	Algorithm EncryptionAlgorithm
+
+	// SubFilter is the format of the encryption dictionary: security.SubFilterPKCS7S3,
+	// security.SubFilterPKCS7S4 (RC4 only) or security.SubFilterPKCS7S5.
+	// Defaults to adbe.pkcs7.s4 for RC4 and adbe.pkcs7.s5 for AES.
+	SubFilter string
+}
+
+// EncryptForRecipients encrypts the output file for the certificates of the specified `recipients`
+// with the public-key security handler. Each recipient can open the document with the private key
+// of its certificate, and is granted the permissions specified for it.
+// Only certificates with RSA keys are supported.
+func (w *PdfWriter) EncryptForRecipients(recipients []security.PubKeyRecipient, options *PubKeyEncryptOptions) error {
+	algo := RC4_128bit
+	subFilter := ""
+	if options != nil {
+		algo = options.Algorithm
+		subFilter = options.SubFilter
+	}
+
+	var cf crypt.Filter
+	switch algo {
+	case RC4_128bit:
+		cf = crypt.NewFilterV2(16)
+		if subFilter == "" {
+			subFilter = security.SubFilterPKCS7S4
+		}
+	case AES_128bit:
+		cf = crypt.NewFilterAESV2()
+	case AES_256bit:
+		cf = crypt.NewFilterAESV3()
+	default:
+		return fmt.Errorf("unsupported algorithm: %v", options.Algorithm)
+	}
+	if subFilter == "" {
+		subFilter = security.SubFilterPKCS7S5
+	}
+	if w.majorVersion >= 2 && algo != AES_256bit {
+		return fmt.Errorf("%v: encryption other than AES-256", ErrDeprecatedPdf20)
+	}
+	crypter, info, err := core.PdfCryptNewEncryptPubKey(cf, subFilter, recipients)
+	if err != nil {
+		return err
+	}
+	w.setCrypter(crypter, info)
+	return nil
+}
+
+// setCrypter sets the crypter used to encrypt the output file and adds the encryption dictionary.
+func (w *PdfWriter) setCrypter(crypter *core.PdfCrypt, info *core.EncryptInfo) {
 	w.crypter = crypter
-	if info.Major != 0 {
+	// The encryption algorithm may require a later version, but should not downgrade it.
+	if info.Major != 0 && versionLess(core.Version{Major: w.majorVersion, Minor: w.minorVersion}, info.Version) {
 		w.SetVersion(info.Major, info.Minor)
 	}
 	w.encryptDict = info.Encrypt
@@ -951,8 +1217,6 @@ func (w *PdfWriter) Encrypt(userPass, ownerPass []byte, options *EncryptOptions)
 	io := core.MakeIndirectObject(info.Encrypt)
 	w.encryptObj = io
 	w.addObject(io)
-
-	return nil
 }
 
 // Wrapper function to handle writing out string.
@@ -977,7 +1241,7 @@ func (w *PdfWriter) writeBytes(bb []byte) {
 
 // Write writes out the PDF.
 func (w *PdfWriter) Write(writer io.Writer) error {
-	common.Log.Trace("Write()")
+	w.log().Trace("Write()")
 
 	lk := license.GetLicenseKey()
 	if lk == nil || !lk.IsLicensed() {
@@ -987,9 +1251,9 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 
 	// Outlines.
 	if w.outlineTree != nil {
-		common.Log.Trace("OutlineTree: %+v", w.outlineTree)
+		w.log().Trace("OutlineTree: %+v", w.outlineTree)
 		outlines := w.outlineTree.ToPdfObject()
-		common.Log.Trace("Outlines: %+v (%T, p:%p)", outlines, outlines, outlines)
+		w.log().Trace("Outlines: %+v (%T, p:%p)", outlines, outlines, outlines)
 		w.catalog.Set("Outlines", outlines)
 		err := w.addObjects(outlines)
 		if err != nil {
@@ -999,9 +1263,9 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 
 	// Form fields.
 	if w.acroForm != nil {
-		common.Log.Trace("Writing acro forms")
+		w.log().Trace("Writing acro forms")
 		indObj := w.acroForm.ToPdfObject()
-		common.Log.Trace("AcroForm: %+v", indObj)
+		w.log().Trace("AcroForm: %+v", indObj)
 		w.catalog.Set("AcroForm", indObj)
 		err := w.addObjects(indObj)
 		if err != nil {
@@ -1009,15 +1273,73 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 		}
 	}
 
+	// XMP metadata, in sync with the document information dictionary. The packet set with
+	// SetXMPMetadata is left unchanged.
+	if w.xmp != nil {
+		xmp := w.xmp.Copy()
+		if infoDict, ok := core.GetDict(w.infoObj); ok {
+			info, err := NewPdfInfoFromObject(infoDict)
+			if err != nil {
+				return err
+			}
+			xmp.SetPdfInfo(info)
+		}
+		metadata := xmp.ToPdfObject()
+		w.catalog.Set("Metadata", metadata)
+		if err := w.addObjects(metadata); err != nil {
+			return err
+		}
+	}
+
+	// Embedded files.
+	if len(w.embeddedFiles) > 0 || len(w.removedEmbeddedFiles) > 0 {
+		objects, err := updateEmbeddedFiles(w.catalog, w.embeddedFiles, w.removedEmbeddedFiles)
+		if err != nil {
+			return err
+		}
+		for _, obj := range objects {
+			if err := w.addObjects(obj); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Portable collection.
+	if w.collection != nil {
+		collection := w.collection.ToPdfObject()
+		w.catalog.Set("Collection", collection)
+		if err := w.addObjects(collection); err != nil {
+			return err
+		}
+		if w.majorVersion == 1 && w.minorVersion < 7 {
+			w.minorVersion = 7
+		}
+	}
+
+	// Logical structure.
+	if w.structTreeRoot != nil {
+		structTreeRoot := w.structTreeRoot.ToPdfObject()
+		w.catalog.Set("StructTreeRoot", structTreeRoot)
+		if err := w.addObjects(structTreeRoot); err != nil {
+			return err
+		}
+		if w.markInfo == nil {
+			w.catalog.Set("MarkInfo", (&PdfMarkInfo{Marked: true}).ToPdfObject())
+		}
+	}
+	if w.markInfo != nil {
+		w.catalog.Set("MarkInfo", w.markInfo.ToPdfObject())
+	}
+
 	// Check pending objects prior to write.
 	for pendingObj, pendingObjDicts := range w.pendingObjects {
 		if !w.hasObject(pendingObj) {
-			common.Log.Debug("WARN Pending object %+v %T (%p) never added for writing", pendingObj, pendingObj, pendingObj)
+			w.log().Debug("WARN Pending object %+v %T (%p) never added for writing", pendingObj, pendingObj, pendingObj)
 			for _, pendingObjDict := range pendingObjDicts {
 				for _, key := range pendingObjDict.Keys() {
 					val := pendingObjDict.Get(key)
 					if val == pendingObj {
-						common.Log.Debug("Pending object found! and replaced with null")
+						w.log().Debug("Pending object found! and replaced with null")
 						pendingObjDict.Set(key, core.MakeNull())
 						break
 					}
@@ -1046,6 +1368,16 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 		w.objectsMap = objMap
 	}
 
+	if w.majorVersion >= 2 {
+		if err := w.checkPdf20(); err != nil {
+			return err
+		}
+	}
+
+	if w.linearized {
+		return w.writeLinearized(writer)
+	}
+
 	w.writePos = w.writeOffset
 	w.writer = bufio.NewWriter(writer)
 	useCrossReferenceStream := w.majorVersion > 1 || (w.majorVersion == 1 && w.minorVersion > 4)
@@ -1082,7 +1414,7 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 	w.updateObjectNumbers()
 
 	// Write objects
-	common.Log.Trace("Writing %d obj", len(w.objects))
+	w.log().Trace("Writing %d obj", len(w.objects))
 	w.crossReferenceMap = make(map[int]crossReference)
 	w.crossReferenceMap[0] = crossReference{Type: 0, ObjectNumber: 0, Generation: 0xFFFF}
 	if w.appendToXrefs.ObjectMap != nil {
@@ -1102,6 +1434,7 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 	}
 
 	// Write out indirect/stream objects that are not in object streams.
+	w.setCryptMetadata()
 	for _, obj := range w.objects {
 		if skip := objectsInObjectStreams[obj]; skip {
 			continue
@@ -1116,7 +1449,7 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 		case *core.PdfObjectStreams:
 			objectNumber = t.ObjectNumber
 		default:
-			common.Log.Debug("ERROR: Unsupported type in writer objects: %T", obj)
+			w.log().Debug("ERROR: Unsupported type in writer objects: %T", obj)
 			return ErrTypeCheck
 		}
 
@@ -1125,7 +1458,7 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 		if w.crypter != nil && obj != w.encryptObj {
 			err := w.crypter.Encrypt(obj, int64(objectNumber), 0)
 			if err != nil {
-				common.Log.Debug("ERROR: Failed encrypting (%s)", err)
+				w.log().Debug("ERROR: Failed encrypting (%s)", err)
 				return err
 			}
 		}
@@ -1205,7 +1538,7 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 		if w.crypter != nil {
 			crossReferenceStream.Set("Encrypt", w.encryptObj)
 			crossReferenceStream.Set("ID", w.ids)
-			common.Log.Trace("Ids: %s", w.ids)
+			w.log().Trace("Ids: %s", w.ids)
 		}
 
 		w.writeObject(int(crossReferenceStream.ObjectNumber), crossReferenceStream)
@@ -1258,7 +1591,7 @@ func (w *PdfWriter) Write(writer io.Writer) error {
 		if w.crypter != nil {
 			trailer.Set("Encrypt", w.encryptObj)
 			trailer.Set("ID", w.ids)
-			common.Log.Trace("Ids: %s", w.ids)
+			w.log().Trace("Ids: %s", w.ids)
 		}
 		w.writeString("trailer\n")
 		w.writeString(trailer.WriteString())
@@ -0,0 +1,526 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// linearizedLayout contains the objects of a linearized file grouped by the sections of the file
+// (Annex F.3 Linearized PDF document structure).
+type linearizedLayout struct {
+	// Catalog and other objects needed to open the document.
+	document []core.PdfObject
+	// First page section, starting with the page object of the first page.
+	firstPage []core.PdfObject
+	// Objects of the remaining pages, each one starting with the page object.
+	pages [][]core.PdfObject
+	// Objects shared by pages other than the first page.
+	shared []core.PdfObject
+	// Objects not needed for displaying pages (e.g. document information, page tree nodes).
+	other []core.PdfObject
+
+	// Objects referenced by each page, in traversal order.
+	pageRefs [][]core.PdfObject
+}
+
+// linearizedObjectKeys are the catalog entries whose objects are needed to open the document.
+var linearizedObjectKeys = []core.PdfObjectName{"ViewerPreferences", "PageMode", "Threads", "OpenAction", "AcroForm"}
+
+// isPageTreeNode returns true if `obj` is a page object or an intermediate node of the page tree.
+func isPageTreeNode(obj core.PdfObject) bool {
+	ind, ok := obj.(*core.PdfIndirectObject)
+	if !ok {
+		return false
+	}
+	dict, ok := ind.PdfObject.(*core.PdfObjectDictionary)
+	if !ok {
+		return false
+	}
+	typ, _ := core.GetNameVal(dict.Get("Type"))
+	return typ == "Page" || typ == "Pages"
+}
+
+// linearizedPages returns the page objects of the page tree rooted at `node`, in page order.
+func linearizedPages(node core.PdfObject, visited map[core.PdfObject]struct{}) []*core.PdfIndirectObject {
+	ind, ok := node.(*core.PdfIndirectObject)
+	if !ok {
+		return nil
+	}
+	if _, ok := visited[ind]; ok {
+		return nil
+	}
+	visited[ind] = struct{}{}
+	dict, ok := ind.PdfObject.(*core.PdfObjectDictionary)
+	if !ok {
+		return nil
+	}
+	if typ, _ := core.GetNameVal(dict.Get("Type")); typ == "Page" {
+		return []*core.PdfIndirectObject{ind}
+	}
+
+	var pages []*core.PdfIndirectObject
+	kids, _ := core.GetArray(dict.Get("Kids"))
+	for _, kid := range kids.Elements() {
+		pages = append(pages, linearizedPages(kid, visited)...)
+	}
+	return pages
+}
+
+// linearizedRefs returns the indirect and stream objects in `objects` that are reachable from
+// `obj`, in depth-first order. Parent entries, the catalog and page tree nodes other than `page`
+// are not followed.
+func (w *PdfWriter) linearizedRefs(obj core.PdfObject, page core.PdfObject,
+	objects map[core.PdfObject]struct{}) []core.PdfObject {
+	var refs []core.PdfObject
+	visited := map[core.PdfObject]struct{}{}
+
+	var visit func(obj core.PdfObject)
+	visit = func(obj core.PdfObject) {
+		if _, ok := visited[obj]; ok {
+			return
+		}
+		switch t := obj.(type) {
+		case *core.PdfIndirectObject:
+			if t == w.root || (t != page && isPageTreeNode(t)) {
+				return
+			}
+			visited[obj] = struct{}{}
+			if _, ok := objects[obj]; ok {
+				refs = append(refs, obj)
+			}
+			visit(t.PdfObject)
+		case *core.PdfObjectStream:
+			visited[obj] = struct{}{}
+			if _, ok := objects[obj]; ok {
+				refs = append(refs, obj)
+			}
+			visit(t.PdfObjectDictionary)
+		case *pdfSignDictionary:
+			visit(t.PdfObjectDictionary)
+		case *core.PdfObjectDictionary:
+			visited[obj] = struct{}{}
+			for _, key := range t.Keys() {
+				if key != "Parent" {
+					visit(t.Get(key))
+				}
+			}
+		case *core.PdfObjectArray:
+			visited[obj] = struct{}{}
+			for _, elem := range t.Elements() {
+				visit(elem)
+			}
+		}
+	}
+	visit(obj)
+	return refs
+}
+
+// linearizedLayout groups the objects to write by section of the linearized file.
+func (w *PdfWriter) linearizedLayout(objects []core.PdfObject) (*linearizedLayout, error) {
+	objectSet := make(map[core.PdfObject]struct{}, len(objects))
+	for _, obj := range objects {
+		objectSet[obj] = struct{}{}
+	}
+
+	catalog, ok := core.GetDict(w.root)
+	if !ok {
+		return nil, errors.New("invalid catalog")
+	}
+	pages := linearizedPages(catalog.Get("Pages"), map[core.PdfObject]struct{}{})
+	if len(pages) == 0 {
+		return nil, errors.New("linearization requires at least one page")
+	}
+
+	layout := &linearizedLayout{pages: make([][]core.PdfObject, len(pages))}
+	users := map[core.PdfObject]int{}
+	for _, page := range pages {
+		if _, ok := objectSet[page]; !ok {
+			return nil, errors.New("page object not included for writing")
+		}
+		refs := w.linearizedRefs(page, page, objectSet)
+		for _, obj := range refs {
+			users[obj]++
+		}
+		layout.pageRefs = append(layout.pageRefs, refs)
+	}
+
+	assigned := map[core.PdfObject]struct{}{}
+	assign := func(list *[]core.PdfObject, obj core.PdfObject) {
+		if _, ok := assigned[obj]; ok {
+			return
+		}
+		if _, ok := objectSet[obj]; !ok {
+			return
+		}
+		assigned[obj] = struct{}{}
+		*list = append(*list, obj)
+	}
+
+	// Everything needed to display the first page goes in the first page section.
+	for _, obj := range layout.pageRefs[0] {
+		assign(&layout.firstPage, obj)
+	}
+	for i := 1; i < len(pages); i++ {
+		for _, obj := range layout.pageRefs[i] {
+			if users[obj] == 1 {
+				assign(&layout.pages[i], obj)
+			}
+		}
+	}
+	for i := 1; i < len(pages); i++ {
+		for _, obj := range layout.pageRefs[i] {
+			assign(&layout.shared, obj)
+		}
+	}
+	layout.pages = layout.pages[1:]
+
+	assign(&layout.document, w.root)
+	if w.encryptObj != nil {
+		assign(&layout.document, w.encryptObj)
+	}
+	for _, key := range linearizedObjectKeys {
+		if obj := catalog.Get(key); obj != nil {
+			for _, ref := range w.linearizedRefs(obj, nil, objectSet) {
+				assign(&layout.document, ref)
+			}
+		}
+	}
+	for _, obj := range objects {
+		assign(&layout.other, obj)
+	}
+	return layout, nil
+}
+
+// setObjectNumber sets the object number of the indirect or stream object `obj`.
+func setObjectNumber(obj core.PdfObject, num int64) {
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		t.ObjectNumber = num
+		t.GenerationNumber = 0
+	case *core.PdfObjectStream:
+		t.ObjectNumber = num
+		t.GenerationNumber = 0
+	}
+}
+
+// objectNumber returns the object number of the indirect or stream object `obj`.
+func objectNumber(obj core.PdfObject) int64 {
+	switch t := obj.(type) {
+	case *core.PdfIndirectObject:
+		return t.ObjectNumber
+	case *core.PdfObjectStream:
+		return t.ObjectNumber
+	}
+	return 0
+}
+
+// padRight pads `s` with spaces to `width` characters.
+func padRight(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-len(s))
+}
+
+// writeLinearized writes out a linearized PDF file (Annex F Linearized PDF) to `out`.
+// The file is made of the following sections:
+//   - header, linearization parameter dictionary, first page cross-reference table and trailer,
+//   - catalog and document-level objects, primary hint stream,
+//   - first page section, remaining pages, shared objects, other objects,
+//   - main cross-reference table and trailer.
+//
+// Cross-reference tables are used, the objects of object streams being written as regular objects.
+// The positions of all objects are computed first, so that the linearization parameters, the hint
+// tables and the first page cross-reference table can be written ahead of the objects.
+func (w *PdfWriter) writeLinearized(out io.Writer) error {
+	if w.appendMode {
+		return errors.New("linearization is not supported in append mode")
+	}
+
+	var objects []core.PdfObject
+	for _, obj := range w.objects {
+		switch t := obj.(type) {
+		case *core.PdfIndirectObject, *core.PdfObjectStream:
+			objects = append(objects, obj)
+		case *core.PdfObjectStreams:
+			objects = append(objects, t.Elements()...)
+		default:
+			w.log().Debug("ERROR: Unsupported type in writer objects: %T", obj)
+			return ErrTypeCheck
+		}
+	}
+
+	layout, err := w.linearizedLayout(objects)
+	if err != nil {
+		return err
+	}
+
+	// Number the objects. The objects of the main cross-reference table (remaining pages, shared and
+	// other objects) come first, followed by the objects of the first page cross-reference table.
+	var mainObjs []core.PdfObject
+	for _, pageObjs := range layout.pages {
+		mainObjs = append(mainObjs, pageObjs...)
+	}
+	mainObjs = append(mainObjs, layout.shared...)
+	mainObjs = append(mainObjs, layout.other...)
+	for i, obj := range mainObjs {
+		setObjectNumber(obj, int64(i+1))
+	}
+	linNum := int64(len(mainObjs) + 1)
+	num := linNum + 1
+	for _, obj := range layout.document {
+		setObjectNumber(obj, num)
+		num++
+	}
+	hintNum := num
+	num++
+	for _, obj := range layout.firstPage {
+		setObjectNumber(obj, num)
+		num++
+	}
+	size := num
+
+	// Encrypt prior to writing. Encrypt dictionary should not be encrypted.
+	if w.crypter != nil {
+		w.setCryptMetadata()
+		for _, obj := range objects {
+			if obj == w.encryptObj {
+				continue
+			}
+			if err := w.crypter.Encrypt(obj, objectNumber(obj), 0); err != nil {
+				w.log().Debug("ERROR: Failed encrypting (%s)", err)
+				return err
+			}
+		}
+	}
+
+	// Measure the objects.
+	w.writer = bufio.NewWriter(ioutil.Discard)
+	w.crossReferenceMap = make(map[int]crossReference)
+	objLen := make(map[core.PdfObject]int64, len(objects))
+	measure := func(obj core.PdfObject) int64 {
+		start := w.writePos
+		w.writeObject(int(objectNumber(obj)), obj)
+		return w.writePos - start
+	}
+	for _, obj := range objects {
+		objLen[obj] = measure(obj)
+	}
+
+	// Compute the positions of the objects, as if the hint stream was not present.
+	header := fmt.Sprintf("%%PDF-%d.%d\n%%âãÏÓ\n", w.majorVersion, w.minorVersion)
+	linearizationDict := func(fileLen, hintOffset, hintLen, firstPageEnd, mainXref int64) string {
+		return fmt.Sprintf("%d 0 obj\n<< /Linearized 1 /L %d /H [ %d %d ] /O %d /E %d /N %d /T %d >>",
+			linNum, fileLen, hintOffset, hintLen, objectNumber(layout.firstPage[0]), firstPageEnd,
+			len(layout.pages)+1, mainXref)
+	}
+	const maxOffset = 9999999999
+	linWidth := len(linearizationDict(maxOffset, maxOffset, maxOffset, maxOffset, maxOffset))
+	linLen := int64(linWidth + len("\nendobj\n"))
+
+	firstTrailer := func(mainXrefOffset int64) string {
+		trailer := core.MakeDict()
+		trailer.Set("Size", core.MakeInteger(size))
+		trailer.Set("Root", w.root)
+		trailer.Set("Info", w.infoObj)
+		trailer.Set("Prev", core.MakeInteger(mainXrefOffset))
+		if w.crypter != nil {
+			trailer.Set("Encrypt", w.encryptObj)
+			trailer.Set("ID", w.ids)
+		}
+		return trailer.WriteString()
+	}
+	trailerWidth := len(firstTrailer(maxOffset))
+	firstXrefHead := fmt.Sprintf("xref\r\n%d %d\r\n", linNum, size-linNum)
+	firstXrefLen := int64(len(firstXrefHead)) + 20*(size-linNum) +
+		int64(len("trailer\n")+trailerWidth+len("\nstartxref\n0\n%%EOF\n"))
+
+	offsets := make(map[core.PdfObject]int64, len(objects))
+	pos := int64(len(header))
+	linOffset := pos
+	pos += linLen
+	firstXrefOffset := pos
+	pos += firstXrefLen
+	place := func(objs []core.PdfObject) {
+		for _, obj := range objs {
+			offsets[obj] = pos
+			pos += objLen[obj]
+		}
+	}
+	place(layout.document)
+	hintOffset := pos
+	place(layout.firstPage)
+	firstPageEnd := pos
+	for _, pageObjs := range layout.pages {
+		place(pageObjs)
+	}
+	place(layout.shared)
+	place(layout.other)
+
+	// Hint stream.
+	hintData, sharedOffset, err := w.linearizedHintTables(layout, offsets, objLen).Encode()
+	if err != nil {
+		return err
+	}
+	hintStream, err := core.MakeStream(hintData, core.NewFlateEncoder())
+	if err != nil {
+		return err
+	}
+	hintStream.Set("S", core.MakeInteger(sharedOffset))
+	hintStream.ObjectNumber = hintNum
+	if w.crypter != nil {
+		if err := w.crypter.Encrypt(hintStream, hintNum, 0); err != nil {
+			return err
+		}
+	}
+	hintLen := measure(hintStream)
+
+	// Actual positions.
+	for obj, offset := range offsets {
+		if offset >= hintOffset {
+			offsets[obj] = offset + hintLen
+		}
+	}
+	offsets[hintStream] = hintOffset
+	firstPageEnd += hintLen
+	mainXrefOffset := pos + hintLen
+	mainXrefHead := fmt.Sprintf("xref\r\n0 %d\r\n", linNum)
+	mainXrefTail := fmt.Sprintf("trailer\n<< /Size %d >>\nstartxref\n%d\n%%%%EOF\n", linNum, firstXrefOffset)
+	fileLen := mainXrefOffset + int64(len(mainXrefHead)) + 20*linNum + int64(len(mainXrefTail))
+	// Offset of the white-space preceding the first entry.
+	firstEntryOffset := mainXrefOffset + int64(len(mainXrefHead)) - 1
+
+	writeEntries := func(objs []core.PdfObject) {
+		for _, obj := range objs {
+			w.writeString(fmt.Sprintf("%.10d %.5d n\r\n", offsets[obj], 0))
+		}
+	}
+
+	// Write out.
+	w.writePos = 0
+	w.writer = bufio.NewWriter(out)
+	w.crossReferenceMap = make(map[int]crossReference)
+	w.writeString(header)
+
+	linDict := linearizationDict(fileLen, hintOffset, hintLen, firstPageEnd, firstEntryOffset)
+	w.writeString(padRight(linDict, linWidth))
+	w.writeString("\nendobj\n")
+
+	w.writeString(firstXrefHead)
+	w.writeString(fmt.Sprintf("%.10d %.5d n\r\n", linOffset, 0))
+	writeEntries(layout.document)
+	writeEntries([]core.PdfObject{hintStream})
+	writeEntries(layout.firstPage)
+	w.writeString("trailer\n")
+	w.writeString(padRight(firstTrailer(mainXrefOffset), trailerWidth))
+	w.writeString("\nstartxref\n0\n%%EOF\n")
+
+	for _, obj := range layout.document {
+		w.writeObject(int(objectNumber(obj)), obj)
+	}
+	w.writeObject(int(hintNum), hintStream)
+	for _, obj := range layout.firstPage {
+		w.writeObject(int(objectNumber(obj)), obj)
+	}
+	for _, obj := range mainObjs {
+		w.writeObject(int(objectNumber(obj)), obj)
+	}
+
+	// Positions of the objects were computed beforehand, check them.
+	for obj, offset := range offsets {
+		if ref := w.crossReferenceMap[int(objectNumber(obj))]; ref.Offset != offset {
+			w.log().Debug("ERROR: object %d written at %d, expected %d", objectNumber(obj), ref.Offset, offset)
+			return errors.New("linearized object offset mismatch")
+		}
+	}
+
+	w.writeString(mainXrefHead)
+	w.writeString(fmt.Sprintf("%.10d %.5d f\r\n", 0, 65535))
+	writeEntries(mainObjs)
+	w.writeString(mainXrefTail)
+
+	if w.werr == nil {
+		w.werr = w.writer.Flush()
+	}
+	if w.werr == nil && w.writePos != fileLen {
+		w.log().Debug("ERROR: linearized file length %d, expected %d", w.writePos, fileLen)
+		return errors.New("linearized file length mismatch")
+	}
+	return w.werr
+}
+
+// linearizedHintTables returns the hint tables of the linearized file, given the `offsets` and the
+// lengths `objLen` of the objects, as if the hint stream was not present.
+func (w *PdfWriter) linearizedHintTables(layout *linearizedLayout, offsets,
+	objLen map[core.PdfObject]int64) *core.HintTables {
+	groupLen := func(objs []core.PdfObject) int64 {
+		var length int64
+		for _, obj := range objs {
+			length += objLen[obj]
+		}
+		return length
+	}
+
+	// Shared object groups: one for each object of the first page section and of the shared objects
+	// section.
+	tables := &core.HintTables{}
+	shared := &tables.SharedObjects
+	sharedIDs := map[core.PdfObject]int{}
+	for _, obj := range append(append([]core.PdfObject{}, layout.firstPage...), layout.shared...) {
+		sharedIDs[obj] = len(shared.Groups)
+		shared.Groups = append(shared.Groups, core.SharedObjectHint{Length: objLen[obj], NumObjects: 1})
+	}
+	shared.NumFirstPageEntries = len(layout.firstPage)
+	if len(layout.shared) > 0 {
+		shared.FirstObjectNumber = int(objectNumber(layout.shared[0]))
+		shared.FirstObjectOffset = offsets[layout.shared[0]]
+	}
+
+	pageGroups := append([][]core.PdfObject{layout.firstPage}, layout.pages...)
+	tables.PageOffsets.FirstPageOffset = offsets[layout.firstPage[0]]
+	for i, objs := range pageGroups {
+		start := offsets[objs[0]]
+		end := start + groupLen(objs)
+		hint := core.PageOffsetHint{NumObjects: len(objs), Length: end - start}
+		if i > 0 {
+			for _, obj := range layout.pageRefs[i] {
+				if id, ok := sharedIDs[obj]; ok {
+					hint.SharedObjects = append(hint.SharedObjects, id)
+				}
+			}
+		}
+
+		// Content streams, when contained in the page.
+		var streams []core.PdfObject
+		pageDict, _ := core.GetDict(objs[0])
+		switch t := pageDict.Get("Contents").(type) {
+		case *core.PdfObjectStream:
+			streams = append(streams, t)
+		case *core.PdfObjectArray:
+			streams = t.Elements()
+		}
+		if len(streams) > 0 {
+			first, last := streams[0], streams[len(streams)-1]
+			firstOffset, hasFirst := offsets[first]
+			lastOffset, hasLast := offsets[last]
+			if hasFirst && hasLast && firstOffset >= start && lastOffset+objLen[last] <= end &&
+				lastOffset >= firstOffset {
+				hint.ContentOffset = firstOffset - start
+				hint.ContentLength = lastOffset + objLen[last] - firstOffset
+			}
+		}
+		tables.PageOffsets.Pages = append(tables.PageOffsets.Pages, hint)
+	}
+	return tables
+}
@@ -7,11 +7,22 @@ package model
 
 import (
 	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"errors"
+	"io/ioutil"
+	"math/big"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/core/security"
 )
 
 // Tests loading annotations from file, writing back out and reloading.
@@ -127,3 +138,280 @@ func TestWriterErrorHandling(t *testing.T) {
 	err = w.Write(&out)
 	require.Error(t, err)
 }
+
+// TestWriterLinearized tests writing linearized documents and validating them on reading.
+func TestWriterLinearized(t *testing.T) {
+	f, err := os.Open("testdata/pages3.pdf")
+	require.NoError(t, err)
+	defer f.Close()
+
+	reader, err := NewPdfReader(f)
+	require.NoError(t, err)
+	require.False(t, reader.IsLinearized())
+	require.Error(t, reader.ValidateLinearization())
+
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+	var contents []string
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		require.NoError(t, err)
+		content, err := page.GetAllContentStreams()
+		require.NoError(t, err)
+		contents = append(contents, content)
+	}
+
+	write := func(encrypt bool) []byte {
+		w := NewPdfWriter()
+		for i := 1; i <= numPages; i++ {
+			page, err := reader.GetPage(i)
+			require.NoError(t, err)
+			require.NoError(t, w.AddPage(page))
+		}
+		if encrypt {
+			require.NoError(t, w.Encrypt([]byte("user"), []byte("owner"), nil))
+		}
+		w.SetLinearized(true)
+		var buf bytes.Buffer
+		require.NoError(t, w.Write(&buf))
+		return buf.Bytes()
+	}
+
+	for _, encrypt := range []bool{false, true} {
+		data := write(encrypt)
+		lreader, err := NewPdfReader(bytes.NewReader(data))
+		require.NoError(t, err)
+		if encrypt {
+			ok, err := lreader.Decrypt([]byte("user"))
+			require.NoError(t, err)
+			require.True(t, ok)
+		}
+
+		require.True(t, lreader.IsLinearized())
+		params := lreader.GetLinearizationParams()
+		require.NotNil(t, params)
+		require.Equal(t, int64(len(data)), params.FileLength)
+		require.Equal(t, numPages, params.NumPages)
+		require.Len(t, lreader.GetRevisions(), 1)
+		require.NoError(t, lreader.ValidateLinearization())
+
+		tables, err := lreader.GetHintTables()
+		require.NoError(t, err)
+		require.Len(t, tables.PageOffsets.Pages, numPages)
+
+		n, err := lreader.GetNumPages()
+		require.NoError(t, err)
+		require.Equal(t, numPages, n)
+		for i := 1; i <= n; i++ {
+			page, err := lreader.GetPage(i)
+			require.NoError(t, err)
+			content, err := page.GetAllContentStreams()
+			require.NoError(t, err)
+			// Unlicensed copies are watermarked.
+			require.True(t, strings.HasPrefix(content, contents[i-1]))
+		}
+
+		// Updating the document invalidates the linearization.
+		updated := append(append([]byte{}, data...), []byte("\n%%EOF\n")...)
+		ureader, err := NewPdfReader(bytes.NewReader(updated))
+		require.NoError(t, err)
+		require.False(t, ureader.IsLinearized())
+		require.NotNil(t, ureader.GetLinearizationParams())
+		require.Error(t, ureader.ValidateLinearization())
+	}
+}
+
+// makeTestRecipient returns a self-signed certificate and its private key for encrypting documents.
+func makeTestRecipient(t *testing.T, serial int64, name string) (*x509.Certificate, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+// TestWriterEncryptForRecipients tests encrypting documents for certificates with the public-key
+// security handler and decrypting them with the private keys of the recipients.
+func TestWriterEncryptForRecipients(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/pages3.pdf")
+	require.NoError(t, err)
+
+	reader, err := NewPdfReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	numPages, err := reader.GetNumPages()
+	require.NoError(t, err)
+	var contents []string
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		require.NoError(t, err)
+		content, err := page.GetAllContentStreams()
+		require.NoError(t, err)
+		contents = append(contents, content)
+	}
+
+	owner, ownerKey := makeTestRecipient(t, 1, "Owner")
+	user, userKey := makeTestRecipient(t, 2, "User")
+	other, otherKey := makeTestRecipient(t, 3, "Other")
+	userPerms := security.PermPrinting | security.PermFillForms
+	recipients := []security.PubKeyRecipient{
+		{Certificate: owner, Permissions: security.PermOwner},
+		{Certificate: user, Permissions: userPerms},
+	}
+
+	cases := []struct {
+		options   *PubKeyEncryptOptions
+		subFilter string
+		method    string
+	}{
+		{nil, security.SubFilterPKCS7S4, "RC4: 128 bits"},
+		{&PubKeyEncryptOptions{SubFilter: security.SubFilterPKCS7S3}, security.SubFilterPKCS7S3, "RC4: 128 bits"},
+		{&PubKeyEncryptOptions{Algorithm: AES_128bit}, security.SubFilterPKCS7S5, "AESV2"},
+		{&PubKeyEncryptOptions{Algorithm: AES_256bit}, security.SubFilterPKCS7S5, "AESV3"},
+	}
+	for _, c := range cases {
+		// Objects are encrypted in place when writing.
+		reader, err := NewPdfReader(bytes.NewReader(data))
+		require.NoError(t, err)
+		w := NewPdfWriter()
+		for i := 1; i <= numPages; i++ {
+			page, err := reader.GetPage(i)
+			require.NoError(t, err)
+			require.NoError(t, w.AddPage(page))
+		}
+		require.NoError(t, w.EncryptForRecipients(recipients, c.options))
+		var buf bytes.Buffer
+		require.NoError(t, w.Write(&buf))
+		require.Contains(t, buf.String(), "/SubFilter /"+c.subFilter)
+
+		open := func() *PdfReader {
+			r, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err)
+			encrypted, err := r.IsEncrypted()
+			require.NoError(t, err)
+			require.True(t, encrypted)
+			return r
+		}
+
+		// Passwords cannot be used.
+		r := open()
+		_, err = r.Decrypt([]byte(""))
+		require.Error(t, err)
+
+		r = open()
+		ok, err := r.DecryptWithCertificate(other, otherKey)
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		for _, recipient := range []struct {
+			cert  *x509.Certificate
+			key   *rsa.PrivateKey
+			perms security.Permissions
+		}{
+			{owner, ownerKey, security.PermOwner},
+			{user, userKey, userPerms},
+		} {
+			r := open()
+			ok, err := r.DecryptWithCertificate(recipient.cert, recipient.key)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, recipient.perms, r.GetAccessPermissions())
+			require.Contains(t, r.GetEncryptionMethod(), c.method)
+
+			n, err := r.GetNumPages()
+			require.NoError(t, err)
+			require.Equal(t, numPages, n)
+			for i := 1; i <= n; i++ {
+				page, err := r.GetPage(i)
+				require.NoError(t, err)
+				content, err := page.GetAllContentStreams()
+				require.NoError(t, err)
+				// Unlicensed copies are watermarked.
+				require.True(t, strings.HasPrefix(content, contents[i-1]))
+			}
+		}
+	}
+
+	// RC4 only for adbe.pkcs7.s3 and adbe.pkcs7.s4.
+	w := NewPdfWriter()
+	err = w.EncryptForRecipients(recipients, &PubKeyEncryptOptions{Algorithm: AES_128bit, SubFilter: security.SubFilterPKCS7S4})
+	require.Error(t, err)
+}
+
+func TestWriterEncryptUnencryptedParts(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/pages3.pdf")
+	require.NoError(t, err)
+	const xmp = `<x:xmpmeta xmlns:x="adobe:ns:meta/"></x:xmpmeta>`
+	const docMarker = "Document metadata"
+
+	cases := []struct {
+		options       *EncryptOptions
+		clearMetadata bool
+		clearContents bool
+	}{
+		{&EncryptOptions{Algorithm: AES_256bit}, false, false},
+		{&EncryptOptions{Algorithm: AES_256bit, UnencryptedMetadata: true}, true, false},
+		{&EncryptOptions{Algorithm: AES_128bit, UnencryptedStreams: true}, true, true},
+		// Not supported by RC4.
+		{&EncryptOptions{Algorithm: RC4_128bit, UnencryptedMetadata: true}, false, false},
+	}
+	for _, c := range cases {
+		// Objects are encrypted in place when writing.
+		reader, err := NewPdfReader(bytes.NewReader(data))
+		require.NoError(t, err)
+		page, err := reader.GetPage(1)
+		require.NoError(t, err)
+		content, err := page.GetAllContentStreams()
+		require.NoError(t, err)
+
+		// Metadata of the page, encrypted unless all the streams are unencrypted.
+		metadata, err := core.MakeStream([]byte(xmp), nil)
+		require.NoError(t, err)
+		metadata.Set("Type", core.MakeName("Metadata"))
+		metadata.Set("Subtype", core.MakeName("XML"))
+		page.Metadata = metadata
+
+		// Metadata of the document.
+		docXMP := NewXMPMetadata()
+		docXMP.SetProperty(XMPNamespacePDFX, "TestMarker", docMarker)
+
+		w := NewPdfWriter()
+		require.NoError(t, w.AddPage(page))
+		w.SetXMPMetadata(docXMP)
+		require.NoError(t, w.Encrypt([]byte(""), []byte("owner"), c.options))
+		var buf bytes.Buffer
+		require.NoError(t, w.Write(&buf))
+		require.Equal(t, c.clearMetadata, strings.Contains(buf.String(), docMarker))
+		require.Equal(t, c.clearContents, strings.Contains(buf.String(), xmp))
+
+		r, err := NewPdfReader(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		ok, err := r.Decrypt([]byte("owner"))
+		require.NoError(t, err)
+		require.True(t, ok)
+		rpage, err := r.GetPage(1)
+		require.NoError(t, err)
+		rcontent, err := rpage.GetAllContentStreams()
+		require.NoError(t, err)
+		// Unlicensed copies are watermarked.
+		require.True(t, strings.HasPrefix(rcontent, content))
+		stream, ok := core.GetStream(rpage.Metadata)
+		require.True(t, ok)
+		require.Equal(t, xmp, string(stream.Stream))
+		md, err := r.GetXMPMetadata()
+		require.NoError(t, err)
+		tool, _ := md.GetProperty(XMPNamespacePDFX, "TestMarker")
+		require.Equal(t, docMarker, tool)
+		if c.clearContents {
+			require.Contains(t, buf.String(), "/StmF /Identity")
+		}
+	}
+}
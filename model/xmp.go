@@ -0,0 +1,559 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/unidoc/unipdf/v3/common"
+	"github.com/unidoc/unipdf/v3/core"
+)
+
+// Namespaces of the XMP properties corresponding to the document information dictionary.
+const (
+	XMPNamespaceDC   = "http://purl.org/dc/elements/1.1/"
+	XMPNamespaceXMP  = "http://ns.adobe.com/xap/1.0/"
+	XMPNamespacePDF  = "http://ns.adobe.com/pdf/1.3/"
+	XMPNamespacePDFX = "http://ns.adobe.com/pdfx/1.3/"
+
+	xmpNamespaceRDF = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmpNamespaceX   = "adobe:ns:meta/"
+)
+
+// xmpPrefixes are the preferred prefixes of the namespaces.
+var xmpPrefixes = map[string]string{
+	XMPNamespaceDC:   "dc",
+	XMPNamespaceXMP:  "xmp",
+	XMPNamespacePDF:  "pdf",
+	XMPNamespacePDFX: "pdfx",
+	xmpNamespaceRDF:  "rdf",
+	xmpNamespaceX:    "x",
+}
+
+// xmpDateLayout is the layout of XMP dates (ISO 8601).
+const xmpDateLayout = "2006-01-02T15:04:05-07:00"
+
+// XMPMetadata represents an XMP metadata packet, the content of a metadata stream such as the
+// Metadata entry of the catalog (14.3.2 Metadata Streams). The properties are kept as parsed, so
+// that the properties which are not modified are written back unchanged.
+type XMPMetadata struct {
+	// Nodes of the packet, outside of the xpacket processing instructions.
+	nodes []*xmpNode
+	// Namespace prefixes declared in the packet.
+	prefixes map[string]string // prefix -> namespace
+}
+
+// xmpNode is an XML element or text of the XMP packet. The names keep their prefixes.
+type xmpNode struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	children []*xmpNode
+
+	// Character data of a text node (name is empty), or of a comment if comment is set.
+	text    string
+	comment bool
+}
+
+// NewXMPMetadata returns a new empty XMP metadata packet.
+func NewXMPMetadata() *XMPMetadata {
+	rdf := &xmpNode{
+		name:  xml.Name{Space: "rdf", Local: "RDF"},
+		attrs: []xml.Attr{{Name: xml.Name{Space: "xmlns", Local: "rdf"}, Value: xmpNamespaceRDF}},
+	}
+	meta := &xmpNode{
+		name:     xml.Name{Space: "x", Local: "xmpmeta"},
+		attrs:    []xml.Attr{{Name: xml.Name{Space: "xmlns", Local: "x"}, Value: xmpNamespaceX}},
+		children: []*xmpNode{rdf},
+	}
+	return &XMPMetadata{
+		nodes: []*xmpNode{meta},
+		prefixes: map[string]string{
+			"x":   xmpNamespaceX,
+			"rdf": xmpNamespaceRDF,
+		},
+	}
+}
+
+// NewXMPMetadataFromBytes parses an XMP metadata packet.
+func NewXMPMetadataFromBytes(data []byte) (*XMPMetadata, error) {
+	m := &XMPMetadata{prefixes: map[string]string{}}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	var stack []*xmpNode
+	appendNode := func(node *xmpNode) {
+		if len(stack) == 0 {
+			m.nodes = append(m.nodes, node)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, node)
+		}
+	}
+	for {
+		tok, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			common.Log.Debug("ERROR: Invalid XMP packet: %v", err)
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			node := &xmpNode{name: t.Name, attrs: append([]xml.Attr(nil), t.Attr...)}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" {
+					m.prefixes[attr.Name.Local] = attr.Value
+				}
+			}
+			appendNode(node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, errors.New("invalid XMP packet: unexpected end element")
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 && len(bytes.TrimSpace(t)) > 0 {
+				appendNode(&xmpNode{text: string(t)})
+			}
+		case xml.Comment:
+			appendNode(&xmpNode{text: string(t), comment: true})
+		}
+	}
+	if len(stack) != 0 {
+		return nil, errors.New("invalid XMP packet: unterminated element")
+	}
+	if m.rdf() == nil {
+		return nil, errors.New("invalid XMP packet: missing rdf:RDF")
+	}
+	return m, nil
+}
+
+// NewXMPMetadataFromStream loads an XMP metadata packet from metadata stream `stream`.
+func NewXMPMetadataFromStream(stream *core.PdfObjectStream) (*XMPMetadata, error) {
+	data, err := core.DecodeStream(stream)
+	if err != nil {
+		return nil, err
+	}
+	return NewXMPMetadataFromBytes(data)
+}
+
+// Copy returns a copy of the XMP metadata packet.
+func (m *XMPMetadata) Copy() *XMPMetadata {
+	c := &XMPMetadata{
+		nodes:    make([]*xmpNode, len(m.nodes)),
+		prefixes: make(map[string]string, len(m.prefixes)),
+	}
+	for i, node := range m.nodes {
+		c.nodes[i] = node.copy()
+	}
+	for prefix, ns := range m.prefixes {
+		c.prefixes[prefix] = ns
+	}
+	return c
+}
+
+// copy returns a deep copy of the node.
+func (node *xmpNode) copy() *xmpNode {
+	c := *node
+	c.attrs = append([]xml.Attr(nil), node.attrs...)
+	c.children = make([]*xmpNode, len(node.children))
+	for i, child := range node.children {
+		c.children[i] = child.copy()
+	}
+	return &c
+}
+
+// namespace returns the namespace of `prefix`.
+func (m *XMPMetadata) namespace(prefix string) string {
+	if ns, ok := m.prefixes[prefix]; ok {
+		return ns
+	}
+	if prefix == "xml" {
+		return "http://www.w3.org/XML/1998/namespace"
+	}
+	return ""
+}
+
+// is returns true if `name` is `local` in namespace `ns`.
+func (m *XMPMetadata) is(name xml.Name, ns, local string) bool {
+	return name.Local == local && m.namespace(name.Space) == ns
+}
+
+// rdf returns the rdf:RDF element.
+func (m *XMPMetadata) rdf() *xmpNode {
+	var find func(nodes []*xmpNode) *xmpNode
+	find = func(nodes []*xmpNode) *xmpNode {
+		for _, node := range nodes {
+			if m.is(node.name, xmpNamespaceRDF, "RDF") {
+				return node
+			}
+			if found := find(node.children); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+	return find(m.nodes)
+}
+
+// descriptions returns the rdf:Description elements.
+func (m *XMPMetadata) descriptions() []*xmpNode {
+	rdf := m.rdf()
+	if rdf == nil {
+		return nil
+	}
+	var descriptions []*xmpNode
+	for _, node := range rdf.children {
+		if m.is(node.name, xmpNamespaceRDF, "Description") {
+			descriptions = append(descriptions, node)
+		}
+	}
+	return descriptions
+}
+
+// GetProperty returns the value of the property `name` of namespace `ns`. The value of a language
+// alternative is the default value, the value of an array is its items joined with "; ".
+func (m *XMPMetadata) GetProperty(ns, name string) (string, bool) {
+	for _, desc := range m.descriptions() {
+		for _, attr := range desc.attrs {
+			if m.is(attr.Name, ns, name) {
+				return attr.Value, true
+			}
+		}
+		for _, node := range desc.children {
+			if m.is(node.name, ns, name) {
+				return m.nodeValue(node), true
+			}
+		}
+	}
+	return "", false
+}
+
+// nodeValue returns the value of property element `node`.
+func (m *XMPMetadata) nodeValue(node *xmpNode) string {
+	var items []string
+	for _, child := range node.children {
+		switch {
+		case child.comment:
+		case child.name.Local == "":
+			return child.text
+		case m.is(child.name, xmpNamespaceRDF, "Alt"):
+			// Language alternative: the x-default item, or the first one.
+			value := ""
+			for i, li := range child.children {
+				if !m.is(li.name, xmpNamespaceRDF, "li") {
+					continue
+				}
+				if i == 0 {
+					value = li.textValue()
+				}
+				for _, attr := range li.attrs {
+					if attr.Name.Local == "lang" && attr.Value == "x-default" {
+						return li.textValue()
+					}
+				}
+			}
+			return value
+		case m.is(child.name, xmpNamespaceRDF, "Seq"), m.is(child.name, xmpNamespaceRDF, "Bag"):
+			for _, li := range child.children {
+				if m.is(li.name, xmpNamespaceRDF, "li") {
+					items = append(items, li.textValue())
+				}
+			}
+		}
+	}
+	return strings.Join(items, "; ")
+}
+
+// textValue returns the text of the element.
+func (node *xmpNode) textValue() string {
+	for _, child := range node.children {
+		if child.name.Local == "" && !child.comment {
+			return child.text
+		}
+	}
+	return ""
+}
+
+// SetProperty sets the simple property `name` of namespace `ns` to `value`, replacing the
+// existing property. An empty value removes the property.
+func (m *XMPMetadata) SetProperty(ns, name, value string) {
+	m.setProperty(ns, name, value, "")
+}
+
+// setProperty sets property `name` of namespace `ns` to `value`, as a simple property if
+// `container` is empty, otherwise as an array of the container type with a single item (Alt, Seq
+// or Bag).
+func (m *XMPMetadata) setProperty(ns, name, value, container string) {
+	// Remove the existing property.
+	var target *xmpNode
+	index := -1
+	for _, desc := range m.descriptions() {
+		for i := 0; i < len(desc.attrs); i++ {
+			if m.is(desc.attrs[i].Name, ns, name) {
+				desc.attrs = append(desc.attrs[:i], desc.attrs[i+1:]...)
+				i--
+			}
+		}
+		for i := 0; i < len(desc.children); i++ {
+			if m.is(desc.children[i].name, ns, name) {
+				if target == nil {
+					target, index = desc, i
+				}
+				desc.children = append(desc.children[:i], desc.children[i+1:]...)
+				i--
+			}
+		}
+	}
+	if value == "" {
+		return
+	}
+
+	prefix := m.prefix(ns)
+	if target == nil {
+		target = m.description(ns, prefix)
+		index = len(target.children)
+	}
+	rdfPrefix := m.prefix(xmpNamespaceRDF)
+	text := &xmpNode{text: value}
+	prop := &xmpNode{name: xml.Name{Space: prefix, Local: name}}
+	if container == "" {
+		prop.children = []*xmpNode{text}
+	} else {
+		li := &xmpNode{name: xml.Name{Space: rdfPrefix, Local: "li"}, children: []*xmpNode{text}}
+		if container == "Alt" {
+			li.attrs = []xml.Attr{{Name: xml.Name{Space: "xml", Local: "lang"}, Value: "x-default"}}
+		}
+		prop.children = []*xmpNode{{
+			name:     xml.Name{Space: rdfPrefix, Local: container},
+			children: []*xmpNode{li},
+		}}
+	}
+	target.children = append(target.children, nil)
+	copy(target.children[index+1:], target.children[index:])
+	target.children[index] = prop
+}
+
+// prefix returns the prefix of namespace `ns`, declaring it if needed.
+func (m *XMPMetadata) prefix(ns string) string {
+	for prefix, uri := range m.prefixes {
+		if uri == ns {
+			return prefix
+		}
+	}
+	prefix, ok := xmpPrefixes[ns]
+	if !ok {
+		prefix = "ns"
+	}
+	base := prefix
+	for i := 1; m.prefixes[prefix] != ""; i++ {
+		prefix = fmt.Sprintf("%s%d", base, i)
+	}
+	m.prefixes[prefix] = ns
+	return prefix
+}
+
+// description returns an rdf:Description element declaring prefix `prefix` of namespace `ns`,
+// adding one if needed.
+func (m *XMPMetadata) description(ns, prefix string) *xmpNode {
+	xmlns := xml.Name{Space: "xmlns", Local: prefix}
+	descriptions := m.descriptions()
+	for _, desc := range descriptions {
+		for _, attr := range desc.attrs {
+			if attr.Name == xmlns {
+				return desc
+			}
+		}
+	}
+
+	rdf := m.rdf()
+	declared := false
+	for _, attr := range rdf.attrs {
+		declared = declared || attr.Name == xmlns
+	}
+	if declared && len(descriptions) > 0 {
+		return descriptions[0]
+	}
+	rdfPrefix := m.prefix(xmpNamespaceRDF)
+	desc := &xmpNode{
+		name: xml.Name{Space: rdfPrefix, Local: "Description"},
+		attrs: []xml.Attr{
+			{Name: xml.Name{Space: rdfPrefix, Local: "about"}, Value: ""},
+		},
+	}
+	if !declared {
+		desc.attrs = append(desc.attrs, xml.Attr{Name: xmlns, Value: ns})
+	}
+	rdf.children = append(rdf.children, desc)
+	return desc
+}
+
+// GetPdfInfo returns the document information corresponding to the XMP properties:
+// dc:title, dc:creator, dc:description, pdf:Keywords, xmp:CreatorTool, pdf:Producer,
+// xmp:CreateDate, xmp:ModifyDate, pdf:Trapped and the pdfx custom properties.
+func (m *XMPMetadata) GetPdfInfo() *PdfInfo {
+	info := NewPdfInfo()
+	info.Title, _ = m.GetProperty(XMPNamespaceDC, "title")
+	info.Author, _ = m.GetProperty(XMPNamespaceDC, "creator")
+	info.Subject, _ = m.GetProperty(XMPNamespaceDC, "description")
+	info.Keywords, _ = m.GetProperty(XMPNamespacePDF, "Keywords")
+	info.Creator, _ = m.GetProperty(XMPNamespaceXMP, "CreatorTool")
+	info.Producer, _ = m.GetProperty(XMPNamespacePDF, "Producer")
+	if trapped, ok := m.GetProperty(XMPNamespacePDF, "Trapped"); ok {
+		info.Trapped = PdfInfoTrapped(trapped)
+	}
+	if date, ok := m.getDate(XMPNamespaceXMP, "CreateDate"); ok {
+		info.CreationDate = &date
+	}
+	if date, ok := m.getDate(XMPNamespaceXMP, "ModifyDate"); ok {
+		info.ModifiedDate = &date
+	}
+	for _, desc := range m.descriptions() {
+		for _, attr := range desc.attrs {
+			if m.namespace(attr.Name.Space) == XMPNamespacePDFX {
+				info.setCustom(attr.Name.Local, attr.Value)
+			}
+		}
+		for _, node := range desc.children {
+			if m.namespace(node.name.Space) == XMPNamespacePDFX {
+				info.setCustom(node.name.Local, m.nodeValue(node))
+			}
+		}
+	}
+	return info
+}
+
+// getDate returns the date property `name` of namespace `ns`.
+func (m *XMPMetadata) getDate(ns, name string) (PdfDate, bool) {
+	value, ok := m.GetProperty(ns, name)
+	if !ok {
+		return PdfDate{}, false
+	}
+	for _, layout := range []string{xmpDateLayout, "2006-01-02T15:04:05Z07:00", "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			date, err := NewPdfDateFromTime(t)
+			return date, err == nil
+		}
+	}
+	common.Log.Debug("Invalid XMP date %s: %q", name, value)
+	return PdfDate{}, false
+}
+
+// SetPdfInfo sets the XMP properties corresponding to the entries of the document information
+// dictionary `info` (see GetPdfInfo). The properties of the empty entries are removed, except for
+// the custom properties which are only added or replaced.
+func (m *XMPMetadata) SetPdfInfo(info *PdfInfo) {
+	m.setProperty(XMPNamespaceDC, "title", info.Title, "Alt")
+	m.setProperty(XMPNamespaceDC, "creator", info.Author, "Seq")
+	m.setProperty(XMPNamespaceDC, "description", info.Subject, "Alt")
+	m.SetProperty(XMPNamespacePDF, "Keywords", info.Keywords)
+	m.SetProperty(XMPNamespaceXMP, "CreatorTool", info.Creator)
+	m.SetProperty(XMPNamespacePDF, "Producer", info.Producer)
+	m.SetProperty(XMPNamespacePDF, "Trapped", string(info.Trapped))
+	dateValue := func(date *PdfDate) string {
+		if date == nil {
+			return ""
+		}
+		return date.ToGoTime().Format(xmpDateLayout)
+	}
+	m.SetProperty(XMPNamespaceXMP, "CreateDate", dateValue(info.CreationDate))
+	m.SetProperty(XMPNamespaceXMP, "ModifyDate", dateValue(info.ModifiedDate))
+	if info.ModifiedDate != nil {
+		m.SetProperty(XMPNamespaceXMP, "MetadataDate", dateValue(info.ModifiedDate))
+	}
+	for _, key := range info.customKeys {
+		m.SetProperty(XMPNamespacePDFX, key, info.customValues[key])
+	}
+}
+
+// Bytes returns the XMP metadata packet, wrapped in xpacket processing instructions.
+func (m *XMPMetadata) Bytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	for _, node := range m.nodes {
+		node.write(&buf, 0)
+	}
+	buf.WriteString("<?xpacket end=\"w\"?>")
+	return buf.Bytes()
+}
+
+// write writes the node with indentation `depth`.
+func (node *xmpNode) write(buf *bytes.Buffer, depth int) {
+	indent := strings.Repeat(" ", depth)
+	if node.comment {
+		fmt.Fprintf(buf, "%s<!--%s-->\n", indent, node.text)
+		return
+	}
+	if node.name.Local == "" {
+		xml.EscapeText(buf, []byte(node.text))
+		return
+	}
+
+	buf.WriteString(indent)
+	buf.WriteByte('<')
+	buf.WriteString(xmpQName(node.name))
+	for _, attr := range node.attrs {
+		fmt.Fprintf(buf, " %s=\"", xmpQName(attr.Name))
+		xml.EscapeText(buf, []byte(attr.Value))
+		buf.WriteByte('"')
+	}
+	if len(node.children) == 0 {
+		buf.WriteString("/>\n")
+		return
+	}
+	buf.WriteByte('>')
+	if len(node.children) == 1 && node.children[0].name.Local == "" && !node.children[0].comment {
+		// Text content.
+		node.children[0].write(buf, 0)
+	} else {
+		buf.WriteByte('\n')
+		for _, child := range node.children {
+			child.write(buf, depth+1)
+		}
+		buf.WriteString(indent)
+	}
+	fmt.Fprintf(buf, "</%s>\n", xmpQName(node.name))
+}
+
+// xmpQName returns the qualified name of `name` with its prefix.
+func xmpQName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// ToPdfObject returns a metadata stream with the XMP metadata packet. The stream is not
+// compressed, so that the metadata can be read by applications which do not parse PDF files.
+func (m *XMPMetadata) ToPdfObject() core.PdfObject {
+	stream, _ := core.MakeStream(m.Bytes(), nil)
+	stream.Set("Type", core.MakeName("Metadata"))
+	stream.Set("Subtype", core.MakeName("XML"))
+	return stream
+}
+
+// GetXMPMetadata returns the XMP metadata packet of the Metadata entry of the catalog, or nil if
+// the document has none.
+func (r *PdfReader) GetXMPMetadata() (*XMPMetadata, error) {
+	obj := core.ResolveReference(r.catalog.Get("Metadata"))
+	if obj == nil {
+		return nil, nil
+	}
+	stream, ok := core.GetStream(obj)
+	if !ok {
+		r.log().Debug("ERROR: Metadata not a stream (%T)", obj)
+		return nil, ErrTypeCheck
+	}
+	return NewXMPMetadataFromStream(stream)
+}
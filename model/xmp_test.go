@@ -0,0 +1,80 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testXMPPacket = `<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">
+   <dc:title><rdf:Alt><rdf:li xml:lang="fr">Titre</rdf:li><rdf:li xml:lang="x-default">Title</rdf:li></rdf:Alt></dc:title>
+   <dc:creator><rdf:Seq><rdf:li>Jane</rdf:li><rdf:li>John</rdf:li></rdf:Seq></dc:creator>
+  </rdf:Description>
+  <rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmlns:pdf="http://ns.adobe.com/pdf/1.3/"
+    xmp:CreatorTool="Editor" pdf:Producer="Converter">
+   <xmp:CreateDate>2019-03-04T05:06:07+02:00</xmp:CreateDate>
+  </rdf:Description>
+  <rdf:Description rdf:about="" xmlns:pdfx="http://ns.adobe.com/pdfx/1.3/" pdfx:Reference="A-1"/>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+func TestXMPMetadataGetPdfInfo(t *testing.T) {
+	md, err := NewXMPMetadataFromBytes([]byte(testXMPPacket))
+	require.NoError(t, err)
+
+	info := md.GetPdfInfo()
+	require.Equal(t, "Title", info.Title)
+	require.Equal(t, "Jane; John", info.Author)
+	require.Equal(t, "Editor", info.Creator)
+	require.Equal(t, "Converter", info.Producer)
+	require.Equal(t, "A-1", info.CustomInfo("Reference"))
+	require.NotNil(t, info.CreationDate)
+	expected := time.Date(2019, 3, 4, 5, 6, 7, 0, time.FixedZone("", 7200))
+	require.True(t, expected.Equal(info.CreationDate.ToGoTime()))
+
+	_, err = NewXMPMetadataFromBytes([]byte("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\"></x:xmpmeta>"))
+	require.Error(t, err)
+}
+
+func TestXMPMetadataSetPdfInfo(t *testing.T) {
+	md, err := NewXMPMetadataFromBytes([]byte(testXMPPacket))
+	require.NoError(t, err)
+
+	info := md.GetPdfInfo()
+	info.Title = "New <title>"
+	info.Author = ""
+	info.Keywords = "a, b"
+	require.NoError(t, info.SetCustomInfo("Status", "Final"))
+	md.SetPdfInfo(info)
+
+	// Round trip through the serialized packet.
+	md, err = NewXMPMetadataFromBytes(md.Bytes())
+	require.NoError(t, err)
+	synced := md.GetPdfInfo()
+	require.Equal(t, "New <title>", synced.Title)
+	require.Equal(t, "", synced.Author)
+	require.Equal(t, "a, b", synced.Keywords)
+	require.Equal(t, "Editor", synced.Creator)
+	require.Equal(t, "Final", synced.CustomInfo("Status"))
+	require.Equal(t, "A-1", synced.CustomInfo("Reference"))
+	require.True(t, info.CreationDate.ToGoTime().Equal(synced.CreationDate.ToGoTime()))
+	_, ok := md.GetProperty(XMPNamespaceDC, "creator")
+	require.False(t, ok)
+
+	// New packet.
+	md = NewXMPMetadata()
+	md.SetPdfInfo(info)
+	md, err = NewXMPMetadataFromBytes(md.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, "New <title>", md.GetPdfInfo().Title)
+}
@@ -216,6 +216,12 @@ type XObjectImage struct {
 	Stream       []byte
 	// Primitive
 	primitive *core.PdfObjectStream
+
+	// jpxColorspace and jpxBitsPerComponent are set when the ColorSpace and BitsPerComponent of
+	// a JPXDecode image were determined from the JPEG 2000 data. They are then not written to the
+	// image dictionary.
+	jpxColorspace       bool
+	jpxBitsPerComponent bool
 }
 
 // NewXObjectImage returns a new XObjectImage.
@@ -406,12 +412,17 @@ func NewXObjectImageFromStream(stream *core.PdfObjectStream) (*XObjectImage, err
 		return nil, errors.New("height missing")
 	}
 
+	jpxEnc, isJPX := encoder.(*core.JPXEncoder)
 	if obj := core.TraceToDirectObject(dict.Get("ColorSpace")); obj != nil {
 		cs, err := NewPdfColorspaceFromPdfObject(obj)
 		if err != nil {
 			return nil, err
 		}
 		img.ColorSpace = cs
+	} else if isJPX {
+		// JPXDecode images take the colorspace from the JPEG 2000 data when not specified.
+		img.ColorSpace = newPdfColorspaceFromJPX(jpxEnc)
+		img.jpxColorspace = true
 	} else {
 		// If not specified, assume gray..
 		common.Log.Debug("XObject Image colorspace not specified - assuming 1 color component")
@@ -425,6 +436,11 @@ func NewXObjectImageFromStream(stream *core.PdfObjectStream) (*XObjectImage, err
 		}
 		iVal := int64(*iObj)
 		img.BitsPerComponent = &iVal
+	} else if isJPX {
+		// Optional for JPXDecode images: the bits per component of the decoded samples.
+		iVal := int64(jpxEnc.BitsPerComponent)
+		img.BitsPerComponent = &iVal
+		img.jpxBitsPerComponent = true
 	}
 
 	img.Intent = dict.Get("Intent")
@@ -448,6 +464,31 @@ func NewXObjectImageFromStream(stream *core.PdfObjectStream) (*XObjectImage, err
 	return img, nil
 }
 
+// newPdfColorspaceFromJPX returns the colorspace of a JPXDecode image without ColorSpace entry,
+// as determined from the JPEG 2000 data.
+func newPdfColorspaceFromJPX(enc *core.JPXEncoder) PdfColorspace {
+	switch enc.ColorSpace {
+	case "ICCBased":
+		cs, err := NewPdfColorspaceICCBased(enc.ColorComponents)
+		if err == nil {
+			cs.Data = enc.ICCProfile
+			return cs
+		}
+		common.Log.Debug("Invalid JPX ICC profile: %v", err)
+	case "DeviceRGB":
+		return NewPdfColorspaceDeviceRGB()
+	case "DeviceCMYK":
+		return NewPdfColorspaceDeviceCMYK()
+	}
+	switch enc.ColorComponents {
+	case 3:
+		return NewPdfColorspaceDeviceRGB()
+	case 4:
+		return NewPdfColorspaceDeviceCMYK()
+	}
+	return NewPdfColorspaceDeviceGray()
+}
+
 // SetImage updates XObject Image with new image data.
 func (ximg *XObjectImage) SetImage(img *Image, cs PdfColorspace) error {
 	// update image parameters of the filter encoder.
@@ -529,11 +570,33 @@ func (ximg *XObjectImage) ToImage() (*Image, error) {
 
 	image.ColorComponents = ximg.ColorSpace.GetNumComponents()
 
-	decoded, err := core.DecodeStream(ximg.primitive)
-	if err != nil {
-		return nil, err
+	if jpxEnc, ok := ximg.Filter.(*core.JPXEncoder); ok {
+		// The samples of JPXDecode images are decoded with 8 or 16 bits per component, and
+		// the opacity channel is the soft mask of the image if SMaskInData is set.
+		jpxImg, err := jpxEnc.DecodeImage(ximg.Stream)
+		if err != nil {
+			return nil, err
+		}
+		image.Width = int64(jpxImg.Width)
+		image.Height = int64(jpxImg.Height)
+		image.BitsPerComponent = int64(jpxImg.BitsPerComponent)
+		image.ColorComponents = jpxImg.ColorComponents
+		image.Data = jpxImg.Data
+		if jpxImg.Alpha != nil {
+			image.alphaData = jpxImg.Alpha
+			image.hasAlpha = true
+		}
+		// The Decode array is ignored for JPXDecode images, except for image masks.
+		if isMask, _ := core.GetBoolVal(ximg.ImageMask); !isMask {
+			return image, nil
+		}
+	} else {
+		decoded, err := core.DecodeStream(ximg.primitive)
+		if err != nil {
+			return nil, err
+		}
+		image.Data = decoded
 	}
-	image.Data = decoded
 
 	if ximg.Decode != nil {
 		darr, ok := ximg.Decode.(*core.PdfObjectArray)
@@ -573,11 +636,12 @@ func (ximg *XObjectImage) ToPdfObject() core.PdfObject {
 	dict.Set("Width", core.MakeInteger(*(ximg.Width)))
 	dict.Set("Height", core.MakeInteger(*(ximg.Height)))
 
-	if ximg.BitsPerComponent != nil {
+	_, isJPX := ximg.Filter.(*core.JPXEncoder)
+	if ximg.BitsPerComponent != nil && !(isJPX && ximg.jpxBitsPerComponent) {
 		dict.Set("BitsPerComponent", core.MakeInteger(*(ximg.BitsPerComponent)))
 	}
 
-	if ximg.ColorSpace != nil {
+	if ximg.ColorSpace != nil && !(isJPX && ximg.jpxColorspace) {
 		dict.SetIfNotNil("ColorSpace", ximg.ColorSpace.ToPdfObject())
 	}
 	dict.SetIfNotNil("Intent", ximg.Intent)
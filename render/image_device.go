@@ -9,9 +9,9 @@ import (
 	"errors"
 	"fmt"
 	"image"
-	"image/draw"
 	"image/jpeg"
 	"image/png"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
@@ -31,35 +31,23 @@ func NewImageDevice() *ImageDevice {
 }
 
 // Render converts the specified PDF page into an image and returns the result.
+// The image shows the visible region of the page (its crop box), rotated as
+// specified by the page, at one pixel per 1/72 inch.
 func (d *ImageDevice) Render(page *model.PdfPage) (image.Image, error) {
 	// Get page dimensions.
-	mbox, err := page.GetMediaBox()
+	geom, err := page.GetGeometry()
 	if err != nil {
 		return nil, err
 	}
+	width, height := geom.Size()
 
 	// Render page.
-	width, height := mbox.Llx+mbox.Width(), mbox.Lly+mbox.Height()
-
-	ctx := imagerender.NewContext(int(width), int(height))
+	ctx := imagerender.NewContext(int(math.Round(width)), int(math.Round(height)))
 	if err := d.renderPage(ctx, page); err != nil {
 		return nil, err
 	}
 
-	// Apply crop box, if one exists.
-	img := ctx.Image()
-	if box := page.CropBox; box != nil {
-		// Calculate crop bounds and crop start position.
-		cropBounds := image.Rect(0, 0, int(box.Width()), int(box.Height()))
-		cropStart := image.Pt(int(box.Llx), int(height-box.Ury))
-
-		// Crop image.
-		cropImg := image.NewRGBA(cropBounds)
-		draw.Draw(cropImg, cropBounds, img, cropStart, draw.Src)
-		img = cropImg
-	}
-
-	return img, nil
+	return ctx.Image(), nil
 }
 
 // RenderToPath converts the specified PDF page into an image and saves the
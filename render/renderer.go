@@ -25,6 +25,9 @@ var (
 )
 
 type renderer struct {
+	// ocVisibility is the visibility of the optional content of the rendered page. The content of
+	// hidden optional content groups is not drawn. All the content is visible if nil.
+	ocVisibility *model.OCVisibility
 }
 
 func (r renderer) renderPage(ctx context.Context, page *model.PdfPage) error {
@@ -33,9 +36,10 @@ func (r renderer) renderPage(ctx context.Context, page *model.PdfPage) error {
 		return err
 	}
 
-	// Change coordinate system.
-	ctx.Translate(0, float64(ctx.Height()))
-	ctx.Scale(1, -1)
+	geom, err := page.GetGeometry()
+	if err != nil {
+		return err
+	}
 
 	// Create white background.
 	ctx.Push()
@@ -44,10 +48,24 @@ func (r renderer) renderPage(ctx context.Context, page *model.PdfPage) error {
 	ctx.Fill()
 	ctx.Pop()
 
+	// Change coordinate system: the visible region of the page, rotated as specified by the page,
+	// is mapped to the context.
+	width, _ := geom.Size()
+	scale := 1.0
+	if width > 0 {
+		scale = float64(ctx.Width()) / width
+	}
+	ctx.SetMatrix(geom.DeviceMatrix(scale))
+
 	// Set defaults.
 	ctx.SetLineWidth(1.0)
 	ctx.SetRGBA(0, 0, 0, 1)
 
+	r.ocVisibility, err = page.GetOCVisibility()
+	if err != nil {
+		common.Log.Debug("ERROR: Optional content properties: %v", err)
+	}
+
 	return r.renderContentStream(ctx, contents, page.Resources)
 }
 
@@ -64,9 +82,33 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 	})
 
 	processor := contentstream.NewContentStreamProcessor(*operations)
+	ocState := r.ocVisibility.NewContentState()
 	processor.AddHandler(contentstream.HandlerConditionEnumAllOperands, "",
 		func(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState, resources *model.PdfPageResources) error {
 			common.Log.Debug("Processing %s", op.Operand)
+			if ocState.Hidden() {
+				// Hidden optional content: discard the paths and skip the painting operators
+				// while keeping track of the text location.
+				switch op.Operand {
+				case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*":
+					ctx.ClearPath()
+					return nil
+				case "Do", "BI", "sh", "Tj", "TJ":
+					return nil
+				case `'`:
+					textState.ProcTStar()
+					return nil
+				case `"`:
+					if len(op.Params) == 3 {
+						if fv, err := core.GetNumbersAsFloat(op.Params[:2]); err == nil {
+							textState.Tw, textState.Tc = fv[0], fv[1]
+						}
+					}
+					textState.ProcTStar()
+					return nil
+				}
+			}
+
 			switch op.Operand {
 			//
 			// Graphics stage operators
@@ -641,7 +683,10 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 					return errType
 				}
 
-				_, xtype := resources.GetXObjectByName(*name)
+				stream, xtype := resources.GetXObjectByName(*name)
+				if stream != nil && !r.ocVisibility.IsVisible(stream.Get("OC")) {
+					break
+				}
 				switch xtype {
 				case model.XObjectTypeImage:
 					common.Log.Debug("XObject image: %s", name.String())
@@ -1053,8 +1098,10 @@ func (r renderer) renderContentStream(ctx context.Context, contents string, reso
 
 			// Begin a marked-content sequence.
 			case "BMC", "BDC":
+				ocState.BeginMarkedContent(op.Params, resources)
 			// End a marked-content sequence.
 			case "EMC":
+				ocState.EndMarkedContent()
 			default:
 				common.Log.Debug("ERROR: unsupported operand: %s", op.Operand)
 			}